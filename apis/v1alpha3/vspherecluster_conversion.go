@@ -40,6 +40,19 @@ func (src *VSphereCluster) ConvertTo(dstRaw conversion.Hub) error {
 	if restored.Spec.IdentityRef != nil {
 		dst.Spec.IdentityRef = restored.Spec.IdentityRef
 	}
+	dst.Spec.ControlPlaneEndpointDNS = restored.Spec.ControlPlaneEndpointDNS
+	dst.Spec.CloudProvider = restored.Spec.CloudProvider
+	dst.Spec.CSI = restored.Spec.CSI
+	dst.Spec.NSXT = restored.Spec.NSXT
+	dst.Spec.Connection = restored.Spec.Connection
+	dst.Spec.ResourceQuota = restored.Spec.ResourceQuota
+	dst.Spec.VCenters = restored.Spec.VCenters
+	dst.Status.VCenterVersion = restored.Status.VCenterVersion
+	dst.Status.VCenterBuild = restored.Status.VCenterBuild
+	dst.Status.OrphanedVMs = restored.Status.OrphanedVMs
+	dst.Status.LastOrphanedVMSweepTime = restored.Status.LastOrphanedVMSweepTime
+	dst.Status.ControlPlaneEndpointDNSRecord = restored.Status.ControlPlaneEndpointDNSRecord
+	dst.Status.Deletion = restored.Status.Deletion
 	return nil
 }
 
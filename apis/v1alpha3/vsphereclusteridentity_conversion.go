@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha3
 
 import (
+	utilconversion "sigs.k8s.io/cluster-api/util/conversion"
 	"sigs.k8s.io/controller-runtime/pkg/conversion"
 
 	infrav1beta1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
@@ -28,6 +29,20 @@ func (src *VSphereClusterIdentity) ConvertTo(dstRaw conversion.Hub) error {
 	if err := Convert_v1alpha3_VSphereClusterIdentity_To_v1beta1_VSphereClusterIdentity(src, dst, nil); err != nil {
 		return err
 	}
+
+	// Manually restore data.
+	restored := &infrav1beta1.VSphereClusterIdentity{}
+	if ok, err := utilconversion.UnmarshalData(src, restored); err != nil || !ok {
+		return err
+	}
+	dst.Spec.RateLimit = restored.Spec.RateLimit
+	dst.Spec.Connection = restored.Spec.Connection
+	dst.Spec.TLS = restored.Spec.TLS
+	dst.Spec.Vault = restored.Spec.Vault
+	if restored.Spec.AllowedNamespaces != nil && dst.Spec.AllowedNamespaces != nil {
+		dst.Spec.AllowedNamespaces.NamespaceList = restored.Spec.AllowedNamespaces.NamespaceList
+	}
+
 	return nil
 }
 
@@ -37,7 +52,9 @@ func (dst *VSphereClusterIdentity) ConvertFrom(srcRaw conversion.Hub) error { //
 	if err := Convert_v1beta1_VSphereClusterIdentity_To_v1alpha3_VSphereClusterIdentity(src, dst, nil); err != nil {
 		return err
 	}
-	return nil
+
+	// Preserve Hub data on down-conversion.
+	return utilconversion.MarshalData(src, dst)
 }
 
 // ConvertTo converts this VSphereClusterIdentityList to the Hub version (v1beta1).
@@ -22,8 +22,107 @@ import (
 	v1beta1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 )
 
+// Convert_v1beta1_AllowedNamespaces_To_v1alpha4_AllowedNamespaces is an autogenerated conversion function.
+//nolint:golint,revive,stylecheck
+func Convert_v1beta1_AllowedNamespaces_To_v1alpha4_AllowedNamespaces(in *v1beta1.AllowedNamespaces, out *AllowedNamespaces, s conversion.Scope) error {
+	return autoConvert_v1beta1_AllowedNamespaces_To_v1alpha4_AllowedNamespaces(in, out, s)
+}
+
+// Convert_v1beta1_FailureDomainHosts_To_v1alpha4_FailureDomainHosts is an autogenerated conversion function.
+//nolint:golint,revive,stylecheck
+func Convert_v1beta1_FailureDomainHosts_To_v1alpha4_FailureDomainHosts(in *v1beta1.FailureDomainHosts, out *FailureDomainHosts, s conversion.Scope) error {
+	return autoConvert_v1beta1_FailureDomainHosts_To_v1alpha4_FailureDomainHosts(in, out, s)
+}
+
+// Convert_v1beta1_Topology_To_v1alpha4_Topology is an autogenerated conversion function.
+//nolint:golint,revive,stylecheck
+func Convert_v1beta1_Topology_To_v1alpha4_Topology(in *v1beta1.Topology, out *Topology, s conversion.Scope) error {
+	return autoConvert_v1beta1_Topology_To_v1alpha4_Topology(in, out, s)
+}
+
 // Convert_v1beta1_VirtualMachineCloneSpec_To_v1alpha4_VirtualMachineCloneSpec is an autogenerated conversion function.
 //nolint:golint,revive,stylecheck
 func Convert_v1beta1_VirtualMachineCloneSpec_To_v1alpha4_VirtualMachineCloneSpec(in *v1beta1.VirtualMachineCloneSpec, out *VirtualMachineCloneSpec, s conversion.Scope) error {
 	return autoConvert_v1beta1_VirtualMachineCloneSpec_To_v1alpha4_VirtualMachineCloneSpec(in, out, s)
 }
+
+// Convert_v1beta1_VSphereVMSpec_To_v1alpha4_VSphereVMSpec is an autogenerated conversion function.
+//nolint:golint,revive,stylecheck
+func Convert_v1beta1_VSphereVMSpec_To_v1alpha4_VSphereVMSpec(in *v1beta1.VSphereVMSpec, out *VSphereVMSpec, s conversion.Scope) error {
+	return autoConvert_v1beta1_VSphereVMSpec_To_v1alpha4_VSphereVMSpec(in, out, s)
+}
+
+// Convert_v1beta1_VSphereMachineSpec_To_v1alpha4_VSphereMachineSpec is an autogenerated conversion function.
+//nolint:golint,revive,stylecheck
+func Convert_v1beta1_VSphereMachineSpec_To_v1alpha4_VSphereMachineSpec(in *v1beta1.VSphereMachineSpec, out *VSphereMachineSpec, s conversion.Scope) error {
+	return autoConvert_v1beta1_VSphereMachineSpec_To_v1alpha4_VSphereMachineSpec(in, out, s)
+}
+
+func Convert_v1beta1_VSphereClusterSpec_To_v1alpha4_VSphereClusterSpec(in *v1beta1.VSphereClusterSpec, out *VSphereClusterSpec, s conversion.Scope) error {
+	return autoConvert_v1beta1_VSphereClusterSpec_To_v1alpha4_VSphereClusterSpec(in, out, s)
+}
+
+// Convert_v1beta1_VSphereClusterIdentitySpec_To_v1alpha4_VSphereClusterIdentitySpec is an autogenerated conversion function.
+//nolint:golint,revive,stylecheck
+func Convert_v1beta1_VSphereClusterIdentitySpec_To_v1alpha4_VSphereClusterIdentitySpec(in *v1beta1.VSphereClusterIdentitySpec, out *VSphereClusterIdentitySpec, s conversion.Scope) error {
+	return autoConvert_v1beta1_VSphereClusterIdentitySpec_To_v1alpha4_VSphereClusterIdentitySpec(in, out, s)
+}
+
+// Convert_v1beta1_VSphereClusterStatus_To_v1alpha4_VSphereClusterStatus is an autogenerated conversion function.
+//nolint:golint,revive,stylecheck
+func Convert_v1beta1_VSphereClusterStatus_To_v1alpha4_VSphereClusterStatus(in *v1beta1.VSphereClusterStatus, out *VSphereClusterStatus, s conversion.Scope) error {
+	return autoConvert_v1beta1_VSphereClusterStatus_To_v1alpha4_VSphereClusterStatus(in, out, s)
+}
+
+// Convert_v1beta1_VSphereMachineStatus_To_v1alpha4_VSphereMachineStatus is an autogenerated conversion function.
+//nolint:golint,revive,stylecheck
+func Convert_v1beta1_VSphereMachineStatus_To_v1alpha4_VSphereMachineStatus(in *v1beta1.VSphereMachineStatus, out *VSphereMachineStatus, s conversion.Scope) error {
+	return autoConvert_v1beta1_VSphereMachineStatus_To_v1alpha4_VSphereMachineStatus(in, out, s)
+}
+
+// Convert_v1beta1_VSphereVMStatus_To_v1alpha4_VSphereVMStatus is an autogenerated conversion function.
+//nolint:golint,revive,stylecheck
+func Convert_v1beta1_VSphereVMStatus_To_v1alpha4_VSphereVMStatus(in *v1beta1.VSphereVMStatus, out *VSphereVMStatus, s conversion.Scope) error {
+	return autoConvert_v1beta1_VSphereVMStatus_To_v1alpha4_VSphereVMStatus(in, out, s)
+}
+
+// Convert_v1beta1_VirtualMachine_To_v1alpha4_VirtualMachine is an autogenerated conversion function.
+//nolint:golint,revive,stylecheck
+func Convert_v1beta1_VirtualMachine_To_v1alpha4_VirtualMachine(in *v1beta1.VirtualMachine, out *VirtualMachine, s conversion.Scope) error {
+	return autoConvert_v1beta1_VirtualMachine_To_v1alpha4_VirtualMachine(in, out, s)
+}
+
+// Convert_v1beta1_NetworkSpec_To_v1alpha4_NetworkSpec is an autogenerated conversion function.
+//nolint:golint,revive,stylecheck
+func Convert_v1beta1_NetworkSpec_To_v1alpha4_NetworkSpec(in *v1beta1.NetworkSpec, out *NetworkSpec, s conversion.Scope) error {
+	return autoConvert_v1beta1_NetworkSpec_To_v1alpha4_NetworkSpec(in, out, s)
+}
+
+// Convert_v1beta1_NetworkDeviceSpec_To_v1alpha4_NetworkDeviceSpec is an autogenerated conversion function.
+//nolint:golint,revive,stylecheck
+func Convert_v1beta1_NetworkDeviceSpec_To_v1alpha4_NetworkDeviceSpec(in *v1beta1.NetworkDeviceSpec, out *NetworkDeviceSpec, s conversion.Scope) error {
+	return autoConvert_v1beta1_NetworkDeviceSpec_To_v1alpha4_NetworkDeviceSpec(in, out, s)
+}
+
+// restoreNetwork restores the fields of the network spec, and of its
+// devices, that do not exist in this API version.
+func restoreNetwork(dst, restored *v1beta1.NetworkSpec) {
+	dst.PreferredNodeIPCIDR = restored.PreferredNodeIPCIDR
+	dst.ExcludeNetworkCIDRs = restored.ExcludeNetworkCIDRs
+	dst.AddressWait = restored.AddressWait
+	restoreNetworkDevices(dst.Devices, restored.Devices)
+}
+
+// restoreNetworkDevices restores the fields of the network devices that do
+// not exist in this API version.
+func restoreNetworkDevices(dst, restored []v1beta1.NetworkDeviceSpec) {
+	if len(dst) != len(restored) {
+		return
+	}
+	for i := range dst {
+		dst[i].SwitchName = restored[i].SwitchName
+		dst[i].VLANID = restored[i].VLANID
+		dst[i].SLAAC = restored[i].SLAAC
+		dst[i].IPPool = restored[i].IPPool
+	}
+}
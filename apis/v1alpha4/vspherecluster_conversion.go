@@ -18,6 +18,7 @@ limitations under the License.
 package v1alpha4
 
 import (
+	utilconversion "sigs.k8s.io/cluster-api/util/conversion"
 	"sigs.k8s.io/controller-runtime/pkg/conversion"
 
 	infrav1beta1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
@@ -26,13 +27,45 @@ import (
 // ConvertTo converts this VSphereCluster to the Hub version (v1beta1).
 func (src *VSphereCluster) ConvertTo(dstRaw conversion.Hub) error {
 	dst := dstRaw.(*infrav1beta1.VSphereCluster)
-	return Convert_v1alpha4_VSphereCluster_To_v1beta1_VSphereCluster(src, dst, nil)
+	if err := Convert_v1alpha4_VSphereCluster_To_v1beta1_VSphereCluster(src, dst, nil); err != nil {
+		return err
+	}
+
+	// Manually restore data.
+	restored := &infrav1beta1.VSphereCluster{}
+	if ok, err := utilconversion.UnmarshalData(src, restored); err != nil || !ok {
+		return err
+	}
+	dst.Spec.ControlPlaneEndpointDNS = restored.Spec.ControlPlaneEndpointDNS
+	dst.Spec.CloudProvider = restored.Spec.CloudProvider
+	dst.Spec.CSI = restored.Spec.CSI
+	dst.Spec.NSXT = restored.Spec.NSXT
+	dst.Spec.Connection = restored.Spec.Connection
+	dst.Spec.ResourceQuota = restored.Spec.ResourceQuota
+	dst.Spec.VCenters = restored.Spec.VCenters
+	dst.Status.VCenterVersion = restored.Status.VCenterVersion
+	dst.Status.VCenterBuild = restored.Status.VCenterBuild
+	dst.Status.OrphanedVMs = restored.Status.OrphanedVMs
+	dst.Status.LastOrphanedVMSweepTime = restored.Status.LastOrphanedVMSweepTime
+	dst.Status.ControlPlaneEndpointDNSRecord = restored.Status.ControlPlaneEndpointDNSRecord
+	dst.Status.Deletion = restored.Status.Deletion
+
+	return nil
 }
 
 // ConvertFrom converts from the Hub version (v1beta1) to this VSphereCluster.
 func (dst *VSphereCluster) ConvertFrom(srcRaw conversion.Hub) error {
 	src := srcRaw.(*infrav1beta1.VSphereCluster)
-	return Convert_v1beta1_VSphereCluster_To_v1alpha4_VSphereCluster(src, dst, nil)
+	if err := Convert_v1beta1_VSphereCluster_To_v1alpha4_VSphereCluster(src, dst, nil); err != nil {
+		return err
+	}
+
+	// Preserve Hub data on down-conversion.
+	if err := utilconversion.MarshalData(src, dst); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 // ConvertTo converts this VSphereClusterList to the Hub version (v1beta1).
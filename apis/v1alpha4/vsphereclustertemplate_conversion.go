@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha4
 
 import (
+	utilconversion "sigs.k8s.io/cluster-api/util/conversion"
 	"sigs.k8s.io/controller-runtime/pkg/conversion"
 
 	infrav1beta1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
@@ -25,13 +26,39 @@ import (
 // ConvertTo converts this VSphereClusterTemplate to the Hub version (v1beta1).
 func (src *VSphereClusterTemplate) ConvertTo(dstRaw conversion.Hub) error {
 	dst := dstRaw.(*infrav1beta1.VSphereClusterTemplate)
-	return Convert_v1alpha4_VSphereClusterTemplate_To_v1beta1_VSphereClusterTemplate(src, dst, nil)
+	if err := Convert_v1alpha4_VSphereClusterTemplate_To_v1beta1_VSphereClusterTemplate(src, dst, nil); err != nil {
+		return err
+	}
+
+	// Manually restore data.
+	restored := &infrav1beta1.VSphereClusterTemplate{}
+	if ok, err := utilconversion.UnmarshalData(src, restored); err != nil || !ok {
+		return err
+	}
+	dst.Spec.Template.Spec.ControlPlaneEndpointDNS = restored.Spec.Template.Spec.ControlPlaneEndpointDNS
+	dst.Spec.Template.Spec.CloudProvider = restored.Spec.Template.Spec.CloudProvider
+	dst.Spec.Template.Spec.CSI = restored.Spec.Template.Spec.CSI
+	dst.Spec.Template.Spec.NSXT = restored.Spec.Template.Spec.NSXT
+	dst.Spec.Template.Spec.Connection = restored.Spec.Template.Spec.Connection
+	dst.Spec.Template.Spec.ResourceQuota = restored.Spec.Template.Spec.ResourceQuota
+	dst.Spec.Template.Spec.VCenters = restored.Spec.Template.Spec.VCenters
+
+	return nil
 }
 
 // ConvertFrom converts from the Hub version (v1beta1) to this VSphereClusterTemplate.
 func (dst *VSphereClusterTemplate) ConvertFrom(srcRaw conversion.Hub) error { // nolint
 	src := srcRaw.(*infrav1beta1.VSphereClusterTemplate)
-	return Convert_v1beta1_VSphereClusterTemplate_To_v1alpha4_VSphereClusterTemplate(src, dst, nil)
+	if err := Convert_v1beta1_VSphereClusterTemplate_To_v1alpha4_VSphereClusterTemplate(src, dst, nil); err != nil {
+		return err
+	}
+
+	// Preserve Hub data on down-conversion.
+	if err := utilconversion.MarshalData(src, dst); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 // ConvertTo converts this VSphereClusterIdentityList to the Hub version (v1beta1).
@@ -38,7 +38,37 @@ func (src *VSphereMachine) ConvertTo(dstRaw conversion.Hub) error {
 	}
 
 	dst.Spec.AdditionalDisksGiB = restored.Spec.AdditionalDisksGiB
+	dst.Spec.Disks = restored.Spec.Disks
+	dst.Spec.PowerOffMode = restored.Spec.PowerOffMode
+	dst.Spec.GuestSoftPowerOffTimeout = restored.Spec.GuestSoftPowerOffTimeout
+	dst.Spec.DeletionPolicy = restored.Spec.DeletionPolicy
+	dst.Spec.DriftPolicy = restored.Spec.DriftPolicy
+	dst.Spec.PowerRecoveryPolicy = restored.Spec.PowerRecoveryPolicy
+	dst.Spec.SnapshotSchedule = restored.Spec.SnapshotSchedule
+	dst.Spec.FailureRetryPolicy = restored.Spec.FailureRetryPolicy
+	dst.Spec.Image = restored.Spec.Image
+	dst.Spec.ImageMapping = restored.Spec.ImageMapping
+	dst.Spec.DeploymentZones = restored.Spec.DeploymentZones
 	dst.Spec.TagIDs = restored.Spec.TagIDs
+	dst.Spec.MetadataPropagation = restored.Spec.MetadataPropagation
+	dst.Spec.Placement = restored.Spec.Placement
+	dst.Spec.CreateTargetHierarchy = restored.Spec.CreateTargetHierarchy
+	dst.Spec.ResourcePoolLimits = restored.Spec.ResourcePoolLimits
+	dst.Spec.ResourceAllocation = restored.Spec.ResourceAllocation
+	dst.Spec.EnableHotAdd = restored.Spec.EnableHotAdd
+	dst.Spec.LatencySensitivity = restored.Spec.LatencySensitivity
+	dst.Spec.NumaNodeAffinity = restored.Spec.NumaNodeAffinity
+	dst.Spec.CPUPinning = restored.Spec.CPUPinning
+	dst.Spec.HardwareVersion = restored.Spec.HardwareVersion
+	dst.Spec.Firmware = restored.Spec.Firmware
+	dst.Spec.SecureBoot = restored.Spec.SecureBoot
+	dst.Spec.VTPM = restored.Spec.VTPM
+	dst.Spec.BootstrapDataTransport = restored.Spec.BootstrapDataTransport
+	dst.Spec.GuestTools = restored.Spec.GuestTools
+	dst.Spec.DiskProvisioningType = restored.Spec.DiskProvisioningType
+	dst.Spec.Datastores = restored.Spec.Datastores
+	restoreNetwork(&dst.Spec.Network, &restored.Spec.Network)
+	dst.Status.NodeTopology = restored.Status.NodeTopology
 
 	return nil
 }
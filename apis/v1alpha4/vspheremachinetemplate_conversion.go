@@ -40,7 +40,37 @@ func (src *VSphereMachineTemplate) ConvertTo(dstRaw conversion.Hub) error {
 		return err
 	}
 	dst.Spec.Template.Spec.TagIDs = restored.Spec.Template.Spec.TagIDs
+	dst.Spec.Template.Spec.MetadataPropagation = restored.Spec.Template.Spec.MetadataPropagation
 	dst.Spec.Template.Spec.AdditionalDisksGiB = restored.Spec.Template.Spec.AdditionalDisksGiB
+	dst.Spec.Template.Spec.Disks = restored.Spec.Template.Spec.Disks
+	dst.Spec.Template.Spec.PowerOffMode = restored.Spec.Template.Spec.PowerOffMode
+	dst.Spec.Template.Spec.GuestSoftPowerOffTimeout = restored.Spec.Template.Spec.GuestSoftPowerOffTimeout
+	dst.Spec.Template.Spec.DeletionPolicy = restored.Spec.Template.Spec.DeletionPolicy
+	dst.Spec.Template.Spec.DriftPolicy = restored.Spec.Template.Spec.DriftPolicy
+	dst.Spec.Template.Spec.PowerRecoveryPolicy = restored.Spec.Template.Spec.PowerRecoveryPolicy
+	dst.Spec.Template.Spec.SnapshotSchedule = restored.Spec.Template.Spec.SnapshotSchedule
+	dst.Spec.Template.Spec.FailureRetryPolicy = restored.Spec.Template.Spec.FailureRetryPolicy
+	dst.Spec.Template.Spec.Image = restored.Spec.Template.Spec.Image
+	dst.Spec.Template.Spec.ImageMapping = restored.Spec.Template.Spec.ImageMapping
+	dst.Spec.Template.Spec.DeploymentZones = restored.Spec.Template.Spec.DeploymentZones
+	dst.Spec.Template.Spec.Placement = restored.Spec.Template.Spec.Placement
+	dst.Spec.Template.Spec.CreateTargetHierarchy = restored.Spec.Template.Spec.CreateTargetHierarchy
+	dst.Spec.Template.Spec.ResourcePoolLimits = restored.Spec.Template.Spec.ResourcePoolLimits
+	dst.Spec.Template.Spec.ResourceAllocation = restored.Spec.Template.Spec.ResourceAllocation
+	dst.Spec.Template.Spec.EnableHotAdd = restored.Spec.Template.Spec.EnableHotAdd
+	dst.Spec.Template.Spec.LatencySensitivity = restored.Spec.Template.Spec.LatencySensitivity
+	dst.Spec.Template.Spec.NumaNodeAffinity = restored.Spec.Template.Spec.NumaNodeAffinity
+	dst.Spec.Template.Spec.CPUPinning = restored.Spec.Template.Spec.CPUPinning
+	dst.Spec.Template.Spec.HardwareVersion = restored.Spec.Template.Spec.HardwareVersion
+	dst.Spec.Template.Spec.Firmware = restored.Spec.Template.Spec.Firmware
+	dst.Spec.Template.Spec.SecureBoot = restored.Spec.Template.Spec.SecureBoot
+	dst.Spec.Template.Spec.VTPM = restored.Spec.Template.Spec.VTPM
+	dst.Spec.Template.Spec.BootstrapDataTransport = restored.Spec.Template.Spec.BootstrapDataTransport
+	dst.Spec.Template.Spec.GuestTools = restored.Spec.Template.Spec.GuestTools
+	dst.Spec.Template.Spec.DiskProvisioningType = restored.Spec.Template.Spec.DiskProvisioningType
+	dst.Spec.Template.Spec.Datastores = restored.Spec.Template.Spec.Datastores
+	restoreNetwork(&dst.Spec.Template.Spec.Network, &restored.Spec.Template.Spec.Network)
+	dst.Status = restored.Status
 
 	return nil
 }
@@ -37,7 +37,48 @@ func (src *VSphereVM) ConvertTo(dstRaw conversion.Hub) error {
 		return err
 	}
 	dst.Spec.TagIDs = restored.Spec.TagIDs
+	dst.Spec.MetadataPropagation = restored.Spec.MetadataPropagation
 	dst.Spec.AdditionalDisksGiB = restored.Spec.AdditionalDisksGiB
+	dst.Spec.Disks = restored.Spec.Disks
+	dst.Spec.PowerOffMode = restored.Spec.PowerOffMode
+	dst.Spec.GuestSoftPowerOffTimeout = restored.Spec.GuestSoftPowerOffTimeout
+	dst.Spec.DeletionPolicy = restored.Spec.DeletionPolicy
+	dst.Spec.DriftPolicy = restored.Spec.DriftPolicy
+	dst.Spec.PowerRecoveryPolicy = restored.Spec.PowerRecoveryPolicy
+	dst.Spec.SnapshotSchedule = restored.Spec.SnapshotSchedule
+	dst.Spec.FailureRetryPolicy = restored.Spec.FailureRetryPolicy
+	dst.Spec.InstanceUUID = restored.Spec.InstanceUUID
+	dst.Spec.Placement = restored.Spec.Placement
+	dst.Spec.CreateTargetHierarchy = restored.Spec.CreateTargetHierarchy
+	dst.Spec.ResourcePoolLimits = restored.Spec.ResourcePoolLimits
+	dst.Spec.ResourceAllocation = restored.Spec.ResourceAllocation
+	dst.Spec.EnableHotAdd = restored.Spec.EnableHotAdd
+	dst.Spec.LatencySensitivity = restored.Spec.LatencySensitivity
+	dst.Spec.NumaNodeAffinity = restored.Spec.NumaNodeAffinity
+	dst.Spec.CPUPinning = restored.Spec.CPUPinning
+	dst.Spec.HardwareVersion = restored.Spec.HardwareVersion
+	dst.Spec.Firmware = restored.Spec.Firmware
+	dst.Spec.SecureBoot = restored.Spec.SecureBoot
+	dst.Spec.VTPM = restored.Spec.VTPM
+	dst.Spec.BootstrapDataTransport = restored.Spec.BootstrapDataTransport
+	dst.Spec.GuestTools = restored.Spec.GuestTools
+	dst.Spec.DiskProvisioningType = restored.Spec.DiskProvisioningType
+	dst.Spec.Datastores = restored.Spec.Datastores
+	restoreNetwork(&dst.Spec.Network, &restored.Spec.Network)
+	dst.Status.ResourcePool = restored.Status.ResourcePool
+	dst.Status.Host = restored.Status.Host
+	dst.Status.ComputeCluster = restored.Status.ComputeCluster
+	dst.Status.CurrentResourcePool = restored.Status.CurrentResourcePool
+	dst.Status.Datastore = restored.Status.Datastore
+	dst.Status.Migrations = restored.Status.Migrations
+	dst.Status.Drift = restored.Status.Drift
+	dst.Status.GuestTools = restored.Status.GuestTools
+	dst.Status.DiskProvisioningType = restored.Status.DiskProvisioningType
+	dst.Status.SelectedDatastore = restored.Status.SelectedDatastore
+	dst.Status.MachineAddresses = restored.Status.MachineAddresses
+	dst.Status.AddressWaitStartTime = restored.Status.AddressWaitStartTime
+	dst.Status.IPAllocations = restored.Status.IPAllocations
+	dst.Status.FailureRetries = restored.Status.FailureRetries
 
 	return nil
 }
@@ -55,11 +55,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.AllowedNamespaces)(nil), (*AllowedNamespaces)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_AllowedNamespaces_To_v1alpha4_AllowedNamespaces(a.(*v1beta1.AllowedNamespaces), b.(*AllowedNamespaces), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*FailureDomain)(nil), (*v1beta1.FailureDomain)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha4_FailureDomain_To_v1beta1_FailureDomain(a.(*FailureDomain), b.(*v1beta1.FailureDomain), scope)
 	}); err != nil {
@@ -75,11 +70,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.FailureDomainHosts)(nil), (*FailureDomainHosts)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_FailureDomainHosts_To_v1alpha4_FailureDomainHosts(a.(*v1beta1.FailureDomainHosts), b.(*FailureDomainHosts), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*Network)(nil), (*v1beta1.Network)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha4_Network_To_v1beta1_Network(a.(*Network), b.(*v1beta1.Network), scope)
 	}); err != nil {
@@ -95,11 +85,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.NetworkDeviceSpec)(nil), (*NetworkDeviceSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_NetworkDeviceSpec_To_v1alpha4_NetworkDeviceSpec(a.(*v1beta1.NetworkDeviceSpec), b.(*NetworkDeviceSpec), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*NetworkRouteSpec)(nil), (*v1beta1.NetworkRouteSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha4_NetworkRouteSpec_To_v1beta1_NetworkRouteSpec(a.(*NetworkRouteSpec), b.(*v1beta1.NetworkRouteSpec), scope)
 	}); err != nil {
@@ -115,11 +100,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.NetworkSpec)(nil), (*NetworkSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_NetworkSpec_To_v1alpha4_NetworkSpec(a.(*v1beta1.NetworkSpec), b.(*NetworkSpec), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*NetworkStatus)(nil), (*v1beta1.NetworkStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha4_NetworkStatus_To_v1beta1_NetworkStatus(a.(*NetworkStatus), b.(*v1beta1.NetworkStatus), scope)
 	}); err != nil {
@@ -155,11 +135,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.Topology)(nil), (*Topology)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_Topology_To_v1alpha4_Topology(a.(*v1beta1.Topology), b.(*Topology), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*VSphereCluster)(nil), (*v1beta1.VSphereCluster)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha4_VSphereCluster_To_v1beta1_VSphereCluster(a.(*VSphereCluster), b.(*v1beta1.VSphereCluster), scope)
 	}); err != nil {
@@ -195,11 +170,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.VSphereClusterIdentitySpec)(nil), (*VSphereClusterIdentitySpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_VSphereClusterIdentitySpec_To_v1alpha4_VSphereClusterIdentitySpec(a.(*v1beta1.VSphereClusterIdentitySpec), b.(*VSphereClusterIdentitySpec), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*VSphereClusterIdentityStatus)(nil), (*v1beta1.VSphereClusterIdentityStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha4_VSphereClusterIdentityStatus_To_v1beta1_VSphereClusterIdentityStatus(a.(*VSphereClusterIdentityStatus), b.(*v1beta1.VSphereClusterIdentityStatus), scope)
 	}); err != nil {
@@ -225,21 +195,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.VSphereClusterSpec)(nil), (*VSphereClusterSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_VSphereClusterSpec_To_v1alpha4_VSphereClusterSpec(a.(*v1beta1.VSphereClusterSpec), b.(*VSphereClusterSpec), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*VSphereClusterStatus)(nil), (*v1beta1.VSphereClusterStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha4_VSphereClusterStatus_To_v1beta1_VSphereClusterStatus(a.(*VSphereClusterStatus), b.(*v1beta1.VSphereClusterStatus), scope)
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.VSphereClusterStatus)(nil), (*VSphereClusterStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_VSphereClusterStatus_To_v1alpha4_VSphereClusterStatus(a.(*v1beta1.VSphereClusterStatus), b.(*VSphereClusterStatus), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*VSphereClusterTemplate)(nil), (*v1beta1.VSphereClusterTemplate)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha4_VSphereClusterTemplate_To_v1beta1_VSphereClusterTemplate(a.(*VSphereClusterTemplate), b.(*v1beta1.VSphereClusterTemplate), scope)
 	}); err != nil {
@@ -385,21 +345,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.VSphereMachineSpec)(nil), (*VSphereMachineSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_VSphereMachineSpec_To_v1alpha4_VSphereMachineSpec(a.(*v1beta1.VSphereMachineSpec), b.(*VSphereMachineSpec), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*VSphereMachineStatus)(nil), (*v1beta1.VSphereMachineStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha4_VSphereMachineStatus_To_v1beta1_VSphereMachineStatus(a.(*VSphereMachineStatus), b.(*v1beta1.VSphereMachineStatus), scope)
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.VSphereMachineStatus)(nil), (*VSphereMachineStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_VSphereMachineStatus_To_v1alpha4_VSphereMachineStatus(a.(*v1beta1.VSphereMachineStatus), b.(*VSphereMachineStatus), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*VSphereMachineTemplate)(nil), (*v1beta1.VSphereMachineTemplate)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha4_VSphereMachineTemplate_To_v1beta1_VSphereMachineTemplate(a.(*VSphereMachineTemplate), b.(*v1beta1.VSphereMachineTemplate), scope)
 	}); err != nil {
@@ -465,31 +415,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.VSphereVMSpec)(nil), (*VSphereVMSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_VSphereVMSpec_To_v1alpha4_VSphereVMSpec(a.(*v1beta1.VSphereVMSpec), b.(*VSphereVMSpec), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*VSphereVMStatus)(nil), (*v1beta1.VSphereVMStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha4_VSphereVMStatus_To_v1beta1_VSphereVMStatus(a.(*VSphereVMStatus), b.(*v1beta1.VSphereVMStatus), scope)
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.VSphereVMStatus)(nil), (*VSphereVMStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_VSphereVMStatus_To_v1alpha4_VSphereVMStatus(a.(*v1beta1.VSphereVMStatus), b.(*VSphereVMStatus), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*VirtualMachine)(nil), (*v1beta1.VirtualMachine)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha4_VirtualMachine_To_v1beta1_VirtualMachine(a.(*VirtualMachine), b.(*v1beta1.VirtualMachine), scope)
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1beta1.VirtualMachine)(nil), (*VirtualMachine)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1beta1_VirtualMachine_To_v1alpha4_VirtualMachine(a.(*v1beta1.VirtualMachine), b.(*VirtualMachine), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*VirtualMachineCloneSpec)(nil), (*v1beta1.VirtualMachineCloneSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha4_VirtualMachineCloneSpec_To_v1beta1_VirtualMachineCloneSpec(a.(*VirtualMachineCloneSpec), b.(*v1beta1.VirtualMachineCloneSpec), scope)
 	}); err != nil {
@@ -505,6 +440,71 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddConversionFunc((*v1beta1.AllowedNamespaces)(nil), (*AllowedNamespaces)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_AllowedNamespaces_To_v1alpha4_AllowedNamespaces(a.(*v1beta1.AllowedNamespaces), b.(*AllowedNamespaces), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.FailureDomainHosts)(nil), (*FailureDomainHosts)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_FailureDomainHosts_To_v1alpha4_FailureDomainHosts(a.(*v1beta1.FailureDomainHosts), b.(*FailureDomainHosts), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.NetworkDeviceSpec)(nil), (*NetworkDeviceSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_NetworkDeviceSpec_To_v1alpha4_NetworkDeviceSpec(a.(*v1beta1.NetworkDeviceSpec), b.(*NetworkDeviceSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.NetworkSpec)(nil), (*NetworkSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_NetworkSpec_To_v1alpha4_NetworkSpec(a.(*v1beta1.NetworkSpec), b.(*NetworkSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.Topology)(nil), (*Topology)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_Topology_To_v1alpha4_Topology(a.(*v1beta1.Topology), b.(*Topology), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.VSphereClusterIdentitySpec)(nil), (*VSphereClusterIdentitySpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_VSphereClusterIdentitySpec_To_v1alpha4_VSphereClusterIdentitySpec(a.(*v1beta1.VSphereClusterIdentitySpec), b.(*VSphereClusterIdentitySpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.VSphereClusterSpec)(nil), (*VSphereClusterSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_VSphereClusterSpec_To_v1alpha4_VSphereClusterSpec(a.(*v1beta1.VSphereClusterSpec), b.(*VSphereClusterSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.VSphereClusterStatus)(nil), (*VSphereClusterStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_VSphereClusterStatus_To_v1alpha4_VSphereClusterStatus(a.(*v1beta1.VSphereClusterStatus), b.(*VSphereClusterStatus), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.VSphereMachineSpec)(nil), (*VSphereMachineSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_VSphereMachineSpec_To_v1alpha4_VSphereMachineSpec(a.(*v1beta1.VSphereMachineSpec), b.(*VSphereMachineSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.VSphereMachineStatus)(nil), (*VSphereMachineStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_VSphereMachineStatus_To_v1alpha4_VSphereMachineStatus(a.(*v1beta1.VSphereMachineStatus), b.(*VSphereMachineStatus), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.VSphereVMSpec)(nil), (*VSphereVMSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_VSphereVMSpec_To_v1alpha4_VSphereVMSpec(a.(*v1beta1.VSphereVMSpec), b.(*VSphereVMSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.VSphereVMStatus)(nil), (*VSphereVMStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_VSphereVMStatus_To_v1alpha4_VSphereVMStatus(a.(*v1beta1.VSphereVMStatus), b.(*VSphereVMStatus), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1beta1.VirtualMachine)(nil), (*VirtualMachine)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_VirtualMachine_To_v1alpha4_VirtualMachine(a.(*v1beta1.VirtualMachine), b.(*VirtualMachine), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddConversionFunc((*v1beta1.VirtualMachineCloneSpec)(nil), (*VirtualMachineCloneSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1beta1_VirtualMachineCloneSpec_To_v1alpha4_VirtualMachineCloneSpec(a.(*v1beta1.VirtualMachineCloneSpec), b.(*VirtualMachineCloneSpec), scope)
 	}); err != nil {
@@ -546,15 +546,11 @@ func Convert_v1alpha4_AllowedNamespaces_To_v1beta1_AllowedNamespaces(in *Allowed
 }
 
 func autoConvert_v1beta1_AllowedNamespaces_To_v1alpha4_AllowedNamespaces(in *v1beta1.AllowedNamespaces, out *AllowedNamespaces, s conversion.Scope) error {
+	// WARNING: in.NamespaceList requires manual conversion: does not exist in peer-type
 	out.Selector = in.Selector
 	return nil
 }
 
-// Convert_v1beta1_AllowedNamespaces_To_v1alpha4_AllowedNamespaces is an autogenerated conversion function.
-func Convert_v1beta1_AllowedNamespaces_To_v1alpha4_AllowedNamespaces(in *v1beta1.AllowedNamespaces, out *AllowedNamespaces, s conversion.Scope) error {
-	return autoConvert_v1beta1_AllowedNamespaces_To_v1alpha4_AllowedNamespaces(in, out, s)
-}
-
 func autoConvert_v1alpha4_FailureDomain_To_v1beta1_FailureDomain(in *FailureDomain, out *v1beta1.FailureDomain, s conversion.Scope) error {
 	out.Name = in.Name
 	out.Type = v1beta1.FailureDomainType(in.Type)
@@ -595,14 +591,11 @@ func Convert_v1alpha4_FailureDomainHosts_To_v1beta1_FailureDomainHosts(in *Failu
 func autoConvert_v1beta1_FailureDomainHosts_To_v1alpha4_FailureDomainHosts(in *v1beta1.FailureDomainHosts, out *FailureDomainHosts, s conversion.Scope) error {
 	out.VMGroupName = in.VMGroupName
 	out.HostGroupName = in.HostGroupName
+	// WARNING: in.AutoConfigure requires manual conversion: does not exist in peer-type
+	// WARNING: in.Mandatory requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_FailureDomainHosts_To_v1alpha4_FailureDomainHosts is an autogenerated conversion function.
-func Convert_v1beta1_FailureDomainHosts_To_v1alpha4_FailureDomainHosts(in *v1beta1.FailureDomainHosts, out *FailureDomainHosts, s conversion.Scope) error {
-	return autoConvert_v1beta1_FailureDomainHosts_To_v1alpha4_FailureDomainHosts(in, out, s)
-}
-
 func autoConvert_v1alpha4_Network_To_v1beta1_Network(in *Network, out *v1beta1.Network, s conversion.Scope) error {
 	out.Name = in.Name
 	out.DHCP4 = (*bool)(unsafe.Pointer(in.DHCP4))
@@ -650,12 +643,16 @@ func Convert_v1alpha4_NetworkDeviceSpec_To_v1beta1_NetworkDeviceSpec(in *Network
 
 func autoConvert_v1beta1_NetworkDeviceSpec_To_v1alpha4_NetworkDeviceSpec(in *v1beta1.NetworkDeviceSpec, out *NetworkDeviceSpec, s conversion.Scope) error {
 	out.NetworkName = in.NetworkName
+	// WARNING: in.SwitchName requires manual conversion: does not exist in peer-type
+	// WARNING: in.VLANID requires manual conversion: does not exist in peer-type
 	out.DeviceName = in.DeviceName
 	out.DHCP4 = in.DHCP4
 	out.DHCP6 = in.DHCP6
+	// WARNING: in.SLAAC requires manual conversion: does not exist in peer-type
 	out.Gateway4 = in.Gateway4
 	out.Gateway6 = in.Gateway6
 	out.IPAddrs = *(*[]string)(unsafe.Pointer(&in.IPAddrs))
+	// WARNING: in.IPPool requires manual conversion: does not exist in peer-type
 	out.MTU = (*int64)(unsafe.Pointer(in.MTU))
 	out.MACAddr = in.MACAddr
 	out.Nameservers = *(*[]string)(unsafe.Pointer(&in.Nameservers))
@@ -664,11 +661,6 @@ func autoConvert_v1beta1_NetworkDeviceSpec_To_v1alpha4_NetworkDeviceSpec(in *v1b
 	return nil
 }
 
-// Convert_v1beta1_NetworkDeviceSpec_To_v1alpha4_NetworkDeviceSpec is an autogenerated conversion function.
-func Convert_v1beta1_NetworkDeviceSpec_To_v1alpha4_NetworkDeviceSpec(in *v1beta1.NetworkDeviceSpec, out *NetworkDeviceSpec, s conversion.Scope) error {
-	return autoConvert_v1beta1_NetworkDeviceSpec_To_v1alpha4_NetworkDeviceSpec(in, out, s)
-}
-
 func autoConvert_v1alpha4_NetworkRouteSpec_To_v1beta1_NetworkRouteSpec(in *NetworkRouteSpec, out *v1beta1.NetworkRouteSpec, s conversion.Scope) error {
 	out.To = in.To
 	out.Via = in.Via
@@ -694,7 +686,17 @@ func Convert_v1beta1_NetworkRouteSpec_To_v1alpha4_NetworkRouteSpec(in *v1beta1.N
 }
 
 func autoConvert_v1alpha4_NetworkSpec_To_v1beta1_NetworkSpec(in *NetworkSpec, out *v1beta1.NetworkSpec, s conversion.Scope) error {
-	out.Devices = *(*[]v1beta1.NetworkDeviceSpec)(unsafe.Pointer(&in.Devices))
+	if in.Devices != nil {
+		in, out := &in.Devices, &out.Devices
+		*out = make([]v1beta1.NetworkDeviceSpec, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha4_NetworkDeviceSpec_To_v1beta1_NetworkDeviceSpec(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Devices = nil
+	}
 	out.Routes = *(*[]v1beta1.NetworkRouteSpec)(unsafe.Pointer(&in.Routes))
 	out.PreferredAPIServerCIDR = in.PreferredAPIServerCIDR
 	return nil
@@ -706,17 +708,25 @@ func Convert_v1alpha4_NetworkSpec_To_v1beta1_NetworkSpec(in *NetworkSpec, out *v
 }
 
 func autoConvert_v1beta1_NetworkSpec_To_v1alpha4_NetworkSpec(in *v1beta1.NetworkSpec, out *NetworkSpec, s conversion.Scope) error {
-	out.Devices = *(*[]NetworkDeviceSpec)(unsafe.Pointer(&in.Devices))
+	if in.Devices != nil {
+		in, out := &in.Devices, &out.Devices
+		*out = make([]NetworkDeviceSpec, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta1_NetworkDeviceSpec_To_v1alpha4_NetworkDeviceSpec(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Devices = nil
+	}
 	out.Routes = *(*[]NetworkRouteSpec)(unsafe.Pointer(&in.Routes))
 	out.PreferredAPIServerCIDR = in.PreferredAPIServerCIDR
+	// WARNING: in.PreferredNodeIPCIDR requires manual conversion: does not exist in peer-type
+	// WARNING: in.ExcludeNetworkCIDRs requires manual conversion: does not exist in peer-type
+	// WARNING: in.AddressWait requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_NetworkSpec_To_v1alpha4_NetworkSpec is an autogenerated conversion function.
-func Convert_v1beta1_NetworkSpec_To_v1alpha4_NetworkSpec(in *v1beta1.NetworkSpec, out *NetworkSpec, s conversion.Scope) error {
-	return autoConvert_v1beta1_NetworkSpec_To_v1alpha4_NetworkSpec(in, out, s)
-}
-
 func autoConvert_v1alpha4_NetworkStatus_To_v1beta1_NetworkStatus(in *NetworkStatus, out *v1beta1.NetworkStatus, s conversion.Scope) error {
 	out.Connected = in.Connected
 	out.IPAddrs = *(*[]string)(unsafe.Pointer(&in.IPAddrs))
@@ -790,7 +800,15 @@ func Convert_v1beta1_SSHUser_To_v1alpha4_SSHUser(in *v1beta1.SSHUser, out *SSHUs
 func autoConvert_v1alpha4_Topology_To_v1beta1_Topology(in *Topology, out *v1beta1.Topology, s conversion.Scope) error {
 	out.Datacenter = in.Datacenter
 	out.ComputeCluster = (*string)(unsafe.Pointer(in.ComputeCluster))
-	out.Hosts = (*v1beta1.FailureDomainHosts)(unsafe.Pointer(in.Hosts))
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = new(v1beta1.FailureDomainHosts)
+		if err := Convert_v1alpha4_FailureDomainHosts_To_v1beta1_FailureDomainHosts(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Hosts = nil
+	}
 	out.Networks = *(*[]string)(unsafe.Pointer(&in.Networks))
 	out.Datastore = in.Datastore
 	return nil
@@ -804,17 +822,22 @@ func Convert_v1alpha4_Topology_To_v1beta1_Topology(in *Topology, out *v1beta1.To
 func autoConvert_v1beta1_Topology_To_v1alpha4_Topology(in *v1beta1.Topology, out *Topology, s conversion.Scope) error {
 	out.Datacenter = in.Datacenter
 	out.ComputeCluster = (*string)(unsafe.Pointer(in.ComputeCluster))
-	out.Hosts = (*FailureDomainHosts)(unsafe.Pointer(in.Hosts))
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = new(FailureDomainHosts)
+		if err := Convert_v1beta1_FailureDomainHosts_To_v1alpha4_FailureDomainHosts(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Hosts = nil
+	}
 	out.Networks = *(*[]string)(unsafe.Pointer(&in.Networks))
 	out.Datastore = in.Datastore
+	// WARNING: in.DatastoreCluster requires manual conversion: does not exist in peer-type
+	// WARNING: in.VSANStretchedCluster requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_Topology_To_v1alpha4_Topology is an autogenerated conversion function.
-func Convert_v1beta1_Topology_To_v1alpha4_Topology(in *v1beta1.Topology, out *Topology, s conversion.Scope) error {
-	return autoConvert_v1beta1_Topology_To_v1alpha4_Topology(in, out, s)
-}
-
 func autoConvert_v1alpha4_VSphereCluster_To_v1beta1_VSphereCluster(in *VSphereCluster, out *v1beta1.VSphereCluster, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v1alpha4_VSphereClusterSpec_To_v1beta1_VSphereClusterSpec(&in.Spec, &out.Spec, s); err != nil {
@@ -881,7 +904,17 @@ func Convert_v1beta1_VSphereClusterIdentity_To_v1alpha4_VSphereClusterIdentity(i
 
 func autoConvert_v1alpha4_VSphereClusterIdentityList_To_v1beta1_VSphereClusterIdentityList(in *VSphereClusterIdentityList, out *v1beta1.VSphereClusterIdentityList, s conversion.Scope) error {
 	out.ListMeta = in.ListMeta
-	out.Items = *(*[]v1beta1.VSphereClusterIdentity)(unsafe.Pointer(&in.Items))
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]v1beta1.VSphereClusterIdentity, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha4_VSphereClusterIdentity_To_v1beta1_VSphereClusterIdentity(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Items = nil
+	}
 	return nil
 }
 
@@ -892,7 +925,17 @@ func Convert_v1alpha4_VSphereClusterIdentityList_To_v1beta1_VSphereClusterIdenti
 
 func autoConvert_v1beta1_VSphereClusterIdentityList_To_v1alpha4_VSphereClusterIdentityList(in *v1beta1.VSphereClusterIdentityList, out *VSphereClusterIdentityList, s conversion.Scope) error {
 	out.ListMeta = in.ListMeta
-	out.Items = *(*[]VSphereClusterIdentity)(unsafe.Pointer(&in.Items))
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VSphereClusterIdentity, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta1_VSphereClusterIdentity_To_v1alpha4_VSphereClusterIdentity(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Items = nil
+	}
 	return nil
 }
 
@@ -903,7 +946,15 @@ func Convert_v1beta1_VSphereClusterIdentityList_To_v1alpha4_VSphereClusterIdenti
 
 func autoConvert_v1alpha4_VSphereClusterIdentitySpec_To_v1beta1_VSphereClusterIdentitySpec(in *VSphereClusterIdentitySpec, out *v1beta1.VSphereClusterIdentitySpec, s conversion.Scope) error {
 	out.SecretName = in.SecretName
-	out.AllowedNamespaces = (*v1beta1.AllowedNamespaces)(unsafe.Pointer(in.AllowedNamespaces))
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = new(v1beta1.AllowedNamespaces)
+		if err := Convert_v1alpha4_AllowedNamespaces_To_v1beta1_AllowedNamespaces(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.AllowedNamespaces = nil
+	}
 	return nil
 }
 
@@ -914,15 +965,22 @@ func Convert_v1alpha4_VSphereClusterIdentitySpec_To_v1beta1_VSphereClusterIdenti
 
 func autoConvert_v1beta1_VSphereClusterIdentitySpec_To_v1alpha4_VSphereClusterIdentitySpec(in *v1beta1.VSphereClusterIdentitySpec, out *VSphereClusterIdentitySpec, s conversion.Scope) error {
 	out.SecretName = in.SecretName
-	out.AllowedNamespaces = (*AllowedNamespaces)(unsafe.Pointer(in.AllowedNamespaces))
+	// WARNING: in.Vault requires manual conversion: does not exist in peer-type
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = new(AllowedNamespaces)
+		if err := Convert_v1beta1_AllowedNamespaces_To_v1alpha4_AllowedNamespaces(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.AllowedNamespaces = nil
+	}
+	// WARNING: in.RateLimit requires manual conversion: does not exist in peer-type
+	// WARNING: in.Connection requires manual conversion: does not exist in peer-type
+	// WARNING: in.TLS requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_VSphereClusterIdentitySpec_To_v1alpha4_VSphereClusterIdentitySpec is an autogenerated conversion function.
-func Convert_v1beta1_VSphereClusterIdentitySpec_To_v1alpha4_VSphereClusterIdentitySpec(in *v1beta1.VSphereClusterIdentitySpec, out *VSphereClusterIdentitySpec, s conversion.Scope) error {
-	return autoConvert_v1beta1_VSphereClusterIdentitySpec_To_v1alpha4_VSphereClusterIdentitySpec(in, out, s)
-}
-
 func autoConvert_v1alpha4_VSphereClusterIdentityStatus_To_v1beta1_VSphereClusterIdentityStatus(in *VSphereClusterIdentityStatus, out *v1beta1.VSphereClusterIdentityStatus, s conversion.Scope) error {
 	out.Ready = in.Ready
 	out.Conditions = *(*apiv1beta1.Conditions)(unsafe.Pointer(&in.Conditions))
@@ -947,7 +1005,17 @@ func Convert_v1beta1_VSphereClusterIdentityStatus_To_v1alpha4_VSphereClusterIden
 
 func autoConvert_v1alpha4_VSphereClusterList_To_v1beta1_VSphereClusterList(in *VSphereClusterList, out *v1beta1.VSphereClusterList, s conversion.Scope) error {
 	out.ListMeta = in.ListMeta
-	out.Items = *(*[]v1beta1.VSphereCluster)(unsafe.Pointer(&in.Items))
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]v1beta1.VSphereCluster, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha4_VSphereCluster_To_v1beta1_VSphereCluster(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Items = nil
+	}
 	return nil
 }
 
@@ -958,7 +1026,17 @@ func Convert_v1alpha4_VSphereClusterList_To_v1beta1_VSphereClusterList(in *VSphe
 
 func autoConvert_v1beta1_VSphereClusterList_To_v1alpha4_VSphereClusterList(in *v1beta1.VSphereClusterList, out *VSphereClusterList, s conversion.Scope) error {
 	out.ListMeta = in.ListMeta
-	out.Items = *(*[]VSphereCluster)(unsafe.Pointer(&in.Items))
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VSphereCluster, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta1_VSphereCluster_To_v1alpha4_VSphereCluster(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Items = nil
+	}
 	return nil
 }
 
@@ -988,15 +1066,17 @@ func autoConvert_v1beta1_VSphereClusterSpec_To_v1alpha4_VSphereClusterSpec(in *v
 	if err := Convert_v1beta1_APIEndpoint_To_v1alpha4_APIEndpoint(&in.ControlPlaneEndpoint, &out.ControlPlaneEndpoint, s); err != nil {
 		return err
 	}
+	// WARNING: in.ControlPlaneEndpointDNS requires manual conversion: does not exist in peer-type
 	out.IdentityRef = (*VSphereIdentityReference)(unsafe.Pointer(in.IdentityRef))
+	// WARNING: in.CloudProvider requires manual conversion: does not exist in peer-type
+	// WARNING: in.CSI requires manual conversion: does not exist in peer-type
+	// WARNING: in.NSXT requires manual conversion: does not exist in peer-type
+	// WARNING: in.Connection requires manual conversion: does not exist in peer-type
+	// WARNING: in.ResourceQuota requires manual conversion: does not exist in peer-type
+	// WARNING: in.VCenters requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_VSphereClusterSpec_To_v1alpha4_VSphereClusterSpec is an autogenerated conversion function.
-func Convert_v1beta1_VSphereClusterSpec_To_v1alpha4_VSphereClusterSpec(in *v1beta1.VSphereClusterSpec, out *VSphereClusterSpec, s conversion.Scope) error {
-	return autoConvert_v1beta1_VSphereClusterSpec_To_v1alpha4_VSphereClusterSpec(in, out, s)
-}
-
 func autoConvert_v1alpha4_VSphereClusterStatus_To_v1beta1_VSphereClusterStatus(in *VSphereClusterStatus, out *v1beta1.VSphereClusterStatus, s conversion.Scope) error {
 	out.Ready = in.Ready
 	out.Conditions = *(*apiv1beta1.Conditions)(unsafe.Pointer(&in.Conditions))
@@ -1013,14 +1093,15 @@ func autoConvert_v1beta1_VSphereClusterStatus_To_v1alpha4_VSphereClusterStatus(i
 	out.Ready = in.Ready
 	out.Conditions = *(*apiv1alpha4.Conditions)(unsafe.Pointer(&in.Conditions))
 	out.FailureDomains = *(*apiv1alpha4.FailureDomains)(unsafe.Pointer(&in.FailureDomains))
+	// WARNING: in.VCenterVersion requires manual conversion: does not exist in peer-type
+	// WARNING: in.VCenterBuild requires manual conversion: does not exist in peer-type
+	// WARNING: in.OrphanedVMs requires manual conversion: does not exist in peer-type
+	// WARNING: in.LastOrphanedVMSweepTime requires manual conversion: does not exist in peer-type
+	// WARNING: in.ControlPlaneEndpointDNSRecord requires manual conversion: does not exist in peer-type
+	// WARNING: in.Deletion requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_VSphereClusterStatus_To_v1alpha4_VSphereClusterStatus is an autogenerated conversion function.
-func Convert_v1beta1_VSphereClusterStatus_To_v1alpha4_VSphereClusterStatus(in *v1beta1.VSphereClusterStatus, out *VSphereClusterStatus, s conversion.Scope) error {
-	return autoConvert_v1beta1_VSphereClusterStatus_To_v1alpha4_VSphereClusterStatus(in, out, s)
-}
-
 func autoConvert_v1alpha4_VSphereClusterTemplate_To_v1beta1_VSphereClusterTemplate(in *VSphereClusterTemplate, out *v1beta1.VSphereClusterTemplate, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v1alpha4_VSphereClusterTemplateSpec_To_v1beta1_VSphereClusterTemplateSpec(&in.Spec, &out.Spec, s); err != nil {
@@ -1049,7 +1130,17 @@ func Convert_v1beta1_VSphereClusterTemplate_To_v1alpha4_VSphereClusterTemplate(i
 
 func autoConvert_v1alpha4_VSphereClusterTemplateList_To_v1beta1_VSphereClusterTemplateList(in *VSphereClusterTemplateList, out *v1beta1.VSphereClusterTemplateList, s conversion.Scope) error {
 	out.ListMeta = in.ListMeta
-	out.Items = *(*[]v1beta1.VSphereClusterTemplate)(unsafe.Pointer(&in.Items))
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]v1beta1.VSphereClusterTemplate, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha4_VSphereClusterTemplate_To_v1beta1_VSphereClusterTemplate(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Items = nil
+	}
 	return nil
 }
 
@@ -1060,7 +1151,17 @@ func Convert_v1alpha4_VSphereClusterTemplateList_To_v1beta1_VSphereClusterTempla
 
 func autoConvert_v1beta1_VSphereClusterTemplateList_To_v1alpha4_VSphereClusterTemplateList(in *v1beta1.VSphereClusterTemplateList, out *VSphereClusterTemplateList, s conversion.Scope) error {
 	out.ListMeta = in.ListMeta
-	out.Items = *(*[]VSphereClusterTemplate)(unsafe.Pointer(&in.Items))
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VSphereClusterTemplate, len(*in))
+		for i := range *in {
+			if err := Convert_v1beta1_VSphereClusterTemplate_To_v1alpha4_VSphereClusterTemplate(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Items = nil
+	}
 	return nil
 }
 
@@ -1423,14 +1524,20 @@ func autoConvert_v1beta1_VSphereMachineSpec_To_v1alpha4_VSphereMachineSpec(in *v
 	}
 	out.ProviderID = (*string)(unsafe.Pointer(in.ProviderID))
 	out.FailureDomain = (*string)(unsafe.Pointer(in.FailureDomain))
+	// WARNING: in.PowerOffMode requires manual conversion: does not exist in peer-type
+	// WARNING: in.GuestSoftPowerOffTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.DeletionPolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.DriftPolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.PowerRecoveryPolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.SnapshotSchedule requires manual conversion: does not exist in peer-type
+	// WARNING: in.FailureRetryPolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.Placement requires manual conversion: does not exist in peer-type
+	// WARNING: in.Image requires manual conversion: does not exist in peer-type
+	// WARNING: in.ImageMapping requires manual conversion: does not exist in peer-type
+	// WARNING: in.DeploymentZones requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_VSphereMachineSpec_To_v1alpha4_VSphereMachineSpec is an autogenerated conversion function.
-func Convert_v1beta1_VSphereMachineSpec_To_v1alpha4_VSphereMachineSpec(in *v1beta1.VSphereMachineSpec, out *VSphereMachineSpec, s conversion.Scope) error {
-	return autoConvert_v1beta1_VSphereMachineSpec_To_v1alpha4_VSphereMachineSpec(in, out, s)
-}
-
 func autoConvert_v1alpha4_VSphereMachineStatus_To_v1beta1_VSphereMachineStatus(in *VSphereMachineStatus, out *v1beta1.VSphereMachineStatus, s conversion.Scope) error {
 	out.Ready = in.Ready
 	out.Addresses = *(*[]apiv1beta1.MachineAddress)(unsafe.Pointer(&in.Addresses))
@@ -1453,14 +1560,10 @@ func autoConvert_v1beta1_VSphereMachineStatus_To_v1alpha4_VSphereMachineStatus(i
 	out.FailureReason = (*errors.MachineStatusError)(unsafe.Pointer(in.FailureReason))
 	out.FailureMessage = (*string)(unsafe.Pointer(in.FailureMessage))
 	out.Conditions = *(*apiv1alpha4.Conditions)(unsafe.Pointer(&in.Conditions))
+	// WARNING: in.NodeTopology requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_VSphereMachineStatus_To_v1alpha4_VSphereMachineStatus is an autogenerated conversion function.
-func Convert_v1beta1_VSphereMachineStatus_To_v1alpha4_VSphereMachineStatus(in *v1beta1.VSphereMachineStatus, out *VSphereMachineStatus, s conversion.Scope) error {
-	return autoConvert_v1beta1_VSphereMachineStatus_To_v1alpha4_VSphereMachineStatus(in, out, s)
-}
-
 func autoConvert_v1alpha4_VSphereMachineTemplate_To_v1beta1_VSphereMachineTemplate(in *VSphereMachineTemplate, out *v1beta1.VSphereMachineTemplate, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v1alpha4_VSphereMachineTemplateSpec_To_v1beta1_VSphereMachineTemplateSpec(&in.Spec, &out.Spec, s); err != nil {
@@ -1479,6 +1582,7 @@ func autoConvert_v1beta1_VSphereMachineTemplate_To_v1alpha4_VSphereMachineTempla
 	if err := Convert_v1beta1_VSphereMachineTemplateSpec_To_v1alpha4_VSphereMachineTemplateSpec(&in.Spec, &out.Spec, s); err != nil {
 		return err
 	}
+	// WARNING: in.Status requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -1677,14 +1781,18 @@ func autoConvert_v1beta1_VSphereVMSpec_To_v1alpha4_VSphereVMSpec(in *v1beta1.VSp
 	}
 	out.BootstrapRef = (*v1.ObjectReference)(unsafe.Pointer(in.BootstrapRef))
 	out.BiosUUID = in.BiosUUID
+	// WARNING: in.InstanceUUID requires manual conversion: does not exist in peer-type
+	// WARNING: in.PowerOffMode requires manual conversion: does not exist in peer-type
+	// WARNING: in.GuestSoftPowerOffTimeout requires manual conversion: does not exist in peer-type
+	// WARNING: in.DeletionPolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.DriftPolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.PowerRecoveryPolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.SnapshotSchedule requires manual conversion: does not exist in peer-type
+	// WARNING: in.FailureRetryPolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.Placement requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_VSphereVMSpec_To_v1alpha4_VSphereVMSpec is an autogenerated conversion function.
-func Convert_v1beta1_VSphereVMSpec_To_v1alpha4_VSphereVMSpec(in *v1beta1.VSphereVMSpec, out *VSphereVMSpec, s conversion.Scope) error {
-	return autoConvert_v1beta1_VSphereVMSpec_To_v1alpha4_VSphereVMSpec(in, out, s)
-}
-
 func autoConvert_v1alpha4_VSphereVMStatus_To_v1beta1_VSphereVMStatus(in *VSphereVMStatus, out *v1beta1.VSphereVMStatus, s conversion.Scope) error {
 	out.Ready = in.Ready
 	out.Addresses = *(*[]string)(unsafe.Pointer(&in.Addresses))
@@ -1707,22 +1815,31 @@ func Convert_v1alpha4_VSphereVMStatus_To_v1beta1_VSphereVMStatus(in *VSphereVMSt
 func autoConvert_v1beta1_VSphereVMStatus_To_v1alpha4_VSphereVMStatus(in *v1beta1.VSphereVMStatus, out *VSphereVMStatus, s conversion.Scope) error {
 	out.Ready = in.Ready
 	out.Addresses = *(*[]string)(unsafe.Pointer(&in.Addresses))
+	// WARNING: in.MachineAddresses requires manual conversion: does not exist in peer-type
+	// WARNING: in.AddressWaitStartTime requires manual conversion: does not exist in peer-type
+	// WARNING: in.IPAllocations requires manual conversion: does not exist in peer-type
 	out.CloneMode = CloneMode(in.CloneMode)
 	out.Snapshot = in.Snapshot
+	// WARNING: in.DiskProvisioningType requires manual conversion: does not exist in peer-type
+	// WARNING: in.ResourcePool requires manual conversion: does not exist in peer-type
+	// WARNING: in.Host requires manual conversion: does not exist in peer-type
+	// WARNING: in.ComputeCluster requires manual conversion: does not exist in peer-type
+	// WARNING: in.CurrentResourcePool requires manual conversion: does not exist in peer-type
+	// WARNING: in.Datastore requires manual conversion: does not exist in peer-type
+	// WARNING: in.SelectedDatastore requires manual conversion: does not exist in peer-type
+	// WARNING: in.Migrations requires manual conversion: does not exist in peer-type
+	// WARNING: in.Drift requires manual conversion: does not exist in peer-type
+	// WARNING: in.GuestTools requires manual conversion: does not exist in peer-type
 	out.RetryAfter = in.RetryAfter
 	out.TaskRef = in.TaskRef
 	out.Network = *(*[]NetworkStatus)(unsafe.Pointer(&in.Network))
 	out.FailureReason = (*errors.MachineStatusError)(unsafe.Pointer(in.FailureReason))
 	out.FailureMessage = (*string)(unsafe.Pointer(in.FailureMessage))
+	// WARNING: in.FailureRetries requires manual conversion: does not exist in peer-type
 	out.Conditions = *(*apiv1alpha4.Conditions)(unsafe.Pointer(&in.Conditions))
 	return nil
 }
 
-// Convert_v1beta1_VSphereVMStatus_To_v1alpha4_VSphereVMStatus is an autogenerated conversion function.
-func Convert_v1beta1_VSphereVMStatus_To_v1alpha4_VSphereVMStatus(in *v1beta1.VSphereVMStatus, out *VSphereVMStatus, s conversion.Scope) error {
-	return autoConvert_v1beta1_VSphereVMStatus_To_v1alpha4_VSphereVMStatus(in, out, s)
-}
-
 func autoConvert_v1alpha4_VirtualMachine_To_v1beta1_VirtualMachine(in *VirtualMachine, out *v1beta1.VirtualMachine, s conversion.Scope) error {
 	out.Name = in.Name
 	out.BiosUUID = in.BiosUUID
@@ -1741,14 +1858,11 @@ func autoConvert_v1beta1_VirtualMachine_To_v1alpha4_VirtualMachine(in *v1beta1.V
 	out.BiosUUID = in.BiosUUID
 	out.State = VirtualMachineState(in.State)
 	out.Network = *(*[]NetworkStatus)(unsafe.Pointer(&in.Network))
+	// WARNING: in.Hostname requires manual conversion: does not exist in peer-type
+	// WARNING: in.DNSNames requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1beta1_VirtualMachine_To_v1alpha4_VirtualMachine is an autogenerated conversion function.
-func Convert_v1beta1_VirtualMachine_To_v1alpha4_VirtualMachine(in *v1beta1.VirtualMachine, out *VirtualMachine, s conversion.Scope) error {
-	return autoConvert_v1beta1_VirtualMachine_To_v1alpha4_VirtualMachine(in, out, s)
-}
-
 func autoConvert_v1alpha4_VirtualMachineCloneSpec_To_v1beta1_VirtualMachineCloneSpec(in *VirtualMachineCloneSpec, out *v1beta1.VirtualMachineCloneSpec, s conversion.Scope) error {
 	out.Template = in.Template
 	out.CloneMode = v1beta1.CloneMode(in.CloneMode)
@@ -1785,19 +1899,36 @@ func autoConvert_v1beta1_VirtualMachineCloneSpec_To_v1alpha4_VirtualMachineClone
 	out.Datacenter = in.Datacenter
 	out.Folder = in.Folder
 	out.Datastore = in.Datastore
+	// WARNING: in.Datastores requires manual conversion: does not exist in peer-type
 	out.StoragePolicyName = in.StoragePolicyName
 	out.ResourcePool = in.ResourcePool
+	// WARNING: in.CreateTargetHierarchy requires manual conversion: does not exist in peer-type
+	// WARNING: in.ResourcePoolLimits requires manual conversion: does not exist in peer-type
 	if err := Convert_v1beta1_NetworkSpec_To_v1alpha4_NetworkSpec(&in.Network, &out.Network, s); err != nil {
 		return err
 	}
+	// WARNING: in.ResourceAllocation requires manual conversion: does not exist in peer-type
 	out.NumCPUs = in.NumCPUs
 	out.NumCoresPerSocket = in.NumCoresPerSocket
 	out.MemoryMiB = in.MemoryMiB
+	// WARNING: in.HardwareVersion requires manual conversion: does not exist in peer-type
+	// WARNING: in.EnableHotAdd requires manual conversion: does not exist in peer-type
+	// WARNING: in.LatencySensitivity requires manual conversion: does not exist in peer-type
+	// WARNING: in.NumaNodeAffinity requires manual conversion: does not exist in peer-type
+	// WARNING: in.CPUPinning requires manual conversion: does not exist in peer-type
 	out.DiskGiB = in.DiskGiB
 	// WARNING: in.AdditionalDisksGiB requires manual conversion: does not exist in peer-type
+	// WARNING: in.DiskProvisioningType requires manual conversion: does not exist in peer-type
 	out.CustomVMXKeys = *(*map[string]string)(unsafe.Pointer(&in.CustomVMXKeys))
 	// WARNING: in.TagIDs requires manual conversion: does not exist in peer-type
+	// WARNING: in.MetadataPropagation requires manual conversion: does not exist in peer-type
 	// WARNING: in.PciDevices requires manual conversion: does not exist in peer-type
+	// WARNING: in.Disks requires manual conversion: does not exist in peer-type
 	// WARNING: in.OS requires manual conversion: does not exist in peer-type
+	// WARNING: in.Firmware requires manual conversion: does not exist in peer-type
+	// WARNING: in.SecureBoot requires manual conversion: does not exist in peer-type
+	// WARNING: in.VTPM requires manual conversion: does not exist in peer-type
+	// WARNING: in.BootstrapDataTransport requires manual conversion: does not exist in peer-type
+	// WARNING: in.GuestTools requires manual conversion: does not exist in peer-type
 	return nil
 }
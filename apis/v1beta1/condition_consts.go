@@ -57,18 +57,64 @@ const (
 	// NOTE: This reason does not apply to VSphereVM (this state happens before the VSphereVM is actually created).
 	WaitingForBootstrapDataReason = "WaitingForBootstrapData"
 
+	// QuotaExceededReason (Severity=Warning) documents a VSphereMachine whose VSphereVM is not created,
+	// or a VSphereMachinePool not scaling up, as the VMs would exceed the ResourceQuota of the
+	// VSphereCluster; the VMs are created once enough resources are released.
+	//
+	// NOTE: This reason does not apply to VSphereVM (this state happens before the VSphereVM is actually created).
+	QuotaExceededReason = "QuotaExceeded"
+
 	// WaitingForStaticIPAllocationReason (Severity=Info) documents a VSphereVM waiting for the allocation of
 	// a static IP address.
 	WaitingForStaticIPAllocationReason = "WaitingForStaticIPAllocation"
 
+	// IPAllocationFailedReason (Severity=Warning) documents a VSphereVM whose network device addresses could not
+	// be allocated from their VSphereIPPool; the allocation is retried.
+	IPAllocationFailedReason = "IPAllocationFailed"
+
 	// CloningReason documents (Severity=Info) a VSphereMachine/VSphereVM currently executing the clone operation.
 	CloningReason = "Cloning"
 
+	// CloneQueuedReason documents (Severity=Info) a VSphereMachine/VSphereVM waiting for in-flight clones
+	// of the same template to complete before its own clone operation is started.
+	CloneQueuedReason = "CloneQueued"
+
+	// CreatingLinkedCloneSnapshotReason documents (Severity=Info) a VSphereMachine/VSphereVM waiting for
+	// the snapshot of its template it is linked cloned from to be created before its clone operation is started.
+	CreatingLinkedCloneSnapshotReason = "CreatingLinkedCloneSnapshot"
+
+	// WaitingForInstantCloneParentReason documents (Severity=Info) a VSphereMachine/VSphereVM waiting for
+	// the parent VM it is instant cloned from to be created, powered on and frozen before its clone
+	// operation is started.
+	WaitingForInstantCloneParentReason = "WaitingForInstantCloneParent"
+
+	// CopyingTemplateDisksReason documents (Severity=Info) a VSphereMachine/VSphereVM on a standalone ESXi
+	// host waiting for the disks of its template to be copied before its VM is created.
+	CopyingTemplateDisksReason = "CopyingTemplateDisks"
+
+	// CloneTimedOutReason (Severity=Warning) documents a VSphereMachine/VSphereVM whose clone operation has
+	// not completed within the clone timeout of the controller; the controller keeps tracking the clone task,
+	// but a user intervention might be required, e.g. on an overloaded datastore.
+	CloneTimedOutReason = "CloneTimedOut"
+
+	// TemplatePreflightFailedReason (Severity=Error) documents a VSphereMachine/VSphereVM whose
+	// template fails the checks run before the first clone, e.g. a template without VMware Tools
+	// or with a guest OS not matching the one of the VSphereVM.
+	TemplatePreflightFailedReason = "TemplatePreflightFailed"
+
+	// UnsupportedByVCenterReason (Severity=Error) documents a VSphereMachine/VSphereVM requesting
+	// features, e.g. a vTPM, which are not provided by the version of its vCenter.
+	UnsupportedByVCenterReason = "UnsupportedByVCenter"
+
 	// CloningFailedReason (Severity=Warning) documents a VSphereMachine/VSphereVM controller detecting
 	// an error while provisioning; those kind of errors are usually transient and failed provisioning
 	// are automatically re-tried by the controller.
 	CloningFailedReason = "CloningFailed"
 
+	// VMNotFoundReason (Severity=Error) documents a VSphereMachine/VSphereVM without a template whose
+	// pre-existing virtual machine to adopt cannot be found.
+	VMNotFoundReason = "VMNotFound"
+
 	// PoweringOnReason documents (Severity=Info) a VSphereMachine/VSphereVM currently executing the power on sequence.
 	PoweringOnReason = "PoweringOn"
 
@@ -77,18 +123,328 @@ const (
 	// are automatically re-tried by the controller.
 	PoweringOnFailedReason = "PoweringOnFailed"
 
+	// PoweredOffReason (Severity=Warning) documents a VSphereMachine/VSphereVM whose virtual machine is
+	// found powered off, or suspended, after it was powered on, e.g. as its host crashed or an
+	// administrator powered it off.
+	PoweredOffReason = "PoweredOff"
+
 	// TaskFailure (Severity=Warning) documents a VSphereMachine/VSphere task failure; the reconcile look will automatically
 	// retry the operation, but a user intervention might be required to fix the problem.
 	TaskFailure = "TaskFailure"
 
+	// InsufficientResourcesReason (Severity=Warning) documents a VSphereMachine/VSphereVM whose provisioning
+	// failed because the infrastructure lacks the compute resources, the storage or the licenses it requires;
+	// the controller retries it with a longer backoff, until the resources are freed or added.
+	InsufficientResourcesReason = "InsufficientResources"
+
+	// InsufficientCapacityReason (Severity=Warning) documents a VSphereMachine/VSphereVM whose clone is not
+	// started because none of the hosts of its compute resource, or one of its datastores, has the free
+	// capacity for it with the headroom of the controller; the check is retried until capacity is freed.
+	InsufficientCapacityReason = "InsufficientCapacity"
+
 	// WaitingForNetworkAddressesReason (Severity=Info) documents a VSphereMachine waiting for the the machine network
 	// settings to be reported after machine being powered on.
 	//
-	// NOTE: This reason does not apply to VSphereVM (this state happens after the VSphereVM is in ready state).
+	// NOTE: This reason only applies to the IPAssignedCondition of VSphereVM (the VMProvisionedCondition of
+	// VSphereVM is true once it is in ready state).
 	WaitingForNetworkAddressesReason = "WaitingForNetworkAddresses"
 
+	// NetworkAddressesTimedOutReason (Severity=Warning) documents a VSphereVM which waited for the addresses of
+	// its DHCP network devices for longer than the timeout of the AddressWait policy of its network, and keeps
+	// waiting for them; the Error severity is used when the policy fails the VSphereVM instead.
+	//
+	// NOTE: This reason only applies to the IPAssignedCondition of VSphereVM.
+	NetworkAddressesTimedOutReason = "NetworkAddressesTimedOut"
+
 	// TagsAttachmentFailedReason (Severity=Error) documents a VSPhereMachine/VSphereVM tags attachment failure.
 	TagsAttachmentFailedReason = "TagsAttachmentFailed"
+
+	// MetadataPropagationFailedReason (Severity=Warning) documents a VSphereMachine/VSphereVM whose labels
+	// or annotations failed to be propagated to the vSphere tags or custom attributes of its VM.
+	MetadataPropagationFailedReason = "MetadataPropagationFailed"
+)
+
+// Conditions and Reasons related to the phases of the provisioning of a VSphereVM, which break the
+// VMProvisionedCondition down. They are reported on the VSphereMachine as well, and an Event is emitted
+// for the VSphereVM whenever one of them becomes true or fails.
+const (
+	// CloneStartedCondition documents whether the clone operation of the virtual machine of a VSphereVM
+	// has been started.
+	//
+	// NOTE: This condition does not apply to VSphereVMs adopting a pre-existing virtual machine.
+	CloneStartedCondition clusterv1.ConditionType = "CloneStarted"
+
+	// CloneCompletedCondition documents whether the clone operation of the virtual machine of a VSphereVM
+	// has completed. It is false with the CloningReason while the clone task runs, with the
+	// CloneTimedOutReason once it runs longer than the clone timeout, and with the CloningFailedReason
+	// when it fails.
+	//
+	// NOTE: This condition does not apply to VSphereVMs adopting a pre-existing virtual machine.
+	CloneCompletedCondition clusterv1.ConditionType = "CloneCompleted"
+
+	// CustomizationAppliedCondition documents whether the guest customization of the virtual machine of a
+	// VSphereVM, e.g. Sysprep for Windows, has been applied on first boot.
+	//
+	// NOTE: This condition only applies to virtual machines cloned with a customization spec, on vCenters
+	// reporting the status of the guest customization.
+	CustomizationAppliedCondition clusterv1.ConditionType = "CustomizationApplied"
+
+	// CustomizationPendingReason (Severity=Info) documents a VSphereVM whose virtual machine has not
+	// completed its guest customization yet.
+	CustomizationPendingReason = "CustomizationPending"
+
+	// CustomizationFailedReason (Severity=Error) documents a VSphereVM whose virtual machine failed its
+	// guest customization.
+	CustomizationFailedReason = "CustomizationFailed"
+
+	// PoweredOnCondition documents whether the virtual machine of a VSphereVM is powered on. It is false
+	// with the PoweringOnReason while it is being powered on, with the PoweringOnFailedReason when it
+	// fails to, and with the PoweredOffReason when it is found powered off after it was powered on.
+	PoweredOnCondition clusterv1.ConditionType = "PoweredOn"
+
+	// IPAssignedCondition documents whether the virtual machine of a VSphereVM reports the IP addresses
+	// it is expected to, of every IP family of a dual-stack network. It is false with the
+	// WaitingForStaticIPAllocationReason or the WaitingForNetworkAddressesReason otherwise.
+	IPAssignedCondition clusterv1.ConditionType = "IPAssigned"
+
+	// BootstrapDataDeliveredCondition documents whether the bootstrap data and the metadata of a
+	// VSphereVM have been delivered to its virtual machine, either with the clone operation or
+	// afterwards, e.g. Ignition configs or bootstrap data delivered through an ISO.
+	BootstrapDataDeliveredCondition clusterv1.ConditionType = "BootstrapDataDelivered"
+
+	// DeliveringBootstrapDataReason (Severity=Info) documents a VSphereVM whose bootstrap data or
+	// metadata is being set on its virtual machine.
+	DeliveringBootstrapDataReason = "DeliveringBootstrapData"
+
+	// BootstrapDataDeliveryFailedReason (Severity=Warning) documents a VSphereVM whose bootstrap data
+	// or metadata cannot be set on its virtual machine; the delivery is retried by the controller.
+	BootstrapDataDeliveryFailedReason = "BootstrapDataDeliveryFailed"
+)
+
+const (
+	// GuestSoftPowerOffSucceededCondition documents the status of performing guest initiated
+	// graceful shutdown of a VSphereVM before it is deleted.
+	GuestSoftPowerOffSucceededCondition clusterv1.ConditionType = "GuestSoftPowerOffSucceeded"
+
+	// GuestSoftPowerOffInProgressReason (Severity=Info) documents a VSphereVM waiting for the
+	// guest OS to shut down.
+	GuestSoftPowerOffInProgressReason = "GuestSoftPowerOffInProgress"
+
+	// GuestSoftPowerOffFailedReason (Severity=Warning) documents a VSphereVM whose guest OS could
+	// not be shut down gracefully, either because VMware Tools are not running or because the
+	// shutdown did not complete within the timeout.
+	GuestSoftPowerOffFailedReason = "GuestSoftPowerOffFailed"
+)
+
+const (
+	// PCIDevicesAttachedCondition documents the status of the PCI passthrough and vGPU
+	// devices requested for a VSphereVM.
+	PCIDevicesAttachedCondition clusterv1.ConditionType = "PCIDevicesAttached"
+
+	// PCIDevicesAttachFailedReason (Severity=Warning) documents a VSphereVM controller detecting
+	// an error while attaching the requested PCI devices; those kind of errors are usually caused
+	// by hosts without matching devices available and failed clones are automatically re-tried
+	// by the controller.
+	PCIDevicesAttachFailedReason = "PCIDevicesAttachFailed"
+
+	// PCIDevicesMissingReason (Severity=Warning) documents a VSphereVM whose virtual machine does
+	// not have all the requested PCI devices attached.
+	PCIDevicesMissingReason = "PCIDevicesMissing"
+)
+
+const (
+	// StoragePolicyAvailableCondition documents the availability of the storage policies
+	// requested for a VSphereVM and its data disks.
+	StoragePolicyAvailableCondition clusterv1.ConditionType = "StoragePolicyAvailable"
+
+	// StoragePolicyNotFoundReason (Severity=Error) documents a VSphereVM requesting storage
+	// policies which do not exist in vCenter.
+	StoragePolicyNotFoundReason = "StoragePolicyNotFound"
+)
+
+const (
+	// TaskProgressCondition documents the progress of the vCenter task a VSphereVM is waiting on,
+	// e.g. cloning or reconfiguring its virtual machine.
+	TaskProgressCondition clusterv1.ConditionType = "TaskProgress"
+
+	// TaskInProgressReason (Severity=Info) documents a VSphereVM waiting on a queued or running
+	// vCenter task; the message reports the progress of the task.
+	TaskInProgressReason = "TaskInProgress"
+
+	// TaskFailedReason (Severity=Warning) documents a VSphereVM whose last vCenter task failed;
+	// the message reports the error returned by vCenter.
+	TaskFailedReason = "TaskFailed"
+)
+
+const (
+	// UnhealthyHostCondition documents a VSphereVM or VSphereMachine whose virtual machine runs on an
+	// ESXi host that is in maintenance mode, disconnected, or reports hardware faults. The condition is
+	// only present, with Status=True, while the host is unhealthy.
+	UnhealthyHostCondition clusterv1.ConditionType = "UnhealthyHost"
+
+	// HostInMaintenanceModeReason documents a host that is entering or in maintenance mode.
+	HostInMaintenanceModeReason = "HostInMaintenanceMode"
+
+	// HostDisconnectedReason documents a host that is disconnected from vCenter or not responding.
+	HostDisconnectedReason = "HostDisconnected"
+
+	// HostHardwareFaultReason documents a host whose hardware sensors report a fault, or that is
+	// quarantined by Proactive HA because of a predicted hardware failure.
+	HostHardwareFaultReason = "HostHardwareFault"
+)
+
+const (
+	// VMResizedCondition documents whether the number of CPUs and the memory size of the virtual
+	// machine of a VSphereVM or VSphereMachine match its spec after they are changed.
+	VMResizedCondition clusterv1.ConditionType = "VMResized"
+
+	// VMResizeRequiresReplacementReason (Severity=Warning) documents a VSphereVM whose virtual
+	// machine cannot be resized in place, because it is running without hot-add enabled or its
+	// resources are decreased; a rolling replacement of the machine is required.
+	VMResizeRequiresReplacementReason = "VMResizeRequiresReplacement"
+)
+
+const (
+	// DiskResizedCondition documents whether the size of the OS disk of the virtual machine of a
+	// VSphereVM or VSphereMachine matches its spec after it is changed.
+	DiskResizedCondition clusterv1.ConditionType = "DiskResized"
+
+	// DiskResizingReason (Severity=Info) documents a VSphereVM whose OS disk is being extended.
+	DiskResizingReason = "DiskResizing"
+
+	// DiskShrinkNotSupportedReason (Severity=Warning) documents a VSphereVM whose OS disk is
+	// larger than its spec; disks cannot be shrunk, so a rolling replacement of the machine is
+	// required.
+	DiskShrinkNotSupportedReason = "DiskShrinkNotSupported"
+)
+
+const (
+	// VMSpecSyncedCondition documents whether the configuration of the virtual machine of a
+	// VSphereVM or VSphereMachine matches its spec; the differences are listed in the drift
+	// field of the VSphereVM status.
+	VMSpecSyncedCondition clusterv1.ConditionType = "SpecSynced"
+
+	// VMSpecDriftedReason (Severity=Warning) documents a VSphereVM whose virtual machine was
+	// changed outside of Cluster API, e.g. through the vSphere Client, and no longer matches
+	// its spec.
+	VMSpecDriftedReason = "VMSpecDrifted"
+
+	// VMSpecRevertingReason (Severity=Info) documents a VSphereVM with the Revert drift policy
+	// whose virtual machine is being reconfigured back to its spec.
+	VMSpecRevertingReason = "VMSpecReverting"
+
+	// VMSpecDriftRequiresReplacementReason (Severity=Warning) documents a VSphereVM with the Revert
+	// drift policy whose virtual machine drifted from its spec in a way that cannot be reverted in
+	// place; the condition is also set on the node of the machine, so a MachineHealthCheck can
+	// remediate it.
+	VMSpecDriftRequiresReplacementReason = "VMSpecDriftRequiresReplacement"
+)
+
+// Conditions and Reasons related to the VMware Tools of the VM of a VSphereVM.
+const (
+	// GuestToolsRunningCondition documents whether the VMware Tools of the virtual machine of a
+	// VSphereVM are running; their status is reported in the guestTools field of the VSphereVM
+	// status.
+	GuestToolsRunningCondition clusterv1.ConditionType = "GuestToolsRunning"
+
+	// GuestToolsNotRunningReason (Severity=Info) documents a VSphereVM whose virtual machine does
+	// not run its VMware Tools, e.g. while its guest OS boots.
+	GuestToolsNotRunningReason = "GuestToolsNotRunning"
+
+	// GuestToolsNotInstalledReason (Severity=Warning) documents a VSphereVM whose virtual machine
+	// does not have VMware Tools installed, so it does not report its IP addresses.
+	GuestToolsNotInstalledReason = "GuestToolsNotInstalled"
+
+	// GuestToolsOutdatedReason documents a VSphereVM whose virtual machine runs a version of the
+	// VMware Tools older than the one available on its host.
+	GuestToolsOutdatedReason = "GuestToolsOutdated"
+
+	// WaitingForGuestToolsReason (Severity=Info) documents a VSphereVM requiring VMware Tools to be
+	// running which waits for them before it is ready.
+	WaitingForGuestToolsReason = "WaitingForGuestTools"
+)
+
+// Conditions and Reasons related to the snapshot of the VM of a VSphereVMSnapshot.
+const (
+	// SnapshotReadyCondition documents whether the snapshot of the virtual machine of a
+	// VSphereVMSnapshot has been created.
+	SnapshotReadyCondition clusterv1.ConditionType = "SnapshotReady"
+
+	// SnapshotWaitingForVMReason (Severity=Info) documents a VSphereVMSnapshot waiting for the
+	// virtual machine of its VSphereVM to be created.
+	SnapshotWaitingForVMReason = "WaitingForVM"
+
+	// SnapshotCreationFailedReason (Severity=Warning) documents a VSphereVMSnapshot controller
+	// detecting an error while creating the snapshot; those kind of errors are usually transient
+	// and failed snapshots are automatically re-tried by the controller.
+	SnapshotCreationFailedReason = "SnapshotCreationFailed"
+)
+
+// Conditions and Reasons related to the import of the OVA of a VSphereMachineImage.
+const (
+	// ImageImportedCondition documents whether the OVA of a VSphereMachineImage has been
+	// imported as a template.
+	ImageImportedCondition clusterv1.ConditionType = "ImageImported"
+
+	// ImageImportFailedReason (Severity=Warning) documents a VSphereMachineImage controller
+	// detecting an error while downloading or importing the OVA; those kind of errors are usually
+	// transient and failed imports are automatically re-tried by the controller.
+	ImageImportFailedReason = "ImageImportFailed"
+
+	// ImageChecksumMismatchReason (Severity=Error) documents a VSphereMachineImage whose OVA
+	// does not match its checksum; the imported template is removed and the import is re-tried.
+	ImageChecksumMismatchReason = "ImageChecksumMismatch"
+
+	// WaitingForImageReason (Severity=Info) documents a VSphereMachine waiting for the
+	// VSphereMachineImage it references to be imported before its VM is cloned.
+	WaitingForImageReason = "WaitingForImage"
+
+	// WaitingForDefaultTemplateReason (Severity=Warning) documents a VSphereMachine, or a VSphereMachinePool,
+	// which sets neither a template nor an image, waiting for the VSphereProviderConfig to set a default
+	// template for its Kubernetes version before its VMs are cloned.
+	WaitingForDefaultTemplateReason = "WaitingForDefaultTemplate"
+
+	// WaitingForImageMappingReason (Severity=Warning) documents a VSphereMachine, or a VSphereMachinePool,
+	// waiting for the VSphereMachineImageMapping it references to map its Kubernetes version before its VMs
+	// are cloned.
+	WaitingForImageMappingReason = "WaitingForImageMapping"
+)
+
+// Conditions and Reasons related to the VMs of a VSphereMachinePool.
+const (
+	// ReplicasReadyCondition documents whether the VSphereVMs of a VSphereMachinePool match the
+	// replicas of its MachinePool and are ready.
+	//
+	// NOTE: A VSphereMachinePool waiting for the cluster infrastructure, the bootstrap data or its
+	// VSphereMachineImage reports it with the reasons of the VMProvisionedCondition.
+	ReplicasReadyCondition clusterv1.ConditionType = "ReplicasReady"
+
+	// ScalingUpReason (Severity=Info) documents a VSphereMachinePool creating VSphereVMs to match
+	// the replicas of its MachinePool.
+	ScalingUpReason = "ScalingUp"
+
+	// ScalingDownReason (Severity=Info) documents a VSphereMachinePool deleting VSphereVMs to match
+	// the replicas of its MachinePool.
+	ScalingDownReason = "ScalingDown"
+
+	// WaitingForReplicasReadyReason (Severity=Info) documents a VSphereMachinePool waiting for the
+	// VMs of its VSphereVMs to be provisioned.
+	WaitingForReplicasReadyReason = "WaitingForReplicasReady"
+)
+
+// Conditions and Reasons related to the VMs of a VSphereLoadBalancerVM.
+const (
+	// LoadBalancerVMsReadyCondition documents whether both VSphereVMs of a VSphereLoadBalancerVM are
+	// provisioned, so the virtual IP can move from one to the other.
+	LoadBalancerVMsReadyCondition clusterv1.ConditionType = "LoadBalancerVMsReady"
+
+	// WaitingForLoadBalancerVMsReason (Severity=Info) documents a VSphereLoadBalancerVM waiting for
+	// the VMs of its VSphereVMs to be provisioned.
+	WaitingForLoadBalancerVMsReason = "WaitingForLoadBalancerVMs"
+
+	// LoadBalancerDegradedReason (Severity=Warning) documents a VSphereLoadBalancerVM of which only
+	// one VM is provisioned, so the virtual IP is not highly available.
+	LoadBalancerDegradedReason = "LoadBalancerDegraded"
 )
 
 // Conditions and Reasons related to utilizing a VSphereIdentity to make connections to a VCenter.
@@ -101,6 +457,107 @@ const (
 	// VCenterUnreachableReason (Severity=Error) documents a controller detecting
 	// issues with VCenter reachability.
 	VCenterUnreachableReason = "VCenterUnreachable"
+
+	// IdentityNotAllowedReason (Severity=Error) documents a VSphereCluster whose namespace is not
+	// allowed to use the VSphereClusterIdentity it references by its allowedNamespaces.
+	IdentityNotAllowedReason = "IdentityNotAllowed"
+)
+
+// Conditions and Reasons related to the privileges of the credentials used
+// by a VSphereCluster to connect to vCenter.
+const (
+	// CredentialsValidCondition documents whether the credentials of a
+	// VSphereCluster hold the privileges required on the inventory objects
+	// used by its machines.
+	CredentialsValidCondition clusterv1.ConditionType = "CredentialsValid"
+
+	// MissingPrivilegesReason (Severity=Warning) documents the credentials of a
+	// VSphereCluster missing privileges on some of the inventory objects used
+	// by its machines, or the objects not being visible to them.
+	MissingPrivilegesReason = "MissingPrivileges"
+)
+
+// Conditions and Reasons related to the add-ons installed by a VSphereCluster
+// into the workload cluster.
+const (
+	// CCMAvailableCondition documents the status of the vSphere cloud controller
+	// manager installed into the workload cluster.
+	CCMAvailableCondition clusterv1.ConditionType = "CCMAvailable"
+
+	// CCMProvisioningFailedReason (Severity=Warning) documents a VSphereCluster controller
+	// detecting an error while installing the vSphere cloud controller manager;
+	// those kind of errors are usually transient and failed provisioning are
+	// automatically re-tried by the controller.
+	CCMProvisioningFailedReason = "CCMProvisioningFailed"
+
+	// CSIAvailableCondition documents the status of the vSphere CSI driver
+	// installed into the workload cluster.
+	CSIAvailableCondition clusterv1.ConditionType = "CSIAvailable"
+
+	// CSIProvisioningFailedReason (Severity=Warning) documents a VSphereCluster controller
+	// detecting an error while installing the vSphere CSI driver; those kind of errors are
+	// usually transient and failed provisioning are automatically re-tried by the controller.
+	CSIProvisioningFailedReason = "CSIProvisioningFailed"
+
+	// CSIComponentsNotReadyReason (Severity=Info) documents a VSphereCluster waiting for the
+	// vSphere CSI controller Deployment and node DaemonSet to become available.
+	CSIComponentsNotReadyReason = "CSIComponentsNotReady"
+)
+
+// Conditions and Reasons related to the control plane endpoint of a VSphereCluster.
+const (
+	// ControlPlaneEndpointReadyCondition documents whether the control plane endpoint of
+	// a VSphereCluster has been provisioned by its control plane endpoint provider.
+	ControlPlaneEndpointReadyCondition clusterv1.ConditionType = "ControlPlaneEndpointReady"
+
+	// WaitingForControlPlaneEndpointReason (Severity=Info) documents a VSphereCluster whose
+	// control plane endpoint provider is waiting for the endpoint to be set or allocated;
+	// the message of the condition explains what the provider is waiting for.
+	WaitingForControlPlaneEndpointReason = "WaitingForControlPlaneEndpoint"
+
+	// ControlPlaneEndpointProvisioningFailedReason (Severity=Warning) documents a VSphereCluster
+	// controller detecting an error while provisioning the control plane endpoint; those kind of
+	// errors are usually transient and failed provisioning are automatically re-tried by the controller.
+	// An unknown control plane endpoint provider is reported with the Error severity.
+	ControlPlaneEndpointProvisioningFailedReason = "ControlPlaneEndpointProvisioningFailed"
+
+	// ControlPlaneEndpointDNSReadyCondition documents whether the DNS record of the control
+	// plane endpoint of a VSphereCluster is registered with its DNS provider.
+	ControlPlaneEndpointDNSReadyCondition clusterv1.ConditionType = "ControlPlaneEndpointDNSReady"
+
+	// DNSRecordProvisioningFailedReason (Severity=Warning) documents a VSphereCluster controller
+	// detecting an error while registering the DNS record of the control plane endpoint; those
+	// kind of errors are usually transient and failed provisioning are automatically re-tried by
+	// the controller. A control plane endpoint whose host is not an IP address is reported with
+	// the Error severity.
+	DNSRecordProvisioningFailedReason = "DNSRecordProvisioningFailed"
+)
+
+// Conditions and Reasons related to the NSX-T segment of a VSphereCluster.
+const (
+	// NSXTSegmentReadyCondition documents the status of the NSX-T segment
+	// created for the nodes of the cluster.
+	NSXTSegmentReadyCondition clusterv1.ConditionType = "NSXTSegmentReady"
+
+	// NSXTSegmentProvisioningFailedReason (Severity=Warning) documents a VSphereCluster controller
+	// detecting an error while creating the NSX-T segment, Tier-1 gateway or SNAT rule; those kind
+	// of errors are usually transient and failed provisioning are automatically re-tried by the controller.
+	NSXTSegmentProvisioningFailedReason = "NSXTSegmentProvisioningFailed"
+)
+
+// Conditions and Reasons related to the deletion of a VSphereCluster.
+const (
+	// MachinesDeletedCondition documents whether all the VSphereMachines of a deleted
+	// VSphereCluster are deleted; status.deletion reports the progress of their deletion.
+	MachinesDeletedCondition clusterv1.ConditionType = "MachinesDeleted"
+
+	// WaitingForMachinesDeletionReason (Severity=Info) documents a deleted VSphereCluster
+	// waiting for its VSphereMachines to be deleted.
+	WaitingForMachinesDeletionReason = "WaitingForMachinesDeletion"
+
+	// MachinesDeletionBlockedReason (Severity=Warning) documents a deleted VSphereCluster
+	// some of whose VSphereMachines fail to destroy their VM.
+	MachinesDeletionBlockedReason = "MachinesDeletionBlocked"
 )
 
 const (
@@ -116,6 +573,10 @@ const (
 
 	// SecretAlreadyInUseReason is used when another VSphereClusterIdentity is using the secret.
 	SecretAlreadyInUseReason = "SecretInUse"
+
+	// VaultCredentialsNotAvailableReason is used when the credentials of the VSphereClusterIdentity
+	// cannot be read from Vault.
+	VaultCredentialsNotAvailableReason = "VaultCredentialsNotAvailable"
 )
 
 const (
@@ -158,7 +619,16 @@ const (
 	// associated to the VSphereDeploymentZone are misconfigured.
 	NetworkNotFoundReason = "NetworkNotFound"
 
-	// DatastoreNotFoundReason (Severity=Error) documents that the datastore in the topology for the Failure Domain
+	// DatastoreNotFoundReason (Severity=Error) documents that the datastore or the datastore cluster in the topology for the Failure Domain
 	// associated to the VSphereDeploymentZone is misconfigured.
 	DatastoreNotFoundReason = "DatastoreNotFound"
+
+	// VSANStretchedClusterMisconfiguredReason (Severity=Error) documents that the storage policy of the vSAN stretched
+	// cluster site in the topology for the Failure Domain associated to the VSphereDeploymentZone cannot be found, or
+	// keeps the data of the VMs on the other site.
+	VSANStretchedClusterMisconfiguredReason = "VSANStretchedClusterMisconfigured"
+
+	// UnsupportedByEndpointReason (Severity=Error) documents that the Failure Domain associated to the
+	// VSphereDeploymentZone cannot be used with the vSphere endpoint, e.g. a standalone ESXi host.
+	UnsupportedByEndpointReason = "UnsupportedByEndpoint"
 )
@@ -20,6 +20,7 @@ package v1beta1
 import (
 	"fmt"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
@@ -50,6 +51,13 @@ const (
 	// clone mode, but it also prevents expanding a VMs disk beyond the size of
 	// the source VM/template.
 	LinkedClone CloneMode = "linkedClone"
+
+	// InstantClone means resulting VMs are forked from the memory and disks
+	// of a running, frozen parent VM created from the source VM/template.
+	// This is the fastest clone mode for scaling out, but the VMs share the
+	// virtual hardware of their parent and the guest OS of the template has
+	// to freeze the parent VM itself.
+	InstantClone CloneMode = "instantClone"
 )
 
 // OS is the type of Operating System the virtual machine uses.
@@ -63,12 +71,50 @@ const (
 	Windows OS = "Windows"
 )
 
+// Firmware is the type of firmware the virtual machine boots with.
+// +kubebuilder:validation:Enum=efi;bios
+type Firmware string
+
+const (
+	// FirmwareEFI indicates the VM boots with UEFI firmware.
+	FirmwareEFI Firmware = "efi"
+
+	// FirmwareBIOS indicates the VM boots with legacy BIOS firmware.
+	FirmwareBIOS Firmware = "bios"
+)
+
+// BootstrapDataTransport is the way the metadata and the cloud-init bootstrap
+// data are delivered to the virtual machine.
+// +kubebuilder:validation:Enum=guestinfo;vappProperties;cdrom
+type BootstrapDataTransport string
+
+const (
+	// GuestInfoTransport delivers the data as guestinfo variables, read by
+	// the VMware datasource of cloud-init.
+	GuestInfoTransport BootstrapDataTransport = "guestinfo"
+
+	// VAppPropertiesTransport delivers the data as vApp properties of the
+	// OVF environment, read by the OVF datasource of cloud-init.
+	VAppPropertiesTransport BootstrapDataTransport = "vappProperties"
+
+	// CDROMTransport delivers the data as a NoCloud ISO image attached to a
+	// CD-ROM drive of the virtual machine, read by the NoCloud datasource of
+	// cloud-init.
+	CDROMTransport BootstrapDataTransport = "cdrom"
+)
+
 // VirtualMachineCloneSpec is information used to clone a virtual machine.
 type VirtualMachineCloneSpec struct {
 	// Template is the name or inventory path of the template used to clone
 	// the virtual machine.
+	// If omitted, no virtual machine is cloned and a pre-existing virtual
+	// machine is adopted instead. The virtual machine is identified by the
+	// BiosUUID or InstanceUUID of the VSphereVM, or by the ProviderID of the
+	// VSphereMachine. It should be powered off so it boots with the bootstrap
+	// data attached by the controller.
 	// +kubebuilder:validation:MinLength=1
-	Template string `json:"template"`
+	// +optional
+	Template string `json:"template,omitempty"`
 
 	// CloneMode specifies the type of clone operation.
 	// The LinkedClone mode is only support for templates that have at least
@@ -78,11 +124,17 @@ type VirtualMachineCloneSpec struct {
 	// not possible to expand disks of linked clones.
 	// Defaults to LinkedClone, but fails gracefully to FullClone if the source
 	// of the clone operation has no snapshots.
+	// When LinkedClone is set explicitly and the LinkedCloneSnapshotCreation
+	// feature gate is enabled, a snapshot is created on sources that are not
+	// marked as templates instead of falling back to FullClone.
+	// The InstantClone mode requires vCenter 6.7 or later, and is not
+	// supported for Windows VMs, or VMs with a vTPM or PCI devices.
 	// +optional
 	CloneMode CloneMode `json:"cloneMode,omitempty"`
 
 	// Snapshot is the name of the snapshot from which to create a linked clone.
-	// This field is ignored if LinkedClone is not enabled.
+	// This field can only be set if CloneMode is LinkedClone, and the clone fails
+	// if the source has no snapshot of that name.
 	// Defaults to the source's current snapshot.
 	// +optional
 	Snapshot string `json:"snapshot,omitempty"`
@@ -115,8 +167,18 @@ type VirtualMachineCloneSpec struct {
 	// +optional
 	Datastore string `json:"datastore,omitempty"`
 
+	// Datastores is an ordered list of names or inventory paths of fallback
+	// datastores. The virtual machine is created on the first of Datastore
+	// and Datastores with the free space for its disks and swap file, so
+	// that a full datastore does not keep machines from being created.
+	// The datastore chosen is reported in the status of the VSphereVM.
+	// +optional
+	Datastores []string `json:"datastores,omitempty"`
+
 	// StoragePolicyName of the storage policy to use with this
-	// Virtual Machine
+	// Virtual Machine. The virtual machine is placed on a datastore
+	// compatible with the storage policy and the policy is applied to
+	// its disks, unless a data disk specifies its own storage policy.
 	// +optional
 	StoragePolicyName string `json:"storagePolicyName,omitempty"`
 
@@ -125,12 +187,35 @@ type VirtualMachineCloneSpec struct {
 	// +optional
 	ResourcePool string `json:"resourcePool,omitempty"`
 
+	// CreateTargetHierarchy creates the Folder and the ResourcePool, along
+	// with their missing parents, when they do not exist instead of failing
+	// to clone the virtual machine. Relative paths are created in the
+	// datacenter's default folder and resource pool.
+	// +optional
+	CreateTargetHierarchy bool `json:"createTargetHierarchy,omitempty"`
+
+	// ResourcePoolLimits are the resource allocation settings of the
+	// ResourcePool when it is created by CreateTargetHierarchy. Existing
+	// resource pools are left unchanged.
+	// +optional
+	ResourcePoolLimits *ResourcePoolLimits `json:"resourcePoolLimits,omitempty"`
+
 	// Network is the network configuration for this machine's VM.
 	Network NetworkSpec `json:"network"`
 
+	// ResourceAllocation is the CPU and memory reservations, limits and
+	// shares of the virtual machine. It is applied when the virtual machine
+	// is cloned and restored whenever it drifts.
+	// Unset values are left as configured in the template.
+	// +optional
+	ResourceAllocation *ResourceAllocation `json:"resourceAllocation,omitempty"`
+
 	// NumCPUs is the number of virtual processors in a virtual machine.
 	// Defaults to the eponymous property value in the template from which the
 	// virtual machine is cloned.
+	// It can only be increased once the virtual machine is created; increases
+	// are applied to powered off virtual machines, and to running virtual
+	// machines with EnableHotAdd set.
 	// +optional
 	NumCPUs int32 `json:"numCPUs,omitempty"`
 	// NumCPUs is the number of cores among which to distribute CPUs in this
@@ -142,11 +227,49 @@ type VirtualMachineCloneSpec struct {
 	// MemoryMiB is the size of a virtual machine's memory, in MiB.
 	// Defaults to the eponymous property value in the template from which the
 	// virtual machine is cloned.
+	// It can only be increased once the virtual machine is created; increases
+	// are applied to powered off virtual machines, and to running virtual
+	// machines with EnableHotAdd set.
 	// +optional
 	MemoryMiB int64 `json:"memoryMiB,omitempty"`
+	// HardwareVersion is the hardware version of the virtual machine, e.g.
+	// vmx-19. Powered off virtual machines with an older hardware version,
+	// e.g. newly cloned ones, are upgraded before they are powered on, while
+	// running ones are upgraded at the next restart of their guest OS once
+	// the vspherevm.infrastructure.cluster.x-k8s.io/upgrade-hardware
+	// annotation is set on their VSphereVM. Downgrades are rejected.
+	// +kubebuilder:validation:Pattern=`^vmx-[0-9]+$`
+	// +optional
+	HardwareVersion string `json:"hardwareVersion,omitempty"`
+	// EnableHotAdd enables CPU and memory hot-add on the virtual machine when
+	// it is cloned, so increases of NumCPUs and MemoryMiB are applied without
+	// powering it off. The guest OS must support hot-add.
+	// +optional
+	EnableHotAdd bool `json:"enableHotAdd,omitempty"`
+	// LatencySensitivity is the latency sensitivity of the virtual machine,
+	// set when it is cloned. The high level gives its vCPUs exclusive access
+	// to physical CPUs and reserves all its memory; its CPU should also be
+	// fully reserved with ResourceAllocation.CPUReservationMHz.
+	// Defaults to the latency sensitivity of the template from which the
+	// virtual machine is cloned.
+	// +optional
+	LatencySensitivity LatencySensitivityLevel `json:"latencySensitivity,omitempty"`
+	// NumaNodeAffinity is the list of the NUMA nodes of the host the
+	// virtual machine is scheduled on, set with the numa.nodeAffinity
+	// advanced option when it is cloned.
+	// +optional
+	NumaNodeAffinity []int32 `json:"numaNodeAffinity,omitempty"`
+	// CPUPinning is the list of the physical CPUs of the host the vCPUs of
+	// the virtual machine are scheduled on, set as its CPU affinity when it
+	// is cloned. It must list at least NumCPUs physical CPUs.
+	// +optional
+	CPUPinning []int32 `json:"cpuPinning,omitempty"`
 	// DiskGiB is the size of a virtual machine's disk, in GiB.
 	// Defaults to the eponymous property value in the template from which the
 	// virtual machine is cloned.
+	// It can only be increased once the virtual machine is created; increases
+	// are applied to the disk of full clones, including running ones, while the file system of the guest is grown by cloud-init at the
+	// next boot.
 	// +optional
 	DiskGiB int32 `json:"diskGiB,omitempty"`
 	// AdditionalDisksGiB holds the sizes of additional disks of the virtual machine, in GiB
@@ -154,6 +277,16 @@ type VirtualMachineCloneSpec struct {
 	// virtual machine is cloned.
 	// +optional
 	AdditionalDisksGiB []int32 `json:"additionalDisksGiB,omitempty"`
+	// DiskProvisioningType is the provisioning type of the disks cloned from
+	// the template, and of the data disks without a provisioning mode.
+	// Defaults to the provisioning type of the disks of the template, and to
+	// Thin for data disks.
+	// It is ignored for the disks of linked clones, which are backed by the
+	// disks of the template, and on vSAN datastores, where the space
+	// reservation of disks is defined by their storage policy. The
+	// provisioning type applied is reported in the status of the VSphereVM.
+	// +optional
+	DiskProvisioningType DiskProvisioningMode `json:"diskProvisioningType,omitempty"`
 	// CustomVMXKeys is a dictionary of advanced VMX options that can be set on VM
 	// Defaults to empty map
 	// +optional
@@ -162,14 +295,167 @@ type VirtualMachineCloneSpec struct {
 	// must use URN-notation instead of display names.
 	// +optional
 	TagIDs []string `json:"tagIDs,omitempty"`
+	// MetadataPropagation maps labels and annotations of the Machine to vSphere
+	// tags and custom attributes of the virtual machine, e.g. for chargeback.
+	// They are set once the virtual machine is cloned and kept in sync with
+	// the Machine.
+	// +optional
+	MetadataPropagation []MetadataPropagationSpec `json:"metadataPropagation,omitempty"`
 	// PciDevices is the list of pci devices used by the virtual machine.
 	// +optional
 	PciDevices []PCIDeviceSpec `json:"pciDevices,omitempty"`
+	// Disks is the list of additional data disks that are created and attached
+	// to the virtual machine when it is cloned. These disks are in addition to
+	// the disks of the template and are deleted along with the virtual machine.
+	// +optional
+	Disks []DiskSpec `json:"disks,omitempty"`
 
 	// OS is the Operating System of the virtual machine
 	// Defaults to Linux
+	// Windows virtual machines are customized with Sysprep, which sets their
+	// computer name and network configuration.
 	// +optional
 	OS OS `json:"os,omitempty"`
+
+	// Firmware is the firmware the virtual machine boots with.
+	// Defaults to the firmware of the template from which the virtual
+	// machine is cloned.
+	// +optional
+	Firmware Firmware `json:"firmware,omitempty"`
+	// SecureBoot enables UEFI Secure Boot on the virtual machine when it is
+	// cloned. Requires Firmware to be efi.
+	// +optional
+	SecureBoot bool `json:"secureBoot,omitempty"`
+	// VTPM adds a virtual TPM device to the virtual machine when it is
+	// cloned. Requires Firmware to be efi, a key provider configured in
+	// vCenter and a hardware version of at least vmx-14.
+	// +optional
+	VTPM bool `json:"vtpm,omitempty"`
+	// BootstrapDataTransport is the way the metadata and the cloud-init
+	// bootstrap data are delivered to the virtual machine. Defaults to
+	// guestinfo. Use vappProperties or cdrom for bootstrap data too large
+	// for guestinfo variables. Ignition configs are always delivered as
+	// guestinfo variables.
+	// +optional
+	BootstrapDataTransport BootstrapDataTransport `json:"bootstrapDataTransport,omitempty"`
+	// GuestTools configures the VMware Tools of the virtual machine, whose
+	// status is reported in the status of its VSphereVM.
+	// +optional
+	GuestTools *GuestToolsSpec `json:"guestTools,omitempty"`
+}
+
+// GuestToolsSpec defines the VMware Tools policy of a virtual machine.
+type GuestToolsSpec struct {
+	// RequireRunning keeps the VSphereVM from being ready until the VMware
+	// Tools of its virtual machine are running. The VMware Tools report the
+	// IP addresses of the virtual machine.
+	// +optional
+	RequireRunning bool `json:"requireRunning,omitempty"`
+	// UpgradePolicy is the upgrade policy of the VMware Tools of the virtual
+	// machine, set when it is cloned.
+	// Defaults to the upgrade policy of the template from which the virtual
+	// machine is cloned.
+	// +optional
+	UpgradePolicy GuestToolsUpgradePolicy `json:"upgradePolicy,omitempty"`
+}
+
+// GuestToolsUpgradePolicy is the upgrade policy of the VMware Tools of a
+// virtual machine.
+// +kubebuilder:validation:Enum=manual;upgradeAtPowerCycle
+type GuestToolsUpgradePolicy string
+
+const (
+	// GuestToolsUpgradePolicyManual leaves the VMware Tools to be upgraded
+	// by hand.
+	GuestToolsUpgradePolicyManual GuestToolsUpgradePolicy = "manual"
+
+	// GuestToolsUpgradePolicyUpgradeAtPowerCycle upgrades the VMware Tools
+	// whenever the virtual machine is powered on, if a newer version is
+	// available on its host.
+	GuestToolsUpgradePolicyUpgradeAtPowerCycle GuestToolsUpgradePolicy = "upgradeAtPowerCycle"
+)
+
+// ResourcePoolLimits defines the CPU and memory allocation of a resource
+// pool. Unset values default to no reservation and an unlimited allocation.
+type ResourcePoolLimits struct {
+	// CPUReservationMHz is the CPU guaranteed to the resource pool, in MHz.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	CPUReservationMHz *int64 `json:"cpuReservationMHz,omitempty"`
+	// CPULimitMHz is the maximum CPU the resource pool can use, in MHz.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	CPULimitMHz *int64 `json:"cpuLimitMHz,omitempty"`
+	// MemoryReservationMiB is the memory guaranteed to the resource pool, in
+	// MiB.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MemoryReservationMiB *int64 `json:"memoryReservationMiB,omitempty"`
+	// MemoryLimitMiB is the maximum memory the resource pool can use, in MiB.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MemoryLimitMiB *int64 `json:"memoryLimitMiB,omitempty"`
+}
+
+// SharesLevel is the relative priority of a virtual machine for a resource
+// when the resource is contended.
+// +kubebuilder:validation:Enum=low;normal;high
+type SharesLevel string
+
+const (
+	// SharesLevelLow grants half the shares of SharesLevelNormal.
+	SharesLevelLow SharesLevel = "low"
+
+	// SharesLevelNormal is the default priority of a virtual machine.
+	SharesLevelNormal SharesLevel = "normal"
+
+	// SharesLevelHigh grants twice the shares of SharesLevelNormal.
+	SharesLevelHigh SharesLevel = "high"
+)
+
+// LatencySensitivityLevel is the sensitivity of a virtual machine to the
+// scheduling latency of its vCPUs and of its memory.
+// +kubebuilder:validation:Enum=normal;high
+type LatencySensitivityLevel string
+
+const (
+	// LatencySensitivityNormal is the default latency sensitivity of a
+	// virtual machine.
+	LatencySensitivityNormal LatencySensitivityLevel = "normal"
+
+	// LatencySensitivityHigh is the latency sensitivity of virtual machines
+	// running latency sensitive workloads, e.g. telco network functions.
+	LatencySensitivityHigh LatencySensitivityLevel = "high"
+)
+
+// ResourceAllocation defines the CPU and memory allocation of a virtual
+// machine.
+type ResourceAllocation struct {
+	// CPUReservationMHz is the CPU guaranteed to the virtual machine, in MHz.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	CPUReservationMHz *int64 `json:"cpuReservationMHz,omitempty"`
+	// CPULimitMHz is the maximum CPU the virtual machine can use, in MHz.
+	// A limit of -1 means the CPU usage is unlimited.
+	// +kubebuilder:validation:Minimum=-1
+	// +optional
+	CPULimitMHz *int64 `json:"cpuLimitMHz,omitempty"`
+	// CPUShares is the priority of the virtual machine for CPU.
+	// +optional
+	CPUShares SharesLevel `json:"cpuShares,omitempty"`
+	// MemoryReservationMiB is the memory guaranteed to the virtual machine,
+	// in MiB.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MemoryReservationMiB *int64 `json:"memoryReservationMiB,omitempty"`
+	// MemoryLimitMiB is the maximum memory the virtual machine can use, in
+	// MiB. A limit of -1 means the memory usage is unlimited.
+	// +kubebuilder:validation:Minimum=-1
+	// +optional
+	MemoryLimitMiB *int64 `json:"memoryLimitMiB,omitempty"`
+	// MemoryShares is the priority of the virtual machine for memory.
+	// +optional
+	MemoryShares SharesLevel `json:"memoryShares,omitempty"`
 }
 
 // VSphereMachineTemplateResource describes the data needed to create a VSphereMachine from a template
@@ -213,18 +499,96 @@ func (v APIEndpoint) String() string {
 	return fmt.Sprintf("%s:%d", v.Host, v.Port)
 }
 
-// PCIDeviceSpec defines virtual machine's PCI configuration
+// PCIDeviceSpec defines virtual machine's PCI configuration.
+// A device is either a DirectPath I/O device identified by its DeviceID and
+// VendorID, or an NVIDIA vGPU device identified by its VGPUProfile.
 type PCIDeviceSpec struct {
 	// DeviceID is the device ID of a virtual machine's PCI, in integer.
 	// Defaults to the eponymous property value in the template from which the
 	// virtual machine is cloned.
-	// +kubebuilder:validation:Required
+	// Required for DirectPath I/O devices.
+	// +optional
 	DeviceID *int32 `json:"deviceId,omitempty"`
 	// VendorId is the vendor ID of a virtual machine's PCI, in integer.
 	// Defaults to the eponymous property value in the template from which the
 	// virtual machine is cloned.
-	// +kubebuilder:validation:Required
+	// Required for DirectPath I/O devices.
+	// +optional
 	VendorID *int32 `json:"vendorId,omitempty"`
+	// VGPUProfile is the name of the NVIDIA vGPU profile, for example
+	// grid_t4-4q, used to attach a vGPU device to the virtual machine.
+	// Mutually exclusive with DeviceID and VendorID.
+	// +optional
+	VGPUProfile string `json:"vGPUProfile,omitempty"`
+}
+
+// DiskProvisioningMode describes the provisioning type of a virtual disk.
+// +kubebuilder:validation:Enum=Thin;Thick;EagerZeroedThick
+type DiskProvisioningMode string
+
+const (
+	// ThinProvisioningMode creates a disk whose space is allocated and zeroed
+	// on demand.
+	ThinProvisioningMode DiskProvisioningMode = "Thin"
+
+	// ThickProvisioningMode creates a disk whose space is allocated at creation
+	// time and zeroed on demand.
+	ThickProvisioningMode DiskProvisioningMode = "Thick"
+
+	// EagerZeroedThickProvisioningMode creates a disk whose space is allocated
+	// and zeroed at creation time.
+	EagerZeroedThickProvisioningMode DiskProvisioningMode = "EagerZeroedThick"
+)
+
+// DiskSpec defines an additional data disk of a virtual machine.
+type DiskSpec struct {
+	// SizeGiB is the size of the disk, in GiB.
+	// +kubebuilder:validation:Minimum=1
+	SizeGiB int32 `json:"sizeGiB"`
+
+	// ProvisioningMode is the provisioning type of the disk.
+	// Defaults to the DiskProvisioningType of the virtual machine, or to Thin.
+	// +optional
+	ProvisioningMode DiskProvisioningMode `json:"provisioningMode,omitempty"`
+
+	// Datastore is the name of the datastore on which the disk is created.
+	// Defaults to the datastore of the virtual machine.
+	// +optional
+	Datastore string `json:"datastore,omitempty"`
+
+	// StoragePolicyName is the name of the storage policy applied to the disk.
+	// Disks without a datastore override are created on a datastore
+	// compatible with the storage policy.
+	// +optional
+	StoragePolicyName string `json:"storagePolicyName,omitempty"`
+}
+
+// MetadataPropagationSpec maps a label or an annotation of a Machine to a
+// vSphere tag or custom attribute of its virtual machine. Exactly one of
+// Label and Annotation, and exactly one of TagCategory and CustomAttribute
+// must be set.
+type MetadataPropagationSpec struct {
+	// Label is the key of the label of the Machine whose value is propagated.
+	// +optional
+	Label string `json:"label,omitempty"`
+
+	// Annotation is the key of the annotation of the Machine whose value is
+	// propagated.
+	// +optional
+	Annotation string `json:"annotation,omitempty"`
+
+	// TagCategory is the name of the tag category of the tag named after the
+	// value which is attached to the virtual machine. Other tags of the
+	// category are detached from the virtual machine. The category and the
+	// tag are created if they do not exist.
+	// +optional
+	TagCategory string `json:"tagCategory,omitempty"`
+
+	// CustomAttribute is the name of the custom attribute of the virtual
+	// machine set to the value. The custom attribute is created if it does
+	// not exist.
+	// +optional
+	CustomAttribute string `json:"customAttribute,omitempty"`
 }
 
 // NetworkSpec defines the virtual machine's network configuration.
@@ -243,14 +607,87 @@ type NetworkSpec struct {
 	// server endpoint on this machine
 	// +optional
 	PreferredAPIServerCIDR string `json:"preferredAPIServerCidr,omitempty"`
+
+	// PreferredNodeIPCIDR is the CIDR of the IP addresses of the machine
+	// which are reported first in its addresses, so one of them is the
+	// primary node IP of a machine with several network devices.
+	// +optional
+	PreferredNodeIPCIDR string `json:"preferredNodeIPCIDR,omitempty"`
+
+	// ExcludeNetworkCIDRs are the CIDRs of the IP addresses of the machine
+	// which are not reported in its addresses, e.g. those of its storage or
+	// backup networks.
+	// +optional
+	ExcludeNetworkCIDRs []string `json:"excludeNetworkCIDRs,omitempty"`
+
+	// AddressWait describes how long the machine waits for an address on
+	// each of its DHCP network devices, and what happens once it waited for
+	// longer. The machine is ready once any of its devices has an address
+	// when unset.
+	// +optional
+	AddressWait *NetworkAddressWaitPolicy `json:"addressWait,omitempty"`
+}
+
+// NetworkAddressWaitAction describes what happens once a machine waited for
+// the addresses of its DHCP network devices for longer than its timeout.
+// +kubebuilder:validation:Enum=Wait;Fail;Fallback
+type NetworkAddressWaitAction string
+
+const (
+	// NetworkAddressWaitActionWait indicates to keep waiting for the
+	// addresses, with the IPAssigned condition reporting the timeout as a
+	// warning.
+	NetworkAddressWaitActionWait NetworkAddressWaitAction = "Wait"
+
+	// NetworkAddressWaitActionFail indicates to fail the machine, so it is
+	// remediated or retried according to its FailureRetryPolicy.
+	NetworkAddressWaitActionFail NetworkAddressWaitAction = "Fail"
+
+	// NetworkAddressWaitActionFallback indicates to make the machine ready
+	// with the addresses of its other network devices, or to keep waiting
+	// if none of them has an address.
+	NetworkAddressWaitActionFallback NetworkAddressWaitAction = "Fallback"
+)
+
+// NetworkAddressWaitPolicy describes how long a machine waits for the
+// addresses of its DHCP network devices.
+type NetworkAddressWaitPolicy struct {
+	// Timeout is the time the machine waits for an address on each of its
+	// DHCP network devices after it is powered on.
+	Timeout metav1.Duration `json:"timeout"`
+
+	// Action is what happens once the machine waited for the addresses for
+	// longer than the Timeout. Wait keeps waiting, Fail fails the machine,
+	// and Fallback makes the machine ready with the addresses of its other
+	// network devices.
+	//
+	// Defaults to Wait.
+	// +optional
+	Action NetworkAddressWaitAction `json:"action,omitempty"`
 }
 
 // NetworkDeviceSpec defines the network configuration for a virtual machine's
 // network device.
 type NetworkDeviceSpec struct {
 	// NetworkName is the name of the vSphere network to which the device
-	// will be connected.
-	NetworkName string `json:"networkName"`
+	// will be connected. Defaults to the NSX-T segment of the cluster when
+	// the VSphereCluster configures one.
+	// +optional
+	NetworkName string `json:"networkName,omitempty"`
+
+	// SwitchName is the name of the distributed switch on which a
+	// distributed port group named NetworkName is created with the VLAN ID
+	// of the device before the VM is cloned, if it does not exist yet. The
+	// VLAN ID of an existing port group must match.
+	// +optional
+	SwitchName string `json:"switchName,omitempty"`
+
+	// VLANID is the VLAN ID of the distributed port group created on
+	// SwitchName. The port group is not tagged when unset.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=4094
+	// +optional
+	VLANID int32 `json:"vlanID,omitempty"`
 
 	// DeviceName may be used to explicitly assign a name to the network device
 	// as it exists in the guest operating system.
@@ -269,22 +706,39 @@ type NetworkDeviceSpec struct {
 	// +optional
 	DHCP6 bool `json:"dhcp6,omitempty"`
 
+	// SLAAC is a flag that indicates whether or not to accept router
+	// advertisements and configure IPv6 addresses with stateless address
+	// autoconfiguration on this device.
+	// It may be combined with DHCP4 or static IPv4 addresses for dual-stack
+	// devices.
+	// +optional
+	SLAAC bool `json:"slaac,omitempty"`
+
 	// Gateway4 is the IPv4 gateway used by this device.
 	// Required when DHCP4 is false.
 	// +optional
 	Gateway4 string `json:"gateway4,omitempty"`
 
-	// Gateway4 is the IPv4 gateway used by this device.
+	// Gateway6 is the IPv6 gateway used by this device.
 	// Required when DHCP6 is false.
 	// +optional
 	Gateway6 string `json:"gateway6,omitempty"`
 
 	// IPAddrs is a list of one or more IPv4 and/or IPv6 addresses to assign
 	// to this device.
-	// Required when DHCP4 and DHCP6 are both false.
+	// Required when DHCP4, DHCP6 and SLAAC are all false.
+	// IPv4 and IPv6 addresses may be combined with DHCP or SLAAC of the
+	// other address family for dual-stack devices.
 	// +optional
 	IPAddrs []string `json:"ipAddrs,omitempty"`
 
+	// IPPool is the name of a VSphereIPPool in the namespace of the machine
+	// the IPv4 address of this device is allocated from when IPAddrs is
+	// empty, along with its gateway and nameservers when Gateway4 and
+	// Nameservers are empty. The address is released once the VM is deleted.
+	// +optional
+	IPPool string `json:"ipPool,omitempty"`
+
 	// MTU is the device’s Maximum Transmission Unit size in bytes.
 	// +optional
 	MTU *int64 `json:"mtu,omitempty"`
@@ -304,6 +758,8 @@ type NetworkDeviceSpec struct {
 	Nameservers []string `json:"nameservers,omitempty"`
 
 	// Routes is a list of optional, static routes applied to the device.
+	// Unlike the routes of the NetworkSpec, they are only applied to this
+	// device, e.g. to reach storage networks through a dedicated gateway.
 	// +optional
 	Routes []NetworkRouteSpec `json:"routes,omitempty"`
 
@@ -315,14 +771,32 @@ type NetworkDeviceSpec struct {
 
 // NetworkRouteSpec defines a static network route.
 type NetworkRouteSpec struct {
-	// To is an IPv4 or IPv6 address.
+	// To is the IPv4 or IPv6 destination of the route, in the CIDR format.
 	To string `json:"to"`
-	// Via is an IPv4 or IPv6 address.
+	// Via is the IPv4 or IPv6 address of the gateway of the route.
 	Via string `json:"via"`
 	// Metric is the weight/priority of the route.
 	Metric int32 `json:"metric"`
 }
 
+// IPAllocation is an IP address allocated to a network device of a VM from
+// a VSphereIPPool.
+type IPAllocation struct {
+	// Device is the index of the network device in the network spec of the
+	// VM.
+	Device int32 `json:"device"`
+
+	// Pool is the name of the VSphereIPPool the address is allocated from.
+	Pool string `json:"pool"`
+
+	// Address is the allocated IP address, in the CIDR format.
+	Address string `json:"address"`
+
+	// Ref is the reference of the allocation in the IPAM of the pool, e.g.
+	// the reference of the host record of the address in Infoblox.
+	Ref string `json:"ref"`
+}
+
 // NetworkStatus provides information about one of a VM's networks.
 type NetworkStatus struct {
 	// Connected is a flag that indicates whether this network is currently
@@ -383,6 +857,15 @@ type VirtualMachine struct {
 
 	// Network is the status of the VM's network devices.
 	Network []NetworkStatus `json:"network"`
+
+	// Hostname is the host name of the guest reported by vm-tools.
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+
+	// DNSNames are the fully qualified domain names of the guest reported by
+	// vm-tools.
+	// +optional
+	DNSNames []string `json:"dnsNames,omitempty"`
 }
 
 // SSHUser is granted remote access to a system.
@@ -27,6 +27,51 @@ const (
 	// resources associated with VSphereCluster before removing it from the
 	// API server.
 	ClusterFinalizer = "vspherecluster.infrastructure.cluster.x-k8s.io"
+
+	// ControlPlaneEndpointProviderAnnotation selects the provider of the
+	// control plane endpoint of a VSphereCluster, which is one of the
+	// ControlPlaneEndpointProvider values. The static provider is used when
+	// the annotation is not set.
+	ControlPlaneEndpointProviderAnnotation = "vspherecluster.infrastructure.cluster.x-k8s.io/control-plane-endpoint-provider"
+
+	// ForceCleanupAnnotation releases the VSphereVMs of a VSphereCluster
+	// being deleted whose vCenter has been unreachable for longer than the
+	// vCenter unreachable timeout of the manager, without destroying their
+	// VMs, as when the manager skips the deletion of the infrastructure of
+	// unreachable vCenters.
+	ForceCleanupAnnotation = "vspherecluster.infrastructure.cluster.x-k8s.io/force-cleanup"
+
+	// DefaultControlPlaneEndpointDNSTTL is the TTL, in seconds, of the DNS
+	// record of the control plane endpoint when the spec does not set one.
+	DefaultControlPlaneEndpointDNSTTL = 60
+)
+
+// ControlPlaneEndpointProvider values of the
+// ControlPlaneEndpointProviderAnnotation.
+const (
+	// StaticControlPlaneEndpointProvider uses the control plane endpoint set
+	// in the spec of the VSphereCluster when it is created.
+	StaticControlPlaneEndpointProvider = "static"
+
+	// KubeVIPControlPlaneEndpointProvider uses the host of the control plane
+	// endpoint set in the spec of the VSphereCluster as the virtual IP
+	// announced by kube-vip on the control plane nodes, and defaults its
+	// port to 6443.
+	KubeVIPControlPlaneEndpointProvider = "kube-vip"
+
+	// NSXALBControlPlaneEndpointProvider load balances the control plane
+	// with NSX Advanced Load Balancer, through a Service of type LoadBalancer
+	// of the management cluster reconciled by the Avi Kubernetes Operator.
+	NSXALBControlPlaneEndpointProvider = "nsx-alb"
+
+	// ExternalControlPlaneEndpointProvider waits for another controller to
+	// set the control plane endpoint in the spec of the VSphereCluster.
+	ExternalControlPlaneEndpointProvider = "external"
+
+	// LoadBalancerVMControlPlaneEndpointProvider load balances the control
+	// plane with the VSphereLoadBalancerVM of the cluster, and uses its
+	// virtual IP and port as the control plane endpoint.
+	LoadBalancerVMControlPlaneEndpointProvider = "load-balancer-vm"
 )
 
 // VSphereClusterSpec defines the desired state of VSphereCluster
@@ -42,10 +87,316 @@ type VSphereClusterSpec struct {
 	// +optional
 	ControlPlaneEndpoint APIEndpoint `json:"controlPlaneEndpoint"`
 
+	// ControlPlaneEndpointDNS registers the address of the control plane
+	// endpoint under a DNS name once its provider assigns it, and removes the
+	// record when the cluster is deleted.
+	// +optional
+	ControlPlaneEndpointDNS *ControlPlaneEndpointDNSSpec `json:"controlPlaneEndpointDNS,omitempty"`
+
 	// IdentityRef is a reference to either a Secret or VSphereClusterIdentity that contains
 	// the identity to use when reconciling the cluster.
 	// +optional
 	IdentityRef *VSphereIdentityReference `json:"identityRef,omitempty"`
+
+	// CloudProvider configures the external vSphere cloud provider (CPI)
+	// installed into the workload cluster once its API server is online.
+	// +optional
+	CloudProvider *CloudProviderSpec `json:"cloudProvider,omitempty"`
+
+	// CSI configures the vSphere CSI driver installed into the workload
+	// cluster once its API server is online.
+	// +optional
+	CSI *CSISpec `json:"csi,omitempty"`
+
+	// NSXT configures a dedicated NSX-T segment created for the nodes of
+	// the cluster. The network devices of the machines that do not specify a
+	// networkName are attached to this segment.
+	// +optional
+	NSXT *NSXTSpec `json:"nsxt,omitempty"`
+
+	// Connection configures the proxy and the CA certificates of the
+	// connections to vCenter. It takes precedence over the one of the
+	// VSphereClusterIdentity of the cluster.
+	// +optional
+	Connection *VCenterConnectionSpec `json:"connection,omitempty"`
+
+	// ResourceQuota limits the vCPUs, the memory and the disk provisioned
+	// for the VMs of the cluster. VMs which would exceed it are not created
+	// until enough resources are released.
+	// +optional
+	ResourceQuota *ResourceQuotaSpec `json:"resourceQuota,omitempty"`
+
+	// VCenters are the additional vCenters of the cluster, e.g. the
+	// independent vCenters of the sites of a stretched control plane. The
+	// machines of the deployment zones of a vCenter are cloned in it, with
+	// its credentials, while the other machines are cloned in the vCenter of
+	// the server of the cluster.
+	// +optional
+	VCenters []VCenterSpec `json:"vcenters,omitempty"`
+}
+
+// VCenterSpec defines an additional vCenter of a VSphereCluster.
+type VCenterSpec struct {
+	// Server is the address of the vCenter.
+	Server string `json:"server"`
+
+	// Thumbprint is the colon-separated SHA-1 checksum of the vCenter's host
+	// certificate.
+	// +optional
+	Thumbprint string `json:"thumbprint,omitempty"`
+
+	// IdentityRef is a reference to either a Secret or VSphereClusterIdentity
+	// that contains the identity used to log in to the vCenter. The identity
+	// of the cluster is used when it is not set.
+	// +optional
+	IdentityRef *VSphereIdentityReference `json:"identityRef,omitempty"`
+
+	// Zones are the names of the VSphereDeploymentZones whose machines are
+	// cloned in the vCenter.
+	// +optional
+	Zones []string `json:"zones,omitempty"`
+}
+
+// CloudProviderSpec defines how the vSphere cloud provider is managed in the
+// workload cluster.
+type CloudProviderSpec struct {
+	// Disabled prevents the controller from installing and upgrading the
+	// vSphere cloud provider in the workload cluster. Use this when the cloud
+	// provider is managed outside of Cluster API Provider vSphere.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Version is the version of the vSphere cloud controller manager image
+	// to deploy, for example v1.18.1. Defaults to the version bundled with
+	// the controller when empty.
+	// +optional
+	Version string `json:"version,omitempty"`
+}
+
+// CSISpec defines how the vSphere CSI driver is managed in the workload
+// cluster.
+type CSISpec struct {
+	// Enabled installs and upgrades the vSphere CSI driver in the workload
+	// cluster.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Version is the version of the vSphere CSI driver and syncer images to
+	// deploy, for example v2.1.0. Defaults to the version bundled with the
+	// controller when empty.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// StorageClass configures the StorageClass created for the CSI driver.
+	// No StorageClass is created when unset.
+	// +optional
+	StorageClass *CSIStorageClassSpec `json:"storageClass,omitempty"`
+
+	// TopologyAware enables topology aware volume provisioning, using the
+	// region and zone tag categories of the failure domains of the cluster.
+	// +optional
+	TopologyAware bool `json:"topologyAware,omitempty"`
+}
+
+// CSIStorageClassSpec defines the StorageClass created for the vSphere CSI
+// driver.
+type CSIStorageClassSpec struct {
+	// Name is the name of the StorageClass. Defaults to vsphere-csi.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Default marks the StorageClass as the default StorageClass of the
+	// workload cluster.
+	// +optional
+	Default bool `json:"default,omitempty"`
+
+	// StoragePolicyName is the name of the vSphere storage policy used to
+	// provision volumes.
+	// +optional
+	StoragePolicyName string `json:"storagePolicyName,omitempty"`
+
+	// DatastoreURL is the URL of the datastore used to provision volumes.
+	// +optional
+	DatastoreURL string `json:"datastoreURL,omitempty"`
+
+	// FSType is the filesystem type of provisioned volumes. Defaults to ext4.
+	// +optional
+	FSType string `json:"fsType,omitempty"`
+}
+
+// NSXTSpec defines the NSX-T manager and the segment created for the nodes
+// of a cluster.
+type NSXTSpec struct {
+	// Server is the address of the NSX-T manager.
+	Server string `json:"server"`
+
+	// Thumbprint is the colon-separated SHA-256 checksum of the NSX-T
+	// manager certificate. The certificate is verified against
+	// the system CAs when empty.
+	// +optional
+	Thumbprint string `json:"thumbprint,omitempty"`
+
+	// SecretName is the name of a Secret in the namespace of the
+	// VSphereCluster holding the username and password used to connect to
+	// the NSX-T manager.
+	SecretName string `json:"secretName"`
+
+	// TransportZonePath is the policy path of the overlay transport zone of
+	// the segment, for example
+	// /infra/sites/default/enforcement-points/default/transport-zones/<id>.
+	TransportZonePath string `json:"transportZonePath"`
+
+	// GatewayCIDR is the gateway address and prefix length of the subnet
+	// of the segment, for example 192.168.10.1/24.
+	GatewayCIDR string `json:"gatewayCIDR"`
+
+	// DHCPConfigPath is the policy path of the DHCP server or relay
+	// configuration of the segment. The nodes must use static addresses
+	// when unset.
+	// +optional
+	DHCPConfigPath string `json:"dhcpConfigPath,omitempty"`
+
+	// Gateway configures a dedicated Tier-1 gateway the segment is
+	// connected to. The segment is isolated when unset.
+	// +optional
+	Gateway *NSXTGatewaySpec `json:"gateway,omitempty"`
+}
+
+// NSXTGatewaySpec defines the Tier-1 gateway created for the segment of a
+// cluster.
+type NSXTGatewaySpec struct {
+	// Tier0Path is the policy path of the Tier-0 gateway the Tier-1 gateway
+	// is connected to, for example /infra/tier-0s/<id>.
+	Tier0Path string `json:"tier0Path"`
+
+	// EdgeClusterPath is the policy path of the edge cluster hosting the
+	// services of the Tier-1 gateway. Required when SNATIP is set.
+	// +optional
+	EdgeClusterPath string `json:"edgeClusterPath,omitempty"`
+
+	// SNATIP is the address the traffic leaving the segment is translated
+	// to. No SNAT rule is created when empty.
+	// +optional
+	SNATIP string `json:"snatIP,omitempty"`
+}
+
+// ControlPlaneEndpointDNSSpec defines the DNS record of the control plane
+// endpoint of a cluster, an A or AAAA record according to the family of the
+// address of the endpoint. Exactly one DNS provider is to be set.
+type ControlPlaneEndpointDNSSpec struct {
+	// Name is the fully qualified domain name of the record, for example
+	// api.cluster.example.com.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// TTL is the time to live of the record, in seconds. Defaults to 60.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TTL int32 `json:"ttl,omitempty"`
+
+	// Route53 registers the record in an Amazon Route 53 hosted zone.
+	// +optional
+	Route53 *Route53DNSSpec `json:"route53,omitempty"`
+
+	// Infoblox registers the record in a DNS view of an Infoblox grid.
+	// +optional
+	Infoblox *InfobloxDNSSpec `json:"infoblox,omitempty"`
+
+	// RFC2136 registers the record with dynamic updates of the primary name
+	// server of the zone of the record.
+	// +optional
+	RFC2136 *RFC2136DNSSpec `json:"rfc2136,omitempty"`
+}
+
+// Route53DNSSpec defines the Amazon Route 53 hosted zone of the DNS record of
+// the control plane endpoint.
+type Route53DNSSpec struct {
+	// HostedZoneID is the ID of the hosted zone, for example Z1D633PJN98FT9.
+	HostedZoneID string `json:"hostedZoneID"`
+
+	// SecretName is the name of a Secret in the namespace of the
+	// VSphereCluster holding the accessKeyID and secretAccessKey, and the
+	// optional sessionToken, used to call the Route 53 API.
+	SecretName string `json:"secretName"`
+}
+
+// InfobloxDNSSpec defines the Infoblox grid of the DNS record of the control
+// plane endpoint.
+type InfobloxDNSSpec struct {
+	// Server is the address of the Infoblox grid master.
+	Server string `json:"server"`
+
+	// Thumbprint is the colon-separated SHA-256 checksum of the grid master
+	// certificate. The certificate is verified against
+	// the system CAs when empty.
+	// +optional
+	Thumbprint string `json:"thumbprint,omitempty"`
+
+	// SecretName is the name of a Secret in the namespace of the
+	// VSphereCluster holding the username and password used to connect to
+	// the grid master.
+	SecretName string `json:"secretName"`
+
+	// WAPIVersion is the version of the Infoblox WAPI, e.g. v2.12.
+	// Defaults to v2.12.
+	// +optional
+	WAPIVersion string `json:"wapiVersion,omitempty"`
+
+	// DNSView is the DNS view of the zone of the record. Defaults to
+	// default.
+	// +optional
+	DNSView string `json:"dnsView,omitempty"`
+}
+
+// RFC2136DNSSpec defines the name server the DNS record of the control plane
+// endpoint is registered with by RFC 2136 dynamic updates, signed with a
+// TSIG key.
+type RFC2136DNSSpec struct {
+	// Server is the address of the primary name server of the zone, with an
+	// optional port, for example ns1.example.com:53.
+	Server string `json:"server"`
+
+	// Zone is the zone of the record, for example cluster.example.com.
+	Zone string `json:"zone"`
+
+	// TSIGKeyName is the name of the TSIG key the updates are signed with.
+	// The updates are not signed when empty.
+	// +optional
+	TSIGKeyName string `json:"tsigKeyName,omitempty"`
+
+	// TSIGAlgorithm is the algorithm of the TSIG key. Defaults to
+	// hmac-sha256.
+	// +kubebuilder:validation:Enum=hmac-sha256;hmac-sha512
+	// +optional
+	TSIGAlgorithm string `json:"tsigAlgorithm,omitempty"`
+
+	// SecretName is the name of a Secret in the namespace of the
+	// VSphereCluster holding the base64 encoded secret of the TSIG key in
+	// its secret key. Required when TSIGKeyName is set.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// ResourceQuotaSpec defines the total resources of the VMs of a cluster.
+// Resources the VMs inherit from their template, as their spec does not set
+// them, are not counted.
+type ResourceQuotaSpec struct {
+	// CPUs is the total number of vCPUs of the VMs. Zero means no limit.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	CPUs int64 `json:"cpus,omitempty"`
+
+	// MemoryMiB is the total memory size of the VMs, in MiB. Zero means no
+	// limit.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MemoryMiB int64 `json:"memoryMiB,omitempty"`
+
+	// DiskGiB is the total size of the disks of the VMs, in GiB. Zero means
+	// no limit.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	DiskGiB int64 `json:"diskGiB,omitempty"`
 }
 
 // VSphereClusterStatus defines the observed state of VSphereClusterSpec
@@ -59,6 +410,86 @@ type VSphereClusterStatus struct {
 
 	// FailureDomains is a list of failure domain objects synced from the infrastructure provider.
 	FailureDomains clusterv1.FailureDomains `json:"failureDomains,omitempty"`
+
+	// VCenterVersion is the version of the vCenter of the VSphereCluster,
+	// e.g. 7.0.3.
+	// +optional
+	VCenterVersion string `json:"vCenterVersion,omitempty"`
+
+	// VCenterBuild is the build number of the vCenter of the VSphereCluster.
+	// +optional
+	VCenterBuild string `json:"vCenterBuild,omitempty"`
+
+	// OrphanedVMs are the VMs created for the cluster which are not backed
+	// by a VSphereVM anymore, as found by the last orphaned VM sweep.
+	// +optional
+	OrphanedVMs []OrphanedVM `json:"orphanedVMs,omitempty"`
+
+	// LastOrphanedVMSweepTime is the time of the last orphaned VM sweep.
+	// +optional
+	LastOrphanedVMSweepTime *metav1.Time `json:"lastOrphanedVMSweepTime,omitempty"`
+
+	// ControlPlaneEndpointDNSRecord is the DNS record of the control plane
+	// endpoint registered with the provider of spec.controlPlaneEndpointDNS.
+	// +optional
+	ControlPlaneEndpointDNSRecord *DNSRecord `json:"controlPlaneEndpointDNSRecord,omitempty"`
+
+	// Deletion is the progress of the deletion of the VSphereMachines of the
+	// cluster, once the VSphereCluster is deleted.
+	// +optional
+	Deletion *ClusterDeletionStatus `json:"deletion,omitempty"`
+}
+
+// ClusterDeletionStatus is the progress of the deletion of the VSphereMachines
+// of a deleted VSphereCluster.
+type ClusterDeletionStatus struct {
+	// Machines is the number of VSphereMachines of the cluster left.
+	Machines int32 `json:"machines"`
+
+	// Deleting is the number of VSphereMachines of the cluster being deleted.
+	Deleting int32 `json:"deleting"`
+
+	// Pending is the number of VSphereMachines of the cluster not deleted
+	// yet, as their Machine is not deleted yet.
+	Pending int32 `json:"pending"`
+
+	// Blocked are the VSphereMachines of the cluster being deleted whose VM
+	// fails to be destroyed.
+	// +optional
+	Blocked []BlockedMachine `json:"blocked,omitempty"`
+}
+
+// BlockedMachine is a VSphereMachine whose deletion is blocked.
+type BlockedMachine struct {
+	// Name is the name of the VSphereMachine.
+	Name string `json:"name"`
+
+	// Reason is the reason the deletion of the VSphereMachine is blocked,
+	// e.g. the error destroying its VM.
+	Reason string `json:"reason"`
+}
+
+// DNSRecord is an address record registered with a DNS provider.
+type DNSRecord struct {
+	// Name is the fully qualified domain name of the record.
+	Name string `json:"name"`
+
+	// Address is the address of the record.
+	Address string `json:"address"`
+}
+
+// OrphanedVM is a VM created for the cluster which is not backed by a
+// VSphereVM anymore, e.g. after the restore of an etcd backup.
+type OrphanedVM struct {
+	// Name is the name of the VM.
+	Name string `json:"name"`
+
+	// MoRef is the managed object reference of the VM, e.g. vm-42.
+	MoRef string `json:"moRef"`
+
+	// VSphereVM is the namespace and the name of the VSphereVM the VM was
+	// created for.
+	VSphereVM string `json:"vsphereVM"`
 }
 
 // +kubebuilder:object:root=true
@@ -24,18 +24,128 @@ import (
 
 const (
 	SecretIdentitySetFinalizer = "vspherecluster/infrastructure.cluster.x-k8s.io"
+
+	// DefaultIdentityAnnotation is set on a namespace to the name of the
+	// VSphereClusterIdentity the VSphereClusters of the namespace without an
+	// IdentityRef are bound to, instead of using the credentials of the
+	// controller manager.
+	DefaultIdentityAnnotation = "vsphereclusteridentity.infrastructure.cluster.x-k8s.io/default"
 )
 
 type VSphereClusterIdentitySpec struct {
-	// SecretName references a Secret inside the controller namespace with the credentials to use
+	// SecretName references a Secret inside the controller namespace with the credentials to use.
+	// It is ignored when Vault is set.
 	// +kubebuilder:validation:MinLength=1
 	SecretName string `json:"secretName,omitempty"`
 
+	// Vault configures the retrieval of short-lived credentials from
+	// HashiCorp Vault instead of a Secret.
+	// +optional
+	Vault *VaultCredentialSource `json:"vault,omitempty"`
+
 	// AllowedNamespaces is used to identify which namespaces are allowed to use this account.
-	// Namespaces can be selected with a label selector.
+	// Namespaces can be selected with a label selector or listed by name.
 	// If this object is nil, no namespaces will be allowed
 	// +optional
 	AllowedNamespaces *AllowedNamespaces `json:"allowedNamespaces,omitempty"`
+
+	// RateLimit overrides the rate limit the controller manager applies to the
+	// vCenter API calls made with this identity.
+	// +optional
+	RateLimit *VCenterRateLimit `json:"rateLimit,omitempty"`
+
+	// Connection configures the proxy and the CA certificates of the
+	// connections to vCenter made with this identity.
+	// +optional
+	Connection *VCenterConnectionSpec `json:"connection,omitempty"`
+
+	// TLS restricts the TLS connections to vCenter made with this identity.
+	// The fields it sets override the TLS policy of the controller manager,
+	// whose strict mode cannot be turned off.
+	// +optional
+	TLS *VCenterTLSPolicy `json:"tls,omitempty"`
+}
+
+// VCenterRateLimit defines the client-side rate limit of the vCenter API
+// calls made against a vCenter endpoint.
+type VCenterRateLimit struct {
+	// QPS is the maximum number of vCenter API calls per second.
+	// A value of 0 disables the rate limit.
+	// +kubebuilder:validation:Minimum=0
+	QPS int32 `json:"qps"`
+
+	// Burst is the maximum number of vCenter API calls which can be made at
+	// once. Defaults to QPS.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Burst int32 `json:"burst,omitempty"`
+}
+
+// VCenterConnectionSpec defines how the connections to a vCenter endpoint
+// are established.
+type VCenterConnectionSpec struct {
+	// Proxy is the URL of the proxy the connections to vCenter go through,
+	// e.g. http://proxy.example.com:3128 or socks5://proxy.example.com:1080.
+	// The proxy of the environment of the controller manager is used when
+	// it is not set.
+	// +kubebuilder:validation:Pattern=`^(http|https|socks5)://`
+	// +optional
+	Proxy string `json:"proxy,omitempty"`
+
+	// CABundle is a PEM encoded bundle of the CA certificates trusted to
+	// verify the certificate of vCenter, in addition to the system ones.
+	// The certificate of vCenter is verified when it is set, even without
+	// a thumbprint.
+	// +optional
+	CABundle []byte `json:"caBundle,omitempty"`
+}
+
+// VCenterTLSPolicy defines the TLS versions and cipher suites of the
+// connections to a vCenter endpoint, and whether its certificate must be
+// verified.
+type VCenterTLSPolicy struct {
+	// MinVersion is the minimum TLS version of the connections. Defaults to
+	// VersionTLS12.
+	// +kubebuilder:validation:Enum=VersionTLS12;VersionTLS13
+	// +optional
+	MinVersion string `json:"minVersion,omitempty"`
+
+	// CipherSuites is the list of the cipher suites allowed for TLS 1.2,
+	// named as in the Go crypto/tls package, e.g.
+	// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. The cipher suites of TLS 1.3 are
+	// not configurable. Defaults to the secure cipher suites of Go.
+	// +optional
+	CipherSuites []string `json:"cipherSuites,omitempty"`
+
+	// Strict refuses the connections to vCenter whose certificate is not
+	// verified. The certificate of vCenter is then verified against the
+	// system and CA bundle certificates, or its thumbprint, instead of being
+	// accepted when no thumbprint is set.
+	// +optional
+	Strict bool `json:"strict,omitempty"`
+}
+
+// VaultCredentialSource defines where the vCenter credentials are read from
+// in HashiCorp Vault.
+type VaultCredentialSource struct {
+	// Address is the URL of the Vault server, e.g. https://vault.example.com:8200.
+	// +kubebuilder:validation:MinLength=1
+	Address string `json:"address"`
+
+	// Path is the path of the secret holding the vCenter credentials under
+	// the username and password keys, e.g. vsphere/creds/capv.
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+
+	// Role is the role the controller manager logs in to Vault with, using
+	// the Kubernetes auth method and its service account token.
+	// +kubebuilder:validation:MinLength=1
+	Role string `json:"role"`
+
+	// AuthPath is the mount path of the Kubernetes auth method.
+	// Defaults to kubernetes.
+	// +optional
+	AuthPath string `json:"authPath,omitempty"`
 }
 
 type VSphereClusterIdentityStatus struct {
@@ -48,7 +158,14 @@ type VSphereClusterIdentityStatus struct {
 }
 
 type AllowedNamespaces struct {
+	// NamespaceList is the list of the names of the namespaces allowed to use
+	// the identity, in addition to the ones matched by Selector.
+	// +optional
+	NamespaceList []string `json:"list,omitempty"`
+
 	// Selector is a standard Kubernetes LabelSelector. A label query over a set of resources.
+	// An empty selector matches all the namespaces when NamespaceList is empty,
+	// and none otherwise.
 	// +optional
 	Selector metav1.LabelSelector `json:"selector"`
 }
@@ -78,6 +78,51 @@ type Topology struct {
 	// virtual machine is created/located.
 	// +optional
 	Datastore string `json:"datastore,omitempty"`
+
+	// DatastoreCluster is the name or inventory path of the datastore
+	// cluster in which the virtual machines of the failure domain are
+	// created, on the datastore of the cluster with the most free space.
+	// It takes precedence over the datastore of the machines, and cannot
+	// be set along with the Datastore.
+	// +optional
+	DatastoreCluster string `json:"datastoreCluster,omitempty"`
+
+	// VSANStretchedCluster places the virtual machines of the failure domain
+	// on a site of a vSAN stretched cluster: their data is kept on the site
+	// by the storage policy of the site, while they run on the hosts of the
+	// Host group of the Hosts, which must hold the hosts of the site.
+	// +optional
+	VSANStretchedCluster *VSANStretchedClusterSite `json:"vsanStretchedCluster,omitempty"`
+}
+
+// VSANSite is a site of a vSAN stretched cluster.
+// +kubebuilder:validation:Enum=Preferred;Secondary
+type VSANSite string
+
+const (
+	// PreferredVSANSite is the preferred fault domain of a vSAN stretched
+	// cluster, which keeps serving the data of the VMs when the sites are
+	// partitioned.
+	PreferredVSANSite VSANSite = "Preferred"
+
+	// SecondaryVSANSite is the secondary fault domain of a vSAN stretched
+	// cluster.
+	SecondaryVSANSite VSANSite = "Secondary"
+)
+
+// VSANStretchedClusterSite defines the site of a vSAN stretched cluster the
+// virtual machines of a failure domain are placed on.
+type VSANStretchedClusterSite struct {
+	// Site is the site of the vSAN stretched cluster.
+	Site VSANSite `json:"site"`
+
+	// StoragePolicyName is the name of the storage policy applied to the
+	// virtual machines, which keeps their data on the site, i.e. whose site
+	// disaster tolerance is "None - keep data on Preferred" or "None - keep
+	// data on Secondary". It takes precedence over the storage policy of the
+	// machines.
+	// +kubebuilder:validation:MinLength=1
+	StoragePolicyName string `json:"storagePolicyName"`
 }
 
 type FailureDomainHosts struct {
@@ -86,6 +131,19 @@ type FailureDomainHosts struct {
 
 	// HostGroupName is the name of the Host group
 	HostGroupName string `json:"hostGroupName"`
+
+	// AutoConfigure creates the VM group and the VM-host affinity rule
+	// binding it to the Host group in the compute cluster when they do not
+	// exist, so that the VMs of the failure domain run on the hosts of the
+	// Host group. The Host group must exist.
+	// +optional
+	AutoConfigure *bool `json:"autoConfigure,omitempty"`
+
+	// Mandatory makes the VM-host affinity rule created by AutoConfigure a
+	// "must run on" rule, which vSphere HA and DRS never violate, instead of
+	// a "should run on" rule. An existing rule is left unchanged.
+	// +optional
+	Mandatory bool `json:"mandatory,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -64,6 +64,14 @@ func (r *VSphereFailureDomain) ValidateCreate() error {
 		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "Topology", "ComputeCluster"), fmt.Sprintf("cannot be nil if zone's Failure Domain type is %s", r.Spec.Zone.Type)))
 	}
 
+	if r.Spec.Topology.Datastore != "" && r.Spec.Topology.DatastoreCluster != "" {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "Topology", "DatastoreCluster"), "cannot be set along with Datastore"))
+	}
+
+	if r.Spec.Topology.VSANStretchedCluster != nil && r.Spec.Topology.Hosts == nil {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "Topology", "Hosts"), "cannot be nil if VSANStretchedCluster is set"))
+	}
+
 	return aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, allErrs)
 }
 
@@ -129,6 +129,69 @@ func TestVSphereFailureDomain_ValidateCreate(t *testing.T) {
 				},
 			}},
 		},
+		{
+			name: "both a datastore and a datastore cluster are set",
+			failureDomain: VSphereFailureDomain{Spec: VSphereFailureDomainSpec{
+				Region: FailureDomain{
+					Name:        "foo",
+					Type:        DatacenterFailureDomain,
+					TagCategory: "k8s-bar",
+				},
+				Zone: FailureDomain{
+					Name:        "foo",
+					Type:        DatacenterFailureDomain,
+					TagCategory: "k8s-bar",
+				},
+				Topology: Topology{
+					Datacenter:       "/blah",
+					Datastore:        "ds-a",
+					DatastoreCluster: "pod-a",
+				},
+			}},
+		},
+		{
+			name: "vSAN stretched cluster site set but hosts are not set",
+			failureDomain: VSphereFailureDomain{Spec: VSphereFailureDomainSpec{
+				Region: FailureDomain{
+					Name:        "foo",
+					Type:        DatacenterFailureDomain,
+					TagCategory: "k8s-bar",
+				},
+				Zone: FailureDomain{
+					Name:        "foo",
+					Type:        ComputeClusterFailureDomain,
+					TagCategory: "k8s-bar",
+				},
+				Topology: Topology{
+					Datacenter:     "/blah",
+					ComputeCluster: pointer.String("blah2"),
+					VSANStretchedCluster: &VSANStretchedClusterSite{
+						Site:              PreferredVSANSite,
+						StoragePolicyName: "keep-data-on-preferred",
+					},
+				},
+			}},
+		},
+		{
+			name:        "datastore cluster",
+			errExpected: pointer.Bool(true),
+			failureDomain: VSphereFailureDomain{Spec: VSphereFailureDomainSpec{
+				Region: FailureDomain{
+					Name:        "foo",
+					Type:        DatacenterFailureDomain,
+					TagCategory: "k8s-bar",
+				},
+				Zone: FailureDomain{
+					Name:        "foo",
+					Type:        DatacenterFailureDomain,
+					TagCategory: "k8s-bar",
+				},
+				Topology: Topology{
+					Datacenter:       "/blah",
+					DatastoreCluster: "pod-a",
+				},
+			}},
+		},
 	}
 
 	for _, tt := range tests {
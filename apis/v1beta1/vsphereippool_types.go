@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DefaultInfobloxWAPIVersion is the version of the Infoblox WAPI used
+	// when the pool does not set one.
+	DefaultInfobloxWAPIVersion = "v2.12"
+
+	// DefaultInfobloxView is the network view and the DNS view used when the
+	// pool does not set them.
+	DefaultInfobloxView = "default"
+)
+
+// VSphereIPPoolSpec defines the IPAM of record the IPv4 addresses of the
+// network devices referencing a VSphereIPPool are allocated from. Exactly one
+// IPAM provider is to be set.
+type VSphereIPPoolSpec struct {
+	// Infoblox allocates the addresses, and registers their DNS records, in
+	// a network of an Infoblox grid.
+	// +optional
+	Infoblox *InfobloxIPPoolSpec `json:"infoblox,omitempty"`
+
+	// Gateway is the IPv4 gateway of the network devices whose addresses are
+	// allocated from the pool, unless they set one.
+	// +optional
+	Gateway string `json:"gateway,omitempty"`
+
+	// Nameservers are the DNS nameservers of the network devices whose
+	// addresses are allocated from the pool, unless they set some.
+	// +optional
+	Nameservers []string `json:"nameservers,omitempty"`
+}
+
+// InfobloxIPPoolSpec defines the network of an Infoblox grid the addresses of
+// a VSphereIPPool are allocated from. Each address is allocated with a host
+// record named after the VM.
+type InfobloxIPPoolSpec struct {
+	// Server is the address of the Infoblox grid master.
+	Server string `json:"server"`
+
+	// Thumbprint is the colon-separated SHA-256 checksum of the grid master
+	// certificate. The certificate is verified against
+	// the system CAs when empty.
+	// +optional
+	Thumbprint string `json:"thumbprint,omitempty"`
+
+	// SecretName is the name of a Secret in the namespace of the VSphereIPPool
+	// holding the username and password used to connect to the grid master.
+	SecretName string `json:"secretName"`
+
+	// WAPIVersion is the version of the Infoblox WAPI, e.g. v2.12.
+	// Defaults to v2.12.
+	// +optional
+	WAPIVersion string `json:"wapiVersion,omitempty"`
+
+	// NetworkView is the network view of the Network. Defaults to default.
+	// +optional
+	NetworkView string `json:"networkView,omitempty"`
+
+	// Network is the network the next available addresses are allocated
+	// from, for example 192.168.10.0/24.
+	Network string `json:"network"`
+
+	// DNSZone is the DNS zone the host records of the addresses are
+	// registered in, as <VM name>.<zone> A and PTR records. The addresses
+	// are not registered in DNS when unset.
+	// +optional
+	DNSZone string `json:"dnsZone,omitempty"`
+
+	// DNSView is the DNS view of the DNSZone. Defaults to default.
+	// +optional
+	DNSView string `json:"dnsView,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=vsphereippools,scope=Namespaced,categories=cluster-api
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Infoblox",type="string",JSONPath=".spec.infoblox.network",description="Infoblox network of the pool"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Time duration since creation of VSphereIPPool"
+
+// VSphereIPPool is the Schema for the vsphereippools API.
+type VSphereIPPool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec VSphereIPPoolSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// VSphereIPPoolList contains a list of VSphereIPPool.
+type VSphereIPPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VSphereIPPool `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VSphereIPPool{}, &VSphereIPPoolList{})
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+const (
+	// LoadBalancerVMFinalizer allows the VSphereLoadBalancerVM controller to
+	// delete the VSphereVMs of the load balancer before the
+	// VSphereLoadBalancerVM is deleted.
+	LoadBalancerVMFinalizer = "vsphereloadbalancervm.infrastructure.cluster.x-k8s.io"
+
+	// LoadBalancerVMNameLabel is the label set on the VSphereVMs of a
+	// VSphereLoadBalancerVM, whose value is the name of the
+	// VSphereLoadBalancerVM.
+	LoadBalancerVMNameLabel = "vsphereloadbalancervm.infrastructure.cluster.x-k8s.io/name"
+
+	// LoadBalancerGuestInfoPrefix is the prefix of the guestinfo variables
+	// the VMs of a VSphereLoadBalancerVM are configured with. They are the only
+	// custom VMX keys of a VSphereVM which can be changed.
+	LoadBalancerGuestInfoPrefix = "guestinfo.capv.loadbalancer."
+
+	// DefaultLoadBalancerVMPort is the port of the virtual IP of a
+	// VSphereLoadBalancerVM when the spec does not set one.
+	DefaultLoadBalancerVMPort = 6443
+
+	// DefaultLoadBalancerVMVirtualRouterID is the VRRP virtual router ID of a
+	// VSphereLoadBalancerVM when the spec does not set one.
+	DefaultLoadBalancerVMVirtualRouterID = 51
+)
+
+// VSphereLoadBalancerVMSpec defines the desired state of VSphereLoadBalancerVM.
+type VSphereLoadBalancerVMSpec struct {
+	// VirtualIP is the IP address load balanced to the API servers of the
+	// control plane machines of the cluster. It is held with VRRP by the
+	// active VM of the pair, and moves to the passive VM when the active VM
+	// or its haproxy fails.
+	VirtualIP string `json:"virtualIP"`
+
+	// Port is the port of the virtual IP. Defaults to 6443.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// VirtualRouterID is the VRRP virtual router ID of the pair, which must
+	// be unique among the VRRP routers of the network. Defaults to 51.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=255
+	// +optional
+	VirtualRouterID int32 `json:"virtualRouterID,omitempty"`
+
+	// Interface is the network interface of the VMs holding the virtual IP.
+	// Defaults to the interface of the route of the VMs to the virtual IP.
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9_.-]+$`
+	// +optional
+	Interface string `json:"interface,omitempty"`
+
+	// SSHAuthorizedKeys are the SSH keys authorized to log in to the VMs as
+	// the capv user.
+	// +optional
+	SSHAuthorizedKeys []string `json:"sshAuthorizedKeys,omitempty"`
+
+	// VirtualMachineCloneSpec is the specification of the VMs of the pair.
+	// The template must provide cloud-init and open-vm-tools; haproxy and
+	// keepalived are installed by cloud-init when the template does not
+	// provide them. Changes only apply to the VMs created after them.
+	VirtualMachineCloneSpec `json:",inline"`
+}
+
+// VSphereLoadBalancerVMStatus defines the observed state of VSphereLoadBalancerVM.
+type VSphereLoadBalancerVMStatus struct {
+	// Ready is true when at least one VM of the pair is provisioned and holds
+	// the virtual IP.
+	// +optional
+	Ready bool `json:"ready"`
+
+	// Members are the IP addresses of the control plane machines the
+	// virtual IP is load balanced to.
+	// +optional
+	Members []string `json:"members,omitempty"`
+
+	// Conditions defines current service state of the VSphereLoadBalancerVM.
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=vsphereloadbalancervms,scope=Namespaced,categories=cluster-api
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".metadata.labels.cluster\\.x-k8s\\.io/cluster-name",description="Cluster to which this VSphereLoadBalancerVM belongs"
+// +kubebuilder:printcolumn:name="VirtualIP",type="string",JSONPath=".spec.virtualIP",description="Virtual IP of the load balancer"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.ready",description="VMs of the load balancer are provisioned"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Time duration since creation of VSphereLoadBalancerVM"
+
+// VSphereLoadBalancerVM is the Schema for the vsphereloadbalancervms API, an
+// active/passive pair of VMs load balancing the control plane of a cluster
+// with haproxy, whose virtual IP is held with keepalived.
+type VSphereLoadBalancerVM struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VSphereLoadBalancerVMSpec   `json:"spec,omitempty"`
+	Status VSphereLoadBalancerVMStatus `json:"status,omitempty"`
+}
+
+func (r *VSphereLoadBalancerVM) GetConditions() clusterv1.Conditions {
+	return r.Status.Conditions
+}
+
+func (r *VSphereLoadBalancerVM) SetConditions(conditions clusterv1.Conditions) {
+	r.Status.Conditions = conditions
+}
+
+// +kubebuilder:object:root=true
+
+// VSphereLoadBalancerVMList contains a list of VSphereLoadBalancerVM.
+type VSphereLoadBalancerVMList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VSphereLoadBalancerVM `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VSphereLoadBalancerVM{}, &VSphereLoadBalancerVMList{})
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"net"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+func (r *VSphereLoadBalancerVM) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-infrastructure-cluster-x-k8s-io-v1beta1-vsphereloadbalancervm,mutating=false,failurePolicy=fail,matchPolicy=Equivalent,groups=infrastructure.cluster.x-k8s.io,resources=vsphereloadbalancervms,versions=v1beta1,name=validation.vsphereloadbalancervm.infrastructure.x-k8s.io,sideEffects=None,admissionReviewVersions=v1beta1
+
+var _ webhook.Validator = &VSphereLoadBalancerVM{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
+func (r *VSphereLoadBalancerVM) ValidateCreate() error {
+	return aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, r.validateSpec())
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
+// The virtual IP, its port and the virtual router ID are immutable, as the
+// control plane endpoint of the cluster does not change. The specification
+// of the VMs can be modified, as the VMs created after the change are cloned
+// from it.
+//nolint:forcetypeassert
+func (r *VSphereLoadBalancerVM) ValidateUpdate(old runtime.Object) error {
+	oldLoadBalancer := old.(*VSphereLoadBalancerVM)
+	allErrs := r.validateSpec()
+	if r.Spec.VirtualIP != oldLoadBalancer.Spec.VirtualIP {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "virtualIP"), "cannot be modified"))
+	}
+	if r.Spec.Port != oldLoadBalancer.Spec.Port {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "port"), "cannot be modified"))
+	}
+	if r.Spec.VirtualRouterID != oldLoadBalancer.Spec.VirtualRouterID {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "virtualRouterID"), "cannot be modified"))
+	}
+	return aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
+func (r *VSphereLoadBalancerVM) ValidateDelete() error {
+	return nil
+}
+
+func (r *VSphereLoadBalancerVM) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	spec := r.Spec
+
+	if net.ParseIP(spec.VirtualIP) == nil {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "virtualIP"), spec.VirtualIP, "must be an IP address"))
+	}
+	if spec.Template == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("spec", "template"), "template is required"))
+	}
+
+	// Both VMs of the pair are cloned from the same specification.
+	for _, device := range spec.Network.Devices {
+		if len(device.IPAddrs) != 0 {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "network", "devices", "ipAddrs"), "cannot be set, as the VMs of the pair get their addresses from DHCP or an IP pool"))
+		}
+		if device.MACAddr != "" {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "network", "devices", "macAddr"), "cannot be set, as it would be shared by the VMs of the pair"))
+		}
+	}
+
+	allErrs = append(allErrs, validatePortGroups(spec.Network.Devices, field.NewPath("spec", "network", "devices"))...)
+	allErrs = append(allErrs, validateNetworkDevices(spec.Network, field.NewPath("spec", "network"))...)
+	allErrs = append(allErrs, validateCloneMode(&spec.VirtualMachineCloneSpec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateFirmware(spec.Firmware, spec.SecureBoot, spec.VTPM, field.NewPath("spec"))...)
+	return allErrs
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestVSphereLoadBalancerVM_ValidateCreate(t *testing.T) {
+	loadBalancer := func(modify func(*VSphereLoadBalancerVMSpec)) *VSphereLoadBalancerVM {
+		lb := &VSphereLoadBalancerVM{
+			Spec: VSphereLoadBalancerVMSpec{
+				VirtualIP: "192.168.10.5",
+				VirtualMachineCloneSpec: VirtualMachineCloneSpec{
+					Server:   "foo.com",
+					Template: "ubuntu-template",
+				},
+			},
+		}
+		modify(&lb.Spec)
+		return lb
+	}
+
+	tests := []struct {
+		name         string
+		loadBalancer *VSphereLoadBalancerVM
+		wantErr      bool
+	}{
+		{
+			name:         "valid spec",
+			loadBalancer: loadBalancer(func(*VSphereLoadBalancerVMSpec) {}),
+			wantErr:      false,
+		},
+		{
+			name:         "IPv6 virtual IP",
+			loadBalancer: loadBalancer(func(spec *VSphereLoadBalancerVMSpec) { spec.VirtualIP = "fd00::5" }),
+			wantErr:      false,
+		},
+		{
+			name:         "virtual IP is not an IP address",
+			loadBalancer: loadBalancer(func(spec *VSphereLoadBalancerVMSpec) { spec.VirtualIP = "api.example.com" }),
+			wantErr:      true,
+		},
+		{
+			name:         "template is not set",
+			loadBalancer: loadBalancer(func(spec *VSphereLoadBalancerVMSpec) { spec.Template = "" }),
+			wantErr:      true,
+		},
+		{
+			name: "IP addresses are set",
+			loadBalancer: loadBalancer(func(spec *VSphereLoadBalancerVMSpec) {
+				spec.Network.Devices = []NetworkDeviceSpec{{IPAddrs: []string{"192.168.10.6/24"}}}
+			}),
+			wantErr: true,
+		},
+		{
+			name: "MAC address is set",
+			loadBalancer: loadBalancer(func(spec *VSphereLoadBalancerVMSpec) {
+				spec.Network.Devices = []NetworkDeviceSpec{{MACAddr: "00:50:56:00:00:01"}}
+			}),
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			err := tc.loadBalancer.ValidateCreate()
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestVSphereLoadBalancerVM_ValidateUpdate(t *testing.T) {
+	loadBalancer := func(modify func(*VSphereLoadBalancerVMSpec)) *VSphereLoadBalancerVM {
+		lb := &VSphereLoadBalancerVM{
+			Spec: VSphereLoadBalancerVMSpec{
+				VirtualIP: "192.168.10.5",
+				VirtualMachineCloneSpec: VirtualMachineCloneSpec{
+					Server:   "foo.com",
+					Template: "ubuntu-template",
+				},
+			},
+		}
+		modify(&lb.Spec)
+		return lb
+	}
+
+	tests := []struct {
+		name         string
+		loadBalancer *VSphereLoadBalancerVM
+		wantErr      bool
+	}{
+		{
+			name:         "template can be modified",
+			loadBalancer: loadBalancer(func(spec *VSphereLoadBalancerVMSpec) { spec.Template = "ubuntu-template-2" }),
+			wantErr:      false,
+		},
+		{
+			name:         "virtual IP cannot be modified",
+			loadBalancer: loadBalancer(func(spec *VSphereLoadBalancerVMSpec) { spec.VirtualIP = "192.168.10.6" }),
+			wantErr:      true,
+		},
+		{
+			name:         "port cannot be modified",
+			loadBalancer: loadBalancer(func(spec *VSphereLoadBalancerVMSpec) { spec.Port = 443 }),
+			wantErr:      true,
+		},
+		{
+			name:         "virtual router ID cannot be modified",
+			loadBalancer: loadBalancer(func(spec *VSphereLoadBalancerVMSpec) { spec.VirtualRouterID = 7 }),
+			wantErr:      true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			err := tc.loadBalancer.ValidateUpdate(loadBalancer(func(*VSphereLoadBalancerVMSpec) {}))
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
@@ -28,6 +28,24 @@ const (
 	// resources associated with VSphereMachine before removing it from the
 	// API Server.
 	MachineFinalizer = "vspheremachine.infrastructure.cluster.x-k8s.io"
+
+	// NodeHostLabel is the label and the annotation of the node of a
+	// VSphereMachine set to the name of the ESXi host its VM runs on.
+	NodeHostLabel = "vsphere.infrastructure.cluster.x-k8s.io/host"
+
+	// NodeComputeClusterLabel is the label and the annotation of the node of
+	// a VSphereMachine set to the name of the compute cluster of the ESXi
+	// host its VM runs on.
+	NodeComputeClusterLabel = "vsphere.infrastructure.cluster.x-k8s.io/compute-cluster"
+
+	// NodeResourcePoolLabel is the label and the annotation of the node of a
+	// VSphereMachine set to the name of the resource pool of its VM.
+	NodeResourcePoolLabel = "vsphere.infrastructure.cluster.x-k8s.io/resource-pool"
+
+	// NodeDatastoreLabel is the label and the annotation of the node of a
+	// VSphereMachine set to the name of the datastore the configuration
+	// files of its VM are stored on.
+	NodeDatastoreLabel = "vsphere.infrastructure.cluster.x-k8s.io/datastore"
 )
 
 // VSphereMachineSpec defines the desired state of VSphereMachine
@@ -42,6 +60,97 @@ type VSphereMachineSpec struct {
 	// FailureDomain is the failure domain unique identifier this Machine should be attached to, as defined in Cluster API.
 	// For this infrastructure provider, the name is equivalent to the name of the VSphereDeploymentZone.
 	FailureDomain *string `json:"failureDomain,omitempty"`
+
+	// PowerOffMode describes the desired behavior when powering off the VM
+	// of this machine before it is deleted. See VSphereVMSpec.PowerOffMode.
+	//
+	// Defaults to hard.
+	// +optional
+	PowerOffMode VirtualMachinePowerOpMode `json:"powerOffMode,omitempty"`
+
+	// GuestSoftPowerOffTimeout sets the wait timeout for shutdown in the VM guest.
+	// See VSphereVMSpec.GuestSoftPowerOffTimeout.
+	// +optional
+	GuestSoftPowerOffTimeout *metav1.Duration `json:"guestSoftPowerOffTimeout,omitempty"`
+
+	// DeletionPolicy describes what happens to the VM of this machine when
+	// it is deleted. See VSphereVMSpec.DeletionPolicy.
+	//
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy VirtualMachineDeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// DriftPolicy describes what happens when the configuration of the VM
+	// of this machine no longer matches the spec. See
+	// VSphereVMSpec.DriftPolicy.
+	//
+	// Defaults to Warn.
+	// +optional
+	DriftPolicy VirtualMachineDriftPolicy `json:"driftPolicy,omitempty"`
+
+	// PowerRecoveryPolicy describes what happens when the VM of this machine
+	// is found powered off after it was powered on. See
+	// VSphereVMSpec.PowerRecoveryPolicy.
+	//
+	// Defaults to PowerOn.
+	// +optional
+	PowerRecoveryPolicy VirtualMachinePowerRecoveryPolicy `json:"powerRecoveryPolicy,omitempty"`
+
+	// SnapshotSchedule takes snapshots of the VM of this machine
+	// periodically. See VSphereVMSpec.SnapshotSchedule.
+	// +optional
+	SnapshotSchedule *VirtualMachineSnapshotSchedule `json:"snapshotSchedule,omitempty"`
+
+	// FailureRetryPolicy retries the VM of this machine when it fails with
+	// one of the failure reasons of the policy. See
+	// VSphereVMSpec.FailureRetryPolicy.
+	// +optional
+	FailureRetryPolicy *VirtualMachineFailureRetryPolicy `json:"failureRetryPolicy,omitempty"`
+
+	// Placement spreads the VMs of the machines created from the same
+	// template across several resource pools instead of the ResourcePool.
+	// See VSphereVMSpec.Placement.
+	// +optional
+	Placement *VirtualMachinePlacement `json:"placement,omitempty"`
+
+	// Image is the name of a VSphereMachineImage in the namespace of this
+	// machine whose template the VM of this machine is cloned from, instead
+	// of the Template. The VM is cloned once the image is imported.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// ImageMapping is the name of a VSphereMachineImageMapping in the
+	// namespace of this machine, whose template or VSphereMachineImage for
+	// the Kubernetes version of the machine the VM of this machine is cloned
+	// from, instead of the Template. A template shared by the machines of
+	// several Kubernetes versions thus does not change on upgrades.
+	// +optional
+	ImageMapping string `json:"imageMapping,omitempty"`
+
+	// DeploymentZones pins the machines created from the same template to a
+	// subset of the VSphereDeploymentZones, and distributes them across the
+	// zones in proportion to their weights. The zone of a machine is chosen
+	// when its Machine has no failure domain, and recorded in the
+	// FailureDomain. The machines of a MachineDeployment are distributed
+	// independently of the other MachineDeployments.
+	// +optional
+	DeploymentZones []DeploymentZoneWeight `json:"deploymentZones,omitempty"`
+}
+
+// DeploymentZoneWeight is a VSphereDeploymentZone machines are distributed
+// to.
+type DeploymentZoneWeight struct {
+	// Name is the name of the VSphereDeploymentZone.
+	Name string `json:"name"`
+
+	// Weight is the share of the machines placed in the zone relative to the
+	// other zones, e.g. a zone with a weight of 2 gets twice as many machines
+	// as a zone with a weight of 1.
+	//
+	// Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Weight int32 `json:"weight,omitempty"`
 }
 
 // VSphereMachineStatus defines the observed state of VSphereMachine
@@ -99,6 +208,31 @@ type VSphereMachineStatus struct {
 	// Conditions defines current service state of the VSphereMachine.
 	// +optional
 	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+
+	// NodeTopology is the location of the VM of the machine in the vSphere
+	// inventory last set on the labels and annotations of its node.
+	// +optional
+	NodeTopology *VirtualMachineTopology `json:"nodeTopology,omitempty"`
+}
+
+// VirtualMachineTopology is the location of a VM in the vSphere inventory.
+type VirtualMachineTopology struct {
+	// Host is the name of the ESXi host the VM runs on.
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// ComputeCluster is the name of the compute cluster of the host, if any.
+	// +optional
+	ComputeCluster string `json:"computeCluster,omitempty"`
+
+	// ResourcePool is the name of the resource pool of the VM.
+	// +optional
+	ResourcePool string `json:"resourcePool,omitempty"`
+
+	// Datastore is the name of the datastore the configuration files of the
+	// VM are stored on.
+	// +optional
+	Datastore string `json:"datastore,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -65,6 +65,32 @@ func (m *VSphereMachine) ValidateCreate() error {
 		}
 	}
 
+	// VSphereMachines without a template or an image adopt the VM identified
+	// by their providerID, or are cloned from the default template of the
+	// VSphereProviderConfig for their Kubernetes version.
+	if spec.Template != "" && spec.Image != "" {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "image"), "cannot be set along with template"))
+	}
+	if spec.ImageMapping != "" && (spec.Template != "" || spec.Image != "") {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "imageMapping"), "cannot be set along with template or image"))
+	}
+
+	allErrs = append(allErrs, validateMACAddrs(spec.Network.Devices, field.NewPath("spec", "network", "devices"))...)
+	allErrs = append(allErrs, validatePortGroups(spec.Network.Devices, field.NewPath("spec", "network", "devices"))...)
+	allErrs = append(allErrs, validateNetworkDevices(spec.Network, field.NewPath("spec", "network"))...)
+	allErrs = append(allErrs, validateAddressFilters(spec.Network, field.NewPath("spec", "network"))...)
+	allErrs = append(allErrs, validateAddressWait(spec.Network, field.NewPath("spec", "network"))...)
+	allErrs = append(allErrs, validateCloneMode(&spec.VirtualMachineCloneSpec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateLatencyTuning(&spec.VirtualMachineCloneSpec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validatePowerOffMode(spec.PowerOffMode, spec.GuestSoftPowerOffTimeout, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateSnapshotSchedule(spec.SnapshotSchedule, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateFailureRetryPolicy(spec.FailureRetryPolicy, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validatePreDeleteBackup(m.Annotations, field.NewPath("metadata", "annotations"))...)
+	allErrs = append(allErrs, validatePCIDevices(spec.PciDevices, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateFirmware(spec.Firmware, spec.SecureBoot, spec.VTPM, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateMetadataPropagation(spec.MetadataPropagation, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateDeploymentZones(spec.DeploymentZones, field.NewPath("spec"))...)
+
 	return aggregateObjErrors(m.GroupVersionKind().GroupKind(), m.Name, allErrs)
 }
 
@@ -90,6 +116,51 @@ func (m *VSphereMachine) ValidateUpdate(old runtime.Object) error {
 	delete(oldVSphereMachineSpec, "providerID")
 	delete(newVSphereMachineSpec, "providerID")
 
+	// allow changes to the power off behavior
+	delete(oldVSphereMachineSpec, "powerOffMode")
+	delete(newVSphereMachineSpec, "powerOffMode")
+	delete(oldVSphereMachineSpec, "guestSoftPowerOffTimeout")
+	delete(newVSphereMachineSpec, "guestSoftPowerOffTimeout")
+
+	// allow changes to the deletion policy
+	delete(oldVSphereMachineSpec, "deletionPolicy")
+	delete(newVSphereMachineSpec, "deletionPolicy")
+
+	// allow changes to the drift policy
+	delete(oldVSphereMachineSpec, "driftPolicy")
+	delete(newVSphereMachineSpec, "driftPolicy")
+
+	// allow changes to the power recovery policy
+	delete(oldVSphereMachineSpec, "powerRecoveryPolicy")
+	delete(newVSphereMachineSpec, "powerRecoveryPolicy")
+
+	// allow changes to the snapshot schedule
+	delete(oldVSphereMachineSpec, "snapshotSchedule")
+	delete(newVSphereMachineSpec, "snapshotSchedule")
+
+	// allow changes to the failure retry policy
+	delete(oldVSphereMachineSpec, "failureRetryPolicy")
+	delete(newVSphereMachineSpec, "failureRetryPolicy")
+
+	// allow changes to the resource allocation
+	delete(oldVSphereMachineSpec, "resourceAllocation")
+	delete(newVSphereMachineSpec, "resourceAllocation")
+
+	// allow increases of the number of CPUs, the memory size and the size of
+	// the OS disk, and upgrades of the hardware version
+	oldMachine := old.(*VSphereMachine)
+	allErrs = append(allErrs, validateResize(&oldMachine.Spec.VirtualMachineCloneSpec, &m.Spec.VirtualMachineCloneSpec, field.NewPath("spec"))...)
+	for _, key := range resizableFields {
+		delete(oldVSphereMachineSpec, key)
+		delete(newVSphereMachineSpec, key)
+	}
+
+	// allow the failure domain to be set once, when the zone of the machine
+	// is chosen among its deployment zones
+	if _, ok := oldVSphereMachineSpec["failureDomain"]; !ok {
+		delete(newVSphereMachineSpec, "failureDomain")
+	}
+
 	newVSphereMachineNetwork := newVSphereMachineSpec["network"].(map[string]interface{})
 	oldVSphereMachineNetwork := oldVSphereMachineSpec["network"].(map[string]interface{})
 
@@ -107,6 +178,19 @@ func (m *VSphereMachine) ValidateUpdate(old runtime.Object) error {
 		}
 	}
 
+	allErrs = append(allErrs, validatePowerOffMode(spec.PowerOffMode, spec.GuestSoftPowerOffTimeout, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateSnapshotSchedule(spec.SnapshotSchedule, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateFailureRetryPolicy(spec.FailureRetryPolicy, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validatePreDeleteBackup(m.Annotations, field.NewPath("metadata", "annotations"))...)
+
+	// validate the network devices only when they are changed, so that
+	// VSphereMachines created before a rule was introduced can still be updated
+	if !reflect.DeepEqual(oldMachine.Spec.Network.Devices, spec.Network.Devices) {
+		allErrs = append(allErrs, validateMACAddrs(spec.Network.Devices, field.NewPath("spec", "network", "devices"))...)
+		allErrs = append(allErrs, validatePortGroups(spec.Network.Devices, field.NewPath("spec", "network", "devices"))...)
+		allErrs = append(allErrs, validateNetworkDevices(spec.Network, field.NewPath("spec", "network"))...)
+	}
+
 	if !reflect.DeepEqual(oldVSphereMachineSpec, newVSphereMachineSpec) {
 		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec"), "cannot be modified"))
 	}
@@ -118,3 +202,22 @@ func (m *VSphereMachine) ValidateUpdate(old runtime.Object) error {
 func (m *VSphereMachine) ValidateDelete() error {
 	return nil
 }
+
+func validateDeploymentZones(zones []DeploymentZoneWeight, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	names := map[string]struct{}{}
+	for i, zone := range zones {
+		zonePath := fldPath.Child("deploymentZones").Index(i)
+		if zone.Name == "" {
+			allErrs = append(allErrs, field.Required(zonePath.Child("name"), "should be the name of a VSphereDeploymentZone"))
+		}
+		if _, ok := names[zone.Name]; ok {
+			allErrs = append(allErrs, field.Duplicate(zonePath.Child("name"), zone.Name))
+		}
+		names[zone.Name] = struct{}{}
+		if zone.Weight < 0 {
+			allErrs = append(allErrs, field.Invalid(zonePath.Child("weight"), zone.Weight, "should be greater than 0"))
+		}
+	}
+	return allErrs
+}
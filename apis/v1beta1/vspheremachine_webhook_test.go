@@ -18,8 +18,11 @@ package v1beta1
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
 )
 
 var someProviderID = "vsphere://42305f0b-dad7-1d3d-5727-0eaffffffffc"
@@ -61,9 +64,89 @@ func TestVSphereMachine_ValidateCreate(t *testing.T) {
 		},
 		{
 			name:           "successful VSphereMachine creation",
+			vsphereMachine: withMachineGateway4(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32", "192.168.0.3/32"}), "192.168.0.254"),
+			wantErr:        false,
+		},
+		{
+			name:           "static IPs without a gateway",
 			vsphereMachine: createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32", "192.168.0.3/32"}),
+			wantErr:        true,
+		},
+		{
+			name:           "no template and no providerID is cloned from the default template",
+			vsphereMachine: withoutMachineTemplate(withMachineGateway4(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), "192.168.0.254")),
+			wantErr:        false,
+		},
+		{
+			name:           "no template with providerID to adopt",
+			vsphereMachine: withoutMachineTemplate(withMachineGateway4(createVSphereMachine("foo.com", &someProviderID, "", []string{"192.168.0.1/32"}), "192.168.0.254")),
+			wantErr:        false,
+		},
+		{
+			name:           "image instead of a template",
+			vsphereMachine: withImage(withoutMachineTemplate(withMachineGateway4(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), "192.168.0.254")), "ubuntu-2004"),
+			wantErr:        false,
+		},
+		{
+			name:           "both an image and a template",
+			vsphereMachine: withImage(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), "ubuntu-2004"),
+			wantErr:        true,
+		},
+		{
+			name:           "image mapping instead of a template",
+			vsphereMachine: withImageMapping(withoutMachineTemplate(withMachineGateway4(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), "192.168.0.254")), "ubuntu"),
+			wantErr:        false,
+		},
+		{
+			name:           "both an image mapping and a template",
+			vsphereMachine: withImageMapping(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), "ubuntu"),
+			wantErr:        true,
+		},
+		{
+			name:           "weighted deployment zones",
+			vsphereMachine: withDeploymentZones(withMachineGateway4(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), "192.168.0.254"), DeploymentZoneWeight{Name: "zone-a", Weight: 2}, DeploymentZoneWeight{Name: "zone-b"}),
+			wantErr:        false,
+		},
+		{
+			name:           "duplicate deployment zones",
+			vsphereMachine: withDeploymentZones(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), DeploymentZoneWeight{Name: "zone-a"}, DeploymentZoneWeight{Name: "zone-a"}),
+			wantErr:        true,
+		},
+		{
+			name:           "address filters",
+			vsphereMachine: withAddressFilters(withMachineGateway4(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), "192.168.0.254"), "192.168.0.0/24", "10.0.0.0/8", "fd00::/8"),
 			wantErr:        false,
 		},
+		{
+			name:           "invalid preferred node IP CIDR",
+			vsphereMachine: withAddressFilters(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), "192.168.0.1"),
+			wantErr:        true,
+		},
+		{
+			name:           "invalid excluded network CIDR",
+			vsphereMachine: withAddressFilters(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), "", "storage"),
+			wantErr:        true,
+		},
+		{
+			name:           "IP pool with DHCP",
+			vsphereMachine: withIPPool(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), "infoblox", true),
+			wantErr:        true,
+		},
+		{
+			name:           "IP pool",
+			vsphereMachine: withIPPool(withMachineGateway4(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), "192.168.0.254"), "infoblox", false),
+			wantErr:        false,
+		},
+		{
+			name:           "address wait policy",
+			vsphereMachine: withAddressWait(withMachineGateway4(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), "192.168.0.254"), 5*time.Minute, NetworkAddressWaitActionFallback),
+			wantErr:        false,
+		},
+		{
+			name:           "address wait policy without timeout",
+			vsphereMachine: withAddressWait(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), 0, NetworkAddressWaitActionFail),
+			wantErr:        true,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -97,9 +180,15 @@ func TestVSphereMachine_ValidateUpdate(t *testing.T) {
 		{
 			name:              "updating ips can be done",
 			oldVSphereMachine: createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}),
-			vsphereMachine:    createVSphereMachine("foo.com", &someProviderID, "", []string{"192.168.0.1/32", "192.168.0.10/32"}),
+			vsphereMachine:    withMachineGateway4(createVSphereMachine("foo.com", &someProviderID, "", []string{"192.168.0.1/32", "192.168.0.10/32"}), "192.168.0.254"),
 			wantErr:           false,
 		},
+		{
+			name:              "updating ips without a gateway cannot be done",
+			oldVSphereMachine: createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}),
+			vsphereMachine:    createVSphereMachine("foo.com", &someProviderID, "", []string{"192.168.0.1/32", "192.168.0.10/32"}),
+			wantErr:           true,
+		},
 		{
 			name:              "updating non-existing IP with invalid ips can not be done",
 			oldVSphereMachine: createVSphereMachine("foo.com", nil, "", nil),
@@ -118,6 +207,39 @@ func TestVSphereMachine_ValidateUpdate(t *testing.T) {
 			vsphereMachine:    createVSphereMachine("bar.com", &someProviderID, "", []string{"192.168.0.1/32", "192.168.0.10/32"}),
 			wantErr:           true,
 		},
+		{
+			name:              "updating the resource allocation can be done",
+			oldVSphereMachine: createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}),
+			vsphereMachine: withResourceAllocation(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), &ResourceAllocation{
+				MemoryReservationMiB: pointer.Int64(4096),
+				CPUShares:            SharesLevelHigh,
+			}),
+			wantErr: false,
+		},
+		{
+			name:              "increasing the number of CPUs can be done",
+			oldVSphereMachine: withMachineSize(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), 2, 4096),
+			vsphereMachine:    withMachineSize(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), 4, 4096),
+			wantErr:           false,
+		},
+		{
+			name:              "decreasing the memory size cannot be done",
+			oldVSphereMachine: withMachineSize(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), 2, 4096),
+			vsphereMachine:    withMachineSize(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), 2, 2048),
+			wantErr:           true,
+		},
+		{
+			name:              "setting the failure domain can be done",
+			oldVSphereMachine: createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}),
+			vsphereMachine:    withFailureDomain(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), "zone-a"),
+			wantErr:           false,
+		},
+		{
+			name:              "updating the failure domain cannot be done",
+			oldVSphereMachine: withFailureDomain(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), "zone-a"),
+			vsphereMachine:    withFailureDomain(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}), "zone-b"),
+			wantErr:           true,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -136,7 +258,8 @@ func createVSphereMachine(server string, providerID *string, preferredAPIServerC
 		Spec: VSphereMachineSpec{
 			ProviderID: providerID,
 			VirtualMachineCloneSpec: VirtualMachineCloneSpec{
-				Server: server,
+				Server:   server,
+				Template: "ubuntu-template",
 				Network: NetworkSpec{
 					PreferredAPIServerCIDR: preferredAPIServerCIDR,
 					Devices:                []NetworkDeviceSpec{},
@@ -151,3 +274,60 @@ func createVSphereMachine(server string, providerID *string, preferredAPIServerC
 	}
 	return VSphereMachine
 }
+
+func withResourceAllocation(m *VSphereMachine, allocation *ResourceAllocation) *VSphereMachine {
+	m.Spec.ResourceAllocation = allocation
+	return m
+}
+
+func withMachineSize(m *VSphereMachine, numCPUs int32, memoryMiB int64) *VSphereMachine {
+	m.Spec.NumCPUs = numCPUs
+	m.Spec.MemoryMiB = memoryMiB
+	return m
+}
+
+func withDeploymentZones(m *VSphereMachine, zones ...DeploymentZoneWeight) *VSphereMachine {
+	m.Spec.DeploymentZones = zones
+	return m
+}
+
+func withAddressFilters(m *VSphereMachine, preferredNodeIPCIDR string, excludeNetworkCIDRs ...string) *VSphereMachine {
+	m.Spec.Network.PreferredNodeIPCIDR = preferredNodeIPCIDR
+	m.Spec.Network.ExcludeNetworkCIDRs = excludeNetworkCIDRs
+	return m
+}
+
+func withIPPool(m *VSphereMachine, pool string, dhcp4 bool) *VSphereMachine {
+	m.Spec.Network.Devices = append(m.Spec.Network.Devices, NetworkDeviceSpec{NetworkName: "storage", IPPool: pool, DHCP4: dhcp4})
+	return m
+}
+
+func withAddressWait(m *VSphereMachine, timeout time.Duration, action NetworkAddressWaitAction) *VSphereMachine {
+	m.Spec.Network.AddressWait = &NetworkAddressWaitPolicy{Timeout: metav1.Duration{Duration: timeout}, Action: action}
+	return m
+}
+
+func withFailureDomain(m *VSphereMachine, failureDomain string) *VSphereMachine {
+	m.Spec.FailureDomain = &failureDomain
+	return m
+}
+
+func withImage(m *VSphereMachine, image string) *VSphereMachine {
+	m.Spec.Image = image
+	return m
+}
+
+func withImageMapping(m *VSphereMachine, imageMapping string) *VSphereMachine {
+	m.Spec.ImageMapping = imageMapping
+	return m
+}
+
+func withMachineGateway4(m *VSphereMachine, gateway4 string) *VSphereMachine {
+	m.Spec.Network.Devices[0].Gateway4 = gateway4
+	return m
+}
+
+func withoutMachineTemplate(m *VSphereMachine) *VSphereMachine {
+	m.Spec.Template = ""
+	return m
+}
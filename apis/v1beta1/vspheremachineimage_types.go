@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// VSphereMachineImageSpec defines the desired state of VSphereMachineImage.
+type VSphereMachineImageSpec struct {
+	// URL is the HTTP or HTTPS URL of the OVA imported as a VM template named
+	// after the VSphereMachineImage.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// Checksum is the hex encoded SHA-256 checksum of the OVA, optionally
+	// prefixed with "sha256:". The template is not kept if the downloaded OVA
+	// does not match the checksum.
+	// +kubebuilder:validation:Pattern=`^(sha256:)?[0-9a-fA-F]{64}$`
+	Checksum string `json:"checksum"`
+
+	// Server is the IP address or FQDN of the vSphere server the template is
+	// imported to.
+	Server string `json:"server"`
+
+	// Thumbprint is the colon-separated SHA-1 checksum of the given vCenter
+	// server's host certificate. When this is set to empty, the template is
+	// imported without TLS certificate validation of the communication
+	// between Cluster API Provider vSphere and the VMware vCenter server.
+	// +optional
+	Thumbprint string `json:"thumbprint,omitempty"`
+
+	// Datacenter is the name or inventory path of the datacenter the template
+	// is imported to.
+	// +optional
+	Datacenter string `json:"datacenter,omitempty"`
+
+	// Folder is the name or inventory path of the folder the template is
+	// imported to.
+	// +optional
+	Folder string `json:"folder,omitempty"`
+
+	// Datastore is the name or inventory path of the datastore the disks of
+	// the template are stored on.
+	// +optional
+	Datastore string `json:"datastore,omitempty"`
+
+	// ResourcePool is the name or inventory path of the resource pool the
+	// template is imported to.
+	// +optional
+	ResourcePool string `json:"resourcePool,omitempty"`
+
+	// NetworkName is the name or inventory path of the network the networks
+	// of the OVA are mapped to. The networks of the VMs cloned from the
+	// template are set by the machines.
+	// +optional
+	NetworkName string `json:"networkName,omitempty"`
+}
+
+// VSphereMachineImageStatus defines the observed state of VSphereMachineImage.
+type VSphereMachineImageStatus struct {
+	// Ready is true when the OVA has been imported as a template.
+	// +optional
+	Ready bool `json:"ready"`
+
+	// TemplatePath is the inventory path of the template the OVA has been
+	// imported as, which the VMs of the machines referencing the image are
+	// cloned from.
+	// +optional
+	TemplatePath string `json:"templatePath,omitempty"`
+
+	// Conditions defines current service state of the VSphereMachineImage.
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=vspheremachineimages,scope=Namespaced,categories=cluster-api
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.ready",description="OVA is imported"
+// +kubebuilder:printcolumn:name="Template",type="string",JSONPath=".status.templatePath",description="Inventory path of the template"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Time duration since creation of VSphereMachineImage"
+
+// VSphereMachineImage is the Schema for the vspheremachineimages API.
+type VSphereMachineImage struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VSphereMachineImageSpec   `json:"spec,omitempty"`
+	Status VSphereMachineImageStatus `json:"status,omitempty"`
+}
+
+func (r *VSphereMachineImage) GetConditions() clusterv1.Conditions {
+	return r.Status.Conditions
+}
+
+func (r *VSphereMachineImage) SetConditions(conditions clusterv1.Conditions) {
+	r.Status.Conditions = conditions
+}
+
+// +kubebuilder:object:root=true
+
+// VSphereMachineImageList contains a list of VSphereMachineImage.
+type VSphereMachineImageList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VSphereMachineImage `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VSphereMachineImage{}, &VSphereMachineImageList{})
+}
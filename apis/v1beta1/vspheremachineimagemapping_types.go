@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VSphereMachineImageMappingSpec defines the desired state of VSphereMachineImageMapping.
+type VSphereMachineImageMappingSpec struct {
+	// Mappings are the templates, or the VSphereMachineImages, of the
+	// Kubernetes versions. A Kubernetes version of the form vMAJOR.MINOR
+	// matches all the patch versions of the minor version, and is used when
+	// no entry matches the version of the machine exactly.
+	// +kubebuilder:validation:MinItems=1
+	Mappings []KubernetesVersionImage `json:"mappings"`
+}
+
+// KubernetesVersionImage defines the template, or the VSphereMachineImage, of
+// a Kubernetes version. Exactly one of Template and Image must be set.
+type KubernetesVersionImage struct {
+	// KubernetesVersion is the Kubernetes version of the machines, e.g.
+	// v1.23.5, or v1.23 for all the patch versions of the minor version.
+	// +kubebuilder:validation:MinLength=1
+	KubernetesVersion string `json:"kubernetesVersion"`
+
+	// Template is the name or inventory path of the template the VMs of the
+	// Kubernetes version are cloned from. The VM templates of a content
+	// library are referenced by the name of their library item.
+	// +optional
+	Template string `json:"template,omitempty"`
+
+	// Image is the name of a VSphereMachineImage in the namespace of the
+	// VSphereMachineImageMapping, the VMs of the Kubernetes version are
+	// cloned from the template of once it is imported.
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=vspheremachineimagemappings,scope=Namespaced,categories=cluster-api
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Time duration since creation of VSphereMachineImageMapping"
+
+// VSphereMachineImageMapping is the Schema for the vspheremachineimagemappings
+// API. The VSphereMachines referencing it are cloned from the template mapped
+// to the Kubernetes version of their Machine, so the machine templates of a
+// ClusterClass do not change when the version of its clusters is upgraded.
+type VSphereMachineImageMapping struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec VSphereMachineImageMappingSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// VSphereMachineImageMappingList contains a list of VSphereMachineImageMapping.
+type VSphereMachineImageMappingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VSphereMachineImageMapping `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VSphereMachineImageMapping{}, &VSphereMachineImageMappingList{})
+}
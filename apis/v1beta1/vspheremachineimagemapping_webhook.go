@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+func (r *VSphereMachineImageMapping) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-infrastructure-cluster-x-k8s-io-v1beta1-vspheremachineimagemapping,mutating=false,failurePolicy=fail,matchPolicy=Equivalent,groups=infrastructure.cluster.x-k8s.io,resources=vspheremachineimagemappings,versions=v1beta1,name=validation.vspheremachineimagemapping.infrastructure.x-k8s.io,sideEffects=None,admissionReviewVersions=v1beta1
+
+var _ webhook.Validator = &VSphereMachineImageMapping{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
+func (r *VSphereMachineImageMapping) ValidateCreate() error {
+	return aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, r.validateSpec())
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
+func (r *VSphereMachineImageMapping) ValidateUpdate(old runtime.Object) error {
+	return aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, r.validateSpec())
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
+func (r *VSphereMachineImageMapping) ValidateDelete() error {
+	return nil
+}
+
+func (r *VSphereMachineImageMapping) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+
+	versions := map[string]bool{}
+	for i, mapping := range r.Spec.Mappings {
+		path := field.NewPath("spec", "mappings").Index(i)
+		if versions[mapping.KubernetesVersion] {
+			allErrs = append(allErrs, field.Duplicate(path.Child("kubernetesVersion"), mapping.KubernetesVersion))
+		}
+		versions[mapping.KubernetesVersion] = true
+
+		switch {
+		case mapping.Template == "" && mapping.Image == "":
+			allErrs = append(allErrs, field.Required(path, "one of template or image must be set"))
+		case mapping.Template != "" && mapping.Image != "":
+			allErrs = append(allErrs, field.Forbidden(path.Child("image"), "cannot be set together with template"))
+		}
+	}
+	return allErrs
+}
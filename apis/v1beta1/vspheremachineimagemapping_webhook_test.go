@@ -0,0 +1,82 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestVSphereMachineImageMapping_Validate(t *testing.T) {
+	imageMapping := func(modify func(*VSphereMachineImageMappingSpec)) *VSphereMachineImageMapping {
+		m := &VSphereMachineImageMapping{
+			Spec: VSphereMachineImageMappingSpec{
+				Mappings: []KubernetesVersionImage{
+					{KubernetesVersion: "v1.23", Template: "ubuntu-2004-kube-v1.23"},
+					{KubernetesVersion: "v1.24.1", Image: "ubuntu-2004-kube-v1.24.1"},
+				},
+			},
+		}
+		modify(&m.Spec)
+		return m
+	}
+
+	tests := []struct {
+		name         string
+		imageMapping *VSphereMachineImageMapping
+		wantErr      bool
+	}{
+		{
+			name:         "valid image mapping",
+			imageMapping: imageMapping(func(*VSphereMachineImageMappingSpec) {}),
+			wantErr:      false,
+		},
+		{
+			name: "duplicate Kubernetes version",
+			imageMapping: imageMapping(func(spec *VSphereMachineImageMappingSpec) {
+				spec.Mappings = append(spec.Mappings, KubernetesVersionImage{KubernetesVersion: "v1.23", Template: "other"})
+			}),
+			wantErr: true,
+		},
+		{
+			name: "neither a template nor an image",
+			imageMapping: imageMapping(func(spec *VSphereMachineImageMappingSpec) {
+				spec.Mappings[0].Template = ""
+			}),
+			wantErr: true,
+		},
+		{
+			name: "both a template and an image",
+			imageMapping: imageMapping(func(spec *VSphereMachineImageMappingSpec) {
+				spec.Mappings[0].Image = "ubuntu-2004-kube-v1.23"
+			}),
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			err := tc.imageMapping.ValidateCreate()
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
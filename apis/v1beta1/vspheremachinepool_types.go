@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+const (
+	// MachinePoolFinalizer allows the VSphereMachinePool controller to delete
+	// the VSphereVMs of the pool before the VSphereMachinePool is deleted.
+	MachinePoolFinalizer = "vspheremachinepool.infrastructure.cluster.x-k8s.io"
+
+	// MachinePoolNameLabel is the label set on the VSphereVMs of a
+	// VSphereMachinePool, whose value is the name of the VSphereMachinePool.
+	MachinePoolNameLabel = "vspheremachinepool.infrastructure.cluster.x-k8s.io/name"
+)
+
+// VSphereMachinePoolSpec defines the desired state of VSphereMachinePool.
+type VSphereMachinePoolSpec struct {
+	// Template is the specification of the VMs of the pool, which are
+	// cloned with the same configuration as the VMs of the machines of a
+	// VSphereMachineTemplate. Changes to the template only apply to the VMs
+	// created after them.
+	Template VSphereMachineTemplateResource `json:"template"`
+
+	// ProviderIDList are the provider IDs of the VMs of the pool, which
+	// Cluster API matches with the nodes of the MachinePool.
+	// +optional
+	ProviderIDList []string `json:"providerIDList,omitempty"`
+}
+
+// VSphereMachinePoolStatus defines the observed state of VSphereMachinePool.
+type VSphereMachinePoolStatus struct {
+	// Ready is true when the VMs of the pool have been provisioned for the
+	// first time.
+	// +optional
+	Ready bool `json:"ready"`
+
+	// Replicas is the number of VMs of the pool which are ready.
+	// +optional
+	Replicas int32 `json:"replicas"`
+
+	// Conditions defines current service state of the VSphereMachinePool.
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=vspheremachinepools,scope=Namespaced,categories=cluster-api
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.ready",description="VMs of the pool are provisioned"
+// +kubebuilder:printcolumn:name="Replicas",type="integer",JSONPath=".status.replicas",description="Number of ready VMs"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Time duration since creation of VSphereMachinePool"
+
+// VSphereMachinePool is the Schema for the vspheremachinepools API, the
+// infrastructure of a Cluster API MachinePool.
+type VSphereMachinePool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VSphereMachinePoolSpec   `json:"spec,omitempty"`
+	Status VSphereMachinePoolStatus `json:"status,omitempty"`
+}
+
+func (r *VSphereMachinePool) GetConditions() clusterv1.Conditions {
+	return r.Status.Conditions
+}
+
+func (r *VSphereMachinePool) SetConditions(conditions clusterv1.Conditions) {
+	r.Status.Conditions = conditions
+}
+
+// +kubebuilder:object:root=true
+
+// VSphereMachinePoolList contains a list of VSphereMachinePool.
+type VSphereMachinePoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VSphereMachinePool `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VSphereMachinePool{}, &VSphereMachinePoolList{})
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+func (r *VSphereMachinePool) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-infrastructure-cluster-x-k8s-io-v1beta1-vspheremachinepool,mutating=false,failurePolicy=fail,matchPolicy=Equivalent,groups=infrastructure.cluster.x-k8s.io,resources=vspheremachinepools,versions=v1beta1,name=validation.vspheremachinepool.infrastructure.x-k8s.io,sideEffects=None,admissionReviewVersions=v1beta1
+
+var _ webhook.Validator = &VSphereMachinePool{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
+func (r *VSphereMachinePool) ValidateCreate() error {
+	return aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, validateMachineTemplateSpec(r.Spec.Template.Spec))
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
+// Unlike the one of a VSphereMachineTemplate, the template of a pool can be
+// modified, as the VMs created after the change are cloned from it.
+func (r *VSphereMachinePool) ValidateUpdate(old runtime.Object) error {
+	return aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, validateMachineTemplateSpec(r.Spec.Template.Spec))
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
+func (r *VSphereMachinePool) ValidateDelete() error {
+	return nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestVSphereMachinePool_Validate(t *testing.T) {
+	pool := func(modify func(*VSphereMachineSpec)) *VSphereMachinePool {
+		p := &VSphereMachinePool{
+			Spec: VSphereMachinePoolSpec{
+				Template: VSphereMachineTemplateResource{
+					Spec: VSphereMachineSpec{
+						VirtualMachineCloneSpec: VirtualMachineCloneSpec{
+							Server:   "foo.com",
+							Template: "ubuntu-template",
+						},
+					},
+				},
+			},
+		}
+		modify(&p.Spec.Template.Spec)
+		return p
+	}
+
+	tests := []struct {
+		name    string
+		pool    *VSphereMachinePool
+		wantErr bool
+	}{
+		{
+			name:    "valid template",
+			pool:    pool(func(*VSphereMachineSpec) {}),
+			wantErr: false,
+		},
+		{
+			name:    "template and image are not set, the default template is used",
+			pool:    pool(func(spec *VSphereMachineSpec) { spec.Template = "" }),
+			wantErr: false,
+		},
+		{
+			name:    "ProviderID is set",
+			pool:    pool(func(spec *VSphereMachineSpec) { spec.ProviderID = &someProviderID }),
+			wantErr: true,
+		},
+		{
+			name: "IP addresses are set",
+			pool: pool(func(spec *VSphereMachineSpec) {
+				spec.Network.Devices = []NetworkDeviceSpec{{IPAddrs: []string{"192.168.0.1/32"}}}
+			}),
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			for _, err := range []error{tc.pool.ValidateCreate(), tc.pool.ValidateUpdate(pool(func(*VSphereMachineSpec) {}))} {
+				if tc.wantErr {
+					g.Expect(err).To(HaveOccurred())
+				} else {
+					g.Expect(err).NotTo(HaveOccurred())
+				}
+			}
+		})
+	}
+}
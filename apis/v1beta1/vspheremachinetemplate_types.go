@@ -18,6 +18,7 @@ limitations under the License.
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -26,16 +27,28 @@ type VSphereMachineTemplateSpec struct {
 	Template VSphereMachineTemplateResource `json:"template"`
 }
 
+// VSphereMachineTemplateStatus defines the observed state of VSphereMachineTemplate
+type VSphereMachineTemplateStatus struct {
+	// Capacity defines the resource capacity of the machines created from
+	// this template, i.e. their cpu, memory, ephemeral-storage and
+	// nvidia.com/gpu. The cluster-autoscaler reads it to scale
+	// MachineDeployments from zero.
+	// +optional
+	Capacity corev1.ResourceList `json:"capacity,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:path=vspheremachinetemplates,scope=Namespaced,categories=cluster-api
 // +kubebuilder:storageversion
+// +kubebuilder:subresource:status
 
 // VSphereMachineTemplate is the Schema for the vspheremachinetemplates API
 type VSphereMachineTemplate struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec VSphereMachineTemplateSpec `json:"spec,omitempty"`
+	Spec   VSphereMachineTemplateSpec   `json:"spec,omitempty"`
+	Status VSphereMachineTemplateStatus `json:"status,omitempty"`
 }
 
 // +kubebuilder:object:root=true
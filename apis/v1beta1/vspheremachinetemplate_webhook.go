@@ -18,7 +18,11 @@ package v1beta1
 
 import (
 	"reflect"
+	"sort"
 
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -37,13 +41,29 @@ var _ webhook.Validator = &VSphereMachineTemplate{}
 
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
 func (r *VSphereMachineTemplate) ValidateCreate() error {
+	allErrs := validateMachineTemplateSpec(r.Spec.Template.Spec)
+	return aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, allErrs)
+}
+
+// validateMachineTemplateSpec validates the spec of the machines, or of the
+// VMs of a pool, created from a template at spec.template.spec.
+func validateMachineTemplateSpec(spec VSphereMachineSpec) field.ErrorList {
 	var allErrs field.ErrorList
-	spec := r.Spec.Template.Spec
 
 	if spec.Network.PreferredAPIServerCIDR != "" {
 		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "PreferredAPIServerCIDR"), spec.Network.PreferredAPIServerCIDR, "cannot be set, as it will be removed and is no longer used"))
 	}
 
+	// The machines without a template or an image are cloned from the
+	// default template of the VSphereProviderConfig for their Kubernetes
+	// version.
+	if spec.Template != "" && spec.Image != "" {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "template", "spec", "image"), "cannot be set along with template"))
+	}
+	if spec.ImageMapping != "" && (spec.Template != "" || spec.Image != "") {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "template", "spec", "imageMapping"), "cannot be set along with template or image"))
+	}
+
 	if spec.ProviderID != nil {
 		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "template", "spec", "providerID"), "cannot be set in templates"))
 	}
@@ -52,19 +72,67 @@ func (r *VSphereMachineTemplate) ValidateCreate() error {
 		if len(device.IPAddrs) != 0 {
 			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "template", "spec", "network", "devices", "ipAddrs"), "cannot be set in templates"))
 		}
+		if device.MACAddr != "" {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "template", "spec", "network", "devices", "macAddr"), "cannot be set in templates"))
+		}
 	}
 
-	return aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, allErrs)
+	allErrs = append(allErrs, validatePortGroups(spec.Network.Devices, field.NewPath("spec", "template", "spec", "network", "devices"))...)
+	allErrs = append(allErrs, validateNetworkDevices(spec.Network, field.NewPath("spec", "template", "spec", "network"))...)
+	allErrs = append(allErrs, validateAddressFilters(spec.Network, field.NewPath("spec", "template", "spec", "network"))...)
+	allErrs = append(allErrs, validateAddressWait(spec.Network, field.NewPath("spec", "template", "spec", "network"))...)
+	allErrs = append(allErrs, validateCloneMode(&spec.VirtualMachineCloneSpec, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateLatencyTuning(&spec.VirtualMachineCloneSpec, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validatePowerOffMode(spec.PowerOffMode, spec.GuestSoftPowerOffTimeout, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateSnapshotSchedule(spec.SnapshotSchedule, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateFailureRetryPolicy(spec.FailureRetryPolicy, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validatePCIDevices(spec.PciDevices, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateFirmware(spec.Firmware, spec.SecureBoot, spec.VTPM, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateMetadataPropagation(spec.MetadataPropagation, field.NewPath("spec", "template", "spec"))...)
+	allErrs = append(allErrs, validateDeploymentZones(spec.DeploymentZones, field.NewPath("spec", "template", "spec"))...)
+	return allErrs
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
+// The spec of the machines is immutable, as changes are not rolled out to the
+// machines created from the template. Only the metadata of the machines can
+// be changed.
+//nolint:forcetypeassert
 func (r *VSphereMachineTemplate) ValidateUpdate(old runtime.Object) error {
-	oldVSphereMachineTemplate := old.(*VSphereMachineTemplate) //nolint:forcetypeassert
-	if !reflect.DeepEqual(r.Spec, oldVSphereMachineTemplate.Spec) {
-		return field.Forbidden(field.NewPath("spec"), "VSphereMachineTemplateSpec is immutable")
+	newVSphereMachineTemplate, err := runtime.DefaultUnstructuredConverter.ToUnstructured(r)
+	if err != nil {
+		return apierrors.NewInternalError(errors.Wrap(err, "failed to convert new VSphereMachineTemplate to unstructured object"))
+	}
+	oldVSphereMachineTemplate, err := runtime.DefaultUnstructuredConverter.ToUnstructured(old)
+	if err != nil {
+		return apierrors.NewInternalError(errors.Wrap(err, "failed to convert old VSphereMachineTemplate to unstructured object"))
 	}
 
-	return nil
+	newSpec, _, _ := unstructured.NestedMap(newVSphereMachineTemplate, "spec", "template", "spec")
+	oldSpec, _, _ := unstructured.NestedMap(oldVSphereMachineTemplate, "spec", "template", "spec")
+
+	// Each modified field is reported, so users know which changes require a
+	// new revision of the template.
+	keys := map[string]struct{}{}
+	for key := range newSpec {
+		keys[key] = struct{}{}
+	}
+	for key := range oldSpec {
+		keys[key] = struct{}{}
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	var allErrs field.ErrorList
+	for _, key := range sortedKeys {
+		if !reflect.DeepEqual(newSpec[key], oldSpec[key]) {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "template", "spec", key), "cannot be modified, create a new VSphereMachineTemplate and reference it from the MachineDeployment or the control plane to roll out the change"))
+		}
+	}
+	return aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, allErrs)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
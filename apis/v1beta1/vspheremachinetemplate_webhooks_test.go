@@ -46,6 +46,24 @@ func TestVSphereMachineTemplate_ValidateCreate(t *testing.T) {
 			vsphereMachine: createVSphereMachineTemplate("foo.com", nil, "", []string{"192.168.0.1/32", "192.168.0.3"}),
 			wantErr:        true,
 		},
+		{
+			name: "template is not set, the default template is used",
+			vsphereMachine: func() *VSphereMachineTemplate {
+				m := createVSphereMachineTemplate("foo.com", nil, "", []string{})
+				m.Spec.Template.Spec.Template = ""
+				return m
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "image mapping set along with a template",
+			vsphereMachine: func() *VSphereMachineTemplate {
+				m := createVSphereMachineTemplate("foo.com", nil, "", []string{})
+				m.Spec.Template.Spec.ImageMapping = "ubuntu"
+				return m
+			}(),
+			wantErr: true,
+		},
 		{
 			name:           "successful VSphereMachine creation",
 			vsphereMachine: createVSphereMachineTemplate("foo.com", nil, "", []string{"192.168.0.1/32", "192.168.0.3/32"}),
@@ -93,6 +111,17 @@ func TestVSphereMachineTemplate_ValidateUpdate(t *testing.T) {
 			vsphereMachine:    createVSphereMachineTemplate("baz.com", &someProviderID, "", []string{"192.168.0.1/32", "192.168.0.10/32"}),
 			wantErr:           true,
 		},
+		{
+			name:              "updating the metadata of the machines can be done",
+			oldVSphereMachine: createVSphereMachineTemplate("foo.com", nil, "", nil),
+			vsphereMachine: func() *VSphereMachineTemplate {
+				m := createVSphereMachineTemplate("foo.com", nil, "", nil)
+				m.Labels = map[string]string{"revision": "2"}
+				m.Spec.Template.ObjectMeta.Labels = map[string]string{"node-role": "worker"}
+				return m
+			}(),
+			wantErr: false,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -106,6 +135,21 @@ func TestVSphereMachineTemplate_ValidateUpdate(t *testing.T) {
 	}
 }
 
+func TestVSphereMachineTemplate_ValidateUpdateReportsModifiedFields(t *testing.T) {
+	g := NewWithT(t)
+	oldVSphereMachineTemplate := createVSphereMachineTemplate("foo.com", nil, "", nil)
+	vsphereMachineTemplate := createVSphereMachineTemplate("baz.com", nil, "", nil)
+	vsphereMachineTemplate.Spec.Template.Spec.Template = "ubuntu-template-v2"
+	vsphereMachineTemplate.Spec.Template.Spec.Network.Devices = []NetworkDeviceSpec{{NetworkName: "vm-network", DHCP4: true}}
+
+	err := vsphereMachineTemplate.ValidateUpdate(oldVSphereMachineTemplate)
+	g.Expect(err).To(HaveOccurred())
+	for _, path := range []string{"spec.template.spec.network", "spec.template.spec.server", "spec.template.spec.template"} {
+		g.Expect(err.Error()).To(ContainSubstring(path + ": Forbidden: cannot be modified, create a new VSphereMachineTemplate"))
+	}
+	g.Expect(err.Error()).NotTo(ContainSubstring("spec.template.spec.numCPUs"))
+}
+
 func createVSphereMachineTemplate(server string, providerID *string, preferredAPIServerCIDR string, ips []string) *VSphereMachineTemplate {
 	VSphereMachineTemplate := &VSphereMachineTemplate{
 		Spec: VSphereMachineTemplateSpec{
@@ -113,7 +157,8 @@ func createVSphereMachineTemplate(server string, providerID *string, preferredAP
 				Spec: VSphereMachineSpec{
 					ProviderID: providerID,
 					VirtualMachineCloneSpec: VirtualMachineCloneSpec{
-						Server: server,
+						Server:   server,
+						Template: "ubuntu-template",
 						Network: NetworkSpec{
 							PreferredAPIServerCIDR: preferredAPIServerCIDR,
 							Devices:                []NetworkDeviceSpec{},
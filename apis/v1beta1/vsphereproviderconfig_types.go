@@ -0,0 +1,118 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ProviderConfigName is the name of the VSphereProviderConfig read by the
+// controller manager. VSphereProviderConfigs of other names are rejected.
+const ProviderConfigName = "default"
+
+// VSphereProviderConfigSpec defines the defaults of the controller manager.
+type VSphereProviderConfigSpec struct {
+	// Templates are the default templates of the machines, and of the VMs of
+	// the machine pools, which set neither a template nor an image, per
+	// Kubernetes version. A Kubernetes version of the form vMAJOR.MINOR
+	// matches all the patch versions of the minor version, and is used when
+	// no entry matches the version of the machine exactly.
+	// +optional
+	Templates []KubernetesVersionTemplate `json:"templates,omitempty"`
+
+	// Datastore is the default datastore of the VMs of the machines, and of
+	// the machine pools, which do not set one and whose failure domain does
+	// not set one either.
+	// +optional
+	Datastore string `json:"datastore,omitempty"`
+
+	// Network is the default network of the network devices of the VMs of
+	// the machines, and of the machine pools, which do not set one and are
+	// not attached to the network of their failure domain or to the NSX-T
+	// segment of their cluster.
+	// +optional
+	Network string `json:"network,omitempty"`
+
+	// KeepAlive overrides the keepalive of the vCenter sessions set by the
+	// flags of the controller manager.
+	// +optional
+	KeepAlive *VCenterKeepAlive `json:"keepAlive,omitempty"`
+
+	// RateLimit overrides the rate limit of the vCenter API calls set by the
+	// flags of the controller manager. The rate limit of a
+	// VSphereClusterIdentity takes precedence.
+	// +optional
+	RateLimit *VCenterRateLimit `json:"rateLimit,omitempty"`
+}
+
+// KubernetesVersionTemplate defines the template of a Kubernetes version.
+type KubernetesVersionTemplate struct {
+	// KubernetesVersion is the Kubernetes version of the machines, e.g.
+	// v1.23.5, or v1.23 for all the patch versions of the minor version.
+	// +kubebuilder:validation:MinLength=1
+	KubernetesVersion string `json:"kubernetesVersion"`
+
+	// Template is the name or inventory path of the template the VMs of the
+	// Kubernetes version are cloned from.
+	// +kubebuilder:validation:MinLength=1
+	Template string `json:"template"`
+}
+
+// VCenterKeepAlive defines the keepalive of the vCenter sessions.
+type VCenterKeepAlive struct {
+	// Duration is the idle time in between the keepalive requests of a
+	// session.
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// Retries is the number of times a keepalive failing with a transient
+	// error is retried before it stops.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Retries *int32 `json:"retries,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=vsphereproviderconfigs,scope=Cluster,categories=cluster-api
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Datastore",type="string",JSONPath=".spec.datastore",description="Default datastore of the VMs"
+// +kubebuilder:printcolumn:name="Network",type="string",JSONPath=".spec.network",description="Default network of the VMs"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Time duration since creation of VSphereProviderConfig"
+
+// VSphereProviderConfig is the Schema for the vsphereproviderconfigs API,
+// which holds the defaults of the controller manager shared by all the
+// clusters. The VSphereMachineTemplates, VSphereMachines and
+// VSphereMachinePools can omit the fields it defaults.
+type VSphereProviderConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec VSphereProviderConfigSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// VSphereProviderConfigList contains a list of VSphereProviderConfig.
+type VSphereProviderConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VSphereProviderConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VSphereProviderConfig{}, &VSphereProviderConfigList{})
+}
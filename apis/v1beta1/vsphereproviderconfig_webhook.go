@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+func (r *VSphereProviderConfig) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-infrastructure-cluster-x-k8s-io-v1beta1-vsphereproviderconfig,mutating=false,failurePolicy=fail,matchPolicy=Equivalent,groups=infrastructure.cluster.x-k8s.io,resources=vsphereproviderconfigs,versions=v1beta1,name=validation.vsphereproviderconfig.infrastructure.x-k8s.io,sideEffects=None,admissionReviewVersions=v1beta1
+
+var _ webhook.Validator = &VSphereProviderConfig{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
+func (r *VSphereProviderConfig) ValidateCreate() error {
+	var allErrs field.ErrorList
+	if r.Name != ProviderConfigName {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("metadata", "name"), r.Name, fmt.Sprintf("must be %s, as the controller manager only reads the VSphereProviderConfig of this name", ProviderConfigName)))
+	}
+	allErrs = append(allErrs, r.validateSpec()...)
+	return aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, allErrs)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
+func (r *VSphereProviderConfig) ValidateUpdate(old runtime.Object) error {
+	return aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, r.validateSpec())
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
+func (r *VSphereProviderConfig) ValidateDelete() error {
+	return nil
+}
+
+func (r *VSphereProviderConfig) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+
+	versions := map[string]bool{}
+	for i, template := range r.Spec.Templates {
+		if versions[template.KubernetesVersion] {
+			allErrs = append(allErrs, field.Duplicate(field.NewPath("spec", "templates").Index(i).Child("kubernetesVersion"), template.KubernetesVersion))
+		}
+		versions[template.KubernetesVersion] = true
+	}
+
+	if keepAlive := r.Spec.KeepAlive; keepAlive != nil && keepAlive.Duration != nil && keepAlive.Duration.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "keepAlive", "duration"), keepAlive.Duration.Duration.String(), "must be positive"))
+	}
+	return allErrs
+}
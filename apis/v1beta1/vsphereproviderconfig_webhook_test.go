@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestVSphereProviderConfig_Validate(t *testing.T) {
+	providerConfig := func(name string, modify func(*VSphereProviderConfigSpec)) *VSphereProviderConfig {
+		c := &VSphereProviderConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: VSphereProviderConfigSpec{
+				Templates: []KubernetesVersionTemplate{
+					{KubernetesVersion: "v1.23", Template: "ubuntu-2004-kube-v1.23"},
+					{KubernetesVersion: "v1.23.5", Template: "ubuntu-2004-kube-v1.23.5"},
+				},
+				Datastore: "ds0",
+			},
+		}
+		modify(&c.Spec)
+		return c
+	}
+
+	tests := []struct {
+		name           string
+		providerConfig *VSphereProviderConfig
+		wantErr        bool
+	}{
+		{
+			name:           "valid provider config",
+			providerConfig: providerConfig(ProviderConfigName, func(*VSphereProviderConfigSpec) {}),
+			wantErr:        false,
+		},
+		{
+			name:           "name is not default",
+			providerConfig: providerConfig("other", func(*VSphereProviderConfigSpec) {}),
+			wantErr:        true,
+		},
+		{
+			name: "duplicate Kubernetes version",
+			providerConfig: providerConfig(ProviderConfigName, func(spec *VSphereProviderConfigSpec) {
+				spec.Templates = append(spec.Templates, KubernetesVersionTemplate{KubernetesVersion: "v1.23", Template: "other"})
+			}),
+			wantErr: true,
+		},
+		{
+			name: "keepalive duration is not positive",
+			providerConfig: providerConfig(ProviderConfigName, func(spec *VSphereProviderConfigSpec) {
+				spec.KeepAlive = &VCenterKeepAlive{Duration: &metav1.Duration{Duration: -time.Minute}}
+			}),
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			err := tc.providerConfig.ValidateCreate()
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
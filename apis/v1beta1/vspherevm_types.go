@@ -18,6 +18,8 @@ limitations under the License.
 package v1beta1
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
@@ -28,6 +30,156 @@ const (
 	// VMFinalizer allows the reconciler to clean up resources associated
 	// with a VSphereVM before removing it from the API Server.
 	VMFinalizer = "vspherevm.infrastructure.cluster.x-k8s.io"
+
+	// VMRestartAnnotation requests a restart of the guest OS of a VSphereVM.
+	// The annotation is removed once the restart has been issued.
+	VMRestartAnnotation = "vspherevm.infrastructure.cluster.x-k8s.io/restart"
+
+	// VMHardwareUpgradeAnnotation requests an upgrade of the hardware version
+	// of a running VSphereVM to its HardwareVersion at the next restart of
+	// its guest OS. The annotation is removed once the upgrade has been
+	// scheduled.
+	VMHardwareUpgradeAnnotation = "vspherevm.infrastructure.cluster.x-k8s.io/upgrade-hardware"
+
+	// VMPreDeleteBackupAnnotation requests a backup of the VM of a VSphereVM
+	// once it is powered off and before it is destroyed, e.g. for post-mortem
+	// analysis of failed nodes. The value is either VMPreDeleteBackupSnapshot,
+	// or the datastore path of a directory the VM is exported to as OVF, e.g.
+	// "[datastore1] backups". The annotation of a VSphereMachine is copied to
+	// its VSphereVM.
+	VMPreDeleteBackupAnnotation = "vspherevm.infrastructure.cluster.x-k8s.io/pre-delete-backup"
+
+	// VMRetryFailureAnnotation requests a new attempt at reconciling a
+	// failed VSphereVM, once the cause of its failure is fixed. The failure
+	// and the annotation are removed, and the count of the retries of the
+	// FailureRetryPolicy is reset.
+	VMRetryFailureAnnotation = "vspherevm.infrastructure.cluster.x-k8s.io/retry-failure"
+
+	// VMTaskRefAnnotation records the in-flight vCenter task of a VSphereVM,
+	// as the status is not moved along with the VSphereVM by clusterctl move.
+	// The task is tracked again once the cluster is unpaused, and the
+	// annotation removed once the task completes.
+	VMTaskRefAnnotation = "vspherevm.infrastructure.cluster.x-k8s.io/task-ref"
+
+	// VMSafeToMoveAnnotation reports whether a VSphereVM can be moved by
+	// clusterctl move, i.e. "false" as long as a vCenter task of its VM is in
+	// flight and "true" otherwise.
+	VMSafeToMoveAnnotation = "vspherevm.infrastructure.cluster.x-k8s.io/safe-to-move"
+
+	// BlockMoveAnnotation blocks clusterctl move while a vCenter task of the
+	// VM of a VSphereVM is in flight. It is removed once the task completes,
+	// including while the cluster is paused for the move.
+	BlockMoveAnnotation = "clusterctl.cluster.x-k8s.io/block-move"
+
+	// DefaultFailureRetryInterval is the default time waited before retrying
+	// a failed VSphereVM according to its FailureRetryPolicy.
+	DefaultFailureRetryInterval = 5 * time.Minute
+
+	// VMPreDeleteBackupSnapshot is the value of the VMPreDeleteBackupAnnotation
+	// requesting a full clone of the powered off VM, as a template named after
+	// the VSphereVM with the "-final" suffix in the folder of the VM.
+	VMPreDeleteBackupSnapshot = "snapshot"
+
+	// GuestSoftPowerOffDefaultTimeout is the default timeout to wait for
+	// shutdown finishes in the guest VM before powering off the VM forcibly.
+	// Only effective when the powerOffMode is set to trySoft.
+	GuestSoftPowerOffDefaultTimeout = 5 * time.Minute
+
+	// RetainedVMTagCategoryName is the name of the tag category of the tag
+	// attached to VMs retained on deletion.
+	RetainedVMTagCategoryName = "capv"
+
+	// RetainedVMTagName is the name of the tag attached to VMs retained on
+	// deletion.
+	RetainedVMTagName = "retained"
+
+	// OwnerTagCategoryName is the name of the tag category of the tags
+	// attached to the folders and resource pools created for a cluster. The
+	// tags are named after the namespace and the name of the cluster.
+	OwnerTagCategoryName = "capv-owner"
+
+	// TemplateBootstrapFormatsKey is the extra config key of a template
+	// listing the comma-separated bootstrap formats its guest OS supports,
+	// e.g. "cloud-config,ignition". Templates setting the key are not cloned
+	// for VSphereVMs whose bootstrap data has another format.
+	TemplateBootstrapFormatsKey = "capv.bootstrapFormats"
+)
+
+// VirtualMachinePowerOpMode represents the various power operation modes
+// when powering off a VM.
+// +kubebuilder:validation:Enum=hard;soft;trySoft
+type VirtualMachinePowerOpMode string
+
+const (
+	// VirtualMachinePowerOpModeHard indicates to perform power operations
+	// by directly powering off the VM.
+	VirtualMachinePowerOpModeHard VirtualMachinePowerOpMode = "hard"
+
+	// VirtualMachinePowerOpModeSoft indicates to perform power operations
+	// through the guest OS by way of VMware Tools. The VM is never powered
+	// off forcibly.
+	VirtualMachinePowerOpModeSoft VirtualMachinePowerOpMode = "soft"
+
+	// VirtualMachinePowerOpModeTrySoft indicates to attempt a shutdown of
+	// the guest OS first and to power off the VM if it is still powered on
+	// after GuestSoftPowerOffTimeout.
+	VirtualMachinePowerOpModeTrySoft VirtualMachinePowerOpMode = "trySoft"
+)
+
+// VirtualMachineDeletionPolicy describes what happens to a VM when it is
+// deleted.
+// +kubebuilder:validation:Enum=Delete;Retain;RetainDisks
+type VirtualMachineDeletionPolicy string
+
+const (
+	// VirtualMachineDeletionPolicyDelete indicates to destroy the VM along
+	// with all of its disks.
+	VirtualMachineDeletionPolicyDelete VirtualMachineDeletionPolicy = "Delete"
+
+	// VirtualMachineDeletionPolicyRetain indicates to leave the VM powered
+	// off and tagged with the RetainedVMTagName tag instead of destroying it.
+	VirtualMachineDeletionPolicyRetain VirtualMachineDeletionPolicy = "Retain"
+
+	// VirtualMachineDeletionPolicyRetainDisks indicates to detach and keep
+	// the data disks of the VM, that is all disks but the first one, before
+	// destroying the VM.
+	VirtualMachineDeletionPolicyRetainDisks VirtualMachineDeletionPolicy = "RetainDisks"
+)
+
+// VirtualMachineDriftPolicy describes what happens when the configuration of
+// a VM drifts from its spec.
+// +kubebuilder:validation:Enum=Ignore;Warn;Revert
+type VirtualMachineDriftPolicy string
+
+const (
+	// VirtualMachineDriftPolicyIgnore indicates to not compare the
+	// configuration of the VM with its spec.
+	VirtualMachineDriftPolicyIgnore VirtualMachineDriftPolicy = "Ignore"
+
+	// VirtualMachineDriftPolicyWarn indicates to report the drift in the
+	// status and the SpecSynced condition.
+	VirtualMachineDriftPolicyWarn VirtualMachineDriftPolicy = "Warn"
+
+	// VirtualMachineDriftPolicyRevert indicates to reconfigure the VM back
+	// to its spec where possible, and to report the VM for remediation
+	// otherwise.
+	VirtualMachineDriftPolicyRevert VirtualMachineDriftPolicy = "Revert"
+)
+
+// VirtualMachinePowerRecoveryPolicy describes what happens when a VM is found
+// powered off, or suspended, after it was powered on.
+// +kubebuilder:validation:Enum=PowerOn;Report
+type VirtualMachinePowerRecoveryPolicy string
+
+const (
+	// VirtualMachinePowerRecoveryPolicyPowerOn indicates to power the VM
+	// back on.
+	VirtualMachinePowerRecoveryPolicyPowerOn VirtualMachinePowerRecoveryPolicy = "PowerOn"
+
+	// VirtualMachinePowerRecoveryPolicyReport indicates to only report the
+	// VM in the PoweredOn and VMProvisioned conditions, and to leave it
+	// powered off.
+	VirtualMachinePowerRecoveryPolicyReport VirtualMachinePowerRecoveryPolicy = "Report"
 )
 
 // VSphereVMSpec defines the desired state of VSphereVM.
@@ -47,6 +199,196 @@ type VSphereVMSpec struct {
 	// this CRD as unstructured data.
 	// +optional
 	BiosUUID string `json:"biosUUID,omitempty"`
+
+	// InstanceUUID is the instance UUID of a pre-existing VM adopted by this
+	// VSphereVM. It is only used to find the VM if BiosUUID is not set.
+	// Defaults to the UID of the VSphereVM, which is the instance UUID
+	// assigned to cloned VMs.
+	// +optional
+	InstanceUUID string `json:"instanceUUID,omitempty"`
+
+	// PowerOffMode describes the desired behavior when powering off a VM
+	// before it is deleted.
+	//
+	// There are three, supported power off modes: hard, soft, and
+	// trySoft. The first mode, hard, is the equivalent of a physical
+	// system's power cord being ripped from the wall. The soft mode
+	// requires the VM's guest to have VM Tools installed and attempts to
+	// gracefully shut down the VM. Its variant, trySoft, first attempts
+	// a graceful shutdown, and if that fails or the VM is not in a powered off
+	// state after reaching the GuestSoftPowerOffTimeout, the VM is halted.
+	//
+	// Defaults to hard.
+	// +optional
+	PowerOffMode VirtualMachinePowerOpMode `json:"powerOffMode,omitempty"`
+
+	// GuestSoftPowerOffTimeout sets the wait timeout for shutdown in the VM guest.
+	// The VM will be powered off forcibly after the timeout if the VM is still
+	// up and running when the PowerOffMode is set to trySoft.
+	//
+	// This parameter only applies when the PowerOffMode is set to trySoft.
+	//
+	// If omitted, the timeout defaults to 5 minutes.
+	// +optional
+	GuestSoftPowerOffTimeout *metav1.Duration `json:"guestSoftPowerOffTimeout,omitempty"`
+
+	// DeletionPolicy describes what happens to the VM when it is deleted.
+	//
+	// There are three supported deletion policies: Delete, Retain, and
+	// RetainDisks. Delete destroys the VM along with its disks. Retain
+	// powers off the VM and tags it as retained instead of destroying it,
+	// so it can be used for forensic analysis. RetainDisks detaches and
+	// keeps the data disks of the VM before destroying it, so their data
+	// can be recovered.
+	//
+	// Defaults to Delete.
+	// +optional
+	DeletionPolicy VirtualMachineDeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// DriftPolicy describes what happens when the configuration of the VM
+	// is changed outside of Cluster API and no longer matches the spec.
+	//
+	// There are three supported drift policies: Ignore, Warn, and Revert.
+	// Ignore does not compare the VM with the spec. Warn lists the
+	// differences in Status.Drift and sets the SpecSynced condition to
+	// false. Revert additionally reconfigures the custom VMX keys and the
+	// networks of the VM back to the spec; other differences cannot be
+	// reverted in place and set the SpecSynced condition of the node of the
+	// machine to false, so a MachineHealthCheck can remediate it.
+	//
+	// Defaults to Warn.
+	// +optional
+	DriftPolicy VirtualMachineDriftPolicy `json:"driftPolicy,omitempty"`
+
+	// PowerRecoveryPolicy describes what happens when the VM is found
+	// powered off, or suspended, after it was powered on, e.g. as its host
+	// crashed or an administrator powered it off.
+	//
+	// There are two supported power recovery policies: PowerOn and Report.
+	// Both set the PoweredOn condition to false with the PoweredOff reason
+	// and emit a warning Event. PowerOn then powers the VM back on. Report
+	// leaves the VM powered off and sets the VMProvisioned condition to
+	// false, until the VM is powered on outside of Cluster API or the
+	// policy is changed.
+	//
+	// Defaults to PowerOn.
+	// +optional
+	PowerRecoveryPolicy VirtualMachinePowerRecoveryPolicy `json:"powerRecoveryPolicy,omitempty"`
+
+	// SnapshotSchedule takes crash-consistent snapshots of the VM
+	// periodically through VSphereVMSnapshots, and prunes the scheduled
+	// snapshots beyond the retention count.
+	// +optional
+	SnapshotSchedule *VirtualMachineSnapshotSchedule `json:"snapshotSchedule,omitempty"`
+
+	// FailureRetryPolicy retries the VM when its reconciliation fails with
+	// one of the failure reasons of the policy, up to a number of attempts,
+	// instead of the failure being terminal. The failures retried are not
+	// reported to the VSphereMachine, and thus to the Machine, until the
+	// attempts are exhausted.
+	// +optional
+	FailureRetryPolicy *VirtualMachineFailureRetryPolicy `json:"failureRetryPolicy,omitempty"`
+
+	// Placement spreads the VMs of the cluster with the same placement across
+	// several resource pools. The VM is cloned into the resource pool with the
+	// fewest VMs of the cluster, which is recorded in Status.ResourcePool, and
+	// the ResourcePool is ignored.
+	// +optional
+	Placement *VirtualMachinePlacement `json:"placement,omitempty"`
+}
+
+// VirtualMachineFailureRetryPolicy describes which failures of a VM are
+// retried.
+type VirtualMachineFailureRetryPolicy struct {
+	// Reasons are the failure reasons retried, e.g. CreateError. All the
+	// failures are retried when empty.
+	// +optional
+	Reasons []errors.MachineStatusError `json:"reasons,omitempty"`
+
+	// MaxAttempts is the number of times a failed VM is retried before its
+	// failure is terminal.
+	// +kubebuilder:validation:Minimum=1
+	MaxAttempts int32 `json:"maxAttempts"`
+
+	// Interval is the time waited before retrying a failed VM. The VM is
+	// retried right away when its spec is changed.
+	//
+	// Defaults to 5m.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// VirtualMachinePlacement defines the placement targets a VM is spread
+// across.
+type VirtualMachinePlacement struct {
+	// ResourcePools is the list of resource pools the VMs are spread across
+	// in a round-robin fashion. A compute cluster is targeted with its root
+	// resource pool, e.g. /dc0/host/cluster0/Resources.
+	// +kubebuilder:validation:MinItems=1
+	ResourcePools []string `json:"resourcePools"`
+}
+
+// MigrationType is the type of a migration of a VM.
+type MigrationType string
+
+const (
+	// HostMigration is a migration of a VM to another ESXi host, i.e. a
+	// vMotion.
+	HostMigration MigrationType = "Host"
+
+	// StorageMigration is a migration of the files of a VM to another
+	// datastore, i.e. a Storage vMotion.
+	StorageMigration MigrationType = "Storage"
+)
+
+// VirtualMachineMigration describes a migration of a VM observed by the
+// controller.
+type VirtualMachineMigration struct {
+	// Type is the type of the migration.
+	// +kubebuilder:validation:Enum=Host;Storage
+	Type MigrationType `json:"type"`
+
+	// Source is the name of the host or datastore the VM was migrated from.
+	Source string `json:"source"`
+
+	// Target is the name of the host or datastore the VM was migrated to.
+	Target string `json:"target"`
+
+	// Time is when the migration was observed.
+	Time metav1.Time `json:"time"`
+}
+
+// VirtualMachineDrift describes a difference between the spec of a VSphereVM
+// and the configuration of its virtual machine, e.g. after the VM was changed
+// outside of Cluster API.
+type VirtualMachineDrift struct {
+	// Field is the path of the field of the spec that drifted, e.g. numCPUs
+	// or network.devices[0].networkName.
+	Field string `json:"field"`
+
+	// Desired is the value of the field in the spec.
+	Desired string `json:"desired"`
+
+	// Actual is the value of the field observed on the VM.
+	Actual string `json:"actual"`
+}
+
+// GuestToolsStatus is the status of the VMware Tools of a VM.
+type GuestToolsStatus struct {
+	// RunningStatus is whether the VMware Tools are running, e.g.
+	// guestToolsRunning or guestToolsNotRunning.
+	// +optional
+	RunningStatus string `json:"runningStatus,omitempty"`
+
+	// VersionStatus is the status of the version of the VMware Tools, e.g.
+	// guestToolsCurrent, guestToolsNeedUpgrade, guestToolsNotInstalled or
+	// guestToolsUnmanaged for open-vm-tools.
+	// +optional
+	VersionStatus string `json:"versionStatus,omitempty"`
+
+	// Version is the version of the VMware Tools.
+	// +optional
+	Version string `json:"version,omitempty"`
 }
 
 // VSphereVMStatus defines the observed state of VSphereVM
@@ -63,6 +405,24 @@ type VSphereVMStatus struct {
 	// +optional
 	Addresses []string `json:"addresses,omitempty"`
 
+	// MachineAddresses are the addresses of the VM typed as Machine
+	// addresses, i.e. its IP addresses as internal or external IPs depending
+	// on whether they belong to the internal IP CIDRs of the manager, and the
+	// host name and the DNS names reported by vm-tools.
+	// +optional
+	MachineAddresses []clusterv1.MachineAddress `json:"machineAddresses,omitempty"`
+
+	// AddressWaitStartTime is when the VM started waiting for its network
+	// addresses. It is unset once the VM has them.
+	// +optional
+	AddressWaitStartTime *metav1.Time `json:"addressWaitStartTime,omitempty"`
+
+	// IPAllocations are the IP addresses allocated to the network devices of
+	// the VM from their VSphereIPPool, which are released once the VM is
+	// deleted.
+	// +optional
+	IPAllocations []IPAllocation `json:"ipAllocations,omitempty"`
+
 	// CloneMode is the type of clone operation used to clone this VM. Since
 	// LinkedMode is the default but fails gracefully if the source of the
 	// clone has no snapshots, this field may be used to determine the actual
@@ -75,6 +435,57 @@ type VSphereVMStatus struct {
 	// +optional
 	Snapshot string `json:"snapshot,omitempty"`
 
+	// DiskProvisioningType is the provisioning type applied to the disks
+	// cloned from the template. It is empty if the disks kept the provisioning
+	// type of the template, as DiskProvisioningType is not set or is ignored
+	// for linked clones and on vSAN datastores.
+	// +optional
+	DiskProvisioningType DiskProvisioningMode `json:"diskProvisioningType,omitempty"`
+
+	// ResourcePool is the resource pool the VM was placed in according to
+	// the Placement.
+	// +optional
+	ResourcePool string `json:"resourcePool,omitempty"`
+
+	// Host is the name of the ESXi host the VM runs on.
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// ComputeCluster is the name of the compute cluster of the ESXi host the
+	// VM runs on, if any.
+	// +optional
+	ComputeCluster string `json:"computeCluster,omitempty"`
+
+	// CurrentResourcePool is the name of the resource pool the VM belongs
+	// to, which changes when the VM is migrated to another compute cluster.
+	// +optional
+	CurrentResourcePool string `json:"currentResourcePool,omitempty"`
+
+	// Datastore is the name of the datastore the configuration files of the
+	// VM are stored on.
+	// +optional
+	Datastore string `json:"datastore,omitempty"`
+
+	// SelectedDatastore is the datastore the VM was created on, chosen from
+	// the Datastore and the fallback Datastores of the VSphereVM.
+	// +optional
+	SelectedDatastore string `json:"selectedDatastore,omitempty"`
+
+	// Migrations is the list of the most recent migrations of the VM across
+	// hosts and datastores, oldest first.
+	// +optional
+	Migrations []VirtualMachineMigration `json:"migrations,omitempty"`
+
+	// Drift is the list of differences between the spec and the
+	// configuration of the VM observed at the last reconciliation.
+	// +optional
+	Drift []VirtualMachineDrift `json:"drift,omitempty"`
+
+	// GuestTools is the status of the VMware Tools of the VM observed at the
+	// last reconciliation.
+	// +optional
+	GuestTools *GuestToolsStatus `json:"guestTools,omitempty"`
+
 	// RetryAfter tracks the time we can retry queueing a task
 	// +optional
 	RetryAfter metav1.Time `json:"retryAfter,omitempty"`
@@ -120,6 +531,12 @@ type VSphereVMStatus struct {
 	// +optional
 	FailureMessage *string `json:"failureMessage,omitempty"`
 
+	// FailureRetries is the number of times the VSphereVM was retried after
+	// a failure according to its FailureRetryPolicy. It is reset once the VM
+	// is ready.
+	// +optional
+	FailureRetries int32 `json:"failureRetries,omitempty"`
+
 	// Conditions defines current service state of the VSphereVM.
 	// +optional
 	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
@@ -20,10 +20,13 @@ import (
 	"fmt"
 	"net"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -71,6 +74,30 @@ func (r *VSphereVM) ValidateCreate() error {
 	if r.Spec.OS == Windows && len(r.Name) > 15 {
 		allErrs = append(allErrs, field.Invalid(field.NewPath("name"), r.Name, "name has to be less than 16 characters for Windows VM"))
 	}
+	// The name is used as the computer name of Windows VMs
+	if r.Spec.OS == Windows && strings.Contains(r.Name, ".") {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("name"), r.Name, "name cannot contain dots for Windows VM"))
+	}
+
+	// VSphereVMs without a template adopt the VM identified by its UUIDs.
+	if spec.Template == "" && spec.BiosUUID == "" && spec.InstanceUUID == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("spec", "template"), "template is required unless biosUUID or instanceUUID identify a VM to adopt"))
+	}
+
+	allErrs = append(allErrs, validateMACAddrs(spec.Network.Devices, field.NewPath("spec", "network", "devices"))...)
+	allErrs = append(allErrs, validatePortGroups(spec.Network.Devices, field.NewPath("spec", "network", "devices"))...)
+	allErrs = append(allErrs, validateNetworkDevices(spec.Network, field.NewPath("spec", "network"))...)
+	allErrs = append(allErrs, validateAddressFilters(spec.Network, field.NewPath("spec", "network"))...)
+	allErrs = append(allErrs, validateAddressWait(spec.Network, field.NewPath("spec", "network"))...)
+	allErrs = append(allErrs, validateCloneMode(&spec.VirtualMachineCloneSpec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateLatencyTuning(&spec.VirtualMachineCloneSpec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validatePowerOffMode(spec.PowerOffMode, spec.GuestSoftPowerOffTimeout, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateSnapshotSchedule(spec.SnapshotSchedule, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateFailureRetryPolicy(spec.FailureRetryPolicy, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validatePreDeleteBackup(r.Annotations, field.NewPath("metadata", "annotations"))...)
+	allErrs = append(allErrs, validatePCIDevices(spec.PciDevices, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateFirmware(spec.Firmware, spec.SecureBoot, spec.VTPM, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateMetadataPropagation(spec.MetadataPropagation, field.NewPath("spec"))...)
 	return aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, allErrs)
 }
 
@@ -95,10 +122,65 @@ func (r *VSphereVM) ValidateUpdate(old runtime.Object) error {
 	delete(oldVSphereVMSpec, "biosUUID")
 	delete(newVSphereVMSpec, "biosUUID")
 
+	// allow the instance UUID to be recorded once, before the VSphereVM is
+	// moved by clusterctl
+	if _, ok := oldVSphereVMSpec["instanceUUID"]; !ok {
+		delete(newVSphereVMSpec, "instanceUUID")
+	}
+
 	// allow changes to bootstrapRef
 	delete(oldVSphereVMSpec, "bootstrapRef")
 	delete(newVSphereVMSpec, "bootstrapRef")
 
+	// allow changes to the power off behavior
+	delete(oldVSphereVMSpec, "powerOffMode")
+	delete(newVSphereVMSpec, "powerOffMode")
+	delete(oldVSphereVMSpec, "guestSoftPowerOffTimeout")
+	delete(newVSphereVMSpec, "guestSoftPowerOffTimeout")
+
+	// allow changes to the deletion policy
+	delete(oldVSphereVMSpec, "deletionPolicy")
+	delete(newVSphereVMSpec, "deletionPolicy")
+
+	// allow changes to the drift policy
+	delete(oldVSphereVMSpec, "driftPolicy")
+	delete(newVSphereVMSpec, "driftPolicy")
+
+	// allow changes to the power recovery policy
+	delete(oldVSphereVMSpec, "powerRecoveryPolicy")
+	delete(newVSphereVMSpec, "powerRecoveryPolicy")
+
+	// allow changes to the snapshot schedule
+	delete(oldVSphereVMSpec, "snapshotSchedule")
+	delete(newVSphereVMSpec, "snapshotSchedule")
+
+	// allow changes to the failure retry policy
+	delete(oldVSphereVMSpec, "failureRetryPolicy")
+	delete(newVSphereVMSpec, "failureRetryPolicy")
+
+	// allow changes to the resource allocation
+	delete(oldVSphereVMSpec, "resourceAllocation")
+	delete(newVSphereVMSpec, "resourceAllocation")
+
+	// allow increases of the number of CPUs, the memory size and the size of
+	// the OS disk, and upgrades of the hardware version
+	oldVM := old.(*VSphereVM)
+	allErrs = append(allErrs, validateResize(&oldVM.Spec.VirtualMachineCloneSpec, &r.Spec.VirtualMachineCloneSpec, field.NewPath("spec"))...)
+	for _, key := range resizableFields {
+		delete(oldVSphereVMSpec, key)
+		delete(newVSphereVMSpec, key)
+	}
+
+	// allow changes to the guestinfo variables of the VMs of load balancers,
+	// which are published to the VM in place
+	deleteLoadBalancerGuestInfo(oldVSphereVMSpec)
+	deleteLoadBalancerGuestInfo(newVSphereVMSpec)
+
+	allErrs = append(allErrs, validatePowerOffMode(r.Spec.PowerOffMode, r.Spec.GuestSoftPowerOffTimeout, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateSnapshotSchedule(r.Spec.SnapshotSchedule, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateFailureRetryPolicy(r.Spec.FailureRetryPolicy, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validatePreDeleteBackup(r.Annotations, field.NewPath("metadata", "annotations"))...)
+
 	newVSphereVMNetwork := newVSphereVMSpec["network"].(map[string]interface{})
 	oldVSphereVMNetwork := oldVSphereVMSpec["network"].(map[string]interface{})
 
@@ -106,6 +188,14 @@ func (r *VSphereVM) ValidateUpdate(old runtime.Object) error {
 	delete(oldVSphereVMNetwork, "devices")
 	delete(newVSphereVMNetwork, "devices")
 
+	// validate the network devices only when they are changed, so that
+	// VSphereVMs created before a rule was introduced can still be updated
+	if !reflect.DeepEqual(oldVM.Spec.Network.Devices, r.Spec.Network.Devices) {
+		allErrs = append(allErrs, validateMACAddrs(r.Spec.Network.Devices, field.NewPath("spec", "network", "devices"))...)
+		allErrs = append(allErrs, validatePortGroups(r.Spec.Network.Devices, field.NewPath("spec", "network", "devices"))...)
+		allErrs = append(allErrs, validateNetworkDevices(r.Spec.Network, field.NewPath("spec", "network"))...)
+	}
+
 	if !reflect.DeepEqual(oldVSphereVMSpec, newVSphereVMSpec) {
 		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec"), "cannot be modified"))
 	}
@@ -117,3 +207,343 @@ func (r *VSphereVM) ValidateUpdate(old runtime.Object) error {
 func (r *VSphereVM) ValidateDelete() error {
 	return nil
 }
+
+// resizableFields are the fields of the spec validated by validateResize.
+var resizableFields = []string{"numCPUs", "memoryMiB", "diskGiB", "hardwareVersion"}
+
+// validateResize rejects the changes of the resources of a virtual machine
+// which are not applied to it, i.e. decreases of the number of CPUs, the
+// memory size and the size of the OS disk, and downgrades of the hardware
+// version.
+func validateResize(oldSpec, newSpec *VirtualMachineCloneSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if newSpec.NumCPUs < oldSpec.NumCPUs {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("numCPUs"), newSpec.NumCPUs, fmt.Sprintf("cannot be decreased from %d", oldSpec.NumCPUs)))
+	}
+	if newSpec.MemoryMiB < oldSpec.MemoryMiB {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("memoryMiB"), newSpec.MemoryMiB, fmt.Sprintf("cannot be decreased from %d", oldSpec.MemoryMiB)))
+	}
+	if newSpec.DiskGiB < oldSpec.DiskGiB {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("diskGiB"), newSpec.DiskGiB, fmt.Sprintf("cannot be decreased from %d", oldSpec.DiskGiB)))
+	}
+	if hardwareVersionNumber(newSpec.HardwareVersion) < hardwareVersionNumber(oldSpec.HardwareVersion) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("hardwareVersion"), newSpec.HardwareVersion, fmt.Sprintf("cannot be downgraded from %s", oldSpec.HardwareVersion)))
+	}
+	return allErrs
+}
+
+// hardwareVersionNumber returns the number of a vmx-NN hardware version, or 0
+// if it is not set.
+func hardwareVersionNumber(version string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(version, "vmx-"))
+	return n
+}
+
+// deleteLoadBalancerGuestInfo removes the guestinfo variables of the VMs of
+// load balancers from the custom VMX keys of the unstructured spec.
+func deleteLoadBalancerGuestInfo(spec map[string]interface{}) {
+	keys, ok := spec["customVMXKeys"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for key := range keys {
+		if strings.HasPrefix(key, LoadBalancerGuestInfoPrefix) {
+			delete(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		delete(spec, "customVMXKeys")
+	}
+}
+
+func validatePowerOffMode(mode VirtualMachinePowerOpMode, timeout *metav1.Duration, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if timeout == nil {
+		return allErrs
+	}
+	if mode != VirtualMachinePowerOpModeTrySoft {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("guestSoftPowerOffTimeout"), "should only be set when powerOffMode is trySoft"))
+	}
+	if timeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("guestSoftPowerOffTimeout"), timeout.Duration.String(), "should be greater than 0"))
+	}
+	return allErrs
+}
+
+func validateSnapshotSchedule(schedule *VirtualMachineSnapshotSchedule, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if schedule == nil {
+		return allErrs
+	}
+	if schedule.Interval.Duration < time.Minute {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("snapshotSchedule", "interval"), schedule.Interval.Duration.String(), "should be at least 1m"))
+	}
+	return allErrs
+}
+
+func validateFailureRetryPolicy(policy *VirtualMachineFailureRetryPolicy, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if policy == nil || policy.Interval == nil {
+		return allErrs
+	}
+	if policy.Interval.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("failureRetryPolicy", "interval"), policy.Interval.Duration.String(), "should be greater than 0"))
+	}
+	return allErrs
+}
+
+func validatePreDeleteBackup(annotations map[string]string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	backup, ok := annotations[VMPreDeleteBackupAnnotation]
+	if !ok || backup == VMPreDeleteBackupSnapshot {
+		return allErrs
+	}
+	// The value is a datastore path, e.g. "[datastore1] backups".
+	if !strings.HasPrefix(backup, "[") || !strings.Contains(backup, "]") {
+		allErrs = append(allErrs, field.Invalid(fldPath.Key(VMPreDeleteBackupAnnotation), backup, fmt.Sprintf("should be %q or the datastore path of a directory", VMPreDeleteBackupSnapshot)))
+	}
+	return allErrs
+}
+
+func validatePCIDevices(devices []PCIDeviceSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, device := range devices {
+		devicePath := fldPath.Child("pciDevices").Index(i)
+		if device.VGPUProfile != "" {
+			if device.DeviceID != nil || device.VendorID != nil {
+				allErrs = append(allErrs, field.Forbidden(devicePath, "deviceId and vendorId should not be set with vGPUProfile"))
+			}
+			continue
+		}
+		if device.DeviceID == nil {
+			allErrs = append(allErrs, field.Required(devicePath.Child("deviceId"), "should be set when vGPUProfile is not set"))
+		}
+		if device.VendorID == nil {
+			allErrs = append(allErrs, field.Required(devicePath.Child("vendorId"), "should be set when vGPUProfile is not set"))
+		}
+	}
+	return allErrs
+}
+
+func validateFirmware(firmware Firmware, secureBoot, vtpm bool, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if secureBoot && firmware != FirmwareEFI {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("secureBoot"), "should only be set when firmware is efi"))
+	}
+	if vtpm && firmware != FirmwareEFI {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("vtpm"), "should only be set when firmware is efi"))
+	}
+	return allErrs
+}
+
+func validateMetadataPropagation(mappings []MetadataPropagationSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	tagCategories := map[string]bool{}
+	customAttributes := map[string]bool{}
+	for i, mapping := range mappings {
+		mappingPath := fldPath.Child("metadataPropagation").Index(i)
+		if (mapping.Label == "") == (mapping.Annotation == "") {
+			allErrs = append(allErrs, field.Invalid(mappingPath, mapping, "exactly one of label and annotation should be set"))
+		}
+		switch {
+		case (mapping.TagCategory == "") == (mapping.CustomAttribute == ""):
+			allErrs = append(allErrs, field.Invalid(mappingPath, mapping, "exactly one of tagCategory and customAttribute should be set"))
+		case mapping.TagCategory != "":
+			if tagCategories[mapping.TagCategory] {
+				allErrs = append(allErrs, field.Duplicate(mappingPath.Child("tagCategory"), mapping.TagCategory))
+			}
+			tagCategories[mapping.TagCategory] = true
+		default:
+			if customAttributes[mapping.CustomAttribute] {
+				allErrs = append(allErrs, field.Duplicate(mappingPath.Child("customAttribute"), mapping.CustomAttribute))
+			}
+			customAttributes[mapping.CustomAttribute] = true
+		}
+	}
+	return allErrs
+}
+
+func validatePortGroups(devices []NetworkDeviceSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, device := range devices {
+		if device.SwitchName == "" {
+			if device.VLANID != 0 {
+				allErrs = append(allErrs, field.Forbidden(fldPath.Index(i).Child("vlanID"), "should only be set when switchName is set"))
+			}
+			continue
+		}
+		if device.NetworkName == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Index(i).Child("networkName"), "is required when switchName is set"))
+		}
+	}
+	return allErrs
+}
+
+func validateNetworkDevices(network NetworkSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, family := range []struct {
+		name        string
+		isIPv4      bool
+		dhcp        func(NetworkDeviceSpec) bool
+		gateway     func(NetworkDeviceSpec) string
+		gatewayName string
+	}{
+		{
+			name:        "IPv4",
+			isIPv4:      true,
+			dhcp:        func(d NetworkDeviceSpec) bool { return d.DHCP4 },
+			gateway:     func(d NetworkDeviceSpec) string { return d.Gateway4 },
+			gatewayName: "gateway4",
+		},
+		{
+			name:        "IPv6",
+			dhcp:        func(d NetworkDeviceSpec) bool { return d.DHCP6 },
+			gateway:     func(d NetworkDeviceSpec) string { return d.Gateway6 },
+			gatewayName: "gateway6",
+		},
+	} {
+		isFamily := func(addr string) bool {
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				ip, _, _ = net.ParseCIDR(addr)
+			}
+			return ip != nil && (ip.To4() != nil) == family.isIPv4
+		}
+
+		// A VM with static addresses needs a route out of their subnets,
+		// either through a gateway or a route of any of its devices.
+		routed := false
+		for _, route := range network.Routes {
+			routed = routed || isFamily(route.To)
+		}
+		for _, device := range network.Devices {
+			routed = routed || family.gateway(device) != "" || family.dhcp(device) || (!family.isIPv4 && device.SLAAC)
+			for _, route := range device.Routes {
+				routed = routed || isFamily(route.To)
+			}
+		}
+
+		for i, device := range network.Devices {
+			hasStatic := false
+			for j, addr := range device.IPAddrs {
+				if !isFamily(addr) {
+					continue
+				}
+				hasStatic = true
+				if family.dhcp(device) {
+					allErrs = append(allErrs, field.Forbidden(fldPath.Child("devices").Index(i).Child("ipAddrs").Index(j), fmt.Sprintf("cannot contain %s addresses when dhcp%s is set", family.name, strings.TrimPrefix(family.gatewayName, "gateway"))))
+				}
+			}
+			if hasStatic && !routed {
+				allErrs = append(allErrs, field.Required(fldPath.Child("devices").Index(i).Child(family.gatewayName), fmt.Sprintf("is required for static %s addresses unless another device has a default route or routes are set", family.name)))
+			}
+		}
+	}
+
+	// The IPv4 address of a device is either allocated from a VSphereIPPool
+	// or leased over DHCP.
+	for i, device := range network.Devices {
+		if device.IPPool != "" && device.DHCP4 {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("devices").Index(i).Child("ipPool"), "cannot be set when dhcp4 is set"))
+		}
+	}
+	return allErrs
+}
+
+// validateAddressFilters validates the CIDRs filtering the addresses reported
+// for a machine.
+func validateAddressFilters(network NetworkSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if cidr := network.PreferredNodeIPCIDR; cidr != "" {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("preferredNodeIPCIDR"), cidr, "must be a CIDR"))
+		}
+	}
+	for i, cidr := range network.ExcludeNetworkCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("excludeNetworkCIDRs").Index(i), cidr, "must be a CIDR"))
+		}
+	}
+	return allErrs
+}
+
+// validateAddressWait validates the policy of a machine waiting for the
+// addresses of its DHCP network devices.
+func validateAddressWait(network NetworkSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if wait := network.AddressWait; wait != nil && wait.Timeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("addressWait", "timeout"), wait.Timeout.Duration.String(), "must be greater than 0"))
+	}
+	return allErrs
+}
+
+func validateCloneMode(spec *VirtualMachineCloneSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if spec.Snapshot != "" && (spec.CloneMode == FullClone || spec.CloneMode == InstantClone) {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("snapshot"), "should only be set when cloneMode is linkedClone"))
+	}
+	// Instant clones share the virtual hardware of their parent VM, and
+	// cannot be customized with Sysprep.
+	if spec.CloneMode == InstantClone {
+		if spec.OS == Windows {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("cloneMode"), "cannot be instantClone for Windows VMs"))
+		}
+		if spec.VTPM {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("cloneMode"), "cannot be instantClone when vtpm is set"))
+		}
+		if len(spec.PciDevices) > 0 {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("cloneMode"), "cannot be instantClone when pciDevices are set"))
+		}
+	}
+	return allErrs
+}
+
+// validateLatencyTuning validates the NUMA nodes and the physical CPUs the
+// virtual machine is scheduled on, which vSphere requires to be distinct and
+// to provide a physical CPU per vCPU.
+func validateLatencyTuning(spec *VirtualMachineCloneSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, list := range []struct {
+		path *field.Path
+		ids  []int32
+	}{
+		{fldPath.Child("numaNodeAffinity"), spec.NumaNodeAffinity},
+		{fldPath.Child("cpuPinning"), spec.CPUPinning},
+	} {
+		seen := map[int32]struct{}{}
+		for i, id := range list.ids {
+			if id < 0 {
+				allErrs = append(allErrs, field.Invalid(list.path.Index(i), id, "should not be negative"))
+			}
+			if _, ok := seen[id]; ok {
+				allErrs = append(allErrs, field.Duplicate(list.path.Index(i), id))
+			}
+			seen[id] = struct{}{}
+		}
+	}
+	if len(spec.CPUPinning) > 0 && int32(len(spec.CPUPinning)) < spec.NumCPUs {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("cpuPinning"), spec.CPUPinning, "should list at least numCPUs physical CPUs"))
+	}
+	return allErrs
+}
+
+func validateMACAddrs(devices []NetworkDeviceSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	seen := map[string]struct{}{}
+	for i, device := range devices {
+		if device.MACAddr == "" {
+			continue
+		}
+		macPath := fldPath.Index(i).Child("macAddr")
+		mac, err := net.ParseMAC(device.MACAddr)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(macPath, device.MACAddr, "should be a valid MAC address"))
+			continue
+		}
+		if _, ok := seen[mac.String()]; ok {
+			allErrs = append(allErrs, field.Duplicate(macPath, device.MACAddr))
+		}
+		seen[mac.String()] = struct{}{}
+	}
+	return allErrs
+}
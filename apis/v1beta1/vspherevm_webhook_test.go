@@ -18,6 +18,7 @@ package v1beta1
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
@@ -72,9 +73,14 @@ func TestVSphereVM_ValidateCreate(t *testing.T) {
 		},
 		{
 			name:      "successful VSphereVM creation",
-			vSphereVM: createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32", "192.168.0.3/32"}, nil, Linux),
+			vSphereVM: withGateway4(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32", "192.168.0.3/32"}, nil, Linux), "192.168.0.254"),
 			wantErr:   false,
 		},
+		{
+			name:      "static IPs without a gateway",
+			vSphereVM: createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32", "192.168.0.3/32"}, nil, Linux),
+			wantErr:   true,
+		},
 		{
 			name:      "name too long for Windows VM",
 			vSphereVM: createVSphereVM(windowsVMName, "foo.com", "", "", []string{"192.168.0.1/32", "192.168.0.3/32"}, nil, Windows),
@@ -82,7 +88,22 @@ func TestVSphereVM_ValidateCreate(t *testing.T) {
 		},
 		{
 			name:      "name too long for Linux VM",
-			vSphereVM: createVSphereVM(linuxVMName, "foo.com", "", "", []string{"192.168.0.1/32", "192.168.0.3/32"}, nil, Linux),
+			vSphereVM: withGateway4(createVSphereVM(linuxVMName, "foo.com", "", "", []string{"192.168.0.1/32", "192.168.0.3/32"}, nil, Linux), "192.168.0.254"),
+			wantErr:   false,
+		},
+		{
+			name:      "name with dots for Windows VM",
+			vSphereVM: createVSphereVM("win.vm-1", "foo.com", "", "", []string{"192.168.0.1/32", "192.168.0.3/32"}, nil, Windows),
+			wantErr:   true,
+		},
+		{
+			name:      "no template and no UUID to adopt",
+			vSphereVM: withoutTemplate(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux)),
+			wantErr:   true,
+		},
+		{
+			name:      "no template with BIOS UUID to adopt",
+			vSphereVM: withoutTemplate(withGateway4(createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", []string{"192.168.0.1/32"}, nil, Linux), "192.168.0.254")),
 			wantErr:   false,
 		},
 	}
@@ -117,13 +138,31 @@ func TestVSphereVM_ValidateUpdate(t *testing.T) {
 		{
 			name:         "updating ips can be done",
 			oldVSphereVM: createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux),
+			vSphereVM:    withGateway4(createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", []string{"192.168.0.1/32", "192.168.0.10/32"}, nil, Linux), "192.168.0.254"),
+			wantErr:      false,
+		},
+		{
+			name:         "updating ips without a gateway cannot be done",
+			oldVSphereVM: createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux),
 			vSphereVM:    createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", []string{"192.168.0.1/32", "192.168.0.10/32"}, nil, Linux),
+			wantErr:      true,
+		},
+		{
+			name:         "updating a VM with static ips without a gateway can be done",
+			oldVSphereVM: createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux),
+			vSphereVM:    withDiskSize(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), 50),
 			wantErr:      false,
 		},
+		{
+			name:         "updating to an invalid MAC address cannot be done",
+			oldVSphereVM: createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux),
+			vSphereVM:    withMACAddr(createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux), "00:50:56:00:00"),
+			wantErr:      true,
+		},
 		{
 			name:         "updating bootstrapRef can be done",
 			oldVSphereVM: createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux),
-			vSphereVM:    createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", []string{"192.168.0.1/32", "192.168.0.10/32"}, &corev1.ObjectReference{}, Linux),
+			vSphereVM:    withGateway4(createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", []string{"192.168.0.1/32", "192.168.0.10/32"}, &corev1.ObjectReference{}, Linux), "192.168.0.254"),
 			wantErr:      false,
 		},
 		{
@@ -132,6 +171,75 @@ func TestVSphereVM_ValidateUpdate(t *testing.T) {
 			vSphereVM:    createVSphereVM("vsphere-vm-1", "bar.com", biosUUID, "", []string{"192.168.0.1/32", "192.168.0.10/32"}, nil, Linux),
 			wantErr:      true,
 		},
+		{
+			name:         "updating the number of CPUs and the memory size can be done",
+			oldVSphereVM: createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux),
+			vSphereVM:    withSize(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), 4, 8192),
+			wantErr:      false,
+		},
+		{
+			name:         "updating the size of the OS disk can be done",
+			oldVSphereVM: createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux),
+			vSphereVM:    withDiskSize(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), 50),
+			wantErr:      false,
+		},
+		{
+			name:         "decreasing the number of CPUs cannot be done",
+			oldVSphereVM: withSize(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), 4, 8192),
+			vSphereVM:    withSize(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), 2, 8192),
+			wantErr:      true,
+		},
+		{
+			name:         "decreasing the memory size cannot be done",
+			oldVSphereVM: withSize(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), 4, 8192),
+			vSphereVM:    withSize(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), 4, 4096),
+			wantErr:      true,
+		},
+		{
+			name:         "decreasing the size of the OS disk cannot be done",
+			oldVSphereVM: withDiskSize(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), 50),
+			vSphereVM:    withDiskSize(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), 40),
+			wantErr:      true,
+		},
+		{
+			name:         "upgrading the hardware version can be done",
+			oldVSphereVM: withHardwareVersion(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), "vmx-15"),
+			vSphereVM:    withHardwareVersion(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), "vmx-19"),
+			wantErr:      false,
+		},
+		{
+			name:         "downgrading the hardware version cannot be done",
+			oldVSphereVM: withHardwareVersion(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), "vmx-19"),
+			vSphereVM:    withHardwareVersion(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), "vmx-15"),
+			wantErr:      true,
+		},
+		{
+			name:         "updating the guestinfo of load balancer VMs can be done",
+			oldVSphereVM: withCustomVMXKeys(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), map[string]string{"guestinfo.foo": "bar"}),
+			vSphereVM: withCustomVMXKeys(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), map[string]string{
+				"guestinfo.foo":                         "bar",
+				LoadBalancerGuestInfoPrefix + "members": "10.0.0.1",
+			}),
+			wantErr: false,
+		},
+		{
+			name:         "updating the other custom VMX keys cannot be done",
+			oldVSphereVM: createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux),
+			vSphereVM:    withCustomVMXKeys(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), map[string]string{"guestinfo.foo": "bar"}),
+			wantErr:      true,
+		},
+		{
+			name:         "the instance UUID can be set once",
+			oldVSphereVM: createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux),
+			vSphereVM:    withInstanceUUID(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), "uuid-1"),
+			wantErr:      false,
+		},
+		{
+			name:         "updating the instance UUID cannot be done",
+			oldVSphereVM: withInstanceUUID(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), "uuid-1"),
+			vSphereVM:    withInstanceUUID(createVSphereVM("vsphere-vm-1", "foo.com", "", "", []string{"192.168.0.1/32"}, nil, Linux), "uuid-2"),
+			wantErr:      true,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -145,6 +253,60 @@ func TestVSphereVM_ValidateUpdate(t *testing.T) {
 	}
 }
 
+//nolint
+func TestVSphereVM_ValidatePowerOffMode(t *testing.T) {
+	g := NewWithT(t)
+
+	tests := []struct {
+		name         string
+		powerOffMode VirtualMachinePowerOpMode
+		timeout      *metav1.Duration
+		wantErr      bool
+	}{
+		{
+			name:         "trySoft without timeout",
+			powerOffMode: VirtualMachinePowerOpModeTrySoft,
+			wantErr:      false,
+		},
+		{
+			name:         "trySoft with timeout",
+			powerOffMode: VirtualMachinePowerOpModeTrySoft,
+			timeout:      &metav1.Duration{Duration: time.Minute},
+			wantErr:      false,
+		},
+		{
+			name:         "trySoft with negative timeout",
+			powerOffMode: VirtualMachinePowerOpModeTrySoft,
+			timeout:      &metav1.Duration{Duration: -time.Minute},
+			wantErr:      true,
+		},
+		{
+			name:         "hard with timeout",
+			powerOffMode: VirtualMachinePowerOpModeHard,
+			timeout:      &metav1.Duration{Duration: time.Minute},
+			wantErr:      true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			vm := createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux)
+			vm.Spec.PowerOffMode = tc.powerOffMode
+			vm.Spec.GuestSoftPowerOffTimeout = tc.timeout
+			createErr := vm.ValidateCreate()
+
+			oldVM := createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux)
+			updateErr := vm.ValidateUpdate(oldVM)
+			if tc.wantErr {
+				g.Expect(createErr).To(HaveOccurred())
+				g.Expect(updateErr).To(HaveOccurred())
+			} else {
+				g.Expect(createErr).NotTo(HaveOccurred())
+				g.Expect(updateErr).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
 func createVSphereVM(name, server, biosUUID, preferredAPIServerCIDR string, ips []string, bootstrapRef *corev1.ObjectReference, os OS) *VSphereVM {
 	VSphereVM := &VSphereVM{
 		ObjectMeta: metav1.ObjectMeta{
@@ -154,7 +316,8 @@ func createVSphereVM(name, server, biosUUID, preferredAPIServerCIDR string, ips
 			BiosUUID:     biosUUID,
 			BootstrapRef: bootstrapRef,
 			VirtualMachineCloneSpec: VirtualMachineCloneSpec{
-				Server: server,
+				Server:   server,
+				Template: "ubuntu-template",
 				Network: NetworkSpec{
 					PreferredAPIServerCIDR: preferredAPIServerCIDR,
 					Devices:                []NetworkDeviceSpec{},
@@ -173,3 +336,414 @@ func createVSphereVM(name, server, biosUUID, preferredAPIServerCIDR string, ips
 	}
 	return VSphereVM
 }
+
+func withSize(vm *VSphereVM, numCPUs int32, memoryMiB int64) *VSphereVM {
+	vm.Spec.NumCPUs = numCPUs
+	vm.Spec.MemoryMiB = memoryMiB
+	return vm
+}
+
+func withDiskSize(vm *VSphereVM, diskGiB int32) *VSphereVM {
+	vm.Spec.DiskGiB = diskGiB
+	return vm
+}
+
+func withHardwareVersion(vm *VSphereVM, hardwareVersion string) *VSphereVM {
+	vm.Spec.HardwareVersion = hardwareVersion
+	return vm
+}
+
+func withCustomVMXKeys(vm *VSphereVM, keys map[string]string) *VSphereVM {
+	vm.Spec.CustomVMXKeys = keys
+	return vm
+}
+
+func withInstanceUUID(vm *VSphereVM, instanceUUID string) *VSphereVM {
+	vm.Spec.InstanceUUID = instanceUUID
+	return vm
+}
+
+func withGateway4(vm *VSphereVM, gateway4 string) *VSphereVM {
+	vm.Spec.Network.Devices[0].Gateway4 = gateway4
+	return vm
+}
+
+func withMACAddr(vm *VSphereVM, macAddr string) *VSphereVM {
+	vm.Spec.Network.Devices = append(vm.Spec.Network.Devices, NetworkDeviceSpec{NetworkName: "net", MACAddr: macAddr})
+	return vm
+}
+
+func withoutTemplate(vm *VSphereVM) *VSphereVM {
+	vm.Spec.Template = ""
+	return vm
+}
+
+func TestVSphereVM_ValidatePCIDevices(t *testing.T) {
+	deviceID, vendorID := int32(4318), int32(7864)
+
+	tests := []struct {
+		name       string
+		pciDevices []PCIDeviceSpec
+		wantErr    bool
+	}{
+		{
+			name:       "DirectPath I/O device",
+			pciDevices: []PCIDeviceSpec{{DeviceID: &deviceID, VendorID: &vendorID}},
+			wantErr:    false,
+		},
+		{
+			name:       "vGPU device",
+			pciDevices: []PCIDeviceSpec{{VGPUProfile: "grid_t4-4q"}},
+			wantErr:    false,
+		},
+		{
+			name:       "DirectPath I/O device without vendor ID",
+			pciDevices: []PCIDeviceSpec{{DeviceID: &deviceID}},
+			wantErr:    true,
+		},
+		{
+			name:       "vGPU device with device ID",
+			pciDevices: []PCIDeviceSpec{{VGPUProfile: "grid_t4-4q", DeviceID: &deviceID}},
+			wantErr:    true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			vm := createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux)
+			vm.Spec.PciDevices = tc.pciDevices
+			if tc.wantErr {
+				g.Expect(vm.ValidateCreate()).To(HaveOccurred())
+			} else {
+				g.Expect(vm.ValidateCreate()).To(Succeed())
+			}
+		})
+	}
+}
+
+func TestVSphereVM_ValidateMACAddrs(t *testing.T) {
+	tests := []struct {
+		name     string
+		macAddrs []string
+		wantErr  bool
+	}{
+		{
+			name:     "unique MAC addresses",
+			macAddrs: []string{"00:50:56:00:00:01", "00:50:56:00:00:02"},
+			wantErr:  false,
+		},
+		{
+			name:     "invalid MAC address",
+			macAddrs: []string{"00:50:56:00:00"},
+			wantErr:  true,
+		},
+		{
+			name:     "duplicate MAC addresses",
+			macAddrs: []string{"00:50:56:00:00:01", "00:50:56:00:00:01"},
+			wantErr:  true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			vm := createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux)
+			for _, macAddr := range tc.macAddrs {
+				vm.Spec.Network.Devices = append(vm.Spec.Network.Devices, NetworkDeviceSpec{MACAddr: macAddr})
+			}
+			if tc.wantErr {
+				g.Expect(vm.ValidateCreate()).To(HaveOccurred())
+			} else {
+				g.Expect(vm.ValidateCreate()).To(Succeed())
+			}
+		})
+	}
+}
+
+func TestVSphereVM_ValidateFirmware(t *testing.T) {
+	tests := []struct {
+		name       string
+		firmware   Firmware
+		secureBoot bool
+		vtpm       bool
+		wantErr    bool
+	}{
+		{
+			name:       "EFI with secure boot and vTPM",
+			firmware:   FirmwareEFI,
+			secureBoot: true,
+			vtpm:       true,
+			wantErr:    false,
+		},
+		{
+			name:     "BIOS",
+			firmware: FirmwareBIOS,
+			wantErr:  false,
+		},
+		{
+			name:       "secure boot with BIOS",
+			firmware:   FirmwareBIOS,
+			secureBoot: true,
+			wantErr:    true,
+		},
+		{
+			name:    "vTPM without firmware",
+			vtpm:    true,
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			vm := createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux)
+			vm.Spec.Firmware = tc.firmware
+			vm.Spec.SecureBoot = tc.secureBoot
+			vm.Spec.VTPM = tc.vtpm
+			if tc.wantErr {
+				g.Expect(vm.ValidateCreate()).To(HaveOccurred())
+			} else {
+				g.Expect(vm.ValidateCreate()).To(Succeed())
+			}
+		})
+	}
+}
+
+func TestVSphereVM_ValidatePortGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		device  NetworkDeviceSpec
+		wantErr bool
+	}{
+		{
+			name:    "port group with VLAN on a distributed switch",
+			device:  NetworkDeviceSpec{NetworkName: "capv-vlan-100", SwitchName: "dvs", VLANID: 100},
+			wantErr: false,
+		},
+		{
+			name:    "untagged port group on a distributed switch",
+			device:  NetworkDeviceSpec{NetworkName: "capv", SwitchName: "dvs"},
+			wantErr: false,
+		},
+		{
+			name:    "VLAN without a distributed switch",
+			device:  NetworkDeviceSpec{NetworkName: "capv-vlan-100", VLANID: 100},
+			wantErr: true,
+		},
+		{
+			name:    "distributed switch without a port group name",
+			device:  NetworkDeviceSpec{SwitchName: "dvs", VLANID: 100},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			vm := createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux)
+			vm.Spec.Network.Devices = []NetworkDeviceSpec{tc.device}
+			if tc.wantErr {
+				g.Expect(vm.ValidateCreate()).To(HaveOccurred())
+			} else {
+				g.Expect(vm.ValidateCreate()).To(Succeed())
+			}
+		})
+	}
+}
+
+func TestVSphereVM_ValidateNetworkDevices(t *testing.T) {
+	tests := []struct {
+		name    string
+		network NetworkSpec
+		wantErr string
+	}{
+		{
+			name: "static addresses with gateways",
+			network: NetworkSpec{Devices: []NetworkDeviceSpec{
+				{NetworkName: "net", IPAddrs: []string{"192.168.0.10/24", "2001:db8::10/64"}, Gateway4: "192.168.0.1", Gateway6: "2001:db8::1"},
+			}},
+		},
+		{
+			name: "static IPv4 address without gateway",
+			network: NetworkSpec{Devices: []NetworkDeviceSpec{
+				{NetworkName: "net", IPAddrs: []string{"192.168.0.10/24"}},
+			}},
+			wantErr: "spec.network.devices[0].gateway4: Required value",
+		},
+		{
+			name: "static IPv6 address without gateway",
+			network: NetworkSpec{Devices: []NetworkDeviceSpec{
+				{NetworkName: "net", IPAddrs: []string{"192.168.0.10/24", "2001:db8::10/64"}, Gateway4: "192.168.0.1"},
+			}},
+			wantErr: "spec.network.devices[0].gateway6: Required value",
+		},
+		{
+			name: "static address on a secondary device without gateway",
+			network: NetworkSpec{Devices: []NetworkDeviceSpec{
+				{NetworkName: "net", DHCP4: true},
+				{NetworkName: "storage", IPAddrs: []string{"10.0.0.10/24"}},
+			}},
+		},
+		{
+			name: "static address with routes",
+			network: NetworkSpec{
+				Devices: []NetworkDeviceSpec{{NetworkName: "net", IPAddrs: []string{"10.0.0.10/24"}}},
+				Routes:  []NetworkRouteSpec{{To: "10.1.0.0/16", Via: "10.0.0.1"}},
+			},
+		},
+		{
+			name: "static IPv6 address with SLAAC",
+			network: NetworkSpec{Devices: []NetworkDeviceSpec{
+				{NetworkName: "net", DHCP4: true, SLAAC: true, IPAddrs: []string{"2001:db8::10/64"}},
+			}},
+		},
+		{
+			name: "DHCP with a static address of the same family",
+			network: NetworkSpec{Devices: []NetworkDeviceSpec{
+				{NetworkName: "net", DHCP4: true, IPAddrs: []string{"192.168.0.10/24"}},
+			}},
+			wantErr: "spec.network.devices[0].ipAddrs[0]: Forbidden: cannot contain IPv4 addresses when dhcp4 is set",
+		},
+		{
+			name: "DHCP with a static address of the other family",
+			network: NetworkSpec{Devices: []NetworkDeviceSpec{
+				{NetworkName: "net", DHCP6: true, IPAddrs: []string{"192.168.0.10/24"}, Gateway4: "192.168.0.1"},
+			}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			vm := createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux)
+			vm.Spec.Network = tc.network
+			err := vm.ValidateCreate()
+			if tc.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.wantErr)))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestVSphereVM_ValidateCloneMode(t *testing.T) {
+	g := NewWithT(t)
+	vm := createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux)
+	vm.Spec.Snapshot = "golden"
+	g.Expect(vm.ValidateCreate()).To(Succeed())
+
+	vm.Spec.CloneMode = LinkedClone
+	g.Expect(vm.ValidateCreate()).To(Succeed())
+
+	vm.Spec.CloneMode = FullClone
+	g.Expect(vm.ValidateCreate()).To(MatchError(ContainSubstring("spec.snapshot: Forbidden")))
+
+	vm.Spec.CloneMode = InstantClone
+	g.Expect(vm.ValidateCreate()).To(MatchError(ContainSubstring("spec.snapshot: Forbidden")))
+
+	vm.Spec.Snapshot = ""
+	g.Expect(vm.ValidateCreate()).To(Succeed())
+
+	vm.Spec.VTPM = true
+	g.Expect(vm.ValidateCreate()).To(MatchError(ContainSubstring("spec.cloneMode: Forbidden: cannot be instantClone when vtpm is set")))
+}
+
+func TestVSphereVM_ValidateLatencyTuning(t *testing.T) {
+	g := NewWithT(t)
+	vm := createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux)
+	vm.Spec.NumCPUs = 4
+	vm.Spec.LatencySensitivity = LatencySensitivityHigh
+	vm.Spec.NumaNodeAffinity = []int32{0}
+	vm.Spec.CPUPinning = []int32{2, 3, 4, 5}
+	g.Expect(vm.ValidateCreate()).To(Succeed())
+
+	vm.Spec.NumaNodeAffinity = []int32{0, -1}
+	g.Expect(vm.ValidateCreate()).To(MatchError(ContainSubstring("spec.numaNodeAffinity[1]: Invalid value")))
+
+	vm.Spec.NumaNodeAffinity = []int32{0}
+	vm.Spec.CPUPinning = []int32{2, 3, 4, 4}
+	g.Expect(vm.ValidateCreate()).To(MatchError(ContainSubstring("spec.cpuPinning[3]: Duplicate value")))
+
+	vm.Spec.CPUPinning = []int32{2, 3}
+	g.Expect(vm.ValidateCreate()).To(MatchError(ContainSubstring("should list at least numCPUs physical CPUs")))
+}
+
+func TestVSphereVM_ValidateMetadataPropagation(t *testing.T) {
+	g := NewWithT(t)
+	vm := createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux)
+	vm.Spec.MetadataPropagation = []MetadataPropagationSpec{
+		{Label: "team", TagCategory: "team"},
+		{Annotation: "example.com/cost-center", CustomAttribute: "cost-center"},
+	}
+	g.Expect(vm.ValidateCreate()).To(Succeed())
+
+	vm.Spec.MetadataPropagation = append(vm.Spec.MetadataPropagation, MetadataPropagationSpec{Label: "env", Annotation: "env", TagCategory: "env"})
+	g.Expect(vm.ValidateCreate()).To(MatchError(ContainSubstring("exactly one of label and annotation should be set")))
+
+	vm.Spec.MetadataPropagation[2] = MetadataPropagationSpec{Label: "env", TagCategory: "team"}
+	g.Expect(vm.ValidateCreate()).To(MatchError(ContainSubstring("spec.metadataPropagation[2].tagCategory: Duplicate value")))
+
+	vm.Spec.MetadataPropagation[2] = MetadataPropagationSpec{Label: "env"}
+	g.Expect(vm.ValidateCreate()).To(MatchError(ContainSubstring("exactly one of tagCategory and customAttribute should be set")))
+}
+
+func TestVSphereVM_ValidateSnapshotSchedule(t *testing.T) {
+	g := NewWithT(t)
+	vm := createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux)
+	vm.Spec.SnapshotSchedule = &VirtualMachineSnapshotSchedule{Interval: metav1.Duration{Duration: 24 * time.Hour}}
+	g.Expect(vm.ValidateCreate()).To(Succeed())
+
+	oldVM := createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux)
+	g.Expect(vm.ValidateUpdate(oldVM)).To(Succeed())
+
+	vm.Spec.SnapshotSchedule.Interval.Duration = 10 * time.Second
+	g.Expect(vm.ValidateCreate()).To(MatchError(ContainSubstring("spec.snapshotSchedule.interval: Invalid value")))
+	g.Expect(vm.ValidateUpdate(oldVM)).To(MatchError(ContainSubstring("spec.snapshotSchedule.interval: Invalid value")))
+}
+
+func TestVSphereVM_ValidateFailureRetryPolicy(t *testing.T) {
+	g := NewWithT(t)
+	vm := createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux)
+	vm.Spec.FailureRetryPolicy = &VirtualMachineFailureRetryPolicy{MaxAttempts: 3, Interval: &metav1.Duration{Duration: time.Minute}}
+	g.Expect(vm.ValidateCreate()).To(Succeed())
+
+	oldVM := createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux)
+	g.Expect(vm.ValidateUpdate(oldVM)).To(Succeed())
+
+	vm.Spec.FailureRetryPolicy.Interval.Duration = 0
+	g.Expect(vm.ValidateCreate()).To(MatchError(ContainSubstring("spec.failureRetryPolicy.interval: Invalid value")))
+}
+
+func TestVSphereVM_ValidatePreDeleteBackup(t *testing.T) {
+	tests := []struct {
+		name    string
+		backup  string
+		wantErr bool
+	}{
+		{
+			name:   "snapshot",
+			backup: VMPreDeleteBackupSnapshot,
+		},
+		{
+			name:   "datastore path",
+			backup: "[datastore1] backups",
+		},
+		{
+			name:    "path without datastore",
+			backup:  "/backups",
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			vm := createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux)
+			vm.Annotations = map[string]string{VMPreDeleteBackupAnnotation: tc.backup}
+			oldVM := createVSphereVM("vsphere-vm-1", "foo.com", "", "", nil, nil, Linux)
+			if tc.wantErr {
+				g.Expect(vm.ValidateCreate()).To(MatchError(ContainSubstring("metadata.annotations[vspherevm.infrastructure.cluster.x-k8s.io/pre-delete-backup]: Invalid value")))
+				g.Expect(vm.ValidateUpdate(oldVM)).To(HaveOccurred())
+			} else {
+				g.Expect(vm.ValidateCreate()).To(Succeed())
+				g.Expect(vm.ValidateUpdate(oldVM)).To(Succeed())
+			}
+		})
+	}
+}
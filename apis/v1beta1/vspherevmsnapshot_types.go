@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+const (
+	// SnapshotFinalizer allows the snapshot controller to remove the snapshot
+	// of the VM in vCenter before the VSphereVMSnapshot is deleted.
+	SnapshotFinalizer = "vspherevmsnapshot.infrastructure.cluster.x-k8s.io"
+
+	// SnapshotScheduleLabel is the label set on the VSphereVMSnapshots
+	// created by the snapshot schedule of a VSphereVM, whose value is the name
+	// of the VSphereVM.
+	SnapshotScheduleLabel = "vspherevmsnapshot.infrastructure.cluster.x-k8s.io/schedule"
+
+	// DefaultSnapshotRetention is the number of scheduled snapshots of a VM
+	// kept when the retention of the snapshot schedule is not set.
+	DefaultSnapshotRetention = 3
+)
+
+// VSphereVMSnapshotSpec defines the desired state of VSphereVMSnapshot.
+type VSphereVMSnapshotSpec struct {
+	// VMName is the name of the VSphereVM in the namespace of the snapshot
+	// whose VM is snapshotted. The snapshot is crash-consistent, that is it
+	// neither includes the memory of the VM nor quiesces its file systems.
+	// +kubebuilder:validation:MinLength=1
+	VMName string `json:"vmName"`
+
+	// Description is the description of the snapshot in vCenter.
+	// +optional
+	Description string `json:"description,omitempty"`
+}
+
+// VSphereVMSnapshotStatus defines the observed state of VSphereVMSnapshot.
+type VSphereVMSnapshotStatus struct {
+	// Ready is true when the snapshot of the VM has been created.
+	// +optional
+	Ready bool `json:"ready"`
+
+	// SnapshotRef is the managed object reference of the snapshot in vCenter.
+	// +optional
+	SnapshotRef string `json:"snapshotRef,omitempty"`
+
+	// CreationTime is when the snapshot of the VM was created.
+	// +optional
+	CreationTime *metav1.Time `json:"creationTime,omitempty"`
+
+	// Conditions defines current service state of the VSphereVMSnapshot.
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
+}
+
+// VirtualMachineSnapshotSchedule takes snapshots of a VM periodically.
+type VirtualMachineSnapshotSchedule struct {
+	// Interval is the time between two snapshots, e.g. 24h.
+	Interval metav1.Duration `json:"interval"`
+
+	// Retention is the number of scheduled snapshots to keep; older ones are
+	// pruned. Snapshots requested with a VSphereVMSnapshot outside of the
+	// schedule are not pruned.
+	//
+	// Defaults to 3.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Retention int32 `json:"retention,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=vspherevmsnapshots,scope=Namespaced,categories=cluster-api
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="VM",type="string",JSONPath=".spec.vmName",description="VSphereVM the snapshot was taken of"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.ready",description="Snapshot is created"
+// +kubebuilder:printcolumn:name="Created",type="date",JSONPath=".status.creationTime",description="Time the snapshot was created"
+
+// VSphereVMSnapshot is the Schema for the vspherevmsnapshots API.
+type VSphereVMSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VSphereVMSnapshotSpec   `json:"spec,omitempty"`
+	Status VSphereVMSnapshotStatus `json:"status,omitempty"`
+}
+
+func (r *VSphereVMSnapshot) GetConditions() clusterv1.Conditions {
+	return r.Status.Conditions
+}
+
+func (r *VSphereVMSnapshot) SetConditions(conditions clusterv1.Conditions) {
+	r.Status.Conditions = conditions
+}
+
+// +kubebuilder:object:root=true
+
+// VSphereVMSnapshotList contains a list of VSphereVMSnapshot.
+type VSphereVMSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VSphereVMSnapshot `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VSphereVMSnapshot{}, &VSphereVMSnapshotList{})
+}
@@ -22,7 +22,8 @@ limitations under the License.
 package v1beta1
 
 import (
-	"k8s.io/api/core/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	apiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/errors"
@@ -46,6 +47,11 @@ func (in *APIEndpoint) DeepCopy() *APIEndpoint {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AllowedNamespaces) DeepCopyInto(out *AllowedNamespaces) {
 	*out = *in
+	if in.NamespaceList != nil {
+		in, out := &in.NamespaceList, &out.NamespaceList
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	in.Selector.DeepCopyInto(&out.Selector)
 }
 
@@ -60,434 +66,1092 @@ func (in *AllowedNamespaces) DeepCopy() *AllowedNamespaces {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *FailureDomain) DeepCopyInto(out *FailureDomain) {
+func (in *BlockedMachine) DeepCopyInto(out *BlockedMachine) {
 	*out = *in
-	if in.AutoConfigure != nil {
-		in, out := &in.AutoConfigure, &out.AutoConfigure
-		*out = new(bool)
-		**out = **in
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailureDomain.
-func (in *FailureDomain) DeepCopy() *FailureDomain {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlockedMachine.
+func (in *BlockedMachine) DeepCopy() *BlockedMachine {
 	if in == nil {
 		return nil
 	}
-	out := new(FailureDomain)
+	out := new(BlockedMachine)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *FailureDomainHosts) DeepCopyInto(out *FailureDomainHosts) {
+func (in *CSISpec) DeepCopyInto(out *CSISpec) {
 	*out = *in
+	if in.StorageClass != nil {
+		in, out := &in.StorageClass, &out.StorageClass
+		*out = new(CSIStorageClassSpec)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailureDomainHosts.
-func (in *FailureDomainHosts) DeepCopy() *FailureDomainHosts {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CSISpec.
+func (in *CSISpec) DeepCopy() *CSISpec {
 	if in == nil {
 		return nil
 	}
-	out := new(FailureDomainHosts)
+	out := new(CSISpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Network) DeepCopyInto(out *Network) {
+func (in *CSIStorageClassSpec) DeepCopyInto(out *CSIStorageClassSpec) {
 	*out = *in
-	if in.DHCP4 != nil {
-		in, out := &in.DHCP4, &out.DHCP4
-		*out = new(bool)
-		**out = **in
-	}
-	if in.DHCP6 != nil {
-		in, out := &in.DHCP6, &out.DHCP6
-		*out = new(bool)
-		**out = **in
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Network.
-func (in *Network) DeepCopy() *Network {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CSIStorageClassSpec.
+func (in *CSIStorageClassSpec) DeepCopy() *CSIStorageClassSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(Network)
+	out := new(CSIStorageClassSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NetworkDeviceSpec) DeepCopyInto(out *NetworkDeviceSpec) {
+func (in *CloudProviderSpec) DeepCopyInto(out *CloudProviderSpec) {
 	*out = *in
-	if in.IPAddrs != nil {
-		in, out := &in.IPAddrs, &out.IPAddrs
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.MTU != nil {
-		in, out := &in.MTU, &out.MTU
-		*out = new(int64)
-		**out = **in
-	}
-	if in.Nameservers != nil {
-		in, out := &in.Nameservers, &out.Nameservers
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.Routes != nil {
-		in, out := &in.Routes, &out.Routes
-		*out = make([]NetworkRouteSpec, len(*in))
-		copy(*out, *in)
-	}
-	if in.SearchDomains != nil {
-		in, out := &in.SearchDomains, &out.SearchDomains
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkDeviceSpec.
-func (in *NetworkDeviceSpec) DeepCopy() *NetworkDeviceSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudProviderSpec.
+func (in *CloudProviderSpec) DeepCopy() *CloudProviderSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(NetworkDeviceSpec)
+	out := new(CloudProviderSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NetworkRouteSpec) DeepCopyInto(out *NetworkRouteSpec) {
+func (in *ClusterDeletionStatus) DeepCopyInto(out *ClusterDeletionStatus) {
 	*out = *in
+	if in.Blocked != nil {
+		in, out := &in.Blocked, &out.Blocked
+		*out = make([]BlockedMachine, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkRouteSpec.
-func (in *NetworkRouteSpec) DeepCopy() *NetworkRouteSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDeletionStatus.
+func (in *ClusterDeletionStatus) DeepCopy() *ClusterDeletionStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(NetworkRouteSpec)
+	out := new(ClusterDeletionStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NetworkSpec) DeepCopyInto(out *NetworkSpec) {
+func (in *ControlPlaneEndpointDNSSpec) DeepCopyInto(out *ControlPlaneEndpointDNSSpec) {
 	*out = *in
-	if in.Devices != nil {
-		in, out := &in.Devices, &out.Devices
-		*out = make([]NetworkDeviceSpec, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.Route53 != nil {
+		in, out := &in.Route53, &out.Route53
+		*out = new(Route53DNSSpec)
+		**out = **in
 	}
-	if in.Routes != nil {
-		in, out := &in.Routes, &out.Routes
-		*out = make([]NetworkRouteSpec, len(*in))
-		copy(*out, *in)
+	if in.Infoblox != nil {
+		in, out := &in.Infoblox, &out.Infoblox
+		*out = new(InfobloxDNSSpec)
+		**out = **in
+	}
+	if in.RFC2136 != nil {
+		in, out := &in.RFC2136, &out.RFC2136
+		*out = new(RFC2136DNSSpec)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkSpec.
-func (in *NetworkSpec) DeepCopy() *NetworkSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneEndpointDNSSpec.
+func (in *ControlPlaneEndpointDNSSpec) DeepCopy() *ControlPlaneEndpointDNSSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(NetworkSpec)
+	out := new(ControlPlaneEndpointDNSSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NetworkStatus) DeepCopyInto(out *NetworkStatus) {
+func (in *DNSRecord) DeepCopyInto(out *DNSRecord) {
 	*out = *in
-	if in.IPAddrs != nil {
-		in, out := &in.IPAddrs, &out.IPAddrs
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkStatus.
-func (in *NetworkStatus) DeepCopy() *NetworkStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSRecord.
+func (in *DNSRecord) DeepCopy() *DNSRecord {
 	if in == nil {
 		return nil
 	}
-	out := new(NetworkStatus)
+	out := new(DNSRecord)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PCIDeviceSpec) DeepCopyInto(out *PCIDeviceSpec) {
+func (in *DeploymentZoneWeight) DeepCopyInto(out *DeploymentZoneWeight) {
 	*out = *in
-	if in.DeviceID != nil {
-		in, out := &in.DeviceID, &out.DeviceID
-		*out = new(int32)
-		**out = **in
-	}
-	if in.VendorID != nil {
-		in, out := &in.VendorID, &out.VendorID
-		*out = new(int32)
-		**out = **in
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PCIDeviceSpec.
-func (in *PCIDeviceSpec) DeepCopy() *PCIDeviceSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentZoneWeight.
+func (in *DeploymentZoneWeight) DeepCopy() *DeploymentZoneWeight {
 	if in == nil {
 		return nil
 	}
-	out := new(PCIDeviceSpec)
+	out := new(DeploymentZoneWeight)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PlacementConstraint) DeepCopyInto(out *PlacementConstraint) {
+func (in *DiskSpec) DeepCopyInto(out *DiskSpec) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlacementConstraint.
-func (in *PlacementConstraint) DeepCopy() *PlacementConstraint {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskSpec.
+func (in *DiskSpec) DeepCopy() *DiskSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PlacementConstraint)
+	out := new(DiskSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SSHUser) DeepCopyInto(out *SSHUser) {
+func (in *FailureDomain) DeepCopyInto(out *FailureDomain) {
 	*out = *in
-	if in.AuthorizedKeys != nil {
-		in, out := &in.AuthorizedKeys, &out.AuthorizedKeys
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+	if in.AutoConfigure != nil {
+		in, out := &in.AutoConfigure, &out.AutoConfigure
+		*out = new(bool)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSHUser.
-func (in *SSHUser) DeepCopy() *SSHUser {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailureDomain.
+func (in *FailureDomain) DeepCopy() *FailureDomain {
 	if in == nil {
 		return nil
 	}
-	out := new(SSHUser)
+	out := new(FailureDomain)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Topology) DeepCopyInto(out *Topology) {
+func (in *FailureDomainHosts) DeepCopyInto(out *FailureDomainHosts) {
 	*out = *in
-	if in.ComputeCluster != nil {
-		in, out := &in.ComputeCluster, &out.ComputeCluster
-		*out = new(string)
-		**out = **in
-	}
-	if in.Hosts != nil {
-		in, out := &in.Hosts, &out.Hosts
-		*out = new(FailureDomainHosts)
+	if in.AutoConfigure != nil {
+		in, out := &in.AutoConfigure, &out.AutoConfigure
+		*out = new(bool)
 		**out = **in
 	}
-	if in.Networks != nil {
-		in, out := &in.Networks, &out.Networks
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Topology.
-func (in *Topology) DeepCopy() *Topology {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailureDomainHosts.
+func (in *FailureDomainHosts) DeepCopy() *FailureDomainHosts {
 	if in == nil {
 		return nil
 	}
-	out := new(Topology)
+	out := new(FailureDomainHosts)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereCluster) DeepCopyInto(out *VSphereCluster) {
+func (in *GuestToolsSpec) DeepCopyInto(out *GuestToolsSpec) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereCluster.
-func (in *VSphereCluster) DeepCopy() *VSphereCluster {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuestToolsSpec.
+func (in *GuestToolsSpec) DeepCopy() *GuestToolsSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereCluster)
+	out := new(GuestToolsSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VSphereCluster) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereClusterIdentity) DeepCopyInto(out *VSphereClusterIdentity) {
+func (in *GuestToolsStatus) DeepCopyInto(out *GuestToolsStatus) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterIdentity.
-func (in *VSphereClusterIdentity) DeepCopy() *VSphereClusterIdentity {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuestToolsStatus.
+func (in *GuestToolsStatus) DeepCopy() *GuestToolsStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereClusterIdentity)
+	out := new(GuestToolsStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VSphereClusterIdentity) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereClusterIdentityList) DeepCopyInto(out *VSphereClusterIdentityList) {
+func (in *IPAllocation) DeepCopyInto(out *IPAllocation) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]VSphereClusterIdentity, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterIdentityList.
-func (in *VSphereClusterIdentityList) DeepCopy() *VSphereClusterIdentityList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPAllocation.
+func (in *IPAllocation) DeepCopy() *IPAllocation {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereClusterIdentityList)
+	out := new(IPAllocation)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VSphereClusterIdentityList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereClusterIdentitySpec) DeepCopyInto(out *VSphereClusterIdentitySpec) {
+func (in *InfobloxDNSSpec) DeepCopyInto(out *InfobloxDNSSpec) {
 	*out = *in
-	if in.AllowedNamespaces != nil {
-		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
-		*out = new(AllowedNamespaces)
-		(*in).DeepCopyInto(*out)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterIdentitySpec.
-func (in *VSphereClusterIdentitySpec) DeepCopy() *VSphereClusterIdentitySpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InfobloxDNSSpec.
+func (in *InfobloxDNSSpec) DeepCopy() *InfobloxDNSSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereClusterIdentitySpec)
+	out := new(InfobloxDNSSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereClusterIdentityStatus) DeepCopyInto(out *VSphereClusterIdentityStatus) {
+func (in *InfobloxIPPoolSpec) DeepCopyInto(out *InfobloxIPPoolSpec) {
 	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make(apiv1beta1.Conditions, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterIdentityStatus.
-func (in *VSphereClusterIdentityStatus) DeepCopy() *VSphereClusterIdentityStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InfobloxIPPoolSpec.
+func (in *InfobloxIPPoolSpec) DeepCopy() *InfobloxIPPoolSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereClusterIdentityStatus)
+	out := new(InfobloxIPPoolSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereClusterList) DeepCopyInto(out *VSphereClusterList) {
+func (in *KubernetesVersionImage) DeepCopyInto(out *KubernetesVersionImage) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]VSphereCluster, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterList.
-func (in *VSphereClusterList) DeepCopy() *VSphereClusterList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubernetesVersionImage.
+func (in *KubernetesVersionImage) DeepCopy() *KubernetesVersionImage {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereClusterList)
+	out := new(KubernetesVersionImage)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VSphereClusterList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereClusterSpec) DeepCopyInto(out *VSphereClusterSpec) {
+func (in *KubernetesVersionTemplate) DeepCopyInto(out *KubernetesVersionTemplate) {
 	*out = *in
-	out.ControlPlaneEndpoint = in.ControlPlaneEndpoint
-	if in.IdentityRef != nil {
-		in, out := &in.IdentityRef, &out.IdentityRef
-		*out = new(VSphereIdentityReference)
-		**out = **in
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterSpec.
-func (in *VSphereClusterSpec) DeepCopy() *VSphereClusterSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubernetesVersionTemplate.
+func (in *KubernetesVersionTemplate) DeepCopy() *KubernetesVersionTemplate {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereClusterSpec)
+	out := new(KubernetesVersionTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetadataPropagationSpec) DeepCopyInto(out *MetadataPropagationSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetadataPropagationSpec.
+func (in *MetadataPropagationSpec) DeepCopy() *MetadataPropagationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MetadataPropagationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NSXTGatewaySpec) DeepCopyInto(out *NSXTGatewaySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NSXTGatewaySpec.
+func (in *NSXTGatewaySpec) DeepCopy() *NSXTGatewaySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NSXTGatewaySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NSXTSpec) DeepCopyInto(out *NSXTSpec) {
+	*out = *in
+	if in.Gateway != nil {
+		in, out := &in.Gateway, &out.Gateway
+		*out = new(NSXTGatewaySpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NSXTSpec.
+func (in *NSXTSpec) DeepCopy() *NSXTSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NSXTSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Network) DeepCopyInto(out *Network) {
+	*out = *in
+	if in.DHCP4 != nil {
+		in, out := &in.DHCP4, &out.DHCP4
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DHCP6 != nil {
+		in, out := &in.DHCP6, &out.DHCP6
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Network.
+func (in *Network) DeepCopy() *Network {
+	if in == nil {
+		return nil
+	}
+	out := new(Network)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkAddressWaitPolicy) DeepCopyInto(out *NetworkAddressWaitPolicy) {
+	*out = *in
+	out.Timeout = in.Timeout
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkAddressWaitPolicy.
+func (in *NetworkAddressWaitPolicy) DeepCopy() *NetworkAddressWaitPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkAddressWaitPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkDeviceSpec) DeepCopyInto(out *NetworkDeviceSpec) {
+	*out = *in
+	if in.IPAddrs != nil {
+		in, out := &in.IPAddrs, &out.IPAddrs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MTU != nil {
+		in, out := &in.MTU, &out.MTU
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Nameservers != nil {
+		in, out := &in.Nameservers, &out.Nameservers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]NetworkRouteSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.SearchDomains != nil {
+		in, out := &in.SearchDomains, &out.SearchDomains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkDeviceSpec.
+func (in *NetworkDeviceSpec) DeepCopy() *NetworkDeviceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkDeviceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkRouteSpec) DeepCopyInto(out *NetworkRouteSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkRouteSpec.
+func (in *NetworkRouteSpec) DeepCopy() *NetworkRouteSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkRouteSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkSpec) DeepCopyInto(out *NetworkSpec) {
+	*out = *in
+	if in.Devices != nil {
+		in, out := &in.Devices, &out.Devices
+		*out = make([]NetworkDeviceSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]NetworkRouteSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeNetworkCIDRs != nil {
+		in, out := &in.ExcludeNetworkCIDRs, &out.ExcludeNetworkCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AddressWait != nil {
+		in, out := &in.AddressWait, &out.AddressWait
+		*out = new(NetworkAddressWaitPolicy)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkSpec.
+func (in *NetworkSpec) DeepCopy() *NetworkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkStatus) DeepCopyInto(out *NetworkStatus) {
+	*out = *in
+	if in.IPAddrs != nil {
+		in, out := &in.IPAddrs, &out.IPAddrs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkStatus.
+func (in *NetworkStatus) DeepCopy() *NetworkStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OrphanedVM) DeepCopyInto(out *OrphanedVM) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OrphanedVM.
+func (in *OrphanedVM) DeepCopy() *OrphanedVM {
+	if in == nil {
+		return nil
+	}
+	out := new(OrphanedVM)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PCIDeviceSpec) DeepCopyInto(out *PCIDeviceSpec) {
+	*out = *in
+	if in.DeviceID != nil {
+		in, out := &in.DeviceID, &out.DeviceID
+		*out = new(int32)
+		**out = **in
+	}
+	if in.VendorID != nil {
+		in, out := &in.VendorID, &out.VendorID
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PCIDeviceSpec.
+func (in *PCIDeviceSpec) DeepCopy() *PCIDeviceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PCIDeviceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlacementConstraint) DeepCopyInto(out *PlacementConstraint) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlacementConstraint.
+func (in *PlacementConstraint) DeepCopy() *PlacementConstraint {
+	if in == nil {
+		return nil
+	}
+	out := new(PlacementConstraint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RFC2136DNSSpec) DeepCopyInto(out *RFC2136DNSSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RFC2136DNSSpec.
+func (in *RFC2136DNSSpec) DeepCopy() *RFC2136DNSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RFC2136DNSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceAllocation) DeepCopyInto(out *ResourceAllocation) {
+	*out = *in
+	if in.CPUReservationMHz != nil {
+		in, out := &in.CPUReservationMHz, &out.CPUReservationMHz
+		*out = new(int64)
+		**out = **in
+	}
+	if in.CPULimitMHz != nil {
+		in, out := &in.CPULimitMHz, &out.CPULimitMHz
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MemoryReservationMiB != nil {
+		in, out := &in.MemoryReservationMiB, &out.MemoryReservationMiB
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MemoryLimitMiB != nil {
+		in, out := &in.MemoryLimitMiB, &out.MemoryLimitMiB
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceAllocation.
+func (in *ResourceAllocation) DeepCopy() *ResourceAllocation {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceAllocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourcePoolLimits) DeepCopyInto(out *ResourcePoolLimits) {
+	*out = *in
+	if in.CPUReservationMHz != nil {
+		in, out := &in.CPUReservationMHz, &out.CPUReservationMHz
+		*out = new(int64)
+		**out = **in
+	}
+	if in.CPULimitMHz != nil {
+		in, out := &in.CPULimitMHz, &out.CPULimitMHz
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MemoryReservationMiB != nil {
+		in, out := &in.MemoryReservationMiB, &out.MemoryReservationMiB
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MemoryLimitMiB != nil {
+		in, out := &in.MemoryLimitMiB, &out.MemoryLimitMiB
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourcePoolLimits.
+func (in *ResourcePoolLimits) DeepCopy() *ResourcePoolLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourcePoolLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceQuotaSpec) DeepCopyInto(out *ResourceQuotaSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceQuotaSpec.
+func (in *ResourceQuotaSpec) DeepCopy() *ResourceQuotaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceQuotaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Route53DNSSpec) DeepCopyInto(out *Route53DNSSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Route53DNSSpec.
+func (in *Route53DNSSpec) DeepCopy() *Route53DNSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(Route53DNSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SSHUser) DeepCopyInto(out *SSHUser) {
+	*out = *in
+	if in.AuthorizedKeys != nil {
+		in, out := &in.AuthorizedKeys, &out.AuthorizedKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSHUser.
+func (in *SSHUser) DeepCopy() *SSHUser {
+	if in == nil {
+		return nil
+	}
+	out := new(SSHUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Topology) DeepCopyInto(out *Topology) {
+	*out = *in
+	if in.ComputeCluster != nil {
+		in, out := &in.ComputeCluster, &out.ComputeCluster
+		*out = new(string)
+		**out = **in
+	}
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = new(FailureDomainHosts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Networks != nil {
+		in, out := &in.Networks, &out.Networks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.VSANStretchedCluster != nil {
+		in, out := &in.VSANStretchedCluster, &out.VSANStretchedCluster
+		*out = new(VSANStretchedClusterSite)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Topology.
+func (in *Topology) DeepCopy() *Topology {
+	if in == nil {
+		return nil
+	}
+	out := new(Topology)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VCenterConnectionSpec) DeepCopyInto(out *VCenterConnectionSpec) {
+	*out = *in
+	if in.CABundle != nil {
+		in, out := &in.CABundle, &out.CABundle
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCenterConnectionSpec.
+func (in *VCenterConnectionSpec) DeepCopy() *VCenterConnectionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VCenterConnectionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VCenterKeepAlive) DeepCopyInto(out *VCenterKeepAlive) {
+	*out = *in
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Retries != nil {
+		in, out := &in.Retries, &out.Retries
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCenterKeepAlive.
+func (in *VCenterKeepAlive) DeepCopy() *VCenterKeepAlive {
+	if in == nil {
+		return nil
+	}
+	out := new(VCenterKeepAlive)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VCenterRateLimit) DeepCopyInto(out *VCenterRateLimit) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCenterRateLimit.
+func (in *VCenterRateLimit) DeepCopy() *VCenterRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(VCenterRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VCenterSpec) DeepCopyInto(out *VCenterSpec) {
+	*out = *in
+	if in.IdentityRef != nil {
+		in, out := &in.IdentityRef, &out.IdentityRef
+		*out = new(VSphereIdentityReference)
+		**out = **in
+	}
+	if in.Zones != nil {
+		in, out := &in.Zones, &out.Zones
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCenterSpec.
+func (in *VCenterSpec) DeepCopy() *VCenterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VCenterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VCenterTLSPolicy) DeepCopyInto(out *VCenterTLSPolicy) {
+	*out = *in
+	if in.CipherSuites != nil {
+		in, out := &in.CipherSuites, &out.CipherSuites
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCenterTLSPolicy.
+func (in *VCenterTLSPolicy) DeepCopy() *VCenterTLSPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(VCenterTLSPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSANStretchedClusterSite) DeepCopyInto(out *VSANStretchedClusterSite) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSANStretchedClusterSite.
+func (in *VSANStretchedClusterSite) DeepCopy() *VSANStretchedClusterSite {
+	if in == nil {
+		return nil
+	}
+	out := new(VSANStretchedClusterSite)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereCluster) DeepCopyInto(out *VSphereCluster) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereCluster.
+func (in *VSphereCluster) DeepCopy() *VSphereCluster {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereCluster)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereCluster) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereClusterIdentity) DeepCopyInto(out *VSphereClusterIdentity) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterIdentity.
+func (in *VSphereClusterIdentity) DeepCopy() *VSphereClusterIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereClusterIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereClusterIdentity) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereClusterIdentityList) DeepCopyInto(out *VSphereClusterIdentityList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VSphereClusterIdentity, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterIdentityList.
+func (in *VSphereClusterIdentityList) DeepCopy() *VSphereClusterIdentityList {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereClusterIdentityList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereClusterIdentityList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereClusterIdentitySpec) DeepCopyInto(out *VSphereClusterIdentitySpec) {
+	*out = *in
+	if in.Vault != nil {
+		in, out := &in.Vault, &out.Vault
+		*out = new(VaultCredentialSource)
+		**out = **in
+	}
+	if in.AllowedNamespaces != nil {
+		in, out := &in.AllowedNamespaces, &out.AllowedNamespaces
+		*out = new(AllowedNamespaces)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(VCenterRateLimit)
+		**out = **in
+	}
+	if in.Connection != nil {
+		in, out := &in.Connection, &out.Connection
+		*out = new(VCenterConnectionSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(VCenterTLSPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterIdentitySpec.
+func (in *VSphereClusterIdentitySpec) DeepCopy() *VSphereClusterIdentitySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereClusterIdentitySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereClusterIdentityStatus) DeepCopyInto(out *VSphereClusterIdentityStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(apiv1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterIdentityStatus.
+func (in *VSphereClusterIdentityStatus) DeepCopy() *VSphereClusterIdentityStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereClusterIdentityStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereClusterList) DeepCopyInto(out *VSphereClusterList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VSphereCluster, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterList.
+func (in *VSphereClusterList) DeepCopy() *VSphereClusterList {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereClusterList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereClusterList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereClusterSpec) DeepCopyInto(out *VSphereClusterSpec) {
+	*out = *in
+	out.ControlPlaneEndpoint = in.ControlPlaneEndpoint
+	if in.ControlPlaneEndpointDNS != nil {
+		in, out := &in.ControlPlaneEndpointDNS, &out.ControlPlaneEndpointDNS
+		*out = new(ControlPlaneEndpointDNSSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IdentityRef != nil {
+		in, out := &in.IdentityRef, &out.IdentityRef
+		*out = new(VSphereIdentityReference)
+		**out = **in
+	}
+	if in.CloudProvider != nil {
+		in, out := &in.CloudProvider, &out.CloudProvider
+		*out = new(CloudProviderSpec)
+		**out = **in
+	}
+	if in.CSI != nil {
+		in, out := &in.CSI, &out.CSI
+		*out = new(CSISpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NSXT != nil {
+		in, out := &in.NSXT, &out.NSXT
+		*out = new(NSXTSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Connection != nil {
+		in, out := &in.Connection, &out.Connection
+		*out = new(VCenterConnectionSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResourceQuota != nil {
+		in, out := &in.ResourceQuota, &out.ResourceQuota
+		*out = new(ResourceQuotaSpec)
+		**out = **in
+	}
+	if in.VCenters != nil {
+		in, out := &in.VCenters, &out.VCenters
+		*out = make([]VCenterSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterSpec.
+func (in *VSphereClusterSpec) DeepCopy() *VSphereClusterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereClusterSpec)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -495,52 +1159,443 @@ func (in *VSphereClusterSpec) DeepCopy() *VSphereClusterSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VSphereClusterStatus) DeepCopyInto(out *VSphereClusterStatus) {
 	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make(apiv1beta1.Conditions, len(*in))
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(apiv1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FailureDomains != nil {
+		in, out := &in.FailureDomains, &out.FailureDomains
+		*out = make(apiv1beta1.FailureDomains, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.OrphanedVMs != nil {
+		in, out := &in.OrphanedVMs, &out.OrphanedVMs
+		*out = make([]OrphanedVM, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastOrphanedVMSweepTime != nil {
+		in, out := &in.LastOrphanedVMSweepTime, &out.LastOrphanedVMSweepTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ControlPlaneEndpointDNSRecord != nil {
+		in, out := &in.ControlPlaneEndpointDNSRecord, &out.ControlPlaneEndpointDNSRecord
+		*out = new(DNSRecord)
+		**out = **in
+	}
+	if in.Deletion != nil {
+		in, out := &in.Deletion, &out.Deletion
+		*out = new(ClusterDeletionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterStatus.
+func (in *VSphereClusterStatus) DeepCopy() *VSphereClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereClusterTemplate) DeepCopyInto(out *VSphereClusterTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterTemplate.
+func (in *VSphereClusterTemplate) DeepCopy() *VSphereClusterTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereClusterTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereClusterTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereClusterTemplateList) DeepCopyInto(out *VSphereClusterTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VSphereClusterTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterTemplateList.
+func (in *VSphereClusterTemplateList) DeepCopy() *VSphereClusterTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereClusterTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereClusterTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereClusterTemplateResource) DeepCopyInto(out *VSphereClusterTemplateResource) {
+	*out = *in
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterTemplateResource.
+func (in *VSphereClusterTemplateResource) DeepCopy() *VSphereClusterTemplateResource {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereClusterTemplateResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereClusterTemplateSpec) DeepCopyInto(out *VSphereClusterTemplateSpec) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterTemplateSpec.
+func (in *VSphereClusterTemplateSpec) DeepCopy() *VSphereClusterTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereClusterTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereDeploymentZone) DeepCopyInto(out *VSphereDeploymentZone) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereDeploymentZone.
+func (in *VSphereDeploymentZone) DeepCopy() *VSphereDeploymentZone {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereDeploymentZone)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereDeploymentZone) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereDeploymentZoneList) DeepCopyInto(out *VSphereDeploymentZoneList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VSphereDeploymentZone, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereDeploymentZoneList.
+func (in *VSphereDeploymentZoneList) DeepCopy() *VSphereDeploymentZoneList {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereDeploymentZoneList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereDeploymentZoneList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereDeploymentZoneSpec) DeepCopyInto(out *VSphereDeploymentZoneSpec) {
+	*out = *in
+	if in.ControlPlane != nil {
+		in, out := &in.ControlPlane, &out.ControlPlane
+		*out = new(bool)
+		**out = **in
+	}
+	out.PlacementConstraint = in.PlacementConstraint
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereDeploymentZoneSpec.
+func (in *VSphereDeploymentZoneSpec) DeepCopy() *VSphereDeploymentZoneSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereDeploymentZoneSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereDeploymentZoneStatus) DeepCopyInto(out *VSphereDeploymentZoneStatus) {
+	*out = *in
+	if in.Ready != nil {
+		in, out := &in.Ready, &out.Ready
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(apiv1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereDeploymentZoneStatus.
+func (in *VSphereDeploymentZoneStatus) DeepCopy() *VSphereDeploymentZoneStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereDeploymentZoneStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereFailureDomain) DeepCopyInto(out *VSphereFailureDomain) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereFailureDomain.
+func (in *VSphereFailureDomain) DeepCopy() *VSphereFailureDomain {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereFailureDomain)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereFailureDomain) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereFailureDomainList) DeepCopyInto(out *VSphereFailureDomainList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VSphereFailureDomain, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.FailureDomains != nil {
-		in, out := &in.FailureDomains, &out.FailureDomains
-		*out = make(apiv1beta1.FailureDomains, len(*in))
-		for key, val := range *in {
-			(*out)[key] = *val.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereFailureDomainList.
+func (in *VSphereFailureDomainList) DeepCopy() *VSphereFailureDomainList {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereFailureDomainList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereFailureDomainList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereFailureDomainSpec) DeepCopyInto(out *VSphereFailureDomainSpec) {
+	*out = *in
+	in.Region.DeepCopyInto(&out.Region)
+	in.Zone.DeepCopyInto(&out.Zone)
+	in.Topology.DeepCopyInto(&out.Topology)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereFailureDomainSpec.
+func (in *VSphereFailureDomainSpec) DeepCopy() *VSphereFailureDomainSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereFailureDomainSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereIPPool) DeepCopyInto(out *VSphereIPPool) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereIPPool.
+func (in *VSphereIPPool) DeepCopy() *VSphereIPPool {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereIPPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereIPPool) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereIPPoolList) DeepCopyInto(out *VSphereIPPoolList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VSphereIPPool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterStatus.
-func (in *VSphereClusterStatus) DeepCopy() *VSphereClusterStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereIPPoolList.
+func (in *VSphereIPPoolList) DeepCopy() *VSphereIPPoolList {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereClusterStatus)
+	out := new(VSphereIPPoolList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereIPPoolList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereClusterTemplate) DeepCopyInto(out *VSphereClusterTemplate) {
+func (in *VSphereIPPoolSpec) DeepCopyInto(out *VSphereIPPoolSpec) {
+	*out = *in
+	if in.Infoblox != nil {
+		in, out := &in.Infoblox, &out.Infoblox
+		*out = new(InfobloxIPPoolSpec)
+		**out = **in
+	}
+	if in.Nameservers != nil {
+		in, out := &in.Nameservers, &out.Nameservers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereIPPoolSpec.
+func (in *VSphereIPPoolSpec) DeepCopy() *VSphereIPPoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereIPPoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereIdentityReference) DeepCopyInto(out *VSphereIdentityReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereIdentityReference.
+func (in *VSphereIdentityReference) DeepCopy() *VSphereIdentityReference {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereIdentityReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereLoadBalancerVM) DeepCopyInto(out *VSphereLoadBalancerVM) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterTemplate.
-func (in *VSphereClusterTemplate) DeepCopy() *VSphereClusterTemplate {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereLoadBalancerVM.
+func (in *VSphereLoadBalancerVM) DeepCopy() *VSphereLoadBalancerVM {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereClusterTemplate)
+	out := new(VSphereLoadBalancerVM)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VSphereClusterTemplate) DeepCopyObject() runtime.Object {
+func (in *VSphereLoadBalancerVM) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -548,31 +1603,31 @@ func (in *VSphereClusterTemplate) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereClusterTemplateList) DeepCopyInto(out *VSphereClusterTemplateList) {
+func (in *VSphereLoadBalancerVMList) DeepCopyInto(out *VSphereLoadBalancerVMList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]VSphereClusterTemplate, len(*in))
+		*out = make([]VSphereLoadBalancerVM, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterTemplateList.
-func (in *VSphereClusterTemplateList) DeepCopy() *VSphereClusterTemplateList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereLoadBalancerVMList.
+func (in *VSphereLoadBalancerVMList) DeepCopy() *VSphereLoadBalancerVMList {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereClusterTemplateList)
+	out := new(VSphereLoadBalancerVMList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VSphereClusterTemplateList) DeepCopyObject() runtime.Object {
+func (in *VSphereLoadBalancerVMList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -580,58 +1635,159 @@ func (in *VSphereClusterTemplateList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereClusterTemplateResource) DeepCopyInto(out *VSphereClusterTemplateResource) {
+func (in *VSphereLoadBalancerVMSpec) DeepCopyInto(out *VSphereLoadBalancerVMSpec) {
+	*out = *in
+	if in.SSHAuthorizedKeys != nil {
+		in, out := &in.SSHAuthorizedKeys, &out.SSHAuthorizedKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.VirtualMachineCloneSpec.DeepCopyInto(&out.VirtualMachineCloneSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereLoadBalancerVMSpec.
+func (in *VSphereLoadBalancerVMSpec) DeepCopy() *VSphereLoadBalancerVMSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereLoadBalancerVMSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereLoadBalancerVMStatus) DeepCopyInto(out *VSphereLoadBalancerVMStatus) {
+	*out = *in
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(apiv1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereLoadBalancerVMStatus.
+func (in *VSphereLoadBalancerVMStatus) DeepCopy() *VSphereLoadBalancerVMStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereLoadBalancerVMStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereMachine) DeepCopyInto(out *VSphereMachine) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterTemplateResource.
-func (in *VSphereClusterTemplateResource) DeepCopy() *VSphereClusterTemplateResource {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachine.
+func (in *VSphereMachine) DeepCopy() *VSphereMachine {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereClusterTemplateResource)
+	out := new(VSphereMachine)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereMachine) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereClusterTemplateSpec) DeepCopyInto(out *VSphereClusterTemplateSpec) {
+func (in *VSphereMachineImage) DeepCopyInto(out *VSphereMachineImage) {
 	*out = *in
-	in.Template.DeepCopyInto(&out.Template)
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterTemplateSpec.
-func (in *VSphereClusterTemplateSpec) DeepCopy() *VSphereClusterTemplateSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineImage.
+func (in *VSphereMachineImage) DeepCopy() *VSphereMachineImage {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereClusterTemplateSpec)
+	out := new(VSphereMachineImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereMachineImage) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereMachineImageList) DeepCopyInto(out *VSphereMachineImageList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VSphereMachineImage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineImageList.
+func (in *VSphereMachineImageList) DeepCopy() *VSphereMachineImageList {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereMachineImageList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereMachineImageList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereDeploymentZone) DeepCopyInto(out *VSphereDeploymentZone) {
+func (in *VSphereMachineImageMapping) DeepCopyInto(out *VSphereMachineImageMapping) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereDeploymentZone.
-func (in *VSphereDeploymentZone) DeepCopy() *VSphereDeploymentZone {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineImageMapping.
+func (in *VSphereMachineImageMapping) DeepCopy() *VSphereMachineImageMapping {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereDeploymentZone)
+	out := new(VSphereMachineImageMapping)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VSphereDeploymentZone) DeepCopyObject() runtime.Object {
+func (in *VSphereMachineImageMapping) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -639,31 +1795,31 @@ func (in *VSphereDeploymentZone) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereDeploymentZoneList) DeepCopyInto(out *VSphereDeploymentZoneList) {
+func (in *VSphereMachineImageMappingList) DeepCopyInto(out *VSphereMachineImageMappingList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]VSphereDeploymentZone, len(*in))
+		*out = make([]VSphereMachineImageMapping, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereDeploymentZoneList.
-func (in *VSphereDeploymentZoneList) DeepCopy() *VSphereDeploymentZoneList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineImageMappingList.
+func (in *VSphereMachineImageMappingList) DeepCopy() *VSphereMachineImageMappingList {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereDeploymentZoneList)
+	out := new(VSphereMachineImageMappingList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VSphereDeploymentZoneList) DeepCopyObject() runtime.Object {
+func (in *VSphereMachineImageMappingList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -671,34 +1827,43 @@ func (in *VSphereDeploymentZoneList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereDeploymentZoneSpec) DeepCopyInto(out *VSphereDeploymentZoneSpec) {
+func (in *VSphereMachineImageMappingSpec) DeepCopyInto(out *VSphereMachineImageMappingSpec) {
 	*out = *in
-	if in.ControlPlane != nil {
-		in, out := &in.ControlPlane, &out.ControlPlane
-		*out = new(bool)
-		**out = **in
+	if in.Mappings != nil {
+		in, out := &in.Mappings, &out.Mappings
+		*out = make([]KubernetesVersionImage, len(*in))
+		copy(*out, *in)
 	}
-	out.PlacementConstraint = in.PlacementConstraint
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereDeploymentZoneSpec.
-func (in *VSphereDeploymentZoneSpec) DeepCopy() *VSphereDeploymentZoneSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineImageMappingSpec.
+func (in *VSphereMachineImageMappingSpec) DeepCopy() *VSphereMachineImageMappingSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereDeploymentZoneSpec)
+	out := new(VSphereMachineImageMappingSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereDeploymentZoneStatus) DeepCopyInto(out *VSphereDeploymentZoneStatus) {
+func (in *VSphereMachineImageSpec) DeepCopyInto(out *VSphereMachineImageSpec) {
 	*out = *in
-	if in.Ready != nil {
-		in, out := &in.Ready, &out.Ready
-		*out = new(bool)
-		**out = **in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineImageSpec.
+func (in *VSphereMachineImageSpec) DeepCopy() *VSphereMachineImageSpec {
+	if in == nil {
+		return nil
 	}
+	out := new(VSphereMachineImageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereMachineImageStatus) DeepCopyInto(out *VSphereMachineImageStatus) {
+	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make(apiv1beta1.Conditions, len(*in))
@@ -708,36 +1873,42 @@ func (in *VSphereDeploymentZoneStatus) DeepCopyInto(out *VSphereDeploymentZoneSt
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereDeploymentZoneStatus.
-func (in *VSphereDeploymentZoneStatus) DeepCopy() *VSphereDeploymentZoneStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineImageStatus.
+func (in *VSphereMachineImageStatus) DeepCopy() *VSphereMachineImageStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereDeploymentZoneStatus)
+	out := new(VSphereMachineImageStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereFailureDomain) DeepCopyInto(out *VSphereFailureDomain) {
+func (in *VSphereMachineList) DeepCopyInto(out *VSphereMachineList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VSphereMachine, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereFailureDomain.
-func (in *VSphereFailureDomain) DeepCopy() *VSphereFailureDomain {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineList.
+func (in *VSphereMachineList) DeepCopy() *VSphereMachineList {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereFailureDomain)
+	out := new(VSphereMachineList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VSphereFailureDomain) DeepCopyObject() runtime.Object {
+func (in *VSphereMachineList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -745,31 +1916,26 @@ func (in *VSphereFailureDomain) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereFailureDomainList) DeepCopyInto(out *VSphereFailureDomainList) {
+func (in *VSphereMachinePool) DeepCopyInto(out *VSphereMachinePool) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]VSphereFailureDomain, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereFailureDomainList.
-func (in *VSphereFailureDomainList) DeepCopy() *VSphereFailureDomainList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachinePool.
+func (in *VSphereMachinePool) DeepCopy() *VSphereMachinePool {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereFailureDomainList)
+	out := new(VSphereMachinePool)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VSphereFailureDomainList) DeepCopyObject() runtime.Object {
+func (in *VSphereMachinePool) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -777,97 +1943,80 @@ func (in *VSphereFailureDomainList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereFailureDomainSpec) DeepCopyInto(out *VSphereFailureDomainSpec) {
+func (in *VSphereMachinePoolList) DeepCopyInto(out *VSphereMachinePoolList) {
 	*out = *in
-	in.Region.DeepCopyInto(&out.Region)
-	in.Zone.DeepCopyInto(&out.Zone)
-	in.Topology.DeepCopyInto(&out.Topology)
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VSphereMachinePool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereFailureDomainSpec.
-func (in *VSphereFailureDomainSpec) DeepCopy() *VSphereFailureDomainSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachinePoolList.
+func (in *VSphereMachinePoolList) DeepCopy() *VSphereMachinePoolList {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereFailureDomainSpec)
+	out := new(VSphereMachinePoolList)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereIdentityReference) DeepCopyInto(out *VSphereIdentityReference) {
-	*out = *in
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereIdentityReference.
-func (in *VSphereIdentityReference) DeepCopy() *VSphereIdentityReference {
-	if in == nil {
-		return nil
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereMachinePoolList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
 	}
-	out := new(VSphereIdentityReference)
-	in.DeepCopyInto(out)
-	return out
+	return nil
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereMachine) DeepCopyInto(out *VSphereMachine) {
+func (in *VSphereMachinePoolSpec) DeepCopyInto(out *VSphereMachinePoolSpec) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	in.Template.DeepCopyInto(&out.Template)
+	if in.ProviderIDList != nil {
+		in, out := &in.ProviderIDList, &out.ProviderIDList
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachine.
-func (in *VSphereMachine) DeepCopy() *VSphereMachine {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachinePoolSpec.
+func (in *VSphereMachinePoolSpec) DeepCopy() *VSphereMachinePoolSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereMachine)
+	out := new(VSphereMachinePoolSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VSphereMachine) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereMachineList) DeepCopyInto(out *VSphereMachineList) {
+func (in *VSphereMachinePoolStatus) DeepCopyInto(out *VSphereMachinePoolStatus) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]VSphereMachine, len(*in))
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(apiv1beta1.Conditions, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineList.
-func (in *VSphereMachineList) DeepCopy() *VSphereMachineList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachinePoolStatus.
+func (in *VSphereMachinePoolStatus) DeepCopy() *VSphereMachinePoolStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereMachineList)
+	out := new(VSphereMachinePoolStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VSphereMachineList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VSphereMachineSpec) DeepCopyInto(out *VSphereMachineSpec) {
 	*out = *in
@@ -882,6 +2031,31 @@ func (in *VSphereMachineSpec) DeepCopyInto(out *VSphereMachineSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.GuestSoftPowerOffTimeout != nil {
+		in, out := &in.GuestSoftPowerOffTimeout, &out.GuestSoftPowerOffTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.SnapshotSchedule != nil {
+		in, out := &in.SnapshotSchedule, &out.SnapshotSchedule
+		*out = new(VirtualMachineSnapshotSchedule)
+		**out = **in
+	}
+	if in.FailureRetryPolicy != nil {
+		in, out := &in.FailureRetryPolicy, &out.FailureRetryPolicy
+		*out = new(VirtualMachineFailureRetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Placement != nil {
+		in, out := &in.Placement, &out.Placement
+		*out = new(VirtualMachinePlacement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DeploymentZones != nil {
+		in, out := &in.DeploymentZones, &out.DeploymentZones
+		*out = make([]DeploymentZoneWeight, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineSpec.
@@ -926,38 +2100,157 @@ func (in *VSphereMachineStatus) DeepCopyInto(out *VSphereMachineStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.NodeTopology != nil {
+		in, out := &in.NodeTopology, &out.NodeTopology
+		*out = new(VirtualMachineTopology)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineStatus.
+func (in *VSphereMachineStatus) DeepCopy() *VSphereMachineStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereMachineStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereMachineTemplate) DeepCopyInto(out *VSphereMachineTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineTemplate.
+func (in *VSphereMachineTemplate) DeepCopy() *VSphereMachineTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereMachineTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereMachineTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereMachineTemplateList) DeepCopyInto(out *VSphereMachineTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VSphereMachineTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineTemplateList.
+func (in *VSphereMachineTemplateList) DeepCopy() *VSphereMachineTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereMachineTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereMachineTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereMachineTemplateResource) DeepCopyInto(out *VSphereMachineTemplateResource) {
+	*out = *in
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineTemplateResource.
+func (in *VSphereMachineTemplateResource) DeepCopy() *VSphereMachineTemplateResource {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereMachineTemplateResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereMachineTemplateSpec) DeepCopyInto(out *VSphereMachineTemplateSpec) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineTemplateSpec.
+func (in *VSphereMachineTemplateSpec) DeepCopy() *VSphereMachineTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereMachineTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereMachineTemplateStatus) DeepCopyInto(out *VSphereMachineTemplateStatus) {
+	*out = *in
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineStatus.
-func (in *VSphereMachineStatus) DeepCopy() *VSphereMachineStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineTemplateStatus.
+func (in *VSphereMachineTemplateStatus) DeepCopy() *VSphereMachineTemplateStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereMachineStatus)
+	out := new(VSphereMachineTemplateStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereMachineTemplate) DeepCopyInto(out *VSphereMachineTemplate) {
+func (in *VSphereProviderConfig) DeepCopyInto(out *VSphereProviderConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineTemplate.
-func (in *VSphereMachineTemplate) DeepCopy() *VSphereMachineTemplate {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereProviderConfig.
+func (in *VSphereProviderConfig) DeepCopy() *VSphereProviderConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereMachineTemplate)
+	out := new(VSphereProviderConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VSphereMachineTemplate) DeepCopyObject() runtime.Object {
+func (in *VSphereProviderConfig) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -965,31 +2258,31 @@ func (in *VSphereMachineTemplate) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereMachineTemplateList) DeepCopyInto(out *VSphereMachineTemplateList) {
+func (in *VSphereProviderConfigList) DeepCopyInto(out *VSphereProviderConfigList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]VSphereMachineTemplate, len(*in))
+		*out = make([]VSphereProviderConfig, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineTemplateList.
-func (in *VSphereMachineTemplateList) DeepCopy() *VSphereMachineTemplateList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereProviderConfigList.
+func (in *VSphereProviderConfigList) DeepCopy() *VSphereProviderConfigList {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereMachineTemplateList)
+	out := new(VSphereProviderConfigList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VSphereMachineTemplateList) DeepCopyObject() runtime.Object {
+func (in *VSphereProviderConfigList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -997,34 +2290,31 @@ func (in *VSphereMachineTemplateList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereMachineTemplateResource) DeepCopyInto(out *VSphereMachineTemplateResource) {
+func (in *VSphereProviderConfigSpec) DeepCopyInto(out *VSphereProviderConfigSpec) {
 	*out = *in
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineTemplateResource.
-func (in *VSphereMachineTemplateResource) DeepCopy() *VSphereMachineTemplateResource {
-	if in == nil {
-		return nil
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = make([]KubernetesVersionTemplate, len(*in))
+		copy(*out, *in)
+	}
+	if in.KeepAlive != nil {
+		in, out := &in.KeepAlive, &out.KeepAlive
+		*out = new(VCenterKeepAlive)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(VCenterRateLimit)
+		**out = **in
 	}
-	out := new(VSphereMachineTemplateResource)
-	in.DeepCopyInto(out)
-	return out
-}
-
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VSphereMachineTemplateSpec) DeepCopyInto(out *VSphereMachineTemplateSpec) {
-	*out = *in
-	in.Template.DeepCopyInto(&out.Template)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereMachineTemplateSpec.
-func (in *VSphereMachineTemplateSpec) DeepCopy() *VSphereMachineTemplateSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereProviderConfigSpec.
+func (in *VSphereProviderConfigSpec) DeepCopy() *VSphereProviderConfigSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(VSphereMachineTemplateSpec)
+	out := new(VSphereProviderConfigSpec)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -1088,15 +2378,135 @@ func (in *VSphereVMList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereVMSnapshot) DeepCopyInto(out *VSphereVMSnapshot) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereVMSnapshot.
+func (in *VSphereVMSnapshot) DeepCopy() *VSphereVMSnapshot {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereVMSnapshot)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereVMSnapshot) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereVMSnapshotList) DeepCopyInto(out *VSphereVMSnapshotList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VSphereVMSnapshot, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereVMSnapshotList.
+func (in *VSphereVMSnapshotList) DeepCopy() *VSphereVMSnapshotList {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereVMSnapshotList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VSphereVMSnapshotList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereVMSnapshotSpec) DeepCopyInto(out *VSphereVMSnapshotSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereVMSnapshotSpec.
+func (in *VSphereVMSnapshotSpec) DeepCopy() *VSphereVMSnapshotSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereVMSnapshotSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereVMSnapshotStatus) DeepCopyInto(out *VSphereVMSnapshotStatus) {
+	*out = *in
+	if in.CreationTime != nil {
+		in, out := &in.CreationTime, &out.CreationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(apiv1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereVMSnapshotStatus.
+func (in *VSphereVMSnapshotStatus) DeepCopy() *VSphereVMSnapshotStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereVMSnapshotStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VSphereVMSpec) DeepCopyInto(out *VSphereVMSpec) {
 	*out = *in
 	in.VirtualMachineCloneSpec.DeepCopyInto(&out.VirtualMachineCloneSpec)
 	if in.BootstrapRef != nil {
 		in, out := &in.BootstrapRef, &out.BootstrapRef
-		*out = new(v1.ObjectReference)
+		*out = new(corev1.ObjectReference)
 		**out = **in
 	}
+	if in.GuestSoftPowerOffTimeout != nil {
+		in, out := &in.GuestSoftPowerOffTimeout, &out.GuestSoftPowerOffTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.SnapshotSchedule != nil {
+		in, out := &in.SnapshotSchedule, &out.SnapshotSchedule
+		*out = new(VirtualMachineSnapshotSchedule)
+		**out = **in
+	}
+	if in.FailureRetryPolicy != nil {
+		in, out := &in.FailureRetryPolicy, &out.FailureRetryPolicy
+		*out = new(VirtualMachineFailureRetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Placement != nil {
+		in, out := &in.Placement, &out.Placement
+		*out = new(VirtualMachinePlacement)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereVMSpec.
@@ -1117,6 +2527,37 @@ func (in *VSphereVMStatus) DeepCopyInto(out *VSphereVMStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.MachineAddresses != nil {
+		in, out := &in.MachineAddresses, &out.MachineAddresses
+		*out = make([]apiv1beta1.MachineAddress, len(*in))
+		copy(*out, *in)
+	}
+	if in.AddressWaitStartTime != nil {
+		in, out := &in.AddressWaitStartTime, &out.AddressWaitStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.IPAllocations != nil {
+		in, out := &in.IPAllocations, &out.IPAllocations
+		*out = make([]IPAllocation, len(*in))
+		copy(*out, *in)
+	}
+	if in.Migrations != nil {
+		in, out := &in.Migrations, &out.Migrations
+		*out = make([]VirtualMachineMigration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Drift != nil {
+		in, out := &in.Drift, &out.Drift
+		*out = make([]VirtualMachineDrift, len(*in))
+		copy(*out, *in)
+	}
+	if in.GuestTools != nil {
+		in, out := &in.GuestTools, &out.GuestTools
+		*out = new(GuestToolsStatus)
+		**out = **in
+	}
 	in.RetryAfter.DeepCopyInto(&out.RetryAfter)
 	if in.Network != nil {
 		in, out := &in.Network, &out.Network
@@ -1154,6 +2595,21 @@ func (in *VSphereVMStatus) DeepCopy() *VSphereVMStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultCredentialSource) DeepCopyInto(out *VaultCredentialSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultCredentialSource.
+func (in *VaultCredentialSource) DeepCopy() *VaultCredentialSource {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultCredentialSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtualMachine) DeepCopyInto(out *VirtualMachine) {
 	*out = *in
@@ -1164,6 +2620,11 @@ func (in *VirtualMachine) DeepCopyInto(out *VirtualMachine) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.DNSNames != nil {
+		in, out := &in.DNSNames, &out.DNSNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachine.
@@ -1179,7 +2640,32 @@ func (in *VirtualMachine) DeepCopy() *VirtualMachine {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtualMachineCloneSpec) DeepCopyInto(out *VirtualMachineCloneSpec) {
 	*out = *in
+	if in.Datastores != nil {
+		in, out := &in.Datastores, &out.Datastores
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ResourcePoolLimits != nil {
+		in, out := &in.ResourcePoolLimits, &out.ResourcePoolLimits
+		*out = new(ResourcePoolLimits)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Network.DeepCopyInto(&out.Network)
+	if in.ResourceAllocation != nil {
+		in, out := &in.ResourceAllocation, &out.ResourceAllocation
+		*out = new(ResourceAllocation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NumaNodeAffinity != nil {
+		in, out := &in.NumaNodeAffinity, &out.NumaNodeAffinity
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	if in.CPUPinning != nil {
+		in, out := &in.CPUPinning, &out.CPUPinning
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
 	if in.AdditionalDisksGiB != nil {
 		in, out := &in.AdditionalDisksGiB, &out.AdditionalDisksGiB
 		*out = make([]int32, len(*in))
@@ -1197,6 +2683,11 @@ func (in *VirtualMachineCloneSpec) DeepCopyInto(out *VirtualMachineCloneSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.MetadataPropagation != nil {
+		in, out := &in.MetadataPropagation, &out.MetadataPropagation
+		*out = make([]MetadataPropagationSpec, len(*in))
+		copy(*out, *in)
+	}
 	if in.PciDevices != nil {
 		in, out := &in.PciDevices, &out.PciDevices
 		*out = make([]PCIDeviceSpec, len(*in))
@@ -1204,6 +2695,16 @@ func (in *VirtualMachineCloneSpec) DeepCopyInto(out *VirtualMachineCloneSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Disks != nil {
+		in, out := &in.Disks, &out.Disks
+		*out = make([]DiskSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.GuestTools != nil {
+		in, out := &in.GuestTools, &out.GuestTools
+		*out = new(GuestToolsSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineCloneSpec.
@@ -1215,3 +2716,110 @@ func (in *VirtualMachineCloneSpec) DeepCopy() *VirtualMachineCloneSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineDrift) DeepCopyInto(out *VirtualMachineDrift) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineDrift.
+func (in *VirtualMachineDrift) DeepCopy() *VirtualMachineDrift {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineDrift)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineFailureRetryPolicy) DeepCopyInto(out *VirtualMachineFailureRetryPolicy) {
+	*out = *in
+	if in.Reasons != nil {
+		in, out := &in.Reasons, &out.Reasons
+		*out = make([]errors.MachineStatusError, len(*in))
+		copy(*out, *in)
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineFailureRetryPolicy.
+func (in *VirtualMachineFailureRetryPolicy) DeepCopy() *VirtualMachineFailureRetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineFailureRetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineMigration) DeepCopyInto(out *VirtualMachineMigration) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineMigration.
+func (in *VirtualMachineMigration) DeepCopy() *VirtualMachineMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachinePlacement) DeepCopyInto(out *VirtualMachinePlacement) {
+	*out = *in
+	if in.ResourcePools != nil {
+		in, out := &in.ResourcePools, &out.ResourcePools
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachinePlacement.
+func (in *VirtualMachinePlacement) DeepCopy() *VirtualMachinePlacement {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachinePlacement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineSnapshotSchedule) DeepCopyInto(out *VirtualMachineSnapshotSchedule) {
+	*out = *in
+	out.Interval = in.Interval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineSnapshotSchedule.
+func (in *VirtualMachineSnapshotSchedule) DeepCopy() *VirtualMachineSnapshotSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineSnapshotSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineTopology) DeepCopyInto(out *VirtualMachineTopology) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineTopology.
+func (in *VirtualMachineTopology) DeepCopy() *VirtualMachineTopology {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineTopology)
+	in.DeepCopyInto(out)
+	return out
+}
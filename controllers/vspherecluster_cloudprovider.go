@@ -0,0 +1,229 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controllers/remote"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/identity"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/cloudprovider"
+	infrautilv1 "sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+// workloadFieldOwner is the field manager used when applying add-on objects
+// into the workload cluster.
+const workloadFieldOwner = "capv-controller-manager"
+
+// reconcileCloudProvider installs or upgrades the vSphere cloud controller
+// manager in the workload cluster.
+func (r clusterReconciler) reconcileCloudProvider(ctx *context.ClusterContext) error {
+	cpiSpec := ctx.VSphereCluster.Spec.CloudProvider
+	if cpiSpec != nil && cpiSpec.Disabled {
+		conditions.Delete(ctx.VSphereCluster, infrav1.CCMAvailableCondition)
+		return nil
+	}
+
+	guestClient, err := remote.NewClusterClient(ctx, r.Name, r.Client, client.ObjectKeyFromObject(ctx.Cluster))
+	if err != nil {
+		return errors.Wrapf(err, "failed to get client for workload cluster %s", ctx)
+	}
+
+	vcenters, err := r.cloudProviderVCenters(ctx)
+	if err != nil {
+		return err
+	}
+	cloudConfig, err := cloudprovider.CloudControllerManagerCloudConfig(vcenters)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate cloud config")
+	}
+
+	var version string
+	if cpiSpec != nil {
+		version = cpiSpec.Version
+	}
+
+	objs := []client.Object{
+		cloudprovider.CloudControllerManagerServiceAccount(),
+		cloudprovider.CloudControllerManagerCredentialsSecret(vcenters),
+		cloudprovider.CloudControllerManagerConfigMap(cloudConfig),
+		cloudprovider.CloudControllerManagerClusterRole(),
+		cloudprovider.CloudControllerManagerClusterRoleBinding(),
+		cloudprovider.CloudControllerManagerRoleBinding(),
+		cloudprovider.CloudControllerManagerService(),
+		cloudprovider.CloudControllerManagerDaemonSet(cloudprovider.CPIControllerImage(version), cloudprovider.CloudControllerManagerArgs()),
+	}
+	for _, obj := range objs {
+		if err := applyWorkloadObject(ctx, guestClient, obj); err != nil {
+			return err
+		}
+	}
+
+	conditions.MarkTrue(ctx.VSphereCluster, infrav1.CCMAvailableCondition)
+	return nil
+}
+
+// vCenterCredentials returns the credentials used to reconcile the cluster,
+// either from its identity or from the ones the manager was started with.
+func (r clusterReconciler) vCenterCredentials(ctx *context.ClusterContext) (*identity.Credentials, error) {
+	if ctx.VSphereCluster.Spec.IdentityRef != nil {
+		return identity.GetCredentials(ctx, r.Client, ctx.VSphereCluster, r.Namespace)
+	}
+	return &identity.Credentials{Username: ctx.Username, Password: ctx.Password}, nil
+}
+
+// cloudProviderVCenters returns the vCenters the cloud controller manager
+// connects to: the one of the cluster followed by the additional ones of its
+// spec, each with its credentials and the datacenters of its machines.
+func (r clusterReconciler) cloudProviderVCenters(ctx *context.ClusterContext) ([]cloudprovider.VCenter, error) {
+	creds, err := r.vCenterCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	datacenters, err := r.clusterDatacentersByServer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	server := ctx.VSphereCluster.Spec.Server
+	vcenters := []cloudprovider.VCenter{{
+		Server:      server,
+		Thumbprint:  ctx.VSphereCluster.Spec.Thumbprint,
+		Username:    creds.Username,
+		Password:    creds.Password,
+		Datacenters: datacenters[server],
+	}}
+	for _, vcenter := range ctx.VSphereCluster.Spec.VCenters {
+		if vcenter.Server == server {
+			continue
+		}
+		vcenterCreds := &identity.Credentials{Username: ctx.Username, Password: ctx.Password}
+		if identity.IdentityRefForServer(ctx.VSphereCluster, vcenter.Server) != nil {
+			if vcenterCreds, err = identity.GetCredentialsForServer(ctx, r.Client, ctx.VSphereCluster, vcenter.Server, r.Namespace); err != nil {
+				return nil, err
+			}
+		}
+		vcenters = append(vcenters, cloudprovider.VCenter{
+			Server:      vcenter.Server,
+			Thumbprint:  vcenter.Thumbprint,
+			Username:    vcenterCreds.Username,
+			Password:    vcenterCreds.Password,
+			Datacenters: datacenters[vcenter.Server],
+		})
+	}
+	return vcenters, nil
+}
+
+// clusterDatacenters returns the sorted list of datacenters the machines of
+// the cluster are deployed into in the vCenter of the cluster.
+func (r clusterReconciler) clusterDatacenters(ctx *context.ClusterContext) ([]string, error) {
+	datacenters, err := r.clusterDatacentersByServer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return datacenters[ctx.VSphereCluster.Spec.Server], nil
+}
+
+// clusterDatacentersByServer returns the sorted lists of datacenters the
+// machines of the cluster are deployed into by vCenter. The machines without
+// a server are deployed into the vCenter of the cluster.
+func (r clusterReconciler) clusterDatacentersByServer(ctx *context.ClusterContext) (map[string][]string, error) {
+	specs, err := clusterCloneSpecs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]map[string]struct{}{}
+	datacenters := map[string][]string{}
+	for _, spec := range specs {
+		server := spec.Server
+		if server == "" {
+			server = ctx.VSphereCluster.Spec.Server
+		}
+		if seen[server] == nil {
+			seen[server] = map[string]struct{}{}
+		}
+		dc := spec.Datacenter
+		if _, ok := seen[server][dc]; dc == "" || ok {
+			continue
+		}
+		seen[server][dc] = struct{}{}
+		datacenters[server] = append(datacenters[server], dc)
+	}
+	for _, dcs := range datacenters {
+		sort.Strings(dcs)
+	}
+	return datacenters, nil
+}
+
+// clusterCloneSpecs returns the clone specs of the VSphereMachines of the
+// cluster. The clone spec of the VSphereVM of a machine is returned instead
+// once it exists, as it holds the placement resolved from the failure domain
+// of the machine, e.g. its datacenter.
+func clusterCloneSpecs(ctx *context.ClusterContext) ([]*infrav1.VirtualMachineCloneSpec, error) {
+	vsphereMachines, err := infrautilv1.GetVSphereMachinesInCluster(ctx, ctx.Client, ctx.Cluster.Namespace, ctx.Cluster.Name)
+	if err != nil {
+		return nil, errors.Wrapf(err,
+			"unable to list VSphereMachines part of VSphereCluster %s/%s", ctx.VSphereCluster.Namespace, ctx.VSphereCluster.Name)
+	}
+	vsphereVMs := &infrav1.VSphereVMList{}
+	if err := ctx.Client.List(ctx, vsphereVMs,
+		client.InNamespace(ctx.Cluster.Namespace),
+		client.MatchingLabels{clusterv1.ClusterLabelName: ctx.Cluster.Name}); err != nil {
+		return nil, errors.Wrapf(err, "failed to list VSphereVMs for %s", ctx)
+	}
+	vmSpecs := map[string]*infrav1.VirtualMachineCloneSpec{}
+	for i := range vsphereVMs.Items {
+		vsphereVM := &vsphereVMs.Items[i]
+		for _, ref := range vsphereVM.OwnerReferences {
+			if ref.Kind == "VSphereMachine" {
+				vmSpecs[ref.Name] = &vsphereVM.Spec.VirtualMachineCloneSpec
+			}
+		}
+	}
+
+	specs := make([]*infrav1.VirtualMachineCloneSpec, 0, len(vsphereMachines))
+	for _, vsphereMachine := range vsphereMachines {
+		if spec, ok := vmSpecs[vsphereMachine.Name]; ok {
+			specs = append(specs, spec)
+			continue
+		}
+		specs = append(specs, &vsphereMachine.Spec.VirtualMachineCloneSpec)
+	}
+	return specs, nil
+}
+
+// applyWorkloadObject server-side applies the object into the workload cluster.
+func applyWorkloadObject(ctx *context.ClusterContext, c client.Client, obj client.Object) error {
+	gvk, err := apiutil.GVKForObject(obj, c.Scheme())
+	if err != nil {
+		return err
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	if err := c.Patch(ctx, obj, client.Apply, client.ForceOwnership, client.FieldOwner(workloadFieldOwner)); err != nil {
+		return errors.Wrapf(err, "failed to apply %s %s in workload cluster for %s", gvk.Kind, obj.GetName(), ctx)
+	}
+	return nil
+}
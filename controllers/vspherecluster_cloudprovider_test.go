@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+)
+
+func TestClusterReconciler_ClusterDatacenters(t *testing.T) {
+	g := NewWithT(t)
+	controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext())
+	ctx := fake.NewClusterContext(controllerCtx)
+	r := clusterReconciler{controllerCtx}
+
+	labels := map[string]string{clusterv1.ClusterLabelName: ctx.Cluster.Name}
+	for _, name := range []string{"machine-0", "machine-1", "machine-2"} {
+		g.Expect(ctx.Client.Create(ctx, &infrav1.VSphereMachine{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ctx.Cluster.Namespace, Name: name, Labels: labels},
+			Spec: infrav1.VSphereMachineSpec{
+				VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{Datacenter: "*"},
+			},
+		})).To(Succeed())
+	}
+	// The datacenters of the machines in failure domains are resolved by
+	// their VSphereVMs.
+	for name, datacenter := range map[string]string{"machine-0": "dc-east", "machine-1": "dc-west"} {
+		g.Expect(ctx.Client.Create(ctx, &infrav1.VSphereVM{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       ctx.Cluster.Namespace,
+				Name:            name + "-vm",
+				Labels:          labels,
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: infrav1.GroupVersion.String(), Kind: "VSphereMachine", Name: name, UID: "uid"}},
+			},
+			Spec: infrav1.VSphereVMSpec{
+				VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{Datacenter: datacenter},
+			},
+		})).To(Succeed())
+	}
+
+	datacenters, err := r.clusterDatacenters(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(datacenters).To(Equal([]string{"*", "dc-east", "dc-west"}))
+}
+
+func TestClusterReconciler_CloudProviderVCenters(t *testing.T) {
+	g := NewWithT(t)
+	controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext())
+	ctx := fake.NewClusterContext(controllerCtx)
+	r := clusterReconciler{controllerCtx}
+	ctx.VSphereCluster.Spec.Server = "vcenter.example.com"
+	ctx.VSphereCluster.Spec.Thumbprint = "AA:BB"
+	ctx.VSphereCluster.Spec.VCenters = []infrav1.VCenterSpec{
+		{Server: "edge.example.com", Thumbprint: "CC:DD", Zones: []string{"zone-edge"}},
+	}
+
+	labels := map[string]string{clusterv1.ClusterLabelName: ctx.Cluster.Name}
+	for name, server := range map[string]string{"machine-0": "", "machine-1": "edge.example.com"} {
+		g.Expect(ctx.Client.Create(ctx, &infrav1.VSphereMachine{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ctx.Cluster.Namespace, Name: name, Labels: labels},
+			Spec: infrav1.VSphereMachineSpec{
+				VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{Server: server, Datacenter: "dc-" + name},
+			},
+		})).To(Succeed())
+	}
+
+	vcenters, err := r.cloudProviderVCenters(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(vcenters).To(HaveLen(2))
+	g.Expect(vcenters[0].Server).To(Equal("vcenter.example.com"))
+	g.Expect(vcenters[0].Thumbprint).To(Equal("AA:BB"))
+	g.Expect(vcenters[0].Datacenters).To(Equal([]string{"dc-machine-0"}))
+	g.Expect(vcenters[1].Server).To(Equal("edge.example.com"))
+	g.Expect(vcenters[1].Thumbprint).To(Equal("CC:DD"))
+	g.Expect(vcenters[1].Datacenters).To(Equal([]string{"dc-machine-1"}))
+}
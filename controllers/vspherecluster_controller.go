@@ -22,15 +22,19 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+	apiv1 "k8s.io/api/core/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	clusterutilv1 "sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/predicates"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
@@ -41,11 +45,13 @@ import (
 	inframanager "sigs.k8s.io/cluster-api-provider-vsphere/pkg/manager"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/vmoperator"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
 )
 
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;patch;update
 // +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=services;endpoints,verbs=get;list;watch;create;patch;update
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vsphereclusteridentities,verbs=get;list;watch;delete
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vsphereclusters,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vsphereclusters/status,verbs=get;update;patch
@@ -99,8 +105,8 @@ func AddClusterControllerToManager(ctx *context.ControllerManagerContext, mgr ma
 				&source.Kind{Type: &vmwarev1.VSphereMachine{}},
 				handler.EnqueueRequestsFromMapFunc(reconciler.VSphereMachineToCluster),
 			).
-			WithOptions(controller.Options{MaxConcurrentReconciles: ctx.MaxConcurrentReconciles}).
-			Complete(reconciler)
+			WithOptions(controller.Options{MaxConcurrentReconciles: ctx.VSphereClusterConcurrency}).
+			Complete(tracing.Reconciler(clusterControlledTypeName, reconciler))
 	}
 
 	reconciler := clusterReconciler{ControllerContext: controllerContext}
@@ -138,12 +144,46 @@ func AddClusterControllerToManager(ctx *context.ControllerManagerContext, mgr ma
 			&source.Kind{Type: &infrav1.VSphereMachine{}},
 			handler.EnqueueRequestsFromMapFunc(reconciler.controlPlaneMachineToCluster),
 		).
+		// Watch the creation of VSphereMachines so the privileges on the
+		// inventory objects they use are checked before their VMs are cloned.
+		Watches(
+			&source.Kind{Type: &infrav1.VSphereMachine{}},
+			handler.EnqueueRequestsFromMapFunc(reconciler.machineToCluster),
+			builder.WithPredicates(predicate.Funcs{
+				UpdateFunc:  func(event.UpdateEvent) bool { return false },
+				DeleteFunc:  func(event.DeleteEvent) bool { return false },
+				GenericFunc: func(event.GenericEvent) bool { return false },
+			}),
+		).
+		// Watch the load balancers of the clusters, whose virtual IP is the
+		// control plane endpoint once one of their VMs is provisioned.
+		Watches(
+			&source.Kind{Type: &infrav1.VSphereLoadBalancerVM{}},
+			handler.EnqueueRequestsFromMapFunc(reconciler.loadBalancerVMToCluster),
+		).
 		// Watch the Vsphere deployment zone with the Server field matching the
 		// server field of the VSphereCluster.
 		Watches(
 			&source.Kind{Type: &infrav1.VSphereDeploymentZone{}},
 			handler.EnqueueRequestsFromMapFunc(reconciler.deploymentZoneToCluster),
 		).
+		// Watch the identity secrets so the sessions of rotated credentials
+		// are torn down right away.
+		Watches(
+			&source.Kind{Type: &apiv1.Secret{}},
+			handler.EnqueueRequestsFromMapFunc(reconciler.identitySecretToCluster),
+		).
+		// Watch the identities and the namespaces so the allowed namespaces
+		// of the identities, and the default identities of the namespaces,
+		// are enforced right away.
+		Watches(
+			&source.Kind{Type: &infrav1.VSphereClusterIdentity{}},
+			handler.EnqueueRequestsFromMapFunc(reconciler.identityToCluster),
+		).
+		Watches(
+			&source.Kind{Type: &apiv1.Namespace{}},
+			handler.EnqueueRequestsFromMapFunc(reconciler.namespaceToCluster),
+		).
 		// Watch a GenericEvent channel for the controlled resource.
 		//
 		// This is useful when there are events outside of Kubernetes that
@@ -154,6 +194,6 @@ func AddClusterControllerToManager(ctx *context.ControllerManagerContext, mgr ma
 			&handler.EnqueueRequestForObject{},
 		).
 		WithEventFilter(predicates.ResourceIsNotExternallyManaged(reconciler.Logger)).
-		WithOptions(controller.Options{MaxConcurrentReconciles: ctx.MaxConcurrentReconciles}).
-		Complete(reconciler)
+		WithOptions(controller.Options{MaxConcurrentReconciles: ctx.VSphereClusterConcurrency}).
+		Complete(tracing.Reconciler(clusterControlledTypeName, reconciler))
 }
@@ -0,0 +1,193 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controllers/remote"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/packaging/flavorgen/flavors/crs/types"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/cloudprovider"
+)
+
+// reconcileCSI installs or upgrades the vSphere CSI driver in the workload
+// cluster. It returns true once the CSI controller Deployment and node
+// DaemonSet are available.
+func (r clusterReconciler) reconcileCSI(ctx *context.ClusterContext) (bool, error) {
+	csiSpec := ctx.VSphereCluster.Spec.CSI
+	if csiSpec == nil || !csiSpec.Enabled {
+		conditions.Delete(ctx.VSphereCluster, infrav1.CSIAvailableCondition)
+		return true, nil
+	}
+
+	guestClient, err := remote.NewClusterClient(ctx, r.Name, r.Client, client.ObjectKeyFromObject(ctx.Cluster))
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get client for workload cluster %s", ctx)
+	}
+
+	cloudConfig, err := r.csiCloudConfig(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	storageConfig := cloudprovider.CSIStorageConfig(csiSpec.Version)
+	deployment := cloudprovider.CSIControllerDeployment(storageConfig)
+	if csiSpec.TopologyAware {
+		cloudprovider.EnableCSIControllerTopology(deployment)
+	}
+	daemonSet := cloudprovider.VSphereCSINodeDaemonSet(storageConfig)
+
+	objs := []client.Object{
+		cloudprovider.CSIControllerServiceAccount(),
+		cloudprovider.CSIControllerClusterRole(),
+		cloudprovider.CSIControllerClusterRoleBinding(),
+		cloudprovider.CSICloudConfigSecret(cloudConfig),
+		cloudprovider.CSIDriver(),
+		daemonSet,
+		deployment,
+	}
+	if storageClass := csiSpec.StorageClass; storageClass != nil {
+		objs = append(objs, csiStorageClass(storageClass))
+	}
+	for _, obj := range objs {
+		if err := applyWorkloadObject(ctx, guestClient, obj); err != nil {
+			return false, err
+		}
+	}
+
+	if msg := csiComponentsNotReadyMessage(deployment, daemonSet); msg != "" {
+		conditions.MarkFalse(ctx.VSphereCluster, infrav1.CSIAvailableCondition, infrav1.CSIComponentsNotReadyReason, clusterv1.ConditionSeverityInfo, msg)
+		return false, nil
+	}
+	conditions.MarkTrue(ctx.VSphereCluster, infrav1.CSIAvailableCondition)
+	return true, nil
+}
+
+// csiCloudConfig returns the INI configuration used by the vSphere CSI driver
+// to connect to the vCenter of the cluster.
+func (r clusterReconciler) csiCloudConfig(ctx *context.ClusterContext) (string, error) {
+	creds, err := r.vCenterCredentials(ctx)
+	if err != nil {
+		return "", err
+	}
+	datacenters, err := r.clusterDatacenters(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	config := &types.CPIConfig{}
+	config.Global.ClusterID = fmt.Sprintf("%s/%s", ctx.Cluster.Namespace, ctx.Cluster.Name)
+	config.Global.Thumbprint = ctx.VSphereCluster.Spec.Thumbprint
+	config.VCenter = map[string]types.CPIVCenterConfig{
+		ctx.VSphereCluster.Spec.Server: {
+			Username:    creds.Username,
+			Password:    creds.Password,
+			Datacenters: strings.Join(datacenters, ","),
+			Thumbprint:  ctx.VSphereCluster.Spec.Thumbprint,
+		},
+	}
+	if ctx.VSphereCluster.Spec.CSI.TopologyAware {
+		region, zone, err := r.clusterTopologyCategories(ctx)
+		if err != nil {
+			return "", err
+		}
+		config.Labels.Region = region
+		config.Labels.Zone = zone
+	}
+
+	cloudConfig, err := config.MarshalINI()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to generate CSI cloud config")
+	}
+	return string(cloudConfig), nil
+}
+
+// clusterTopologyCategories returns the region and zone tag categories of the
+// failure domains used by the cluster.
+func (r clusterReconciler) clusterTopologyCategories(ctx *context.ClusterContext) (string, string, error) {
+	for name := range ctx.VSphereCluster.Status.FailureDomains {
+		zone := &infrav1.VSphereDeploymentZone{}
+		if err := r.Client.Get(ctx, client.ObjectKey{Name: name}, zone); err != nil {
+			return "", "", errors.Wrapf(err, "failed to get VSphereDeploymentZone %s", name)
+		}
+		failureDomain := &infrav1.VSphereFailureDomain{}
+		if err := r.Client.Get(ctx, client.ObjectKey{Name: zone.Spec.FailureDomain}, failureDomain); err != nil {
+			return "", "", errors.Wrapf(err, "failed to get VSphereFailureDomain %s", zone.Spec.FailureDomain)
+		}
+		return failureDomain.Spec.Region.TagCategory, failureDomain.Spec.Zone.TagCategory, nil
+	}
+	return "", "", errors.Errorf("topology aware provisioning requires failure domains for %s", ctx)
+}
+
+func csiStorageClass(spec *infrav1.CSIStorageClassSpec) client.Object {
+	name := spec.Name
+	if name == "" {
+		name = cloudprovider.DefaultCSIStorageClassName
+	}
+	fsType := spec.FSType
+	if fsType == "" {
+		fsType = cloudprovider.DefaultCSIFSType
+	}
+	parameters := map[string]string{
+		"csi.storage.k8s.io/fstype": fsType,
+	}
+	if spec.StoragePolicyName != "" {
+		parameters["storagepolicyname"] = spec.StoragePolicyName
+	}
+	if spec.DatastoreURL != "" {
+		parameters["datastoreurl"] = spec.DatastoreURL
+	}
+	return cloudprovider.CSIStorageClass(name, spec.Default, parameters)
+}
+
+// csiComponentsNotReadyMessage returns a message describing the CSI components
+// which are not yet available, or an empty string if all of them are.
+// The objects are expected to hold the state returned by the workload cluster.
+func csiComponentsNotReadyMessage(deployment *appsv1.Deployment, daemonSet *appsv1.DaemonSet) string {
+	var notReady []string
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	if deployment.Status.ObservedGeneration < deployment.Generation ||
+		deployment.Status.UpdatedReplicas < replicas ||
+		deployment.Status.AvailableReplicas < replicas {
+		notReady = append(notReady, fmt.Sprintf("Deployment %s (%d/%d available)", deployment.Name, deployment.Status.AvailableReplicas, replicas))
+	}
+
+	desired := daemonSet.Status.DesiredNumberScheduled
+	if daemonSet.Status.ObservedGeneration < daemonSet.Generation ||
+		daemonSet.Status.UpdatedNumberScheduled < desired ||
+		daemonSet.Status.NumberAvailable < desired {
+		notReady = append(notReady, fmt.Sprintf("DaemonSet %s (%d/%d available)", daemonSet.Name, daemonSet.Status.NumberAvailable, desired))
+	}
+
+	if len(notReady) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("waiting for %s", strings.Join(notReady, ", "))
+}
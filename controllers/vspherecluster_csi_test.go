@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/cloudprovider"
+)
+
+func TestCSIComponentsNotReadyMessage(t *testing.T) {
+	storageConfig := cloudprovider.CSIStorageConfig("")
+
+	t.Run("components which have not been observed are not ready", func(t *testing.T) {
+		g := NewWithT(t)
+		deployment := cloudprovider.CSIControllerDeployment(storageConfig)
+		deployment.Generation = 1
+		daemonSet := cloudprovider.VSphereCSINodeDaemonSet(storageConfig)
+		daemonSet.Generation = 1
+
+		msg := csiComponentsNotReadyMessage(deployment, daemonSet)
+		g.Expect(msg).To(ContainSubstring("Deployment vsphere-csi-controller"))
+		g.Expect(msg).To(ContainSubstring("DaemonSet vsphere-csi-node"))
+	})
+
+	t.Run("available components are ready", func(t *testing.T) {
+		g := NewWithT(t)
+		deployment := cloudprovider.CSIControllerDeployment(storageConfig)
+		deployment.Generation = 1
+		deployment.Status = appsv1.DeploymentStatus{ObservedGeneration: 1, UpdatedReplicas: 1, AvailableReplicas: 1}
+		daemonSet := cloudprovider.VSphereCSINodeDaemonSet(storageConfig)
+		daemonSet.Generation = 1
+		daemonSet.Status = appsv1.DaemonSetStatus{ObservedGeneration: 1, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3}
+
+		g.Expect(csiComponentsNotReadyMessage(deployment, daemonSet)).To(BeEmpty())
+
+		daemonSet.Status.NumberAvailable = 2
+		g.Expect(csiComponentsNotReadyMessage(deployment, daemonSet)).To(Equal("waiting for DaemonSet vsphere-csi-node (2/3 available)"))
+	})
+}
+
+func TestCSIStorageClass(t *testing.T) {
+	g := NewWithT(t)
+
+	obj := csiStorageClass(&infrav1.CSIStorageClassSpec{Default: true, StoragePolicyName: "gold"})
+	g.Expect(obj.GetName()).To(Equal(cloudprovider.DefaultCSIStorageClassName))
+	g.Expect(obj.GetAnnotations()).To(HaveKeyWithValue("storageclass.kubernetes.io/is-default-class", "true"))
+}
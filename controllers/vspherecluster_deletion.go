@@ -0,0 +1,185 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+)
+
+// clusterDeletionStatus returns the progress of the deletion of the
+// VSphereMachines of a deleted cluster. A VSphereMachine being deleted is
+// blocked while the destruction of its VM, or of its VSphereVM, fails.
+func clusterDeletionStatus(vsphereMachines []*infrav1.VSphereMachine, vsphereVMs []infrav1.VSphereVM) *infrav1.ClusterDeletionStatus {
+	vmsByName := map[string]*infrav1.VSphereVM{}
+	for i := range vsphereVMs {
+		vmsByName[vsphereVMs[i].Name] = &vsphereVMs[i]
+	}
+
+	status := &infrav1.ClusterDeletionStatus{Machines: int32(len(vsphereMachines))}
+	for _, vsphereMachine := range vsphereMachines {
+		if vsphereMachine.DeletionTimestamp.IsZero() {
+			status.Pending++
+			continue
+		}
+		status.Deleting++
+		if reason := deletionBlockedReason(vsphereMachine, vmsByName[vsphereMachine.Name]); reason != "" {
+			status.Blocked = append(status.Blocked, infrav1.BlockedMachine{Name: vsphereMachine.Name, Reason: reason})
+		}
+	}
+	return status
+}
+
+// deletionBlockedReason returns the message of the VMProvisioned condition of
+// the VSphereVM, or of the VSphereMachine, reporting the failure to destroy
+// the VM, or of the VCenterAvailable condition of the VSphereVM when its
+// vCenter is unreachable, or an empty string if its destruction does not fail.
+func deletionBlockedReason(vsphereMachine *infrav1.VSphereMachine, vsphereVM *infrav1.VSphereVM) string {
+	var c *clusterv1.Condition
+	if vsphereVM != nil {
+		if conditions.IsFalse(vsphereVM, infrav1.VCenterAvailableCondition) {
+			since := conditions.GetLastTransitionTime(vsphereVM, infrav1.VCenterAvailableCondition)
+			return fmt.Sprintf("vCenter unreachable since %s: %s", since.UTC().Format(time.RFC3339), conditions.GetMessage(vsphereVM, infrav1.VCenterAvailableCondition))
+		}
+		c = conditions.Get(vsphereVM, infrav1.VMProvisionedCondition)
+	}
+	if c == nil || c.Reason != clusterv1.DeletionFailedReason {
+		c = conditions.Get(vsphereMachine, infrav1.VMProvisionedCondition)
+	}
+	if c == nil || c.Reason != clusterv1.DeletionFailedReason {
+		return ""
+	}
+	if c.Message == "" {
+		return c.Reason
+	}
+	return c.Message
+}
+
+// reportMachinesDeletion reports the progress of the deletion of the
+// VSphereMachines of the cluster in its MachinesDeleted condition.
+func reportMachinesDeletion(ctx *context.ClusterContext, status *infrav1.ClusterDeletionStatus) {
+	if status.Machines == 0 {
+		conditions.MarkTrue(ctx.VSphereCluster, infrav1.MachinesDeletedCondition)
+		return
+	}
+	if len(status.Blocked) > 0 {
+		conditions.MarkFalse(ctx.VSphereCluster, infrav1.MachinesDeletedCondition, infrav1.MachinesDeletionBlockedReason, clusterv1.ConditionSeverityWarning,
+			"%d of %d VSphereMachines left fail to destroy their VM, e.g. %s: %s", len(status.Blocked), status.Machines, status.Blocked[0].Name, status.Blocked[0].Reason)
+		return
+	}
+	conditions.MarkFalse(ctx.VSphereCluster, infrav1.MachinesDeletedCondition, infrav1.WaitingForMachinesDeletionReason, clusterv1.ConditionSeverityInfo,
+		"%d of %d VSphereMachines left are being deleted, %d are waiting for their Machine to be deleted", status.Deleting, status.Machines, status.Pending)
+}
+
+// reconcileForceDelete removes the finalizer of the VSphereVMs of the cluster
+// being deleted for longer than the VMForceDeleteTimeout whose VM is not found
+// in vCenter anymore, e.g. as the datacenter or the folder of the VM was
+// removed, so their deletion does not block the one of the cluster. The VMs
+// are searched for in all the datacenters of the vCenter of the cluster, and
+// the finalizers are left in place while it cannot be reached.
+func (r clusterReconciler) reconcileForceDelete(ctx *context.ClusterContext, vsphereVMs []infrav1.VSphereVM) error {
+	if r.VMForceDeleteTimeout <= 0 {
+		return nil
+	}
+	var expired []*infrav1.VSphereVM
+	for i := range vsphereVMs {
+		vsphereVM := &vsphereVMs[i]
+		if vsphereVM.DeletionTimestamp.IsZero() || !ctrlutil.ContainsFinalizer(vsphereVM, infrav1.VMFinalizer) {
+			continue
+		}
+		if time.Since(vsphereVM.DeletionTimestamp.Time) >= r.VMForceDeleteTimeout {
+			expired = append(expired, vsphereVM)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	vCenterSession, err := r.reconcileVCenterConnectivity(ctx)
+	if err != nil {
+		ctx.Logger.Error(err, "unable to connect to vcenter, leaving the finalizers of the VSphereVMs in place")
+		return nil
+	}
+	return r.forceDeleteVMs(ctx, vCenterSession, expired)
+}
+
+// forceDeleteVMs releases the addresses allocated to the VSphereVMs whose VM
+// is not found in vCenter, and removes their finalizer.
+func (r clusterReconciler) forceDeleteVMs(ctx *context.ClusterContext, s *session.Session, vsphereVMs []*infrav1.VSphereVM) error {
+	var errs []error
+	for _, vsphereVM := range vsphereVMs {
+		exists, err := vmExists(ctx, s, vsphereVM)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if exists {
+			continue
+		}
+
+		patchHelper, err := patch.NewHelper(vsphereVM, ctx.Client)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		vmCtx := &context.VMContext{
+			ControllerContext: r.ControllerContext,
+			VSphereVM:         vsphereVM,
+			Logger:            ctx.Logger.WithValues("vspherevm", vsphereVM.Name),
+		}
+		if err := releaseIPAllocations(vmCtx); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ctx.Logger.Info("removing the finalizer of VSphereVM whose VM is not found", "vspherevm", vsphereVM.Name, "deletionTimestamp", vsphereVM.DeletionTimestamp)
+		ctrlutil.RemoveFinalizer(vsphereVM, infrav1.VMFinalizer)
+		if err := patchHelper.Patch(ctx, vsphereVM); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to remove the finalizer of VSphereVM %s/%s", vsphereVM.Namespace, vsphereVM.Name))
+			continue
+		}
+		ctx.Recorder.Eventf(ctx.VSphereCluster, "VSphereVMForceDeleted", "Removed the finalizer of VSphereVM %s deleted for longer than %s, whose VM is not found", vsphereVM.Name, r.VMForceDeleteTimeout)
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+// vmExists returns whether the VM of the VSphereVM is found by its BIOS UUID,
+// or by its instance UUID, in any of the datacenters of the vCenter.
+func vmExists(ctx *context.ClusterContext, s *session.Session, vsphereVM *infrav1.VSphereVM) (bool, error) {
+	if biosUUID := vsphereVM.Spec.BiosUUID; biosUUID != "" {
+		ref, err := s.FindByBIOSUUID(ctx, biosUUID)
+		if err != nil || ref != nil {
+			return ref != nil, err
+		}
+	}
+	instanceUUID := vsphereVM.Spec.InstanceUUID
+	if instanceUUID == "" {
+		instanceUUID = string(vsphereVM.UID)
+	}
+	ref, err := s.FindByInstanceUUID(ctx, instanceUUID)
+	return ref != nil, err
+}
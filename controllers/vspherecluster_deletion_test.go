@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/vim25/mo"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestClusterDeletionStatus(t *testing.T) {
+	g := NewWithT(t)
+	now := metav1.Now()
+	machine := func(name string, deleted bool) *infrav1.VSphereMachine {
+		m := &infrav1.VSphereMachine{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if deleted {
+			m.DeletionTimestamp = &now
+		}
+		return m
+	}
+	blockedVM := infrav1.VSphereVM{ObjectMeta: metav1.ObjectMeta{Name: "machine-1"}}
+	conditions.MarkFalse(&blockedVM, infrav1.VMProvisionedCondition, clusterv1.DeletionFailedReason, clusterv1.ConditionSeverityWarning, "failed to destroy VM: datacenter 'DC0' not found")
+	deletingVM := infrav1.VSphereVM{ObjectMeta: metav1.ObjectMeta{Name: "machine-2"}}
+	conditions.MarkFalse(&deletingVM, infrav1.VMProvisionedCondition, clusterv1.DeletingReason, clusterv1.ConditionSeverityInfo, "")
+	unreachableVM := infrav1.VSphereVM{ObjectMeta: metav1.ObjectMeta{Name: "machine-3"}}
+	conditions.Set(&unreachableVM, &clusterv1.Condition{
+		Type:               infrav1.VCenterAvailableCondition,
+		Status:             corev1.ConditionFalse,
+		Reason:             infrav1.VCenterUnreachableReason,
+		Message:            "connection refused",
+		LastTransitionTime: metav1.NewTime(time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)),
+	})
+
+	status := clusterDeletionStatus(
+		[]*infrav1.VSphereMachine{machine("machine-0", false), machine("machine-1", true), machine("machine-2", true), machine("machine-3", true)},
+		[]infrav1.VSphereVM{blockedVM, deletingVM, unreachableVM},
+	)
+	g.Expect(status).To(Equal(&infrav1.ClusterDeletionStatus{
+		Machines: 4,
+		Deleting: 3,
+		Pending:  1,
+		Blocked: []infrav1.BlockedMachine{
+			{Name: "machine-1", Reason: "failed to destroy VM: datacenter 'DC0' not found"},
+			{Name: "machine-3", Reason: "vCenter unreachable since 2022-06-01T12:00:00Z: connection refused"},
+		},
+	}))
+
+	ctx := fake.NewClusterContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+	reportMachinesDeletion(ctx, status)
+	g.Expect(conditions.GetReason(ctx.VSphereCluster, infrav1.MachinesDeletedCondition)).To(Equal(infrav1.MachinesDeletionBlockedReason))
+	reportMachinesDeletion(ctx, clusterDeletionStatus(nil, nil))
+	g.Expect(conditions.IsTrue(ctx.VSphereCluster, infrav1.MachinesDeletedCondition)).To(BeTrue())
+}
+
+func TestClusterReconciler_ForceDeleteVMs(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext())
+	ctx := fake.NewClusterContext(controllerCtx)
+	r := clusterReconciler{controllerCtx}
+
+	s, err := session.GetOrCreate(ctx, session.NewParams().
+		WithServer(simr.ServerURL().Host).
+		WithUserInfo(simr.Username(), simr.Password()))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	vm, err := s.Finder.VirtualMachine(ctx, "/DC0/vm/DC0_H0_VM0")
+	g.Expect(err).NotTo(HaveOccurred())
+	var o mo.VirtualMachine
+	g.Expect(vm.Properties(ctx, vm.Reference(), []string{"config.uuid"}, &o)).To(Succeed())
+
+	deletedVSphereVM := func(name, biosUUID string) *infrav1.VSphereVM {
+		vsphereVM := &infrav1.VSphereVM{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:  ctx.Cluster.Namespace,
+				Name:       name,
+				Finalizers: []string{infrav1.VMFinalizer},
+			},
+			Spec: infrav1.VSphereVMSpec{BiosUUID: biosUUID},
+		}
+		g.Expect(ctx.Client.Create(ctx, vsphereVM)).To(Succeed())
+		g.Expect(ctx.Client.Delete(ctx, vsphereVM)).To(Succeed())
+		g.Expect(ctx.Client.Get(ctx, client.ObjectKeyFromObject(vsphereVM), vsphereVM)).To(Succeed())
+		return vsphereVM
+	}
+	existing := deletedVSphereVM("existing", o.Config.Uuid)
+	gone := deletedVSphereVM("gone", "42000000-0000-0000-0000-000000000000")
+
+	g.Expect(r.forceDeleteVMs(ctx, s, []*infrav1.VSphereVM{existing, gone})).To(Succeed())
+	g.Expect(ctx.Client.Get(ctx, client.ObjectKeyFromObject(existing), &infrav1.VSphereVM{})).To(Succeed())
+	err = ctx.Client.Get(ctx, client.ObjectKeyFromObject(gone), &infrav1.VSphereVM{})
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"net"
+	"strings"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/dns"
+)
+
+// newDNSProvider returns the DNS provider of the control plane endpoint of a
+// cluster. It is replaced by the tests.
+var newDNSProvider = dns.NewProvider
+
+// reconcileControlPlaneEndpointDNS registers the address of the control plane
+// endpoint under the name of spec.controlPlaneEndpointDNS. The record is only
+// updated when the name or the address changed since it was registered, and
+// the record of a previous name or IP family is then deleted. The record is
+// left in place when spec.controlPlaneEndpointDNS is removed.
+func (r clusterReconciler) reconcileControlPlaneEndpointDNS(ctx *context.ClusterContext) error {
+	spec := ctx.VSphereCluster.Spec.ControlPlaneEndpointDNS
+	if spec == nil {
+		conditions.Delete(ctx.VSphereCluster, infrav1.ControlPlaneEndpointDNSReadyCondition)
+		ctx.VSphereCluster.Status.ControlPlaneEndpointDNSRecord = nil
+		return nil
+	}
+
+	host := ctx.VSphereCluster.Spec.ControlPlaneEndpoint.Host
+	address := net.ParseIP(host)
+	if address == nil {
+		conditions.MarkFalse(ctx.VSphereCluster, infrav1.ControlPlaneEndpointDNSReadyCondition, infrav1.DNSRecordProvisioningFailedReason, clusterv1.ConditionSeverityError,
+			"host %q of the control plane endpoint is not an IP address", host)
+		return nil
+	}
+	record := &infrav1.DNSRecord{Name: dnsRecordName(spec.Name), Address: address.String()}
+	registered := ctx.VSphereCluster.Status.ControlPlaneEndpointDNSRecord
+	if registered != nil && *registered == *record && conditions.IsTrue(ctx.VSphereCluster, infrav1.ControlPlaneEndpointDNSReadyCondition) {
+		return nil
+	}
+
+	provider, err := newDNSProvider(ctx, ctx.Client, ctx.VSphereCluster.Namespace, spec)
+	if err != nil {
+		return err
+	}
+	ttl := spec.TTL
+	if ttl == 0 {
+		ttl = infrav1.DefaultControlPlaneEndpointDNSTTL
+	}
+	if err := provider.UpsertRecord(ctx, record.Name, address, ttl); err != nil {
+		return err
+	}
+	ctx.Logger.Info("registered DNS record of the control plane endpoint", "name", record.Name, "address", record.Address)
+	ctx.Recorder.Eventf(ctx.VSphereCluster, "DNSRecordRegistered", "Registered %s for control plane endpoint %s", record.Name, record.Address)
+
+	// The upsert replaced the address of a record of the same name and IP
+	// family, other records are deleted.
+	if registered != nil && (registered.Name != record.Name || !sameIPFamily(registered.Address, record.Address)) {
+		if err := provider.DeleteRecord(ctx, registered.Name, net.ParseIP(registered.Address)); err != nil {
+			return err
+		}
+		ctx.Recorder.Eventf(ctx.VSphereCluster, "DNSRecordDeleted", "Deleted previous record %s of control plane endpoint %s", registered.Name, registered.Address)
+	}
+	ctx.VSphereCluster.Status.ControlPlaneEndpointDNSRecord = record
+	conditions.MarkTrue(ctx.VSphereCluster, infrav1.ControlPlaneEndpointDNSReadyCondition)
+	return nil
+}
+
+// reconcileControlPlaneEndpointDNSDelete deletes the DNS record of the
+// control plane endpoint registered for the cluster, if any.
+func (r clusterReconciler) reconcileControlPlaneEndpointDNSDelete(ctx *context.ClusterContext) error {
+	spec := ctx.VSphereCluster.Spec.ControlPlaneEndpointDNS
+	registered := ctx.VSphereCluster.Status.ControlPlaneEndpointDNSRecord
+	if spec == nil || registered == nil {
+		return nil
+	}
+
+	// The deletion of the cluster is not blocked on credentials that are
+	// already deleted.
+	provider, err := newDNSProvider(ctx, ctx.Client, ctx.VSphereCluster.Namespace, spec)
+	if err != nil {
+		ctx.Logger.Error(err, "unable to get the DNS provider, leaving the DNS record of the control plane endpoint in place")
+		ctx.Recorder.Warnf(ctx.VSphereCluster, "DNSRecordCleanupSkipped", "Unable to get the DNS provider to delete record %s: %v", registered.Name, err)
+		return nil
+	}
+	if err := provider.DeleteRecord(ctx, registered.Name, net.ParseIP(registered.Address)); err != nil {
+		return err
+	}
+	ctx.Recorder.Eventf(ctx.VSphereCluster, "DNSRecordDeleted", "Deleted record %s of control plane endpoint %s", registered.Name, registered.Address)
+	ctx.VSphereCluster.Status.ControlPlaneEndpointDNSRecord = nil
+	return nil
+}
+
+// dnsRecordName returns the name in lower case, without trailing dot.
+func dnsRecordName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+func sameIPFamily(a, b string) bool {
+	return (net.ParseIP(a).To4() == nil) == (net.ParseIP(b).To4() == nil)
+}
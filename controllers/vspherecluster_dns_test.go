@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	goctx "context"
+	"net"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services"
+)
+
+// fakeDNSProvider stores the addresses of the records by name.
+type fakeDNSProvider struct {
+	records map[string]string
+	calls   int
+}
+
+func (p *fakeDNSProvider) UpsertRecord(_ goctx.Context, name string, address net.IP, _ int32) error {
+	p.calls++
+	p.records[name] = address.String()
+	return nil
+}
+
+func (p *fakeDNSProvider) DeleteRecord(_ goctx.Context, name string, address net.IP) error {
+	p.calls++
+	if p.records[name] == address.String() {
+		delete(p.records, name)
+	}
+	return nil
+}
+
+func TestClusterReconciler_ReconcileControlPlaneEndpointDNS(t *testing.T) {
+	g := NewWithT(t)
+	provider := &fakeDNSProvider{records: map[string]string{}}
+	defer func(f func(goctx.Context, ctrlclient.Client, string, *infrav1.ControlPlaneEndpointDNSSpec) (services.DNSProvider, error)) {
+		newDNSProvider = f
+	}(newDNSProvider)
+	newDNSProvider = func(goctx.Context, ctrlclient.Client, string, *infrav1.ControlPlaneEndpointDNSSpec) (services.DNSProvider, error) {
+		return provider, nil
+	}
+
+	controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext())
+	ctx := fake.NewClusterContext(controllerCtx)
+	r := clusterReconciler{controllerCtx}
+	ctx.VSphereCluster.Spec.ControlPlaneEndpointDNS = &infrav1.ControlPlaneEndpointDNSSpec{
+		Name:    "API.cluster.example.com.",
+		RFC2136: &infrav1.RFC2136DNSSpec{Server: "ns1.example.com", Zone: "cluster.example.com"},
+	}
+
+	// Hostnames are not registered.
+	ctx.VSphereCluster.Spec.ControlPlaneEndpoint = infrav1.APIEndpoint{Host: "lb.example.com", Port: 6443}
+	g.Expect(r.reconcileControlPlaneEndpointDNS(ctx)).To(Succeed())
+	g.Expect(conditions.GetReason(ctx.VSphereCluster, infrav1.ControlPlaneEndpointDNSReadyCondition)).To(Equal(infrav1.DNSRecordProvisioningFailedReason))
+	g.Expect(provider.calls).To(BeZero())
+
+	ctx.VSphereCluster.Spec.ControlPlaneEndpoint.Host = "192.168.10.5"
+	g.Expect(r.reconcileControlPlaneEndpointDNS(ctx)).To(Succeed())
+	g.Expect(conditions.IsTrue(ctx.VSphereCluster, infrav1.ControlPlaneEndpointDNSReadyCondition)).To(BeTrue())
+	g.Expect(provider.records).To(Equal(map[string]string{"api.cluster.example.com": "192.168.10.5"}))
+	g.Expect(ctx.VSphereCluster.Status.ControlPlaneEndpointDNSRecord).To(Equal(&infrav1.DNSRecord{Name: "api.cluster.example.com", Address: "192.168.10.5"}))
+
+	// The registered record is not updated again.
+	g.Expect(r.reconcileControlPlaneEndpointDNS(ctx)).To(Succeed())
+	g.Expect(provider.calls).To(Equal(1))
+
+	// The record of a previous name is deleted.
+	ctx.VSphereCluster.Spec.ControlPlaneEndpointDNS.Name = "kube.cluster.example.com"
+	g.Expect(r.reconcileControlPlaneEndpointDNS(ctx)).To(Succeed())
+	g.Expect(provider.records).To(Equal(map[string]string{"kube.cluster.example.com": "192.168.10.5"}))
+
+	g.Expect(r.reconcileControlPlaneEndpointDNSDelete(ctx)).To(Succeed())
+	g.Expect(provider.records).To(BeEmpty())
+	g.Expect(ctx.VSphereCluster.Status.ControlPlaneEndpointDNSRecord).To(BeNil())
+}
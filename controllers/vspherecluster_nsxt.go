@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/pkg/errors"
+	apiv1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/identity"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/nsxt"
+)
+
+// reconcileNSXTSegment creates or updates the NSX-T segment of the cluster,
+// which the machines of the cluster are attached to.
+func (r clusterReconciler) reconcileNSXTSegment(ctx *context.ClusterContext) error {
+	spec := ctx.VSphereCluster.Spec.NSXT
+	if spec == nil {
+		conditions.Delete(ctx.VSphereCluster, infrav1.NSXTSegmentReadyCondition)
+		return nil
+	}
+
+	nsxtClient, err := r.nsxtClient(ctx)
+	if err != nil {
+		return err
+	}
+	if err := nsxtClient.ReconcileSegment(ctx, spec, nsxt.SegmentName(ctx.Cluster.Namespace, ctx.Cluster.Name)); err != nil {
+		return err
+	}
+	conditions.MarkTrue(ctx.VSphereCluster, infrav1.NSXTSegmentReadyCondition)
+	return nil
+}
+
+// reconcileNSXTSegmentDelete deletes the NSX-T segment of the cluster once
+// all its VMs are gone.
+func (r clusterReconciler) reconcileNSXTSegmentDelete(ctx *context.ClusterContext) error {
+	if ctx.VSphereCluster.Spec.NSXT == nil {
+		return nil
+	}
+
+	// The deletion of the cluster is not blocked on credentials that are
+	// already deleted.
+	nsxtClient, err := r.nsxtClient(ctx)
+	if err != nil {
+		ctx.Logger.Error(err, "unable to get NSX-T credentials, leaving the segment of the cluster in place")
+		ctx.Recorder.Warnf(ctx.VSphereCluster, "NSXTSegmentCleanupSkipped", "Unable to get credentials of NSX-T manager %s to delete the segment: %v", ctx.VSphereCluster.Spec.NSXT.Server, err)
+		return nil
+	}
+	return nsxtClient.DeleteSegment(ctx, nsxt.SegmentName(ctx.Cluster.Namespace, ctx.Cluster.Name))
+}
+
+// nsxtClient returns a client of the NSX-T manager of the cluster, using the
+// credentials of the Secret referenced by the cluster.
+func (r clusterReconciler) nsxtClient(ctx *context.ClusterContext) (*nsxt.Client, error) {
+	spec := ctx.VSphereCluster.Spec.NSXT
+	secret := &apiv1.Secret{}
+	secretKey := client.ObjectKey{
+		Namespace: ctx.VSphereCluster.Namespace,
+		Name:      spec.SecretName,
+	}
+	if err := ctx.Client.Get(ctx, secretKey, secret); err != nil {
+		return nil, errors.Wrapf(err, "failed to get NSX-T credentials secret %s", secretKey)
+	}
+	username, password := string(secret.Data[identity.UsernameKey]), string(secret.Data[identity.PasswordKey])
+	if username == "" || password == "" {
+		return nil, errors.Errorf("NSX-T credentials secret %s does not contain a %s and a %s", secretKey, identity.UsernameKey, identity.PasswordKey)
+	}
+	return nsxt.NewClient(spec, username, password), nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/inventory"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	infrautilv1 "sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+// reconcileOrphanedVMs sweeps the VMs created for the cluster which are not
+// backed by a VSphereVM anymore, e.g. after the restore of an etcd backup, at
+// most once per OrphanedVMSweepInterval. They are reported in the status of
+// the VSphereCluster, and destroyed when DeleteOrphanedVMs is set and the
+// previous sweep already found them, so a VM is never destroyed on the
+// strength of a single sweep.
+func (r clusterReconciler) reconcileOrphanedVMs(ctx *context.ClusterContext, s *session.Session) error {
+	if r.OrphanedVMSweepInterval <= 0 {
+		return nil
+	}
+	if last := ctx.VSphereCluster.Status.LastOrphanedVMSweepTime; last != nil && time.Since(last.Time) < r.OrphanedVMSweepInterval {
+		return nil
+	}
+
+	vsphereVMList := &infrav1.VSphereVMList{}
+	if err := ctx.Client.List(ctx, vsphereVMList, client.InNamespace(ctx.Cluster.Namespace)); err != nil {
+		return errors.Wrapf(err, "unable to list VSphereVMs in namespace %s", ctx.Cluster.Namespace)
+	}
+	vsphereMachines, err := infrautilv1.GetVSphereMachinesInCluster(ctx, ctx.Client, ctx.Cluster.Namespace, ctx.Cluster.Name)
+	if err != nil {
+		return errors.Wrapf(err,
+			"unable to list VSphereMachines part of VSphereCluster %s/%s", ctx.VSphereCluster.Namespace, ctx.VSphereCluster.Name)
+	}
+
+	// The VMs are searched for in the folders and the resource pools of the
+	// machines of the cluster, and in those created for the cluster.
+	existing := map[string]bool{}
+	seen := map[inventory.Location]bool{}
+	var locations []inventory.Location
+	addLocation := func(spec *infrav1.VirtualMachineCloneSpec) {
+		if spec.Server != "" && spec.Server != ctx.VSphereCluster.Spec.Server {
+			return
+		}
+		location := inventory.Location{Datacenter: spec.Datacenter, Folder: spec.Folder, ResourcePool: spec.ResourcePool}
+		if !seen[location] {
+			seen[location] = true
+			locations = append(locations, location)
+		}
+	}
+	for i := range vsphereVMList.Items {
+		vsphereVM := &vsphereVMList.Items[i]
+		existing[vsphereVM.Namespace+"/"+vsphereVM.Name] = true
+		if vsphereVM.Labels[clusterv1.ClusterLabelName] == ctx.Cluster.Name {
+			addLocation(&vsphereVM.Spec.VirtualMachineCloneSpec)
+		}
+	}
+	for _, vsphereMachine := range vsphereMachines {
+		addLocation(&vsphereMachine.Spec.VirtualMachineCloneSpec)
+	}
+
+	vms, err := inventory.FindOwnedVMs(ctx, s, inventory.OwnerTagName(ctx.Cluster.Namespace, ctx.Cluster.Name), locations)
+	if err != nil {
+		return errors.Wrapf(err, "unable to find the VMs of %s", ctx)
+	}
+
+	previous := map[string]bool{}
+	for _, vm := range ctx.VSphereCluster.Status.OrphanedVMs {
+		previous[vm.MoRef] = true
+	}
+	var orphanedVMs []infrav1.OrphanedVM
+	for _, vm := range vms {
+		if existing[vm.VSphereVM] {
+			continue
+		}
+		if r.DeleteOrphanedVMs && previous[vm.Ref.Value] {
+			ctx.Logger.Info("destroying orphaned VM", "vm", vm.Name, "ref", vm.Ref.Value, "vspherevm", vm.VSphereVM)
+			if err := inventory.DestroyVM(ctx, s, vm.Ref); err != nil {
+				return errors.Wrapf(err, "unable to destroy orphaned VM %s", vm.Name)
+			}
+			ctx.Recorder.Eventf(ctx.VSphereCluster, "OrphanedVMDestroyed", "Destroyed VM %s created for VSphereVM %s which does not exist", vm.Name, vm.VSphereVM)
+			continue
+		}
+		if !previous[vm.Ref.Value] {
+			ctx.Logger.Info("found orphaned VM", "vm", vm.Name, "ref", vm.Ref.Value, "vspherevm", vm.VSphereVM)
+			ctx.Recorder.Warnf(ctx.VSphereCluster, "OrphanedVMFound", "VM %s was created for VSphereVM %s which does not exist", vm.Name, vm.VSphereVM)
+		}
+		orphanedVMs = append(orphanedVMs, infrav1.OrphanedVM{Name: vm.Name, MoRef: vm.Ref.Value, VSphereVM: vm.VSphereVM})
+	}
+
+	now := metav1.Now()
+	ctx.VSphereCluster.Status.OrphanedVMs = orphanedVMs
+	ctx.VSphereCluster.Status.LastOrphanedVMSweepTime = &now
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/extra"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/inventory"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestClusterReconciler_ReconcileOrphanedVMs(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext())
+	controllerCtx.OrphanedVMSweepInterval = time.Hour
+	controllerCtx.DeleteOrphanedVMs = true
+	ctx := fake.NewClusterContext(controllerCtx)
+	ctx.VSphereCluster.Spec.Server = simr.ServerURL().Host
+	r := clusterReconciler{controllerCtx}
+
+	s, err := session.GetOrCreate(ctx, session.NewParams().
+		WithServer(simr.ServerURL().Host).
+		WithUserInfo(simr.Username(), simr.Password()).
+		WithDatacenter("*"))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	setOwner := func(name, vsphereVM string) types.ManagedObjectReference {
+		vm, err := s.Finder.VirtualMachine(ctx, name)
+		g.Expect(err).NotTo(HaveOccurred())
+		var config extra.Config
+		config.SetOwner(inventory.OwnerTagName(ctx.Cluster.Namespace, ctx.Cluster.Name), vsphereVM)
+		task, err := vm.Reconfigure(ctx, types.VirtualMachineConfigSpec{ExtraConfig: config})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(task.Wait(ctx)).To(Succeed())
+		return vm.Reference()
+	}
+	setOwner("DC0_H0_VM0", ctx.Cluster.Namespace+"/vm-0")
+	orphaned := setOwner("DC0_H0_VM1", ctx.Cluster.Namespace+"/vm-1")
+
+	g.Expect(ctx.Client.Create(ctx, &infrav1.VSphereVM{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: ctx.Cluster.Namespace,
+			Name:      "vm-0",
+			Labels:    map[string]string{clusterv1.ClusterLabelName: ctx.Cluster.Name},
+		},
+		Spec: infrav1.VSphereVMSpec{
+			VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+				Datacenter:   "DC0",
+				ResourcePool: "/DC0/host/DC0_H0/Resources",
+			},
+		},
+	})).To(Succeed())
+
+	// The VM is only reported by the first sweep finding it.
+	g.Expect(r.reconcileOrphanedVMs(ctx, s)).To(Succeed())
+	g.Expect(ctx.VSphereCluster.Status.OrphanedVMs).To(Equal([]infrav1.OrphanedVM{
+		{Name: "DC0_H0_VM1", MoRef: orphaned.Value, VSphereVM: ctx.Cluster.Namespace + "/vm-1"},
+	}))
+	g.Expect(ctx.VSphereCluster.Status.LastOrphanedVMSweepTime).NotTo(BeNil())
+	g.Expect(simulator.Map.Get(orphaned)).NotTo(BeNil())
+
+	// The VM is not swept again before the interval expired.
+	g.Expect(r.reconcileOrphanedVMs(ctx, s)).To(Succeed())
+	g.Expect(simulator.Map.Get(orphaned)).NotTo(BeNil())
+
+	ctx.VSphereCluster.Status.LastOrphanedVMSweepTime = &metav1.Time{Time: time.Now().Add(-2 * time.Hour)}
+	g.Expect(r.reconcileOrphanedVMs(ctx, s)).To(Succeed())
+	g.Expect(ctx.VSphereCluster.Status.OrphanedVMs).To(BeEmpty())
+	g.Expect(simulator.Map.Get(orphaned)).To(BeNil())
+}
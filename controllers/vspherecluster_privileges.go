@@ -0,0 +1,387 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	clusterutilv1 "sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+)
+
+// privilegeCheckInterval is how long the privileges of the credentials of a
+// VSphereCluster are not checked again while the inventory objects used by
+// its machines do not change.
+const privilegeCheckInterval = 10 * time.Minute
+
+var (
+	// datacenterPrivileges are the privileges required on the datacenter of
+	// the machines.
+	datacenterPrivileges = []string{"System.Read"}
+
+	// templatePrivileges are the privileges required on the template of the
+	// machines. Deploying from a template also requires
+	// VirtualMachine.Provisioning.DeployTemplate.
+	templatePrivileges = []string{"VirtualMachine.Provisioning.Clone"}
+
+	// folderPrivileges are the privileges required on the folder of the
+	// machines to create, configure and delete their VMs.
+	folderPrivileges = []string{
+		"VirtualMachine.Inventory.CreateFromExisting",
+		"VirtualMachine.Inventory.Delete",
+		"VirtualMachine.Interact.PowerOn",
+		"VirtualMachine.Interact.PowerOff",
+		"VirtualMachine.Config.AddNewDisk",
+		"VirtualMachine.Config.AdvancedConfig",
+		"VirtualMachine.Config.CPUCount",
+		"VirtualMachine.Config.Memory",
+		"VirtualMachine.Config.DiskExtend",
+		"VirtualMachine.Config.EditDevice",
+		"VirtualMachine.Config.Settings",
+	}
+
+	// resourcePoolPrivileges are the privileges required on the resource
+	// pool of the machines.
+	resourcePoolPrivileges = []string{"Resource.AssignVMToPool"}
+
+	// datastorePrivileges are the privileges required on the datastore of
+	// the machines.
+	datastorePrivileges = []string{
+		"Datastore.AllocateSpace",
+		"Datastore.Browse",
+		"Datastore.FileManagement",
+	}
+
+	// networkPrivileges are the privileges required on the networks of the
+	// machines.
+	networkPrivileges = []string{"Network.Assign"}
+)
+
+// privilegeChecks records the inventory objects whose privileges were last
+// checked for each VSphereCluster.
+var privilegeChecks = newPrivilegeCheckCache()
+
+// privilegeCheckCache records when the privileges of the credentials of
+// VSphereClusters were last checked, keyed by namespace and name.
+type privilegeCheckCache struct {
+	mu      sync.Mutex
+	entries map[string]privilegeCheckEntry
+}
+
+type privilegeCheckEntry struct {
+	objects   string
+	checkedAt time.Time
+}
+
+func newPrivilegeCheckCache() *privilegeCheckCache {
+	return &privilegeCheckCache{entries: map[string]privilegeCheckEntry{}}
+}
+
+// due returns whether the privileges on the objects are to be checked, i.e.
+// they changed since the last check or the last check expired.
+func (c *privilegeCheckCache) due(key, objects string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	return !ok || entry.objects != objects || time.Since(entry.checkedAt) > privilegeCheckInterval
+}
+
+// done records the check of the privileges on the objects.
+func (c *privilegeCheckCache) done(key, objects string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = privilegeCheckEntry{objects: objects, checkedAt: time.Now()}
+}
+
+// forget drops the last check, e.g. once the VSphereCluster is deleted.
+func (c *privilegeCheckCache) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
+
+// inventoryObject is an inventory object used by the machines of a cluster,
+// e.g. their datastore.
+type inventoryObject struct {
+	kind                  string
+	datacenter            string
+	path                  string
+	createTargetHierarchy bool
+}
+
+func (o inventoryObject) String() string {
+	return o.kind + " " + o.datacenter + "/" + o.path
+}
+
+// clusterInventoryObjects returns the inventory objects used by the
+// VSphereMachines of the cluster on the server of the VSphereCluster.
+func clusterInventoryObjects(ctx *context.ClusterContext) ([]inventoryObject, error) {
+	specs, err := clusterCloneSpecs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[inventoryObject]bool{}
+	var objects []inventoryObject
+	add := func(obj inventoryObject) {
+		if !seen[obj] {
+			seen[obj] = true
+			objects = append(objects, obj)
+		}
+	}
+	for _, spec := range specs {
+		if spec.Server != "" && spec.Server != ctx.VSphereCluster.Spec.Server {
+			continue
+		}
+		add(inventoryObject{kind: "datacenter", datacenter: spec.Datacenter})
+		if spec.Template != "" {
+			add(inventoryObject{kind: "template", datacenter: spec.Datacenter, path: spec.Template})
+		}
+		add(inventoryObject{kind: "folder", datacenter: spec.Datacenter, path: spec.Folder, createTargetHierarchy: spec.CreateTargetHierarchy})
+		add(inventoryObject{kind: "resource pool", datacenter: spec.Datacenter, path: spec.ResourcePool, createTargetHierarchy: spec.CreateTargetHierarchy})
+		if spec.Datastore != "" {
+			add(inventoryObject{kind: "datastore", datacenter: spec.Datacenter, path: spec.Datastore})
+		}
+		for _, device := range spec.Network.Devices {
+			if device.NetworkName != "" {
+				add(inventoryObject{kind: "network", datacenter: spec.Datacenter, path: device.NetworkName})
+			}
+		}
+	}
+	return objects, nil
+}
+
+// reconcileCredentials checks the credentials of the VSphereCluster hold the
+// privileges required on the inventory objects used by its machines, and
+// reports the missing ones with the CredentialsValid condition. The check is
+// repeated when the objects change, or once privilegeCheckInterval expired.
+func (r clusterReconciler) reconcileCredentials(ctx *context.ClusterContext, s *session.Session) error {
+	objects, err := clusterInventoryObjects(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		keys = append(keys, obj.String())
+	}
+	sort.Strings(keys)
+	checked := strings.Join(keys, ";")
+	key := ctx.VSphereCluster.Namespace + "/" + ctx.VSphereCluster.Name
+	if conditions.Has(ctx.VSphereCluster, infrav1.CredentialsValidCondition) && !privilegeChecks.due(key, checked) {
+		return nil
+	}
+
+	finders := map[string]*find.Finder{}
+	var failures []string
+	for _, obj := range objects {
+		finder, ok := finders[obj.datacenter]
+		if !ok {
+			finder = find.NewFinder(s.Client.Client, false)
+			dc, err := finder.DatacenterOrDefault(ctx, obj.datacenter)
+			if err != nil {
+				failure, err := lookupFailure("datacenter", obj.datacenter, err)
+				if err != nil {
+					return err
+				}
+				failures = append(failures, failure)
+				finder = nil
+			} else {
+				finder.SetDatacenter(dc)
+			}
+			finders[obj.datacenter] = finder
+		}
+		// The objects of a datacenter which cannot be found are not checked.
+		if finder == nil {
+			continue
+		}
+
+		failure, err := checkInventoryObjectPrivileges(ctx, s, finder, obj)
+		if err != nil {
+			return err
+		}
+		if failure != "" {
+			failures = append(failures, failure)
+		}
+	}
+	privilegeChecks.done(key, checked)
+
+	if len(failures) > 0 {
+		ctx.Logger.Info("vCenter credentials are missing privileges", "failures", failures)
+		conditions.MarkFalse(ctx.VSphereCluster, infrav1.CredentialsValidCondition, infrav1.MissingPrivilegesReason, clusterv1.ConditionSeverityWarning,
+			"%s", strings.Join(failures, "; "))
+		return nil
+	}
+	conditions.MarkTrue(ctx.VSphereCluster, infrav1.CredentialsValidCondition)
+	return nil
+}
+
+// checkInventoryObjectPrivileges returns which privileges the credentials of
+// the session miss on the object, or that it cannot be found, if any.
+func checkInventoryObjectPrivileges(ctx *context.ClusterContext, s *session.Session, finder *find.Finder, obj inventoryObject) (string, error) {
+	var (
+		ref        types.ManagedObjectReference
+		privileges []string
+		err        error
+	)
+	switch obj.kind {
+	case "datacenter":
+		var dc *object.Datacenter
+		dc, err = finder.DefaultDatacenter(ctx)
+		if err == nil {
+			ref, privileges = dc.Reference(), datacenterPrivileges
+		}
+	case "template":
+		var tpl *object.VirtualMachine
+		tpl, err = findTemplate(ctx, s, finder, obj.path)
+		if err == nil {
+			ref, privileges = tpl.Reference(), templatePrivileges
+			var vm mo.VirtualMachine
+			if err := tpl.Properties(ctx, ref, []string{"config.template"}, &vm); err != nil {
+				return "", errors.Wrapf(err, "unable to get properties of template %q", obj.path)
+			}
+			if vm.Config != nil && vm.Config.Template {
+				privileges = append(privileges, "VirtualMachine.Provisioning.DeployTemplate")
+			}
+		}
+	case "folder":
+		var folder *object.Folder
+		privileges = folderPrivileges
+		folder, err = finder.FolderOrDefault(ctx, obj.path)
+		// The folder is created by CAPV in the default VM folder.
+		if _, ok := err.(*find.NotFoundError); ok && obj.createTargetHierarchy {
+			folder, err = finder.DefaultFolder(ctx)
+			privileges = append([]string{"Folder.Create"}, privileges...)
+		}
+		if err == nil {
+			ref = folder.Reference()
+		}
+	case "resource pool":
+		var pool *object.ResourcePool
+		privileges = resourcePoolPrivileges
+		pool, err = finder.ResourcePoolOrDefault(ctx, obj.path)
+		// The resource pool is created by CAPV in the default resource pool.
+		if _, ok := err.(*find.NotFoundError); ok && obj.createTargetHierarchy {
+			pool, err = finder.DefaultResourcePool(ctx)
+			privileges = append([]string{"Resource.CreatePool"}, privileges...)
+		}
+		if err == nil {
+			ref = pool.Reference()
+		}
+	case "datastore":
+		var ds *object.Datastore
+		ds, err = finder.Datastore(ctx, obj.path)
+		if err == nil {
+			ref, privileges = ds.Reference(), datastorePrivileges
+		}
+	case "network":
+		var network object.NetworkReference
+		network, err = finder.Network(ctx, obj.path)
+		if err == nil {
+			ref, privileges = network.Reference(), networkPrivileges
+		}
+	}
+	if err != nil {
+		return lookupFailure(obj.kind, obj.path, err)
+	}
+
+	missing, err := s.MissingPrivileges(ctx, ref, privileges)
+	if err != nil {
+		return "", err
+	}
+	if len(missing) == 0 {
+		return "", nil
+	}
+	name := obj.path
+	if name == "" {
+		name = ref.Value
+	}
+	return fmt.Sprintf("missing privileges %s on %s %q", strings.Join(missing, ", "), obj.kind, name), nil
+}
+
+// lookupFailure returns why the object of the kind cannot be checked when
+// looking it up failed, or the error if vCenter could not be queried.
+func lookupFailure(kind, path string, err error) (string, error) {
+	switch errors.Cause(err).(type) {
+	case *find.NotFoundError, *find.DefaultNotFoundError:
+		return fmt.Sprintf("%s %q not found or not visible to the credentials", kind, path), nil
+	case *find.MultipleFoundError, *find.DefaultMultipleFoundError:
+		return fmt.Sprintf("%s %q matches several objects", kind, path), nil
+	}
+	return "", errors.Wrapf(err, "unable to find %s %q", kind, path)
+}
+
+// findTemplate finds the template by instance UUID or by name in the
+// datacenter of the finder.
+func findTemplate(ctx *context.ClusterContext, s *session.Session, finder *find.Finder, templateID string) (*object.VirtualMachine, error) {
+	if _, err := uuid.Parse(templateID); err == nil {
+		dc, err := finder.DefaultDatacenter(ctx)
+		if err != nil {
+			return nil, err
+		}
+		instanceUUID := true
+		ref, err := object.NewSearchIndex(s.Client.Client).FindByUuid(ctx, dc, templateID, true, &instanceUUID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error finding template by instance UUID %q", templateID)
+		}
+		if ref != nil {
+			return object.NewVirtualMachine(s.Client.Client, ref.Reference()), nil
+		}
+	}
+	return finder.VirtualMachine(ctx, templateID)
+}
+
+// machineToCluster maps a VSphereMachine to the VSphereCluster of its
+// cluster.
+func (r clusterReconciler) machineToCluster(o client.Object) []ctrl.Request {
+	vsphereMachine, ok := o.(*infrav1.VSphereMachine)
+	if !ok {
+		r.Logger.Error(nil, fmt.Sprintf("expected a VSphereMachine but got a %T", o))
+		return nil
+	}
+	cluster, err := clusterutilv1.GetClusterFromMetadata(r, r.Client, vsphereMachine.ObjectMeta)
+	if err != nil || cluster.Spec.InfrastructureRef == nil {
+		return nil
+	}
+	return []ctrl.Request{{
+		NamespacedName: apitypes.NamespacedName{
+			Namespace: vsphereMachine.Namespace,
+			Name:      cluster.Spec.InfrastructureRef.Name,
+		},
+	}}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+// denyingAuthorizationManager is a simulated AuthorizationManager which does
+// not grant the denied privileges.
+type denyingAuthorizationManager struct {
+	*simulator.AuthorizationManager
+	denied map[string]bool
+}
+
+func (m *denyingAuthorizationManager) HasPrivilegeOnEntities(req *types.HasPrivilegeOnEntities) soap.HasFault {
+	var res []types.EntityPrivilege
+	for _, entity := range req.Entity {
+		privilege := types.EntityPrivilege{Entity: entity}
+		for _, id := range req.PrivId {
+			privilege.PrivAvailability = append(privilege.PrivAvailability, types.PrivilegeAvailability{
+				PrivId:    id,
+				IsGranted: !m.denied[id],
+			})
+		}
+		res = append(res, privilege)
+	}
+	return &methods.HasPrivilegeOnEntitiesBody{
+		Res: &types.HasPrivilegeOnEntitiesResponse{Returnval: res},
+	}
+}
+
+func TestClusterReconciler_ReconcileCredentials(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext())
+	ctx := fake.NewClusterContext(controllerCtx)
+	ctx.VSphereCluster.Spec.Server = simr.ServerURL().Host
+	defer privilegeChecks.forget(ctx.VSphereCluster.Namespace + "/" + ctx.VSphereCluster.Name)
+	r := clusterReconciler{controllerCtx}
+
+	s, err := session.GetOrCreate(ctx, session.NewParams().
+		WithServer(simr.ServerURL().Host).
+		WithUserInfo(simr.Username(), simr.Password()).
+		WithDatacenter("*"))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	vsphereMachine := &infrav1.VSphereMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: ctx.Cluster.Namespace,
+			Name:      "machine",
+			Labels:    map[string]string{clusterv1.ClusterLabelName: ctx.Cluster.Name},
+		},
+		Spec: infrav1.VSphereMachineSpec{
+			VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+				Datacenter:   "DC0",
+				Template:     "DC0_H0_VM0",
+				ResourcePool: "/DC0/host/DC0_C0/Resources",
+				Datastore:    "LocalDS_0",
+				Network: infrav1.NetworkSpec{
+					Devices: []infrav1.NetworkDeviceSpec{{NetworkName: "VM Network"}},
+				},
+			},
+		},
+	}
+	g.Expect(ctx.Client.Create(ctx, vsphereMachine)).To(Succeed())
+
+	g.Expect(r.reconcileCredentials(ctx, s)).To(Succeed())
+	g.Expect(conditions.IsTrue(ctx.VSphereCluster, infrav1.CredentialsValidCondition)).To(BeTrue())
+
+	// The privileges are not checked again until the machines change.
+	authManager := simulator.Map.Get(*s.Client.ServiceContent.AuthorizationManager).(*simulator.AuthorizationManager) //nolint:forcetypeassert
+	simulator.Map.Put(&denyingAuthorizationManager{
+		AuthorizationManager: authManager,
+		denied:               map[string]bool{"Datastore.AllocateSpace": true, "Network.Assign": true},
+	})
+	defer simulator.Map.Put(authManager)
+	g.Expect(r.reconcileCredentials(ctx, s)).To(Succeed())
+	g.Expect(conditions.IsTrue(ctx.VSphereCluster, infrav1.CredentialsValidCondition)).To(BeTrue())
+
+	vsphereMachine.Spec.Network.Devices = append(vsphereMachine.Spec.Network.Devices, infrav1.NetworkDeviceSpec{NetworkName: "missing"})
+	g.Expect(ctx.Client.Update(ctx, vsphereMachine)).To(Succeed())
+	g.Expect(r.reconcileCredentials(ctx, s)).To(Succeed())
+	g.Expect(conditions.GetReason(ctx.VSphereCluster, infrav1.CredentialsValidCondition)).To(Equal(infrav1.MissingPrivilegesReason))
+	message := conditions.GetMessage(ctx.VSphereCluster, infrav1.CredentialsValidCondition)
+	g.Expect(message).To(ContainSubstring(`missing privileges Datastore.AllocateSpace on datastore "LocalDS_0"`))
+	g.Expect(message).To(ContainSubstring(`missing privileges Network.Assign on network "VM Network"`))
+	g.Expect(message).To(ContainSubstring(`network "missing" not found`))
+	g.Expect(message).NotTo(ContainSubstring("template"))
+}
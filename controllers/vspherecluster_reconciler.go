@@ -44,6 +44,9 @@ import (
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/identity"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/controlplaneendpoint"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/inventory"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
 	infrautilv1 "sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
 )
@@ -101,6 +104,7 @@ func (r clusterReconciler) Reconcile(ctx goctx.Context, req ctrl.Request) (_ ctr
 		VSphereCluster:    vsphereCluster,
 		Logger:            r.Logger.WithName(req.Namespace).WithName(req.Name),
 		PatchHelper:       patchHelper,
+		RequestContext:    ctx,
 	}
 
 	// Always issue a patch when exiting this function so changes to the
@@ -136,35 +140,75 @@ func (r clusterReconciler) reconcileDelete(ctx *context.ClusterContext) (reconci
 			"unable to list VSphereMachines part of VSphereCluster %s/%s", ctx.VSphereCluster.Namespace, ctx.VSphereCluster.Name)
 	}
 
-	machineDeletionCount := 0
+	// The VSphereMachines whose Machine was deleted before owning them are
+	// all deleted at once, so the failure to delete one of them does not hold
+	// back the others.
+	var remainingMachines []*infrav1.VSphereMachine
 	var deletionErrors []error
 	for _, vsphereMachine := range vsphereMachines {
 		// If the VSphereMachine is not owned by the CAPI Machine object because the machine object was deleted
 		// before setting the owner references, then proceed with the deletion of the VSphereMachine object.
 		// This is required until CAPI has a solution for https://github.com/kubernetes-sigs/cluster-api/issues/5483
 		if clusterutilv1.IsOwnedByObject(vsphereMachine, ctx.VSphereCluster) && len(vsphereMachine.OwnerReferences) == 1 {
-			machineDeletionCount++
 			// Remove the finalizer since VM creation wouldn't proceed
 			r.Logger.Info("Removing finalizer from VSphereMachine", "namespace", vsphereMachine.Namespace, "name", vsphereMachine.Name)
 			ctrlutil.RemoveFinalizer(vsphereMachine, infrav1.MachineFinalizer)
 			if err := r.Client.Update(ctx, vsphereMachine); err != nil {
-				return reconcile.Result{}, err
+				deletionErrors = append(deletionErrors, err)
+				continue
 			}
 			if err := r.Client.Delete(ctx, vsphereMachine); err != nil && !apierrors.IsNotFound(err) {
 				ctx.Logger.Error(err, "Failed to delete for VSphereMachine", "namespace", vsphereMachine.Namespace, "name", vsphereMachine.Name)
 				deletionErrors = append(deletionErrors, err)
 			}
+			continue
 		}
+		remainingMachines = append(remainingMachines, vsphereMachine)
 	}
 	if len(deletionErrors) > 0 {
 		return reconcile.Result{}, kerrors.NewAggregate(deletionErrors)
 	}
 
-	if len(vsphereMachines)-machineDeletionCount > 0 {
-		ctx.Logger.Info("Waiting for VSphereMachines to be deleted", "count", len(vsphereMachines)-machineDeletionCount)
+	vsphereVMList := &infrav1.VSphereVMList{}
+	if err := ctx.Client.List(ctx, vsphereVMList,
+		client.InNamespace(ctx.Cluster.Namespace),
+		client.MatchingLabels{clusterv1.ClusterLabelName: ctx.Cluster.Name}); err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "unable to list VSphereVMs part of VSphereCluster %s/%s", ctx.VSphereCluster.Namespace, ctx.VSphereCluster.Name)
+	}
+	if err := r.reconcileForceDelete(ctx, vsphereVMList.Items); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// The progress of the deletion of the VSphereMachines is reported in the
+	// status of the VSphereCluster until all of them are gone.
+	deletion := clusterDeletionStatus(remainingMachines, vsphereVMList.Items)
+	ctx.VSphereCluster.Status.Deletion = deletion
+	reportMachinesDeletion(ctx, deletion)
+	if deletion.Machines > 0 {
+		ctx.Logger.Info("Waiting for VSphereMachines to be deleted", "count", deletion.Machines,
+			"deleting", deletion.Deleting, "pending", deletion.Pending, "blocked", len(deletion.Blocked))
 		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
 	}
 
+	// Destroy the folders and resource pools created for the cluster once
+	// all its VMs are gone.
+	if ok, err := r.reconcileInventoryCleanup(ctx); err != nil || !ok {
+		return reconcile.Result{RequeueAfter: 10 * time.Second}, err
+	}
+
+	if err := r.reconcileNSXTSegmentDelete(ctx); err != nil {
+		return reconcile.Result{}, errors.Wrapf(err,
+			"failed to delete NSX-T segment for %s", ctx)
+	}
+
+	if err := r.reconcileControlPlaneEndpointDNSDelete(ctx); err != nil {
+		return reconcile.Result{}, errors.Wrapf(err,
+			"failed to delete the DNS record of the control plane endpoint of %s", ctx)
+	}
+
+	session.ForgetCredentials(ctx.VSphereCluster.Namespace + "/" + ctx.VSphereCluster.Name)
+	privilegeChecks.forget(ctx.VSphereCluster.Namespace + "/" + ctx.VSphereCluster.Name)
+
 	// Remove finalizer on Identity Secret
 	if identity.IsSecretIdentity(ctx.VSphereCluster) {
 		secret := &apiv1.Secret{}
@@ -217,26 +261,69 @@ func (r clusterReconciler) reconcileNormal(ctx *context.ClusterContext) (reconci
 		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
 	}
 
+	if err := r.reconcileDefaultIdentity(ctx); err != nil {
+		conditions.MarkFalse(ctx.VSphereCluster, infrav1.VCenterAvailableCondition, infrav1.VCenterUnreachableReason, clusterv1.ConditionSeverityError, err.Error())
+		return reconcile.Result{}, err
+	}
+
 	if err := r.reconcileIdentitySecret(ctx); err != nil {
 		conditions.MarkFalse(ctx.VSphereCluster, infrav1.VCenterAvailableCondition, infrav1.VCenterUnreachableReason, clusterv1.ConditionSeverityError, err.Error())
 		return reconcile.Result{}, err
 	}
 
-	if err := r.reconcileVCenterConnectivity(ctx); err != nil {
+	vcenterSession, err := r.reconcileVCenterConnectivity(ctx)
+	// The cluster is reconciled again when its identity or the labels of its
+	// namespace change.
+	if errors.Is(err, identity.ErrNamespaceNotAllowed) {
+		ctx.Logger.Info("namespace not allowed to use the identity", "identity", ctx.VSphereCluster.Spec.IdentityRef.Name)
+		conditions.MarkFalse(ctx.VSphereCluster, infrav1.VCenterAvailableCondition, infrav1.IdentityNotAllowedReason, clusterv1.ConditionSeverityError, err.Error())
+		return reconcile.Result{}, nil
+	}
+	if err != nil {
 		conditions.MarkFalse(ctx.VSphereCluster, infrav1.VCenterAvailableCondition, infrav1.VCenterUnreachableReason, clusterv1.ConditionSeverityError, err.Error())
 		return reconcile.Result{}, errors.Wrapf(err,
 			"unexpected error while probing vcenter for %s", ctx)
 	}
 	conditions.MarkTrue(ctx.VSphereCluster, infrav1.VCenterAvailableCondition)
+	ctx.VSphereCluster.Status.VCenterVersion, ctx.VSphereCluster.Status.VCenterBuild = vcenterSession.VCenterVersion()
+
+	if err := r.reconcileCredentials(ctx, vcenterSession); err != nil {
+		return reconcile.Result{}, errors.Wrapf(err,
+			"failed to check the privileges of the vcenter credentials for %s", ctx)
+	}
+
+	// The sweep does not hold up the rest of the reconciliation, and is
+	// retried by the next one.
+	if err := r.reconcileOrphanedVMs(ctx, vcenterSession); err != nil {
+		ctx.Logger.Error(err, "unable to sweep orphaned VMs")
+	}
+
+	// The machines of the cluster are only created once the segment they
+	// are attached to exists.
+	if err := r.reconcileNSXTSegment(ctx); err != nil {
+		conditions.MarkFalse(ctx.VSphereCluster, infrav1.NSXTSegmentReadyCondition, infrav1.NSXTSegmentProvisioningFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		return reconcile.Result{}, errors.Wrapf(err,
+			"failed to reconcile NSX-T segment for %s", ctx)
+	}
 	ctx.VSphereCluster.Status.Ready = true
 
 	// Ensure the VSphereCluster is reconciled when the API server first comes online.
 	// A reconcile event will only be triggered if the Cluster is not marked as
 	// ControlPlaneInitialized.
 	r.reconcileVSphereClusterWhenAPIServerIsOnline(ctx)
-	if ctx.VSphereCluster.Spec.ControlPlaneEndpoint.IsZero() {
-		ctx.Logger.Info("control plane endpoint is not reconciled")
-		return reconcile.Result{}, nil
+
+	ok, err = r.reconcileControlPlaneEndpoint(ctx)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if !ok {
+		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
+	if err := r.reconcileControlPlaneEndpointDNS(ctx); err != nil {
+		conditions.MarkFalse(ctx.VSphereCluster, infrav1.ControlPlaneEndpointDNSReadyCondition, infrav1.DNSRecordProvisioningFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		return reconcile.Result{}, errors.Wrapf(err,
+			"failed to register the DNS record of the control plane endpoint of %s", ctx)
 	}
 
 	// If the cluster is deleted, that's mean that the workload cluster is being deleted and so the CCM/CSI instances
@@ -249,9 +336,92 @@ func (r clusterReconciler) reconcileNormal(ctx *context.ClusterContext) (reconci
 		return reconcile.Result{}, nil
 	}
 
+	if err := r.reconcileCloudProvider(ctx); err != nil {
+		conditions.MarkFalse(ctx.VSphereCluster, infrav1.CCMAvailableCondition, infrav1.CCMProvisioningFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		return reconcile.Result{}, errors.Wrapf(err,
+			"failed to reconcile cloud provider for %s", ctx)
+	}
+
+	ready, err := r.reconcileCSI(ctx)
+	if err != nil {
+		conditions.MarkFalse(ctx.VSphereCluster, infrav1.CSIAvailableCondition, infrav1.CSIProvisioningFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		return reconcile.Result{}, errors.Wrapf(err,
+			"failed to reconcile CSI driver for %s", ctx)
+	}
+	if !ready {
+		ctx.Logger.Info("waiting for CSI driver to become available")
+		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+
 	return reconcile.Result{}, nil
 }
 
+// controlPlaneEndpointProviders are the providers of the control plane
+// endpoint which can be selected by the control plane endpoint provider
+// annotation of a VSphereCluster.
+var controlPlaneEndpointProviders = map[string]services.ControlPlaneEndpointProvider{
+	infrav1.StaticControlPlaneEndpointProvider:         controlplaneendpoint.Static{},
+	infrav1.KubeVIPControlPlaneEndpointProvider:        controlplaneendpoint.KubeVIP{},
+	infrav1.NSXALBControlPlaneEndpointProvider:         controlplaneendpoint.NSXALB{},
+	infrav1.ExternalControlPlaneEndpointProvider:       controlplaneendpoint.External{},
+	infrav1.LoadBalancerVMControlPlaneEndpointProvider: controlplaneendpoint.LoadBalancerVM{},
+}
+
+// controlPlaneEndpointProviderName returns the name of the control plane
+// endpoint provider of the VSphereCluster.
+func controlPlaneEndpointProviderName(vsphereCluster *infrav1.VSphereCluster) string {
+	if name := vsphereCluster.Annotations[infrav1.ControlPlaneEndpointProviderAnnotation]; name != "" {
+		return name
+	}
+	return infrav1.StaticControlPlaneEndpointProvider
+}
+
+// reconcileControlPlaneEndpoint sets the control plane endpoint of the
+// VSphereCluster from its control plane endpoint provider and reports what
+// the provider is waiting for in the ControlPlaneEndpointReady condition.
+func (r clusterReconciler) reconcileControlPlaneEndpoint(ctx *context.ClusterContext) (bool, error) {
+	name := controlPlaneEndpointProviderName(ctx.VSphereCluster)
+	provider, ok := controlPlaneEndpointProviders[name]
+	if !ok {
+		conditions.MarkFalse(ctx.VSphereCluster, infrav1.ControlPlaneEndpointReadyCondition, infrav1.ControlPlaneEndpointProvisioningFailedReason, clusterv1.ConditionSeverityError,
+			"unknown control plane endpoint provider %q set by annotation %s", name, infrav1.ControlPlaneEndpointProviderAnnotation)
+		return false, nil
+	}
+
+	endpoint, waitingFor, err := provider.ReconcileControlPlaneEndpoint(ctx)
+	if err != nil {
+		conditions.MarkFalse(ctx.VSphereCluster, infrav1.ControlPlaneEndpointReadyCondition, infrav1.ControlPlaneEndpointProvisioningFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		return false, errors.Wrapf(err,
+			"failed to reconcile control plane endpoint of %s with provider %s", ctx, name)
+	}
+	if endpoint == nil {
+		ctx.Logger.Info("waiting for control plane endpoint", "provider", name, "reason", waitingFor)
+		conditions.MarkFalse(ctx.VSphereCluster, infrav1.ControlPlaneEndpointReadyCondition, infrav1.WaitingForControlPlaneEndpointReason, clusterv1.ConditionSeverityInfo, waitingFor)
+		return false, nil
+	}
+
+	ctx.VSphereCluster.Spec.ControlPlaneEndpoint = *endpoint
+	conditions.MarkTrue(ctx.VSphereCluster, infrav1.ControlPlaneEndpointReadyCondition)
+	return true, nil
+}
+
+// reconcileDefaultIdentity binds a VSphereCluster without an IdentityRef to
+// the default identity of its namespace, if any, so the credentials it uses
+// are recorded in its spec.
+func (r clusterReconciler) reconcileDefaultIdentity(ctx *context.ClusterContext) error {
+	if ctx.VSphereCluster.Spec.IdentityRef != nil {
+		return nil
+	}
+	ref, err := identity.DefaultIdentityRef(ctx, r.Client, ctx.VSphereCluster.Namespace)
+	if err != nil || ref == nil {
+		return err
+	}
+	ctx.VSphereCluster.Spec.IdentityRef = ref
+	ctx.Logger.Info("bound to the default identity of the namespace", "identity", ref.Name)
+	ctx.Recorder.Eventf(ctx.VSphereCluster, "DefaultIdentityBound", "Bound to VSphereClusterIdentity %s, the default identity of namespace %s", ref.Name, ctx.VSphereCluster.Namespace)
+	return nil
+}
+
 func (r clusterReconciler) reconcileIdentitySecret(ctx *context.ClusterContext) error {
 	vsphereCluster := ctx.VSphereCluster
 	if identity.IsSecretIdentity(vsphereCluster) {
@@ -265,6 +435,13 @@ func (r clusterReconciler) reconcileIdentitySecret(ctx *context.ClusterContext)
 			return err
 		}
 
+		// a restored cluster is the owner of the secret referencing the UID
+		// of the cluster it was backed up from
+		if r.RestoreRecoveryMode && identity.RestampOwnerReference(secret, vsphereCluster, "VSphereCluster") {
+			ctx.Logger.Info("restamped the owner reference of restored identity secret", "secret", secret.Name)
+			ctx.Recorder.Eventf(vsphereCluster, "OwnerReferenceRestamped", "Restamped the owner reference of identity secret %s/%s", secret.Namespace, secret.Name)
+		}
+
 		// check if cluster is already an owner
 		if !clusterutilv1.IsOwnedByObject(secret, vsphereCluster) {
 			ownerReferences := secret.GetOwnerReferences()
@@ -291,29 +468,73 @@ func (r clusterReconciler) reconcileIdentitySecret(ctx *context.ClusterContext)
 	return nil
 }
 
-func (r clusterReconciler) reconcileVCenterConnectivity(ctx *context.ClusterContext) error {
+func (r clusterReconciler) reconcileVCenterConnectivity(ctx *context.ClusterContext) (*session.Session, error) {
+	creds, err := r.vCenterCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	providerConfig, err := infrautilv1.GetProviderConfig(ctx, ctx.Client)
+	if err != nil {
+		return nil, err
+	}
+
 	params := session.NewParams().
 		WithServer(ctx.VSphereCluster.Spec.Server).
 		WithThumbprint(ctx.VSphereCluster.Spec.Thumbprint).
+		WithConnection(creds.Connection).
+		WithConnection(ctx.VSphereCluster.Spec.Connection).
 		WithFeatures(session.Feature{
 			KeepAliveDuration: r.KeepAliveDuration,
-		})
+			KeepAliveRetries:  r.KeepAliveRetries,
+			QPS:               float32(r.VCenterQPS),
+			Burst:             r.VCenterBurst,
+			TLS:               r.VCenterTLSPolicy,
+		}.WithProviderConfig(providerConfig).WithRateLimit(creds.RateLimit).WithTLSPolicy(creds.TLS))
 
-	if ctx.VSphereCluster.Spec.IdentityRef != nil {
-		creds, err := identity.GetCredentials(ctx, r.Client, ctx.VSphereCluster, r.Namespace)
-		if err != nil {
-			return err
-		}
+	params = params.WithUserInfo(creds.Username, creds.Password)
 
-		params = params.WithUserInfo(creds.Username, creds.Password)
-		_, err = session.GetOrCreate(ctx, params)
-		return err
+	// Short-lived credentials are renewed before they expire.
+	if !creds.RenewAt.IsZero() {
+		r.reconcileVSphereClusterWhenCredentialsRenew(ctx, creds.RenewAt)
 	}
 
-	params = params.WithUserInfo(ctx.Username, ctx.Password)
-	_, err := session.GetOrCreate(ctx,
-		params)
-	return err
+	// Tear down the sessions of the previous credentials once the identity
+	// of the cluster is rotated.
+	if session.RotateCredentials(ctx, ctx.VSphereCluster.Namespace+"/"+ctx.VSphereCluster.Name, params) {
+		ctx.Logger.Info("vCenter credentials rotated, re-authenticating", "server", ctx.VSphereCluster.Spec.Server)
+		ctx.Recorder.Eventf(ctx.VSphereCluster, "CredentialsRotated", "Re-authenticated to vCenter %s with rotated credentials", ctx.VSphereCluster.Spec.Server)
+	}
+
+	return session.GetOrCreate(ctx, params)
+}
+
+// reconcileInventoryCleanup destroys the folders and resource pools created
+// for the cluster on its vCenter, along with their owner tag, once all the
+// VSphereVMs of the cluster are deleted.
+func (r clusterReconciler) reconcileInventoryCleanup(ctx *context.ClusterContext) (bool, error) {
+	vsphereVMList := &infrav1.VSphereVMList{}
+	if err := ctx.Client.List(ctx, vsphereVMList,
+		client.InNamespace(ctx.Cluster.Namespace),
+		client.MatchingLabels{clusterv1.ClusterLabelName: ctx.Cluster.Name}); err != nil {
+		return false, errors.Wrapf(err, "unable to list VSphereVMs part of VSphereCluster %s/%s", ctx.VSphereCluster.Namespace, ctx.VSphereCluster.Name)
+	}
+	if len(vsphereVMList.Items) > 0 {
+		ctx.Logger.Info("Waiting for VSphereVMs to be deleted", "count", len(vsphereVMList.Items))
+		return false, nil
+	}
+
+	// The deletion of the cluster is not blocked on a vCenter that cannot be
+	// reached anymore, e.g. because its credentials are already deleted.
+	vCenterSession, err := r.reconcileVCenterConnectivity(ctx)
+	if err != nil {
+		ctx.Logger.Error(err, "unable to connect to vcenter, leaving the inventory of the cluster in place")
+		ctx.Recorder.Warnf(ctx.VSphereCluster, "InventoryCleanupSkipped", "Unable to connect to vCenter %s to clean up the inventory: %v", ctx.VSphereCluster.Spec.Server, err)
+		return true, nil
+	}
+	if err := inventory.Cleanup(ctx, vCenterSession, inventory.OwnerTagName(ctx.Cluster.Namespace, ctx.Cluster.Name)); err != nil {
+		return false, errors.Wrapf(err, "unable to clean up the inventory of %s", ctx)
+	}
+	return true, nil
 }
 
 func (r clusterReconciler) reconcileDeploymentZones(ctx *context.ClusterContext) (bool, error) {
@@ -407,6 +628,39 @@ func (r clusterReconciler) reconcileVSphereClusterWhenAPIServerIsOnline(ctx *con
 	}()
 }
 
+var (
+	// credentialRenewTriggers is used to prevent multiple goroutines for a
+	// single VSphereCluster waiting to renew the same credentials.
+	credentialRenewTriggers   = map[types.UID]time.Time{}
+	credentialRenewTriggersMu sync.Mutex
+)
+
+// reconcileVSphereClusterWhenCredentialsRenew triggers a reconcile of the
+// VSphereCluster once its short-lived credentials are to be renewed.
+func (r clusterReconciler) reconcileVSphereClusterWhenCredentialsRenew(ctx *context.ClusterContext, renewAt time.Time) {
+	credentialRenewTriggersMu.Lock()
+	defer credentialRenewTriggersMu.Unlock()
+	if scheduled, ok := credentialRenewTriggers[ctx.VSphereCluster.UID]; ok && !scheduled.After(renewAt) {
+		return
+	}
+	credentialRenewTriggers[ctx.VSphereCluster.UID] = renewAt
+
+	obj := ctx.VSphereCluster.DeepCopy()
+	time.AfterFunc(time.Until(renewAt), func() {
+		credentialRenewTriggersMu.Lock()
+		if credentialRenewTriggers[obj.UID].Equal(renewAt) {
+			delete(credentialRenewTriggers, obj.UID)
+		}
+		credentialRenewTriggersMu.Unlock()
+
+		ctx.Logger.Info("triggering GenericEvent", "reason", "credentials-renew")
+		eventChannel := ctx.GetGenericEventChannelFor(obj.GetObjectKind().GroupVersionKind())
+		eventChannel <- event.GenericEvent{
+			Object: obj,
+		}
+	})
+}
+
 func (r clusterReconciler) isAPIServerOnline(ctx *context.ClusterContext) bool {
 	if kubeClient, err := infrautilv1.NewKubeClient(ctx, ctx.Client, ctx.Cluster); err == nil {
 		if _, err := kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{}); err == nil {
@@ -511,14 +765,6 @@ func (r clusterReconciler) controlPlaneMachineToCluster(o client.Object) []ctrl.
 		return nil
 	}
 
-	if conditions.IsTrue(cluster, clusterv1.ControlPlaneInitializedCondition) {
-		return nil
-	}
-
-	if !cluster.Spec.ControlPlaneEndpoint.IsZero() {
-		return nil
-	}
-
 	// Fetch the VSphereCluster
 	vsphereCluster := &infrav1.VSphereCluster{}
 	vsphereClusterKey := client.ObjectKey{
@@ -531,8 +777,16 @@ func (r clusterReconciler) controlPlaneMachineToCluster(o client.Object) []ctrl.
 		return nil
 	}
 
-	if !vsphereCluster.Spec.ControlPlaneEndpoint.IsZero() {
-		return nil
+	// The Endpoints of the control plane load balanced by NSX Advanced Load
+	// Balancer track the addresses of the control plane machines for the
+	// whole life of the cluster.
+	if controlPlaneEndpointProviderName(vsphereCluster) != infrav1.NSXALBControlPlaneEndpointProvider {
+		if conditions.IsTrue(cluster, clusterv1.ControlPlaneInitializedCondition) {
+			return nil
+		}
+		if !cluster.Spec.ControlPlaneEndpoint.IsZero() || !vsphereCluster.Spec.ControlPlaneEndpoint.IsZero() {
+			return nil
+		}
 	}
 
 	return []ctrl.Request{{
@@ -543,6 +797,26 @@ func (r clusterReconciler) controlPlaneMachineToCluster(o client.Object) []ctrl.
 	}}
 }
 
+// loadBalancerVMToCluster maps a VSphereLoadBalancerVM to the VSphereCluster
+// of its cluster, whose control plane endpoint may be its virtual IP.
+func (r clusterReconciler) loadBalancerVMToCluster(o client.Object) []ctrl.Request {
+	loadBalancer, ok := o.(*infrav1.VSphereLoadBalancerVM)
+	if !ok {
+		r.Logger.Error(nil, fmt.Sprintf("expected a VSphereLoadBalancerVM but got a %T", o))
+		return nil
+	}
+	cluster, err := clusterutilv1.GetClusterFromMetadata(r, r.Client, loadBalancer.ObjectMeta)
+	if err != nil || cluster.Spec.InfrastructureRef == nil {
+		return nil
+	}
+	return []ctrl.Request{{
+		NamespacedName: types.NamespacedName{
+			Namespace: loadBalancer.Namespace,
+			Name:      cluster.Spec.InfrastructureRef.Name,
+		},
+	}}
+}
+
 func (r clusterReconciler) deploymentZoneToCluster(o client.Object) []ctrl.Request {
 	var requests []ctrl.Request
 	obj, ok := o.(*infrav1.VSphereDeploymentZone)
@@ -571,3 +845,96 @@ func (r clusterReconciler) deploymentZoneToCluster(o client.Object) []ctrl.Reque
 	}
 	return requests
 }
+
+// identityToCluster maps a VSphereClusterIdentity to the VSphereClusters
+// referencing it, so changes of its allowed namespaces are enforced.
+func (r clusterReconciler) identityToCluster(o client.Object) []ctrl.Request {
+	return r.clustersWithIdentity(func(cluster *infrav1.VSphereCluster) bool {
+		return cluster.Spec.IdentityRef != nil && cluster.Spec.IdentityRef.Name == o.GetName()
+	})
+}
+
+// namespaceToCluster maps a namespace to the VSphereClusters it holds which
+// reference a VSphereClusterIdentity, whose allowed namespaces may select
+// the namespace by its labels, or which are to be bound to the default
+// identity of the namespace.
+func (r clusterReconciler) namespaceToCluster(o client.Object) []ctrl.Request {
+	return r.clustersWithIdentity(func(cluster *infrav1.VSphereCluster) bool {
+		return cluster.Namespace == o.GetName()
+	})
+}
+
+// clustersWithIdentity returns the requests of the VSphereClusters matching
+// and either referencing a VSphereClusterIdentity or without any identity.
+func (r clusterReconciler) clustersWithIdentity(match func(*infrav1.VSphereCluster) bool) []ctrl.Request {
+	var clusterList infrav1.VSphereClusterList
+	if err := r.Client.List(r.Context, &clusterList); err != nil {
+		r.Logger.Error(err, "unable to list clusters")
+		return nil
+	}
+
+	var requests []ctrl.Request
+	for i := range clusterList.Items {
+		cluster := &clusterList.Items[i]
+		if ref := cluster.Spec.IdentityRef; (ref != nil && ref.Kind != infrav1.VSphereClusterIdentityKind) || !match(cluster) {
+			continue
+		}
+		requests = append(requests, ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: cluster.Namespace,
+				Name:      cluster.Name,
+			},
+		})
+	}
+	return requests
+}
+
+// identitySecretToCluster maps an identity secret to the VSphereClusters
+// using it, either directly or through a VSphereClusterIdentity.
+func (r clusterReconciler) identitySecretToCluster(o client.Object) []ctrl.Request {
+	secret, ok := o.(*apiv1.Secret)
+	if !ok {
+		r.Logger.Error(nil, fmt.Sprintf("expected a Secret but got a %T", o))
+		return nil
+	}
+
+	var clusterList infrav1.VSphereClusterList
+	if err := r.Client.List(r.Context, &clusterList); err != nil {
+		r.Logger.Error(err, "unable to list clusters")
+		return nil
+	}
+
+	var requests []ctrl.Request
+	for _, cluster := range clusterList.Items {
+		ref := cluster.Spec.IdentityRef
+		if ref == nil {
+			continue
+		}
+		switch ref.Kind {
+		case infrav1.SecretKind:
+			if cluster.Namespace != secret.Namespace || ref.Name != secret.Name {
+				continue
+			}
+		case infrav1.VSphereClusterIdentityKind:
+			if secret.Namespace != r.Namespace {
+				continue
+			}
+			vsphereClusterIdentity := &infrav1.VSphereClusterIdentity{}
+			if err := r.Client.Get(r.Context, client.ObjectKey{Name: ref.Name}, vsphereClusterIdentity); err != nil {
+				continue
+			}
+			if vsphereClusterIdentity.Spec.SecretName != secret.Name {
+				continue
+			}
+		default:
+			continue
+		}
+		requests = append(requests, ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: cluster.Namespace,
+				Name:      cluster.Name,
+			},
+		})
+	}
+	return requests
+}
@@ -733,6 +733,96 @@ func TestClusterReconciler_ReconcileDeploymentZones(t *testing.T) {
 	}
 }
 
+func TestClusterReconciler_IdentitySecretToCluster(t *testing.T) {
+	g := NewWithT(t)
+
+	newCluster := func(name string, ref *infrav1.VSphereIdentityReference) *infrav1.VSphereCluster {
+		return &infrav1.VSphereCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: fake.Namespace},
+			Spec:       infrav1.VSphereClusterSpec{IdentityRef: ref},
+		}
+	}
+	initObjs := []client.Object{
+		newCluster("secret-cluster", &infrav1.VSphereIdentityReference{Kind: infrav1.SecretKind, Name: "secret"}),
+		newCluster("other-secret-cluster", &infrav1.VSphereIdentityReference{Kind: infrav1.SecretKind, Name: "other-secret"}),
+		newCluster("identity-cluster", &infrav1.VSphereIdentityReference{Kind: infrav1.VSphereClusterIdentityKind, Name: "identity"}),
+		newCluster("no-identity-cluster", nil),
+		&infrav1.VSphereClusterIdentity{
+			ObjectMeta: metav1.ObjectMeta{Name: "identity"},
+			Spec:       infrav1.VSphereClusterIdentitySpec{SecretName: "identity-secret"},
+		},
+	}
+	controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext(initObjs...))
+	r := clusterReconciler{controllerCtx}
+
+	requests := r.identitySecretToCluster(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "secret", Namespace: fake.Namespace}})
+	g.Expect(requests).To(HaveLen(1))
+	g.Expect(requests[0].Name).To(Equal("secret-cluster"))
+
+	requests = r.identitySecretToCluster(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "identity-secret", Namespace: fake.ControllerManagerNamespace}})
+	g.Expect(requests).To(HaveLen(1))
+	g.Expect(requests[0].Name).To(Equal("identity-cluster"))
+
+	// Secrets of VSphereClusterIdentities live in the controller namespace.
+	requests = r.identitySecretToCluster(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "identity-secret", Namespace: fake.Namespace}})
+	g.Expect(requests).To(BeEmpty())
+}
+
+func TestClusterReconciler_IdentityAndNamespaceToCluster(t *testing.T) {
+	g := NewWithT(t)
+
+	newCluster := func(name string, ref *infrav1.VSphereIdentityReference) *infrav1.VSphereCluster {
+		return &infrav1.VSphereCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: fake.Namespace},
+			Spec:       infrav1.VSphereClusterSpec{IdentityRef: ref},
+		}
+	}
+	controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext(
+		newCluster("secret-cluster", &infrav1.VSphereIdentityReference{Kind: infrav1.SecretKind, Name: "identity"}),
+		newCluster("identity-cluster", &infrav1.VSphereIdentityReference{Kind: infrav1.VSphereClusterIdentityKind, Name: "identity"}),
+		newCluster("no-identity-cluster", nil),
+	))
+	r := clusterReconciler{controllerCtx}
+
+	requests := r.identityToCluster(&infrav1.VSphereClusterIdentity{ObjectMeta: metav1.ObjectMeta{Name: "identity"}})
+	g.Expect(requests).To(HaveLen(1))
+	g.Expect(requests[0].Name).To(Equal("identity-cluster"))
+
+	// Clusters without identity are bound to the default identity of their
+	// namespace.
+	requests = r.namespaceToCluster(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: fake.Namespace}})
+	g.Expect(requests).To(HaveLen(2))
+
+	requests = r.namespaceToCluster(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}})
+	g.Expect(requests).To(BeEmpty())
+}
+
+func TestClusterReconciler_ReconcileDefaultIdentity(t *testing.T) {
+	g := NewWithT(t)
+
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: fake.Namespace}}
+	controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext(namespace))
+	ctx := fake.NewClusterContext(controllerCtx)
+	r := clusterReconciler{controllerCtx}
+
+	g.Expect(r.reconcileDefaultIdentity(ctx)).To(Succeed())
+	g.Expect(ctx.VSphereCluster.Spec.IdentityRef).To(BeNil())
+
+	namespace.Annotations = map[string]string{infrav1.DefaultIdentityAnnotation: "team-identity"}
+	g.Expect(controllerCtx.Client.Update(ctx, namespace)).To(Succeed())
+	g.Expect(r.reconcileDefaultIdentity(ctx)).To(Succeed())
+	g.Expect(ctx.VSphereCluster.Spec.IdentityRef).To(Equal(&infrav1.VSphereIdentityReference{
+		Kind: infrav1.VSphereClusterIdentityKind,
+		Name: "team-identity",
+	}))
+
+	// The identity of a bound cluster is kept.
+	namespace.Annotations[infrav1.DefaultIdentityAnnotation] = "other-identity"
+	g.Expect(controllerCtx.Client.Update(ctx, namespace)).To(Succeed())
+	g.Expect(r.reconcileDefaultIdentity(ctx)).To(Succeed())
+	g.Expect(ctx.VSphereCluster.Spec.IdentityRef.Name).To(Equal("team-identity"))
+}
+
 func deploymentZone(server, fdName string, cp, ready *bool) *infrav1.VSphereDeploymentZone {
 	return &infrav1.VSphereDeploymentZone{
 		ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("zone-%s", fdName)},
@@ -41,6 +41,7 @@ import (
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
 	pkgidentity "sigs.k8s.io/cluster-api-provider-vsphere/pkg/identity"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 )
 
 var (
@@ -71,7 +72,7 @@ func AddVsphereClusterIdentityControllerToManager(ctx *context.ControllerManager
 	return ctrl.NewControllerManagedBy(mgr).
 		For(identityControlledType).
 		WithOptions(controller.Options{MaxConcurrentReconciles: ctx.MaxConcurrentReconciles}).
-		Complete(reconciler)
+		Complete(tracing.Reconciler(identityControlledTypeName, reconciler))
 }
 
 type clusterIdentityReconciler struct {
@@ -117,6 +118,18 @@ func (r clusterIdentityReconciler) Reconcile(ctx _context.Context, req reconcile
 		return r.reconcileDelete(ctx, identity)
 	}
 
+	// Credentials read from Vault do not involve a secret.
+	if identity.Spec.Vault != nil {
+		if _, err := pkgidentity.NewVaultProvider(identity.Spec.Vault).Retrieve(ctx); err != nil {
+			conditions.MarkFalse(identity, infrav1.CredentialsAvailableCondidtion, infrav1.VaultCredentialsNotAvailableReason, clusterv1.ConditionSeverityWarning, err.Error())
+			identity.Status.Ready = false
+			return reconcile.Result{}, err
+		}
+		conditions.MarkTrue(identity, infrav1.CredentialsAvailableCondidtion)
+		identity.Status.Ready = true
+		return reconcile.Result{}, nil
+	}
+
 	// fetch secret
 	secret := &corev1.Secret{}
 	secretKey := client.ObjectKey{
@@ -128,6 +141,22 @@ func (r clusterIdentityReconciler) Reconcile(ctx _context.Context, req reconcile
 		return reconcile.Result{}, errors.Errorf("secret: %s not found in namespace: %s", secretKey.Name, secretKey.Namespace)
 	}
 
+	// a restored identity is the owner of the secret referencing the UID of
+	// the identity it was backed up from, and the finalizer of the secret may
+	// not have been restored along with it
+	if r.RestoreRecoveryMode && clusterutilv1.IsOwnedByObject(secret, identity) {
+		restamped := pkgidentity.RestampOwnerReference(secret, identity, "VSphereClusterIdentity")
+		if restamped || !ctrlutil.ContainsFinalizer(secret, infrav1.SecretIdentitySetFinalizer) {
+			r.Logger.Info("restamping the owner reference and the finalizer of restored identity secret", "secret", secret.Name)
+			ctrlutil.AddFinalizer(secret, infrav1.SecretIdentitySetFinalizer)
+			if err := r.Client.Update(ctx, secret); err != nil {
+				conditions.MarkFalse(identity, infrav1.CredentialsAvailableCondidtion, infrav1.SecretOwnerReferenceFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+				return reconcile.Result{}, err
+			}
+			r.Recorder.Eventf(identity, "OwnerReferenceRestamped", "Restamped the owner reference of identity secret %s/%s", secret.Namespace, secret.Name)
+		}
+	}
+
 	if !clusterutilv1.IsOwnedByObject(secret, identity) {
 		ownerReferences := secret.GetOwnerReferences()
 		if pkgidentity.IsOwnedByIdentityOrCluster(ownerReferences) {
@@ -161,6 +190,9 @@ func (r clusterIdentityReconciler) Reconcile(ctx _context.Context, req reconcile
 
 func (r clusterIdentityReconciler) reconcileDelete(ctx _context.Context, identity *infrav1.VSphereClusterIdentity) (reconcile.Result, error) {
 	r.Logger.Info("Reconciling VSphereClusterIdentity delete")
+	if identity.Spec.Vault != nil {
+		return reconcile.Result{}, nil
+	}
 	secret := &corev1.Secret{}
 	secretKey := client.ObjectKey{
 		Namespace: r.Namespace,
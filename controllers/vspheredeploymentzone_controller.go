@@ -46,6 +46,7 @@ import (
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/identity"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
 )
 
@@ -87,8 +88,8 @@ func AddVSphereDeploymentZoneControllerToManager(ctx *context.ControllerManagerC
 			&source.Channel{Source: ctx.GetGenericEventChannelFor(controlledTypeGVK)},
 			&handler.EnqueueRequestForObject{},
 		).
-		WithOptions(controller.Options{MaxConcurrentReconciles: ctx.MaxConcurrentReconciles}).
-		Complete(reconciler)
+		WithOptions(controller.Options{MaxConcurrentReconciles: ctx.VSphereDeploymentZoneConcurrency}).
+		Complete(tracing.Reconciler(controlledTypeName, reconciler))
 }
 
 type vsphereDeploymentZoneReconciler struct {
@@ -133,6 +134,7 @@ func (r vsphereDeploymentZoneReconciler) Reconcile(ctx goctx.Context, request re
 		VSphereFailureDomain:  failureDomain,
 		Logger:                logr,
 		PatchHelper:           patchHelper,
+		RequestContext:        ctx,
 	}
 	defer func() {
 		if err := vsphereDeploymentZoneContext.Patch(); err != nil {
@@ -221,41 +223,72 @@ func (r vsphereDeploymentZoneReconciler) reconcilePlacementConstraint(ctx *conte
 }
 
 func (r vsphereDeploymentZoneReconciler) getVCenterSession(ctx *context.VSphereDeploymentZoneContext) (*session.Session, error) {
+	// The session outlives the request, whose span it is created in.
+	sessionCtx := tracing.ContextWithSpanOf(r.Context, ctx)
+	providerConfig, err := util.GetProviderConfig(ctx, r.Client)
+	if err != nil {
+		return nil, err
+	}
+	feature := session.Feature{
+		KeepAliveDuration: r.KeepAliveDuration,
+		KeepAliveRetries:  r.KeepAliveRetries,
+		QPS:               float32(r.VCenterQPS),
+		Burst:             r.VCenterBurst,
+		TLS:               r.VCenterTLSPolicy,
+	}.WithProviderConfig(providerConfig)
 	params := session.NewParams().
 		WithServer(ctx.VSphereDeploymentZone.Spec.Server).
 		WithDatacenter(ctx.VSphereFailureDomain.Spec.Topology.Datacenter).
 		WithUserInfo(r.ControllerContext.Username, r.ControllerContext.Password).
-		WithFeatures(session.Feature{
-			KeepAliveDuration: r.KeepAliveDuration,
-		})
+		WithFeatures(feature)
 
 	clusterList := &infrav1.VSphereClusterList{}
 	if err := r.Client.List(ctx, clusterList); err != nil {
 		return nil, err
 	}
 
-	for _, vsphereCluster := range clusterList.Items {
-		if ctx.VSphereDeploymentZone.Spec.Server == vsphereCluster.Spec.Server && vsphereCluster.Spec.IdentityRef != nil {
+	server := ctx.VSphereDeploymentZone.Spec.Server
+	for i := range clusterList.Items {
+		vsphereCluster := &clusterList.Items[i]
+		thumbprint, ok := clusterServerThumbprint(vsphereCluster, server)
+		if ok && identity.IdentityRefForServer(vsphereCluster, server) != nil {
 			logger := ctx.Logger.WithValues("cluster", vsphereCluster.Name)
-			params = params.WithThumbprint(vsphereCluster.Spec.Thumbprint)
-			clust := vsphereCluster
-			creds, err := identity.GetCredentials(ctx, r.Client, &clust, r.Namespace)
+			params = params.WithThumbprint(thumbprint)
+			creds, err := identity.GetCredentialsForServer(ctx, r.Client, vsphereCluster, server, r.Namespace)
 			if err != nil {
 				logger.Error(err, "error retrieving credentials from IdentityRef")
 				continue
 			}
 			logger.Info("using server credentials to create the authenticated session")
-			params = params.WithUserInfo(creds.Username, creds.Password)
-			return session.GetOrCreate(r.Context,
+			params = params.WithUserInfo(creds.Username, creds.Password).
+				WithConnection(creds.Connection).
+				WithConnection(vsphereCluster.Spec.Connection).
+				WithFeatures(feature.WithRateLimit(creds.RateLimit).WithTLSPolicy(creds.TLS))
+			return session.GetOrCreate(sessionCtx,
 				params)
 		}
 	}
 
 	// Fallback to using credentials provided to the manager
-	return session.GetOrCreate(r.Context,
+	return session.GetOrCreate(sessionCtx,
 		params)
 }
 
+// clusterServerThumbprint returns the thumbprint of the vCenter at the server
+// and whether it is the vCenter of the cluster or one of its additional
+// vCenters.
+func clusterServerThumbprint(vsphereCluster *infrav1.VSphereCluster, server string) (string, bool) {
+	if vsphereCluster.Spec.Server == server {
+		return vsphereCluster.Spec.Thumbprint, true
+	}
+	for _, vcenter := range vsphereCluster.Spec.VCenters {
+		if vcenter.Server == server {
+			return vcenter.Thumbprint, true
+		}
+	}
+	return "", false
+}
+
 func (r vsphereDeploymentZoneReconciler) reconcileDelete(ctx *context.VSphereDeploymentZoneContext) (reconcile.Result, error) {
 	r.Logger.Info("Deleting VSphereDeploymentZone")
 
@@ -17,8 +17,12 @@ limitations under the License.
 package controllers
 
 import (
+	"strings"
+
 	"github.com/pkg/errors"
+	pbmTypes "github.com/vmware/govmomi/pbm/types"
 	apierrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -27,12 +31,21 @@ import (
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/cluster"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/metadata"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/taggable"
 )
 
 func (r vsphereDeploymentZoneReconciler) reconcileFailureDomain(ctx *context.VSphereDeploymentZoneContext) error {
 	logger := ctrl.LoggerFrom(ctx).WithValues("failure domain", ctx.VSphereFailureDomain.Name)
 
+	// Failure domains are identified by tags, which standalone ESXi hosts
+	// do not provide.
+	if !ctx.AuthSession.Supports(session.TaggingCapability) {
+		err := errors.Errorf("failure domains are not supported by standalone ESXi hosts, as they require %s", session.TaggingCapability)
+		conditions.MarkFalse(ctx.VSphereDeploymentZone, infrav1.VSphereFailureDomainValidatedCondition, infrav1.UnsupportedByEndpointReason, clusterv1.ConditionSeverityError, err.Error())
+		return err
+	}
+
 	// verify the failure domain for the region
 	if err := r.reconcileInfraFailureDomain(ctx, ctx.VSphereFailureDomain.Spec.Region); err != nil {
 		conditions.MarkFalse(ctx.VSphereDeploymentZone, infrav1.VSphereFailureDomainValidatedCondition, infrav1.RegionMisconfiguredReason, clusterv1.ConditionSeverityError, err.Error())
@@ -77,6 +90,13 @@ func (r vsphereDeploymentZoneReconciler) reconcileTopology(ctx *context.VSphereD
 		}
 	}
 
+	if datastoreCluster := topology.DatastoreCluster; datastoreCluster != "" {
+		if _, err := ctx.AuthSession.Finder.DatastoreCluster(ctx, datastoreCluster); err != nil {
+			conditions.MarkFalse(ctx.VSphereDeploymentZone, infrav1.VSphereFailureDomainValidatedCondition, infrav1.DatastoreNotFoundReason, clusterv1.ConditionSeverityError, "datastore cluster %s is misconfigured", datastoreCluster)
+			return errors.Wrapf(err, "unable to find datastore cluster %s", datastoreCluster)
+		}
+	}
+
 	for _, network := range topology.Networks {
 		if _, err := ctx.AuthSession.Finder.Network(ctx, network); err != nil {
 			conditions.MarkFalse(ctx.VSphereDeploymentZone, infrav1.VSphereFailureDomainValidatedCondition, infrav1.NetworkNotFoundReason, clusterv1.ConditionSeverityError, "network %s is misconfigured", network)
@@ -84,7 +104,20 @@ func (r vsphereDeploymentZoneReconciler) reconcileTopology(ctx *context.VSphereD
 		}
 	}
 
+	if site := topology.VSANStretchedCluster; site != nil {
+		if err := verifyVSANStretchedClusterSite(ctx, site); err != nil {
+			conditions.MarkFalse(ctx.VSphereDeploymentZone, infrav1.VSphereFailureDomainValidatedCondition, infrav1.VSANStretchedClusterMisconfiguredReason, clusterv1.ConditionSeverityError, err.Error())
+			return err
+		}
+	}
+
 	if hostPlacementInfo := topology.Hosts; hostPlacementInfo != nil {
+		if pointer.BoolDeref(hostPlacementInfo.AutoConfigure, false) {
+			if err := cluster.EnsureAffinityRule(ctx, *topology.ComputeCluster, hostPlacementInfo.HostGroupName, hostPlacementInfo.VMGroupName, hostPlacementInfo.Mandatory); err != nil {
+				conditions.MarkFalse(ctx.VSphereDeploymentZone, infrav1.VSphereFailureDomainValidatedCondition, infrav1.HostsMisconfiguredReason, clusterv1.ConditionSeverityError, err.Error())
+				return err
+			}
+		}
 		rule, err := cluster.VerifyAffinityRule(ctx, *topology.ComputeCluster, hostPlacementInfo.HostGroupName, hostPlacementInfo.VMGroupName)
 		switch {
 		case err != nil:
@@ -131,6 +164,70 @@ func (r vsphereDeploymentZoneReconciler) reconcileComputeCluster(ctx *context.VS
 	return nil
 }
 
+// verifyVSANStretchedClusterSite verifies that the storage policy of the vSAN
+// stretched cluster site exists, and does not keep the data of the VMs on the
+// other site. Storage policies without a site disaster tolerance rule are not
+// rejected, as their placement cannot be verified.
+func verifyVSANStretchedClusterSite(ctx *context.VSphereDeploymentZoneContext, site *infrav1.VSANStretchedClusterSite) error {
+	pbmClient, err := ctx.AuthSession.NewPbmClient(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to create pbm client")
+	}
+	resourceType := pbmTypes.PbmProfileResourceType{
+		ResourceType: string(pbmTypes.PbmProfileResourceTypeEnumSTORAGE),
+	}
+	ids, err := pbmClient.QueryProfile(ctx, resourceType, string(pbmTypes.PbmProfileCategoryEnumREQUIREMENT))
+	if err != nil {
+		return errors.Wrap(err, "unable to query storage policies")
+	}
+	profiles, err := pbmClient.RetrieveContent(ctx, ids)
+	if err != nil {
+		return errors.Wrap(err, "unable to retrieve storage policies")
+	}
+
+	for _, profile := range profiles {
+		if profile.GetPbmProfile().Name != site.StoragePolicyName {
+			continue
+		}
+		locality := vsanLocality(profile)
+		for _, otherSite := range []infrav1.VSANSite{infrav1.PreferredVSANSite, infrav1.SecondaryVSANSite} {
+			if otherSite != site.Site && strings.HasPrefix(locality, string(otherSite)) {
+				return errors.Errorf("storage policy %s keeps data on the %s site rather than the %s site", site.StoragePolicyName, otherSite, site.Site)
+			}
+		}
+		return nil
+	}
+	return errors.Errorf("storage policy %s not found", site.StoragePolicyName)
+}
+
+// vsanLocality returns the value of the vSAN locality rule of the storage
+// policy, e.g. "Preferred Fault Domain", or an empty string if it has none.
+func vsanLocality(profile pbmTypes.BasePbmProfile) string {
+	capabilityProfile, ok := profile.(*pbmTypes.PbmCapabilityProfile)
+	if !ok {
+		return ""
+	}
+	constraints, ok := capabilityProfile.Constraints.(*pbmTypes.PbmCapabilitySubProfileConstraints)
+	if !ok {
+		return ""
+	}
+	for _, subProfile := range constraints.SubProfiles {
+		for _, capability := range subProfile.Capability {
+			if capability.Id.Namespace != "VSAN" || capability.Id.Id != "locality" {
+				continue
+			}
+			for _, constraint := range capability.Constraint {
+				for _, property := range constraint.PropertyInstance {
+					if value, ok := property.Value.(string); ok {
+						return value
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
 // verifyFailureDomain verifies the Failure Domain. It verifies the existence of tag and category specified and
 // checks whether the specified tags exist on the DataCenter or Compute Cluster or Hosts (in a HostGroup).
 func (r vsphereDeploymentZoneReconciler) verifyFailureDomain(ctx *context.VSphereDeploymentZoneContext, failureDomain infrav1.FailureDomain) error {
@@ -22,8 +22,12 @@ import (
 	"github.com/go-logr/logr"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
+	// run init func to register the storage policy API endpoints.
+	_ "github.com/vmware/govmomi/pbm/simulator"
+	pbmTypes "github.com/vmware/govmomi/pbm/types"
 	"github.com/vmware/govmomi/simulator"
 	"k8s.io/utils/pointer"
+	"sigs.k8s.io/cluster-api/util/conditions"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
@@ -120,6 +124,28 @@ func ForComputeClusterZone(t *testing.T) {
 	g.Expect(reconciler.verifyFailureDomain(deploymentZoneCtx, vsphereFailureDomain.Spec.Zone)).To(HaveOccurred())
 }
 
+func TestVSANLocality(t *testing.T) {
+	g := NewWithT(t)
+	profile := &pbmTypes.PbmCapabilityProfile{
+		Constraints: &pbmTypes.PbmCapabilitySubProfileConstraints{
+			SubProfiles: []pbmTypes.PbmCapabilitySubProfile{{
+				Capability: []pbmTypes.PbmCapabilityInstance{
+					{
+						Id:         pbmTypes.PbmCapabilityMetadataUniqueId{Namespace: "VSAN", Id: "hostFailuresToTolerate"},
+						Constraint: []pbmTypes.PbmCapabilityConstraintInstance{{PropertyInstance: []pbmTypes.PbmCapabilityPropertyInstance{{Id: "hostFailuresToTolerate", Value: int32(1)}}}},
+					},
+					{
+						Id:         pbmTypes.PbmCapabilityMetadataUniqueId{Namespace: "VSAN", Id: "locality"},
+						Constraint: []pbmTypes.PbmCapabilityConstraintInstance{{PropertyInstance: []pbmTypes.PbmCapabilityPropertyInstance{{Id: "locality", Value: "Secondary Fault Domain"}}}},
+					},
+				},
+			}},
+		},
+	}
+	g.Expect(vsanLocality(profile)).To(Equal("Secondary Fault Domain"))
+	g.Expect(vsanLocality(&pbmTypes.PbmCapabilityProfile{})).To(BeEmpty())
+}
+
 func ForHostGroupZone(t *testing.T) {
 	g := NewWithT(t)
 
@@ -200,6 +226,27 @@ func ForHostGroupZone(t *testing.T) {
 	// Since the tag does not belong to the category
 	vsphereFailureDomain.Spec.Zone.TagCategory = "diff-k8s-region"
 	g.Expect(reconciler.verifyFailureDomain(deploymentZoneCtx, vsphereFailureDomain.Spec.Zone)).To(HaveOccurred())
+
+	// Fails since the VM group and the affinity rule do not exist
+	deploymentZoneCtx.VSphereDeploymentZone = &infrav1.VSphereDeploymentZone{}
+	vsphereFailureDomain.Spec.Topology.Hosts.VMGroupName = "test_vm_grp_1"
+	g.Expect(reconciler.reconcileTopology(deploymentZoneCtx)).To(HaveOccurred())
+	g.Expect(conditions.GetReason(deploymentZoneCtx.VSphereDeploymentZone, infrav1.VSphereFailureDomainValidatedCondition)).To(Equal(infrav1.HostsMisconfiguredReason))
+
+	// Succeeds as they are created
+	vsphereFailureDomain.Spec.Topology.Hosts.AutoConfigure = pointer.Bool(true)
+	g.Expect(reconciler.reconcileTopology(deploymentZoneCtx)).To(Succeed())
+	g.Expect(conditions.IsTrue(deploymentZoneCtx.VSphereDeploymentZone, infrav1.VSphereFailureDomainValidatedCondition)).To(BeTrue())
+
+	// Fails since the storage policy of the vSAN stretched cluster site does not exist
+	vsphereFailureDomain.Spec.Topology.VSANStretchedCluster = &infrav1.VSANStretchedClusterSite{Site: infrav1.PreferredVSANSite, StoragePolicyName: "keep-data-on-preferred"}
+	g.Expect(reconciler.reconcileTopology(deploymentZoneCtx)).To(HaveOccurred())
+	g.Expect(conditions.GetReason(deploymentZoneCtx.VSphereDeploymentZone, infrav1.VSphereFailureDomainValidatedCondition)).To(Equal(infrav1.VSANStretchedClusterMisconfiguredReason))
+
+	// Succeeds with a storage policy without site disaster tolerance rule
+	vsphereFailureDomain.Spec.Topology.VSANStretchedCluster.StoragePolicyName = "vSAN Default Storage Policy"
+	g.Expect(reconciler.reconcileTopology(deploymentZoneCtx)).To(Succeed())
+	g.Expect(conditions.IsTrue(deploymentZoneCtx.VSphereDeploymentZone, infrav1.VSphereFailureDomainValidatedCondition)).To(BeTrue())
 }
 
 func TestVsphereDeploymentZoneReconciler_Reconcile_CreateAndAttachMetadata(t *testing.T) {
@@ -0,0 +1,516 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	goctx "context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	clusterutilv1 "sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/loadbalancer"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/nsxt"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
+	infrautilv1 "sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vsphereloadbalancervms,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vsphereloadbalancervms/status,verbs=get;update;patch
+
+// AddVSphereLoadBalancerVMControllerToManager adds the VSphereLoadBalancerVM
+// controller to the provided manager.
+func AddVSphereLoadBalancerVMControllerToManager(ctx *context.ControllerManagerContext, mgr manager.Manager) error {
+	var (
+		controlledType     = &infrav1.VSphereLoadBalancerVM{}
+		controlledTypeName = reflect.TypeOf(controlledType).Elem().Name()
+
+		controllerNameShort = fmt.Sprintf("%s-controller", strings.ToLower(controlledTypeName))
+		controllerNameLong  = fmt.Sprintf("%s/%s/%s", ctx.Namespace, ctx.Name, controllerNameShort)
+	)
+
+	// Build the controller context.
+	controllerContext := &context.ControllerContext{
+		ControllerManagerContext: ctx,
+		Name:                     controllerNameShort,
+		Recorder:                 record.New(mgr.GetEventRecorderFor(controllerNameLong)),
+		Logger:                   ctx.Logger.WithName(controllerNameShort),
+	}
+	reconciler := loadBalancerVMReconciler{ControllerContext: controllerContext}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		// Watch the controlled, infrastructure resource.
+		For(controlledType).
+		// Watch the VSphereVMs of the load balancers, to report their
+		// readiness and recreate them once deleted.
+		Owns(&infrav1.VSphereVM{}).
+		// Watch the control plane machines, to keep the members of the load
+		// balancers in sync with their addresses.
+		Watches(
+			&source.Kind{Type: &infrav1.VSphereMachine{}},
+			handler.EnqueueRequestsFromMapFunc(reconciler.controlPlaneMachineToLoadBalancerVMs),
+		).
+		WithOptions(controller.Options{MaxConcurrentReconciles: ctx.MaxConcurrentReconciles}).
+		Complete(tracing.Reconciler(controlledTypeName, reconciler))
+}
+
+type loadBalancerVMReconciler struct {
+	*context.ControllerContext
+}
+
+// Reconcile creates the pair of VSphereVMs of a VSphereLoadBalancerVM and
+// publishes the addresses of the control plane machines of its cluster to
+// them, so haproxy load balances the virtual IP to the control plane.
+func (r loadBalancerVMReconciler) Reconcile(ctx goctx.Context, req reconcile.Request) (_ reconcile.Result, reterr error) {
+	vsphereLoadBalancerVM := &infrav1.VSphereLoadBalancerVM{}
+	if err := r.Client.Get(ctx, req.NamespacedName, vsphereLoadBalancerVM); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Logger.V(4).Info("VSphereLoadBalancerVM not found, won't reconcile", "key", req.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	cluster, err := clusterutilv1.GetClusterFromMetadata(ctx, r.Client, vsphereLoadBalancerVM.ObjectMeta)
+	if err == nil && annotations.IsPaused(cluster, vsphereLoadBalancerVM) {
+		r.Logger.V(4).Info("VSphereLoadBalancerVM linked to a cluster that is paused",
+			"namespace", vsphereLoadBalancerVM.Namespace, "name", vsphereLoadBalancerVM.Name)
+		return reconcile.Result{}, nil
+	}
+
+	patchHelper, err := patch.NewHelper(vsphereLoadBalancerVM, r.Client)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(
+			err,
+			"failed to init patch helper for %s %s/%s",
+			vsphereLoadBalancerVM.GroupVersionKind(),
+			vsphereLoadBalancerVM.Namespace,
+			vsphereLoadBalancerVM.Name)
+	}
+	defer func() {
+		conditions.SetSummary(vsphereLoadBalancerVM, conditions.WithConditions(infrav1.LoadBalancerVMsReadyCondition))
+
+		if err := patchHelper.Patch(ctx, vsphereLoadBalancerVM); err != nil {
+			if reterr == nil {
+				reterr = err
+			}
+			r.Logger.Error(err, "patch failed", "namespace", vsphereLoadBalancerVM.Namespace, "name", vsphereLoadBalancerVM.Name)
+		}
+	}()
+
+	if !vsphereLoadBalancerVM.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, vsphereLoadBalancerVM)
+	}
+
+	cluster, err = clusterutilv1.GetClusterFromMetadata(ctx, r.Client, vsphereLoadBalancerVM.ObjectMeta)
+	if err != nil {
+		r.Logger.Info("VSphereLoadBalancerVM is missing cluster label or cluster does not exist", "key", req.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+	if cluster.Spec.InfrastructureRef == nil {
+		r.Logger.Info("Waiting for the infrastructure reference of the cluster to be set", "cluster", cluster.Name)
+		return reconcile.Result{}, nil
+	}
+
+	return r.reconcileNormal(ctx, cluster, vsphereLoadBalancerVM)
+}
+
+func (r loadBalancerVMReconciler) reconcileDelete(ctx goctx.Context, vsphereLoadBalancerVM *infrav1.VSphereLoadBalancerVM) (reconcile.Result, error) {
+	vmList := &infrav1.VSphereVMList{}
+	if err := r.Client.List(ctx, vmList,
+		client.InNamespace(vsphereLoadBalancerVM.Namespace),
+		client.MatchingLabels{infrav1.LoadBalancerVMNameLabel: vsphereLoadBalancerVM.Name}); err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "failed to list VSphereVMs of %s/%s", vsphereLoadBalancerVM.Namespace, vsphereLoadBalancerVM.Name)
+	}
+	for i := range vmList.Items {
+		if vmList.Items[i].DeletionTimestamp.IsZero() {
+			if err := r.Client.Delete(ctx, &vmList.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+				return reconcile.Result{}, errors.Wrapf(err, "failed to delete VSphereVM %s/%s", vmList.Items[i].Namespace, vmList.Items[i].Name)
+			}
+		}
+	}
+
+	// The finalizer is removed once the VSphereVMs are deleted, which
+	// triggers a reconcile. The bootstrap Secrets are garbage collected.
+	if len(vmList.Items) > 0 {
+		conditions.MarkFalse(vsphereLoadBalancerVM, infrav1.LoadBalancerVMsReadyCondition, clusterv1.DeletingReason, clusterv1.ConditionSeverityInfo,
+			"waiting for %d VSphereVMs to be deleted", len(vmList.Items))
+		return reconcile.Result{}, nil
+	}
+	ctrlutil.RemoveFinalizer(vsphereLoadBalancerVM, infrav1.LoadBalancerVMFinalizer)
+	return reconcile.Result{}, nil
+}
+
+func (r loadBalancerVMReconciler) reconcileNormal(ctx goctx.Context, cluster *clusterv1.Cluster, vsphereLoadBalancerVM *infrav1.VSphereLoadBalancerVM) (reconcile.Result, error) {
+	ctrlutil.AddFinalizer(vsphereLoadBalancerVM, infrav1.LoadBalancerVMFinalizer)
+
+	vsphereCluster := &infrav1.VSphereCluster{}
+	vsphereClusterKey := client.ObjectKey{Namespace: cluster.Namespace, Name: cluster.Spec.InfrastructureRef.Name}
+	if err := r.Client.Get(ctx, vsphereClusterKey, vsphereCluster); err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "failed to get VSphereCluster %s", vsphereClusterKey)
+	}
+
+	members, err := r.controlPlaneMembers(ctx, cluster)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if !reflect.DeepEqual(members, vsphereLoadBalancerVM.Status.Members) {
+		r.Recorder.Eventf(vsphereLoadBalancerVM, "MembersUpdated", "Load balancing to control plane machines %s", strings.Join(members, ", "))
+	}
+	vsphereLoadBalancerVM.Status.Members = members
+
+	password, err := r.reconcileVRRPSecret(ctx, vsphereLoadBalancerVM)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// Each VM of the pair is published the address of the other one, which
+	// are fetched first.
+	vms := make([]*infrav1.VSphereVM, loadbalancer.Replicas)
+	for i := range vms {
+		vm := &infrav1.VSphereVM{}
+		vmKey := client.ObjectKey{Namespace: vsphereLoadBalancerVM.Namespace, Name: loadBalancerVMName(vsphereLoadBalancerVM, i)}
+		if err := r.Client.Get(ctx, vmKey, vm); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return reconcile.Result{}, errors.Wrapf(err, "failed to get VSphereVM %s", vmKey)
+			}
+			continue
+		}
+		vms[i] = vm
+	}
+
+	var ready, deleting int
+	for i := range vms {
+		if err := r.reconcileBootstrapSecret(ctx, vsphereLoadBalancerVM, i, password); err != nil {
+			return reconcile.Result{}, err
+		}
+		guestInfo := loadBalancerGuestInfo(vsphereLoadBalancerVM, vms[(i+1)%loadbalancer.Replicas])
+		vm, err := r.reconcileVSphereVM(ctx, cluster, vsphereCluster, vsphereLoadBalancerVM, i, vms[i], guestInfo)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		switch {
+		case !vm.DeletionTimestamp.IsZero():
+			deleting++
+		case vm.Status.Ready:
+			ready++
+		}
+	}
+
+	switch {
+	case ready == 0:
+		conditions.MarkFalse(vsphereLoadBalancerVM, infrav1.LoadBalancerVMsReadyCondition, infrav1.WaitingForLoadBalancerVMsReason, clusterv1.ConditionSeverityInfo,
+			"0 of %d VMs ready", loadbalancer.Replicas)
+	case ready < loadbalancer.Replicas:
+		message := fmt.Sprintf("%d of %d VMs ready", ready, loadbalancer.Replicas)
+		if deleting > 0 {
+			message = fmt.Sprintf("%s, waiting for %d VSphereVMs to be deleted", message, deleting)
+		}
+		conditions.MarkFalse(vsphereLoadBalancerVM, infrav1.LoadBalancerVMsReadyCondition, infrav1.LoadBalancerDegradedReason, clusterv1.ConditionSeverityWarning, message)
+	default:
+		conditions.MarkTrue(vsphereLoadBalancerVM, infrav1.LoadBalancerVMsReadyCondition)
+	}
+	// The virtual IP is available as soon as one VM holds it.
+	vsphereLoadBalancerVM.Status.Ready = ready > 0
+	return reconcile.Result{}, nil
+}
+
+// reconcileVRRPSecret returns the password the VRRP advertisements of the pair
+// are authenticated with, from the Secret of the load balancer. The Secret is
+// created with a random password once, and garbage collected with the load
+// balancer. The password is generated again if the Secret has none.
+func (r loadBalancerVMReconciler) reconcileVRRPSecret(ctx goctx.Context, vsphereLoadBalancerVM *infrav1.VSphereLoadBalancerVM) (string, error) {
+	secret := &corev1.Secret{}
+	secretKey := client.ObjectKey{Namespace: vsphereLoadBalancerVM.Namespace, Name: loadBalancerVRRPSecretName(vsphereLoadBalancerVM)}
+	found := true
+	if err := r.Client.Get(ctx, secretKey, secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return "", errors.Wrapf(err, "failed to get VRRP Secret %s", secretKey)
+		}
+		found = false
+	}
+	if password := string(secret.Data["password"]); password != "" {
+		return password, nil
+	}
+
+	// keepalived uses at most 8 characters of the password.
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Wrap(err, "failed to generate VRRP password")
+	}
+	password := hex.EncodeToString(buf)
+
+	if found {
+		r.Logger.Info("VRRP Secret has no password, generating a new one", "secret", secretKey)
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data["password"] = []byte(password)
+		if err := r.Client.Update(ctx, secret); err != nil {
+			return "", errors.Wrapf(err, "failed to update VRRP Secret %s", secretKey)
+		}
+		return password, nil
+	}
+
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: secretKey.Namespace,
+			Name:      secretKey.Name,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(vsphereLoadBalancerVM, infrav1.GroupVersion.WithKind("VSphereLoadBalancerVM")),
+			},
+		},
+		Data: map[string][]byte{"password": []byte(password)},
+	}
+	if err := r.Client.Create(ctx, secret); err != nil {
+		return "", errors.Wrapf(err, "failed to create VRRP Secret %s", secretKey)
+	}
+	return password, nil
+}
+
+// reconcileBootstrapSecret creates or updates the Secret of the cloud-config
+// of the VM of the pair with the given index.
+func (r loadBalancerVMReconciler) reconcileBootstrapSecret(ctx goctx.Context, vsphereLoadBalancerVM *infrav1.VSphereLoadBalancerVM, index int, password string) error {
+	data, err := loadbalancer.CloudConfig(vsphereLoadBalancerVM, index, password)
+	if err != nil {
+		return err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: vsphereLoadBalancerVM.Namespace,
+			Name:      loadBalancerBootstrapSecretName(vsphereLoadBalancerVM, index),
+		},
+	}
+	if _, err := ctrlutil.CreateOrPatch(ctx, r.Client, secret, func() error {
+		secret.SetOwnerReferences([]metav1.OwnerReference{
+			*metav1.NewControllerRef(vsphereLoadBalancerVM, infrav1.GroupVersion.WithKind("VSphereLoadBalancerVM")),
+		})
+		secret.Data = map[string][]byte{"value": data}
+		return nil
+	}); err != nil {
+		return errors.Wrapf(err, "failed to reconcile bootstrap Secret %s/%s", secret.Namespace, secret.Name)
+	}
+	return nil
+}
+
+// reconcileVSphereVM creates the VSphereVM of the pair with the given index
+// when vm is nil, or publishes the guestinfo variables to it. The VSphereVM is
+// recreated once deleted.
+func (r loadBalancerVMReconciler) reconcileVSphereVM(ctx goctx.Context, cluster *clusterv1.Cluster, vsphereCluster *infrav1.VSphereCluster, vsphereLoadBalancerVM *infrav1.VSphereLoadBalancerVM, index int, vm *infrav1.VSphereVM, guestInfo map[string]string) (*infrav1.VSphereVM, error) {
+	if vm == nil {
+		vm = newLoadBalancerVSphereVM(cluster, vsphereCluster, vsphereLoadBalancerVM, index, guestInfo)
+		if err := r.Client.Create(ctx, vm); err != nil {
+			return nil, errors.Wrapf(err, "failed to create VSphereVM %s/%s", vm.Namespace, vm.Name)
+		}
+		r.Recorder.Eventf(vsphereLoadBalancerVM, "VMCreated", "Created VSphereVM %s", vm.Name)
+		return vm, nil
+	}
+	if !vm.DeletionTimestamp.IsZero() {
+		return vm, nil
+	}
+
+	vmPatch := client.MergeFrom(vm.DeepCopy())
+	changed := false
+	for key, value := range guestInfo {
+		if current, ok := vm.Spec.CustomVMXKeys[key]; ok && current == value {
+			continue
+		}
+		if vm.Spec.CustomVMXKeys == nil {
+			vm.Spec.CustomVMXKeys = map[string]string{}
+		}
+		vm.Spec.CustomVMXKeys[key] = value
+		changed = true
+	}
+	if changed {
+		if err := r.Client.Patch(ctx, vm, vmPatch); err != nil {
+			return nil, errors.Wrapf(err, "failed to update the guestinfo of VSphereVM %s/%s", vm.Namespace, vm.Name)
+		}
+	}
+
+	// The guestinfo variables are reconfigured on the running VM right away,
+	// the Revert drift policy of the VSphereVM only reverting them once the
+	// VSphereVM is reconciled again.
+	if vm.Spec.BiosUUID == "" {
+		return vm, nil
+	}
+	authSession, err := (&vmReconciler{ControllerContext: r.ControllerContext}).retrieveVcenterSession(ctx, vm)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get session for VSphereVM %s/%s", vm.Namespace, vm.Name)
+	}
+	if err := loadbalancer.PublishGuestInfo(ctx, authSession, vm, guestInfo); err != nil {
+		return nil, err
+	}
+	return vm, nil
+}
+
+// controlPlaneMembers returns the sorted preferred IP addresses of the
+// control plane machines of the cluster which are not being deleted.
+func (r loadBalancerVMReconciler) controlPlaneMembers(ctx goctx.Context, cluster *clusterv1.Cluster) ([]string, error) {
+	vsphereMachines, err := infrautilv1.GetVSphereMachinesInCluster(ctx, r.Client, cluster.Namespace, cluster.Name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list VSphereMachines of cluster %s/%s", cluster.Namespace, cluster.Name)
+	}
+	var members []string
+	for _, vsphereMachine := range vsphereMachines {
+		if !infrautilv1.IsControlPlaneMachine(vsphereMachine) || !vsphereMachine.DeletionTimestamp.IsZero() {
+			continue
+		}
+		ip, err := infrautilv1.GetMachinePreferredIPAddress(vsphereMachine)
+		if err != nil {
+			continue
+		}
+		members = append(members, ip)
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// controlPlaneMachineToLoadBalancerVMs maps a control plane machine to the
+// VSphereLoadBalancerVMs of its cluster.
+func (r loadBalancerVMReconciler) controlPlaneMachineToLoadBalancerVMs(o client.Object) []reconcile.Request {
+	vsphereMachine, ok := o.(*infrav1.VSphereMachine)
+	if !ok {
+		r.Logger.Error(nil, fmt.Sprintf("expected a VSphereMachine but got a %T", o))
+		return nil
+	}
+	clusterName, ok := vsphereMachine.Labels[clusterv1.ClusterLabelName]
+	if !ok || !infrautilv1.IsControlPlaneMachine(vsphereMachine) {
+		return nil
+	}
+
+	loadBalancerList := &infrav1.VSphereLoadBalancerVMList{}
+	if err := r.Client.List(r, loadBalancerList,
+		client.InNamespace(vsphereMachine.Namespace),
+		client.MatchingLabels{clusterv1.ClusterLabelName: clusterName}); err != nil {
+		r.Logger.Error(err, "failed to list VSphereLoadBalancerVMs", "namespace", vsphereMachine.Namespace, "cluster", clusterName)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(loadBalancerList.Items))
+	for _, loadBalancer := range loadBalancerList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKey{Namespace: loadBalancer.Namespace, Name: loadBalancer.Name},
+		})
+	}
+	return requests
+}
+
+// loadBalancerVMName returns the name of the VSphereVM of the pair with the
+// given index.
+func loadBalancerVMName(vsphereLoadBalancerVM *infrav1.VSphereLoadBalancerVM, index int) string {
+	return fmt.Sprintf("%s-%d", vsphereLoadBalancerVM.Name, index)
+}
+
+// loadBalancerVRRPSecretName returns the name of the Secret of the password
+// the VRRP advertisements of the pair are authenticated with.
+func loadBalancerVRRPSecretName(vsphereLoadBalancerVM *infrav1.VSphereLoadBalancerVM) string {
+	return fmt.Sprintf("%s-vrrp", vsphereLoadBalancerVM.Name)
+}
+
+// loadBalancerGuestInfo returns the guestinfo variables published to a VM of
+// the pair: the members of the load balancer, and the address of its peer,
+// which is empty while the peer has no address or is being deleted.
+func loadBalancerGuestInfo(vsphereLoadBalancerVM *infrav1.VSphereLoadBalancerVM, peer *infrav1.VSphereVM) map[string]string {
+	var peerAddress string
+	if peer != nil && peer.DeletionTimestamp.IsZero() {
+		peerAddress = loadbalancer.PeerAddress(vsphereLoadBalancerVM.Spec.VirtualIP, peer.Status.Addresses)
+	}
+	return map[string]string{
+		loadbalancer.MembersKey: loadbalancer.Members(vsphereLoadBalancerVM.Status.Members),
+		loadbalancer.PeerKey:    peerAddress,
+	}
+}
+
+// loadBalancerBootstrapSecretName returns the name of the Secret of the
+// cloud-config of the VM of the pair with the given index.
+func loadBalancerBootstrapSecretName(vsphereLoadBalancerVM *infrav1.VSphereLoadBalancerVM, index int) string {
+	return fmt.Sprintf("%s-bootstrap", loadBalancerVMName(vsphereLoadBalancerVM, index))
+}
+
+// newLoadBalancerVSphereVM returns the VSphereVM of the pair with the given
+// index, which publishes the guestinfo variables to its VM.
+func newLoadBalancerVSphereVM(cluster *clusterv1.Cluster, vsphereCluster *infrav1.VSphereCluster, vsphereLoadBalancerVM *infrav1.VSphereLoadBalancerVM, index int, guestInfo map[string]string) *infrav1.VSphereVM {
+	vm := &infrav1.VSphereVM{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: vsphereLoadBalancerVM.Namespace,
+			Name:      loadBalancerVMName(vsphereLoadBalancerVM, index),
+			Labels: map[string]string{
+				clusterv1.ClusterLabelName:      cluster.Name,
+				infrav1.LoadBalancerVMNameLabel: vsphereLoadBalancerVM.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(vsphereLoadBalancerVM, infrav1.GroupVersion.WithKind("VSphereLoadBalancerVM")),
+			},
+		},
+		Spec: infrav1.VSphereVMSpec{
+			BootstrapRef: &corev1.ObjectReference{
+				APIVersion: "v1",
+				Kind:       "Secret",
+				Name:       loadBalancerBootstrapSecretName(vsphereLoadBalancerVM, index),
+				Namespace:  vsphereLoadBalancerVM.Namespace,
+			},
+			DriftPolicy: infrav1.VirtualMachineDriftPolicyRevert,
+		},
+	}
+
+	vsphereLoadBalancerVM.Spec.VirtualMachineCloneSpec.DeepCopyInto(&vm.Spec.VirtualMachineCloneSpec)
+	if vm.Spec.CustomVMXKeys == nil {
+		vm.Spec.CustomVMXKeys = map[string]string{}
+	}
+	for key, value := range guestInfo {
+		vm.Spec.CustomVMXKeys[key] = value
+	}
+	if vm.Spec.Server == "" {
+		vm.Spec.Server = vsphereCluster.Spec.Server
+	}
+	if vm.Spec.Thumbprint == "" {
+		vm.Spec.Thumbprint = vsphereCluster.Spec.Thumbprint
+	}
+	// The network devices without a network are attached to the NSX-T
+	// segment of the cluster.
+	if vsphereCluster.Spec.NSXT != nil {
+		segmentName := nsxt.SegmentName(cluster.Namespace, cluster.Name)
+		for i := range vm.Spec.Network.Devices {
+			if vm.Spec.Network.Devices[i].NetworkName == "" {
+				vm.Spec.Network.Devices[i].NetworkName = segmentName
+			}
+		}
+	}
+	return vm
+}
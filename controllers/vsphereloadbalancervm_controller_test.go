@@ -0,0 +1,154 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/loadbalancer"
+)
+
+func TestNewLoadBalancerVSphereVM(t *testing.T) {
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cluster"},
+	}
+	vsphereCluster := &infrav1.VSphereCluster{
+		Spec: infrav1.VSphereClusterSpec{Server: "vcenter.example.com", Thumbprint: "AA:BB"},
+	}
+	vsphereLoadBalancerVM := &infrav1.VSphereLoadBalancerVM{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "lb"},
+		Spec: infrav1.VSphereLoadBalancerVMSpec{
+			VirtualIP: "192.168.10.5",
+			VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+				Template:      "ubuntu-template",
+				CustomVMXKeys: map[string]string{"foo": "bar"},
+			},
+		},
+	}
+
+	t.Run("VSphereVM is cloned from the spec of the load balancer", func(t *testing.T) {
+		g := NewWithT(t)
+		vm := newLoadBalancerVSphereVM(cluster, vsphereCluster, vsphereLoadBalancerVM, 1, map[string]string{
+			loadbalancer.MembersKey: "10.0.0.1,10.0.0.2",
+			loadbalancer.PeerKey:    "192.168.10.11",
+		})
+		g.Expect(vm.Namespace).To(Equal("ns"))
+		g.Expect(vm.Name).To(Equal("lb-1"))
+		g.Expect(vm.Labels).To(HaveKeyWithValue(clusterv1.ClusterLabelName, "cluster"))
+		g.Expect(vm.Labels).To(HaveKeyWithValue(infrav1.LoadBalancerVMNameLabel, "lb"))
+		g.Expect(vm.OwnerReferences).To(HaveLen(1))
+		g.Expect(vm.OwnerReferences[0].Kind).To(Equal("VSphereLoadBalancerVM"))
+		g.Expect(vm.Spec.BootstrapRef.Name).To(Equal("lb-1-bootstrap"))
+		g.Expect(vm.Spec.DriftPolicy).To(Equal(infrav1.VirtualMachineDriftPolicyRevert))
+		g.Expect(vm.Spec.Template).To(Equal("ubuntu-template"))
+		g.Expect(vm.Spec.Server).To(Equal("vcenter.example.com"))
+		g.Expect(vm.Spec.Thumbprint).To(Equal("AA:BB"))
+		g.Expect(vm.Spec.CustomVMXKeys).To(Equal(map[string]string{
+			"foo":                   "bar",
+			loadbalancer.MembersKey: "10.0.0.1,10.0.0.2",
+			loadbalancer.PeerKey:    "192.168.10.11",
+		}))
+		g.Expect(vsphereLoadBalancerVM.Spec.CustomVMXKeys).To(Equal(map[string]string{"foo": "bar"}))
+	})
+
+	t.Run("network devices without a network are attached to the NSX-T segment", func(t *testing.T) {
+		g := NewWithT(t)
+		nsxtCluster := vsphereCluster.DeepCopy()
+		nsxtCluster.Spec.NSXT = &infrav1.NSXTSpec{}
+		lb := vsphereLoadBalancerVM.DeepCopy()
+		lb.Spec.Network.Devices = []infrav1.NetworkDeviceSpec{{}, {NetworkName: "vm-network"}}
+
+		vm := newLoadBalancerVSphereVM(cluster, nsxtCluster, lb, 0, nil)
+		g.Expect(vm.Spec.Network.Devices[0].NetworkName).NotTo(BeEmpty())
+		g.Expect(vm.Spec.Network.Devices[1].NetworkName).To(Equal("vm-network"))
+	})
+}
+
+func TestLoadBalancerGuestInfo(t *testing.T) {
+	g := NewWithT(t)
+	vsphereLoadBalancerVM := &infrav1.VSphereLoadBalancerVM{
+		Spec:   infrav1.VSphereLoadBalancerVMSpec{VirtualIP: "192.168.10.5"},
+		Status: infrav1.VSphereLoadBalancerVMStatus{Members: []string{"10.0.0.2", "10.0.0.1"}},
+	}
+	peer := &infrav1.VSphereVM{
+		Status: infrav1.VSphereVMStatus{Addresses: []string{"fe80::1", "192.168.10.5", "192.168.10.11"}},
+	}
+
+	g.Expect(loadBalancerGuestInfo(vsphereLoadBalancerVM, peer)).To(Equal(map[string]string{
+		loadbalancer.MembersKey: "10.0.0.1,10.0.0.2",
+		loadbalancer.PeerKey:    "192.168.10.11",
+	}))
+	g.Expect(loadBalancerGuestInfo(vsphereLoadBalancerVM, nil)).To(HaveKeyWithValue(loadbalancer.PeerKey, ""))
+
+	now := metav1.Now()
+	peer.DeletionTimestamp = &now
+	g.Expect(loadBalancerGuestInfo(vsphereLoadBalancerVM, peer)).To(HaveKeyWithValue(loadbalancer.PeerKey, ""))
+}
+
+func TestReconcileVRRPSecret(t *testing.T) {
+	vsphereLoadBalancerVM := &infrav1.VSphereLoadBalancerVM{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "lb", UID: "lb-uid"},
+	}
+	secretKey := client.ObjectKey{Namespace: "ns", Name: "lb-vrrp"}
+
+	t.Run("Secret is created with a password once", func(t *testing.T) {
+		g := NewWithT(t)
+		controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext())
+		r := loadBalancerVMReconciler{ControllerContext: controllerCtx}
+
+		password, err := r.reconcileVRRPSecret(controllerCtx, vsphereLoadBalancerVM)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(password).To(HaveLen(8))
+
+		secret := &corev1.Secret{}
+		g.Expect(controllerCtx.Client.Get(controllerCtx, secretKey, secret)).To(Succeed())
+		g.Expect(secret.Data).To(HaveKeyWithValue("password", []byte(password)))
+		g.Expect(secret.OwnerReferences).To(HaveLen(1))
+		g.Expect(r.reconcileVRRPSecret(controllerCtx, vsphereLoadBalancerVM)).To(Equal(password))
+	})
+
+	for name, data := range map[string]map[string][]byte{
+		"without password":    nil,
+		"with empty password": {"password": {}},
+	} {
+		data := data
+		t.Run("password is generated again for a Secret "+name, func(t *testing.T) {
+			g := NewWithT(t)
+			controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: secretKey.Namespace, Name: secretKey.Name},
+				Data:       data,
+			}))
+			r := loadBalancerVMReconciler{ControllerContext: controllerCtx}
+
+			password, err := r.reconcileVRRPSecret(controllerCtx, vsphereLoadBalancerVM)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(password).To(HaveLen(8))
+
+			secret := &corev1.Secret{}
+			g.Expect(controllerCtx.Client.Get(controllerCtx, secretKey, secret)).To(Succeed())
+			g.Expect(secret.Data).To(HaveKeyWithValue("password", []byte(password)))
+		})
+	}
+}
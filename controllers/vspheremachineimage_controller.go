@@ -0,0 +1,158 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	goctx "context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/audit"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
+	infrautilv1 "sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vspheremachineimages,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vspheremachineimages/status,verbs=get;update;patch
+
+// AddVSphereMachineImageControllerToManager adds the VSphereMachineImage
+// controller to the provided manager.
+func AddVSphereMachineImageControllerToManager(ctx *context.ControllerManagerContext, mgr manager.Manager) error {
+	var (
+		controlledType     = &infrav1.VSphereMachineImage{}
+		controlledTypeName = reflect.TypeOf(controlledType).Elem().Name()
+
+		controllerNameShort = fmt.Sprintf("%s-controller", strings.ToLower(controlledTypeName))
+		controllerNameLong  = fmt.Sprintf("%s/%s/%s", ctx.Namespace, ctx.Name, controllerNameShort)
+	)
+
+	// Build the controller context.
+	controllerContext := &context.ControllerContext{
+		ControllerManagerContext: ctx,
+		Name:                     controllerNameShort,
+		Recorder:                 record.New(mgr.GetEventRecorderFor(controllerNameLong)),
+		Logger:                   ctx.Logger.WithName(controllerNameShort),
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		// Watch the controlled, infrastructure resource.
+		For(controlledType).
+		WithOptions(controller.Options{MaxConcurrentReconciles: ctx.MaxConcurrentReconciles}).
+		Complete(tracing.Reconciler(controlledTypeName, imageReconciler{
+			ControllerContext: controllerContext,
+			imageService:      &govmomi.ImageService{},
+		}))
+}
+
+type imageReconciler struct {
+	*context.ControllerContext
+
+	imageService services.MachineImageService
+}
+
+// Reconcile imports the OVA of a VSphereMachineImage as a template. The
+// template is not removed when the VSphereMachineImage is deleted, as the
+// VMs cloned from it may still be linked clones of it.
+func (r imageReconciler) Reconcile(ctx goctx.Context, req reconcile.Request) (_ reconcile.Result, reterr error) {
+	image := &infrav1.VSphereMachineImage{}
+	if err := r.Client.Get(ctx, req.NamespacedName, image); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Logger.V(4).Info("VSphereMachineImage not found, won't reconcile", "key", req.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if image.Status.Ready || !image.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+
+	patchHelper, err := patch.NewHelper(image, r.Client)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(
+			err,
+			"failed to init patch helper for %s %s/%s",
+			image.GroupVersionKind(),
+			image.Namespace,
+			image.Name)
+	}
+	defer func() {
+		conditions.SetSummary(image, conditions.WithConditions(infrav1.ImageImportedCondition))
+
+		if err := patchHelper.Patch(ctx, image); err != nil {
+			if reterr == nil {
+				reterr = err
+			}
+			r.Logger.Error(err, "patch failed", "namespace", image.Namespace, "name", image.Name)
+		}
+	}()
+
+	providerConfig, err := infrautilv1.GetProviderConfig(ctx, r.Client)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	authSession, err := session.GetOrCreate(tracing.ContextWithSpanOf(r.Context, ctx), session.NewParams().
+		WithServer(image.Spec.Server).
+		WithDatacenter(image.Spec.Datacenter).
+		WithUserInfo(r.ControllerContext.Username, r.ControllerContext.Password).
+		WithThumbprint(image.Spec.Thumbprint).
+		WithFeatures(session.Feature{
+			KeepAliveDuration: r.KeepAliveDuration,
+			KeepAliveRetries:  r.KeepAliveRetries,
+			QPS:               float32(r.VCenterQPS),
+			Burst:             r.VCenterBurst,
+			TLS:               r.VCenterTLSPolicy,
+		}.WithProviderConfig(providerConfig)))
+	if err != nil {
+		conditions.MarkFalse(image, infrav1.ImageImportedCondition, infrav1.ImageImportFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		return reconcile.Result{}, errors.Wrapf(err, "failed to get session for VSphereMachineImage %s/%s", image.Namespace, image.Name)
+	}
+
+	logger := r.Logger.WithValues("namespace", image.Namespace, "name", image.Name)
+	templatePath, err := r.imageService.ImportOVA(audit.WithInitiator(ctrl.LoggerInto(ctx, logger), image), authSession, image)
+	if err != nil {
+		if errors.Is(err, govmomi.ErrImageChecksumMismatch) {
+			conditions.MarkFalse(image, infrav1.ImageImportedCondition, infrav1.ImageChecksumMismatchReason, clusterv1.ConditionSeverityError, err.Error())
+		} else {
+			conditions.MarkFalse(image, infrav1.ImageImportedCondition, infrav1.ImageImportFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		}
+		return reconcile.Result{}, err
+	}
+	r.Recorder.Eventf(image, "ImageImported", "Imported OVA %s as template %s", image.Spec.URL, templatePath)
+
+	image.Status.Ready = true
+	image.Status.TemplatePath = templatePath
+	conditions.MarkTrue(image, infrav1.ImageImportedCondition)
+	return reconcile.Result{}, nil
+}
@@ -0,0 +1,498 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	goctx "context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
+	exputil "sigs.k8s.io/cluster-api/exp/util"
+	clusterutilv1 "sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/nsxt"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
+	infrautilv1 "sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vspheremachinepools,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vspheremachinepools/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machinepools;machinepools/status,verbs=get;list;watch
+
+// quotaRequeueInterval is the interval at which a VSphereMachinePool which
+// cannot scale up within the resource quota of its cluster checks it again.
+const quotaRequeueInterval = 10 * time.Second
+
+// templateRequeueInterval is the interval at which a VSphereMachinePool
+// waiting for a default template, or an image mapping, for its Kubernetes
+// version checks the VSphereProviderConfig, or the
+// VSphereMachineImageMapping, again.
+const templateRequeueInterval = 10 * time.Second
+
+// AddVSphereMachinePoolControllerToManager adds the VSphereMachinePool
+// controller to the provided manager.
+func AddVSphereMachinePoolControllerToManager(ctx *context.ControllerManagerContext, mgr manager.Manager) error {
+	var (
+		controlledType     = &infrav1.VSphereMachinePool{}
+		controlledTypeName = reflect.TypeOf(controlledType).Elem().Name()
+		controlledTypeGVK  = infrav1.GroupVersion.WithKind(controlledTypeName)
+
+		controllerNameShort = fmt.Sprintf("%s-controller", strings.ToLower(controlledTypeName))
+		controllerNameLong  = fmt.Sprintf("%s/%s/%s", ctx.Namespace, ctx.Name, controllerNameShort)
+	)
+
+	// Build the controller context.
+	controllerContext := &context.ControllerContext{
+		ControllerManagerContext: ctx,
+		Name:                     controllerNameShort,
+		Recorder:                 record.New(mgr.GetEventRecorderFor(controllerNameLong)),
+		Logger:                   ctx.Logger.WithName(controllerNameShort),
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		// Watch the controlled, infrastructure resource.
+		For(controlledType).
+		// Watch the VSphereVMs of the pools, to report their readiness.
+		Owns(&infrav1.VSphereVM{}).
+		// Watch the CAPI MachinePools, to scale the pools to their replicas.
+		Watches(
+			&source.Kind{Type: &expv1.MachinePool{}},
+			handler.EnqueueRequestsFromMapFunc(exputil.MachinePoolToInfrastructureMapFunc(controlledTypeGVK, controllerContext.Logger)),
+		).
+		WithOptions(controller.Options{MaxConcurrentReconciles: ctx.MaxConcurrentReconciles}).
+		Complete(tracing.Reconciler(controlledTypeName, machinePoolReconciler{ControllerContext: controllerContext}))
+}
+
+type machinePoolReconciler struct {
+	*context.ControllerContext
+}
+
+// Reconcile creates and deletes the VSphereVMs of a VSphereMachinePool to
+// match the replicas of its MachinePool, and reports the provider IDs of the
+// VMs which are ready to the MachinePool.
+func (r machinePoolReconciler) Reconcile(ctx goctx.Context, req reconcile.Request) (_ reconcile.Result, reterr error) {
+	vsphereMachinePool := &infrav1.VSphereMachinePool{}
+	if err := r.Client.Get(ctx, req.NamespacedName, vsphereMachinePool); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Logger.V(4).Info("VSphereMachinePool not found, won't reconcile", "key", req.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	cluster, err := clusterutilv1.GetClusterFromMetadata(ctx, r.Client, vsphereMachinePool.ObjectMeta)
+	if err == nil && annotations.IsPaused(cluster, vsphereMachinePool) {
+		r.Logger.V(4).Info("VSphereMachinePool linked to a cluster that is paused",
+			"namespace", vsphereMachinePool.Namespace, "name", vsphereMachinePool.Name)
+		return reconcile.Result{}, nil
+	}
+
+	patchHelper, err := patch.NewHelper(vsphereMachinePool, r.Client)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(
+			err,
+			"failed to init patch helper for %s %s/%s",
+			vsphereMachinePool.GroupVersionKind(),
+			vsphereMachinePool.Namespace,
+			vsphereMachinePool.Name)
+	}
+	defer func() {
+		conditions.SetSummary(vsphereMachinePool, conditions.WithConditions(infrav1.ReplicasReadyCondition))
+
+		if err := patchHelper.Patch(ctx, vsphereMachinePool); err != nil {
+			if reterr == nil {
+				reterr = err
+			}
+			r.Logger.Error(err, "patch failed", "namespace", vsphereMachinePool.Namespace, "name", vsphereMachinePool.Name)
+		}
+	}()
+
+	if !vsphereMachinePool.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, vsphereMachinePool)
+	}
+
+	machinePool, err := exputil.GetOwnerMachinePool(ctx, r.Client, vsphereMachinePool.ObjectMeta)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if machinePool == nil {
+		r.Logger.Info("Waiting for MachinePool controller to set OwnerRef on VSphereMachinePool", "key", req.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+	cluster, err = clusterutilv1.GetClusterFromMetadata(ctx, r.Client, machinePool.ObjectMeta)
+	if err != nil {
+		r.Logger.Info("MachinePool is missing cluster label or cluster does not exist", "key", req.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+
+	return r.reconcileNormal(ctx, cluster, machinePool, vsphereMachinePool)
+}
+
+func (r machinePoolReconciler) reconcileDelete(ctx goctx.Context, vsphereMachinePool *infrav1.VSphereMachinePool) (reconcile.Result, error) {
+	vms, err := r.listVMs(ctx, vsphereMachinePool)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	for i := range vms {
+		if vms[i].DeletionTimestamp.IsZero() {
+			if err := r.Client.Delete(ctx, &vms[i]); err != nil && !apierrors.IsNotFound(err) {
+				return reconcile.Result{}, errors.Wrapf(err, "failed to delete VSphereVM %s/%s", vms[i].Namespace, vms[i].Name)
+			}
+		}
+	}
+
+	// The finalizer is removed once the VSphereVMs are deleted, which
+	// triggers a reconcile.
+	if len(vms) > 0 {
+		conditions.MarkFalse(vsphereMachinePool, infrav1.ReplicasReadyCondition, clusterv1.DeletingReason, clusterv1.ConditionSeverityInfo,
+			"waiting for %d VSphereVMs to be deleted", len(vms))
+		return reconcile.Result{}, nil
+	}
+	ctrlutil.RemoveFinalizer(vsphereMachinePool, infrav1.MachinePoolFinalizer)
+	return reconcile.Result{}, nil
+}
+
+func (r machinePoolReconciler) reconcileNormal(ctx goctx.Context, cluster *clusterv1.Cluster, machinePool *expv1.MachinePool, vsphereMachinePool *infrav1.VSphereMachinePool) (reconcile.Result, error) {
+	ctrlutil.AddFinalizer(vsphereMachinePool, infrav1.MachinePoolFinalizer)
+
+	if !cluster.Status.InfrastructureReady {
+		r.Logger.Info("Cluster infrastructure is not ready yet", "cluster", cluster.Name)
+		conditions.MarkFalse(vsphereMachinePool, infrav1.ReplicasReadyCondition, infrav1.WaitingForClusterInfrastructureReason, clusterv1.ConditionSeverityInfo, "")
+		return reconcile.Result{}, nil
+	}
+	if machinePool.Spec.Template.Spec.Bootstrap.DataSecretName == nil {
+		r.Logger.Info("Waiting for bootstrap data to be available", "machinepool", machinePool.Name)
+		conditions.MarkFalse(vsphereMachinePool, infrav1.ReplicasReadyCondition, infrav1.WaitingForBootstrapDataReason, clusterv1.ConditionSeverityInfo, "")
+		return reconcile.Result{}, nil
+	}
+
+	vsphereCluster := &infrav1.VSphereCluster{}
+	vsphereClusterKey := client.ObjectKey{Namespace: cluster.Namespace, Name: cluster.Spec.InfrastructureRef.Name}
+	if err := r.Client.Get(ctx, vsphereClusterKey, vsphereCluster); err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "failed to get VSphereCluster %s", vsphereClusterKey)
+	}
+
+	vms, err := r.listVMs(ctx, vsphereMachinePool)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	var active []infrav1.VSphereVM
+	deleting := 0
+	for _, vm := range vms {
+		if vm.DeletionTimestamp.IsZero() {
+			active = append(active, vm)
+		} else {
+			deleting++
+		}
+	}
+
+	replicas := int32(1)
+	if machinePool.Spec.Replicas != nil {
+		replicas = *machinePool.Spec.Replicas
+	}
+
+	// The VMs of a pool are interchangeable, so they are created or deleted
+	// all at once.
+	var reason, message string
+	severity := clusterv1.ConditionSeverityInfo
+	result := reconcile.Result{}
+	switch n := int(replicas) - len(active); {
+	case n > 0:
+		providerConfig, err := infrautilv1.GetProviderConfig(ctx, r.Client)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		template, image, err := r.template(ctx, machinePool, vsphereMachinePool, providerConfig)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if template == "" && image != "" {
+			reason = infrav1.WaitingForImageReason
+			message = fmt.Sprintf("waiting for VSphereMachineImage %s to be imported", image)
+			break
+		}
+		if imageMapping := vsphereMachinePool.Spec.Template.Spec.ImageMapping; template == "" && imageMapping != "" {
+			reason = infrav1.WaitingForImageMappingReason
+			message = fmt.Sprintf("waiting for VSphereMachineImageMapping %s to map Kubernetes version %s", imageMapping, pointer.StringDeref(machinePool.Spec.Template.Spec.Version, ""))
+			severity = clusterv1.ConditionSeverityWarning
+			result.RequeueAfter = templateRequeueInterval
+			break
+		}
+		if template == "" {
+			reason = infrav1.WaitingForDefaultTemplateReason
+			message = fmt.Sprintf("waiting for VSphereProviderConfig %s to set a default template for Kubernetes version %s", infrav1.ProviderConfigName, pointer.StringDeref(machinePool.Spec.Template.Spec.Version, ""))
+			severity = clusterv1.ConditionSeverityWarning
+			result.RequeueAfter = templateRequeueInterval
+			break
+		}
+		// The VMs are only created within the resource quota of the
+		// cluster, which is checked again periodically.
+		quotaMessage, err := infrautilv1.CheckResourceQuota(ctx, r.Client, vsphereCluster, cluster.Name, &vsphereMachinePool.Spec.Template.Spec.VirtualMachineCloneSpec, n)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if quotaMessage != "" {
+			reason = infrav1.QuotaExceededReason
+			message = quotaMessage
+			severity = clusterv1.ConditionSeverityWarning
+			result.RequeueAfter = quotaRequeueInterval
+			break
+		}
+		// The VMs are named after the lowest free indexes of the pool, so
+		// that the VMs created by a previous reconcile but not yet in the
+		// cache already exist instead of being created twice.
+		for _, name := range poolVMNames(vsphereMachinePool, vms, n) {
+			vm := newPoolVSphereVM(cluster, vsphereCluster, machinePool, vsphereMachinePool, providerConfig, template, name)
+			if err := r.Client.Create(ctx, vm); err != nil {
+				if apierrors.IsAlreadyExists(err) {
+					continue
+				}
+				return reconcile.Result{}, errors.Wrapf(err, "failed to create VSphereVM for %s/%s", vsphereMachinePool.Namespace, vsphereMachinePool.Name)
+			}
+			active = append(active, *vm)
+		}
+		r.Recorder.Eventf(vsphereMachinePool, "ScaledUp", "Created %d VSphereVMs", n)
+		reason = infrav1.ScalingUpReason
+		message = fmt.Sprintf("scaling up to %d replicas", replicas)
+	case n < 0:
+		surplus := vmsToDelete(active, -n)
+		for i := range surplus {
+			if err := r.Client.Delete(ctx, &surplus[i]); err != nil && !apierrors.IsNotFound(err) {
+				return reconcile.Result{}, errors.Wrapf(err, "failed to delete VSphereVM %s/%s", surplus[i].Namespace, surplus[i].Name)
+			}
+		}
+		active = vmsToKeep(active, surplus)
+		r.Recorder.Eventf(vsphereMachinePool, "ScaledDown", "Deleted %d VSphereVMs", -n)
+		reason = infrav1.ScalingDownReason
+		message = fmt.Sprintf("scaling down to %d replicas", replicas)
+	case deleting > 0:
+		reason = infrav1.ScalingDownReason
+		message = fmt.Sprintf("waiting for %d VSphereVMs to be deleted", deleting)
+	}
+
+	// The provider IDs of the VMs are reported once they are ready, so
+	// Cluster API only waits for the nodes of provisioned VMs.
+	var ready int32
+	providerIDs := []string{}
+	for _, vm := range active {
+		if !vm.Status.Ready || vm.Spec.BiosUUID == "" {
+			continue
+		}
+		ready++
+		providerIDs = append(providerIDs, infrautilv1.ConvertUUIDToProviderID(vm.Spec.BiosUUID))
+	}
+	sort.Strings(providerIDs)
+	vsphereMachinePool.Spec.ProviderIDList = providerIDs
+	vsphereMachinePool.Status.Replicas = ready
+
+	vsphereMachinePool.Status.Ready = false
+	switch {
+	case reason != "":
+		conditions.MarkFalse(vsphereMachinePool, infrav1.ReplicasReadyCondition, reason, severity, message)
+	case ready < replicas:
+		conditions.MarkFalse(vsphereMachinePool, infrav1.ReplicasReadyCondition, infrav1.WaitingForReplicasReadyReason, clusterv1.ConditionSeverityInfo,
+			"%d of %d replicas ready", ready, replicas)
+	default:
+		conditions.MarkTrue(vsphereMachinePool, infrav1.ReplicasReadyCondition)
+		vsphereMachinePool.Status.Ready = true
+	}
+	return result, nil
+}
+
+// listVMs returns the VSphereVMs of the pool.
+func (r machinePoolReconciler) listVMs(ctx goctx.Context, vsphereMachinePool *infrav1.VSphereMachinePool) ([]infrav1.VSphereVM, error) {
+	vmList := &infrav1.VSphereVMList{}
+	if err := r.Client.List(ctx, vmList,
+		client.InNamespace(vsphereMachinePool.Namespace),
+		client.MatchingLabels{infrav1.MachinePoolNameLabel: vsphereMachinePool.Name}); err != nil {
+		return nil, errors.Wrapf(err, "failed to list VSphereVMs of %s/%s", vsphereMachinePool.Namespace, vsphereMachinePool.Name)
+	}
+	return vmList.Items, nil
+}
+
+// template returns the template the VMs of the pool are cloned from, and the
+// name of the VSphereMachineImage it is the template of, if any. The template
+// is empty while the image is not imported. The VMs of a pool referencing a
+// VSphereMachineImageMapping are cloned from the template, or the image,
+// mapped to the Kubernetes version of the MachinePool, and the ones of a pool
+// which sets neither a template, an image nor an image mapping from the
+// default template of the VSphereProviderConfig for this version.
+func (r machinePoolReconciler) template(ctx goctx.Context, machinePool *expv1.MachinePool, vsphereMachinePool *infrav1.VSphereMachinePool, providerConfig *infrav1.VSphereProviderConfig) (string, string, error) {
+	spec := vsphereMachinePool.Spec.Template.Spec
+	version := machinePool.Spec.Template.Spec.Version
+	if spec.Template != "" {
+		return spec.Template, "", nil
+	}
+	imageName := spec.Image
+	if spec.ImageMapping != "" {
+		template, mappedImage, err := infrautilv1.ResolveImageMapping(ctx, r.Client, vsphereMachinePool.Namespace, spec.ImageMapping, version)
+		if err != nil || template != "" {
+			return template, "", err
+		}
+		imageName = mappedImage
+	}
+	if imageName == "" {
+		if spec.ImageMapping != "" {
+			return "", "", nil
+		}
+		return infrautilv1.DefaultTemplate(providerConfig, version), "", nil
+	}
+	image := &infrav1.VSphereMachineImage{}
+	imageKey := client.ObjectKey{Namespace: vsphereMachinePool.Namespace, Name: imageName}
+	if err := r.Client.Get(ctx, imageKey, image); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", imageName, nil
+		}
+		return "", imageName, errors.Wrapf(err, "failed to get VSphereMachineImage %s", imageKey)
+	}
+	if !image.Status.Ready {
+		return "", imageName, nil
+	}
+	return image.Status.TemplatePath, imageName, nil
+}
+
+// poolVMNames returns the names of count new VSphereVMs of the pool, the pool
+// name suffixed by the lowest indexes not used by its VSphereVMs, including
+// the ones being deleted.
+func poolVMNames(vsphereMachinePool *infrav1.VSphereMachinePool, vms []infrav1.VSphereVM, count int) []string {
+	used := make(map[string]struct{}, len(vms))
+	for _, vm := range vms {
+		used[vm.Name] = struct{}{}
+	}
+	names := make([]string, 0, count)
+	for i := 0; len(names) < count; i++ {
+		name := fmt.Sprintf("%s-%d", vsphereMachinePool.Name, i)
+		if _, ok := used[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// newPoolVSphereVM returns the VSphereVM of the pool with the name cloned
+// from the template, whose datastore and networks left unset are defaulted by
+// the VSphereProviderConfig, if any.
+func newPoolVSphereVM(cluster *clusterv1.Cluster, vsphereCluster *infrav1.VSphereCluster, machinePool *expv1.MachinePool, vsphereMachinePool *infrav1.VSphereMachinePool, providerConfig *infrav1.VSphereProviderConfig, template, name string) *infrav1.VSphereVM {
+	vm := &infrav1.VSphereVM{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   vsphereMachinePool.Namespace,
+			Name:        name,
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(vsphereMachinePool, infrav1.GroupVersion.WithKind("VSphereMachinePool")),
+			},
+		},
+		Spec: infrav1.VSphereVMSpec{
+			BootstrapRef: &corev1.ObjectReference{
+				APIVersion: "v1",
+				Kind:       "Secret",
+				Name:       *machinePool.Spec.Template.Spec.Bootstrap.DataSecretName,
+				Namespace:  machinePool.Namespace,
+			},
+		},
+	}
+
+	// The metadata of the template, e.g. the pre-delete backup annotation,
+	// is set on the VSphereVMs.
+	for k, v := range vsphereMachinePool.Spec.Template.ObjectMeta.Labels {
+		vm.Labels[k] = v
+	}
+	for k, v := range vsphereMachinePool.Spec.Template.ObjectMeta.Annotations {
+		vm.Annotations[k] = v
+	}
+	vm.Labels[clusterv1.ClusterLabelName] = cluster.Name
+	vm.Labels[infrav1.MachinePoolNameLabel] = vsphereMachinePool.Name
+
+	vsphereMachinePool.Spec.Template.Spec.VirtualMachineCloneSpec.DeepCopyInto(&vm.Spec.VirtualMachineCloneSpec)
+	vm.Spec.Template = template
+	if vm.Spec.Server == "" {
+		vm.Spec.Server = vsphereCluster.Spec.Server
+	}
+	if vm.Spec.Thumbprint == "" {
+		vm.Spec.Thumbprint = vsphereCluster.Spec.Thumbprint
+	}
+	// The network devices without a network are attached to the NSX-T
+	// segment of the cluster.
+	if vsphereCluster.Spec.NSXT != nil {
+		segmentName := nsxt.SegmentName(cluster.Namespace, cluster.Name)
+		for i := range vm.Spec.Network.Devices {
+			if vm.Spec.Network.Devices[i].NetworkName == "" {
+				vm.Spec.Network.Devices[i].NetworkName = segmentName
+			}
+		}
+	}
+	infrautilv1.ApplyProviderConfig(&vm.Spec.VirtualMachineCloneSpec, providerConfig)
+	return vm
+}
+
+// vmsToDelete returns the VSphereVMs deleted to scale the pool in by count.
+// The VMs which are not ready are deleted first, as they do not run a node
+// yet, then the most recent ones.
+func vmsToDelete(vms []infrav1.VSphereVM, count int) []infrav1.VSphereVM {
+	sorted := make([]infrav1.VSphereVM, len(vms))
+	copy(sorted, vms)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Status.Ready != sorted[j].Status.Ready {
+			return !sorted[i].Status.Ready
+		}
+		return sorted[j].CreationTimestamp.Before(&sorted[i].CreationTimestamp)
+	})
+	if count > len(sorted) {
+		count = len(sorted)
+	}
+	return sorted[:count]
+}
+
+// vmsToKeep returns the VSphereVMs which are not deleted.
+func vmsToKeep(vms, deleted []infrav1.VSphereVM) []infrav1.VSphereVM {
+	names := make(map[string]struct{}, len(deleted))
+	for _, vm := range deleted {
+		names[vm.Name] = struct{}{}
+	}
+	var kept []infrav1.VSphereVM
+	for _, vm := range vms {
+		if _, ok := names[vm.Name]; !ok {
+			kept = append(kept, vm)
+		}
+	}
+	return kept
+}
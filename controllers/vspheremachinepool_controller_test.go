@@ -0,0 +1,170 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+)
+
+func TestVMsToDelete(t *testing.T) {
+	now := time.Now()
+	vm := func(name string, age time.Duration, ready bool) infrav1.VSphereVM {
+		return infrav1.VSphereVM{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			},
+			Status: infrav1.VSphereVMStatus{Ready: ready},
+		}
+	}
+	names := func(vms []infrav1.VSphereVM) []string {
+		var names []string
+		for _, vm := range vms {
+			names = append(names, vm.Name)
+		}
+		return names
+	}
+	vms := []infrav1.VSphereVM{
+		vm("old", 3*time.Hour, true),
+		vm("provisioning", 2*time.Hour, false),
+		vm("new", time.Hour, true),
+	}
+
+	t.Run("VMs which are not ready are deleted first", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(names(vmsToDelete(vms, 1))).To(Equal([]string{"provisioning"}))
+	})
+
+	t.Run("then the most recent VMs", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(names(vmsToDelete(vms, 2))).To(Equal([]string{"provisioning", "new"}))
+		g.Expect(names(vmsToKeep(vms, vmsToDelete(vms, 2)))).To(Equal([]string{"old"}))
+	})
+
+	t.Run("all VMs at most", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(vmsToDelete(vms, 5)).To(HaveLen(3))
+	})
+}
+
+func TestPoolVMNames(t *testing.T) {
+	g := NewWithT(t)
+	vsphereMachinePool := &infrav1.VSphereMachinePool{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pool"}}
+	vms := []infrav1.VSphereVM{
+		{ObjectMeta: metav1.ObjectMeta{Name: "pool-0"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pool-2"}},
+	}
+	g.Expect(poolVMNames(vsphereMachinePool, vms, 3)).To(Equal([]string{"pool-1", "pool-3", "pool-4"}))
+	g.Expect(poolVMNames(vsphereMachinePool, nil, 0)).To(BeEmpty())
+}
+
+func TestNewPoolVSphereVM(t *testing.T) {
+	g := NewWithT(t)
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cluster"}}
+	vsphereCluster := &infrav1.VSphereCluster{
+		Spec: infrav1.VSphereClusterSpec{Server: "vcenter.example.com", Thumbprint: "AA:BB"},
+	}
+	machinePool := &expv1.MachinePool{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pool"}}
+	machinePool.Spec.Template.Spec.Bootstrap.DataSecretName = pointer.String("pool-bootstrap")
+	vsphereMachinePool := &infrav1.VSphereMachinePool{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pool"},
+		Spec: infrav1.VSphereMachinePoolSpec{
+			Template: infrav1.VSphereMachineTemplateResource{
+				ObjectMeta: clusterv1.ObjectMeta{
+					Annotations: map[string]string{infrav1.VMPreDeleteBackupAnnotation: "true"},
+				},
+				Spec: infrav1.VSphereMachineSpec{
+					Image: "ubuntu",
+					VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+						NumCPUs: 4,
+						Network: infrav1.NetworkSpec{
+							Devices: []infrav1.NetworkDeviceSpec{{DHCP4: true}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	providerConfig := &infrav1.VSphereProviderConfig{
+		Spec: infrav1.VSphereProviderConfigSpec{Datastore: "ds0", Network: "vm-network"},
+	}
+
+	vm := newPoolVSphereVM(cluster, vsphereCluster, machinePool, vsphereMachinePool, providerConfig, "/dc0/vm/ubuntu", "pool-0")
+	g.Expect(vm.Name).To(Equal("pool-0"))
+	g.Expect(vm.Labels).To(HaveKeyWithValue(clusterv1.ClusterLabelName, "cluster"))
+	g.Expect(vm.Labels).To(HaveKeyWithValue(infrav1.MachinePoolNameLabel, "pool"))
+	g.Expect(vm.Annotations).To(HaveKeyWithValue(infrav1.VMPreDeleteBackupAnnotation, "true"))
+	g.Expect(vm.OwnerReferences).To(HaveLen(1))
+	g.Expect(vm.OwnerReferences[0].Kind).To(Equal("VSphereMachinePool"))
+	g.Expect(vm.Spec.BootstrapRef.Name).To(Equal("pool-bootstrap"))
+	g.Expect(vm.Spec.Template).To(Equal("/dc0/vm/ubuntu"))
+	g.Expect(vm.Spec.NumCPUs).To(Equal(int32(4)))
+	g.Expect(vm.Spec.Server).To(Equal("vcenter.example.com"))
+	g.Expect(vm.Spec.Thumbprint).To(Equal("AA:BB"))
+	g.Expect(vm.Spec.Datastore).To(Equal("ds0"))
+	g.Expect(vm.Spec.Network.Devices[0].NetworkName).To(Equal("vm-network"))
+}
+
+func TestMachinePoolTemplate(t *testing.T) {
+	g := NewWithT(t)
+	imageMapping := &infrav1.VSphereMachineImageMapping{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "ubuntu"},
+		Spec: infrav1.VSphereMachineImageMappingSpec{
+			Mappings: []infrav1.KubernetesVersionImage{
+				{KubernetesVersion: "v1.23", Template: "ubuntu-2004-kube-v1.23"},
+				{KubernetesVersion: "v1.24", Image: "ubuntu-2004-kube-v1.24"},
+			},
+		},
+	}
+	image := &infrav1.VSphereMachineImage{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "ubuntu-2004-kube-v1.24"},
+	}
+	r := machinePoolReconciler{ControllerContext: fake.NewControllerContext(fake.NewControllerManagerContext(imageMapping, image))}
+
+	machinePool := &expv1.MachinePool{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pool"}}
+	vsphereMachinePool := &infrav1.VSphereMachinePool{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pool"}}
+	vsphereMachinePool.Spec.Template.Spec.ImageMapping = "ubuntu"
+
+	machinePool.Spec.Template.Spec.Version = pointer.String("v1.23.5")
+	template, imageName, err := r.template(r.ControllerContext, machinePool, vsphereMachinePool, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(template).To(Equal("ubuntu-2004-kube-v1.23"))
+	g.Expect(imageName).To(BeEmpty())
+
+	machinePool.Spec.Template.Spec.Version = pointer.String("v1.24.1")
+	template, imageName, err = r.template(r.ControllerContext, machinePool, vsphereMachinePool, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(template).To(BeEmpty())
+	g.Expect(imageName).To(Equal("ubuntu-2004-kube-v1.24"))
+
+	machinePool.Spec.Template.Spec.Version = pointer.String("v1.25.0")
+	template, imageName, err = r.template(r.ControllerContext, machinePool, vsphereMachinePool, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(template).To(BeEmpty())
+	g.Expect(imageName).To(BeEmpty())
+}
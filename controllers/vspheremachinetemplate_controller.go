@@ -0,0 +1,148 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	goctx "context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
+)
+
+const (
+	// nvidiaVendorID is the PCI vendor ID of NVIDIA.
+	nvidiaVendorID = 0x10de
+
+	// gpuResourceName is the resource name of the NVIDIA GPUs of a node.
+	gpuResourceName corev1.ResourceName = "nvidia.com/gpu"
+
+	// The VMs are cloned with at least two CPUs, and with 2 GiB of memory
+	// unless the template sets it.
+	minNumCPUs       = 2
+	defaultMemoryMiB = 2048
+)
+
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vspheremachinetemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vspheremachinetemplates/status,verbs=get;update;patch
+
+// AddVSphereMachineTemplateControllerToManager adds the VSphereMachineTemplate
+// controller to the provided manager.
+func AddVSphereMachineTemplateControllerToManager(ctx *context.ControllerManagerContext, mgr manager.Manager) error {
+	var (
+		controlledType     = &infrav1.VSphereMachineTemplate{}
+		controlledTypeName = reflect.TypeOf(controlledType).Elem().Name()
+
+		controllerNameShort = fmt.Sprintf("%s-controller", strings.ToLower(controlledTypeName))
+		controllerNameLong  = fmt.Sprintf("%s/%s/%s", ctx.Namespace, ctx.Name, controllerNameShort)
+	)
+
+	// Build the controller context.
+	controllerContext := &context.ControllerContext{
+		ControllerManagerContext: ctx,
+		Name:                     controllerNameShort,
+		Recorder:                 record.New(mgr.GetEventRecorderFor(controllerNameLong)),
+		Logger:                   ctx.Logger.WithName(controllerNameShort),
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		// Watch the controlled, infrastructure resource.
+		For(controlledType).
+		WithOptions(controller.Options{MaxConcurrentReconciles: ctx.MaxConcurrentReconciles}).
+		Complete(tracing.Reconciler(controlledTypeName, machineTemplateReconciler{ControllerContext: controllerContext}))
+}
+
+type machineTemplateReconciler struct {
+	*context.ControllerContext
+}
+
+// Reconcile sets the capacity of the machines created from a
+// VSphereMachineTemplate in its status, so the cluster-autoscaler can scale
+// MachineDeployments without machines from zero.
+func (r machineTemplateReconciler) Reconcile(ctx goctx.Context, req reconcile.Request) (reconcile.Result, error) {
+	machineTemplate := &infrav1.VSphereMachineTemplate{}
+	if err := r.Client.Get(ctx, req.NamespacedName, machineTemplate); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Logger.V(4).Info("VSphereMachineTemplate not found, won't reconcile", "key", req.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	capacity := machineCapacity(&machineTemplate.Spec.Template.Spec)
+	if reflect.DeepEqual(machineTemplate.Status.Capacity, capacity) {
+		return reconcile.Result{}, nil
+	}
+
+	patchHelper, err := patch.NewHelper(machineTemplate, r.Client)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(
+			err,
+			"failed to init patch helper for %s %s/%s",
+			machineTemplate.GroupVersionKind(),
+			machineTemplate.Namespace,
+			machineTemplate.Name)
+	}
+	machineTemplate.Status.Capacity = capacity
+	return reconcile.Result{}, patchHelper.Patch(ctx, machineTemplate)
+}
+
+// machineCapacity returns the capacity of the VMs cloned for the spec, as
+// configured by the clone. The size of their disk is only known when the spec
+// sets it, as it is otherwise the one of the template.
+func machineCapacity(spec *infrav1.VSphereMachineSpec) corev1.ResourceList {
+	numCPUs := int64(spec.NumCPUs)
+	if numCPUs < minNumCPUs {
+		numCPUs = minNumCPUs
+	}
+	memoryMiB := spec.MemoryMiB
+	if memoryMiB == 0 {
+		memoryMiB = defaultMemoryMiB
+	}
+	capacity := corev1.ResourceList{
+		corev1.ResourceCPU:    *resource.NewQuantity(numCPUs, resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(memoryMiB*1024*1024, resource.BinarySI),
+	}
+	if spec.DiskGiB > 0 {
+		capacity[corev1.ResourceEphemeralStorage] = *resource.NewQuantity(int64(spec.DiskGiB)*1024*1024*1024, resource.BinarySI)
+	}
+
+	var gpus int64
+	for _, device := range spec.PciDevices {
+		if device.VGPUProfile != "" || (device.VendorID != nil && *device.VendorID == nvidiaVendorID) {
+			gpus++
+		}
+	}
+	if gpus > 0 {
+		capacity[gpuResourceName] = *resource.NewQuantity(gpus, resource.DecimalSI)
+	}
+	return capacity
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/pointer"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+func TestMachineCapacity(t *testing.T) {
+	t.Run("defaults of the clone", func(t *testing.T) {
+		g := NewWithT(t)
+		capacity := machineCapacity(&infrav1.VSphereMachineSpec{})
+		g.Expect(capacity).To(HaveLen(2))
+		g.Expect(capacity.Cpu().Cmp(resource.MustParse("2"))).To(BeZero())
+		g.Expect(capacity.Memory().Cmp(resource.MustParse("2Gi"))).To(BeZero())
+	})
+
+	t.Run("sizes of the spec", func(t *testing.T) {
+		g := NewWithT(t)
+		capacity := machineCapacity(&infrav1.VSphereMachineSpec{
+			VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+				NumCPUs:   8,
+				MemoryMiB: 16384,
+				DiskGiB:   40,
+			},
+		})
+		g.Expect(capacity.Cpu().Cmp(resource.MustParse("8"))).To(BeZero())
+		g.Expect(capacity.Memory().Cmp(resource.MustParse("16Gi"))).To(BeZero())
+		g.Expect(capacity.StorageEphemeral().Cmp(resource.MustParse("40Gi"))).To(BeZero())
+		g.Expect(capacity).NotTo(HaveKey(gpuResourceName))
+	})
+
+	t.Run("NVIDIA GPUs and vGPUs", func(t *testing.T) {
+		g := NewWithT(t)
+		capacity := machineCapacity(&infrav1.VSphereMachineSpec{
+			VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+				PciDevices: []infrav1.PCIDeviceSpec{
+					{DeviceID: pointer.Int32(0x1eb8), VendorID: pointer.Int32(nvidiaVendorID)},
+					{VGPUProfile: "grid_t4-4q"},
+					{DeviceID: pointer.Int32(0x1234), VendorID: pointer.Int32(0x8086)},
+				},
+			},
+		})
+		gpus := capacity[gpuResourceName]
+		g.Expect(gpus.Cmp(resource.MustParse("2"))).To(BeZero())
+		g.Expect(capacity).To(HaveKey(corev1.ResourceCPU))
+	})
+}
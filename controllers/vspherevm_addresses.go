@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+)
+
+// reconcileAddressWait returns whether the VSphereVM reports the network
+// addresses it is expected to, or is to be made ready without the missing
+// ones according to the AddressWait policy of its network. Otherwise the
+// IPAssigned condition reports how long the VSphereVM has been waiting for
+// them, and the VSphereVM is failed once it waited for longer than the
+// timeout of a policy with the Fail action.
+func reconcileAddressWait(ctx *context.VMContext) bool {
+	vm := ctx.VSphereVM
+	waiting := waitingForAddresses(vm)
+	if waiting == "" {
+		vm.Status.AddressWaitStartTime = nil
+		return true
+	}
+	// The addresses are reported by the VMware Tools of the VM.
+	if conditions.IsFalse(vm, infrav1.GuestToolsRunningCondition) {
+		waiting += " (" + conditions.GetMessage(vm, infrav1.GuestToolsRunningCondition) + ")"
+	}
+
+	if vm.Status.AddressWaitStartTime == nil {
+		now := metav1.Now()
+		vm.Status.AddressWaitStartTime = &now
+	}
+	waited := time.Since(vm.Status.AddressWaitStartTime.Time).Truncate(time.Second)
+	policy := vm.Spec.Network.AddressWait
+	if policy == nil || waited < policy.Timeout.Duration {
+		ctx.Logger.Info(waiting, "waited", waited.String())
+		conditions.MarkFalse(vm, infrav1.IPAssignedCondition, infrav1.WaitingForNetworkAddressesReason, clusterv1.ConditionSeverityInfo,
+			"%s for %s", waiting, waited)
+		return false
+	}
+
+	switch policy.Action {
+	case infrav1.NetworkAddressWaitActionFail:
+		message := fmt.Sprintf("%s for more than %s", waiting, policy.Timeout.Duration)
+		conditions.MarkFalse(vm, infrav1.IPAssignedCondition, infrav1.NetworkAddressesTimedOutReason, clusterv1.ConditionSeverityError, message)
+		failureReason := capierrors.UpdateMachineError
+		if !vm.Status.Ready {
+			failureReason = capierrors.CreateMachineError
+		}
+		vm.Status.FailureReason = capierrors.MachineStatusErrorPtr(failureReason)
+		vm.Status.FailureMessage = pointer.StringPtr(message)
+		vm.Status.AddressWaitStartTime = nil
+		ctx.Recorder.Warnf(vm, infrav1.NetworkAddressesTimedOutReason, "Failing VM: %s", message)
+		return false
+	case infrav1.NetworkAddressWaitActionFallback:
+		if len(vm.Status.Addresses) > 0 {
+			ctx.Recorder.Eventf(vm, infrav1.NetworkAddressesTimedOutReason, "Stopped %s after %s, falling back to addresses %s",
+				waiting, waited, strings.Join(vm.Status.Addresses, ", "))
+			vm.Status.AddressWaitStartTime = nil
+			return true
+		}
+	}
+
+	if conditions.GetReason(vm, infrav1.IPAssignedCondition) != infrav1.NetworkAddressesTimedOutReason {
+		ctx.Recorder.Warnf(vm, infrav1.NetworkAddressesTimedOutReason, "Still %s after %s", waiting, policy.Timeout.Duration)
+	}
+	conditions.MarkFalse(vm, infrav1.IPAssignedCondition, infrav1.NetworkAddressesTimedOutReason, clusterv1.ConditionSeverityWarning,
+		"%s for %s", waiting, waited)
+	return false
+}
+
+// waitingForAddresses returns what the VSphereVM is waiting for, or an empty
+// string if it reports the network addresses it is expected to: an address of
+// every IP family of a dual-stack network and, until it is ready and when the
+// AddressWait policy of its network is set, an address on every DHCP network
+// device.
+func waitingForAddresses(vm *infrav1.VSphereVM) string {
+	if len(vm.Status.Addresses) == 0 {
+		return "waiting for network addresses"
+	}
+
+	// Dual-stack VMs are only ready once they report addresses of both
+	// families, so the Machine addresses include both of them.
+	if missing := missingIPFamilies(vm); len(missing) > 0 {
+		return fmt.Sprintf("waiting for %s addresses", strings.Join(missing, ", "))
+	}
+
+	if vm.Spec.Network.AddressWait == nil || vm.Status.Ready {
+		return ""
+	}
+	var devices []string
+	for i, dev := range vm.Spec.Network.Devices {
+		if !dev.DHCP4 && !dev.DHCP6 {
+			continue
+		}
+		if i >= len(vm.Status.Network) || len(vm.Status.Network[i].IPAddrs) == 0 {
+			devices = append(devices, strconv.Itoa(i))
+		}
+	}
+	if len(devices) > 0 {
+		return fmt.Sprintf("waiting for addresses of network devices %s", strings.Join(devices, ", "))
+	}
+	return ""
+}
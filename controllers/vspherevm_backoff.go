@@ -0,0 +1,189 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi"
+)
+
+const (
+	// transientErrorBackoff and maxTransientErrorBackoff bound the delay
+	// before reconciling again a VSphereVM whose reconciliation failed
+	// because vCenter was unavailable.
+	transientErrorBackoff    = 5 * time.Second
+	maxTransientErrorBackoff = 5 * time.Minute
+
+	// quotaErrorBackoff and maxQuotaErrorBackoff bound the delay before
+	// reconciling again a VSphereVM whose reconciliation failed for lack of
+	// resources.
+	quotaErrorBackoff    = 1 * time.Minute
+	maxQuotaErrorBackoff = 30 * time.Minute
+
+	// pollBackoff and maxPollBackoff bound the delay between the
+	// reconciliations of a VSphereVM waiting on its VM without a vCenter task
+	// to watch, e.g. for its IP addresses.
+	pollBackoff    = 10 * time.Second
+	maxPollBackoff = 1 * time.Minute
+)
+
+var (
+	// vmErrorBackoff tracks the VSphereVMs whose reconciliation failed with
+	// a transient or a quota error.
+	vmErrorBackoff = newRequeueBackoff()
+
+	// vmPollBackoff tracks the VSphereVMs polling their VM.
+	vmPollBackoff = newRequeueBackoff()
+)
+
+// requeueBackoff computes the exponential backoff of the reconciliations of
+// VSphereVMs, keyed by namespace and name.
+type requeueBackoff struct {
+	mu      sync.Mutex
+	entries map[string]*requeueBackoffEntry
+}
+
+type requeueBackoffEntry struct {
+	delay      time.Duration
+	requeueAt  time.Time
+	generation int64
+}
+
+func newRequeueBackoff() *requeueBackoff {
+	return &requeueBackoff{entries: map[string]*requeueBackoffEntry{}}
+}
+
+func requeueBackoffKey(vm *infrav1.VSphereVM) string {
+	return vm.Namespace + "/" + vm.Name
+}
+
+// next returns the delay before the next reconciliation of the VSphereVM,
+// which doubles with every call from initial up to max.
+func (b *requeueBackoff) next(vm *infrav1.VSphereVM, initial, max time.Duration) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := requeueBackoffKey(vm)
+	entry, ok := b.entries[key]
+	if !ok {
+		entry = &requeueBackoffEntry{}
+		b.entries[key] = entry
+	}
+	entry.delay *= 2
+	if entry.delay < initial {
+		entry.delay = initial
+	}
+	if entry.delay > max {
+		entry.delay = max
+	}
+	entry.requeueAt = time.Now().Add(entry.delay)
+	entry.generation = vm.Generation
+	return entry.delay
+}
+
+// remaining returns how long the VSphereVM is still backed off for, unless
+// its spec changed or it is being deleted since.
+func (b *requeueBackoff) remaining(vm *infrav1.VSphereVM) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, ok := b.entries[requeueBackoffKey(vm)]
+	if !ok || entry.generation != vm.Generation || !vm.DeletionTimestamp.IsZero() {
+		return 0
+	}
+	if remaining := time.Until(entry.requeueAt); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// has returns whether the VSphereVM is backed off.
+func (b *requeueBackoff) has(vm *infrav1.VSphereVM) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, ok := b.entries[requeueBackoffKey(vm)]
+	return ok
+}
+
+// reset forgets the backoff of the VSphereVM.
+func (b *requeueBackoff) reset(vm *infrav1.VSphereVM) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.entries, requeueBackoffKey(vm))
+}
+
+// requeueOnError determines how the reconciliation of the VSphereVM is
+// retried based on the class of its error:
+//   - transient errors, e.g. during a vCenter brownout, are retried with an
+//     exponential backoff rather than the rate limiter of the controller, and
+//     the VSphereVM is not reconciled again before the backoff expires when
+//     its status is patched.
+//   - quota errors are retried with a longer backoff.
+//   - permanent errors fail the VSphereVM, which is no longer reconciled
+//     until its configuration is fixed, unless it is being deleted.
+//   - other errors are returned to the controller.
+func requeueOnError(vm *infrav1.VSphereVM, recorder record.Recorder, logger logr.Logger, result reconcile.Result, err error) (reconcile.Result, error) {
+	if err == nil {
+		vmErrorBackoff.reset(vm)
+		return result, nil
+	}
+
+	switch class := govmomi.ClassifyError(err); class {
+	case govmomi.TransientError, govmomi.QuotaError:
+		initial, max := transientErrorBackoff, maxTransientErrorBackoff
+		if class == govmomi.QuotaError {
+			initial, max = quotaErrorBackoff, maxQuotaErrorBackoff
+			if !vm.Status.Ready {
+				conditions.MarkFalse(vm, infrav1.VMProvisionedCondition, infrav1.InsufficientResourcesReason, clusterv1.ConditionSeverityWarning, err.Error())
+			}
+		}
+		delay := vmErrorBackoff.next(vm, initial, max)
+		logger.Info("Reconciliation failed, retrying after backoff", "errorClass", class, "backoff", delay, "error", err.Error())
+		return reconcile.Result{RequeueAfter: delay}, nil
+	case govmomi.PermanentError:
+		// The deletion of a VSphereVM is retried regardless, so that its
+		// finalizer is removed once the error is fixed.
+		if !vm.DeletionTimestamp.IsZero() {
+			return result, err
+		}
+		vmErrorBackoff.reset(vm)
+		failureReason := capierrors.UpdateMachineError
+		if !vm.Status.Ready {
+			failureReason = capierrors.CreateMachineError
+		}
+		vm.Status.FailureReason = capierrors.MachineStatusErrorPtr(failureReason)
+		vm.Status.FailureMessage = pointer.StringPtr(err.Error())
+		recorder.Warn(vm, "ReconcileFailed", err.Error())
+		logger.Error(err, "Reconciliation failed permanently")
+		return reconcile.Result{}, nil
+	default:
+		return result, err
+	}
+}
@@ -19,9 +19,9 @@ package controllers
 import (
 	goctx "context"
 	"fmt"
+	"net"
 	"reflect"
 	"strings"
-	"time"
 
 	"github.com/pkg/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -49,11 +49,13 @@ import (
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
 )
 
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vspherevms,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vspherevms/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vsphereippools,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events,verbs=get;list;watch;create;update;patch
 
 // AddVMControllerToManager adds the VM controller to the provided manager.
@@ -88,8 +90,8 @@ func AddVMControllerToManager(ctx *context.ControllerManagerContext, mgr manager
 			&source.Channel{Source: ctx.GetGenericEventChannelFor(controlledTypeGVK)},
 			&handler.EnqueueRequestForObject{},
 		).
-		WithOptions(controller.Options{MaxConcurrentReconciles: ctx.MaxConcurrentReconciles}).
-		Build(r)
+		WithOptions(controller.Options{MaxConcurrentReconciles: ctx.VSphereVMConcurrency}).
+		Build(tracing.Reconciler(controlledTypeName, r))
 	if err != nil {
 		return err
 	}
@@ -143,36 +145,59 @@ func (r vmReconciler) Reconcile(ctx goctx.Context, req ctrl.Request) (_ ctrl.Res
 			vsphereVM.Name)
 	}
 
+	// Patching the status of a VSphereVM whose reconciliation failed triggers
+	// another one right away, so do not call vCenter again before the backoff
+	// of the failure expires, unless the VSphereVM changed since.
+	if remaining := vmErrorBackoff.remaining(vsphereVM); remaining > 0 {
+		r.Logger.V(4).Info("VSphereVM is backing off, won't reconcile", "key", req.NamespacedName, "remaining", remaining)
+		return reconcile.Result{RequeueAfter: remaining}, nil
+	}
+
 	authSession, err := r.retrieveVcenterSession(ctx, vsphereVM)
 	if err != nil {
 		conditions.MarkFalse(vsphereVM, infrav1.VCenterAvailableCondition, infrav1.VCenterUnreachableReason, clusterv1.ConditionSeverityError, err.Error())
-		return reconcile.Result{}, err
+		if !vsphereVM.DeletionTimestamp.IsZero() {
+			return r.reconcileDeleteUnreachable(ctx, vsphereVM, patchHelper, err)
+		}
+		return requeueOnError(vsphereVM, r.Recorder, r.Logger, reconcile.Result{}, err)
 	}
 	conditions.MarkTrue(vsphereVM, infrav1.VCenterAvailableCondition)
 
-	// Fetch the owner VSphereMachine.
-	vsphereMachine, err := util.GetOwnerVSphereMachine(r, r.Client, vsphereVM.ObjectMeta)
-	// vsphereMachine can be nil in cases where custom mover other than clusterctl
-	// moves the resources without ownerreferences set
-	// in that case nil vsphereMachine can cause panic and CrashLoopBackOff the pod
-	// preventing vspheremachine_controller from setting the ownerref
-	if err != nil || vsphereMachine == nil {
-		r.Logger.Info("Owner VSphereMachine not found, won't reconcile", "key", req.NamespacedName)
-		return reconcile.Result{}, nil
-	}
+	// The VSphereVMs of a VSphereMachinePool or of a VSphereLoadBalancerVM
+	// have neither a VSphereMachine nor a CAPI Machine, and are not placed in
+	// a failure domain.
+	var failureDomain *string
+	if !util.IsOwnedByVSphereMachinePool(vsphereVM.ObjectMeta) && !util.IsOwnedByVSphereLoadBalancerVM(vsphereVM.ObjectMeta) {
+		// Fetch the owner VSphereMachine.
+		vsphereMachine, err := util.GetOwnerVSphereMachine(r, r.Client, vsphereVM.ObjectMeta)
+		// vsphereMachine can be nil in cases where custom mover other than clusterctl
+		// moves the resources without ownerreferences set
+		// in that case nil vsphereMachine can cause panic and CrashLoopBackOff the pod
+		// preventing vspheremachine_controller from setting the ownerref
+		if err != nil || vsphereMachine == nil {
+			r.Logger.Info("Owner VSphereMachine not found, won't reconcile", "key", req.NamespacedName)
+			return reconcile.Result{}, nil
+		}
 
-	// Fetch the CAPI Machine.
-	machine, err := clusterutilv1.GetOwnerMachine(r, r.Client, vsphereMachine.ObjectMeta)
-	if err != nil {
-		return reconcile.Result{}, err
-	}
-	if machine == nil {
-		r.Logger.Info("Waiting for OwnerRef to be set on VSphereMachine", "key", vsphereMachine.Name)
-		return reconcile.Result{}, nil
+		// Fetch the CAPI Machine.
+		machine, err := clusterutilv1.GetOwnerMachine(r, r.Client, vsphereMachine.ObjectMeta)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if machine == nil {
+			r.Logger.Info("Waiting for OwnerRef to be set on VSphereMachine", "key", vsphereMachine.Name)
+			return reconcile.Result{}, nil
+		}
+		failureDomain = machine.Spec.FailureDomain
+		// The deployment zone chosen for the VSphereMachine is only copied
+		// to the Machine by Cluster API after a while.
+		if failureDomain == nil {
+			failureDomain = vsphereMachine.Spec.FailureDomain
+		}
 	}
 
 	var vsphereFailureDomain *infrav1.VSphereFailureDomain
-	if failureDomain := machine.Spec.FailureDomain; failureDomain != nil {
+	if failureDomain != nil {
 		vsphereDeploymentZone := &infrav1.VSphereDeploymentZone{}
 		if err := r.Client.Get(r, apitypes.NamespacedName{Name: *failureDomain}, vsphereDeploymentZone); err != nil {
 			return reconcile.Result{}, errors.Wrapf(err, "failed to find vsphere deployment zone %s", *failureDomain)
@@ -192,6 +217,7 @@ func (r vmReconciler) Reconcile(ctx goctx.Context, req ctrl.Request) (_ ctrl.Res
 		Session:              authSession,
 		Logger:               r.Logger.WithName(req.Namespace).WithName(req.Name),
 		PatchHelper:          patchHelper,
+		RequestContext:       ctx,
 	}
 
 	// Print the task-ref upon entry and upon exit.
@@ -229,17 +255,26 @@ func (r vmReconciler) Reconcile(ctx goctx.Context, req ctrl.Request) (_ ctrl.Res
 		if annotations.IsPaused(cluster, vsphereVM) {
 			r.Logger.V(4).Info("VSphereVM %s/%s linked to a cluster that is paused",
 				vsphereVM.Namespace, vsphereVM.Name)
+			// The cluster is paused before it is moved by clusterctl, which
+			// waits for the in-flight task of the VM to complete.
+			if govmomi.PrepareMove(vmContext) {
+				return reconcile.Result{RequeueAfter: pollBackoff}, nil
+			}
 			return reconcile.Result{}, nil
 		}
 	}
+	govmomi.ResumeAfterMove(vmContext)
 
-	// Handle deleted machines
+	var result reconcile.Result
 	if !vsphereVM.ObjectMeta.DeletionTimestamp.IsZero() {
-		return r.reconcileDelete(vmContext)
+		// Handle deleted machines
+		result, err = r.reconcileDelete(vmContext)
+	} else {
+		// Handle non-deleted machines
+		result, err = r.reconcileNormal(vmContext)
 	}
-
-	// Handle non-deleted machines
-	return r.reconcileNormal(vmContext)
+	govmomi.RecordForMove(vmContext)
+	return requeueOnError(vsphereVM, vmContext.Recorder, vmContext.Logger, result, err)
 }
 
 func (r vmReconciler) reconcileDelete(ctx *context.VMContext) (reconcile.Result, error) {
@@ -251,26 +286,40 @@ func (r vmReconciler) reconcileDelete(ctx *context.VMContext) (reconcile.Result,
 	conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, clusterv1.DeletingReason, clusterv1.ConditionSeverityInfo, "")
 	vm, err := vmService.DestroyVM(ctx)
 	if err != nil {
-		conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, "DeletionFailed", clusterv1.ConditionSeverityWarning, err.Error())
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, clusterv1.DeletionFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
 		return reconcile.Result{}, errors.Wrapf(err, "failed to destroy VM")
 	}
 
 	// Requeue the operation until the VM is "notfound".
 	if vm.State != infrav1.VirtualMachineStateNotFound {
 		ctx.Logger.Info("vm state is not reconciled", "expected-vm-state", infrav1.VirtualMachineStateNotFound, "actual-vm-state", vm.State)
+		// A guest OS shutdown is not tracked by a task, so poll until the VM
+		// is powered off.
+		if conditions.GetReason(ctx.VSphereVM, infrav1.GuestSoftPowerOffSucceededCondition) == infrav1.GuestSoftPowerOffInProgressReason {
+			return reconcile.Result{RequeueAfter: vmPollBackoff.next(ctx.VSphereVM, pollBackoff, maxPollBackoff)}, nil
+		}
 		return reconcile.Result{}, nil
 	}
 
+	// Release the addresses allocated to the network devices once the VM is
+	// gone.
+	if err := releaseIPAllocations(ctx); err != nil {
+		return reconcile.Result{}, err
+	}
+
 	// The VM is deleted so remove the finalizer.
 	ctrlutil.RemoveFinalizer(ctx.VSphereVM, infrav1.VMFinalizer)
+	vmErrorBackoff.reset(ctx.VSphereVM)
+	vmPollBackoff.reset(ctx.VSphereVM)
+	vmFailureRetryBackoff.reset(ctx.VSphereVM)
 
 	return reconcile.Result{}, nil
 }
 
 func (r vmReconciler) reconcileNormal(ctx *context.VMContext) (reconcile.Result, error) {
-	if ctx.VSphereVM.Status.FailureReason != nil || ctx.VSphereVM.Status.FailureMessage != nil {
-		r.Logger.Info("VM is failed, won't reconcile", "namespace", ctx.VSphereVM.Namespace, "name", ctx.VSphereVM.Name)
-		return reconcile.Result{}, nil
+	if ok, requeueAfter := reconcileFailure(ctx); !ok {
+		r.Logger.Info("VM is failed, won't reconcile", "namespace", ctx.VSphereVM.Namespace, "name", ctx.VSphereVM.Name, "retryAfter", requeueAfter)
+		return reconcile.Result{RequeueAfter: requeueAfter}, nil
 	}
 	// If the VSphereVM doesn't have our finalizer, add it.
 	ctrlutil.AddFinalizer(ctx.VSphereVM, infrav1.VMFinalizer)
@@ -278,8 +327,15 @@ func (r vmReconciler) reconcileNormal(ctx *context.VMContext) (reconcile.Result,
 	// TODO(akutz) Implement selection of VM service based on vSphere version
 	var vmService services.VirtualMachineService = &govmomi.VMService{}
 
+	// Allocate the addresses of the network devices from their VSphereIPPool.
+	if err := reconcileIPAllocations(ctx); err != nil {
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.IPAllocationFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		return reconcile.Result{}, err
+	}
+
 	if r.isWaitingForStaticIPAllocation(ctx) {
 		conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.WaitingForStaticIPAllocationReason, clusterv1.ConditionSeverityInfo, "")
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.IPAssignedCondition, infrav1.WaitingForStaticIPAllocationReason, clusterv1.ConditionSeverityInfo, "")
 		ctx.Logger.Info("vm is waiting for static ip to be available")
 		return reconcile.Result{}, nil
 	}
@@ -296,6 +352,16 @@ func (r vmReconciler) reconcileNormal(ctx *context.VMContext) (reconcile.Result,
 			"VM state is not reconciled",
 			"expected-vm-state", infrav1.VirtualMachineStateReady,
 			"actual-vm-state", vm.State)
+		// Neither a queued clone, a clone waiting for free capacity nor the
+		// freeze of the parent VM of an instant clone is tracked by a task,
+		// so poll until the clone is started. The VMware Tools are polled
+		// likewise until they are running.
+		if conditions.GetReason(ctx.VSphereVM, infrav1.VMProvisionedCondition) == infrav1.CloneQueuedReason ||
+			conditions.GetReason(ctx.VSphereVM, infrav1.VMProvisionedCondition) == infrav1.InsufficientCapacityReason ||
+			conditions.GetReason(ctx.VSphereVM, infrav1.VMProvisionedCondition) == infrav1.WaitingForGuestToolsReason ||
+			conditions.GetReason(ctx.VSphereVM, infrav1.CloneStartedCondition) == infrav1.WaitingForInstantCloneParentReason {
+			return reconcile.Result{RequeueAfter: vmPollBackoff.next(ctx.VSphereVM, pollBackoff, maxPollBackoff)}, nil
+		}
 		return reconcile.Result{}, nil
 	}
 
@@ -312,13 +378,23 @@ func (r vmReconciler) reconcileNormal(ctx *context.VMContext) (reconcile.Result,
 	// Update the VSphereVM's network status.
 	r.reconcileNetwork(ctx, vm)
 
-	// we didn't get any addresses, requeue
-	if len(ctx.VSphereVM.Status.Addresses) == 0 {
-		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+	// Wait for the addresses the VM is expected to report, and requeue until
+	// it does, unless the wait timed out and failed the VM.
+	if !reconcileAddressWait(ctx) {
+		if ctx.VSphereVM.Status.FailureReason != nil {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{RequeueAfter: vmPollBackoff.next(ctx.VSphereVM, pollBackoff, maxPollBackoff)}, nil
+	}
+	if !conditions.IsTrue(ctx.VSphereVM, infrav1.IPAssignedCondition) {
+		conditions.MarkTrue(ctx.VSphereVM, infrav1.IPAssignedCondition)
+		ctx.Recorder.Eventf(ctx.VSphereVM, string(infrav1.IPAssignedCondition), "VM reported IP addresses %s", strings.Join(ctx.VSphereVM.Status.Addresses, ", "))
 	}
 
 	// Once the network is online the VM is considered ready.
+	vmPollBackoff.reset(ctx.VSphereVM)
 	ctx.VSphereVM.Status.Ready = true
+	ctx.VSphereVM.Status.FailureRetries = 0
 	conditions.MarkTrue(ctx.VSphereVM, infrav1.VMProvisionedCondition)
 	ctx.Logger.Info("VSphereVM is ready")
 
@@ -332,7 +408,7 @@ func (r vmReconciler) reconcileNormal(ctx *context.VMContext) (reconcile.Result,
 func (r vmReconciler) isWaitingForStaticIPAllocation(ctx *context.VMContext) bool {
 	devices := ctx.VSphereVM.Spec.Network.Devices
 	for _, dev := range devices {
-		if !dev.DHCP4 && !dev.DHCP6 && len(dev.IPAddrs) == 0 {
+		if !dev.DHCP4 && !dev.DHCP6 && !dev.SLAAC && len(dev.IPAddrs) == 0 {
 			// Static IP is not available yet
 			return true
 		}
@@ -341,13 +417,48 @@ func (r vmReconciler) isWaitingForStaticIPAllocation(ctx *context.VMContext) boo
 	return false
 }
 
+// missingIPFamilies returns the IP families configured on the network
+// devices of the VSphereVM for which no address is reported in its status.
+func missingIPFamilies(vm *infrav1.VSphereVM) []string {
+	var wantIPv4, wantIPv6 bool
+	for _, dev := range vm.Spec.Network.Devices {
+		wantIPv4 = wantIPv4 || dev.DHCP4
+		wantIPv6 = wantIPv6 || dev.DHCP6 || dev.SLAAC
+		for _, addr := range dev.IPAddrs {
+			if ip, _, err := net.ParseCIDR(addr); err == nil {
+				wantIPv4 = wantIPv4 || ip.To4() != nil
+				wantIPv6 = wantIPv6 || ip.To4() == nil
+			}
+		}
+	}
+
+	var hasIPv4, hasIPv6 bool
+	for _, addr := range vm.Status.Addresses {
+		if ip := net.ParseIP(addr); ip != nil {
+			hasIPv4 = hasIPv4 || ip.To4() != nil
+			hasIPv6 = hasIPv6 || ip.To4() == nil
+		}
+	}
+
+	var missing []string
+	if wantIPv4 && !hasIPv4 {
+		missing = append(missing, "IPv4")
+	}
+	if wantIPv6 && !hasIPv6 {
+		missing = append(missing, "IPv6")
+	}
+	return missing
+}
+
 func (r vmReconciler) reconcileNetwork(ctx *context.VMContext, vm infrav1.VirtualMachine) {
 	ctx.VSphereVM.Status.Network = vm.Network
 	ipAddrs := make([]string, 0, len(vm.Network))
 	for _, netStatus := range ctx.VSphereVM.Status.Network {
 		ipAddrs = append(ipAddrs, netStatus.IPAddrs...)
 	}
+	ipAddrs = util.FilterIPAddrs(ipAddrs, ctx.VSphereVM.Spec.Network)
 	ctx.VSphereVM.Status.Addresses = ipAddrs
+	ctx.VSphereVM.Status.MachineAddresses = util.GetMachineAddresses(ipAddrs, ctx.InternalIPCIDRs, vm.Hostname, vm.DNSNames)
 }
 
 func (r *vmReconciler) clusterToVSphereVMs(a ctrlclient.Object) []reconcile.Request {
@@ -374,20 +485,31 @@ func (r *vmReconciler) clusterToVSphereVMs(a ctrlclient.Object) []reconcile.Requ
 }
 
 func (r *vmReconciler) retrieveVcenterSession(ctx goctx.Context, vsphereVM *infrav1.VSphereVM) (*session.Session, error) {
+	// The session outlives the request, whose span it is created in.
+	sessionCtx := tracing.ContextWithSpanOf(r.Context, ctx)
 	// Get cluster object and then get VSphereCluster object
 
+	providerConfig, err := util.GetProviderConfig(ctx, r.Client)
+	if err != nil {
+		return nil, err
+	}
+	feature := session.Feature{
+		KeepAliveDuration: r.KeepAliveDuration,
+		KeepAliveRetries:  r.KeepAliveRetries,
+		QPS:               float32(r.VCenterQPS),
+		Burst:             r.VCenterBurst,
+		TLS:               r.VCenterTLSPolicy,
+	}.WithProviderConfig(providerConfig)
 	params := session.NewParams().
 		WithServer(vsphereVM.Spec.Server).
 		WithDatacenter(vsphereVM.Spec.Datacenter).
 		WithUserInfo(r.ControllerContext.Username, r.ControllerContext.Password).
 		WithThumbprint(vsphereVM.Spec.Thumbprint).
-		WithFeatures(session.Feature{
-			KeepAliveDuration: r.KeepAliveDuration,
-		})
+		WithFeatures(feature)
 	cluster, err := clusterutilv1.GetClusterFromMetadata(r.ControllerContext, r.Client, vsphereVM.ObjectMeta)
 	if err != nil {
 		r.Logger.Info("VsphereVM is missing cluster label or cluster does not exist")
-		return session.GetOrCreate(r.Context,
+		return session.GetOrCreate(sessionCtx,
 			params)
 	}
 
@@ -399,21 +521,22 @@ func (r *vmReconciler) retrieveVcenterSession(ctx goctx.Context, vsphereVM *infr
 	err = r.Client.Get(r, key, vsphereCluster)
 	if err != nil {
 		r.Logger.Info("VSphereCluster couldn't be retrieved")
-		return session.GetOrCreate(r.Context,
+		return session.GetOrCreate(sessionCtx,
 			params)
 	}
 
-	if vsphereCluster.Spec.IdentityRef != nil {
-		creds, err := identity.GetCredentials(ctx, r.Client, vsphereCluster, r.Namespace)
+	// The VMs of the additional vCenters of the cluster are reconciled with
+	// the identity of their vCenter.
+	if identity.IdentityRefForServer(vsphereCluster, vsphereVM.Spec.Server) != nil {
+		creds, err := identity.GetCredentialsForServer(ctx, r.Client, vsphereCluster, vsphereVM.Spec.Server, r.Namespace)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to retrieve credentials from IdentityRef")
 		}
-		params = params.WithUserInfo(creds.Username, creds.Password)
-		return session.GetOrCreate(r.Context,
-			params)
+		params = params.WithUserInfo(creds.Username, creds.Password).
+			WithConnection(creds.Connection).
+			WithFeatures(feature.WithRateLimit(creds.RateLimit).WithTLSPolicy(creds.TLS))
 	}
-
-	// Fallback to using credentials provided to the manager
-	return session.GetOrCreate(r.Context,
-		params)
+	// The credentials provided to the manager are used without identity.
+	return session.GetOrCreate(sessionCtx,
+		params.WithConnection(vsphereCluster.Spec.Connection))
 }
@@ -18,24 +18,34 @@ package controllers
 
 import (
 	goctx "context"
+	"fmt"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
 	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	apirecord "k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/identity"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services"
 	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
 )
 
@@ -178,6 +188,11 @@ func TestVmReconciler_WaitingForStaticIPAllocation(t *testing.T) {
 			},
 			shouldWait: true,
 		},
+		{
+			name:       "for one n/w device with SLAAC set to true",
+			devices:    []infrav1.NetworkDeviceSpec{{SLAAC: true, NetworkName: "nw-1"}},
+			shouldWait: false,
+		},
 		{
 			name: "for multiple n/w devices with DHCP4, DHCP6 & IP address unset",
 			devices: []infrav1.NetworkDeviceSpec{
@@ -204,6 +219,52 @@ func TestVmReconciler_WaitingForStaticIPAllocation(t *testing.T) {
 	}
 }
 
+func TestMissingIPFamilies(t *testing.T) {
+	tests := []struct {
+		name      string
+		devices   []infrav1.NetworkDeviceSpec
+		addresses []string
+		missing   []string
+	}{
+		{
+			name:      "single-stack IPv4 with an IPv4 address",
+			devices:   []infrav1.NetworkDeviceSpec{{DHCP4: true}},
+			addresses: []string{"192.168.1.2"},
+		},
+		{
+			name:      "dual-stack with an IPv4 address",
+			devices:   []infrav1.NetworkDeviceSpec{{DHCP4: true, SLAAC: true}},
+			addresses: []string{"192.168.1.2"},
+			missing:   []string{"IPv6"},
+		},
+		{
+			name:      "dual-stack with static IPv6 address and no address",
+			devices:   []infrav1.NetworkDeviceSpec{{DHCP4: true, IPAddrs: []string{"fd00::2/64"}}},
+			addresses: nil,
+			missing:   []string{"IPv4", "IPv6"},
+		},
+		{
+			name: "dual-stack on separate devices with both addresses",
+			devices: []infrav1.NetworkDeviceSpec{
+				{IPAddrs: []string{"192.168.1.2/24"}},
+				{DHCP6: true},
+			},
+			addresses: []string{"192.168.1.2", "fd00::2"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			vm := &infrav1.VSphereVM{}
+			vm.Spec.Network.Devices = tt.devices
+			vm.Status.Addresses = tt.addresses
+			g.Expect(missingIPFamilies(vm)).To(Equal(tt.missing))
+		})
+	}
+}
+
 func TestRetrievingVCenterCredentialsFromCluster(t *testing.T) {
 	// initializing a fake server to replace the vSphere endpoint
 	model := simulator.VPX()
@@ -320,3 +381,313 @@ func TestRetrievingVCenterCredentialsFromCluster(t *testing.T) {
 	vCenterCondition := conditions.Get(vm, infrav1.VCenterAvailableCondition)
 	g.Expect(vCenterCondition.Status).To(Equal(corev1.ConditionTrue))
 }
+
+func TestRequeueOnError(t *testing.T) {
+	logger := log.Log
+	newVM := func(name string) *infrav1.VSphereVM {
+		return &infrav1.VSphereVM{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, Generation: 1}}
+	}
+
+	t.Run("transient errors back off exponentially", func(t *testing.T) {
+		g := NewWithT(t)
+		vm := newVM("transient")
+		recorder := record.New(apirecord.NewFakeRecorder(10))
+		defer vmErrorBackoff.reset(vm)
+
+		err := soap.WrapVimFault(&types.NotAuthenticated{})
+		result, reterr := requeueOnError(vm, recorder, logger, reconcile.Result{}, err)
+		g.Expect(reterr).NotTo(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(transientErrorBackoff))
+		result, _ = requeueOnError(vm, recorder, logger, reconcile.Result{}, err)
+		g.Expect(result.RequeueAfter).To(Equal(2 * transientErrorBackoff))
+
+		// The VSphereVM is not reconciled again before the backoff expires,
+		// unless its spec changes.
+		g.Expect(vmErrorBackoff.remaining(vm)).To(BeNumerically(">", transientErrorBackoff))
+		vm.Generation++
+		g.Expect(vmErrorBackoff.remaining(vm)).To(BeZero())
+
+		// A successful reconciliation resets the backoff.
+		_, reterr = requeueOnError(vm, recorder, logger, reconcile.Result{}, nil)
+		g.Expect(reterr).NotTo(HaveOccurred())
+		result, _ = requeueOnError(vm, recorder, logger, reconcile.Result{}, err)
+		g.Expect(result.RequeueAfter).To(Equal(transientErrorBackoff))
+	})
+
+	t.Run("quota errors back off longer", func(t *testing.T) {
+		g := NewWithT(t)
+		vm := newVM("quota")
+		recorder := record.New(apirecord.NewFakeRecorder(10))
+		defer vmErrorBackoff.reset(vm)
+
+		result, reterr := requeueOnError(vm, recorder, logger, reconcile.Result{}, soap.WrapVimFault(&types.NoDiskSpace{}))
+		g.Expect(reterr).NotTo(HaveOccurred())
+		g.Expect(result.RequeueAfter).To(Equal(quotaErrorBackoff))
+		g.Expect(conditions.GetReason(vm, infrav1.VMProvisionedCondition)).To(Equal(infrav1.InsufficientResourcesReason))
+		g.Expect(vm.Status.FailureReason).To(BeNil())
+	})
+
+	t.Run("permanent errors fail the VSphereVM", func(t *testing.T) {
+		g := NewWithT(t)
+		vm := newVM("permanent")
+		events := apirecord.NewFakeRecorder(10)
+
+		result, reterr := requeueOnError(vm, record.New(events), logger, reconcile.Result{}, soap.WrapVimFault(&types.InvalidArgument{}))
+		g.Expect(reterr).NotTo(HaveOccurred())
+		g.Expect(result.IsZero()).To(BeTrue())
+		g.Expect(*vm.Status.FailureReason).To(Equal(capierrors.CreateMachineError))
+		g.Expect(vm.Status.FailureMessage).NotTo(BeNil())
+		g.Expect(events.Events).To(HaveLen(1))
+	})
+
+	t.Run("other errors are returned", func(t *testing.T) {
+		g := NewWithT(t)
+		vm := newVM("unknown")
+
+		_, reterr := requeueOnError(vm, record.New(apirecord.NewFakeRecorder(10)), logger, reconcile.Result{}, errors.New("bios uuid is empty"))
+		g.Expect(reterr).To(MatchError("bios uuid is empty"))
+		g.Expect(vm.Status.FailureReason).To(BeNil())
+		g.Expect(vmErrorBackoff.remaining(vm)).To(BeZero())
+	})
+}
+
+func TestReconcileFailure(t *testing.T) {
+	newVMContext := func(name string) (*context.VMContext, *apirecord.FakeRecorder) {
+		events := apirecord.NewFakeRecorder(10)
+		ctx := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+		ctx.Recorder = record.New(events)
+		ctx.VSphereVM.Name = name
+		ctx.VSphereVM.Status.FailureReason = capierrors.MachineStatusErrorPtr(capierrors.CreateMachineError)
+		ctx.VSphereVM.Status.FailureMessage = pointer.StringPtr("datastore full")
+		return ctx, events
+	}
+
+	t.Run("failures are terminal without policy", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx, _ := newVMContext("terminal")
+
+		ok, requeueAfter := reconcileFailure(ctx)
+		g.Expect(ok).To(BeFalse())
+		g.Expect(requeueAfter).To(BeZero())
+	})
+
+	t.Run("failures are retried on request", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx, events := newVMContext("requested")
+		ctx.VSphereVM.Annotations = map[string]string{infrav1.VMRetryFailureAnnotation: ""}
+		ctx.VSphereVM.Status.FailureRetries = 2
+
+		ok, _ := reconcileFailure(ctx)
+		g.Expect(ok).To(BeTrue())
+		g.Expect(ctx.VSphereVM.Annotations).NotTo(HaveKey(infrav1.VMRetryFailureAnnotation))
+		g.Expect(ctx.VSphereVM.Status.FailureReason).To(BeNil())
+		g.Expect(ctx.VSphereVM.Status.FailureRetries).To(BeZero())
+		g.Expect(<-events.Events).To(Equal("Normal FailureRetried Retrying failed VM on request: datastore full"))
+	})
+
+	t.Run("failures are retried according to the policy", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx, events := newVMContext("policy")
+		defer vmFailureRetryBackoff.reset(ctx.VSphereVM)
+		ctx.VSphereVM.Spec.FailureRetryPolicy = &infrav1.VirtualMachineFailureRetryPolicy{
+			Reasons:     []capierrors.MachineStatusError{capierrors.CreateMachineError},
+			MaxAttempts: 1,
+			Interval:    &metav1.Duration{Duration: time.Hour},
+		}
+
+		// The failure is retried once the interval has elapsed, or right away
+		// when the spec changes.
+		ok, requeueAfter := reconcileFailure(ctx)
+		g.Expect(ok).To(BeFalse())
+		g.Expect(requeueAfter).To(Equal(time.Hour))
+		ok, requeueAfter = reconcileFailure(ctx)
+		g.Expect(ok).To(BeFalse())
+		g.Expect(requeueAfter).To(BeNumerically("~", time.Hour, time.Minute))
+
+		ctx.VSphereVM.Generation++
+		ok, _ = reconcileFailure(ctx)
+		g.Expect(ok).To(BeTrue())
+		g.Expect(ctx.VSphereVM.Status.FailureReason).To(BeNil())
+		g.Expect(ctx.VSphereVM.Status.FailureRetries).To(Equal(int32(1)))
+		g.Expect(<-events.Events).To(Equal("Normal FailureRetried Retrying failed VM (attempt 1 of 1): datastore full"))
+
+		// The failure is terminal once the attempts are exhausted.
+		ctx.VSphereVM.Status.FailureReason = capierrors.MachineStatusErrorPtr(capierrors.CreateMachineError)
+		ok, requeueAfter = reconcileFailure(ctx)
+		g.Expect(ok).To(BeFalse())
+		g.Expect(requeueAfter).To(BeZero())
+	})
+
+	t.Run("failures with other reasons are terminal", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx, _ := newVMContext("other-reason")
+		ctx.VSphereVM.Spec.FailureRetryPolicy = &infrav1.VirtualMachineFailureRetryPolicy{
+			Reasons:     []capierrors.MachineStatusError{capierrors.UpdateMachineError},
+			MaxAttempts: 3,
+		}
+
+		ok, requeueAfter := reconcileFailure(ctx)
+		g.Expect(ok).To(BeFalse())
+		g.Expect(requeueAfter).To(BeZero())
+	})
+}
+
+func TestReconcileAddressWait(t *testing.T) {
+	newVMContext := func(action infrav1.NetworkAddressWaitAction) (*context.VMContext, *apirecord.FakeRecorder) {
+		events := apirecord.NewFakeRecorder(10)
+		ctx := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+		ctx.Recorder = record.New(events)
+		ctx.VSphereVM.Spec.Network = infrav1.NetworkSpec{
+			Devices: []infrav1.NetworkDeviceSpec{
+				{NetworkName: "primary", DHCP4: true},
+				{NetworkName: "storage", IPAddrs: []string{"10.0.0.10/24"}},
+			},
+			AddressWait: &infrav1.NetworkAddressWaitPolicy{
+				Timeout: metav1.Duration{Duration: 5 * time.Minute},
+				Action:  action,
+			},
+		}
+		ctx.VSphereVM.Status.Network = []infrav1.NetworkStatus{{}, {IPAddrs: []string{"10.0.0.10"}}}
+		ctx.VSphereVM.Status.Addresses = []string{"10.0.0.10"}
+		return ctx, events
+	}
+	timeOut := func(ctx *context.VMContext) {
+		ctx.VSphereVM.Status.AddressWaitStartTime = &metav1.Time{Time: time.Now().Add(-10 * time.Minute)}
+	}
+
+	t.Run("waits for the addresses of the DHCP devices until the timeout", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx, _ := newVMContext(infrav1.NetworkAddressWaitActionFail)
+
+		g.Expect(reconcileAddressWait(ctx)).To(BeFalse())
+		g.Expect(ctx.VSphereVM.Status.AddressWaitStartTime).NotTo(BeNil())
+		g.Expect(conditions.GetReason(ctx.VSphereVM, infrav1.IPAssignedCondition)).To(Equal(infrav1.WaitingForNetworkAddressesReason))
+		g.Expect(conditions.GetMessage(ctx.VSphereVM, infrav1.IPAssignedCondition)).To(HavePrefix("waiting for addresses of network devices 0 for "))
+		g.Expect(ctx.VSphereVM.Status.FailureReason).To(BeNil())
+
+		ctx.VSphereVM.Status.Network[0].IPAddrs = []string{"192.168.0.10"}
+		ctx.VSphereVM.Status.Addresses = []string{"192.168.0.10", "10.0.0.10"}
+		g.Expect(reconcileAddressWait(ctx)).To(BeTrue())
+		g.Expect(ctx.VSphereVM.Status.AddressWaitStartTime).To(BeNil())
+	})
+
+	t.Run("fails the VM once timed out", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx, events := newVMContext(infrav1.NetworkAddressWaitActionFail)
+		timeOut(ctx)
+
+		g.Expect(reconcileAddressWait(ctx)).To(BeFalse())
+		g.Expect(*ctx.VSphereVM.Status.FailureReason).To(Equal(capierrors.CreateMachineError))
+		g.Expect(*ctx.VSphereVM.Status.FailureMessage).To(Equal("waiting for addresses of network devices 0 for more than 5m0s"))
+		g.Expect(*conditions.GetSeverity(ctx.VSphereVM, infrav1.IPAssignedCondition)).To(Equal(clusterv1.ConditionSeverityError))
+		g.Expect(<-events.Events).To(Equal("Warning NetworkAddressesTimedOut Failing VM: waiting for addresses of network devices 0 for more than 5m0s"))
+	})
+
+	t.Run("falls back to the addresses of the other devices once timed out", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx, events := newVMContext(infrav1.NetworkAddressWaitActionFallback)
+		timeOut(ctx)
+
+		g.Expect(reconcileAddressWait(ctx)).To(BeTrue())
+		g.Expect(ctx.VSphereVM.Status.AddressWaitStartTime).To(BeNil())
+		g.Expect(<-events.Events).To(HavePrefix("Normal NetworkAddressesTimedOut Stopped waiting for addresses of network devices 0 after "))
+
+		// There is nothing to fall back to without any address.
+		ctx.VSphereVM.Status.Addresses = nil
+		timeOut(ctx)
+		g.Expect(reconcileAddressWait(ctx)).To(BeFalse())
+		g.Expect(conditions.GetReason(ctx.VSphereVM, infrav1.IPAssignedCondition)).To(Equal(infrav1.NetworkAddressesTimedOutReason))
+	})
+
+	t.Run("keeps waiting once timed out", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx, events := newVMContext("")
+		timeOut(ctx)
+
+		g.Expect(reconcileAddressWait(ctx)).To(BeFalse())
+		g.Expect(ctx.VSphereVM.Status.FailureReason).To(BeNil())
+		g.Expect(conditions.GetReason(ctx.VSphereVM, infrav1.IPAssignedCondition)).To(Equal(infrav1.NetworkAddressesTimedOutReason))
+		g.Expect(*conditions.GetSeverity(ctx.VSphereVM, infrav1.IPAssignedCondition)).To(Equal(clusterv1.ConditionSeverityWarning))
+		g.Expect(<-events.Events).To(Equal("Warning NetworkAddressesTimedOut Still waiting for addresses of network devices 0 after 5m0s"))
+	})
+
+	t.Run("ready VMs only wait for any address", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx, _ := newVMContext(infrav1.NetworkAddressWaitActionFail)
+		ctx.VSphereVM.Status.Ready = true
+
+		g.Expect(reconcileAddressWait(ctx)).To(BeTrue())
+	})
+}
+
+// fakeIPAllocator allocates the addresses of a /24 network to hosts.
+type fakeIPAllocator struct {
+	allocated map[string]string
+	released  []string
+}
+
+func (a *fakeIPAllocator) AllocateIP(_ goctx.Context, hostname string) (string, string, error) {
+	ref := "host/" + hostname
+	if _, ok := a.allocated[ref]; !ok {
+		a.allocated[ref] = fmt.Sprintf("192.168.10.%d/24", 10+len(a.allocated))
+	}
+	return a.allocated[ref], ref, nil
+}
+
+func (a *fakeIPAllocator) ReleaseIP(_ goctx.Context, ref string) error {
+	delete(a.allocated, ref)
+	a.released = append(a.released, ref)
+	return nil
+}
+
+func TestIPAllocations(t *testing.T) {
+	g := NewWithT(t)
+	allocator := &fakeIPAllocator{allocated: map[string]string{}}
+	defer func(f func(goctx.Context, ctrlclient.Client, *infrav1.VSphereIPPool) (services.IPAllocator, error)) {
+		newIPAllocator = f
+	}(newIPAllocator)
+	newIPAllocator = func(goctx.Context, ctrlclient.Client, *infrav1.VSphereIPPool) (services.IPAllocator, error) {
+		return allocator, nil
+	}
+
+	pool := &infrav1.VSphereIPPool{
+		ObjectMeta: metav1.ObjectMeta{Namespace: fake.Namespace, Name: "infoblox"},
+		Spec: infrav1.VSphereIPPoolSpec{
+			Infoblox:    &infrav1.InfobloxIPPoolSpec{Server: "infoblox.local", SecretName: "infoblox", Network: "192.168.10.0/24"},
+			Gateway:     "192.168.10.1",
+			Nameservers: []string{"192.168.10.2"},
+		},
+	}
+	ctx := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext(pool)))
+	ctx.VSphereVM.Spec.Network.Devices = []infrav1.NetworkDeviceSpec{
+		{NetworkName: "vm-network", DHCP4: true},
+		{NetworkName: "storage", IPPool: "infoblox"},
+		{NetworkName: "backup", IPPool: "infoblox", Gateway4: "192.168.20.1"},
+	}
+
+	// The addresses are allocated to the devices referencing a pool, along
+	// with the gateway and nameservers of the pool.
+	g.Expect(reconcileIPAllocations(ctx)).To(Succeed())
+	devices := ctx.VSphereVM.Spec.Network.Devices
+	g.Expect(devices[0].IPAddrs).To(BeEmpty())
+	g.Expect(devices[1].IPAddrs).To(Equal([]string{"192.168.10.10/24"}))
+	g.Expect(devices[1].Gateway4).To(Equal("192.168.10.1"))
+	g.Expect(devices[1].Nameservers).To(Equal([]string{"192.168.10.2"}))
+	g.Expect(devices[2].IPAddrs).To(Equal([]string{"192.168.10.11/24"}))
+	g.Expect(devices[2].Gateway4).To(Equal("192.168.20.1"))
+	g.Expect(ctx.VSphereVM.Status.IPAllocations).To(ConsistOf(
+		infrav1.IPAllocation{Device: 1, Pool: "infoblox", Address: "192.168.10.10/24", Ref: "host/" + ctx.VSphereVM.Name},
+		infrav1.IPAllocation{Device: 2, Pool: "infoblox", Address: "192.168.10.11/24", Ref: "host/" + ctx.VSphereVM.Name + "-2"},
+	))
+
+	// The recorded allocations are set again on devices which lost them.
+	ctx.VSphereVM.Spec.Network.Devices[1].IPAddrs = nil
+	g.Expect(reconcileIPAllocations(ctx)).To(Succeed())
+	g.Expect(ctx.VSphereVM.Spec.Network.Devices[1].IPAddrs).To(Equal([]string{"192.168.10.10/24"}))
+	g.Expect(allocator.allocated).To(HaveLen(2))
+
+	// The addresses are released once the VM is deleted.
+	g.Expect(releaseIPAllocations(ctx)).To(Succeed())
+	g.Expect(allocator.released).To(HaveLen(2))
+	g.Expect(ctx.VSphereVM.Status.IPAllocations).To(BeEmpty())
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+// vmFailureRetryBackoff tracks the failed VSphereVMs waiting for the interval
+// of their FailureRetryPolicy before being retried.
+var vmFailureRetryBackoff = newRequeueBackoff()
+
+// reconcileFailure returns whether the VSphereVM is to be reconciled, or the
+// time left before it is retried if it is failed. The failure is cleared when
+// it is retried, either on request with the VMRetryFailureAnnotation or
+// according to the FailureRetryPolicy of the VSphereVM once its interval has
+// elapsed.
+func reconcileFailure(ctx *context.VMContext) (bool, time.Duration) {
+	vm := ctx.VSphereVM
+	_, requested := vm.Annotations[infrav1.VMRetryFailureAnnotation]
+	delete(vm.Annotations, infrav1.VMRetryFailureAnnotation)
+	if vm.Status.FailureReason == nil && vm.Status.FailureMessage == nil {
+		return true, 0
+	}
+
+	if requested {
+		ctx.Recorder.Eventf(vm, "FailureRetried", "Retrying failed VM on request: %s", failureMessage(vm))
+		vm.Status.FailureRetries = 0
+		clearFailure(vm)
+		return true, 0
+	}
+
+	if !util.IsFailureRetried(vm) {
+		return false, 0
+	}
+
+	// Wait for the interval of the policy before retrying, unless the spec of
+	// the VSphereVM changes in the meantime.
+	if !vmFailureRetryBackoff.has(vm) {
+		interval := infrav1.DefaultFailureRetryInterval
+		if vm.Spec.FailureRetryPolicy.Interval != nil {
+			interval = vm.Spec.FailureRetryPolicy.Interval.Duration
+		}
+		return false, vmFailureRetryBackoff.next(vm, interval, interval)
+	}
+	if remaining := vmFailureRetryBackoff.remaining(vm); remaining > 0 {
+		return false, remaining
+	}
+	vmFailureRetryBackoff.reset(vm)
+
+	vm.Status.FailureRetries++
+	ctx.Recorder.Eventf(vm, "FailureRetried", "Retrying failed VM (attempt %d of %d): %s",
+		vm.Status.FailureRetries, vm.Spec.FailureRetryPolicy.MaxAttempts, failureMessage(vm))
+	clearFailure(vm)
+	return true, 0
+}
+
+func failureMessage(vm *infrav1.VSphereVM) string {
+	if vm.Status.FailureMessage != nil {
+		return *vm.Status.FailureMessage
+	}
+	return string(*vm.Status.FailureReason)
+}
+
+func clearFailure(vm *infrav1.VSphereVM) {
+	vm.Status.FailureReason = nil
+	vm.Status.FailureMessage = nil
+}
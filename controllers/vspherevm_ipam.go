@@ -0,0 +1,139 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/ipam"
+)
+
+// newIPAllocator returns the IPAllocator of a VSphereIPPool.
+var newIPAllocator = ipam.NewAllocator
+
+// reconcileIPAllocations allocates an address to the network devices of the
+// VSphereVM referencing a VSphereIPPool which have none, and sets it in the
+// spec of the device along with the gateway and nameservers of the pool. The
+// allocations are recorded in the status of the VSphereVM, so they are kept
+// until the VM is deleted.
+func reconcileIPAllocations(ctx *context.VMContext) error {
+	vm := ctx.VSphereVM
+	for i := range vm.Spec.Network.Devices {
+		dev := &vm.Spec.Network.Devices[i]
+		if dev.IPPool == "" || len(dev.IPAddrs) > 0 {
+			continue
+		}
+		pool := &infrav1.VSphereIPPool{}
+		poolKey := client.ObjectKey{Namespace: vm.Namespace, Name: dev.IPPool}
+		if err := ctx.Client.Get(ctx, poolKey, pool); err != nil {
+			return errors.Wrapf(err, "failed to get VSphereIPPool %s of network device %d", poolKey, i)
+		}
+
+		allocation := findIPAllocation(vm, i)
+		if allocation == nil {
+			allocator, err := newIPAllocator(ctx, ctx.Client, pool)
+			if err != nil {
+				return err
+			}
+			address, ref, err := allocator.AllocateIP(ctx, ipAllocationHostname(vm, i))
+			if err != nil {
+				return errors.Wrapf(err, "failed to allocate an address to network device %d from VSphereIPPool %s", i, poolKey)
+			}
+			vm.Status.IPAllocations = append(vm.Status.IPAllocations, infrav1.IPAllocation{
+				Device:  int32(i),
+				Pool:    pool.Name,
+				Address: address,
+				Ref:     ref,
+			})
+			allocation = &vm.Status.IPAllocations[len(vm.Status.IPAllocations)-1]
+			ctx.Recorder.Eventf(vm, "IPAllocated", "Allocated address %s to network device %d from VSphereIPPool %s", address, i, pool.Name)
+		}
+
+		dev.IPAddrs = []string{allocation.Address}
+		if dev.Gateway4 == "" {
+			dev.Gateway4 = pool.Spec.Gateway
+		}
+		if len(dev.Nameservers) == 0 {
+			dev.Nameservers = pool.Spec.Nameservers
+		}
+	}
+	return nil
+}
+
+// releaseIPAllocations releases the addresses allocated to the network
+// devices of the VSphereVM once its VM is deleted. The allocations from pools
+// which no longer exist are dropped.
+func releaseIPAllocations(ctx *context.VMContext) error {
+	vm := ctx.VSphereVM
+	var remaining []infrav1.IPAllocation
+	var errs []error
+	for _, allocation := range vm.Status.IPAllocations {
+		if err := releaseIPAllocation(ctx, allocation); err != nil {
+			remaining = append(remaining, allocation)
+			errs = append(errs, err)
+			continue
+		}
+		ctx.Recorder.Eventf(vm, "IPReleased", "Released address %s of network device %d to VSphereIPPool %s", allocation.Address, allocation.Device, allocation.Pool)
+	}
+	vm.Status.IPAllocations = remaining
+	return kerrors.NewAggregate(errs)
+}
+
+func releaseIPAllocation(ctx *context.VMContext, allocation infrav1.IPAllocation) error {
+	pool := &infrav1.VSphereIPPool{}
+	poolKey := client.ObjectKey{Namespace: ctx.VSphereVM.Namespace, Name: allocation.Pool}
+	if err := ctx.Client.Get(ctx, poolKey, pool); err != nil {
+		if apierrors.IsNotFound(err) {
+			ctx.Logger.Info("VSphereIPPool not found, dropping the allocation of the address", "pool", poolKey, "address", allocation.Address)
+			return nil
+		}
+		return errors.Wrapf(err, "failed to get VSphereIPPool %s", poolKey)
+	}
+	allocator, err := newIPAllocator(ctx, ctx.Client, pool)
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(allocator.ReleaseIP(ctx, allocation.Ref), "failed to release address %s to VSphereIPPool %s", allocation.Address, poolKey)
+}
+
+func findIPAllocation(vm *infrav1.VSphereVM, device int) *infrav1.IPAllocation {
+	for i := range vm.Status.IPAllocations {
+		if vm.Status.IPAllocations[i].Device == int32(device) {
+			return &vm.Status.IPAllocations[i]
+		}
+	}
+	return nil
+}
+
+// ipAllocationHostname returns the name the address of the network device is
+// allocated to, which is the name of the VM for the first device allocated
+// from a pool, and <VM name>-<device index> for the other ones.
+func ipAllocationHostname(vm *infrav1.VSphereVM, device int) string {
+	for i := 0; i < device; i++ {
+		if vm.Spec.Network.Devices[i].IPPool != "" {
+			return fmt.Sprintf("%s-%d", vm.Name, device)
+		}
+	}
+	return vm.Name
+}
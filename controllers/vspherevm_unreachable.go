@@ -0,0 +1,121 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	goctx "context"
+	"time"
+
+	"github.com/pkg/errors"
+	clusterutilv1 "sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+)
+
+// reconcileDeleteUnreachable handles a VSphereVM being deleted whose vCenter
+// cannot be reached. The VCenterAvailable condition is patched, so that its
+// last transition records since when the vCenter is unreachable across
+// restarts of the manager. Once the vCenter has been unreachable for longer
+// than the VCenterUnreachableTimeout, and the force cleanup is enabled, the
+// addresses of the VSphereVM are released and its finalizer removed without
+// destroying its VM, which may be left behind in vCenter.
+func (r vmReconciler) reconcileDeleteUnreachable(ctx goctx.Context, vsphereVM *infrav1.VSphereVM, patchHelper *patch.Helper, sessionErr error) (reconcile.Result, error) {
+	var unreachable time.Duration
+	if since := conditions.GetLastTransitionTime(vsphereVM, infrav1.VCenterAvailableCondition); since != nil {
+		unreachable = time.Since(since.Time)
+	}
+
+	vsphereCluster, enabled := r.forceCleanupEnabled(ctx, vsphereVM)
+	if !enabled || r.VCenterUnreachableTimeout <= 0 || !ctrlutil.ContainsFinalizer(vsphereVM, infrav1.VMFinalizer) {
+		if err := patchHelper.Patch(ctx, vsphereVM); err != nil {
+			return reconcile.Result{}, err
+		}
+		return requeueOnError(vsphereVM, r.Recorder, r.Logger, reconcile.Result{}, sessionErr)
+	}
+
+	if remaining := r.VCenterUnreachableTimeout - unreachable; remaining > 0 {
+		if err := patchHelper.Patch(ctx, vsphereVM); err != nil {
+			return reconcile.Result{}, err
+		}
+		result, err := requeueOnError(vsphereVM, r.Recorder, r.Logger, reconcile.Result{}, sessionErr)
+		if err == nil && (result.RequeueAfter == 0 || result.RequeueAfter > remaining) {
+			result.RequeueAfter = remaining
+		}
+		return result, err
+	}
+
+	vmCtx := &context.VMContext{
+		ControllerContext: r.ControllerContext,
+		VSphereVM:         vsphereVM,
+		Logger:            r.Logger.WithName(vsphereVM.Namespace).WithName(vsphereVM.Name),
+		PatchHelper:       patchHelper,
+		RequestContext:    ctx,
+	}
+	if err := releaseIPAllocations(vmCtx); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	vmCtx.Logger.Info("vCenter unreachable, removing the finalizer of VSphereVM without destroying its VM",
+		"server", vsphereVM.Spec.Server, "biosUUID", vsphereVM.Spec.BiosUUID, "unreachableFor", unreachable.Round(time.Second), "error", sessionErr.Error())
+	ctrlutil.RemoveFinalizer(vsphereVM, infrav1.VMFinalizer)
+	if err := patchHelper.Patch(ctx, vsphereVM); err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "failed to remove the finalizer of VSphereVM %s/%s", vsphereVM.Namespace, vsphereVM.Name)
+	}
+	vmErrorBackoff.reset(vsphereVM)
+	vmPollBackoff.reset(vsphereVM)
+	vmFailureRetryBackoff.reset(vsphereVM)
+
+	r.Recorder.Warnf(vsphereVM, "InfrastructureOrphaned",
+		"Removed the finalizer without destroying VM with BIOS UUID %q, as vCenter %s has been unreachable for %s: the VM may be left behind",
+		vsphereVM.Spec.BiosUUID, vsphereVM.Spec.Server, unreachable.Round(time.Second))
+	if vsphereCluster != nil {
+		r.Recorder.Warnf(vsphereCluster, "InfrastructureOrphaned",
+			"Removed the finalizer of VSphereVM %s without destroying VM with BIOS UUID %q, as vCenter %s has been unreachable for %s: the VM may be left behind",
+			vsphereVM.Name, vsphereVM.Spec.BiosUUID, vsphereVM.Spec.Server, unreachable.Round(time.Second))
+	}
+	return reconcile.Result{}, nil
+}
+
+// forceCleanupEnabled returns the VSphereCluster of the VSphereVM, if found,
+// and whether the VSphereVM is released once its vCenter has been unreachable
+// for longer than the VCenterUnreachableTimeout, i.e. the manager skips the
+// deletion of the infrastructure of unreachable vCenters or the VSphereCluster
+// has the force-cleanup annotation.
+func (r vmReconciler) forceCleanupEnabled(ctx goctx.Context, vsphereVM *infrav1.VSphereVM) (*infrav1.VSphereCluster, bool) {
+	var vsphereCluster *infrav1.VSphereCluster
+	if cluster, err := clusterutilv1.GetClusterFromMetadata(ctx, r.Client, vsphereVM.ObjectMeta); err == nil && cluster.Spec.InfrastructureRef != nil {
+		vsphereCluster = &infrav1.VSphereCluster{}
+		key := ctrlclient.ObjectKey{Namespace: cluster.Namespace, Name: cluster.Spec.InfrastructureRef.Name}
+		if err := r.Client.Get(ctx, key, vsphereCluster); err != nil {
+			vsphereCluster = nil
+		}
+	}
+	if r.SkipInfraDeletionOnUnreachable {
+		return vsphereCluster, true
+	}
+	if vsphereCluster == nil {
+		return nil, false
+	}
+	_, ok := vsphereCluster.Annotations[infrav1.ForceCleanupAnnotation]
+	return vsphereCluster, ok
+}
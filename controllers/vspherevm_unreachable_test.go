@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+)
+
+func TestVMReconciler_DeleteUnreachable(t *testing.T) {
+	tests := []struct {
+		name               string
+		skipInfraDeletion  bool
+		forceCleanup       bool
+		unreachableFor     time.Duration
+		expectedFinalizers bool
+	}{
+		{
+			name:               "force cleanup disabled",
+			unreachableFor:     2 * time.Hour,
+			expectedFinalizers: true,
+		},
+		{
+			name:               "unreachable for less than the timeout",
+			skipInfraDeletion:  true,
+			unreachableFor:     30 * time.Minute,
+			expectedFinalizers: true,
+		},
+		{
+			name:              "manager skips the deletion of the infrastructure",
+			skipInfraDeletion: true,
+			unreachableFor:    2 * time.Hour,
+		},
+		{
+			name:           "VSphereCluster has the force-cleanup annotation",
+			forceCleanup:   true,
+			unreachableFor: 2 * time.Hour,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext())
+			controllerCtx.SkipInfraDeletionOnUnreachable = tc.skipInfraDeletion
+			controllerCtx.VCenterUnreachableTimeout = time.Hour
+			ctx := fake.NewClusterContext(controllerCtx)
+			ctx.Cluster.Spec.InfrastructureRef = &corev1.ObjectReference{Kind: "VSphereCluster", Name: ctx.VSphereCluster.Name}
+			g.Expect(ctx.Client.Update(ctx, ctx.Cluster)).To(Succeed())
+			if tc.forceCleanup {
+				ctx.VSphereCluster.Annotations = map[string]string{infrav1.ForceCleanupAnnotation: ""}
+				g.Expect(ctx.Client.Update(ctx, ctx.VSphereCluster)).To(Succeed())
+			}
+
+			vsphereVM := &infrav1.VSphereVM{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:  ctx.Cluster.Namespace,
+					Name:       "machine-0",
+					Labels:     map[string]string{clusterv1.ClusterLabelName: ctx.Cluster.Name},
+					Finalizers: []string{infrav1.VMFinalizer},
+				},
+				Spec: infrav1.VSphereVMSpec{BiosUUID: "42000000-0000-0000-0000-000000000000"},
+			}
+			g.Expect(ctx.Client.Create(ctx, vsphereVM)).To(Succeed())
+			g.Expect(ctx.Client.Delete(ctx, vsphereVM)).To(Succeed())
+			g.Expect(ctx.Client.Get(ctx, client.ObjectKeyFromObject(vsphereVM), vsphereVM)).To(Succeed())
+			patchHelper, err := patch.NewHelper(vsphereVM, ctx.Client)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			sessionErr := errors.New("dial tcp: connect: connection refused")
+			conditions.Set(vsphereVM, &clusterv1.Condition{
+				Type:               infrav1.VCenterAvailableCondition,
+				Status:             corev1.ConditionFalse,
+				Severity:           clusterv1.ConditionSeverityError,
+				Reason:             infrav1.VCenterUnreachableReason,
+				Message:            sessionErr.Error(),
+				LastTransitionTime: metav1.NewTime(time.Now().Add(-tc.unreachableFor)),
+			})
+
+			r := vmReconciler{controllerCtx}
+			result, _ := r.reconcileDeleteUnreachable(ctx, vsphereVM, patchHelper, sessionErr)
+			defer vmErrorBackoff.reset(vsphereVM)
+
+			err = ctx.Client.Get(ctx, client.ObjectKeyFromObject(vsphereVM), vsphereVM)
+			if !tc.expectedFinalizers {
+				g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(vsphereVM.Finalizers).To(ContainElement(infrav1.VMFinalizer))
+			g.Expect(conditions.IsFalse(vsphereVM, infrav1.VCenterAvailableCondition)).To(BeTrue())
+			if tc.skipInfraDeletion {
+				g.Expect(result.RequeueAfter).To(BeNumerically("<=", time.Hour-tc.unreachableFor))
+			}
+		})
+	}
+}
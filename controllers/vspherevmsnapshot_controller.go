@@ -0,0 +1,353 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	goctx "context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	clusterutilv1 "sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
+)
+
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vspherevmsnapshots,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=vspherevmsnapshots/status,verbs=get;update;patch
+
+// AddVSphereVMSnapshotControllerToManager adds the VSphereVMSnapshot
+// controller, and the controller taking the scheduled snapshots of
+// VSphereVMs, to the provided manager.
+func AddVSphereVMSnapshotControllerToManager(ctx *context.ControllerManagerContext, mgr manager.Manager) error {
+	var (
+		controlledType     = &infrav1.VSphereVMSnapshot{}
+		controlledTypeName = reflect.TypeOf(controlledType).Elem().Name()
+
+		controllerNameShort = fmt.Sprintf("%s-controller", strings.ToLower(controlledTypeName))
+		controllerNameLong  = fmt.Sprintf("%s/%s/%s", ctx.Namespace, ctx.Name, controllerNameShort)
+	)
+
+	// Build the controller context.
+	controllerContext := &context.ControllerContext{
+		ControllerManagerContext: ctx,
+		Name:                     controllerNameShort,
+		Recorder:                 record.New(mgr.GetEventRecorderFor(controllerNameLong)),
+		Logger:                   ctx.Logger.WithName(controllerNameShort),
+	}
+
+	reconciler := snapshotReconciler{
+		ControllerContext: controllerContext,
+		snapshotService:   &govmomi.SnapshotService{},
+	}
+	err := ctrl.NewControllerManagedBy(mgr).
+		// Watch the controlled, infrastructure resource.
+		For(controlledType).
+		// Snapshots waiting for their VM are reconciled once the VM is
+		// created.
+		Watches(
+			&source.Kind{Type: &infrav1.VSphereVM{}},
+			handler.EnqueueRequestsFromMapFunc(reconciler.vsphereVMToSnapshots)).
+		WithOptions(controller.Options{MaxConcurrentReconciles: ctx.MaxConcurrentReconciles}).
+		Complete(tracing.Reconciler(controlledTypeName, reconciler))
+	if err != nil {
+		return err
+	}
+
+	scheduleReconciler := snapshotScheduleReconciler{ControllerContext: controllerContext}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(fmt.Sprintf("%s-schedule", controllerNameShort)).
+		For(&infrav1.VSphereVM{}).
+		Owns(controlledType).
+		WithOptions(controller.Options{MaxConcurrentReconciles: ctx.MaxConcurrentReconciles}).
+		Complete(tracing.Reconciler(controlledTypeName+"Schedule", scheduleReconciler))
+}
+
+type snapshotReconciler struct {
+	*context.ControllerContext
+
+	snapshotService services.VirtualMachineSnapshotService
+}
+
+// Reconcile takes the snapshot of the VM of a VSphereVMSnapshot, and removes
+// it when the VSphereVMSnapshot is deleted.
+func (r snapshotReconciler) Reconcile(ctx goctx.Context, req reconcile.Request) (_ reconcile.Result, reterr error) {
+	snapshot := &infrav1.VSphereVMSnapshot{}
+	if err := r.Client.Get(ctx, req.NamespacedName, snapshot); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Logger.V(4).Info("VSphereVMSnapshot not found, won't reconcile", "key", req.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	patchHelper, err := patch.NewHelper(snapshot, r.Client)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(
+			err,
+			"failed to init patch helper for %s %s/%s",
+			snapshot.GroupVersionKind(),
+			snapshot.Namespace,
+			snapshot.Name)
+	}
+	defer func() {
+		conditions.SetSummary(snapshot, conditions.WithConditions(infrav1.SnapshotReadyCondition))
+
+		if err := patchHelper.Patch(ctx, snapshot); err != nil {
+			if reterr == nil {
+				reterr = err
+			}
+			r.Logger.Error(err, "patch failed", "namespace", snapshot.Namespace, "name", snapshot.Name)
+		}
+	}()
+
+	vsphereVM := &infrav1.VSphereVM{}
+	vmKey := apitypes.NamespacedName{Namespace: snapshot.Namespace, Name: snapshot.Spec.VMName}
+	if err := r.Client.Get(ctx, vmKey, vsphereVM); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		vsphereVM = nil
+	}
+
+	if !snapshot.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, snapshot, vsphereVM)
+	}
+	return r.reconcileNormal(ctx, snapshot, vsphereVM)
+}
+
+func (r snapshotReconciler) reconcileDelete(ctx goctx.Context, snapshot *infrav1.VSphereVMSnapshot, vsphereVM *infrav1.VSphereVM) (reconcile.Result, error) {
+	// The snapshot is removed along with the VM.
+	if vsphereVM != nil && snapshot.Status.SnapshotRef != "" {
+		vmCtx, err := r.getVMContext(ctx, vsphereVM)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if err := r.snapshotService.RemoveSnapshot(vmCtx, snapshot.Status.SnapshotRef); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	ctrlutil.RemoveFinalizer(snapshot, infrav1.SnapshotFinalizer)
+	return reconcile.Result{}, nil
+}
+
+func (r snapshotReconciler) reconcileNormal(ctx goctx.Context, snapshot *infrav1.VSphereVMSnapshot, vsphereVM *infrav1.VSphereVM) (reconcile.Result, error) {
+	ctrlutil.AddFinalizer(snapshot, infrav1.SnapshotFinalizer)
+
+	if snapshot.Status.Ready {
+		return reconcile.Result{}, nil
+	}
+
+	if vsphereVM == nil || vsphereVM.Spec.BiosUUID == "" {
+		r.Logger.Info("Waiting for the VM to be created", "snapshot", snapshot.Name, "vm", snapshot.Spec.VMName)
+		conditions.MarkFalse(snapshot, infrav1.SnapshotReadyCondition, infrav1.SnapshotWaitingForVMReason, clusterv1.ConditionSeverityInfo, "")
+		return reconcile.Result{}, nil
+	}
+
+	// The snapshot is garbage collected along with the VSphereVM.
+	// Scheduled snapshots are already controlled by the VSphereVM.
+	if !isOwnedBy(snapshot, vsphereVM) {
+		snapshot.SetOwnerReferences(clusterutilv1.EnsureOwnerRef(snapshot.OwnerReferences, metav1.OwnerReference{
+			APIVersion: infrav1.GroupVersion.String(),
+			Kind:       "VSphereVM",
+			Name:       vsphereVM.Name,
+			UID:        vsphereVM.UID,
+		}))
+	}
+
+	vmCtx, err := r.getVMContext(ctx, vsphereVM)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	snapshotRef, err := r.snapshotService.CreateSnapshot(vmCtx, snapshot.Name, snapshot.Spec.Description)
+	if err != nil {
+		conditions.MarkFalse(snapshot, infrav1.SnapshotReadyCondition, infrav1.SnapshotCreationFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		return reconcile.Result{}, err
+	}
+	r.Recorder.Eventf(snapshot, "SnapshotCreated", "Created snapshot %s of VM %s", snapshotRef, vsphereVM.Name)
+
+	now := metav1.Now()
+	snapshot.Status.Ready = true
+	snapshot.Status.SnapshotRef = snapshotRef
+	snapshot.Status.CreationTime = &now
+	conditions.MarkTrue(snapshot, infrav1.SnapshotReadyCondition)
+	return reconcile.Result{}, nil
+}
+
+func (r snapshotReconciler) getVMContext(ctx goctx.Context, vsphereVM *infrav1.VSphereVM) (*context.VMContext, error) {
+	authSession, err := (&vmReconciler{ControllerContext: r.ControllerContext}).retrieveVcenterSession(ctx, vsphereVM)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get session for VSphereVM %s/%s", vsphereVM.Namespace, vsphereVM.Name)
+	}
+	return &context.VMContext{
+		ControllerContext: r.ControllerContext,
+		VSphereVM:         vsphereVM,
+		Session:           authSession,
+		Logger:            r.Logger.WithName(vsphereVM.Namespace).WithName(vsphereVM.Name),
+		RequestContext:    ctx,
+	}, nil
+}
+
+func isOwnedBy(obj, owner metav1.Object) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == owner.GetUID() {
+			return true
+		}
+	}
+	return false
+}
+
+// vsphereVMToSnapshots maps a VSphereVM to the VSphereVMSnapshots of its VM.
+func (r snapshotReconciler) vsphereVMToSnapshots(o client.Object) []reconcile.Request {
+	snapshots := &infrav1.VSphereVMSnapshotList{}
+	if err := r.Client.List(r, snapshots, client.InNamespace(o.GetNamespace())); err != nil {
+		r.Logger.Error(err, "failed to list VSphereVMSnapshots", "namespace", o.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, snapshot := range snapshots.Items {
+		if snapshot.Spec.VMName != o.GetName() {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: apitypes.NamespacedName{Namespace: snapshot.Namespace, Name: snapshot.Name},
+		})
+	}
+	return requests
+}
+
+type snapshotScheduleReconciler struct {
+	*context.ControllerContext
+}
+
+// Reconcile creates the scheduled VSphereVMSnapshots of a VSphereVM and
+// prunes the ones exceeding the retention of its schedule.
+func (r snapshotScheduleReconciler) Reconcile(ctx goctx.Context, req reconcile.Request) (reconcile.Result, error) {
+	vsphereVM := &infrav1.VSphereVM{}
+	if err := r.Client.Get(ctx, req.NamespacedName, vsphereVM); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	schedule := vsphereVM.Spec.SnapshotSchedule
+	if schedule == nil || !vsphereVM.DeletionTimestamp.IsZero() || vsphereVM.Spec.BiosUUID == "" {
+		return reconcile.Result{}, nil
+	}
+
+	snapshots := &infrav1.VSphereVMSnapshotList{}
+	if err := r.Client.List(ctx, snapshots,
+		client.InNamespace(vsphereVM.Namespace),
+		client.MatchingLabels{infrav1.SnapshotScheduleLabel: vsphereVM.Name}); err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "failed to list scheduled snapshots of VSphereVM %s/%s", vsphereVM.Namespace, vsphereVM.Name)
+	}
+
+	now := time.Now()
+	create, prune, requeueAfter := scheduleSnapshots(schedule, snapshots.Items, now)
+	for i := range prune {
+		r.Logger.Info("Pruning scheduled snapshot", "snapshot", prune[i].Name, "vm", vsphereVM.Name)
+		if err := r.Client.Delete(ctx, &prune[i]); err != nil && !apierrors.IsNotFound(err) {
+			return reconcile.Result{}, errors.Wrapf(err, "failed to delete VSphereVMSnapshot %s/%s", prune[i].Namespace, prune[i].Name)
+		}
+	}
+	if create {
+		snapshot := &infrav1.VSphereVMSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: vsphereVM.Namespace,
+				Name:      fmt.Sprintf("%s-%s", vsphereVM.Name, now.UTC().Format("20060102150405")),
+				Labels:    map[string]string{infrav1.SnapshotScheduleLabel: vsphereVM.Name},
+			},
+			Spec: infrav1.VSphereVMSnapshotSpec{
+				VMName:      vsphereVM.Name,
+				Description: "Scheduled snapshot taken by Cluster API",
+			},
+		}
+		if err := ctrlutil.SetControllerReference(vsphereVM, snapshot, r.Scheme); err != nil {
+			return reconcile.Result{}, err
+		}
+		r.Logger.Info("Creating scheduled snapshot", "snapshot", snapshot.Name, "vm", vsphereVM.Name)
+		if err := r.Client.Create(ctx, snapshot); err != nil && !apierrors.IsAlreadyExists(err) {
+			return reconcile.Result{}, errors.Wrapf(err, "failed to create VSphereVMSnapshot %s/%s", snapshot.Namespace, snapshot.Name)
+		}
+	}
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// scheduleSnapshots returns whether a scheduled snapshot is due, the
+// scheduled snapshots to prune to keep the retention of the schedule once it
+// is taken, and the time until the next snapshot is due.
+func scheduleSnapshots(schedule *infrav1.VirtualMachineSnapshotSchedule, snapshots []infrav1.VSphereVMSnapshot, now time.Time) (bool, []infrav1.VSphereVMSnapshot, time.Duration) {
+	retention := int(schedule.Retention)
+	if retention <= 0 {
+		retention = infrav1.DefaultSnapshotRetention
+	}
+
+	var existing []infrav1.VSphereVMSnapshot
+	for _, snapshot := range snapshots {
+		if snapshot.DeletionTimestamp.IsZero() {
+			existing = append(existing, snapshot)
+		}
+	}
+	// Newest first.
+	sort.SliceStable(existing, func(i, j int) bool {
+		return existing[j].CreationTimestamp.Before(&existing[i].CreationTimestamp)
+	})
+
+	requeueAfter := schedule.Interval.Duration
+	create := true
+	if len(existing) > 0 {
+		if age := now.Sub(existing[0].CreationTimestamp.Time); age < schedule.Interval.Duration {
+			create = false
+			requeueAfter = schedule.Interval.Duration - age
+		}
+	}
+
+	keep := retention
+	if create {
+		keep--
+	}
+	var prune []infrav1.VSphereVMSnapshot
+	if len(existing) > keep {
+		prune = existing[keep:]
+	}
+	return create, prune, requeueAfter
+}
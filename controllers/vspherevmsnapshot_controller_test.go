@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+func TestScheduleSnapshots(t *testing.T) {
+	now := time.Now()
+	snapshot := func(name string, age time.Duration) infrav1.VSphereVMSnapshot {
+		return infrav1.VSphereVMSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			},
+		}
+	}
+	names := func(snapshots []infrav1.VSphereVMSnapshot) []string {
+		var names []string
+		for _, s := range snapshots {
+			names = append(names, s.Name)
+		}
+		return names
+	}
+	schedule := &infrav1.VirtualMachineSnapshotSchedule{
+		Interval:  metav1.Duration{Duration: 24 * time.Hour},
+		Retention: 2,
+	}
+
+	t.Run("the first snapshot is taken right away", func(t *testing.T) {
+		g := NewWithT(t)
+		create, prune, requeueAfter := scheduleSnapshots(schedule, nil, now)
+		g.Expect(create).To(BeTrue())
+		g.Expect(prune).To(BeEmpty())
+		g.Expect(requeueAfter).To(Equal(24 * time.Hour))
+	})
+
+	t.Run("no snapshot is due within the interval", func(t *testing.T) {
+		g := NewWithT(t)
+		snapshots := []infrav1.VSphereVMSnapshot{snapshot("a", 30*time.Hour), snapshot("b", 6*time.Hour)}
+		create, prune, requeueAfter := scheduleSnapshots(schedule, snapshots, now)
+		g.Expect(create).To(BeFalse())
+		g.Expect(prune).To(BeEmpty())
+		g.Expect(requeueAfter).To(Equal(18 * time.Hour))
+	})
+
+	t.Run("the oldest snapshots are pruned when a snapshot is due", func(t *testing.T) {
+		g := NewWithT(t)
+		snapshots := []infrav1.VSphereVMSnapshot{snapshot("a", 72*time.Hour), snapshot("c", 24*time.Hour), snapshot("b", 48*time.Hour)}
+		create, prune, _ := scheduleSnapshots(schedule, snapshots, now)
+		g.Expect(create).To(BeTrue())
+		g.Expect(names(prune)).To(Equal([]string{"b", "a"}))
+	})
+
+	t.Run("snapshots being deleted are not counted", func(t *testing.T) {
+		g := NewWithT(t)
+		deleted := snapshot("b", time.Hour)
+		deleted.DeletionTimestamp = &metav1.Time{Time: now}
+		snapshots := []infrav1.VSphereVMSnapshot{snapshot("a", 48*time.Hour), deleted}
+		create, prune, _ := scheduleSnapshots(schedule, snapshots, now)
+		g.Expect(create).To(BeTrue())
+		g.Expect(prune).To(BeEmpty())
+	})
+
+	t.Run("the retention defaults to three snapshots", func(t *testing.T) {
+		g := NewWithT(t)
+		snapshots := []infrav1.VSphereVMSnapshot{snapshot("a", 72*time.Hour), snapshot("b", 48*time.Hour), snapshot("c", 24*time.Hour)}
+		create, prune, _ := scheduleSnapshots(&infrav1.VirtualMachineSnapshotSchedule{Interval: schedule.Interval}, snapshots, now)
+		g.Expect(create).To(BeTrue())
+		g.Expect(names(prune)).To(Equal([]string{"a"}))
+	})
+}
@@ -28,6 +28,24 @@ const (
 // // alpha: v1.X
 // MyFeature featuregate.Feature = "MyFeature".
 
+	// NodeAntiAffinity is a feature gate for the DRS anti-affinity rule that keeps
+	// the control plane VMs of a cluster on different ESXi hosts.
+	//
+	// alpha: v1.3
+	NodeAntiAffinity featuregate.Feature = "NodeAntiAffinity"
+
+	// LinkedCloneSnapshotCreation is a feature gate allowing the controller to
+	// create a snapshot of the clone source when a linked clone is requested
+	// and the source has no usable snapshot.
+	//
+	// alpha: v1.3
+	LinkedCloneSnapshotCreation featuregate.Feature = "LinkedCloneSnapshotCreation"
+
+	// MachinePool is a feature gate for the VSphereMachinePools backing the
+	// MachinePools of Cluster API, which must be enabled in Cluster API too.
+	//
+	// alpha: v1.3
+	MachinePool featuregate.Feature = "MachinePool"
 )
 
 func init() {
@@ -38,5 +56,7 @@ func init() {
 // To add a new feature, define a key for it above and add it here.
 var defaultCAPVFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
 	// Every feature should be initiated here:
-
+	NodeAntiAffinity:            {Default: false, PreRelease: featuregate.Alpha},
+	LinkedCloneSnapshotCreation: {Default: false, PreRelease: featuregate.Alpha},
+	MachinePool:                 {Default: false, PreRelease: featuregate.Alpha},
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	goctx "context"
 	"flag"
 	"fmt"
 	"math/rand"
@@ -26,6 +27,7 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/spf13/pflag"
 	"gopkg.in/fsnotify.v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/klog/v2"
@@ -41,9 +43,15 @@ import (
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/constants"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/manager"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/version"
 )
 
+// tracingShutdownTimeout is how long the spans which are not exported yet are
+// flushed for when the controller manager stops.
+const tracingShutdownTimeout = 5 * time.Second
+
 var (
 	setupLog = ctrllog.Log.WithName("entrypoint")
 
@@ -57,6 +65,9 @@ var (
 	defaultWebhookPort       = manager.DefaultWebhookServiceContainerPort
 	defaultEnableKeepAlive   = constants.DefaultEnableKeepAlive
 	defaultKeepAliveDuration = constants.DefaultKeepAliveDuration
+	defaultKeepAliveRetries  = constants.DefaultKeepAliveRetries
+
+	defaultBootstrapDataCompressionThreshold = constants.DefaultBootstrapDataCompressionThreshold
 )
 
 func main() {
@@ -102,6 +113,26 @@ func main() {
 		"max-concurrent-reconciles",
 		10,
 		"The maximum number of allowed, concurrent reconciles.")
+	flag.IntVar(
+		&managerOpts.VSphereVMConcurrency,
+		"vspherevm-concurrency",
+		0,
+		"The maximum number of concurrent reconciles of VSphereVMs (defaults to --max-concurrent-reconciles).")
+	flag.IntVar(
+		&managerOpts.VSphereMachineConcurrency,
+		"vspheremachine-concurrency",
+		0,
+		"The maximum number of concurrent reconciles of VSphereMachines (defaults to --max-concurrent-reconciles).")
+	flag.IntVar(
+		&managerOpts.VSphereClusterConcurrency,
+		"vspherecluster-concurrency",
+		0,
+		"The maximum number of concurrent reconciles of VSphereClusters (defaults to --max-concurrent-reconciles).")
+	flag.IntVar(
+		&managerOpts.VSphereDeploymentZoneConcurrency,
+		"vspheredeploymentzone-concurrency",
+		0,
+		"The maximum number of concurrent reconciles of VSphereDeploymentZones (defaults to --max-concurrent-reconciles).")
 	flag.StringVar(
 		&managerOpts.PodName,
 		"pod-name",
@@ -136,13 +167,182 @@ func main() {
 		defaultKeepAliveDuration,
 		"idle time interval(minutes) in between send() requests in keepalive handler")
 
+	flag.IntVar(
+		&managerOpts.SessionCacheMaxEntries,
+		"session-cache-max-entries",
+		0,
+		"The maximum number of vCenter sessions kept by the manager, beyond which the least recently used ones are logged out (set to 0 to not limit the number of sessions).")
+
+	flag.DurationVar(
+		&managerOpts.SessionIdleTTL,
+		"session-idle-ttl",
+		time.Hour,
+		"The time after which a vCenter session which was not used is logged out (set to 0 to keep idle sessions).")
+
+	flag.DurationVar(
+		&managerOpts.InventoryCacheTTL,
+		"inventory-cache-ttl",
+		5*time.Minute,
+		"The time the datacenters, folders, resource pools, networks and datastores looked up in vCenter are cached for (set to 0 to disable the cache).")
+
+	flag.IntVar(
+		&managerOpts.KeepAliveRetries,
+		"keep-alive-retries",
+		defaultKeepAliveRetries,
+		"Number of times a keepalive failing with a transient error, e.g. a network error, is retried with a jittered backoff before it stops. The vCenter sessions are only recreated once they expired.")
+
+	flag.Float64Var(
+		&managerOpts.VCenterQPS,
+		"vcenter-qps",
+		0,
+		"The maximum number of vCenter API calls per second made against a vCenter endpoint (set to 0 to disable the rate limit). Can be overridden per VSphereClusterIdentity.")
+
+	flag.IntVar(
+		&managerOpts.VCenterBurst,
+		"vcenter-burst",
+		0,
+		"The maximum number of vCenter API calls which can be made at once against a vCenter endpoint. Defaults to vcenter-qps.")
+
+	flag.StringVar(
+		&managerOpts.VCenterTLSPolicy.MinVersion,
+		"vcenter-tls-min-version",
+		"",
+		"The minimum TLS version of the connections to vCenter endpoints, VersionTLS12 or VersionTLS13. Can be overridden per VSphereClusterIdentity.")
+
+	pflag.CommandLine.StringSliceVar(
+		&managerOpts.VCenterTLSPolicy.CipherSuites,
+		"vcenter-tls-cipher-suites",
+		nil,
+		"Comma-separated list of the TLS 1.2 cipher suites allowed for the connections to vCenter endpoints, named as in the Go crypto/tls package. Can be overridden per VSphereClusterIdentity.")
+
+	flag.BoolVar(
+		&managerOpts.VCenterTLSPolicy.Strict,
+		"vcenter-tls-strict",
+		false,
+		"Refuse the connections to vCenter endpoints whose certificate is not verified, instead of accepting any certificate when no thumbprint is set.")
+
+	flag.StringVar(
+		&managerOpts.VCenterAuditLogPath,
+		"vcenter-audit-log-path",
+		"",
+		"The file the JSON audit log of the vCenter operations changing the inventory is appended to, or - for the standard output (set to empty to disable the audit log).")
+
+	flag.BoolVar(
+		&managerOpts.VCenterAuditEvents,
+		"vcenter-audit-events",
+		false,
+		"Emit the audited vCenter operations as Events of the objects initiating them.")
+
+	flag.StringVar(
+		&managerOpts.Tracing.OTLPEndpoint,
+		"tracing-otlp-endpoint",
+		"",
+		"The host:port of the OTLP/HTTP endpoint the spans of the reconciliations and of the vCenter API calls are exported to (set to empty to disable the tracing).")
+
+	flag.BoolVar(
+		&managerOpts.Tracing.OTLPInsecure,
+		"tracing-otlp-insecure",
+		false,
+		"Export the spans to the OTLP endpoint over HTTP instead of HTTPS.")
+
+	flag.Float64Var(
+		&managerOpts.Tracing.SamplingRatio,
+		"tracing-sampling-ratio",
+		1,
+		"The ratio of the reconciliations which are traced, between 0 and 1.")
+
+	flag.IntVar(
+		&managerOpts.MaxConcurrentClonesPerTemplate,
+		"max-concurrent-clones-per-template",
+		0,
+		"The maximum number of in-flight clones of a template (set to 0 for no limit).")
+
+	flag.DurationVar(
+		&managerOpts.VMStateTTL,
+		"vm-state-ttl",
+		10*time.Second,
+		"The time the guest network and the power state of the VMs of a cluster, retrieved with a single query for all of them, are used by the reconciliations of their VSphereVMs for (set to 0 to retrieve them VM by VM).")
+
+	pflag.CommandLine.StringSliceVar(
+		&managerOpts.InternalIPCIDRs,
+		"internal-ip-cidrs",
+		nil,
+		"Comma-separated list of the CIDRs of the IP addresses of the VMs which are published as internal IPs in the Machine addresses. The other IP addresses are published as external IPs.")
+
+	flag.IntVar(
+		&managerOpts.CapacityHeadroomPercent,
+		"capacity-headroom-percent",
+		10,
+		"The percentage of the CPU, the memory and the datastore capacity which must remain free after a VM is cloned (set to a negative value to disable the capacity check).")
+
+	flag.DurationVar(
+		&managerOpts.CloneTimeout,
+		"clone-timeout",
+		30*time.Minute,
+		"The duration after which a clone which has not completed is reported as timed out in the conditions of its VSphereVM (set to 0 to disable the timeout).")
+
+	flag.DurationVar(
+		&managerOpts.OrphanedVMSweepInterval,
+		"orphaned-vm-sweep-interval",
+		30*time.Minute,
+		"The minimum interval between two sweeps of the VMs of a cluster which are not backed by a VSphereVM anymore (set to 0 to disable the sweep).")
+
+	flag.BoolVar(
+		&managerOpts.DeleteOrphanedVMs,
+		"delete-orphaned-vms",
+		false,
+		"Destroy the VMs found orphaned by two consecutive sweeps, rather than only reporting them in the status of their VSphereCluster.")
+
+	flag.DurationVar(
+		&managerOpts.VMForceDeleteTimeout,
+		"vm-force-delete-timeout",
+		0,
+		"The duration after which the finalizer of the VSphereVMs of a deleted cluster still being deleted, whose VM is not found in vCenter anymore, is removed (set to 0 to disable the removal).")
+
+	flag.BoolVar(
+		&managerOpts.SkipInfraDeletionOnUnreachable,
+		"skip-infra-deletion-on-unreachable",
+		false,
+		"Remove the finalizer of the VSphereVMs being deleted whose vCenter has been unreachable for longer than the vcenter-unreachable-timeout, without destroying their VMs, which may be left behind.")
+
+	flag.DurationVar(
+		&managerOpts.VCenterUnreachableTimeout,
+		"vcenter-unreachable-timeout",
+		time.Hour,
+		"The duration a vCenter must be unreachable before the VSphereVMs being deleted are released without destroying their VMs, when skip-infra-deletion-on-unreachable is set or their VSphereCluster has the force-cleanup annotation.")
+
+	flag.BoolVar(
+		&managerOpts.RestoreRecoveryMode,
+		"restore-recovery-mode",
+		false,
+		"Adopt the identity secrets whose owner references name a VSphereCluster or a VSphereClusterIdentity of another UID, as left by restoring a backup with new UIDs, and restore their finalizer.")
+
+	flag.IntVar(
+		&managerOpts.MaxConcurrentVCenterOperations,
+		"max-concurrent-vcenter-operations",
+		10,
+		"The maximum number of long-running vCenter operations, e.g. OVF exports, which run in the background at once (set to 0 for no limit).")
+
+	flag.IntVar(
+		&managerOpts.BootstrapDataCompressionThreshold,
+		"bootstrap-data-compression-threshold",
+		defaultBootstrapDataCompressionThreshold,
+		"The size in bytes above which the bootstrap data and the metadata of VMs are gzip compressed (set to 0 to disable the compression).")
+
 	flag.StringVar(
 		&managerOpts.NetworkProvider,
 		"network-provider",
 		"",
 		"network provider to be used by Supervisor based clusters.")
 
-	flag.Parse()
+	feature.MutableGates.AddFlag(pflag.CommandLine)
+	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
+	pflag.Parse()
+
+	if err := session.ValidateTLSPolicy(managerOpts.VCenterTLSPolicy); err != nil {
+		setupLog.Error(err, "invalid vCenter TLS policy")
+		os.Exit(1)
+	}
 
 	if managerOpts.Namespace != "" {
 		setupLog.Info(
@@ -196,6 +396,12 @@ func main() {
 		return nil
 	}
 
+	shutdownTracing, err := tracing.Setup(goctx.Background(), managerOpts.Tracing)
+	if err != nil {
+		setupLog.Error(err, "problem setting up tracing")
+		os.Exit(1)
+	}
+
 	setupLog.Info("creating controller manager", "version", version.Get().String())
 	managerOpts.AddToManager = addToManager
 	mgr, err := manager.New(managerOpts)
@@ -208,7 +414,16 @@ func main() {
 
 	sigHandler := ctrlsig.SetupSignalHandler()
 	setupLog.Info("starting controller manager")
-	if err := mgr.Start(sigHandler); err != nil {
+	err = mgr.Start(sigHandler)
+
+	// Flush the spans which are not exported yet.
+	shutdownCtx, cancel := goctx.WithTimeout(goctx.Background(), tracingShutdownTimeout)
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		setupLog.Error(err, "problem shutting down tracing")
+	}
+	cancel()
+
+	if err != nil {
 		setupLog.Error(err, "problem running controller manager")
 		os.Exit(1)
 	}
@@ -264,6 +479,13 @@ func setupVAPIControllers(ctx *context.ControllerManagerContext, mgr ctrlmgr.Man
 		return err
 	}
 
+	if err := (&v1beta1.VSphereProviderConfig{}).SetupWebhookWithManager(mgr); err != nil {
+		return err
+	}
+	if err := (&v1beta1.VSphereMachineImageMapping{}).SetupWebhookWithManager(mgr); err != nil {
+		return err
+	}
+
 	if err := controllers.AddClusterControllerToManager(ctx, mgr, &v1beta1.VSphereCluster{}); err != nil {
 		return err
 	}
@@ -279,6 +501,29 @@ func setupVAPIControllers(ctx *context.ControllerManagerContext, mgr ctrlmgr.Man
 	if err := controllers.AddVSphereDeploymentZoneControllerToManager(ctx, mgr); err != nil {
 		return err
 	}
+	if err := controllers.AddVSphereVMSnapshotControllerToManager(ctx, mgr); err != nil {
+		return err
+	}
+	if err := controllers.AddVSphereMachineImageControllerToManager(ctx, mgr); err != nil {
+		return err
+	}
+	if err := controllers.AddVSphereMachineTemplateControllerToManager(ctx, mgr); err != nil {
+		return err
+	}
+	if err := (&v1beta1.VSphereLoadBalancerVM{}).SetupWebhookWithManager(mgr); err != nil {
+		return err
+	}
+	if err := controllers.AddVSphereLoadBalancerVMControllerToManager(ctx, mgr); err != nil {
+		return err
+	}
+	if feature.Gates.Enabled(feature.MachinePool) {
+		if err := (&v1beta1.VSphereMachinePool{}).SetupWebhookWithManager(mgr); err != nil {
+			return err
+		}
+		if err := controllers.AddVSphereMachinePoolControllerToManager(ctx, mgr); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
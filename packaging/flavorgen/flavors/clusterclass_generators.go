@@ -110,6 +110,9 @@ func getClusterClassPatches() []clusterv1.ClusterClassPatch {
 	return []clusterv1.ClusterClassPatch{
 		enableSSHPatch(),
 		infraClusterPatch(),
+		infraMachinePatch(),
+		datacenterPatch(),
+		templatePatch(),
 		kubeVipEnabledPatch(),
 	}
 }
@@ -171,6 +174,26 @@ func getClusterClassVariables() []clusterv1.ClusterClassVariable {
 				},
 			},
 		},
+		{
+			Name:     "vsphereDatacenter",
+			Required: false,
+			Schema: clusterv1.VariableSchema{
+				OpenAPIV3Schema: clusterv1.JSONSchemaProps{
+					Type:        "string",
+					Description: "Datacenter of the VMs, overriding the one of the machine templates.",
+				},
+			},
+		},
+		{
+			Name:     "vsphereTemplate",
+			Required: false,
+			Schema: clusterv1.VariableSchema{
+				OpenAPIV3Schema: clusterv1.JSONSchemaProps{
+					Type:        "string",
+					Description: "VM template the VMs are cloned from, overriding the one of the machine templates.",
+				},
+			},
+		},
 		{
 			Name:     "controlPlaneIpAddr",
 			Required: true,
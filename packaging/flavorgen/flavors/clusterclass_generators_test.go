@@ -0,0 +1,50 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flavors
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestClusterClassVariables(t *testing.T) {
+	g := NewWithT(t)
+
+	declared := map[string]bool{}
+	for _, variable := range getClusterClassVariables() {
+		declared[variable.Name] = true
+	}
+	set := map[string]bool{}
+	for _, variable := range clusterTopologyVariables() {
+		set[variable.Name] = true
+	}
+
+	for _, patch := range getClusterClassPatches() {
+		for _, definition := range patch.Definitions {
+			for _, jsonPatch := range definition.JSONPatches {
+				if jsonPatch.ValueFrom == nil || jsonPatch.ValueFrom.Variable == nil {
+					continue
+				}
+				name := strings.Split(*jsonPatch.ValueFrom.Variable, ".")[0]
+				g.Expect(declared).To(HaveKey(name), "variable %s of patch %s is not declared", name, patch.Name)
+				g.Expect(set).To(HaveKey(name), "variable %s of patch %s is not set by the cluster topology", name, patch.Name)
+			}
+		}
+	}
+}
@@ -74,6 +74,8 @@ func clusterTopologyVariables() []clusterv1.ClusterVariable {
 	controlPlaneIP, _ := json.Marshal(env.ControlPlaneEndpointVar)
 	secretName, _ := json.Marshal(env.ClusterNameVar)
 	kubeVipPod, _ := json.Marshal(kubeVIPPodYaml())
+	datacenter, _ := json.Marshal(env.VSphereDataCenterVar)
+	template, _ := json.Marshal(env.VSphereTemplateVar)
 	return []clusterv1.ClusterVariable{
 		{
 			Name: "sshKey",
@@ -87,6 +89,18 @@ func clusterTopologyVariables() []clusterv1.ClusterVariable {
 				Raw: getInfraServerValue(),
 			},
 		},
+		{
+			Name: "vsphereDatacenter",
+			Value: apiextensionsv1.JSON{
+				Raw: datacenter,
+			},
+		},
+		{
+			Name: "vsphereTemplate",
+			Value: apiextensionsv1.JSON{
+				Raw: template,
+			},
+		},
 		{
 			Name: "kubeVipPodManifest",
 			Value: apiextensionsv1.JSON{
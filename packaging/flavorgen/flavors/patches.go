@@ -149,3 +149,86 @@ func kubeVipEnabledPatch() clusterv1.ClusterClassPatch {
 		},
 	}
 }
+
+func infraMachinePatch() clusterv1.ClusterClassPatch {
+	return clusterv1.ClusterClassPatch{
+		Name: "infraMachineSubstitutions",
+		Definitions: machineTemplateDefinitions(
+			clusterv1.JSONPatch{
+				Op:   "add",
+				Path: "/spec/template/spec/server",
+				ValueFrom: &clusterv1.JSONPatchValue{
+					Variable: pointer.StringPtr("infraServer.url"),
+				},
+			},
+			clusterv1.JSONPatch{
+				Op:   "add",
+				Path: "/spec/template/spec/thumbprint",
+				ValueFrom: &clusterv1.JSONPatchValue{
+					Variable: pointer.StringPtr("infraServer.thumbprint"),
+				},
+			},
+		),
+	}
+}
+
+func datacenterPatch() clusterv1.ClusterClassPatch {
+	return clusterv1.ClusterClassPatch{
+		Name:      "vsphereDatacenter",
+		EnabledIf: pointer.StringPtr("{{ if .vsphereDatacenter }}true{{end}}"),
+		Definitions: machineTemplateDefinitions(
+			clusterv1.JSONPatch{
+				Op:   "add",
+				Path: "/spec/template/spec/datacenter",
+				ValueFrom: &clusterv1.JSONPatchValue{
+					Variable: pointer.StringPtr("vsphereDatacenter"),
+				},
+			},
+		),
+	}
+}
+
+func templatePatch() clusterv1.ClusterClassPatch {
+	return clusterv1.ClusterClassPatch{
+		Name:      "vsphereTemplate",
+		EnabledIf: pointer.StringPtr("{{ if .vsphereTemplate }}true{{end}}"),
+		Definitions: machineTemplateDefinitions(
+			clusterv1.JSONPatch{
+				Op:   "add",
+				Path: "/spec/template/spec/template",
+				ValueFrom: &clusterv1.JSONPatchValue{
+					Variable: pointer.StringPtr("vsphereTemplate"),
+				},
+			},
+		),
+	}
+}
+
+// machineTemplateDefinitions applies the JSON patches to the
+// VSphereMachineTemplates of both the control plane and the workers.
+func machineTemplateDefinitions(patches ...clusterv1.JSONPatch) []clusterv1.PatchDefinition {
+	return []clusterv1.PatchDefinition{
+		{
+			Selector: clusterv1.PatchSelector{
+				APIVersion: infrav1.GroupVersion.String(),
+				Kind:       util.TypeToKind(&infrav1.VSphereMachineTemplate{}),
+				MatchResources: clusterv1.PatchSelectorMatch{
+					ControlPlane: true,
+				},
+			},
+			JSONPatches: patches,
+		},
+		{
+			Selector: clusterv1.PatchSelector{
+				APIVersion: infrav1.GroupVersion.String(),
+				Kind:       util.TypeToKind(&infrav1.VSphereMachineTemplate{}),
+				MatchResources: clusterv1.PatchSelectorMatch{
+					MachineDeploymentClass: &clusterv1.PatchSelectorMatchMachineDeploymentClass{
+						Names: []string{fmt.Sprintf("%s-worker", env.ClusterClassNameVar)},
+					},
+				},
+			},
+			JSONPatches: patches,
+		},
+	}
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit records the operations changing the vCenter inventory, with
+// the object initiating them, as an audit log of JSON lines and optionally as
+// Kubernetes Events.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
+)
+
+// Outcome is the outcome of an audited operation.
+type Outcome string
+
+const (
+	// Submitted is the outcome of the operations started as a vCenter task,
+	// whose final outcome is recorded by the reconciliation tracking the
+	// task or by the caller waiting for it once it completes.
+	Submitted Outcome = "Submitted"
+
+	// Succeeded is the outcome of the operations which succeeded.
+	Succeeded Outcome = "Succeeded"
+
+	// Failed is the outcome of the operations which failed.
+	Failed Outcome = "Failed"
+
+	// Unknown is the outcome of the tasks which could not be waited for.
+	Unknown Outcome = "Unknown"
+)
+
+// ObjectReference identifies the object initiating an operation.
+type ObjectReference struct {
+	Kind      string       `json:"kind"`
+	Namespace string       `json:"namespace,omitempty"`
+	Name      string       `json:"name"`
+	UID       apitypes.UID `json:"uid,omitempty"`
+	object    client.Object
+}
+
+// Record is an entry of the audit log.
+type Record struct {
+	Time time.Time `json:"time"`
+
+	// Server is the vCenter the operation is made against.
+	Server string `json:"server"`
+
+	// Operation is the name of the vCenter API method, e.g. CloneVM_Task or
+	// AttachTag.
+	Operation string `json:"operation"`
+
+	// Target is the managed object reference or the ID of the object the
+	// operation is made on, e.g. VirtualMachine:vm-42.
+	Target string `json:"target,omitempty"`
+
+	// TaskID is the ID of the vCenter task of the operation, if any.
+	TaskID string `json:"taskID,omitempty"`
+
+	// Initiator is the object whose reconciliation initiated the operation,
+	// if known.
+	Initiator *ObjectReference `json:"initiator,omitempty"`
+
+	Outcome Outcome `json:"outcome"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// Auditor writes the audit records.
+type Auditor struct {
+	mu       sync.Mutex
+	encoder  *json.Encoder
+	recorder record.Recorder
+}
+
+// New returns an auditor writing the records as JSON lines to out, and
+// emitting them as Events of their initiator with the recorder, unless they
+// are nil.
+func New(out io.Writer, recorder record.Recorder) *Auditor {
+	a := &Auditor{recorder: recorder}
+	if out != nil {
+		a.encoder = json.NewEncoder(out)
+	}
+	return a
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultAuditor *Auditor
+)
+
+// SetDefault sets the auditor of the vCenter operations of the controller
+// manager. The operations are not audited while it is nil.
+func SetDefault(a *Auditor) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultAuditor = a
+}
+
+// Default returns the auditor of the vCenter operations of the controller
+// manager, or nil if they are not audited.
+func Default() *Auditor {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultAuditor
+}
+
+// Record writes the record, and emits it as an Event of its initiator. The
+// submission of tasks is not emitted as an Event, only their outcome.
+func (a *Auditor) Record(r Record) {
+	if r.Time.IsZero() {
+		r.Time = time.Now().UTC()
+	}
+	if a.encoder != nil {
+		a.mu.Lock()
+		// The records are best effort, the operations are not failed when
+		// they cannot be written.
+		_ = a.encoder.Encode(r)
+		a.mu.Unlock()
+	}
+	if a.recorder == nil || r.Initiator == nil || r.Initiator.object == nil || r.Outcome == Submitted {
+		return
+	}
+	message := r.Operation + " " + r.Target
+	if r.TaskID != "" {
+		message += " (task " + r.TaskID + ")"
+	}
+	message += " on vCenter " + r.Server
+	if r.Outcome == Succeeded {
+		a.recorder.Event(r.Initiator.object, "VCenterOperationSucceeded", message)
+		return
+	}
+	if r.Error != "" {
+		message += ": " + r.Error
+	}
+	a.recorder.Warn(r.Initiator.object, "VCenterOperation"+string(r.Outcome), message)
+}
+
+// Initiator is implemented by the contexts of the reconciliation of an
+// object, which initiates the vCenter operations made with the context.
+type Initiator interface {
+	AuditInitiator() client.Object
+}
+
+type initiatorKey struct{}
+
+// WithInitiator returns a copy of the context with the object initiating the
+// vCenter operations made with it.
+func WithInitiator(ctx context.Context, obj client.Object) context.Context {
+	return context.WithValue(ctx, initiatorKey{}, obj)
+}
+
+// InitiatorFrom returns a reference to the object initiating the vCenter
+// operations made with the context, or nil if it is not known.
+func InitiatorFrom(ctx context.Context) *ObjectReference {
+	var obj client.Object
+	if initiator, ok := ctx.(Initiator); ok {
+		obj = initiator.AuditInitiator()
+	}
+	if obj == nil || reflect.ValueOf(obj).IsNil() {
+		obj, _ = ctx.Value(initiatorKey{}).(client.Object)
+	}
+	if obj == nil || reflect.ValueOf(obj).IsNil() {
+		return nil
+	}
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		kind = reflect.TypeOf(obj).Elem().Name()
+	}
+	return &ObjectReference{
+		Kind:      kind,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		UID:       obj.GetUID(),
+		object:    obj,
+	}
+}
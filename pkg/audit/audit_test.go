@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/audit"
+	capvrecord "sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
+)
+
+type vmContext struct {
+	context.Context
+	vm *infrav1.VSphereVM
+}
+
+func (c vmContext) AuditInitiator() client.Object {
+	return c.vm
+}
+
+func TestInitiatorFrom(t *testing.T) {
+	g := NewWithT(t)
+	vm := &infrav1.VSphereVM{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "vm", UID: "uid"}}
+	image := &infrav1.VSphereMachineImage{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "image"}}
+
+	g.Expect(audit.InitiatorFrom(context.Background())).To(BeNil())
+	g.Expect(audit.InitiatorFrom(vmContext{Context: context.Background()})).To(BeNil())
+
+	ref := audit.InitiatorFrom(vmContext{Context: context.Background(), vm: vm})
+	g.Expect(ref.Kind).To(Equal("VSphereVM"))
+	g.Expect(ref.Namespace).To(Equal("ns"))
+	g.Expect(ref.Name).To(Equal("vm"))
+	g.Expect(ref.UID).To(BeEquivalentTo("uid"))
+
+	ref = audit.InitiatorFrom(audit.WithInitiator(context.Background(), image))
+	g.Expect(ref.Kind).To(Equal("VSphereMachineImage"))
+	g.Expect(ref.Name).To(Equal("image"))
+}
+
+func TestRecord(t *testing.T) {
+	g := NewWithT(t)
+	vm := &infrav1.VSphereVM{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "vm"}}
+	initiator := audit.InitiatorFrom(audit.WithInitiator(context.Background(), vm))
+
+	out := &bytes.Buffer{}
+	events := record.NewFakeRecorder(10)
+	auditor := audit.New(out, capvrecord.New(events))
+
+	auditor.Record(audit.Record{Server: "vcenter", Operation: "PowerOnVM_Task", Target: "VirtualMachine:vm-42", TaskID: "task-1", Initiator: initiator, Outcome: audit.Submitted})
+	auditor.Record(audit.Record{Server: "vcenter", Operation: "PowerOnVM_Task", Target: "VirtualMachine:vm-42", TaskID: "task-1", Initiator: initiator, Outcome: audit.Succeeded})
+	auditor.Record(audit.Record{Server: "vcenter", Operation: "Destroy_Task", Target: "VirtualMachine:vm-42", Initiator: initiator, Outcome: audit.Failed, Error: "denied"})
+
+	decoder := json.NewDecoder(out)
+	var records []audit.Record
+	for decoder.More() {
+		var r audit.Record
+		g.Expect(decoder.Decode(&r)).To(Succeed())
+		records = append(records, r)
+	}
+	g.Expect(records).To(HaveLen(3))
+	g.Expect(records[0].Time).NotTo(BeZero())
+	g.Expect(records[0].TaskID).To(Equal("task-1"))
+	g.Expect(records[0].Initiator.Name).To(Equal("vm"))
+	g.Expect(records[1].Outcome).To(Equal(audit.Succeeded))
+	g.Expect(records[2].Error).To(Equal("denied"))
+
+	// The submission of the task is not emitted.
+	g.Expect(events.Events).To(HaveLen(2))
+	g.Expect(<-events.Events).To(Equal("Normal VCenterOperationSucceeded PowerOnVM_Task VirtualMachine:vm-42 (task task-1) on vCenter vcenter"))
+	g.Expect(<-events.Events).To(Equal("Warning VCenterOperationFailed Destroy_Task VirtualMachine:vm-42 on vCenter vcenter: denied"))
+}
+
+func TestRecordTaskOutcome(t *testing.T) {
+	g := NewWithT(t)
+	vm := &infrav1.VSphereVM{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "vm"}}
+	ctx := audit.WithInitiator(context.Background(), vm)
+	ref := types.ManagedObjectReference{Type: "Task", Value: "task-1"}
+	entity := &types.ManagedObjectReference{Type: "ClusterComputeResource", Value: "domain-c7"}
+
+	out := &bytes.Buffer{}
+	audit.SetDefault(audit.New(out, nil))
+	defer audit.SetDefault(nil)
+
+	audit.RecordTaskOutcome(ctx, "vcenter", ref, &types.TaskInfo{Name: "ReconfigureComputeResource_Task", Entity: entity, State: types.TaskInfoStateSuccess}, nil)
+	audit.RecordTaskOutcome(ctx, "vcenter", ref, &types.TaskInfo{Name: "CreateSnapshot_Task", Entity: entity, State: types.TaskInfoStateError, Error: &types.LocalizedMethodFault{LocalizedMessage: "denied"}}, errors.New("fault"))
+	audit.RecordTaskOutcome(ctx, "vcenter", ref, nil, errors.New("connection reset"))
+
+	decoder := json.NewDecoder(out)
+	var records []audit.Record
+	for decoder.More() {
+		var r audit.Record
+		g.Expect(decoder.Decode(&r)).To(Succeed())
+		records = append(records, r)
+	}
+	g.Expect(records).To(HaveLen(3))
+	g.Expect(records[0].Operation).To(Equal("ReconfigureComputeResource_Task"))
+	g.Expect(records[0].Target).To(Equal("ClusterComputeResource:domain-c7"))
+	g.Expect(records[0].TaskID).To(Equal("task-1"))
+	g.Expect(records[0].Initiator.Name).To(Equal("vm"))
+	g.Expect(records[0].Outcome).To(Equal(audit.Succeeded))
+	g.Expect(records[1].Outcome).To(Equal(audit.Failed))
+	g.Expect(records[1].Error).To(Equal("denied"))
+	g.Expect(records[2].Outcome).To(Equal(audit.Unknown))
+	g.Expect(records[2].Error).To(Equal("connection reset"))
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// WaitForTask waits for the task of an audited operation, whose submission
+// was recorded by the session, and records its outcome with the default
+// auditor. It returns the error of the task as object.Task.Wait does.
+func WaitForTask(ctx context.Context, task *object.Task) error {
+	info, err := task.WaitForResult(ctx)
+	RecordTaskOutcome(ctx, task.Client().URL().Host, task.Reference(), info, err)
+	return err
+}
+
+// RecordTaskOutcome records the outcome of the task with the default auditor.
+// The outcome is Unknown when the info of the task is nil, err being the
+// error of retrieving it.
+func RecordTaskOutcome(ctx context.Context, server string, ref types.ManagedObjectReference, info *types.TaskInfo, err error) {
+	auditor := Default()
+	if auditor == nil {
+		return
+	}
+	r := Record{
+		Server:    server,
+		TaskID:    ref.Value,
+		Initiator: InitiatorFrom(ctx),
+		Outcome:   Succeeded,
+	}
+	if info == nil {
+		r.Outcome = Unknown
+		if err != nil {
+			r.Error = err.Error()
+		}
+		auditor.Record(r)
+		return
+	}
+	r.Operation = info.Name
+	if info.Entity != nil {
+		r.Target = info.Entity.String()
+	}
+	if info.State == types.TaskInfoStateError {
+		r.Outcome = Failed
+		switch {
+		case info.Error != nil && info.Error.LocalizedMessage != "":
+			r.Error = info.Error.LocalizedMessage
+		case err != nil:
+			r.Error = err.Error()
+		}
+	}
+	auditor.Record(r)
+}
@@ -56,4 +56,12 @@ const (
 
 	// KeepaliveDuration unit minutes.
 	DefaultKeepAliveDuration = time.Minute * 5
+
+	// DefaultKeepAliveRetries is the number of times a failed keepalive is
+	// retried before it stops.
+	DefaultKeepAliveRetries = 3
+
+	// DefaultBootstrapDataCompressionThreshold is the size in bytes above
+	// which the bootstrap data and the metadata of VMs are gzip compressed.
+	DefaultBootstrapDataCompressionThreshold = 32 * 1024
 )
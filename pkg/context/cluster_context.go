@@ -17,12 +17,14 @@ limitations under the License.
 package context
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/go-logr/logr"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 )
@@ -34,6 +36,11 @@ type ClusterContext struct {
 	VSphereCluster *infrav1.VSphereCluster
 	PatchHelper    *patch.Helper
 	Logger         logr.Logger
+
+	// RequestContext is the context of the reconcile request, whose values,
+	// e.g. its tracing span, take precedence over the ones of the controller
+	// manager.
+	RequestContext context.Context
 }
 
 // String returns VSphereClusterGroupVersionKind VSphereClusterNamespace/VSphereClusterName.
@@ -47,8 +54,21 @@ func (c *ClusterContext) Patch() error {
 	conditions.SetSummary(c.VSphereCluster,
 		conditions.WithConditions(
 			infrav1.VCenterAvailableCondition,
+			infrav1.ControlPlaneEndpointReadyCondition,
 		),
 	)
 
 	return c.PatchHelper.Patch(c, c.VSphereCluster)
 }
+
+// AuditInitiator returns the VSphereCluster, which initiates the vCenter operations
+// made with this context.
+func (c *ClusterContext) AuditInitiator() client.Object {
+	return c.VSphereCluster
+}
+
+// Value returns the value of the key in the context of the reconcile request,
+// if any, or in the context of the controller manager.
+func (c *ClusterContext) Value(key interface{}) interface{} {
+	return requestValue(c.RequestContext, c.ControllerContext, key)
+}
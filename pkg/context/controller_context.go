@@ -17,6 +17,7 @@ limitations under the License.
 package context
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/go-logr/logr"
@@ -42,3 +43,14 @@ type ControllerContext struct {
 func (c *ControllerContext) String() string {
 	return fmt.Sprintf("%s/%s", c.ControllerManagerContext.String(), c.Name)
 }
+
+// requestValue returns the value of the key in the context of a reconcile
+// request, if any, or in the parent context.
+func requestValue(request, parent context.Context, key interface{}) interface{} {
+	if request != nil {
+		if v := request.Value(key); v != nil {
+			return v
+		}
+	}
+	return parent.Value(key)
+}
@@ -18,6 +18,7 @@ package context
 
 import (
 	"context"
+	"net"
 	"sync"
 	"time"
 
@@ -27,6 +28,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
 )
 
@@ -70,6 +72,20 @@ type ControllerManagerContext struct {
 	// controller will receive concurrently.
 	MaxConcurrentReconciles int
 
+	// VSphereVMConcurrency, VSphereMachineConcurrency,
+	// VSphereClusterConcurrency, and VSphereDeploymentZoneConcurrency are the
+	// maximum number of reconcile requests the eponymous controllers receive
+	// concurrently.
+	VSphereVMConcurrency             int
+	VSphereMachineConcurrency        int
+	VSphereClusterConcurrency        int
+	VSphereDeploymentZoneConcurrency int
+
+	// MaxConcurrentVCenterOperations is the maximum number of long-running
+	// vCenter operations, e.g. OVF exports, which run in the background at
+	// once. A value of 0 means there is no limit.
+	MaxConcurrentVCenterOperations int
+
 	// Username is the username for the account used to access remote vSphere
 	// endpoints.
 	Username string
@@ -86,6 +102,84 @@ type ControllerManagerContext struct {
 	// in keepalive handler
 	KeepAliveDuration time.Duration
 
+	// KeepAliveRetries is the number of times a keepalive failing with a
+	// transient error is retried before the keepalive stops.
+	KeepAliveRetries int
+
+	// VCenterQPS is the maximum number of vCenter API calls per second made
+	// against a vCenter endpoint. A value of 0 disables the rate limit.
+	VCenterQPS float64
+
+	// VCenterBurst is the maximum number of vCenter API calls which can be
+	// made at once against a vCenter endpoint.
+	VCenterBurst int
+
+	// VCenterTLSPolicy is the TLS policy of the connections to the vCenter
+	// endpoints, which can be overridden per VSphereClusterIdentity.
+	VCenterTLSPolicy infrav1.VCenterTLSPolicy
+
+	// MaxConcurrentClonesPerTemplate is the maximum number of in-flight
+	// clones of a template. A value of 0 means there is no limit.
+	MaxConcurrentClonesPerTemplate int
+
+	// VMStateTTL is the time the guest network, the power state and the
+	// VMware Tools status of the VMs of a cluster, retrieved at once for all
+	// of them, are used by the reconciliations of their VSphereVMs for. A
+	// value of 0 retrieves them VM by VM.
+	VMStateTTL time.Duration
+
+	// InternalIPCIDRs are the CIDRs of the IP addresses of the VMs which are
+	// published as internal IPs in the Machine addresses. The other IP
+	// addresses are published as external IPs.
+	InternalIPCIDRs []*net.IPNet
+
+	// CapacityHeadroomPercent is the percentage of the CPU, the memory and
+	// the datastore capacity which must remain free after a VM is cloned. A
+	// negative value disables the capacity check before clones.
+	CapacityHeadroomPercent int
+
+	// CloneTimeout is the duration after which a clone task which has not
+	// completed is reported as timed out. A value of 0 disables the timeout.
+	CloneTimeout time.Duration
+
+	// OrphanedVMSweepInterval is the minimum interval between two sweeps of
+	// the VMs of a cluster which are not backed by a VSphereVM anymore. A
+	// value of 0 disables the sweep.
+	OrphanedVMSweepInterval time.Duration
+
+	// DeleteOrphanedVMs destroys the VMs found orphaned by two consecutive
+	// sweeps, rather than only reporting them in the VSphereCluster status.
+	DeleteOrphanedVMs bool
+
+	// VMForceDeleteTimeout is the duration after which the finalizer of the
+	// VSphereVMs of a deleted cluster still being deleted, whose VM is not
+	// found in vCenter anymore, is removed. A value of 0 disables the
+	// removal.
+	VMForceDeleteTimeout time.Duration
+
+	// SkipInfraDeletionOnUnreachable removes the finalizer of the VSphereVMs
+	// being deleted whose vCenter has been unreachable for longer than the
+	// VCenterUnreachableTimeout, without destroying their VMs, which may be
+	// left behind in vCenter.
+	SkipInfraDeletionOnUnreachable bool
+
+	// VCenterUnreachableTimeout is the duration a vCenter must be unreachable
+	// before the VSphereVMs being deleted are released without destroying
+	// their VMs, when SkipInfraDeletionOnUnreachable is set or their
+	// VSphereCluster has the force-cleanup annotation.
+	VCenterUnreachableTimeout time.Duration
+
+	// RestoreRecoveryMode points the owner references of the identity
+	// secrets which reference a VSphereCluster or a VSphereClusterIdentity
+	// of the same name but another UID, as left by the restore of a backup,
+	// to the restored owner, and restores their finalizer.
+	RestoreRecoveryMode bool
+
+	// BootstrapDataCompressionThreshold is the size in bytes above which the
+	// bootstrap data and the metadata of VMs are gzip compressed. A value of
+	// 0 disables the compression.
+	BootstrapDataCompressionThreshold int
+
 	// NetworkProvider is the network provider used by Supervisor based clusters
 	NetworkProvider string
 
@@ -97,12 +191,17 @@ func (c *ControllerManagerContext) String() string {
 	return c.Name
 }
 
+// genericEventBufferSize is the number of generic events buffered per
+// resource, so that the watches of vCenter objects can send their events
+// without blocking.
+const genericEventBufferSize = 1024
+
 // GetGenericEventChannelFor returns a generic event channel for a resource
 // specified by the provided GroupVersionKind.
 func (c *ControllerManagerContext) GetGenericEventChannelFor(gvk schema.GroupVersionKind) chan event.GenericEvent {
 	if val, ok := c.genericEventCache.Load(gvk); ok {
 		return val.(chan event.GenericEvent)
 	}
-	val, _ := c.genericEventCache.LoadOrStore(gvk, make(chan event.GenericEvent))
+	val, _ := c.genericEventCache.LoadOrStore(gvk, make(chan event.GenericEvent, genericEventBufferSize))
 	return val.(chan event.GenericEvent)
 }
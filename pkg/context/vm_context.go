@@ -17,13 +17,17 @@ limitations under the License.
 package context
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
 	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 )
 
 // VMContext is a Go context used with a VSphereVM.
@@ -34,6 +38,11 @@ type VMContext struct {
 	Logger               logr.Logger
 	Session              *session.Session
 	VSphereFailureDomain *infrav1.VSphereFailureDomain
+
+	// RequestContext is the context of the reconcile request, whose values,
+	// e.g. its tracing span, take precedence over the ones of the controller
+	// manager.
+	RequestContext context.Context
 }
 
 // String returns VSphereVMGroupVersionKind VSphereVMNamespace/VSphereVMName.
@@ -55,3 +64,32 @@ func (c *VMContext) GetLogger() logr.Logger {
 func (c *VMContext) GetSession() *session.Session {
 	return c.Session
 }
+
+// AuditInitiator returns the VSphereVM, which initiates the vCenter operations
+// made with this context.
+func (c *VMContext) AuditInitiator() client.Object {
+	return c.VSphereVM
+}
+
+// Value returns the value of the key in the context of the reconcile request,
+// if any, or in the context of the controller manager.
+func (c *VMContext) Value(key interface{}) interface{} {
+	return requestValue(c.RequestContext, c.ControllerContext, key)
+}
+
+// StartSpan starts a span named name as a child of the span of the context,
+// which the spans of the operations made with the context are children of
+// until the returned function ends it.
+func (c *VMContext) StartSpan(name string) (trace.Span, func(error)) {
+	request := c.RequestContext
+	var parent context.Context = c.ControllerContext
+	if request != nil {
+		parent = request
+	}
+	ctx, span := tracing.Tracer().Start(parent, name)
+	c.RequestContext = ctx
+	return span, func(err error) {
+		tracing.End(span, err)
+		c.RequestContext = request
+	}
+}
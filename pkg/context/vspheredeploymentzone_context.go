@@ -17,11 +17,13 @@ limitations under the License.
 package context
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/go-logr/logr"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
@@ -34,6 +36,11 @@ type VSphereDeploymentZoneContext struct {
 	Logger                logr.Logger
 	PatchHelper           *patch.Helper
 	AuthSession           *session.Session
+
+	// RequestContext is the context of the reconcile request, whose values,
+	// e.g. its tracing span, take precedence over the ones of the controller
+	// manager.
+	RequestContext context.Context
 }
 
 func (c *VSphereDeploymentZoneContext) Patch() error {
@@ -58,3 +65,15 @@ func (c *VSphereDeploymentZoneContext) GetSession() *session.Session {
 func (c *VSphereDeploymentZoneContext) GetVsphereFailureDomain() infrav1.VSphereFailureDomain {
 	return *c.VSphereFailureDomain
 }
+
+// AuditInitiator returns the VSphereDeploymentZone, which initiates the vCenter operations
+// made with this context.
+func (c *VSphereDeploymentZoneContext) AuditInitiator() client.Object {
+	return c.VSphereDeploymentZone
+}
+
+// Value returns the value of the key in the context of the reconcile request,
+// if any, or in the context of the controller manager.
+func (c *VSphereDeploymentZoneContext) Value(key interface{}) interface{} {
+	return requestValue(c.RequestContext, c.ControllerContext, key)
+}
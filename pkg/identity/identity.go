@@ -20,7 +20,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
+	"time"
 
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -30,6 +32,10 @@ import (
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 )
 
+// ErrNamespaceNotAllowed is returned when the namespace of a VSphereCluster
+// is not allowed to use the VSphereClusterIdentity it references.
+var ErrNamespaceNotAllowed = errors.New("namespace not allowed to use the identity")
+
 const (
 	UsernameKey = "username"
 	PasswordKey = "password"
@@ -38,22 +44,95 @@ const (
 type Credentials struct {
 	Username string
 	Password string
+
+	// RateLimit is the rate limit override of the VSphereClusterIdentity, if any.
+	RateLimit *infrav1.VCenterRateLimit
+
+	// Connection is the vCenter connection settings of the
+	// VSphereClusterIdentity, if any.
+	Connection *infrav1.VCenterConnectionSpec
+
+	// TLS is the TLS policy of the VSphereClusterIdentity, if any.
+	TLS *infrav1.VCenterTLSPolicy
+
+	// RenewAt is the time short-lived credentials are to be retrieved again.
+	// It is zero for the credentials read from a Secret.
+	RenewAt time.Time
+}
+
+// CredentialProvider retrieves vCenter credentials from a credential backend.
+type CredentialProvider interface {
+	Retrieve(ctx context.Context) (*Credentials, error)
+}
+
+// secretProvider reads the credentials from a Secret.
+type secretProvider struct {
+	client client.Client
+	key    client.ObjectKey
+}
+
+func (p secretProvider) Retrieve(ctx context.Context) (*Credentials, error) {
+	secret := &apiv1.Secret{}
+	if err := p.client.Get(ctx, p.key, secret); err != nil {
+		return nil, err
+	}
+	return &Credentials{
+		Username: getData(secret, UsernameKey),
+		Password: getData(secret, PasswordKey),
+	}, nil
 }
 
 func GetCredentials(ctx context.Context, c client.Client, cluster *infrav1.VSphereCluster, controllerNamespace string) (*Credentials, error) {
 	if err := validateInputs(c, cluster); err != nil {
 		return nil, err
 	}
+	return getCredentials(ctx, c, cluster, cluster.Spec.IdentityRef, controllerNamespace)
+}
 
-	ref := cluster.Spec.IdentityRef
-	secret := &apiv1.Secret{}
-	var secretKey client.ObjectKey
+// GetCredentialsForServer returns the credentials used to log in to the
+// vCenter at the server, i.e. the ones of the identity of the additional
+// vCenter of the cluster with the server, if it has one, or the ones of the
+// identity of the cluster otherwise.
+func GetCredentialsForServer(ctx context.Context, c client.Client, cluster *infrav1.VSphereCluster, server, controllerNamespace string) (*Credentials, error) {
+	if c == nil {
+		return nil, errors.New("kubernetes client is required")
+	}
+	if cluster == nil {
+		return nil, errors.New("vsphere cluster is required")
+	}
+	ref := IdentityRefForServer(cluster, server)
+	if ref == nil {
+		return nil, errors.New("IdentityRef is required")
+	}
+	return getCredentials(ctx, c, cluster, ref, controllerNamespace)
+}
+
+// IdentityRefForServer returns the reference to the identity used to log in
+// to the vCenter at the server, or nil if the credentials provided to the
+// manager are used.
+func IdentityRefForServer(cluster *infrav1.VSphereCluster, server string) *infrav1.VSphereIdentityReference {
+	for _, vcenter := range cluster.Spec.VCenters {
+		if vcenter.Server == server && vcenter.IdentityRef != nil {
+			return vcenter.IdentityRef
+		}
+	}
+	return cluster.Spec.IdentityRef
+}
+
+func getCredentials(ctx context.Context, c client.Client, cluster *infrav1.VSphereCluster, ref *infrav1.VSphereIdentityReference, controllerNamespace string) (*Credentials, error) {
+	var provider CredentialProvider
+	var rateLimit *infrav1.VCenterRateLimit
+	var connection *infrav1.VCenterConnectionSpec
+	var tlsPolicy *infrav1.VCenterTLSPolicy
 
 	switch ref.Kind {
 	case infrav1.SecretKind:
-		secretKey = client.ObjectKey{
-			Namespace: cluster.Namespace,
-			Name:      ref.Name,
+		provider = secretProvider{
+			client: c,
+			key: client.ObjectKey{
+				Namespace: cluster.Namespace,
+				Name:      ref.Name,
+			},
 		}
 	case infrav1.VSphereClusterIdentityKind:
 		identity := &infrav1.VSphereClusterIdentity{}
@@ -68,44 +147,89 @@ func GetCredentials(ctx context.Context, c client.Client, cluster *infrav1.VSphe
 			return nil, errors.New("identity isn't ready to be used yet")
 		}
 
-		if identity.Spec.AllowedNamespaces == nil {
-			return nil, errors.New("allowedNamespaces set to nil, no namespaces are allowed to use this identity")
-		}
-
-		selector, err := metav1.LabelSelectorAsSelector(&identity.Spec.AllowedNamespaces.Selector)
+		allowed, err := IsNamespaceAllowed(ctx, c, identity, cluster.Namespace)
 		if err != nil {
-			return nil, errors.New("failed to build selector")
-		}
-
-		ns := &apiv1.Namespace{}
-		nsKey := client.ObjectKey{
-			Name: cluster.Namespace,
-		}
-		if err := c.Get(ctx, nsKey, ns); err != nil {
 			return nil, err
 		}
-		if !selector.Matches(labels.Set(ns.GetLabels())) {
-			return nil, fmt.Errorf("namespace %s is not allowed to use specifified identity", cluster.Namespace)
+		if !allowed {
+			return nil, fmt.Errorf("%w: namespace %s is not allowed to use VSphereClusterIdentity %s", ErrNamespaceNotAllowed, cluster.Namespace, identity.Name)
 		}
 
-		secretKey = client.ObjectKey{
-			Name:      identity.Spec.SecretName,
-			Namespace: controllerNamespace,
+		if identity.Spec.Vault != nil {
+			provider = NewVaultProvider(identity.Spec.Vault)
+		} else {
+			provider = secretProvider{
+				client: c,
+				key: client.ObjectKey{
+					Name:      identity.Spec.SecretName,
+					Namespace: controllerNamespace,
+				},
+			}
 		}
+		rateLimit = identity.Spec.RateLimit
+		connection = identity.Spec.Connection
+		tlsPolicy = identity.Spec.TLS
 	default:
 		return nil, fmt.Errorf("unknown type %s used for Identity", ref.Kind)
 	}
 
-	if err := c.Get(ctx, secretKey, secret); err != nil {
+	credentials, err := provider.Retrieve(ctx)
+	if err != nil {
 		return nil, err
 	}
+	credentials.RateLimit = rateLimit
+	credentials.Connection = connection
+	credentials.TLS = tlsPolicy
 
-	credentials := &Credentials{
-		Username: getData(secret, UsernameKey),
-		Password: getData(secret, PasswordKey),
+	return credentials, nil
+}
+
+// IsNamespaceAllowed returns whether the namespace is allowed to use the
+// identity, i.e. listed in its allowed namespaces or matching their selector.
+// No namespace is allowed when the allowed namespaces are not set, and all
+// of them are when neither a list nor a selector is set.
+func IsNamespaceAllowed(ctx context.Context, c client.Client, identity *infrav1.VSphereClusterIdentity, namespace string) (bool, error) {
+	allowedNamespaces := identity.Spec.AllowedNamespaces
+	if allowedNamespaces == nil {
+		return false, nil
+	}
+	for _, name := range allowedNamespaces.NamespaceList {
+		if name == namespace {
+			return true, nil
+		}
+	}
+	// An empty selector matches all the namespaces, which is only intended
+	// when no namespace is listed.
+	if len(allowedNamespaces.NamespaceList) > 0 && reflect.DeepEqual(allowedNamespaces.Selector, metav1.LabelSelector{}) {
+		return false, nil
 	}
 
-	return credentials, nil
+	selector, err := metav1.LabelSelectorAsSelector(&allowedNamespaces.Selector)
+	if err != nil {
+		return false, fmt.Errorf("failed to build selector: %w", err)
+	}
+	ns := &apiv1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(ns.GetLabels())), nil
+}
+
+// DefaultIdentityRef returns the reference to the default identity of the
+// namespace, set with the DefaultIdentityAnnotation, or nil if it has none.
+func DefaultIdentityRef(ctx context.Context, c client.Client, namespace string) (*infrav1.VSphereIdentityReference, error) {
+	ns := &apiv1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return nil, err
+	}
+	name := ns.Annotations[infrav1.DefaultIdentityAnnotation]
+	if name == "" {
+		return nil, nil
+	}
+	return &infrav1.VSphereIdentityReference{
+		Kind: infrav1.VSphereClusterIdentityKind,
+		Name: name,
+	}, nil
 }
 
 func validateInputs(c client.Client, cluster *infrav1.VSphereCluster) error {
@@ -144,6 +268,33 @@ func IsOwnedByIdentityOrCluster(ownerReferences []metav1.OwnerReference) bool {
 	return false
 }
 
+// RestampOwnerReference points the owner references of the object to a
+// VSphereCluster or a VSphereClusterIdentity of the kind and the name of the
+// owner, but of another UID, to the owner. Restoring a backup, e.g. with
+// Velero, assigns new UIDs to the restored objects, which leaves the secrets
+// they own referencing owners which do not exist anymore. It returns whether
+// an owner reference was replaced.
+func RestampOwnerReference(obj, owner metav1.Object, kind string) bool {
+	ownerReferences := obj.GetOwnerReferences()
+	restamped := false
+	for i := range ownerReferences {
+		ownerReference := &ownerReferences[i]
+		if !strings.Contains(ownerReference.APIVersion, infrav1.GroupName+"/") {
+			continue
+		}
+		if ownerReference.Kind != kind || ownerReference.Name != owner.GetName() || ownerReference.UID == owner.GetUID() {
+			continue
+		}
+		ownerReference.APIVersion = infrav1.GroupVersion.String()
+		ownerReference.UID = owner.GetUID()
+		restamped = true
+	}
+	if restamped {
+		obj.SetOwnerReferences(ownerReferences)
+	}
+	return restamped
+}
+
 func getData(secret *apiv1.Secret, key string) string {
 	if secret.Data == nil {
 		return ""
@@ -17,12 +17,16 @@ limitations under the License.
 package identity
 
 import (
+	"context"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/manager"
@@ -445,3 +449,147 @@ func TestIsOwnedByIdentityOrCluster(t *testing.T) {
 		})
 	}
 }
+
+func TestRestampOwnerReference(t *testing.T) {
+	owner := &infrav1.VSphereCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster", UID: "restored"}}
+	tests := []struct {
+		name           string
+		ownerReference metav1.OwnerReference
+		want           bool
+		wantUID        types.UID
+	}{
+		{
+			name:           "owner of another UID",
+			ownerReference: metav1.OwnerReference{APIVersion: "infrastructure.cluster.x-k8s.io/v1alpha4", Kind: "VSphereCluster", Name: "cluster", UID: "backed-up"},
+			want:           true,
+			wantUID:        "restored",
+		},
+		{
+			name:           "owner of the same UID",
+			ownerReference: metav1.OwnerReference{APIVersion: infrav1.GroupVersion.String(), Kind: "VSphereCluster", Name: "cluster", UID: "restored"},
+			wantUID:        "restored",
+		},
+		{
+			name:           "owner of another name",
+			ownerReference: metav1.OwnerReference{APIVersion: infrav1.GroupVersion.String(), Kind: "VSphereCluster", Name: "other", UID: "backed-up"},
+			wantUID:        "backed-up",
+		},
+		{
+			name:           "owner of another kind",
+			ownerReference: metav1.OwnerReference{APIVersion: infrav1.GroupVersion.String(), Kind: "VSphereClusterIdentity", Name: "cluster", UID: "backed-up"},
+			wantUID:        "backed-up",
+		},
+		{
+			name:           "owner of another group",
+			ownerReference: metav1.OwnerReference{APIVersion: "cluster.x-k8s.io/v1beta1", Kind: "VSphereCluster", Name: "cluster", UID: "backed-up"},
+			wantUID:        "backed-up",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{tt.ownerReference}}}
+			if got := RestampOwnerReference(secret, owner, "VSphereCluster"); got != tt.want {
+				t.Errorf("RestampOwnerReference() = %v, want %v", got, tt.want)
+			}
+			if uid := secret.OwnerReferences[0].UID; uid != tt.wantUID {
+				t.Errorf("Expected owner reference UID %s, got %s", tt.wantUID, uid)
+			}
+		})
+	}
+}
+
+func TestIdentityRefForServer(t *testing.T) {
+	clusterIdentity := &infrav1.VSphereIdentityReference{Kind: infrav1.VSphereClusterIdentityKind, Name: "primary"}
+	siteIdentity := &infrav1.VSphereIdentityReference{Kind: infrav1.SecretKind, Name: "site-b"}
+	cluster := &infrav1.VSphereCluster{
+		Spec: infrav1.VSphereClusterSpec{
+			Server:      "vcenter-a",
+			IdentityRef: clusterIdentity,
+			VCenters: []infrav1.VCenterSpec{
+				{Server: "vcenter-b", IdentityRef: siteIdentity},
+				{Server: "vcenter-c"},
+			},
+		},
+	}
+	tests := []struct {
+		server string
+		want   *infrav1.VSphereIdentityReference
+	}{
+		{server: "vcenter-a", want: clusterIdentity},
+		{server: "vcenter-b", want: siteIdentity},
+		{server: "vcenter-c", want: clusterIdentity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.server, func(t *testing.T) {
+			if got := IdentityRefForServer(cluster, tt.server); got != tt.want {
+				t.Errorf("IdentityRefForServer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsNamespaceAllowed(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"team": "a"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Labels: map[string]string{"team": "b"}}},
+	).Build()
+	teamA := metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}
+
+	tests := []struct {
+		name              string
+		allowedNamespaces *infrav1.AllowedNamespaces
+		namespace         string
+		want              bool
+	}{
+		{name: "nil allowed namespaces", namespace: "team-a", want: false},
+		{name: "empty allowed namespaces", allowedNamespaces: &infrav1.AllowedNamespaces{}, namespace: "team-b", want: true},
+		{name: "matching selector", allowedNamespaces: &infrav1.AllowedNamespaces{Selector: teamA}, namespace: "team-a", want: true},
+		{name: "not matching selector", allowedNamespaces: &infrav1.AllowedNamespaces{Selector: teamA}, namespace: "team-b", want: false},
+		{name: "listed namespace", allowedNamespaces: &infrav1.AllowedNamespaces{NamespaceList: []string{"team-b"}}, namespace: "team-b", want: true},
+		{name: "not listed namespace", allowedNamespaces: &infrav1.AllowedNamespaces{NamespaceList: []string{"team-b"}}, namespace: "team-a", want: false},
+		{name: "listed or matching selector", allowedNamespaces: &infrav1.AllowedNamespaces{NamespaceList: []string{"team-b"}, Selector: teamA}, namespace: "team-a", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity := &infrav1.VSphereClusterIdentity{Spec: infrav1.VSphereClusterIdentitySpec{AllowedNamespaces: tt.allowedNamespaces}}
+			got, err := IsNamespaceAllowed(context.Background(), c, identity, tt.namespace)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("IsNamespaceAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultIdentityRef(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Annotations: map[string]string{infrav1.DefaultIdentityAnnotation: "team-a"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}},
+	).Build()
+
+	ref, err := DefaultIdentityRef(context.Background(), c, "team-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref == nil || ref.Kind != infrav1.VSphereClusterIdentityKind || ref.Name != "team-a" {
+		t.Errorf("Expected a reference to VSphereClusterIdentity team-a, got %+v", ref)
+	}
+
+	ref, err = DefaultIdentityRef(context.Background(), c, "team-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref != nil {
+		t.Errorf("Expected no default identity, got %+v", ref)
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package identity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+const (
+	defaultVaultAuthPath = "kubernetes"
+
+	// vaultStaticRenewInterval is how often the credentials without a lease,
+	// e.g. read from a KV secrets engine, are retrieved again.
+	vaultStaticRenewInterval = 5 * time.Minute
+)
+
+var (
+	// serviceAccountTokenFile is the token the controller manager logs in to
+	// Vault with.
+	serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	vaultHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+	// vaultCredentials holds the credentials read from Vault until they are
+	// to be renewed, keyed by the credential source.
+	vaultCredentials   = map[infrav1.VaultCredentialSource]Credentials{}
+	vaultCredentialsMu sync.Mutex
+)
+
+// vaultProvider reads short-lived credentials from HashiCorp Vault.
+type vaultProvider struct {
+	source infrav1.VaultCredentialSource
+}
+
+// NewVaultProvider returns a CredentialProvider reading the credentials from
+// the Vault source. The credentials are renewed once two thirds of their
+// lease have elapsed.
+func NewVaultProvider(source *infrav1.VaultCredentialSource) CredentialProvider {
+	return vaultProvider{source: *source}
+}
+
+func (p vaultProvider) Retrieve(ctx context.Context) (*Credentials, error) {
+	vaultCredentialsMu.Lock()
+	defer vaultCredentialsMu.Unlock()
+
+	if credentials, ok := vaultCredentials[p.source]; ok && time.Now().Before(credentials.RenewAt) {
+		return &credentials, nil
+	}
+
+	token, err := p.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := p.read(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	vaultCredentials[p.source] = *credentials
+	return credentials, nil
+}
+
+// login logs in to Vault with the Kubernetes auth method and returns the
+// client token.
+func (p vaultProvider) login(ctx context.Context) (string, error) {
+	jwt, err := os.ReadFile(serviceAccountTokenFile)
+	if err != nil {
+		return "", fmt.Errorf("unable to read service account token: %w", err)
+	}
+	authPath := p.source.AuthPath
+	if authPath == "" {
+		authPath = defaultVaultAuthPath
+	}
+	body, err := json.Marshal(map[string]string{
+		"role": p.source.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var res struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := p.do(ctx, http.MethodPost, "auth/"+strings.Trim(authPath, "/")+"/login", "", body, &res); err != nil {
+		return "", fmt.Errorf("unable to log in to vault with role %s: %w", p.source.Role, err)
+	}
+	if res.Auth.ClientToken == "" {
+		return "", fmt.Errorf("unable to log in to vault with role %s: no client token", p.source.Role)
+	}
+	return res.Auth.ClientToken, nil
+}
+
+// read reads the credentials at the path of the source.
+func (p vaultProvider) read(ctx context.Context, token string) (*Credentials, error) {
+	var res struct {
+		LeaseDuration int                    `json:"lease_duration"`
+		Data          map[string]interface{} `json:"data"`
+	}
+	if err := p.do(ctx, http.MethodGet, strings.Trim(p.source.Path, "/"), token, nil, &res); err != nil {
+		return nil, fmt.Errorf("unable to read vault secret %s: %w", p.source.Path, err)
+	}
+
+	data := res.Data
+	// Secrets of the KV version 2 engine nest the data with its metadata.
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+	username, _ := data[UsernameKey].(string)
+	password, _ := data[PasswordKey].(string)
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("vault secret %s does not contain a %s and a %s", p.source.Path, UsernameKey, PasswordKey)
+	}
+
+	renewAfter := vaultStaticRenewInterval
+	if res.LeaseDuration > 0 {
+		renewAfter = time.Duration(res.LeaseDuration) * time.Second * 2 / 3
+	}
+	return &Credentials{
+		Username: username,
+		Password: password,
+		RenewAt:  time.Now().Add(renewAfter),
+	}, nil
+}
+
+func (p vaultProvider) do(ctx context.Context, method, path, token string, body []byte, res interface{}) error {
+	url := strings.TrimSuffix(p.source.Address, "/") + "/v1/" + path
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+
+	resp, err := vaultHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errRes struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&errRes)
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.Join(errRes.Errors, ", "))
+	}
+	return json.NewDecoder(resp.Body).Decode(res)
+}
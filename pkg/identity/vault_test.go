@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+func TestVaultProvider(t *testing.T) {
+	g := NewWithT(t)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	g.Expect(os.WriteFile(tokenFile, []byte("service-account-token\n"), 0600)).To(Succeed())
+	defaultTokenFile := serviceAccountTokenFile
+	serviceAccountTokenFile = tokenFile
+	defer func() {
+		serviceAccountTokenFile = defaultTokenFile
+	}()
+
+	reads := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/auth/kubernetes/login", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req["role"] != "capv" || req["jwt"] != "service-account-token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"auth":{"client_token":"vault-token"}}`))
+	})
+	mux.HandleFunc("/v1/vsphere/creds/capv", func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.Header.Get("X-Vault-Token")).To(Equal("vault-token"))
+		reads++
+		_, _ = w.Write([]byte(`{"lease_duration":3600,"data":{"username":"user","password":"pass"}}`))
+	})
+	mux.HandleFunc("/v1/secret/data/capv", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"data":{"username":"kv-user","password":"kv-pass"},"metadata":{"version":1}}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Run("reads leased credentials and caches them until renewal", func(t *testing.T) {
+		g := NewWithT(t)
+		provider := NewVaultProvider(&infrav1.VaultCredentialSource{Address: server.URL, Path: "vsphere/creds/capv", Role: "capv"})
+
+		creds, err := provider.Retrieve(context.Background())
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(creds.Username).To(Equal("user"))
+		g.Expect(creds.Password).To(Equal("pass"))
+		g.Expect(creds.RenewAt).To(BeTemporally("~", time.Now().Add(40*time.Minute), time.Minute))
+
+		_, err = provider.Retrieve(context.Background())
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(reads).To(Equal(1))
+	})
+
+	t.Run("reads credentials from a KV version 2 secret", func(t *testing.T) {
+		g := NewWithT(t)
+		provider := NewVaultProvider(&infrav1.VaultCredentialSource{Address: server.URL, Path: "secret/data/capv", Role: "capv"})
+
+		creds, err := provider.Retrieve(context.Background())
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(creds.Username).To(Equal("kv-user"))
+		g.Expect(creds.Password).To(Equal("kv-pass"))
+		g.Expect(creds.RenewAt).To(BeTemporally("~", time.Now().Add(vaultStaticRenewInterval), time.Minute))
+	})
+
+	t.Run("fails to log in with another role", func(t *testing.T) {
+		g := NewWithT(t)
+		provider := NewVaultProvider(&infrav1.VaultCredentialSource{Address: server.URL, Path: "vsphere/creds/capv", Role: "other"})
+
+		_, err := provider.Retrieve(context.Background())
+		g.Expect(err).To(MatchError(ContainSubstring("permission denied")))
+	})
+}
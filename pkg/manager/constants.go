@@ -36,4 +36,12 @@ const (
 
 	// DefaultLeaderElectionID is the default value for the eponymous manager option.
 	DefaultLeaderElectionID = DefaultPodName + "-runtime"
+
+	// sessionLogoutTimeout bounds the logout of the vCenter sessions on
+	// shutdown, within the graceful shutdown timeout of the manager.
+	sessionLogoutTimeout = 10 * time.Second
+
+	// sessionEvictionInterval is the interval between two evictions of the
+	// idle vCenter sessions.
+	sessionEvictionInterval = time.Minute
 )
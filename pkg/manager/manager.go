@@ -19,6 +19,8 @@ package manager
 import (
 	goctx "context"
 	"fmt"
+	"io"
+	"net"
 	"os"
 
 	"github.com/pkg/errors"
@@ -27,17 +29,21 @@ import (
 	ncpv1 "github.com/vmware-tanzu/vm-operator/external/ncp/api/v1alpha1"
 	topologyv1 "github.com/vmware-tanzu/vm-operator/external/tanzu-topology/api/v1alpha1"
 	"gopkg.in/fsnotify.v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+	expv1 "sigs.k8s.io/cluster-api/exp/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	infrav1a3 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1alpha3"
 	infrav1a4 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1alpha4"
 	infrav1b1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	vmwarev1b1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/vmware/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/audit"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
 )
 
 // Manager is a CAPV controller manager.
@@ -59,6 +65,7 @@ func New(opts Options) (Manager, error) {
 	_ = infrav1a4.AddToScheme(opts.Scheme)
 	_ = infrav1b1.AddToScheme(opts.Scheme)
 	_ = bootstrapv1.AddToScheme(opts.Scheme)
+	_ = expv1.AddToScheme(opts.Scheme)
 	_ = vmwarev1b1.AddToScheme(opts.Scheme)
 	_ = vmoprv1.AddToScheme(opts.Scheme)
 	_ = ncpv1.AddToScheme(opts.Scheme)
@@ -66,6 +73,15 @@ func New(opts Options) (Manager, error) {
 	_ = topologyv1.AddToScheme(opts.Scheme)
 	// +kubebuilder:scaffold:scheme
 
+	internalIPCIDRs := make([]*net.IPNet, 0, len(opts.InternalIPCIDRs))
+	for _, cidr := range opts.InternalIPCIDRs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid internal IP CIDR %q", cidr)
+		}
+		internalIPCIDRs = append(internalIPCIDRs, ipNet)
+	}
+
 	podName, err := os.Hostname()
 	if err != nil {
 		podName = DefaultPodName
@@ -77,24 +93,66 @@ func New(opts Options) (Manager, error) {
 		return nil, errors.Wrap(err, "unable to create manager")
 	}
 
+	if err := setupAudit(opts, mgr); err != nil {
+		return nil, err
+	}
+
+	// Log the vCenter sessions out when the manager stops, rather than
+	// leaving them to expire on vCenter after every restart.
+	if err := mgr.Add(sessionLogout{}); err != nil {
+		return nil, errors.Wrap(err, "unable to add session logout to the manager")
+	}
+
+	session.SetCacheOptions(session.CacheOptions{
+		MaxEntries:   opts.SessionCacheMaxEntries,
+		IdleTTL:      opts.SessionIdleTTL,
+		InventoryTTL: opts.InventoryCacheTTL,
+	})
+	if opts.SessionIdleTTL > 0 {
+		if err := mgr.Add(sessionEviction{}); err != nil {
+			return nil, errors.Wrap(err, "unable to add session eviction to the manager")
+		}
+	}
+
 	// Build the controller manager context.
 	controllerManagerContext := &context.ControllerManagerContext{
-		Context:                 goctx.Background(),
-		WatchNamespace:          opts.Namespace,
-		Namespace:               opts.PodNamespace,
-		Name:                    opts.PodName,
-		LeaderElectionID:        opts.LeaderElectionID,
-		LeaderElectionNamespace: opts.LeaderElectionNamespace,
-		MaxConcurrentReconciles: opts.MaxConcurrentReconciles,
-		Client:                  mgr.GetClient(),
-		Logger:                  opts.Logger.WithName(opts.PodName),
-		Recorder:                record.New(mgr.GetEventRecorderFor(fmt.Sprintf("%s/%s", opts.PodNamespace, podName))),
-		Scheme:                  opts.Scheme,
-		Username:                opts.Username,
-		Password:                opts.Password,
-		EnableKeepAlive:         opts.EnableKeepAlive,
-		KeepAliveDuration:       opts.KeepAliveDuration,
-		NetworkProvider:         opts.NetworkProvider,
+		Context:                           goctx.Background(),
+		WatchNamespace:                    opts.Namespace,
+		Namespace:                         opts.PodNamespace,
+		Name:                              opts.PodName,
+		LeaderElectionID:                  opts.LeaderElectionID,
+		LeaderElectionNamespace:           opts.LeaderElectionNamespace,
+		MaxConcurrentReconciles:           opts.MaxConcurrentReconciles,
+		VSphereVMConcurrency:              opts.VSphereVMConcurrency,
+		VSphereMachineConcurrency:         opts.VSphereMachineConcurrency,
+		VSphereClusterConcurrency:         opts.VSphereClusterConcurrency,
+		VSphereDeploymentZoneConcurrency:  opts.VSphereDeploymentZoneConcurrency,
+		MaxConcurrentVCenterOperations:    opts.MaxConcurrentVCenterOperations,
+		Client:                            mgr.GetClient(),
+		Logger:                            opts.Logger.WithName(opts.PodName),
+		Recorder:                          record.New(mgr.GetEventRecorderFor(fmt.Sprintf("%s/%s", opts.PodNamespace, podName))),
+		Scheme:                            opts.Scheme,
+		Username:                          opts.Username,
+		Password:                          opts.Password,
+		EnableKeepAlive:                   opts.EnableKeepAlive,
+		KeepAliveDuration:                 opts.KeepAliveDuration,
+		KeepAliveRetries:                  opts.KeepAliveRetries,
+		VCenterQPS:                        opts.VCenterQPS,
+		VCenterBurst:                      opts.VCenterBurst,
+		VCenterTLSPolicy:                  opts.VCenterTLSPolicy,
+		MaxConcurrentClonesPerTemplate:    opts.MaxConcurrentClonesPerTemplate,
+		VMStateTTL:                        opts.VMStateTTL,
+		InternalIPCIDRs:                   internalIPCIDRs,
+		CapacityHeadroomPercent:           opts.CapacityHeadroomPercent,
+		CloneTimeout:                      opts.CloneTimeout,
+		OrphanedVMSweepInterval:           opts.OrphanedVMSweepInterval,
+		DeleteOrphanedVMs:                 opts.DeleteOrphanedVMs,
+		VMForceDeleteTimeout:              opts.VMForceDeleteTimeout,
+		SkipInfraDeletionOnUnreachable:    opts.SkipInfraDeletionOnUnreachable,
+		VCenterUnreachableTimeout:         opts.VCenterUnreachableTimeout,
+		RestoreRecoveryMode:               opts.RestoreRecoveryMode,
+		BootstrapDataCompressionThreshold: opts.BootstrapDataCompressionThreshold,
+		NetworkProvider:                   opts.NetworkProvider,
 	}
 
 	// Add the requested items to the manager.
@@ -155,3 +213,65 @@ func InitializeWatch(ctx *context.ControllerManagerContext, managerOpts *Options
 
 	return watch, err
 }
+
+// setupAudit sets the auditor of the vCenter operations from the options.
+func setupAudit(opts Options, mgr ctrl.Manager) error {
+	if opts.VCenterAuditLogPath == "" && !opts.VCenterAuditEvents {
+		return nil
+	}
+	var out io.Writer
+	switch opts.VCenterAuditLogPath {
+	case "":
+	case "-":
+		out = os.Stdout
+	default:
+		f, err := os.OpenFile(opts.VCenterAuditLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return errors.Wrapf(err, "unable to open vCenter audit log %s", opts.VCenterAuditLogPath)
+		}
+		out = f
+	}
+	var recorder record.Recorder
+	if opts.VCenterAuditEvents {
+		recorder = record.New(mgr.GetEventRecorderFor("vcenter-audit"))
+	}
+	audit.SetDefault(audit.New(out, recorder))
+	return nil
+}
+
+// sessionLogout is a Runnable which drains the watchers of the cached vCenter
+// sessions and logs them out once the manager stops.
+type sessionLogout struct{}
+
+// Start waits for the manager to stop and logs the sessions out within the
+// session logout timeout.
+func (sessionLogout) Start(ctx goctx.Context) error {
+	<-ctx.Done()
+	logoutCtx, cancel := goctx.WithTimeout(goctx.Background(), sessionLogoutTimeout)
+	defer cancel()
+	session.LogoutAll(logoutCtx)
+	return nil
+}
+
+// NeedLeaderElection returns false as every replica holds the sessions it
+// has created.
+func (sessionLogout) NeedLeaderElection() bool {
+	return false
+}
+
+// sessionEviction is a Runnable which logs out the cached vCenter sessions
+// which were not used for the session idle TTL.
+type sessionEviction struct{}
+
+// Start evicts the idle sessions every session eviction interval until the
+// manager stops.
+func (sessionEviction) Start(ctx goctx.Context) error {
+	wait.UntilWithContext(ctx, session.EvictIdleSessions, sessionEvictionInterval)
+	return nil
+}
+
+// NeedLeaderElection returns false as every replica holds the sessions it
+// has created.
+func (sessionEviction) NeedLeaderElection() bool {
+	return false
+}
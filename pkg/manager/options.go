@@ -28,7 +28,9 @@ import (
 	ctrlmgr "sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/yaml"
 
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 )
 
 // AddToManagerFunc is a function that can be optionally specified with
@@ -50,6 +52,21 @@ type Options struct {
 	// Defaults to the eponymous constant in this package.
 	MaxConcurrentReconciles int
 
+	// VSphereVMConcurrency, VSphereMachineConcurrency,
+	// VSphereClusterConcurrency, and VSphereDeploymentZoneConcurrency are the
+	// maximum number of concurrent reconciles of the eponymous controllers.
+	//
+	// Default to MaxConcurrentReconciles.
+	VSphereVMConcurrency             int
+	VSphereMachineConcurrency        int
+	VSphereClusterConcurrency        int
+	VSphereDeploymentZoneConcurrency int
+
+	// MaxConcurrentVCenterOperations is the maximum number of long-running
+	// vCenter operations, e.g. OVF exports, which run in the background at
+	// once. A value of 0 means there is no limit.
+	MaxConcurrentVCenterOperations int
+
 	// LeaderElectionNamespace is the namespace in which the pod running the
 	// controller maintains a leader election lock
 	//
@@ -73,6 +90,111 @@ type Options struct {
 	// in keepalive handler
 	KeepAliveDuration time.Duration
 
+	// KeepAliveRetries is the number of times a keepalive failing with a
+	// transient error is retried before the keepalive stops.
+	KeepAliveRetries int
+
+	// SessionCacheMaxEntries is the maximum number of vCenter sessions kept
+	// by the manager, beyond which the least recently used ones are logged
+	// out. A value of 0 does not limit the number of sessions.
+	SessionCacheMaxEntries int
+
+	// SessionIdleTTL is the time after which a vCenter session which was not
+	// used is logged out. A value of 0 keeps idle sessions.
+	SessionIdleTTL time.Duration
+
+	// InventoryCacheTTL is the time the datacenters, folders, resource pools,
+	// networks and datastores looked up in vCenter are cached for. A value of
+	// 0 disables the cache.
+	InventoryCacheTTL time.Duration
+
+	// VCenterQPS is the maximum number of vCenter API calls per second made
+	// against a vCenter endpoint. A value of 0 disables the rate limit.
+	VCenterQPS float64
+
+	// VCenterBurst is the maximum number of vCenter API calls which can be
+	// made at once against a vCenter endpoint.
+	VCenterBurst int
+
+	// VCenterTLSPolicy is the TLS policy of the connections to the vCenter
+	// endpoints, which can be overridden per VSphereClusterIdentity.
+	VCenterTLSPolicy infrav1.VCenterTLSPolicy
+
+	// VCenterAuditLogPath is the file the audit log of the vCenter operations
+	// changing the inventory is appended to, or - for the standard output.
+	// The operations are not logged when it is empty.
+	VCenterAuditLogPath string
+
+	// VCenterAuditEvents emits the audited vCenter operations as Events of
+	// the objects initiating them.
+	VCenterAuditEvents bool
+
+	// Tracing describes how the spans of the reconciliations and of the
+	// vCenter API calls they make are exported.
+	Tracing tracing.Options
+
+	// MaxConcurrentClonesPerTemplate is the maximum number of in-flight
+	// clones of a template. A value of 0 means there is no limit.
+	MaxConcurrentClonesPerTemplate int
+
+	// VMStateTTL is the time the guest network, the power state and the
+	// VMware Tools status of the VMs of a cluster, retrieved at once for all
+	// of them, are used by the reconciliations of their VSphereVMs for. A
+	// value of 0 retrieves them VM by VM.
+	VMStateTTL time.Duration
+
+	// InternalIPCIDRs are the CIDRs of the IP addresses of the VMs which are
+	// published as internal IPs in the Machine addresses. The other IP
+	// addresses are published as external IPs.
+	InternalIPCIDRs []string
+
+	// CapacityHeadroomPercent is the percentage of the CPU, the memory and
+	// the datastore capacity which must remain free after a VM is cloned. A
+	// negative value disables the capacity check before clones.
+	CapacityHeadroomPercent int
+
+	// CloneTimeout is the duration after which a clone task which has not
+	// completed is reported as timed out. A value of 0 disables the timeout.
+	CloneTimeout time.Duration
+
+	// OrphanedVMSweepInterval is the minimum interval between two sweeps of
+	// the VMs of a cluster which are not backed by a VSphereVM anymore. A
+	// value of 0 disables the sweep.
+	OrphanedVMSweepInterval time.Duration
+
+	// DeleteOrphanedVMs destroys the VMs found orphaned by two consecutive
+	// sweeps, rather than only reporting them in the VSphereCluster status.
+	DeleteOrphanedVMs bool
+
+	// VMForceDeleteTimeout is the duration after which the finalizer of the
+	// VSphereVMs of a deleted cluster still being deleted, whose VM is not
+	// found in vCenter anymore, is removed. A value of 0 disables the
+	// removal.
+	VMForceDeleteTimeout time.Duration
+
+	// SkipInfraDeletionOnUnreachable removes the finalizer of the VSphereVMs
+	// being deleted whose vCenter has been unreachable for longer than the
+	// VCenterUnreachableTimeout, without destroying their VMs, which may be
+	// left behind in vCenter.
+	SkipInfraDeletionOnUnreachable bool
+
+	// VCenterUnreachableTimeout is the duration a vCenter must be unreachable
+	// before the VSphereVMs being deleted are released without destroying
+	// their VMs, when SkipInfraDeletionOnUnreachable is set or their
+	// VSphereCluster has the force-cleanup annotation.
+	VCenterUnreachableTimeout time.Duration
+
+	// RestoreRecoveryMode points the owner references of the identity
+	// secrets which reference a VSphereCluster or a VSphereClusterIdentity
+	// of the same name but another UID, as left by the restore of a backup,
+	// to the restored owner, and restores their finalizer.
+	RestoreRecoveryMode bool
+
+	// BootstrapDataCompressionThreshold is the size in bytes above which the
+	// bootstrap data and the metadata of VMs are gzip compressed. A value of
+	// 0 disables the compression.
+	BootstrapDataCompressionThreshold int
+
 	// CredentialsFile is the file that contains credentials of CAPV
 	CredentialsFile string
 
@@ -98,6 +220,17 @@ func (o *Options) defaults() {
 		o.PodName = DefaultPodName
 	}
 
+	for _, concurrency := range []*int{
+		&o.VSphereVMConcurrency,
+		&o.VSphereMachineConcurrency,
+		&o.VSphereClusterConcurrency,
+		&o.VSphereDeploymentZoneConcurrency,
+	} {
+		if *concurrency <= 0 {
+			*concurrency = o.MaxConcurrentReconciles
+		}
+	}
+
 	if o.KubeConfig == nil {
 		o.KubeConfig = config.GetConfigOrDie()
 	}
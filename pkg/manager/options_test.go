@@ -82,3 +82,22 @@ password: '%s'
 		})
 	}
 }
+
+func TestOptions_Concurrency(t *testing.T) {
+	g := NewWithT(t)
+	o := &Options{
+		KubeConfig:                &rest.Config{},
+		Username:                  "user",
+		Password:                  "password",
+		MaxConcurrentReconciles:   10,
+		VSphereVMConcurrency:      50,
+		VSphereClusterConcurrency: -1,
+	}
+	o.defaults()
+
+	// The concurrency of the controllers defaults to the global one.
+	g.Expect(o.VSphereVMConcurrency).To(Equal(50))
+	g.Expect(o.VSphereMachineConcurrency).To(Equal(10))
+	g.Expect(o.VSphereClusterConcurrency).To(Equal(10))
+	g.Expect(o.VSphereDeploymentZoneConcurrency).To(Equal(10))
+}
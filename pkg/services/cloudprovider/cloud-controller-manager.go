@@ -17,20 +17,106 @@ limitations under the License.
 package cloudprovider
 
 import (
+	"fmt"
+
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
 )
 
 // NOTE: the contents of this file are derived from https://github.com/kubernetes/cloud-provider-vsphere/tree/master/manifests/controller-manager
 
 const (
-	DefaultCPIControllerImage = "gcr.io/cloud-provider-vsphere/cpi/release/manager:v1.18.1"
+	DefaultCPIVersion            = "v1.18.1"
+	CPIControllerImageRepository = "gcr.io/cloud-provider-vsphere/cpi/release/manager"
+	DefaultCPIControllerImage    = CPIControllerImageRepository + ":" + DefaultCPIVersion
+	CPICredentialsSecretName     = "cloud-provider-vsphere-credentials"
 )
 
+// CPIControllerImage returns the cloud-controller-manager image for the given version.
+// The default version is used when version is empty.
+func CPIControllerImage(version string) string {
+	if version == "" {
+		version = DefaultCPIVersion
+	}
+	return fmt.Sprintf("%s:%s", CPIControllerImageRepository, version)
+}
+
+// CloudControllerManagerArgs returns the arguments passed to the cloud-controller-manager container.
+func CloudControllerManagerArgs() []string {
+	return []string{
+		"--v=2",
+		"--cloud-provider=vsphere",
+		"--cloud-config=/etc/cloud/vsphere.conf",
+	}
+}
+
+// VCenter is a vCenter the cloud-controller-manager connects to.
+type VCenter struct {
+	Server      string
+	Thumbprint  string
+	Username    string
+	Password    string
+	Datacenters []string
+}
+
+// CloudControllerManagerCredentialsSecret returns the Secret holding the credentials
+// of the vCenters used by the cloud-controller-manager.
+func CloudControllerManagerCredentialsSecret(vcenters []VCenter) *corev1.Secret {
+	data := map[string][]byte{}
+	for _, vcenter := range vcenters {
+		data[fmt.Sprintf("%s.username", vcenter.Server)] = []byte(vcenter.Username)
+		data[fmt.Sprintf("%s.password", vcenter.Server)] = []byte(vcenter.Password)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      CPICredentialsSecretName,
+			Namespace: "kube-system",
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+}
+
+// CloudControllerManagerCloudConfig returns the cloud config file used by the
+// cloud-controller-manager to connect to the given vCenters. The thumbprint of
+// the first one is the global default.
+func CloudControllerManagerCloudConfig(vcenters []VCenter) (string, error) {
+	global := map[string]interface{}{
+		"secretName":      CPICredentialsSecretName,
+		"secretNamespace": "kube-system",
+	}
+	if len(vcenters) > 0 {
+		global["thumbprint"] = vcenters[0].Thumbprint
+	}
+	vcenterConfigs := map[string]interface{}{}
+	for _, vcenter := range vcenters {
+		vcenterConfig := map[string]interface{}{
+			"server":          vcenter.Server,
+			"thumbprint":      vcenter.Thumbprint,
+			"secretName":      CPICredentialsSecretName,
+			"secretNamespace": "kube-system",
+		}
+		if len(vcenter.Datacenters) > 0 {
+			vcenterConfig["datacenters"] = vcenter.Datacenters
+		}
+		vcenterConfigs[vcenter.Server] = vcenterConfig
+	}
+	config := map[string]interface{}{
+		"global":  global,
+		"vcenter": vcenterConfigs,
+	}
+	configBytes, err := yaml.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return string(configBytes), nil
+}
+
 // CloudControllerManagerServiceAccount returns the ServiceAccount used for the cloud-controller-manager.
 func CloudControllerManagerServiceAccount() *corev1.ServiceAccount {
 	return &corev1.ServiceAccount{
@@ -0,0 +1,60 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+)
+
+func TestCPIControllerImage(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(CPIControllerImage("")).To(Equal(DefaultCPIControllerImage))
+	g.Expect(CPIControllerImage("v1.22.3")).To(Equal("gcr.io/cloud-provider-vsphere/cpi/release/manager:v1.22.3"))
+}
+
+func TestCloudControllerManagerCloudConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	vcenters := []VCenter{
+		{Server: "vcenter.example.com", Thumbprint: "AA:BB", Username: "user", Password: "pass", Datacenters: []string{"dc0", "dc1"}},
+		{Server: "edge.example.com", Thumbprint: "CC:DD", Username: "edge-user", Password: "edge-pass", Datacenters: []string{"dc-edge"}},
+	}
+	cloudConfig, err := CloudControllerManagerCloudConfig(vcenters)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	config := map[string]map[string]interface{}{}
+	g.Expect(yaml.Unmarshal([]byte(cloudConfig), &config)).To(Succeed())
+	g.Expect(config["global"]).To(HaveKeyWithValue("secretName", CPICredentialsSecretName))
+	g.Expect(config["global"]).To(HaveKeyWithValue("thumbprint", "AA:BB"))
+	g.Expect(config["vcenter"]).To(HaveLen(2))
+
+	vcenter := config["vcenter"]["vcenter.example.com"].(map[string]interface{}) //nolint:forcetypeassert
+	g.Expect(vcenter).To(HaveKeyWithValue("thumbprint", "AA:BB"))
+	g.Expect(vcenter["datacenters"]).To(ConsistOf("dc0", "dc1"))
+	edge := config["vcenter"]["edge.example.com"].(map[string]interface{}) //nolint:forcetypeassert
+	g.Expect(edge).To(HaveKeyWithValue("thumbprint", "CC:DD"))
+	g.Expect(edge["datacenters"]).To(ConsistOf("dc-edge"))
+
+	secret := CloudControllerManagerCredentialsSecret(vcenters)
+	g.Expect(secret.Data).To(HaveKeyWithValue("vcenter.example.com.username", []byte("user")))
+	g.Expect(secret.Data).To(HaveKeyWithValue("vcenter.example.com.password", []byte("pass")))
+	g.Expect(secret.Data).To(HaveKeyWithValue("edge.example.com.username", []byte("edge-user")))
+	g.Expect(secret.Data).To(HaveKeyWithValue("edge.example.com.password", []byte("edge-pass")))
+}
@@ -17,10 +17,12 @@ limitations under the License.
 package cloudprovider
 
 import (
+	"fmt"
+
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
-	storagev1beta1 "k8s.io/api/storage/v1beta1"
+	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
@@ -30,18 +32,41 @@ import (
 // NOTE: the contents of this file are derived from https://github.com/kubernetes-sigs/vsphere-csi-driver/tree/master/manifests/1.14
 
 const (
-	DefaultCSIControllerImage     = "gcr.io/cloud-provider-vsphere/csi/release/driver:v2.1.0"
-	DefaultCSINodeDriverImage     = "gcr.io/cloud-provider-vsphere/csi/release/driver:v2.1.0"
+	DefaultCSIVersion             = "v2.1.0"
+	CSIDriverImageRepository      = "gcr.io/cloud-provider-vsphere/csi/release/driver"
+	CSISyncerImageRepository      = "gcr.io/cloud-provider-vsphere/csi/release/syncer"
+	DefaultCSIControllerImage     = CSIDriverImageRepository + ":" + DefaultCSIVersion
+	DefaultCSINodeDriverImage     = CSIDriverImageRepository + ":" + DefaultCSIVersion
 	DefaultCSIAttacherImage       = "quay.io/k8scsi/csi-attacher:v3.0.0"
 	DefaultCSIProvisionerImage    = "quay.io/k8scsi/csi-provisioner:v2.0.0"
-	DefaultCSIMetadataSyncerImage = "gcr.io/cloud-provider-vsphere/csi/release/syncer:v2.1.0"
+	DefaultCSIMetadataSyncerImage = CSISyncerImageRepository + ":" + DefaultCSIVersion
 	DefaultCSILivenessProbeImage  = "quay.io/k8scsi/livenessprobe:v2.1.0"
 	DefaultCSIRegistrarImage      = "quay.io/k8scsi/csi-node-driver-registrar:v2.0.1"
 	CSINamespace                  = metav1.NamespaceSystem
 	CSIControllerName             = "vsphere-csi-controller"
 	CSIFeatureStateConfigMapName  = "internal-feature-states.csi.vsphere.vmware.com"
+	CSIDriverName                 = "csi.vsphere.vmware.com"
+	DefaultCSIStorageClassName    = "vsphere-csi"
+	DefaultCSIFSType              = "ext4"
 )
 
+// CSIStorageConfig returns the images of the CSI driver components for the
+// given driver version. The default version is used when version is empty.
+func CSIStorageConfig(version string) *types.CPIStorageConfig {
+	if version == "" {
+		version = DefaultCSIVersion
+	}
+	return &types.CPIStorageConfig{
+		ControllerImage:     fmt.Sprintf("%s:%s", CSIDriverImageRepository, version),
+		NodeDriverImage:     fmt.Sprintf("%s:%s", CSIDriverImageRepository, version),
+		AttacherImage:       DefaultCSIAttacherImage,
+		ProvisionerImage:    DefaultCSIProvisionerImage,
+		MetadataSyncerImage: fmt.Sprintf("%s:%s", CSISyncerImageRepository, version),
+		LivenessProbeImage:  DefaultCSILivenessProbeImage,
+		RegistrarImage:      DefaultCSIRegistrarImage,
+	}
+}
+
 func CSIControllerServiceAccount() *corev1.ServiceAccount {
 	return &corev1.ServiceAccount{
 		ObjectMeta: metav1.ObjectMeta{
@@ -136,12 +161,12 @@ func CSIControllerClusterRoleBinding() *rbacv1.ClusterRoleBinding {
 	}
 }
 
-func CSIDriver() *storagev1beta1.CSIDriver {
-	return &storagev1beta1.CSIDriver{
+func CSIDriver() *storagev1.CSIDriver {
+	return &storagev1.CSIDriver{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: "csi.vsphere.vmware.com",
+			Name: CSIDriverName,
 		},
-		Spec: storagev1beta1.CSIDriverSpec{
+		Spec: storagev1.CSIDriverSpec{
 			AttachRequired: boolPtr(true),
 			PodInfoOnMount: boolPtr(false),
 		},
@@ -601,6 +626,35 @@ func CSIProvisionerContainer(image string) corev1.Container {
 	}
 }
 
+// CSIStorageClass returns a StorageClass provisioning volumes with the vSphere CSI driver.
+func CSIStorageClass(name string, isDefault bool, parameters map[string]string) *storagev1.StorageClass {
+	reclaimPolicy := corev1.PersistentVolumeReclaimDelete
+	bindingMode := storagev1.VolumeBindingImmediate
+	return &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Annotations: map[string]string{
+				"storageclass.kubernetes.io/is-default-class": fmt.Sprintf("%t", isDefault),
+			},
+		},
+		Provisioner:       CSIDriverName,
+		Parameters:        parameters,
+		ReclaimPolicy:     &reclaimPolicy,
+		VolumeBindingMode: &bindingMode,
+	}
+}
+
+// EnableCSIControllerTopology configures the provisioner of the CSI controller
+// Deployment for topology aware volume provisioning.
+func EnableCSIControllerTopology(deployment *appsv1.Deployment) {
+	containers := deployment.Spec.Template.Spec.Containers
+	for i := range containers {
+		if containers[i].Name == "csi-provisioner" {
+			containers[i].Args = append(containers[i].Args, "--feature-gates=Topology=true", "--strict-topology")
+		}
+	}
+}
+
 func CSICloudConfigSecret(data string) *corev1.Secret {
 	return &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
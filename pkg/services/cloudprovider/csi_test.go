@@ -0,0 +1,50 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCSIStorageConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	storageConfig := CSIStorageConfig("")
+	g.Expect(storageConfig.ControllerImage).To(Equal(DefaultCSIControllerImage))
+	g.Expect(storageConfig.MetadataSyncerImage).To(Equal(DefaultCSIMetadataSyncerImage))
+
+	storageConfig = CSIStorageConfig("v2.4.0")
+	g.Expect(storageConfig.ControllerImage).To(Equal("gcr.io/cloud-provider-vsphere/csi/release/driver:v2.4.0"))
+	g.Expect(storageConfig.NodeDriverImage).To(Equal("gcr.io/cloud-provider-vsphere/csi/release/driver:v2.4.0"))
+	g.Expect(storageConfig.MetadataSyncerImage).To(Equal("gcr.io/cloud-provider-vsphere/csi/release/syncer:v2.4.0"))
+}
+
+func TestEnableCSIControllerTopology(t *testing.T) {
+	g := NewWithT(t)
+
+	deployment := CSIControllerDeployment(CSIStorageConfig(""))
+	EnableCSIControllerTopology(deployment)
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name == "csi-provisioner" {
+			g.Expect(container.Args).To(ContainElements("--feature-gates=Topology=true", "--strict-topology"))
+		} else {
+			g.Expect(container.Args).NotTo(ContainElement("--strict-topology"))
+		}
+	}
+}
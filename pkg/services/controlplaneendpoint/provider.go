@@ -0,0 +1,236 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package controlplaneendpoint provides the built-in providers of the control
+// plane endpoint of a VSphereCluster.
+package controlplaneendpoint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	infrautilv1 "sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+const (
+	// DefaultAPIServerPort is the port of the control plane endpoint when
+	// the provider does not get one.
+	DefaultAPIServerPort = 6443
+
+	// AVILoadBalancerClass is the load balancer class of the Services
+	// reconciled by the Avi Kubernetes Operator.
+	AVILoadBalancerClass = "ako.vmware.com/avi-lb"
+)
+
+// Static uses the control plane endpoint set in the spec of the
+// VSphereCluster.
+type Static struct{}
+
+// ReconcileControlPlaneEndpoint returns the control plane endpoint of the spec
+// once both its host and port are set.
+func (Static) ReconcileControlPlaneEndpoint(ctx *context.ClusterContext) (*infrav1.APIEndpoint, string, error) {
+	endpoint := ctx.VSphereCluster.Spec.ControlPlaneEndpoint
+	if endpoint.Host == "" || endpoint.Port == 0 {
+		return nil, "waiting for the host and port of spec.controlPlaneEndpoint of the VSphereCluster to be set", nil
+	}
+	return &endpoint, "", nil
+}
+
+// KubeVIP uses the host of the control plane endpoint set in the spec of the
+// VSphereCluster as the virtual IP announced by kube-vip on the control plane
+// nodes.
+type KubeVIP struct{}
+
+// ReconcileControlPlaneEndpoint returns the control plane endpoint of the spec
+// once its host is set, with the default port of the API server unless the
+// spec sets one.
+func (KubeVIP) ReconcileControlPlaneEndpoint(ctx *context.ClusterContext) (*infrav1.APIEndpoint, string, error) {
+	endpoint := ctx.VSphereCluster.Spec.ControlPlaneEndpoint
+	if endpoint.Host == "" {
+		return nil, "waiting for spec.controlPlaneEndpoint.host of the VSphereCluster to be set to the virtual IP announced by kube-vip", nil
+	}
+	if endpoint.Port == 0 {
+		endpoint.Port = DefaultAPIServerPort
+	}
+	return &endpoint, "", nil
+}
+
+// External waits for another controller to set the control plane endpoint in
+// the spec of the VSphereCluster.
+type External struct{}
+
+// ReconcileControlPlaneEndpoint returns the control plane endpoint of the spec
+// once another controller has set it.
+func (External) ReconcileControlPlaneEndpoint(ctx *context.ClusterContext) (*infrav1.APIEndpoint, string, error) {
+	endpoint := ctx.VSphereCluster.Spec.ControlPlaneEndpoint
+	if endpoint.Host == "" || endpoint.Port == 0 {
+		return nil, "waiting for an external controller to set spec.controlPlaneEndpoint of the VSphereCluster", nil
+	}
+	return &endpoint, "", nil
+}
+
+// LoadBalancerVM load balances the control plane with the
+// VSphereLoadBalancerVM of the cluster, an active/passive pair of VMs whose
+// virtual IP and port are the control plane endpoint.
+type LoadBalancerVM struct{}
+
+// ReconcileControlPlaneEndpoint returns the control plane endpoint once one of
+// the VMs of the VSphereLoadBalancerVM of the cluster holds its virtual IP.
+func (LoadBalancerVM) ReconcileControlPlaneEndpoint(ctx *context.ClusterContext) (*infrav1.APIEndpoint, string, error) {
+	loadBalancerList := &infrav1.VSphereLoadBalancerVMList{}
+	if err := ctx.Client.List(ctx, loadBalancerList,
+		client.InNamespace(ctx.VSphereCluster.Namespace),
+		client.MatchingLabels{clusterv1.ClusterLabelName: ctx.Cluster.Name}); err != nil {
+		return nil, "", errors.Wrapf(err, "failed to list VSphereLoadBalancerVMs of cluster %s/%s", ctx.Cluster.Namespace, ctx.Cluster.Name)
+	}
+	switch len(loadBalancerList.Items) {
+	case 0:
+		return nil, fmt.Sprintf("waiting for a VSphereLoadBalancerVM labeled %s=%s", clusterv1.ClusterLabelName, ctx.Cluster.Name), nil
+	case 1:
+	default:
+		return nil, "", errors.Errorf("found %d VSphereLoadBalancerVMs labeled %s=%s, expected one", len(loadBalancerList.Items), clusterv1.ClusterLabelName, ctx.Cluster.Name)
+	}
+
+	loadBalancer := loadBalancerList.Items[0]
+	if !loadBalancer.Status.Ready {
+		return nil, fmt.Sprintf("waiting for the VMs of VSphereLoadBalancerVM %s to be provisioned", loadBalancer.Name), nil
+	}
+	port := loadBalancer.Spec.Port
+	if port == 0 {
+		port = infrav1.DefaultLoadBalancerVMPort
+	}
+	return &infrav1.APIEndpoint{Host: loadBalancer.Spec.VirtualIP, Port: port}, "", nil
+}
+
+// NSXALB load balances the control plane with NSX Advanced Load Balancer. It
+// manages a Service of type LoadBalancer, without selector, in the namespace
+// of the VSphereCluster and the Endpoints of the Service with the addresses of
+// the control plane machines. The Avi Kubernetes Operator of the management
+// cluster allocates the virtual IP of the Service, which is the host of the
+// control plane endpoint.
+type NSXALB struct{}
+
+// ServiceName returns the name of the Service of the control plane of the
+// cluster.
+func ServiceName(clusterName string) string {
+	return fmt.Sprintf("%s-control-plane", clusterName)
+}
+
+// ReconcileControlPlaneEndpoint creates or updates the Service and Endpoints
+// of the control plane and returns the control plane endpoint once the
+// virtual IP of the Service is allocated.
+func (NSXALB) ReconcileControlPlaneEndpoint(ctx *context.ClusterContext) (*infrav1.APIEndpoint, string, error) {
+	port := ctx.VSphereCluster.Spec.ControlPlaneEndpoint.Port
+	if port == 0 {
+		port = DefaultAPIServerPort
+	}
+	ownerRef := metav1.OwnerReference{
+		APIVersion: infrav1.GroupVersion.String(),
+		Kind:       "VSphereCluster",
+		Name:       ctx.VSphereCluster.Name,
+		UID:        ctx.VSphereCluster.UID,
+	}
+	meta := metav1.ObjectMeta{
+		Namespace: ctx.VSphereCluster.Namespace,
+		Name:      ServiceName(ctx.VSphereCluster.Name),
+	}
+
+	service := &corev1.Service{ObjectMeta: meta}
+	if _, err := ctrlutil.CreateOrPatch(ctx, ctx.Client, service, func() error {
+		service.SetOwnerReferences([]metav1.OwnerReference{ownerRef})
+		service.Spec.Type = corev1.ServiceTypeLoadBalancer
+		service.Spec.LoadBalancerClass = pointer.String(AVILoadBalancerClass)
+		service.Spec.Ports = []corev1.ServicePort{{
+			Name:     "kube-apiserver",
+			Protocol: corev1.ProtocolTCP,
+			Port:     port,
+		}}
+		// A virtual IP set in the spec of the VSphereCluster is requested
+		// from NSX Advanced Load Balancer.
+		service.Spec.LoadBalancerIP = ctx.VSphereCluster.Spec.ControlPlaneEndpoint.Host
+		return nil
+	}); err != nil {
+		return nil, "", errors.Wrapf(err, "failed to reconcile control plane Service %s/%s", meta.Namespace, meta.Name)
+	}
+
+	addresses, err := controlPlaneAddresses(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	endpoints := &corev1.Endpoints{ObjectMeta: meta}
+	if _, err := ctrlutil.CreateOrPatch(ctx, ctx.Client, endpoints, func() error {
+		endpoints.SetOwnerReferences([]metav1.OwnerReference{ownerRef})
+		endpoints.Subsets = nil
+		if len(addresses) > 0 {
+			endpoints.Subsets = []corev1.EndpointSubset{{
+				Addresses: addresses,
+				Ports: []corev1.EndpointPort{{
+					Name:     "kube-apiserver",
+					Protocol: corev1.ProtocolTCP,
+					Port:     DefaultAPIServerPort,
+				}},
+			}}
+		}
+		return nil
+	}); err != nil {
+		return nil, "", errors.Wrapf(err, "failed to reconcile control plane Endpoints %s/%s", meta.Namespace, meta.Name)
+	}
+
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		host := ingress.IP
+		if host == "" {
+			host = ingress.Hostname
+		}
+		if host != "" {
+			return &infrav1.APIEndpoint{Host: host, Port: port}, "", nil
+		}
+	}
+	return nil, fmt.Sprintf("waiting for NSX Advanced Load Balancer to allocate the virtual IP of Service %s/%s", meta.Namespace, meta.Name), nil
+}
+
+// controlPlaneAddresses returns the preferred IP addresses of the control
+// plane machines of the cluster which are not being deleted.
+func controlPlaneAddresses(ctx *context.ClusterContext) ([]corev1.EndpointAddress, error) {
+	vsphereMachines, err := infrautilv1.GetVSphereMachinesInCluster(ctx, ctx.Client, ctx.Cluster.Namespace, ctx.Cluster.Name)
+	if err != nil {
+		return nil, errors.Wrapf(err,
+			"unable to list VSphereMachines part of VSphereCluster %s/%s", ctx.VSphereCluster.Namespace, ctx.VSphereCluster.Name)
+	}
+
+	var addresses []corev1.EndpointAddress
+	for _, vsphereMachine := range vsphereMachines {
+		if !infrautilv1.IsControlPlaneMachine(vsphereMachine) || !vsphereMachine.DeletionTimestamp.IsZero() {
+			continue
+		}
+		ip, err := infrautilv1.GetMachinePreferredIPAddress(vsphereMachine)
+		if err != nil {
+			continue
+		}
+		addresses = append(addresses, corev1.EndpointAddress{IP: ip})
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i].IP < addresses[j].IP })
+	return addresses, nil
+}
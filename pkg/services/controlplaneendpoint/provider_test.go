@@ -0,0 +1,160 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplaneendpoint
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services"
+)
+
+func TestStaticAndExternal(t *testing.T) {
+	for _, provider := range []services.ControlPlaneEndpointProvider{Static{}, External{}} {
+		g := NewWithT(t)
+		ctx := fake.NewClusterContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+
+		endpoint, waitingFor, err := provider.ReconcileControlPlaneEndpoint(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(endpoint).To(BeNil())
+		g.Expect(waitingFor).To(ContainSubstring("spec.controlPlaneEndpoint"))
+
+		ctx.VSphereCluster.Spec.ControlPlaneEndpoint = infrav1.APIEndpoint{Host: "10.0.0.10", Port: 6443}
+		endpoint, waitingFor, err = provider.ReconcileControlPlaneEndpoint(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(waitingFor).To(BeEmpty())
+		g.Expect(*endpoint).To(Equal(infrav1.APIEndpoint{Host: "10.0.0.10", Port: 6443}))
+	}
+}
+
+func TestKubeVIP(t *testing.T) {
+	g := NewWithT(t)
+	ctx := fake.NewClusterContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+
+	endpoint, waitingFor, err := KubeVIP{}.ReconcileControlPlaneEndpoint(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(endpoint).To(BeNil())
+	g.Expect(waitingFor).To(ContainSubstring("kube-vip"))
+
+	ctx.VSphereCluster.Spec.ControlPlaneEndpoint.Host = "10.0.0.10"
+	endpoint, _, err = KubeVIP{}.ReconcileControlPlaneEndpoint(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(*endpoint).To(Equal(infrav1.APIEndpoint{Host: "10.0.0.10", Port: DefaultAPIServerPort}))
+}
+
+func TestLoadBalancerVM(t *testing.T) {
+	g := NewWithT(t)
+	ctx := fake.NewClusterContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+
+	endpoint, waitingFor, err := LoadBalancerVM{}.ReconcileControlPlaneEndpoint(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(endpoint).To(BeNil())
+	g.Expect(waitingFor).To(ContainSubstring("waiting for a VSphereLoadBalancerVM"))
+
+	loadBalancer := &infrav1.VSphereLoadBalancerVM{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: ctx.Cluster.Namespace,
+			Name:      "lb",
+			Labels:    map[string]string{clusterv1.ClusterLabelName: ctx.Cluster.Name},
+		},
+		Spec: infrav1.VSphereLoadBalancerVMSpec{VirtualIP: "192.168.0.10"},
+	}
+	g.Expect(ctx.Client.Create(ctx, loadBalancer)).To(Succeed())
+
+	endpoint, waitingFor, err = LoadBalancerVM{}.ReconcileControlPlaneEndpoint(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(endpoint).To(BeNil())
+	g.Expect(waitingFor).To(ContainSubstring("to be provisioned"))
+
+	loadBalancer.Status.Ready = true
+	g.Expect(ctx.Client.Status().Update(ctx, loadBalancer)).To(Succeed())
+
+	endpoint, waitingFor, err = LoadBalancerVM{}.ReconcileControlPlaneEndpoint(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(waitingFor).To(BeEmpty())
+	g.Expect(*endpoint).To(Equal(infrav1.APIEndpoint{Host: "192.168.0.10", Port: infrav1.DefaultLoadBalancerVMPort}))
+
+	other := loadBalancer.DeepCopy()
+	other.ResourceVersion = ""
+	other.Name = "lb-2"
+	g.Expect(ctx.Client.Create(ctx, other)).To(Succeed())
+	_, _, err = LoadBalancerVM{}.ReconcileControlPlaneEndpoint(ctx)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestNSXALB(t *testing.T) {
+	g := NewWithT(t)
+	ctx := fake.NewClusterContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+
+	controlPlaneMachine := func(name, ip string) *infrav1.VSphereMachine {
+		return &infrav1.VSphereMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: ctx.Cluster.Namespace,
+				Name:      name,
+				Labels: map[string]string{
+					clusterv1.ClusterLabelName:             ctx.Cluster.Name,
+					clusterv1.MachineControlPlaneLabelName: "",
+				},
+			},
+			Status: infrav1.VSphereMachineStatus{
+				Addresses: []clusterv1.MachineAddress{{Type: clusterv1.MachineExternalIP, Address: ip}},
+			},
+		}
+	}
+	worker := controlPlaneMachine("worker", "10.0.0.3")
+	delete(worker.Labels, clusterv1.MachineControlPlaneLabelName)
+	for _, machine := range []*infrav1.VSphereMachine{
+		controlPlaneMachine("cp-1", "10.0.0.2"),
+		controlPlaneMachine("cp-0", "10.0.0.1"),
+		worker,
+	} {
+		g.Expect(ctx.Client.Create(ctx, machine)).To(Succeed())
+	}
+
+	endpoint, waitingFor, err := NSXALB{}.ReconcileControlPlaneEndpoint(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(endpoint).To(BeNil())
+	g.Expect(waitingFor).To(ContainSubstring("NSX Advanced Load Balancer"))
+
+	key := client.ObjectKey{Namespace: ctx.Cluster.Namespace, Name: ServiceName(ctx.VSphereCluster.Name)}
+	service := &corev1.Service{}
+	g.Expect(ctx.Client.Get(ctx, key, service)).To(Succeed())
+	g.Expect(service.Spec.Type).To(Equal(corev1.ServiceTypeLoadBalancer))
+	g.Expect(*service.Spec.LoadBalancerClass).To(Equal(AVILoadBalancerClass))
+	g.Expect(service.OwnerReferences).To(HaveLen(1))
+	g.Expect(service.OwnerReferences[0].UID).To(Equal(ctx.VSphereCluster.UID))
+
+	endpoints := &corev1.Endpoints{}
+	g.Expect(ctx.Client.Get(ctx, key, endpoints)).To(Succeed())
+	g.Expect(endpoints.Subsets).To(HaveLen(1))
+	g.Expect(endpoints.Subsets[0].Addresses).To(Equal([]corev1.EndpointAddress{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}}))
+
+	service.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "192.168.0.10"}}
+	g.Expect(ctx.Client.Status().Update(ctx, service)).To(Succeed())
+
+	endpoint, waitingFor, err = NSXALB{}.ReconcileControlPlaneEndpoint(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(waitingFor).To(BeEmpty())
+	g.Expect(*endpoint).To(Equal(infrav1.APIEndpoint{Host: "192.168.0.10", Port: DefaultAPIServerPort}))
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dns registers the DNS record of the control plane endpoint of a
+// VSphereCluster with the DNS provider configured by the cluster.
+package dns
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/identity"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services"
+)
+
+const (
+	// Route53AccessKeyIDKey is the key of the Secret of a Route 53 provider
+	// holding the ID of the AWS access key.
+	Route53AccessKeyIDKey = "accessKeyID"
+
+	// Route53SecretAccessKeyKey is the key of the Secret of a Route 53
+	// provider holding the AWS secret access key.
+	Route53SecretAccessKeyKey = "secretAccessKey"
+
+	// Route53SessionTokenKey is the optional key of the Secret of a Route 53
+	// provider holding the AWS session token of temporary credentials.
+	Route53SessionTokenKey = "sessionToken"
+
+	// TSIGSecretKey is the key of the Secret of an RFC 2136 provider holding
+	// the base64 encoded secret of the TSIG key.
+	TSIGSecretKey = "secret"
+)
+
+// NewProvider returns the DNSProvider of the spec, which authenticates with
+// the credentials of the Secret, in the namespace, referenced by the provider.
+func NewProvider(ctx context.Context, c client.Client, namespace string, spec *infrav1.ControlPlaneEndpointDNSSpec) (services.DNSProvider, error) {
+	switch {
+	case spec.Route53 != nil:
+		secret, err := getSecret(ctx, c, namespace, spec.Route53.SecretName, Route53AccessKeyIDKey, Route53SecretAccessKeyKey)
+		if err != nil {
+			return nil, err
+		}
+		return NewRoute53(spec.Route53.HostedZoneID, string(secret.Data[Route53AccessKeyIDKey]),
+			string(secret.Data[Route53SecretAccessKeyKey]), string(secret.Data[Route53SessionTokenKey])), nil
+	case spec.Infoblox != nil:
+		secret, err := getSecret(ctx, c, namespace, spec.Infoblox.SecretName, identity.UsernameKey, identity.PasswordKey)
+		if err != nil {
+			return nil, err
+		}
+		return NewInfoblox(spec.Infoblox, string(secret.Data[identity.UsernameKey]), string(secret.Data[identity.PasswordKey])), nil
+	case spec.RFC2136 != nil:
+		if spec.RFC2136.TSIGKeyName == "" {
+			return NewRFC2136(spec.RFC2136, nil)
+		}
+		secret, err := getSecret(ctx, c, namespace, spec.RFC2136.SecretName, TSIGSecretKey)
+		if err != nil {
+			return nil, err
+		}
+		return NewRFC2136(spec.RFC2136, secret.Data[TSIGSecretKey])
+	default:
+		return nil, errors.New("no DNS provider is set for the control plane endpoint")
+	}
+}
+
+// getSecret returns the Secret, which must hold all the keys.
+func getSecret(ctx context.Context, c client.Client, namespace, name string, keys ...string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	secretKey := client.ObjectKey{Namespace: namespace, Name: name}
+	if err := c.Get(ctx, secretKey, secret); err != nil {
+		return nil, errors.Wrapf(err, "failed to get DNS credentials secret %s", secretKey)
+	}
+	for _, key := range keys {
+		if len(secret.Data[key]) == 0 {
+			return nil, errors.Errorf("DNS credentials secret %s does not contain a %s", secretKey, strings.Join(keys, ", "))
+		}
+	}
+	return secret, nil
+}
+
+// recordType returns the type of the address record of the address.
+func recordType(address net.IP) string {
+	if address.To4() != nil {
+		return "A"
+	}
+	return "AAAA"
+}
+
+// fqdn returns the name in lower case, without trailing dot.
+func fqdn(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+// Infoblox registers the records in a DNS view of an Infoblox grid through
+// the WAPI, as record:a and record:aaaa objects.
+type Infoblox struct {
+	view       string
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// NewInfoblox returns a provider of the records of the DNS view of the spec,
+// which authenticates with the given credentials. The certificate of the
+// grid master is only checked against the thumbprint of the spec, if any.
+func NewInfoblox(spec *infrav1.InfobloxDNSSpec, username, password string) *Infoblox {
+	version := spec.WAPIVersion
+	if version == "" {
+		version = infrav1.DefaultInfobloxWAPIVersion
+	}
+	view := spec.DNSView
+	if view == "" {
+		view = infrav1.DefaultInfobloxView
+	}
+	server := spec.Server
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	return &Infoblox{
+		view:     view,
+		baseURL:  strings.TrimSuffix(server, "/") + "/wapi/" + version + "/",
+		username: username,
+		password: password,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: util.ThumbprintTLSConfig(spec.Thumbprint, "Infoblox grid master"),
+			},
+		},
+	}
+}
+
+// infobloxRecord is the subset of the fields of a WAPI record:a or
+// record:aaaa object used by the provider.
+type infobloxRecord struct {
+	Ref      string `json:"_ref,omitempty"`
+	Name     string `json:"name,omitempty"`
+	View     string `json:"view,omitempty"`
+	IPv4Addr string `json:"ipv4addr,omitempty"`
+	IPv6Addr string `json:"ipv6addr,omitempty"`
+	TTL      *int32 `json:"ttl,omitempty"`
+	UseTTL   *bool  `json:"use_ttl,omitempty"`
+}
+
+// UpsertRecord updates the address and TTL of the first record of the name,
+// or creates one.
+func (i *Infoblox) UpsertRecord(ctx context.Context, name string, address net.IP, ttl int32) error {
+	name, objType := fqdn(name), infobloxObjectType(address)
+	records, err := i.lookup(ctx, objType, name, nil)
+	if err != nil {
+		return err
+	}
+
+	useTTL := true
+	record := infobloxRecord{TTL: &ttl, UseTTL: &useTTL}
+	setAddress(&record, address)
+	if len(records) > 0 {
+		if err := i.do(ctx, http.MethodPut, records[0].Ref, record, nil); err != nil {
+			return errors.Wrapf(err, "failed to update %s %s", objType, name)
+		}
+		return nil
+	}
+	record.Name, record.View = name, i.view
+	if err := i.do(ctx, http.MethodPost, objType, record, nil); err != nil {
+		return errors.Wrapf(err, "failed to create %s %s", objType, name)
+	}
+	return nil
+}
+
+// DeleteRecord deletes the records of the name with the address.
+func (i *Infoblox) DeleteRecord(ctx context.Context, name string, address net.IP) error {
+	name, objType := fqdn(name), infobloxObjectType(address)
+	records, err := i.lookup(ctx, objType, name, address)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := i.do(ctx, http.MethodDelete, record.Ref, nil, nil); err != nil && !isNotFound(err) {
+			return errors.Wrapf(err, "failed to delete %s %s", objType, name)
+		}
+	}
+	return nil
+}
+
+// lookup returns the records of the name in the view, with the address if
+// not nil.
+func (i *Infoblox) lookup(ctx context.Context, objType, name string, address net.IP) ([]infobloxRecord, error) {
+	query := url.Values{"name": {name}, "view": {i.view}}
+	if address != nil {
+		var filter infobloxRecord
+		setAddress(&filter, address)
+		if filter.IPv4Addr != "" {
+			query.Set("ipv4addr", filter.IPv4Addr)
+		} else {
+			query.Set("ipv6addr", filter.IPv6Addr)
+		}
+	}
+	var records []infobloxRecord
+	if err := i.do(ctx, http.MethodGet, objType+"?"+query.Encode(), nil, &records); err != nil {
+		return nil, errors.Wrapf(err, "failed to look up %s %s", objType, name)
+	}
+	return records, nil
+}
+
+func infobloxObjectType(address net.IP) string {
+	return "record:" + strings.ToLower(recordType(address))
+}
+
+func setAddress(record *infobloxRecord, address net.IP) {
+	if ip := address.To4(); ip != nil {
+		record.IPv4Addr = ip.String()
+		return
+	}
+	record.IPv6Addr = address.String()
+}
+
+// statusError is returned for the responses of the grid master with an
+// unexpected status.
+type statusError struct {
+	code    int
+	status  string
+	message string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %s: %s", e.status, e.message)
+}
+
+func isNotFound(err error) bool {
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && statusErr.code == http.StatusNotFound
+}
+
+func (i *Infoblox) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, i.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(i.username, i.password)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errRes struct {
+			Text string `json:"text"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&errRes)
+		return &statusError{code: resp.StatusCode, status: resp.Status, message: errRes.Text}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// fakeGrid is a minimal Infoblox grid master storing the address records by
+// reference.
+type fakeGrid struct {
+	mu      sync.Mutex
+	records map[string]infobloxRecord
+	next    int
+}
+
+func (f *fakeGrid) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/wapi/v2.12/")
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		records := []infobloxRecord{}
+		for _, record := range f.records {
+			if strings.HasPrefix(record.Ref, path+"/") && record.Name == query.Get("name") && record.View == query.Get("view") &&
+				(query.Get("ipv4addr") == "" || record.IPv4Addr == query.Get("ipv4addr")) &&
+				(query.Get("ipv6addr") == "" || record.IPv6Addr == query.Get("ipv6addr")) {
+				records = append(records, record)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(records)
+	case http.MethodPost:
+		var record infobloxRecord
+		_ = json.NewDecoder(r.Body).Decode(&record)
+		f.next++
+		record.Ref = fmt.Sprintf("%s/%d:%s/%s", path, f.next, record.Name, record.View)
+		f.records[record.Ref] = record
+		_ = json.NewEncoder(w).Encode(record.Ref)
+	case http.MethodPut:
+		record, ok := f.records[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&record)
+		f.records[path] = record
+		_ = json.NewEncoder(w).Encode(record.Ref)
+	case http.MethodDelete:
+		if _, ok := f.records[path]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.records, path)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestInfobloxRecords(t *testing.T) {
+	g := NewWithT(t)
+	grid := &fakeGrid{records: map[string]infobloxRecord{}}
+	server := httptest.NewTLSServer(grid)
+	defer server.Close()
+
+	sum := sha256.Sum256(server.Certificate().Raw)
+	provider := NewInfoblox(&infrav1.InfobloxDNSSpec{
+		Server:     server.URL,
+		Thumbprint: fmt.Sprintf("%X", sum),
+		SecretName: "infoblox",
+	}, "admin", "secret")
+	ctx := context.Background()
+
+	g.Expect(provider.UpsertRecord(ctx, "api.cluster.example.com", net.ParseIP("192.168.10.5"), 60)).To(Succeed())
+	g.Expect(provider.UpsertRecord(ctx, "api.cluster.example.com", net.ParseIP("192.168.10.6"), 30)).To(Succeed())
+	g.Expect(grid.records).To(HaveLen(1))
+	for _, record := range grid.records {
+		g.Expect(record.Name).To(Equal("api.cluster.example.com"))
+		g.Expect(record.View).To(Equal("default"))
+		g.Expect(record.IPv4Addr).To(Equal("192.168.10.6"))
+		g.Expect(*record.TTL).To(Equal(int32(30)))
+	}
+
+	g.Expect(provider.DeleteRecord(ctx, "api.cluster.example.com", net.ParseIP("192.168.10.5"))).To(Succeed())
+	g.Expect(grid.records).To(HaveLen(1))
+	g.Expect(provider.DeleteRecord(ctx, "api.cluster.example.com", net.ParseIP("192.168.10.6"))).To(Succeed())
+	g.Expect(grid.records).To(BeEmpty())
+
+	provider.password = "wrong"
+	g.Expect(provider.UpsertRecord(ctx, "api.cluster.example.com", net.ParseIP("192.168.10.5"), 60)).NotTo(Succeed())
+}
@@ -0,0 +1,327 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+const (
+	// DefaultTSIGAlgorithm is the algorithm of the TSIG key of an RFC 2136
+	// provider when the spec does not set one.
+	DefaultTSIGAlgorithm = "hmac-sha256"
+
+	opcodeUpdate = 5
+
+	typeA    = 1
+	typeSOA  = 6
+	typeAAAA = 28
+	typeTSIG = 250
+
+	classIN   = 1
+	classNone = 254
+	classAny  = 255
+
+	// tsigFudge is the time difference, in seconds, allowed between the
+	// controller and the name server.
+	tsigFudge = 300
+
+	rfc2136Timeout = 10 * time.Second
+)
+
+var tsigAlgorithms = map[string]func() hash.Hash{
+	"hmac-sha256": sha256.New,
+	"hmac-sha512": sha512.New,
+}
+
+var rcodeNames = map[byte]string{
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+}
+
+// RFC2136 registers the records with dynamic updates (RFC 2136) of the
+// primary name server of their zone, sent over TCP and signed with a TSIG
+// key (RFC 8945) when the spec sets one. The responses are not verified.
+type RFC2136 struct {
+	server    string
+	zone      string
+	keyName   string
+	algorithm string
+	newHash   func() hash.Hash
+	secret    []byte
+	now       func() time.Time
+}
+
+// NewRFC2136 returns a provider of the records of the zone of the spec, which
+// signs the updates with the base64 encoded secret of the TSIG key of the
+// spec, if any.
+func NewRFC2136(spec *infrav1.RFC2136DNSSpec, secret []byte) (*RFC2136, error) {
+	server := spec.Server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+	}
+	provider := &RFC2136{
+		server: server,
+		zone:   fqdn(spec.Zone),
+		now:    time.Now,
+	}
+	if spec.TSIGKeyName == "" {
+		return provider, nil
+	}
+
+	provider.keyName = fqdn(spec.TSIGKeyName)
+	provider.algorithm = spec.TSIGAlgorithm
+	if provider.algorithm == "" {
+		provider.algorithm = DefaultTSIGAlgorithm
+	}
+	newHash, ok := tsigAlgorithms[provider.algorithm]
+	if !ok {
+		return nil, errors.Errorf("unsupported TSIG algorithm %q", provider.algorithm)
+	}
+	provider.newHash = newHash
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(secret)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid secret of TSIG key %s", spec.TSIGKeyName)
+	}
+	provider.secret = key
+	return provider, nil
+}
+
+// UpsertRecord replaces the record set of the name with one holding the
+// address.
+func (r *RFC2136) UpsertRecord(ctx context.Context, name string, address net.IP, ttl int32) error {
+	typ, rdata := addressRData(address)
+	deleteSet, err := appendRR(nil, name, typ, classAny, 0, nil)
+	if err != nil {
+		return err
+	}
+	add, err := appendRR(deleteSet, name, typ, classIN, uint32(ttl), rdata)
+	if err != nil {
+		return err
+	}
+	if err := r.update(ctx, name, 2, add); err != nil {
+		return errors.Wrapf(err, "failed to upsert %s record %s", recordType(address), fqdn(name))
+	}
+	return nil
+}
+
+// DeleteRecord deletes the record of the name with the address. Deleting a
+// record which does not exist is a no-op for the name server.
+func (r *RFC2136) DeleteRecord(ctx context.Context, name string, address net.IP) error {
+	typ, rdata := addressRData(address)
+	rr, err := appendRR(nil, name, typ, classNone, 0, rdata)
+	if err != nil {
+		return err
+	}
+	if err := r.update(ctx, name, 1, rr); err != nil {
+		return errors.Wrapf(err, "failed to delete %s record %s", recordType(address), fqdn(name))
+	}
+	return nil
+}
+
+// update sends an update of the zone with the given records of its update
+// section to the name server.
+func (r *RFC2136) update(ctx context.Context, name string, count int, records []byte) error {
+	if name = fqdn(name); name != r.zone && !strings.HasSuffix(name, "."+r.zone) {
+		return errors.Errorf("%s is not in zone %s", name, r.zone)
+	}
+
+	var id [2]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return err
+	}
+	msg := make([]byte, 12)
+	copy(msg, id[:])
+	binary.BigEndian.PutUint16(msg[2:], opcodeUpdate<<11)
+	binary.BigEndian.PutUint16(msg[4:], 1)
+	binary.BigEndian.PutUint16(msg[8:], uint16(count))
+	msg, err := appendName(msg, r.zone)
+	if err != nil {
+		return err
+	}
+	msg = appendUint16(msg, typeSOA)
+	msg = appendUint16(msg, classIN)
+	msg = append(msg, records...)
+	if r.keyName != "" {
+		if msg, err = r.sign(msg); err != nil {
+			return err
+		}
+	}
+
+	resp, err := r.exchange(ctx, msg)
+	if err != nil {
+		return err
+	}
+	if len(resp) < 12 || resp[0] != id[0] || resp[1] != id[1] || resp[2]&0x80 == 0 {
+		return errors.Errorf("invalid response of name server %s", r.server)
+	}
+	if rcode := resp[3] & 0x0f; rcode != 0 {
+		name, ok := rcodeNames[rcode]
+		if !ok {
+			name = fmt.Sprintf("RCODE%d", rcode)
+		}
+		return errors.Errorf("name server %s rejected the update of zone %s with %s", r.server, r.zone, name)
+	}
+	return nil
+}
+
+// sign appends the TSIG record of the message, whose MAC covers the message
+// and the TSIG variables.
+func (r *RFC2136) sign(msg []byte) ([]byte, error) {
+	keyName, err := appendName(nil, r.keyName)
+	if err != nil {
+		return nil, err
+	}
+	algorithm, err := appendName(nil, r.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	timeSigned := uint64(r.now().Unix())
+
+	variables := append([]byte{}, keyName...)
+	variables = appendUint16(variables, classAny)
+	variables = appendUint32(variables, 0)
+	variables = append(variables, algorithm...)
+	variables = appendUint48(variables, timeSigned)
+	variables = appendUint16(variables, tsigFudge)
+	variables = appendUint16(variables, 0) // error
+	variables = appendUint16(variables, 0) // other len
+	mac := hmac.New(r.newHash, r.secret)
+	_, _ = mac.Write(msg)
+	_, _ = mac.Write(variables)
+	sum := mac.Sum(nil)
+
+	rdata := append([]byte{}, algorithm...)
+	rdata = appendUint48(rdata, timeSigned)
+	rdata = appendUint16(rdata, tsigFudge)
+	rdata = appendUint16(rdata, uint16(len(sum)))
+	rdata = append(rdata, sum...)
+	rdata = append(rdata, msg[0], msg[1]) // original ID
+	rdata = appendUint16(rdata, 0)        // error
+	rdata = appendUint16(rdata, 0)        // other len
+
+	signed, err := appendRR(append([]byte{}, msg...), r.keyName, typeTSIG, classAny, 0, rdata)
+	if err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint16(signed[10:], 1)
+	return signed, nil
+}
+
+// exchange sends the message to the name server over TCP and returns its
+// response.
+func (r *RFC2136) exchange(ctx context.Context, msg []byte) ([]byte, error) {
+	dialer := net.Dialer{Timeout: rfc2136Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", r.server)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to name server %s", r.server)
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(rfc2136Timeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Write(append(appendUint16(nil, uint16(len(msg))), msg...)); err != nil {
+		return nil, errors.Wrapf(err, "failed to send update to name server %s", r.server)
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, errors.Wrapf(err, "failed to read response of name server %s", r.server)
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, errors.Wrapf(err, "failed to read response of name server %s", r.server)
+	}
+	return resp, nil
+}
+
+// addressRData returns the type and the data of the address record of the
+// address.
+func addressRData(address net.IP) (uint16, []byte) {
+	if ip := address.To4(); ip != nil {
+		return typeA, ip
+	}
+	return typeAAAA, address.To16()
+}
+
+// appendRR appends the resource record in the wire format.
+func appendRR(b []byte, name string, typ, class uint16, ttl uint32, rdata []byte) ([]byte, error) {
+	b, err := appendName(b, name)
+	if err != nil {
+		return nil, err
+	}
+	b = appendUint16(b, typ)
+	b = appendUint16(b, class)
+	b = appendUint32(b, ttl)
+	b = appendUint16(b, uint16(len(rdata)))
+	return append(b, rdata...), nil
+}
+
+// appendName appends the uncompressed name, in lower case, in the wire
+// format.
+func appendName(b []byte, name string) ([]byte, error) {
+	if name = fqdn(name); name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if label == "" || len(label) > 63 {
+				return nil, errors.Errorf("invalid domain name %q", name)
+			}
+			b = append(b, byte(len(label)))
+			b = append(b, label...)
+		}
+	}
+	return append(b, 0), nil
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint48(b []byte, v uint64) []byte {
+	return append(b, byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
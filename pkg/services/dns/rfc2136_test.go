@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// fakeNameServer is a minimal name server applying the updates of a zone
+// signed with a hmac-sha256 TSIG key, and storing the address records by
+// name and type.
+type fakeNameServer struct {
+	mu      sync.Mutex
+	key     []byte
+	records map[string][]string
+}
+
+func (s *fakeNameServer) serve(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err == nil {
+			msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+			if _, err := io.ReadFull(conn, msg); err == nil {
+				resp := append([]byte{}, msg[:12]...)
+				resp[2] |= 0x80
+				resp[3] = s.apply(msg)
+				_, _ = conn.Write(append(appendUint16(nil, uint16(len(resp))), resp...))
+			}
+		}
+		conn.Close()
+	}
+}
+
+// apply applies the update and returns its RCODE.
+func (s *fakeNameServer) apply(msg []byte) byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Verify the MAC of the TSIG record, which is the last record.
+	tsigName, _ := appendName(nil, "capv-key")
+	start := bytes.LastIndex(msg, append(tsigName, 0, typeTSIG, 0, classAny))
+	if binary.BigEndian.Uint16(msg[10:]) != 1 || start < 0 {
+		return 9
+	}
+	unsigned := append([]byte{}, msg[:start]...)
+	binary.BigEndian.PutUint16(unsigned[10:], 0)
+	rdata := msg[start+len(tsigName)+10:]
+	algorithm, _ := appendName(nil, "hmac-sha256")
+	macStart := len(algorithm) + 10
+	macSize := int(binary.BigEndian.Uint16(rdata[macStart-2:]))
+	variables := append(append([]byte{}, tsigName...), 0, classAny, 0, 0, 0, 0)
+	variables = append(variables, rdata[:macStart-2]...)
+	variables = append(variables, 0, 0, 0, 0)
+	mac := hmac.New(sha256.New, s.key)
+	_, _ = mac.Write(unsigned)
+	_, _ = mac.Write(variables)
+	if !hmac.Equal(mac.Sum(nil), rdata[macStart:macStart+macSize]) {
+		return 9
+	}
+
+	// Skip the zone section and apply the update section.
+	zone, _ := appendName(nil, "cluster.example.com")
+	if !bytes.Equal(unsigned[12:12+len(zone)], zone) {
+		return 10
+	}
+	rest := unsigned[12+len(zone)+4:]
+	for i := 0; i < int(binary.BigEndian.Uint16(unsigned[8:])); i++ {
+		end := bytes.IndexByte(rest, 0)
+		var labels []string
+		for n := 0; n < end; n += int(rest[n]) + 1 {
+			labels = append(labels, string(rest[n+1:n+1+int(rest[n])]))
+		}
+		typ := binary.BigEndian.Uint16(rest[end+1:])
+		class := binary.BigEndian.Uint16(rest[end+3:])
+		rdlength := int(binary.BigEndian.Uint16(rest[end+9:]))
+		address := net.IP(rest[end+11 : end+11+rdlength]).String()
+		key := strings.Join(labels, ".") + "/" + map[uint16]string{typeA: "A", typeAAAA: "AAAA"}[typ]
+		switch class {
+		case classAny:
+			delete(s.records, key)
+		case classNone:
+			var kept []string
+			for _, a := range s.records[key] {
+				if a != address {
+					kept = append(kept, a)
+				}
+			}
+			s.records[key] = kept
+		case classIN:
+			s.records[key] = append(s.records[key], address)
+		}
+		rest = rest[end+11+rdlength:]
+	}
+	return 0
+}
+
+func TestRFC2136Records(t *testing.T) {
+	g := NewWithT(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer l.Close()
+	server := &fakeNameServer{key: []byte("0123456789abcdef"), records: map[string][]string{}}
+	go server.serve(l)
+
+	spec := &infrav1.RFC2136DNSSpec{
+		Server:      l.Addr().String(),
+		Zone:        "cluster.example.com.",
+		TSIGKeyName: "capv-key",
+	}
+	provider, err := NewRFC2136(spec, []byte(base64.StdEncoding.EncodeToString(server.key)))
+	g.Expect(err).NotTo(HaveOccurred())
+	ctx := context.Background()
+
+	g.Expect(provider.UpsertRecord(ctx, "api.cluster.example.com", net.ParseIP("192.168.10.5"), 60)).To(Succeed())
+	g.Expect(provider.UpsertRecord(ctx, "api.cluster.example.com", net.ParseIP("192.168.10.6"), 60)).To(Succeed())
+	g.Expect(provider.UpsertRecord(ctx, "api.cluster.example.com", net.ParseIP("fd00::5"), 60)).To(Succeed())
+	g.Expect(server.records).To(Equal(map[string][]string{
+		"api.cluster.example.com/A":    {"192.168.10.6"},
+		"api.cluster.example.com/AAAA": {"fd00::5"},
+	}))
+	g.Expect(provider.DeleteRecord(ctx, "api.cluster.example.com", net.ParseIP("fd00::5"))).To(Succeed())
+	g.Expect(server.records["api.cluster.example.com/AAAA"]).To(BeEmpty())
+
+	// Names out of the zone are rejected, and so are the updates signed
+	// with another key.
+	g.Expect(provider.UpsertRecord(ctx, "api.example.org", net.ParseIP("192.168.10.5"), 60)).To(MatchError(ContainSubstring("not in zone")))
+	provider.secret = []byte("another key")
+	g.Expect(provider.UpsertRecord(ctx, "api.cluster.example.com", net.ParseIP("192.168.10.5"), 60)).To(MatchError(ContainSubstring("NOTAUTH")))
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	route53Endpoint  = "https://route53.amazonaws.com"
+	route53APIPath   = "/2013-04-01"
+	route53Namespace = "https://route53.amazonaws.com/doc/2013-04-01/"
+
+	// route53Region is the region the requests to the global endpoint of
+	// Route 53 are signed for.
+	route53Region  = "us-east-1"
+	route53Service = "route53"
+)
+
+// Route53 registers the records in an Amazon Route 53 hosted zone through
+// the Route 53 API, with requests signed with AWS Signature Version 4.
+type Route53 struct {
+	endpoint        string
+	hostedZoneID    string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	httpClient      *http.Client
+	now             func() time.Time
+}
+
+// NewRoute53 returns a provider of the records of the hosted zone, which
+// authenticates with the given AWS credentials.
+func NewRoute53(hostedZoneID, accessKeyID, secretAccessKey, sessionToken string) *Route53 {
+	return &Route53{
+		endpoint:        route53Endpoint,
+		hostedZoneID:    strings.TrimPrefix(hostedZoneID, "/hostedzone/"),
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		sessionToken:    sessionToken,
+		httpClient:      &http.Client{Timeout: 30 * time.Second},
+		now:             time.Now,
+	}
+}
+
+type route53ResourceRecord struct {
+	Value string `xml:"Value"`
+}
+
+type route53ResourceRecordSet struct {
+	Name            string                  `xml:"Name"`
+	Type            string                  `xml:"Type"`
+	TTL             int32                   `xml:"TTL"`
+	ResourceRecords []route53ResourceRecord `xml:"ResourceRecords>ResourceRecord"`
+}
+
+type route53Change struct {
+	Action            string                   `xml:"Action"`
+	ResourceRecordSet route53ResourceRecordSet `xml:"ResourceRecordSet"`
+}
+
+type route53ChangeRequest struct {
+	XMLName xml.Name        `xml:"ChangeResourceRecordSetsRequest"`
+	XMLNS   string          `xml:"xmlns,attr"`
+	Comment string          `xml:"ChangeBatch>Comment"`
+	Changes []route53Change `xml:"ChangeBatch>Changes>Change"`
+}
+
+type route53ListResponse struct {
+	ResourceRecordSets []route53ResourceRecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+}
+
+type route53ErrorResponse struct {
+	Code    string `xml:"Error>Code"`
+	Message string `xml:"Error>Message"`
+}
+
+// UpsertRecord creates or replaces the record set of the name with one
+// holding the address.
+func (r *Route53) UpsertRecord(ctx context.Context, name string, address net.IP, ttl int32) error {
+	set := route53ResourceRecordSet{
+		Name:            fqdn(name),
+		Type:            recordType(address),
+		TTL:             ttl,
+		ResourceRecords: []route53ResourceRecord{{Value: address.String()}},
+	}
+	if err := r.change(ctx, "UPSERT", set); err != nil {
+		return errors.Wrapf(err, "failed to upsert %s record %s in hosted zone %s", set.Type, set.Name, r.hostedZoneID)
+	}
+	return nil
+}
+
+// DeleteRecord deletes the record set of the name if it holds the address.
+// Route 53 only deletes record sets matching exactly the current one, which
+// is looked up first.
+func (r *Route53) DeleteRecord(ctx context.Context, name string, address net.IP) error {
+	name, typ := fqdn(name), recordType(address)
+	query := url.Values{"name": {name}, "type": {typ}, "maxitems": {"1"}}
+	var list route53ListResponse
+	if err := r.do(ctx, http.MethodGet, r.hostedZonePath()+"/rrset?"+query.Encode(), nil, &list); err != nil {
+		return errors.Wrapf(err, "failed to look up %s record %s in hosted zone %s", typ, name, r.hostedZoneID)
+	}
+	for _, set := range list.ResourceRecordSets {
+		if fqdn(set.Name) != name || set.Type != typ {
+			continue
+		}
+		for _, record := range set.ResourceRecords {
+			if !address.Equal(net.ParseIP(record.Value)) {
+				continue
+			}
+			if err := r.change(ctx, "DELETE", set); err != nil {
+				return errors.Wrapf(err, "failed to delete %s record %s in hosted zone %s", typ, name, r.hostedZoneID)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *Route53) hostedZonePath() string {
+	return route53APIPath + "/hostedzone/" + r.hostedZoneID
+}
+
+func (r *Route53) change(ctx context.Context, action string, set route53ResourceRecordSet) error {
+	body, err := xml.Marshal(route53ChangeRequest{
+		XMLNS:   route53Namespace,
+		Comment: "Control plane endpoint managed by Cluster API Provider vSphere",
+		Changes: []route53Change{{Action: action, ResourceRecordSet: set}},
+	})
+	if err != nil {
+		return err
+	}
+	return r.do(ctx, http.MethodPost, r.hostedZonePath()+"/rrset", append([]byte(xml.Header), body...), nil)
+}
+
+func (r *Route53) do(ctx context.Context, method, path string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, r.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	if r.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", r.sessionToken)
+	}
+	signV4(req, body, r.accessKeyID, r.secretAccessKey, route53Region, route53Service, r.now())
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errRes route53ErrorResponse
+		_ = xml.NewDecoder(resp.Body).Decode(&errRes)
+		return errors.Errorf("unexpected status %s: %s: %s", resp.Status, errRes.Code, errRes.Message)
+	}
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return xml.NewDecoder(resp.Body).Decode(out)
+}
+
+// signV4 signs the request with AWS Signature Version 4, with the host, the
+// X-Amz-* headers and the content type as signed headers.
+func signV4(req *http.Request, body []byte, accessKeyID, secretAccessKey, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for key, values := range req.Header {
+		key = strings.ToLower(key)
+		if strings.HasPrefix(key, "x-amz-") || key == "content-type" {
+			headers[key] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		strings.ReplaceAll(req.URL.Query().Encode(), "+", "%20"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := []byte("AWS4" + secretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
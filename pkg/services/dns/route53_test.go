@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"context"
+	"encoding/xml"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestSignV4(t *testing.T) {
+	g := NewWithT(t)
+
+	// The get-vanilla case of the AWS Signature Version 4 test suite.
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	signV4(req, nil, "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "us-east-1", "service",
+		time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	g.Expect(req.Header.Get("X-Amz-Date")).To(Equal("20150830T123600Z"))
+	g.Expect(req.Header.Get("Authorization")).To(Equal("AWS4-HMAC-SHA256 " +
+		"Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, " +
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"))
+}
+
+// fakeRoute53 is a minimal Route 53 API storing the record sets of a hosted
+// zone by name and type.
+type fakeRoute53 struct {
+	mu      sync.Mutex
+	sets    map[string]route53ResourceRecordSet
+	changes []string
+}
+
+func (f *fakeRoute53) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if r.URL.Path != "/2013-04-01/hostedzone/Z1D633PJN98FT9/rrset" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		var list route53ListResponse
+		key := r.URL.Query().Get("name") + "/" + r.URL.Query().Get("type")
+		if set, ok := f.sets[key]; ok {
+			list.ResourceRecordSets = append(list.ResourceRecordSets, set)
+		}
+		_ = xml.NewEncoder(w).Encode(list)
+	case http.MethodPost:
+		var req route53ChangeRequest
+		_ = xml.NewDecoder(r.Body).Decode(&req)
+		for _, change := range req.Changes {
+			set := change.ResourceRecordSet
+			key := set.Name + "/" + set.Type
+			f.changes = append(f.changes, change.Action+" "+key)
+			switch change.Action {
+			case "UPSERT":
+				f.sets[key] = set
+			case "DELETE":
+				if current, ok := f.sets[key]; !ok || current.TTL != set.TTL {
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(`<ErrorResponse><Error><Code>InvalidChangeBatch</Code><Message>not found</Message></Error></ErrorResponse>`))
+					return
+				}
+				delete(f.sets, key)
+			}
+		}
+		_, _ = w.Write([]byte(`<ChangeResourceRecordSetsResponse/>`))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestRoute53Records(t *testing.T) {
+	g := NewWithT(t)
+	fake := &fakeRoute53{sets: map[string]route53ResourceRecordSet{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	provider := NewRoute53("/hostedzone/Z1D633PJN98FT9", "AKID", "secret", "")
+	provider.endpoint = server.URL
+	ctx := context.Background()
+
+	g.Expect(provider.UpsertRecord(ctx, "API.cluster.example.com.", net.ParseIP("192.168.10.5"), 60)).To(Succeed())
+	g.Expect(provider.UpsertRecord(ctx, "api.cluster.example.com", net.ParseIP("fd00::5"), 30)).To(Succeed())
+	g.Expect(fake.sets).To(HaveKeyWithValue("api.cluster.example.com/A", route53ResourceRecordSet{
+		Name: "api.cluster.example.com", Type: "A", TTL: 60,
+		ResourceRecords: []route53ResourceRecord{{Value: "192.168.10.5"}},
+	}))
+	g.Expect(fake.sets).To(HaveKey("api.cluster.example.com/AAAA"))
+
+	// Only the record set holding the address is deleted, matching its
+	// current TTL.
+	g.Expect(provider.DeleteRecord(ctx, "api.cluster.example.com", net.ParseIP("192.168.10.6"))).To(Succeed())
+	g.Expect(fake.sets).To(HaveLen(2))
+	g.Expect(provider.DeleteRecord(ctx, "api.cluster.example.com", net.ParseIP("fd00::5"))).To(Succeed())
+	g.Expect(fake.sets).To(HaveLen(1))
+	g.Expect(provider.DeleteRecord(ctx, "api.cluster.example.com", net.ParseIP("fd00::5"))).To(Succeed())
+	g.Expect(fake.changes).To(Equal([]string{
+		"UPSERT api.cluster.example.com/A",
+		"UPSERT api.cluster.example.com/AAAA",
+		"DELETE api.cluster.example.com/AAAA",
+	}))
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/feature"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/cluster"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+// controlPlaneAntiAffinityRuleName returns the name of the DRS rule that keeps
+// the control plane VMs of a cluster on different ESXi hosts.
+func controlPlaneAntiAffinityRuleName(namespace, clusterName string) string {
+	return fmt.Sprintf("capv-%s-%s-control-plane-anti-affinity", namespace, clusterName)
+}
+
+// legacyControlPlaneAntiAffinityRuleName returns the name of the rule of the
+// cluster created by previous versions, which is shared by the clusters with
+// the same name in other namespaces.
+func legacyControlPlaneAntiAffinityRuleName(clusterName string) string {
+	return fmt.Sprintf("%s-control-plane-anti-affinity", clusterName)
+}
+
+// isAntiAffinityCandidate returns whether the VSphereVM is a member of the
+// control plane anti-affinity rule of its cluster.
+func isAntiAffinityCandidate(vsphereVM *infrav1.VSphereVM) bool {
+	return feature.Gates.Enabled(feature.NodeAntiAffinity) &&
+		util.IsControlPlaneMachine(vsphereVM) &&
+		vsphereVM.Labels[clusterv1.ClusterLabelName] != ""
+}
+
+// reconcileControlPlaneAntiAffinityRule updates the anti-affinity rule of the
+// compute cluster the VM runs in so it contains the control plane VMs of the
+// VM's cluster that run in the same compute cluster. The VM itself is left out
+// of the rule when it is being deleted.
+func reconcileControlPlaneAntiAffinityRule(ctx *virtualMachineContext) (*object.Task, error) {
+	ccr, err := cluster.ComputeClusterForVM(ctx, ctx.Obj)
+	if err != nil {
+		return nil, err
+	}
+	if ccr == nil {
+		ctx.Logger.V(4).Info("VM is not part of a compute cluster. skipping reconcile anti-affinity rule")
+		return nil, nil
+	}
+
+	clusterName := ctx.VSphereVM.Labels[clusterv1.ClusterLabelName]
+	vsphereVMList := &infrav1.VSphereVMList{}
+	if err := ctx.Client.List(ctx, vsphereVMList,
+		client.InNamespace(ctx.VSphereVM.Namespace),
+		client.MatchingLabels{clusterv1.ClusterLabelName: clusterName},
+		client.HasLabels{clusterv1.MachineControlPlaneLabelName}); err != nil {
+		return nil, errors.Wrapf(err, "unable to list control plane VSphereVMs of cluster %s", clusterName)
+	}
+
+	var vmRefs []types.ManagedObjectReference
+	for i := range vsphereVMList.Items {
+		vsphereVM := &vsphereVMList.Items[i]
+		if !vsphereVM.DeletionTimestamp.IsZero() || vsphereVM.Spec.Server != ctx.VSphereVM.Spec.Server {
+			continue
+		}
+
+		var vmRef types.ManagedObjectReference
+		if vsphereVM.UID == ctx.VSphereVM.UID {
+			vmRef = ctx.Ref
+		} else {
+			objRef, err := ctx.Session.FindByInstanceUUID(ctx, instanceUUID(vsphereVM))
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to find VM for VSphereVM %s", vsphereVM.Name)
+			}
+			if objRef == nil {
+				continue
+			}
+			vmRef = objRef.Reference()
+		}
+
+		vmCCR, err := cluster.ComputeClusterForVM(ctx, object.NewVirtualMachine(ctx.Session.Client.Client, vmRef))
+		if err != nil {
+			return nil, err
+		}
+		if vmCCR != nil && vmCCR.Reference() == ccr.Reference() {
+			vmRefs = append(vmRefs, vmRef)
+		}
+	}
+
+	// The VMs of the cluster are moved out of the rule created by previous
+	// versions first.
+	legacyRuleName := legacyControlPlaneAntiAffinityRuleName(clusterName)
+	legacyRule, err := cluster.FindVMAntiAffinityRule(ctx, ccr, legacyRuleName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to find anti-affinity rule %s", legacyRuleName)
+	}
+	if legacyRule != nil {
+		if others := otherVMs(legacyRule.Vm, vmRefs); len(others) < len(legacyRule.Vm) {
+			task, err := cluster.ReconcileVMAntiAffinityRule(ctx, ccr, legacyRuleName, others)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to reconcile anti-affinity rule %s", legacyRuleName)
+			}
+			if task != nil {
+				return task, nil
+			}
+		}
+	}
+
+	ruleName := controlPlaneAntiAffinityRuleName(ctx.VSphereVM.Namespace, clusterName)
+	task, err := cluster.ReconcileVMAntiAffinityRule(ctx, ccr, ruleName, vmRefs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to reconcile anti-affinity rule %s", ruleName)
+	}
+	return task, nil
+}
+
+// otherVMs returns the VMs which are not excluded.
+func otherVMs(vms, excluded []types.ManagedObjectReference) []types.ManagedObjectReference {
+	others := []types.ManagedObjectReference{}
+	for _, vm := range vms {
+		found := false
+		for _, ref := range excluded {
+			if ref == vm {
+				found = true
+				break
+			}
+		}
+		if !found {
+			others = append(others, vm)
+		}
+	}
+	return others
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	goctx "context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	featuregatetesting "k8s.io/component-base/featuregate/testing"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/feature"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/cluster"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestReconcileControlPlaneAntiAffinityRule(t *testing.T) {
+	g := NewWithT(t)
+	defer featuregatetesting.SetFeatureGateDuringTest(t, feature.Gates, feature.NodeAntiAffinity, true)()
+
+	model := simulator.VPX()
+	model.Host = 0 // ClusterHost only
+
+	simr, err := vcsim.NewBuilder().WithModel(model).Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vmContext := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+	vmContext.VSphereVM.Spec.Server = simr.ServerURL().Host
+	vmContext.VSphereVM.Labels = map[string]string{
+		clusterv1.ClusterLabelName:             "test-cluster",
+		clusterv1.MachineControlPlaneLabelName: "",
+	}
+	g.Expect(vmContext.Client.Update(vmContext, vmContext.VSphereVM)).To(Succeed())
+	g.Expect(isAntiAffinityCandidate(vmContext.VSphereVM)).To(BeTrue())
+
+	authSession, err := session.GetOrCreate(
+		vmContext.Context,
+		session.NewParams().
+			WithServer(vmContext.VSphereVM.Spec.Server).
+			WithUserInfo(simr.Username(), simr.Password()).
+			WithDatacenter("*"))
+	g.Expect(err).NotTo(HaveOccurred())
+	vmContext.Session = authSession
+
+	vms, err := authSession.Finder.VirtualMachineList(vmContext, "DC0_C0_RP0_VM*")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(len(vms)).To(BeNumerically(">=", 2))
+
+	vmCtx := &virtualMachineContext{
+		VMContext: *vmContext,
+		Obj:       vms[0],
+		Ref:       vms[0].Reference(),
+		State:     &infrav1.VirtualMachine{},
+	}
+	ccr, err := cluster.ComputeClusterForVM(vmContext, vms[0])
+	g.Expect(err).NotTo(HaveOccurred())
+	ruleName := controlPlaneAntiAffinityRuleName(vmContext.VSphereVM.Namespace, "test-cluster")
+
+	reconcile := func() {
+		for {
+			task, err := reconcileControlPlaneAntiAffinityRule(vmCtx)
+			g.Expect(err).NotTo(HaveOccurred())
+			if task == nil {
+				return
+			}
+			g.Expect(task.Wait(vmContext)).To(Succeed())
+		}
+	}
+
+	// A single control plane VM does not create a rule.
+	reconcile()
+	rule, err := cluster.FindVMAntiAffinityRule(vmContext, ccr, ruleName)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rule).To(BeNil())
+
+	// A second control plane VM in the same compute cluster creates the rule.
+	var otherVM mo.VirtualMachine
+	g.Expect(vms[1].Properties(vmContext, vms[1].Reference(), []string{"config.instanceUuid"}, &otherVM)).To(Succeed())
+	other := &infrav1.VSphereVM{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: vmContext.VSphereVM.Namespace,
+			Name:      "other-control-plane",
+			UID:       apitypes.UID(otherVM.Config.InstanceUuid),
+			Labels:    vmContext.VSphereVM.Labels,
+		},
+		Spec: vmContext.VSphereVM.Spec,
+	}
+	g.Expect(vmContext.Client.Create(vmContext, other)).To(Succeed())
+
+	reconcile()
+	rule, err = cluster.FindVMAntiAffinityRule(vmContext, ccr, ruleName)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rule).NotTo(BeNil())
+	g.Expect(rule.Vm).To(ConsistOf(vms[0].Reference(), vms[1].Reference()))
+
+	// The VMs are moved out of the rule created by previous versions.
+	legacyRuleName := legacyControlPlaneAntiAffinityRuleName("test-cluster")
+	g.Expect(reconcileRule(vmContext, ccr, legacyRuleName, vms[0].Reference(), vms[1].Reference())).To(Succeed())
+	reconcile()
+	rule, err = cluster.FindVMAntiAffinityRule(vmContext, ccr, legacyRuleName)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rule).To(BeNil())
+	rule, err = cluster.FindVMAntiAffinityRule(vmContext, ccr, ruleName)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rule).NotTo(BeNil())
+
+	// Deleting the other control plane VM removes the rule.
+	g.Expect(vmContext.Client.Delete(vmContext, other)).To(Succeed())
+	reconcile()
+	rule, err = cluster.FindVMAntiAffinityRule(vmContext, ccr, ruleName)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rule).To(BeNil())
+}
+
+func reconcileRule(ctx goctx.Context, ccr *object.ClusterComputeResource, ruleName string, vms ...types.ManagedObjectReference) error {
+	task, err := cluster.ReconcileVMAntiAffinityRule(ctx, ccr, ruleName, vms)
+	if err != nil || task == nil {
+		return err
+	}
+	return task.Wait(ctx)
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/nfc"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/ovf"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// finalSnapshotSuffix is the suffix of the name of the template cloned from
+// a VM before it is destroyed.
+const finalSnapshotSuffix = "-final"
+
+// reconcilePreDeleteBackup backs up the powered off VM before it is
+// destroyed, as requested by the pre-delete backup annotation of the
+// VSphereVM. It returns true once the backup is complete.
+func (vms *VMService) reconcilePreDeleteBackup(ctx *virtualMachineContext) (bool, error) {
+	backup, ok := ctx.VSphereVM.Annotations[infrav1.VMPreDeleteBackupAnnotation]
+	if !ok {
+		return true, nil
+	}
+	if backup == infrav1.VMPreDeleteBackupSnapshot {
+		return vms.cloneFinalSnapshot(ctx)
+	}
+
+	var dir object.DatastorePath
+	if !dir.FromString(backup) {
+		return false, errors.Errorf("invalid datastore path %q in annotation %s of vm %s", backup, infrav1.VMPreDeleteBackupAnnotation, ctx)
+	}
+	return vms.exportOVF(ctx, dir)
+}
+
+// cloneFinalSnapshot clones the VM as a template in its folder. The clone
+// outlives the VM, unlike a snapshot of the VM.
+func (vms *VMService) cloneFinalSnapshot(ctx *virtualMachineContext) (bool, error) {
+	var obj mo.VirtualMachine
+	if err := ctx.Obj.Properties(ctx, ctx.Ref, []string{"parent"}, &obj); err != nil {
+		return false, errors.Wrapf(err, "unable to get folder of vm %s", ctx)
+	}
+	if obj.Parent == nil {
+		return false, errors.Errorf("unable to get folder of vm %s", ctx)
+	}
+	folder := object.NewFolder(ctx.Session.Client.Client, *obj.Parent)
+
+	name := ctx.VSphereVM.Name + finalSnapshotSuffix
+	clone, err := object.NewSearchIndex(ctx.Session.Client.Client).FindChild(ctx, folder, name)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to find final snapshot %q of vm %s", name, ctx)
+	}
+	if clone != nil {
+		return true, nil
+	}
+
+	ctx.Logger.Info("cloning final snapshot of vm", "name", name)
+	task, err := ctx.Obj.Clone(ctx, folder, name, types.VirtualMachineCloneSpec{Template: true})
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to clone final snapshot %q of vm %s", name, ctx)
+	}
+	ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+	ctx.Recorder.Eventf(ctx.VSphereVM, "PreDeleteBackup", "Cloning VM %s to template %s before it is destroyed", ctx.Ref.Value, name)
+	ctx.Logger.Info("wait for final snapshot to be cloned")
+	return false, nil
+}
+
+// exportOVF exports the disks and the OVF descriptor of the VM to a
+// directory named after the VSphereVM in the directory. The descriptor is
+// uploaded last, so a complete export is not repeated. The export streams
+// the disks through the controller and runs in the background, as it takes
+// as long as their size requires. It returns true once the export is
+// complete.
+func (vms *VMService) exportOVF(ctx *virtualMachineContext, dir object.DatastorePath) (bool, error) {
+	datastore, err := ctx.Session.Finder.Datastore(ctx, dir.Datastore)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to find datastore %q to export vm %s", dir.Datastore, ctx)
+	}
+	dir.Path = path.Join(dir.Path, ctx.VSphereVM.Name)
+	descriptorPath := path.Join(dir.Path, ctx.VSphereVM.Name+".ovf")
+	key := ctx.VSphereVM.Namespace + "/" + ctx.VSphereVM.Name + "/export"
+
+	_, err = datastore.Stat(ctx, descriptorPath)
+	switch err.(type) {
+	case nil:
+		operations.forget(key)
+		return true, nil
+	case object.DatastoreNoSuchFileError, object.DatastoreNoSuchDirectoryError:
+	default:
+		return false, errors.Wrapf(err, "unable to find OVF export of vm %s", ctx)
+	}
+
+	// The background export gets its own copy of the VSphereVM, which is
+	// patched once this reconciliation returns.
+	exportCtx := *ctx
+	exportCtx.VSphereVM = ctx.VSphereVM.DeepCopy()
+	done, err := operations.run(key, ctx.MaxConcurrentVCenterOperations, func() error {
+		return vms.exportDisks(&exportCtx, datastore, dir, descriptorPath)
+	}, reconcileVSphereVMOnCompletion(&ctx.VMContext, "export-completed"))
+	if err != nil || !done {
+		return false, err
+	}
+	ctx.Recorder.Eventf(ctx.VSphereVM, "PreDeleteBackup", "Exported VM %s to %s before it is destroyed", ctx.Ref.Value, dir.String())
+	return true, nil
+}
+
+// exportDisks exports the disks of the VM to the directory, and then uploads
+// the OVF descriptor of the VM to the path.
+func (vms *VMService) exportDisks(ctx *virtualMachineContext, datastore *object.Datastore, dir object.DatastorePath, descriptorPath string) error {
+	datacenter, err := ctx.Session.CachedFinder.DatacenterOrDefault(ctx, ctx.VSphereVM.Spec.Datacenter)
+	if err != nil {
+		return errors.Wrapf(err, "unable to find datacenter of vm %s", ctx)
+	}
+	if err := object.NewFileManager(ctx.Session.Client.Client).MakeDirectory(ctx, dir.String(), datacenter, true); err != nil {
+		return errors.Wrapf(err, "unable to create directory %q to export vm %s", dir.String(), ctx)
+	}
+
+	ctx.Logger.Info("exporting vm as OVF", "path", dir.String())
+	lease, err := ctx.Obj.Export(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "unable to export vm %s", ctx)
+	}
+	info, err := lease.Wait(ctx, nil)
+	if err != nil {
+		return errors.Wrapf(err, "unable to export vm %s", ctx)
+	}
+	updater := lease.StartUpdater(ctx, info)
+	defer updater.Done()
+
+	params := types.OvfCreateDescriptorParams{Name: ctx.VSphereVM.Name}
+	for _, item := range info.Items {
+		// Only the disks are exported, not e.g. the NVRAM of the VM.
+		if path.Ext(item.Path) != ".vmdk" {
+			continue
+		}
+		size, err := vms.exportFile(ctx, datastore, path.Join(dir.Path, item.Path), item)
+		if err != nil {
+			_ = lease.Abort(ctx, nil)
+			return errors.Wrapf(err, "unable to export disk %s of vm %s", item.Path, ctx)
+		}
+		item.Size = size
+		params.OvfFiles = append(params.OvfFiles, item.File())
+	}
+	if err := lease.Complete(ctx); err != nil {
+		return errors.Wrapf(err, "unable to export vm %s", ctx)
+	}
+
+	descriptor, err := ovf.NewManager(ctx.Session.Client.Client).CreateDescriptor(ctx, ctx.Obj, params)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create OVF descriptor of vm %s", ctx)
+	}
+	if len(descriptor.Error) > 0 {
+		return errors.Errorf("unable to create OVF descriptor of vm %s: %s", ctx, descriptor.Error[0].LocalizedMessage)
+	}
+	upload := soap.DefaultUpload
+	upload.ContentLength = int64(len(descriptor.OvfDescriptor))
+	if err := datastore.Upload(ctx, strings.NewReader(descriptor.OvfDescriptor), descriptorPath, &upload); err != nil {
+		return errors.Wrapf(err, "unable to upload OVF descriptor of vm %s", ctx)
+	}
+	return nil
+}
+
+// exportFile streams a file of the export lease of the VM to the datastore
+// and returns its size.
+func (vms *VMService) exportFile(ctx *virtualMachineContext, datastore *object.Datastore, name string, item nfc.FileItem) (int64, error) {
+	reader, size, err := ctx.Session.Client.Client.Download(ctx, item.URL, &soap.DefaultDownload)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	upload := soap.DefaultUpload
+	if size > 0 {
+		upload.ContentLength = size
+	}
+	if err := datastore.Upload(ctx, reader, name, &upload); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
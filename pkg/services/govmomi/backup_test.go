@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestReconcilePreDeleteBackup(t *testing.T) {
+	vms := &VMService{}
+
+	t.Run("no backup is taken without the annotation", func(t *testing.T) {
+		g := NewWithT(t)
+		simr, err := vcsim.NewBuilder().Build()
+		g.Expect(err).NotTo(HaveOccurred())
+		defer simr.Destroy()
+
+		vmCtx := newTestVirtualMachineContext(t, simr)
+		done, err := vms.reconcilePreDeleteBackup(vmCtx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(done).To(BeTrue())
+		g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(BeEmpty())
+	})
+
+	t.Run("the final snapshot is a template cloned from the VM", func(t *testing.T) {
+		g := NewWithT(t)
+		simr, err := vcsim.NewBuilder().Build()
+		g.Expect(err).NotTo(HaveOccurred())
+		defer simr.Destroy()
+
+		vmCtx := newTestVirtualMachineContext(t, simr)
+		simVM := simulator.Map.Get(vmCtx.Ref).(*simulator.VirtualMachine) //nolint:forcetypeassert
+		vmCtx.VSphereVM.Name = simVM.Name
+		vmCtx.VSphereVM.Annotations = map[string]string{infrav1.VMPreDeleteBackupAnnotation: infrav1.VMPreDeleteBackupSnapshot}
+
+		done, err := vms.reconcilePreDeleteBackup(vmCtx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(done).To(BeFalse())
+		g.Expect(vmCtx.VSphereVM.Status.TaskRef).NotTo(BeEmpty())
+		task := object.NewTask(vmCtx.Session.Client.Client, types.ManagedObjectReference{Type: "Task", Value: vmCtx.VSphereVM.Status.TaskRef})
+		g.Expect(task.Wait(vmCtx)).To(Succeed())
+		vmCtx.VSphereVM.Status.TaskRef = ""
+
+		done, err = vms.reconcilePreDeleteBackup(vmCtx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(done).To(BeTrue())
+		g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(BeEmpty())
+
+		clone, err := vmCtx.Session.Finder.VirtualMachine(vmCtx, simVM.Name+"-final")
+		g.Expect(err).NotTo(HaveOccurred())
+		var obj mo.VirtualMachine
+		g.Expect(clone.Properties(vmCtx, clone.Reference(), []string{"config.template", "parent"}, &obj)).To(Succeed())
+		g.Expect(obj.Config.Template).To(BeTrue())
+		g.Expect(obj.Parent).To(Equal(simVM.Parent))
+	})
+
+	t.Run("the OVF export requires a datastore path", func(t *testing.T) {
+		g := NewWithT(t)
+		simr, err := vcsim.NewBuilder().Build()
+		g.Expect(err).NotTo(HaveOccurred())
+		defer simr.Destroy()
+
+		vmCtx := newTestVirtualMachineContext(t, simr)
+		vmCtx.VSphereVM.Annotations = map[string]string{infrav1.VMPreDeleteBackupAnnotation: "/backups"}
+		_, err = vms.reconcilePreDeleteBackup(vmCtx)
+		g.Expect(err).To(MatchError(ContainSubstring("invalid datastore path")))
+	})
+
+	t.Run("a complete OVF export is not repeated", func(t *testing.T) {
+		g := NewWithT(t)
+		simr, err := vcsim.NewBuilder().Build()
+		g.Expect(err).NotTo(HaveOccurred())
+		defer simr.Destroy()
+
+		vmCtx := newTestVirtualMachineContext(t, simr)
+		vmCtx.VSphereVM.Name = "vm-1"
+		vmCtx.VSphereVM.Annotations = map[string]string{infrav1.VMPreDeleteBackupAnnotation: "[LocalDS_0] backups"}
+		datastore, err := vmCtx.Session.Finder.Datastore(vmCtx, "LocalDS_0")
+		g.Expect(err).NotTo(HaveOccurred())
+		datacenter, err := vmCtx.Session.Finder.DefaultDatacenter(vmCtx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(object.NewFileManager(vmCtx.Session.Client.Client).MakeDirectory(vmCtx, "[LocalDS_0] backups/vm-1", datacenter, true)).To(Succeed())
+		g.Expect(datastore.UploadFile(vmCtx, "backup_test.go", "backups/vm-1/vm-1.ovf", nil)).To(Succeed())
+
+		done, err := vms.reconcilePreDeleteBackup(vmCtx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(done).To(BeTrue())
+	})
+}
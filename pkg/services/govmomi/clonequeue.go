@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"sync"
+)
+
+// clones limits the number of in-flight clones of each template.
+var clones = newCloneSemaphore()
+
+// cloneSemaphore is a counting semaphore keyed by template. A slot is held
+// by a VSphereVM from the moment its clone is requested until the clone task
+// completes.
+type cloneSemaphore struct {
+	mu sync.Mutex
+	// holders is the set of VSphereVMs holding a slot, keyed by template.
+	holders map[string]map[string]struct{}
+	// templates is the template of the slot held by each VSphereVM.
+	templates map[string]string
+}
+
+func newCloneSemaphore() *cloneSemaphore {
+	return &cloneSemaphore{
+		holders:   map[string]map[string]struct{}{},
+		templates: map[string]string{},
+	}
+}
+
+// tryAcquire acquires a slot of the template for the VSphereVM unless max
+// slots are already held by other VSphereVMs. A max of 0 or less means the
+// number of in-flight clones is unlimited.
+func (s *cloneSemaphore) tryAcquire(template, vm string, max int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if held, ok := s.templates[vm]; ok && held == template {
+		return true
+	}
+	if max > 0 && len(s.holders[template]) >= max {
+		return false
+	}
+	s.releaseLocked(vm)
+
+	if s.holders[template] == nil {
+		s.holders[template] = map[string]struct{}{}
+	}
+	s.holders[template][vm] = struct{}{}
+	s.templates[vm] = template
+	return true
+}
+
+// release releases the slot held by the VSphereVM, if any.
+func (s *cloneSemaphore) release(vm string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked(vm)
+}
+
+func (s *cloneSemaphore) releaseLocked(vm string) {
+	template, ok := s.templates[vm]
+	if !ok {
+		return
+	}
+	delete(s.templates, vm)
+	delete(s.holders[template], vm)
+	if len(s.holders[template]) == 0 {
+		delete(s.holders, template)
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCloneSemaphore(t *testing.T) {
+	t.Run("limits the in-flight clones per template", func(t *testing.T) {
+		g := NewWithT(t)
+		s := newCloneSemaphore()
+
+		g.Expect(s.tryAcquire("tpl-1", "vm-1", 2)).To(BeTrue())
+		g.Expect(s.tryAcquire("tpl-1", "vm-2", 2)).To(BeTrue())
+		g.Expect(s.tryAcquire("tpl-1", "vm-3", 2)).To(BeFalse())
+		// Other templates have their own slots.
+		g.Expect(s.tryAcquire("tpl-2", "vm-3", 2)).To(BeTrue())
+		// Acquiring a held slot again succeeds.
+		g.Expect(s.tryAcquire("tpl-1", "vm-1", 2)).To(BeTrue())
+
+		s.release("vm-1")
+		g.Expect(s.tryAcquire("tpl-1", "vm-4", 2)).To(BeTrue())
+		g.Expect(s.tryAcquire("tpl-1", "vm-5", 2)).To(BeFalse())
+	})
+
+	t.Run("releasing an unknown VM is a no-op", func(t *testing.T) {
+		g := NewWithT(t)
+		s := newCloneSemaphore()
+
+		s.release("vm-1")
+		g.Expect(s.holders).To(BeEmpty())
+		g.Expect(s.templates).To(BeEmpty())
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		g := NewWithT(t)
+		s := newCloneSemaphore()
+
+		for _, vm := range []string{"vm-1", "vm-2", "vm-3"} {
+			g.Expect(s.tryAcquire("tpl-1", vm, 0)).To(BeTrue())
+		}
+		g.Expect(s.holders["tpl-1"]).To(HaveLen(3))
+	})
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/utils/pointer"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/audit"
+)
+
+// minAntiAffinityRuleVMs is the minimum number of VMs vCenter accepts in a
+// VM anti-affinity rule.
+const minAntiAffinityRuleVMs = 2
+
+// ComputeClusterForVM returns the compute cluster the VM is running in.
+// A nil compute cluster is returned if the VM runs on a standalone host.
+func ComputeClusterForVM(ctx context.Context, vm *object.VirtualMachine) (*object.ClusterComputeResource, error) {
+	pool, err := vm.ResourcePool(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get resource pool for VM %s", vm.Reference())
+	}
+
+	var poolMo mo.ResourcePool
+	if err := pool.Properties(ctx, pool.Reference(), []string{"owner"}, &poolMo); err != nil {
+		return nil, errors.Wrapf(err, "unable to get owner of resource pool %s", pool.Reference())
+	}
+
+	if poolMo.Owner.Type != "ClusterComputeResource" {
+		return nil, nil
+	}
+	return object.NewClusterComputeResource(vm.Client(), poolMo.Owner), nil
+}
+
+// ruleLocks serializes the reconciliation of the rules of each compute
+// cluster, so concurrent reconciles of the VMs of a cluster do not add the
+// same rule twice. The lock of a compute cluster is dropped once no reconcile
+// holds or waits for it.
+var ruleLocks = &computeClusterLocks{locks: map[string]*computeClusterLock{}}
+
+type computeClusterLocks struct {
+	mu    sync.Mutex
+	locks map[string]*computeClusterLock
+}
+
+type computeClusterLock struct {
+	sync.Mutex
+	// refs is the number of reconciles holding or waiting for the lock.
+	refs int
+}
+
+func (l *computeClusterLocks) lock(ccr *object.ClusterComputeResource) func() {
+	key := ccr.Client().URL().Host + "/" + ccr.Reference().Value
+	l.mu.Lock()
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &computeClusterLock{}
+		l.locks[key] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if lock.refs--; lock.refs == 0 {
+			delete(l.locks, key)
+		}
+	}
+}
+
+// FindVMAntiAffinityRule returns the VM anti-affinity rule with the given name.
+// A nil rule is returned if no such rule exists.
+func FindVMAntiAffinityRule(ctx context.Context, ccr *object.ClusterComputeResource, ruleName string) (*types.ClusterAntiAffinityRuleSpec, error) {
+	rules, err := findVMAntiAffinityRules(ctx, ccr, ruleName)
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+	return rules[0], nil
+}
+
+// findVMAntiAffinityRules returns the VM anti-affinity rules with the given
+// name, sorted by key. vCenter does not enforce unique rule names.
+func findVMAntiAffinityRules(ctx context.Context, ccr *object.ClusterComputeResource, ruleName string) ([]*types.ClusterAntiAffinityRuleSpec, error) {
+	clusterConfigInfoEx, err := ccr.Configuration(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []*types.ClusterAntiAffinityRuleSpec
+	for _, rule := range clusterConfigInfoEx.Rule {
+		if antiAffinityRule, ok := rule.(*types.ClusterAntiAffinityRuleSpec); ok {
+			if antiAffinityRule.Name == ruleName {
+				rules = append(rules, antiAffinityRule)
+			}
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Key < rules[j].Key })
+	return rules, nil
+}
+
+// ReconcileVMAntiAffinityRule ensures the VM anti-affinity rule with the given
+// name contains exactly the provided VMs. The rule is created if it does not
+// exist and removed once fewer than two VMs are left to keep apart. The
+// duplicates of the rule are removed.
+// A nil task is returned if the rule is already in the desired state, or once
+// it is created, as the rules of the compute cluster are reconciled one at a
+// time until the rule is created.
+func ReconcileVMAntiAffinityRule(ctx context.Context, ccr *object.ClusterComputeResource, ruleName string, vms []types.ManagedObjectReference) (*object.Task, error) {
+	defer ruleLocks.lock(ccr)()
+
+	rules, err := findVMAntiAffinityRules(ctx, ccr, ruleName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to find anti-affinity rule %s", ruleName)
+	}
+	var rule *types.ClusterAntiAffinityRuleSpec
+	var rulesSpec []types.ClusterRuleSpec
+	for i, r := range rules {
+		if i == 0 {
+			rule = r
+			continue
+		}
+		rulesSpec = append(rulesSpec, types.ClusterRuleSpec{
+			ArrayUpdateSpec: types.ArrayUpdateSpec{
+				Operation: types.ArrayUpdateOperationRemove,
+				RemoveKey: r.Key,
+			},
+		})
+	}
+
+	switch {
+	case len(vms) < minAntiAffinityRuleVMs && rule == nil:
+	case len(vms) < minAntiAffinityRuleVMs:
+		rulesSpec = append(rulesSpec, types.ClusterRuleSpec{
+			ArrayUpdateSpec: types.ArrayUpdateSpec{
+				Operation: types.ArrayUpdateOperationRemove,
+				RemoveKey: rule.Key,
+			},
+		})
+	case rule == nil:
+		return nil, addVMAntiAffinityRule(ctx, ccr, ruleName, vms)
+	case !sameVMs(rule.Vm, vms):
+		rule.Vm = vms
+		rulesSpec = append(rulesSpec, types.ClusterRuleSpec{
+			ArrayUpdateSpec: types.ArrayUpdateSpec{
+				Operation: types.ArrayUpdateOperationEdit,
+			},
+			Info: rule,
+		})
+	}
+	if len(rulesSpec) == 0 {
+		return nil, nil
+	}
+
+	spec := &types.ClusterConfigSpecEx{
+		RulesSpec: rulesSpec,
+	}
+	return ccr.Reconfigure(ctx, spec, true)
+}
+
+// addVMAntiAffinityRule creates the VM anti-affinity rule and waits for it to
+// be created. The add fails when the rule was created concurrently, e.g. by
+// another manager, in which case the existing rule is edited by the next
+// reconcile.
+func addVMAntiAffinityRule(ctx context.Context, ccr *object.ClusterComputeResource, ruleName string, vms []types.ManagedObjectReference) error {
+	spec := &types.ClusterConfigSpecEx{
+		RulesSpec: []types.ClusterRuleSpec{{
+			ArrayUpdateSpec: types.ArrayUpdateSpec{
+				Operation: types.ArrayUpdateOperationAdd,
+			},
+			Info: &types.ClusterAntiAffinityRuleSpec{
+				ClusterRuleInfo: types.ClusterRuleInfo{
+					Name:    ruleName,
+					Enabled: pointer.Bool(true),
+				},
+				Vm: vms,
+			},
+		}},
+	}
+	task, err := ccr.Reconfigure(ctx, spec, true)
+	if err == nil {
+		err = audit.WaitForTask(ctx, task)
+	}
+	if err != nil {
+		if rule, findErr := FindVMAntiAffinityRule(ctx, ccr, ruleName); findErr == nil && rule != nil {
+			return nil
+		}
+		return errors.Wrapf(err, "unable to create anti-affinity rule %s", ruleName)
+	}
+	return nil
+}
+
+func sameVMs(a, b []types.ManagedObjectReference) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	values := func(refs []types.ManagedObjectReference) []string {
+		result := make([]string, 0, len(refs))
+		for _, ref := range refs {
+			result = append(result, ref.Value)
+		}
+		sort.Strings(result)
+		return result
+	}
+
+	aValues, bValues := values(a), values(b)
+	for i := range aValues {
+		if aValues[i] != bValues[i] {
+			return false
+		}
+	}
+	return true
+}
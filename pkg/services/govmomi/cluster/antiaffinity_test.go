@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestReconcileVMAntiAffinityRule(t *testing.T) {
+	g := NewWithT(t)
+	sim, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create a VC simulator object %s", err)
+	}
+	defer sim.Destroy()
+
+	ctx := context.Background()
+	client, _ := govmomi.NewClient(ctx, sim.ServerURL(), true)
+	finder := find.NewFinder(client.Client, false)
+
+	dc, _ := finder.DatacenterOrDefault(ctx, "DC0")
+	finder.SetDatacenter(dc)
+
+	vms, err := finder.VirtualMachineList(ctx, "DC0_C0_RP0_VM*")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(len(vms)).To(BeNumerically(">=", 2))
+
+	ccr, err := ComputeClusterForVM(ctx, vms[0])
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ccr).NotTo(BeNil())
+	expectedCCR, err := finder.ClusterComputeResource(ctx, "DC0_C0")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ccr.Reference()).To(Equal(expectedCCR.Reference()))
+
+	reconcile := func(refs ...types.ManagedObjectReference) *types.ClusterAntiAffinityRuleSpec {
+		task, err := ReconcileVMAntiAffinityRule(ctx, ccr, "test-rule", refs)
+		g.Expect(err).NotTo(HaveOccurred())
+		if task != nil {
+			g.Expect(task.Wait(ctx)).To(Succeed())
+		}
+		rule, err := FindVMAntiAffinityRule(ctx, ccr, "test-rule")
+		g.Expect(err).NotTo(HaveOccurred())
+		return rule
+	}
+	refs := func(vms ...*object.VirtualMachine) []types.ManagedObjectReference {
+		result := make([]types.ManagedObjectReference, 0, len(vms))
+		for _, vm := range vms {
+			result = append(result, vm.Reference())
+		}
+		return result
+	}
+
+	// A single VM does not create a rule.
+	g.Expect(reconcile(refs(vms[0])...)).To(BeNil())
+
+	rule := reconcile(refs(vms[0], vms[1])...)
+	g.Expect(rule).NotTo(BeNil())
+	g.Expect(rule.Vm).To(ConsistOf(refs(vms[0], vms[1])))
+
+	if len(vms) > 2 {
+		rule = reconcile(refs(vms[1], vms[2])...)
+		g.Expect(rule).NotTo(BeNil())
+		g.Expect(rule.Vm).To(ConsistOf(refs(vms[1], vms[2])))
+	}
+
+	// The rule created concurrently is edited by the next reconcile.
+	g.Expect(addVMAntiAffinityRule(ctx, ccr, "test-rule", refs(vms[0], vms[1]))).To(Succeed())
+	rules, err := findVMAntiAffinityRules(ctx, ccr, "test-rule")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rules).To(HaveLen(1))
+
+	// The rule is removed once fewer than two VMs are left.
+	g.Expect(reconcile(refs(vms[1])...)).To(BeNil())
+
+	// The lock of the compute cluster is dropped once released.
+	g.Expect(ruleLocks.locks).To(BeEmpty())
+}
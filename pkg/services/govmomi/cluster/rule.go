@@ -17,9 +17,13 @@ limitations under the License.
 package cluster
 
 import (
+	"fmt"
+
 	"github.com/pkg/errors"
 	"github.com/vmware/govmomi/vim25/types"
 	"k8s.io/utils/pointer"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/audit"
 )
 
 type Rule interface {
@@ -64,6 +68,70 @@ func VerifyAffinityRule(ctx computeClusterContext, clusterName, hostGroupName, v
 	return nil, errors.New("no matching affinity rule found/exists")
 }
 
+// EnsureAffinityRule creates the VM group and the VM-host affinity rule
+// binding it to the host group in the compute cluster when they do not exist.
+// The created rule is enabled, and is a "must run on" rule if mandatory. The
+// host group must exist, and an existing rule is left unchanged.
+func EnsureAffinityRule(ctx computeClusterContext, clusterName, hostGroupName, vmGroupName string, mandatory bool) error {
+	ccr, err := ctx.GetSession().Finder.ClusterComputeResource(ctx, clusterName)
+	if err != nil {
+		return errors.Wrapf(err, "unable to find compute cluster %s", clusterName)
+	}
+	clusterConfigInfoEx, err := ccr.Configuration(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get configuration of compute cluster %s", clusterName)
+	}
+
+	var hostGroupFound, vmGroupFound bool
+	for _, group := range clusterConfigInfoEx.Group {
+		switch group := group.(type) {
+		case *types.ClusterHostGroup:
+			hostGroupFound = hostGroupFound || group.Name == hostGroupName
+		case *types.ClusterVmGroup:
+			vmGroupFound = vmGroupFound || group.Name == vmGroupName
+		}
+	}
+	if !hostGroupFound {
+		return errors.Errorf("cannot find host group %s in compute cluster %s", hostGroupName, clusterName)
+	}
+
+	spec := &types.ClusterConfigSpecEx{}
+	if !vmGroupFound {
+		spec.GroupSpec = append(spec.GroupSpec, types.ClusterGroupSpec{
+			ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: types.ArrayUpdateOperationAdd},
+			Info: &types.ClusterVmGroup{
+				ClusterGroupInfo: types.ClusterGroupInfo{Name: vmGroupName},
+			},
+		})
+	}
+	if _, err := VerifyAffinityRule(ctx, clusterName, hostGroupName, vmGroupName); err != nil {
+		spec.RulesSpec = append(spec.RulesSpec, types.ClusterRuleSpec{
+			ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: types.ArrayUpdateOperationAdd},
+			Info: &types.ClusterVmHostRuleInfo{
+				ClusterRuleInfo: types.ClusterRuleInfo{
+					Name:      fmt.Sprintf("%s-%s", vmGroupName, hostGroupName),
+					Enabled:   pointer.Bool(true),
+					Mandatory: pointer.Bool(mandatory),
+				},
+				VmGroupName:         vmGroupName,
+				AffineHostGroupName: hostGroupName,
+			},
+		})
+	}
+	if len(spec.GroupSpec) == 0 && len(spec.RulesSpec) == 0 {
+		return nil
+	}
+
+	task, err := ccr.Reconfigure(ctx, spec, true)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create affinity rule of VM group %s and host group %s", vmGroupName, hostGroupName)
+	}
+	if err := audit.WaitForTask(ctx, task); err != nil {
+		return errors.Wrapf(err, "unable to create affinity rule of VM group %s and host group %s", vmGroupName, hostGroupName)
+	}
+	return nil
+}
+
 func listRules(ctx computeClusterContext, clusterName string) ([]types.BaseClusterRuleInfo, error) {
 	ccr, err := ctx.GetSession().Finder.ClusterComputeResource(ctx, clusterName)
 	if err != nil {
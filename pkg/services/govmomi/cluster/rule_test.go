@@ -56,3 +56,45 @@ func TestVerifyAffinityRule(t *testing.T) {
 	g.Expect(rule.IsMandatory()).To(BeTrue())
 	g.Expect(rule.Disabled()).To(BeFalse())
 }
+
+func TestEnsureAffinityRule(t *testing.T) {
+	g := NewWithT(t)
+	sim, err := vcsim.NewBuilder().
+		WithOperations("cluster.group.create -cluster DC0_C0 -name rack-1 -host DC0_C0_H0 DC0_C0_H1").
+		Build()
+	if err != nil {
+		t.Fatalf("failed to create a VC simulator object %s", err)
+	}
+	defer sim.Destroy()
+
+	ctx := context.Background()
+	client, _ := govmomi.NewClient(ctx, sim.ServerURL(), true)
+	finder := find.NewFinder(client.Client, false)
+
+	dc, _ := finder.DatacenterOrDefault(ctx, "DC0")
+	finder.SetDatacenter(dc)
+
+	computeClusterCtx := testComputeClusterCtx{
+		Context: context.Background(),
+		finder:  finder,
+	}
+
+	// The VM group and the rule are created once.
+	for i := 0; i < 2; i++ {
+		g.Expect(EnsureAffinityRule(computeClusterCtx, "DC0_C0", "rack-1", "rack-1-vms", true)).To(Succeed())
+	}
+	vmGroup, err := FindVMGroup(computeClusterCtx, "DC0_C0", "rack-1-vms")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(vmGroup.listVMs()).To(BeEmpty())
+	rules, err := listRules(computeClusterCtx, "DC0_C0")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rules).To(HaveLen(1))
+	rule, err := VerifyAffinityRule(computeClusterCtx, "DC0_C0", "rack-1", "rack-1-vms")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rule.IsMandatory()).To(BeTrue())
+	g.Expect(rule.Disabled()).To(BeFalse())
+
+	// The host group is not created.
+	err = EnsureAffinityRule(computeClusterCtx, "DC0_C0", "rack-2", "rack-2-vms", false)
+	g.Expect(err).To(MatchError(ContainSubstring("cannot find host group rack-2")))
+}
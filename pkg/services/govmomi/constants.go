@@ -16,14 +16,28 @@ limitations under the License.
 
 package govmomi
 
+import (
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+)
+
 const (
 	morefTypeTask = "Task"
+
+	// taskProgressStep is the minimum progress, in percents, of an in-flight
+	// task which triggers a reconcile of its VSphereVM.
+	taskProgressStep = 10
 )
 
+// talosFormat is the format of Talos machine configs.
+const talosFormat bootstrapv1.Format = "talos"
+
 // nolint
 const (
 	guestInfoKeyMetadata    = "guestinfo.metadata"
 	guestInfoKeyMetadataEnc = "guestinfo.metadata.encoding"
 	guestInfoKeyUserdata    = "guestinfo.userdata"
 	guestInfoKeyUserdataEnc = "guestinfo.userdata.encoding"
+	guestInfoKeyIgnition    = "guestinfo.ignition.config.data"
+	guestInfoKeyTalosConfig = "guestinfo.talos.config"
+	guestInfoKeyAfterburn   = "guestinfo.afterburn.metadata"
 )
@@ -29,6 +29,10 @@ type virtualMachineContext struct {
 	Ref   types.ManagedObjectReference
 	Obj   *object.VirtualMachine
 	State *infrav1.VirtualMachine
+
+	// vmState is the state of the VM retrieved along with the VMs of its
+	// cluster, if any.
+	vmState *vmState
 }
 
 func (c *virtualMachineContext) String() string {
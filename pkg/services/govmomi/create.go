@@ -18,15 +18,12 @@ package govmomi
 
 import (
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
-	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/esxi"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/vcenter"
 )
 
 // createVM creates a new VM with the data in the VMContext passed. This method does not wait
-// for the new VM to be created.
+// for the new VM to be created. VMs of standalone ESXi hosts are created from copies of the
+// disks of their template, as ESXi hosts cannot clone VMs.
 func createVM(ctx *context.VMContext, bootstrapData []byte) error {
-	if ctx.Session.IsVC() {
-		return vcenter.Clone(ctx, bootstrapData)
-	}
-	return esxi.Clone(ctx, bootstrapData)
+	return vcenter.Clone(ctx, bootstrapData)
 }
@@ -0,0 +1,297 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// reconcileDrift compares the number of CPUs, the memory size, the disks,
+// the networks and the custom VMX keys of the spec with the configuration of
+// the VM, which may have been changed outside of Cluster API. The differences
+// are reported in the drift field of the status and the SpecSynced
+// condition. As the VM is reconciled periodically, the drift is detected at
+// the latest after the sync period of the controller.
+//
+// With the Revert drift policy, the custom VMX keys and the networks of the
+// VM are reconfigured back to the spec. Changes of the number of CPUs, the
+// memory size and the disks are already applied by reconcileResize and
+// reconcileDiskResize where possible, so any remaining drift requires the
+// machine to be replaced.
+func (vms *VMService) reconcileDrift(ctx *virtualMachineContext) (bool, error) {
+	policy := ctx.VSphereVM.Spec.DriftPolicy
+	if policy == infrav1.VirtualMachineDriftPolicyIgnore {
+		ctx.VSphereVM.Status.Drift = nil
+		conditions.Delete(ctx.VSphereVM, infrav1.VMSpecSyncedCondition)
+		return true, nil
+	}
+
+	var obj mo.VirtualMachine
+	props := []string{"config.hardware", "config.extraConfig", "network"}
+	if err := ctx.Obj.Properties(ctx, ctx.Ref, props, &obj); err != nil {
+		return false, errors.Wrapf(err, "unable to fetch props %v for vm %s", props, ctx)
+	}
+	if obj.Config == nil {
+		return true, nil
+	}
+
+	networkNames, err := getNetworkNames(ctx, obj.Network)
+	if err != nil {
+		return false, err
+	}
+
+	drift := getDrift(ctx.VSphereVM, obj.Config, networkNames)
+	ctx.VSphereVM.Status.Drift = drift
+	if len(drift) == 0 {
+		conditions.MarkTrue(ctx.VSphereVM, infrav1.VMSpecSyncedCondition)
+		return true, nil
+	}
+
+	fields := make([]string, 0, len(drift))
+	for _, d := range drift {
+		fields = append(fields, d.Field)
+	}
+	ctx.Logger.V(4).Info("VM drifted from spec", "drift", drift)
+
+	if policy != infrav1.VirtualMachineDriftPolicyRevert {
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.VMSpecSyncedCondition, infrav1.VMSpecDriftedReason, clusterv1.ConditionSeverityWarning,
+			"the VM no longer matches the spec: %s", strings.Join(fields, ", "))
+		return true, nil
+	}
+
+	spec, err := getDriftRevertSpec(ctx, obj.Config, networkNames)
+	if err != nil {
+		return false, err
+	}
+	if len(spec.ExtraConfig) == 0 && len(spec.DeviceChange) == 0 {
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.VMSpecSyncedCondition, infrav1.VMSpecDriftRequiresReplacementReason, clusterv1.ConditionSeverityWarning,
+			"the VM no longer matches the spec and cannot be reverted in place: %s; a rolling replacement of the machine is required", strings.Join(fields, ", "))
+		return true, nil
+	}
+
+	task, err := ctx.Obj.Reconfigure(ctx, spec)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to revert drift of vm %s", ctx)
+	}
+	conditions.MarkFalse(ctx.VSphereVM, infrav1.VMSpecSyncedCondition, infrav1.VMSpecRevertingReason, clusterv1.ConditionSeverityInfo,
+		"reverting the VM to the spec: %s", strings.Join(fields, ", "))
+	ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+	ctx.Logger.Info("wait for VM drift to be reverted", "drift", drift)
+	return false, nil
+}
+
+// getDriftRevertSpec returns the config spec that sets the custom VMX keys
+// and reconnects the network devices of the VM as in the spec.
+func getDriftRevertSpec(ctx *virtualMachineContext, config *types.VirtualMachineConfigInfo, networkNames map[string]string) (types.VirtualMachineConfigSpec, error) {
+	spec := types.VirtualMachineConfigSpec{}
+
+	extraConfig := getExtraConfigValues(config)
+	keys := make([]string, 0, len(ctx.VSphereVM.Spec.CustomVMXKeys))
+	for key := range ctx.VSphereVM.Spec.CustomVMXKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if value := ctx.VSphereVM.Spec.CustomVMXKeys[key]; extraConfig[key] != value {
+			spec.ExtraConfig = append(spec.ExtraConfig, &types.OptionValue{Key: key, Value: value})
+		}
+	}
+
+	devices := object.VirtualDeviceList(config.Hardware.Device)
+	nics := devices.SelectByType((*types.VirtualEthernetCard)(nil))
+	if len(nics) != len(ctx.VSphereVM.Spec.Network.Devices) {
+		return spec, nil
+	}
+	for i, device := range ctx.VSphereVM.Spec.Network.Devices {
+		nic := nics[i].(types.BaseVirtualEthernetCard).GetVirtualEthernetCard() //nolint:forcetypeassert
+		if device.NetworkName == "" {
+			continue
+		}
+		if actual, ok := getNICNetworkName(nic, networkNames); !ok || actual == path.Base(device.NetworkName) {
+			continue
+		}
+		ref, err := ctx.Session.CachedFinder.Network(ctx, device.NetworkName)
+		if err != nil {
+			return spec, errors.Wrapf(err, "unable to find network %q", device.NetworkName)
+		}
+		backing, err := ref.EthernetCardBackingInfo(ctx)
+		if err != nil {
+			return spec, errors.Wrapf(err, "unable to create ethernet card backing info for network %q on %q", device.NetworkName, ctx)
+		}
+		nic.Backing = backing
+		spec.DeviceChange = append(spec.DeviceChange, &types.VirtualDeviceConfigSpec{
+			Operation: types.VirtualDeviceConfigSpecOperationEdit,
+			Device:    nics[i],
+		})
+	}
+	return spec, nil
+}
+
+// getNetworkNames returns the names of the networks the VM is connected to
+// by their managed object reference value, which is also the key of
+// distributed port groups.
+func getNetworkNames(ctx *virtualMachineContext, refs []types.ManagedObjectReference) (map[string]string, error) {
+	names := map[string]string{}
+	if len(refs) == 0 {
+		return names, nil
+	}
+	var networks []mo.Network
+	pc := property.DefaultCollector(ctx.Session.Client.Client)
+	if err := pc.Retrieve(ctx, refs, []string{"name"}, &networks); err != nil {
+		return nil, errors.Wrapf(err, "unable to get networks of vm %s", ctx)
+	}
+	for _, network := range networks {
+		names[network.Self.Value] = network.Name
+	}
+	return names, nil
+}
+
+// getDrift returns the differences between the spec of the VSphereVM and
+// the configuration of its VM.
+func getDrift(vsphereVM *infrav1.VSphereVM, config *types.VirtualMachineConfigInfo, networkNames map[string]string) []infrav1.VirtualMachineDrift {
+	var drift []infrav1.VirtualMachineDrift
+	add := func(field string, desired, actual interface{}) {
+		drift = append(drift, infrav1.VirtualMachineDrift{
+			Field:   field,
+			Desired: fmt.Sprint(desired),
+			Actual:  fmt.Sprint(actual),
+		})
+	}
+	spec := vsphereVM.Spec
+
+	// The number of CPUs is set the same way as when the VM is cloned.
+	numCPUs := spec.NumCPUs
+	if numCPUs > 0 && numCPUs < 2 {
+		numCPUs = 2
+	}
+	if numCPUs > 0 && numCPUs != config.Hardware.NumCPU {
+		add("numCPUs", numCPUs, config.Hardware.NumCPU)
+	}
+	if spec.MemoryMiB > 0 && spec.MemoryMiB != int64(config.Hardware.MemoryMB) {
+		add("memoryMiB", spec.MemoryMiB, config.Hardware.MemoryMB)
+	}
+
+	devices := object.VirtualDeviceList(config.Hardware.Device)
+	disks := devices.SelectByType((*types.VirtualDisk)(nil))
+	// The disks of linked clones keep the size of the template.
+	if len(disks) > 0 && vsphereVM.Status.CloneMode != infrav1.LinkedClone {
+		if spec.DiskGiB > 0 {
+			if actual := diskGiB(disks[0]); actual != int64(spec.DiskGiB) {
+				add("diskGiB", spec.DiskGiB, actual)
+			}
+		}
+		for i, desired := range spec.AdditionalDisksGiB {
+			if i+1 >= len(disks) {
+				break
+			}
+			if actual := diskGiB(disks[i+1]); actual != int64(desired) {
+				add(fmt.Sprintf("additionalDisksGiB[%d]", i), desired, actual)
+			}
+		}
+	}
+	// The data disks are added after the disks of the template.
+	if len(spec.Disks) > 0 {
+		switch {
+		case len(disks) == 0:
+			add("disks", len(spec.Disks), 0)
+		case len(disks) < len(spec.Disks)+1:
+			add("disks", len(spec.Disks), len(disks)-1)
+		default:
+			dataDisks := disks[len(disks)-len(spec.Disks):]
+			for i, diskSpec := range spec.Disks {
+				if actual := diskGiB(dataDisks[i]); actual != int64(diskSpec.SizeGiB) {
+					add(fmt.Sprintf("disks[%d].sizeGiB", i), diskSpec.SizeGiB, actual)
+				}
+			}
+		}
+	}
+
+	nics := devices.SelectByType((*types.VirtualEthernetCard)(nil))
+	if len(nics) != len(spec.Network.Devices) {
+		add("network.devices", len(spec.Network.Devices), len(nics))
+	} else {
+		for i, device := range spec.Network.Devices {
+			nic := nics[i].(types.BaseVirtualEthernetCard).GetVirtualEthernetCard() //nolint:forcetypeassert
+			if device.NetworkName != "" {
+				if actual, ok := getNICNetworkName(nic, networkNames); ok && actual != path.Base(device.NetworkName) {
+					add(fmt.Sprintf("network.devices[%d].networkName", i), device.NetworkName, actual)
+				}
+			}
+			if device.MACAddr != "" && !strings.EqualFold(device.MACAddr, nic.MacAddress) {
+				add(fmt.Sprintf("network.devices[%d].macAddr", i), device.MACAddr, nic.MacAddress)
+			}
+		}
+	}
+
+	extraConfig := getExtraConfigValues(config)
+	keys := make([]string, 0, len(spec.CustomVMXKeys))
+	for key := range spec.CustomVMXKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if actual := extraConfig[key]; actual != spec.CustomVMXKeys[key] {
+			add("customVMXKeys["+strconv.Quote(key)+"]", spec.CustomVMXKeys[key], actual)
+		}
+	}
+
+	return drift
+}
+
+// getExtraConfigValues returns the extra config of the VM by key.
+func getExtraConfigValues(config *types.VirtualMachineConfigInfo) map[string]string {
+	values := map[string]string{}
+	for _, option := range config.ExtraConfig {
+		if value := option.GetOptionValue(); value != nil {
+			values[value.Key] = fmt.Sprint(value.Value)
+		}
+	}
+	return values
+}
+
+func diskGiB(device types.BaseVirtualDevice) int64 {
+	return device.(*types.VirtualDisk).CapacityInKB / (1024 * 1024) //nolint:forcetypeassert
+}
+
+// getNICNetworkName returns the name of the network the NIC is connected to,
+// or false if the backing of the NIC is not a standard or distributed port
+// group.
+func getNICNetworkName(nic *types.VirtualEthernetCard, networkNames map[string]string) (string, bool) {
+	switch backing := nic.Backing.(type) {
+	case *types.VirtualEthernetCardNetworkBackingInfo:
+		return backing.DeviceName, true
+	case *types.VirtualEthernetCardDistributedVirtualPortBackingInfo:
+		name, ok := networkNames[backing.Port.PortgroupKey]
+		return name, ok
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestReconcileDrift(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vms := &VMService{}
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	simVM := simulator.Map.Get(vmCtx.Ref).(*simulator.VirtualMachine) //nolint:forcetypeassert
+
+	// The spec matches the VM.
+	devices := object.VirtualDeviceList(simVM.Config.Hardware.Device)
+	nics := devices.SelectByType((*types.VirtualEthernetCard)(nil))
+	g.Expect(nics).To(HaveLen(1))
+	nic := nics[0].(types.BaseVirtualEthernetCard).GetVirtualEthernetCard()         //nolint:forcetypeassert
+	disk := devices.SelectByType((*types.VirtualDisk)(nil))[0].(*types.VirtualDisk) //nolint:forcetypeassert
+	simVM.Config.Hardware.NumCPU = 2
+	vmCtx.VSphereVM.Spec.NumCPUs = 2
+	vmCtx.VSphereVM.Spec.MemoryMiB = int64(simVM.Config.Hardware.MemoryMB)
+	vmCtx.VSphereVM.Spec.DiskGiB = int32(disk.CapacityInKB / (1024 * 1024))
+	vmCtx.VSphereVM.Spec.Network.Devices = []infrav1.NetworkDeviceSpec{{
+		NetworkName: "/DC0/network/DC0_DVPG0",
+		MACAddr:     nic.MacAddress,
+	}}
+	vmCtx.VSphereVM.Spec.CustomVMXKeys = map[string]string{"foo": "bar"}
+	simVM.Config.ExtraConfig = append(simVM.Config.ExtraConfig, &types.OptionValue{Key: "foo", Value: "bar"})
+
+	ok, err := vms.reconcileDrift(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(vmCtx.VSphereVM.Status.Drift).To(BeEmpty())
+	g.Expect(conditions.IsTrue(vmCtx.VSphereVM, infrav1.VMSpecSyncedCondition)).To(BeTrue())
+
+	// The VM is changed outside of Cluster API.
+	simVM.Config.Hardware.NumCPU = 4
+	simVM.Config.ExtraConfig[len(simVM.Config.ExtraConfig)-1] = &types.OptionValue{Key: "foo", Value: "baz"}
+	vmCtx.VSphereVM.Spec.Network.Devices[0].NetworkName = "VM Network"
+
+	ok, err = vms.reconcileDrift(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(vmCtx.VSphereVM.Status.Drift).To(ConsistOf(
+		infrav1.VirtualMachineDrift{Field: "numCPUs", Desired: "2", Actual: "4"},
+		infrav1.VirtualMachineDrift{Field: "network.devices[0].networkName", Desired: "VM Network", Actual: "DC0_DVPG0"},
+		infrav1.VirtualMachineDrift{Field: `customVMXKeys["foo"]`, Desired: "bar", Actual: "baz"},
+	))
+	g.Expect(conditions.IsFalse(vmCtx.VSphereVM, infrav1.VMSpecSyncedCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.VMSpecSyncedCondition)).To(Equal(infrav1.VMSpecDriftedReason))
+
+	// The Revert drift policy reconfigures the custom VMX keys and the
+	// networks of the VM.
+	vmCtx.VSphereVM.Spec.DriftPolicy = infrav1.VirtualMachineDriftPolicyRevert
+	ok, err = vms.reconcileDrift(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeFalse())
+	g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.VMSpecSyncedCondition)).To(Equal(infrav1.VMSpecRevertingReason))
+	task := object.NewTask(vmCtx.Session.Client.Client, types.ManagedObjectReference{Type: "Task", Value: vmCtx.VSphereVM.Status.TaskRef})
+	g.Expect(task.Wait(vmCtx)).To(Succeed())
+
+	// The number of CPUs cannot be reverted in place.
+	ok, err = vms.reconcileDrift(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(vmCtx.VSphereVM.Status.Drift).To(ConsistOf(
+		infrav1.VirtualMachineDrift{Field: "numCPUs", Desired: "2", Actual: "4"},
+	))
+	g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.VMSpecSyncedCondition)).To(Equal(infrav1.VMSpecDriftRequiresReplacementReason))
+
+	// The Ignore drift policy does not compare the VM with the spec.
+	vmCtx.VSphereVM.Spec.DriftPolicy = infrav1.VirtualMachineDriftPolicyIgnore
+	ok, err = vms.reconcileDrift(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(vmCtx.VSphereVM.Status.Drift).To(BeEmpty())
+	g.Expect(conditions.Has(vmCtx.VSphereVM, infrav1.VMSpecSyncedCondition)).To(BeFalse())
+}
+
+func TestGetDrift(t *testing.T) {
+	disk := func(capacityGiB int64) types.BaseVirtualDevice {
+		return &types.VirtualDisk{CapacityInKB: capacityGiB * 1024 * 1024}
+	}
+	config := &types.VirtualMachineConfigInfo{
+		Hardware: types.VirtualHardware{
+			NumCPU:   4,
+			MemoryMB: 8192,
+			Device:   []types.BaseVirtualDevice{disk(20), disk(10), disk(5)},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		spec     infrav1.VirtualMachineCloneSpec
+		status   infrav1.VSphereVMStatus
+		expected []infrav1.VirtualMachineDrift
+	}{
+		{
+			name: "in sync",
+			spec: infrav1.VirtualMachineCloneSpec{NumCPUs: 4, MemoryMiB: 8192, DiskGiB: 20, AdditionalDisksGiB: []int32{10}, Disks: []infrav1.DiskSpec{{SizeGiB: 5}}},
+		},
+		{
+			name: "a single CPU is raised to two",
+			spec: infrav1.VirtualMachineCloneSpec{NumCPUs: 1},
+			expected: []infrav1.VirtualMachineDrift{
+				{Field: "numCPUs", Desired: "2", Actual: "4"},
+			},
+		},
+		{
+			name: "resized disks and memory",
+			spec: infrav1.VirtualMachineCloneSpec{MemoryMiB: 4096, DiskGiB: 30, Disks: []infrav1.DiskSpec{{SizeGiB: 8}}},
+			expected: []infrav1.VirtualMachineDrift{
+				{Field: "memoryMiB", Desired: "4096", Actual: "8192"},
+				{Field: "diskGiB", Desired: "30", Actual: "20"},
+				{Field: "disks[0].sizeGiB", Desired: "8", Actual: "5"},
+			},
+		},
+		{
+			name:   "the disk of linked clones is ignored",
+			spec:   infrav1.VirtualMachineCloneSpec{DiskGiB: 30},
+			status: infrav1.VSphereVMStatus{CloneMode: infrav1.LinkedClone},
+		},
+		{
+			name: "removed data disks",
+			spec: infrav1.VirtualMachineCloneSpec{Disks: []infrav1.DiskSpec{{SizeGiB: 5}, {SizeGiB: 5}, {SizeGiB: 5}}},
+			expected: []infrav1.VirtualMachineDrift{
+				{Field: "disks", Desired: "3", Actual: "2"},
+			},
+		},
+		{
+			name: "removed network devices",
+			spec: infrav1.VirtualMachineCloneSpec{Network: infrav1.NetworkSpec{Devices: []infrav1.NetworkDeviceSpec{{NetworkName: "VM Network"}}}},
+			expected: []infrav1.VirtualMachineDrift{
+				{Field: "network.devices", Desired: "1", Actual: "0"},
+			},
+		},
+		{
+			name: "missing custom VMX keys",
+			spec: infrav1.VirtualMachineCloneSpec{CustomVMXKeys: map[string]string{"foo": "bar"}},
+			expected: []infrav1.VirtualMachineDrift{
+				{Field: `customVMXKeys["foo"]`, Desired: "bar", Actual: ""},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			vsphereVM := &infrav1.VSphereVM{
+				Spec:   infrav1.VSphereVMSpec{VirtualMachineCloneSpec: tt.spec},
+				Status: tt.status,
+			}
+			g.Expect(getDrift(vsphereVM, config, nil)).To(Equal(tt.expected))
+		})
+	}
+}
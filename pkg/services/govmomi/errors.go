@@ -17,9 +17,17 @@ limitations under the License.
 package govmomi
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net"
+	"reflect"
 
+	"github.com/pkg/errors"
 	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/task"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
 )
 
 // errNotFound is returned by the findVM function when a VM is not found.
@@ -73,3 +81,136 @@ func wasNotFoundByBIOSUUID(err error) bool {
 		return false
 	}
 }
+
+// isFileNotFound returns whether a datastore file operation failed because
+// the file does not exist.
+func isFileNotFound(err error) bool {
+	var fault interface{}
+	switch {
+	case soap.IsSoapFault(err):
+		fault = soap.ToSoapFault(err).VimFault()
+	default:
+		if taskErr, ok := err.(task.Error); ok {
+			fault = taskErr.Fault()
+		}
+	}
+	switch fault.(type) {
+	case types.FileNotFound, *types.FileNotFound:
+		return true
+	default:
+		return false
+	}
+}
+
+// isManagedObjectNotFound returns whether an operation failed because the
+// managed object it was called on no longer exists.
+func isManagedObjectNotFound(err error) bool {
+	var fault interface{}
+	switch {
+	case soap.IsSoapFault(err):
+		fault = soap.ToSoapFault(err).VimFault()
+	default:
+		if taskErr, ok := err.(task.Error); ok {
+			fault = taskErr.Fault()
+		}
+	}
+	switch fault.(type) {
+	case types.ManagedObjectNotFound, *types.ManagedObjectNotFound:
+		return true
+	default:
+		return false
+	}
+}
+
+// ErrorClass is the class of the error of a vCenter operation, which
+// determines how the operation is retried.
+type ErrorClass string
+
+const (
+	// TransientError is the class of the errors caused by vCenter or the
+	// network being unavailable for a while, e.g. during a brownout. The
+	// operation is retried with an exponential backoff.
+	TransientError ErrorClass = "Transient"
+
+	// QuotaError is the class of the errors caused by the infrastructure
+	// lacking the resources, the storage or the licenses required by the
+	// operation. The operation is retried with a longer backoff, until the
+	// resources are freed or added.
+	QuotaError ErrorClass = "Quota"
+
+	// PermanentError is the class of the errors caused by an invalid
+	// configuration, which fail the operation until the configuration is
+	// changed.
+	PermanentError ErrorClass = "Permanent"
+
+	// UnknownError is the class of the other errors.
+	UnknownError ErrorClass = "Unknown"
+)
+
+// ClassifyError returns the class of the error of a vCenter operation, which
+// may be the fault of a failed task.
+func ClassifyError(err error) ErrorClass {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		var fault interface{}
+		switch {
+		case soap.IsSoapFault(e):
+			fault = soap.ToSoapFault(e).VimFault()
+		case soap.IsVimFault(e):
+			fault = soap.ToVimFault(e)
+		default:
+			if taskErr, ok := e.(task.Error); ok {
+				fault = taskErr.Fault()
+			}
+		}
+		if fault != nil {
+			return classifyFault(fault)
+		}
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.As(err, &netErr):
+		return TransientError
+	default:
+		return UnknownError
+	}
+}
+
+// classifyFault returns the class of the vSphere fault.
+func classifyFault(fault interface{}) ErrorClass {
+	// The faults are matched through the interfaces of their base types,
+	// which are only implemented by the pointers to the faults.
+	if v := reflect.ValueOf(fault); v.Kind() == reflect.Struct {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		fault = ptr.Interface()
+	}
+
+	switch fault.(type) {
+	case types.BaseInsufficientResourcesFault,
+		*types.NoDiskSpace,
+		*types.InsufficientStorageSpace,
+		*types.VmLimitLicense:
+		return QuotaError
+	case types.BaseInvalidArgument,
+		types.BaseInvalidVmConfig,
+		types.BaseNotSupported,
+		*types.InvalidName,
+		*types.InvalidDatastorePath:
+		return PermanentError
+	case types.BaseHostCommunication,
+		types.BaseHostConnectFault,
+		types.BaseTaskInProgress,
+		types.BaseInvalidState,
+		types.BaseResourceInUse,
+		*types.NotAuthenticated,
+		*types.RequestCanceled,
+		*types.SystemError:
+		return TransientError
+	default:
+		return UnknownError
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/task"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		class ErrorClass
+	}{
+		{
+			name:  "network error",
+			err:   errors.Wrapf(&net.OpError{Op: "dial", Err: errors.New("connection refused")}, "unable to create session"),
+			class: TransientError,
+		},
+		{
+			name:  "timeout",
+			err:   errors.Wrapf(context.DeadlineExceeded, "unable to find vm"),
+			class: TransientError,
+		},
+		{
+			name:  "expired session",
+			err:   soap.WrapVimFault(&types.NotAuthenticated{}),
+			class: TransientError,
+		},
+		{
+			name:  "host disconnected during a task",
+			err:   task.Error{LocalizedMethodFault: &types.LocalizedMethodFault{Fault: &types.HostNotConnected{}}},
+			class: TransientError,
+		},
+		{
+			name:  "insufficient memory during a task",
+			err:   task.Error{LocalizedMethodFault: &types.LocalizedMethodFault{Fault: &types.InsufficientMemoryResourcesFault{}}},
+			class: QuotaError,
+		},
+		{
+			name:  "datastore full",
+			err:   errors.Wrapf(soap.WrapVimFault(&types.NoDiskSpace{}), "failed to create vm"),
+			class: QuotaError,
+		},
+		{
+			name:  "invalid clone spec",
+			err:   soap.WrapVimFault(&types.InvalidArgument{InvalidProperty: "numCPUs"}),
+			class: PermanentError,
+		},
+		{
+			name:  "invalid device spec",
+			err:   task.Error{LocalizedMethodFault: &types.LocalizedMethodFault{Fault: &types.InvalidDeviceSpec{}}},
+			class: PermanentError,
+		},
+		{
+			name:  "unclassified fault",
+			err:   soap.WrapVimFault(&types.FileNotFound{}),
+			class: UnknownError,
+		},
+		{
+			name:  "other error",
+			err:   errors.New("bios uuid is empty while VM is ready"),
+			class: UnknownError,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(ClassifyError(tt.err)).To(Equal(tt.class))
+		})
+	}
+}
@@ -17,11 +17,30 @@ limitations under the License.
 package extra
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/base64"
 
 	"github.com/vmware/govmomi/vim25/types"
 )
 
+const (
+	// Base64Encoding is the encoding of base64 encoded guestinfo values.
+	Base64Encoding = "base64"
+
+	// GzipBase64Encoding is the encoding of gzip compressed, base64 encoded
+	// guestinfo values.
+	GzipBase64Encoding = "gzip+base64"
+
+	// OwnerClusterKey is the key of the namespace and the name of the
+	// cluster a VM is created for.
+	OwnerClusterKey = "capv.owner.cluster"
+
+	// OwnerVSphereVMKey is the key of the namespace and the name of the
+	// VSphereVM a VM is created for.
+	OwnerVSphereVMKey = "capv.owner.vspherevm"
+)
+
 // Config is data used with a VM's guestInfo RPC interface.
 type Config []types.BaseOptionValue
 
@@ -37,45 +56,102 @@ func (e *Config) SetCustomVMXKeys(customKeys map[string]string) error {
 	return nil
 }
 
-// SetCloudInitUserData sets the cloud init user data at the key
-// "guestinfo.userdata" as a base64-encoded string.
-func (e *Config) SetCloudInitUserData(data []byte) error {
+// SetOwner sets the namespace and the name of the cluster and of the
+// VSphereVM a VM is created for, which are not exposed to the guest OS.
+func (e *Config) SetOwner(cluster, vsphereVM string) {
 	*e = append(*e,
 		&types.OptionValue{
-			Key:   "guestinfo.userdata",
-			Value: e.encode(data),
+			Key:   OwnerClusterKey,
+			Value: cluster,
 		},
 		&types.OptionValue{
-			Key:   "guestinfo.userdata.encoding",
-			Value: "base64",
+			Key:   OwnerVSphereVMKey,
+			Value: vsphereVM,
 		},
 	)
+}
+
+// SetCloudInitUserData sets the cloud init user data at the key
+// "guestinfo.userdata" as a base64-encoded string. The data is gzip
+// compressed first if it is larger than the compression threshold, unless
+// the threshold is 0.
+func (e *Config) SetCloudInitUserData(data []byte, compressionThreshold int) error {
+	return e.set("guestinfo.userdata", data, compressionThreshold)
+}
+
+// SetIgnitionUserData sets the Ignition config at the key
+// "guestinfo.ignition.config.data" as a base64-encoded string. The data is
+// gzip compressed first if it is larger than the compression threshold,
+// unless the threshold is 0.
+func (e *Config) SetIgnitionUserData(data []byte, compressionThreshold int) error {
+	return e.set("guestinfo.ignition.config.data", data, compressionThreshold)
+}
+
+// SetTalosConfig sets the Talos machine config at the key
+// "guestinfo.talos.config" as a base64-encoded string. Talos neither reads
+// an encoding key nor decompresses its config.
+func (e *Config) SetTalosConfig(data []byte) error {
+	value, _, err := e.encode(data, 0)
+	if err != nil {
+		return err
+	}
+	*e = append(*e, &types.OptionValue{
+		Key:   "guestinfo.talos.config",
+		Value: value,
+	})
+	return nil
+}
+
+// SetAfterburnMetadata sets the Afterburn metadata of Ignition based images at
+// the key "guestinfo.afterburn.metadata" as a base64-encoded string. The
+// metadata is decoded by a unit of the image, which does not decompress it.
+func (e *Config) SetAfterburnMetadata(data []byte) error {
+	value, _, err := e.encode(data, 0)
+	if err != nil {
+		return err
+	}
+	*e = append(*e, &types.OptionValue{
+		Key:   "guestinfo.afterburn.metadata",
+		Value: value,
+	})
 	return nil
 }
 
 // SetCloudInitMetadata sets the cloud init user data at the key
-// "guestinfo.metadata" as a base64-encoded string.
-func (e *Config) SetCloudInitMetadata(data []byte) error {
+// "guestinfo.metadata" as a base64-encoded string. The data is gzip
+// compressed first if it is larger than the compression threshold, unless
+// the threshold is 0.
+func (e *Config) SetCloudInitMetadata(data []byte, compressionThreshold int) error {
+	return e.set("guestinfo.metadata", data, compressionThreshold)
+}
+
+// set sets the encoded data at the key, and its encoding at the key with the
+// ".encoding" suffix.
+func (e *Config) set(key string, data []byte, compressionThreshold int) error {
+	value, encoding, err := e.encode(data, compressionThreshold)
+	if err != nil {
+		return err
+	}
 	*e = append(*e,
 		&types.OptionValue{
-			Key:   "guestinfo.metadata",
-			Value: e.encode(data),
+			Key:   key,
+			Value: value,
 		},
 		&types.OptionValue{
-			Key:   "guestinfo.metadata.encoding",
-			Value: "base64",
+			Key:   key + ".encoding",
+			Value: encoding,
 		},
 	)
-
 	return nil
 }
 
 // encode first attempts to decode the data as many times as necessary
 // to ensure it is plain-text before returning the result as a base64
-// encoded string.
-func (e *Config) encode(data []byte) string {
+// encoded string, gzip compressed if the plain-text is larger than the
+// compression threshold.
+func (e *Config) encode(data []byte, compressionThreshold int) (string, string, error) {
 	if len(data) == 0 {
-		return ""
+		return "", Base64Encoding, nil
 	}
 	for {
 		decoded, err := base64.StdEncoding.DecodeString(string(data))
@@ -84,5 +160,39 @@ func (e *Config) encode(data []byte) string {
 		}
 		data = decoded
 	}
-	return base64.StdEncoding.EncodeToString(data)
+	if compressionThreshold <= 0 || len(data) <= compressionThreshold {
+		return base64.StdEncoding.EncodeToString(data), Base64Encoding, nil
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return "", "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), GzipBase64Encoding, nil
+}
+
+// Decode returns the plain-text of a guestinfo value with the given
+// encoding.
+func Decode(value, encoding string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if encoding != GzipBase64Encoding {
+		return data, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
@@ -19,6 +19,7 @@ package extra
 import (
 	"encoding/base64"
 	"fmt"
+	"strings"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
@@ -59,7 +60,7 @@ var _ = Describe("Config_SetCustomVMXKeys", func() {
 var _ = Describe("Config_SetCloudInitUserData", func() {
 	ConfigInitFnTester(
 		func(config *Config, s string) error {
-			return config.SetCloudInitUserData([]byte(s))
+			return config.SetCloudInitUserData([]byte(s), 0)
 		},
 		"SetCloudInitUserData",
 		"guestinfo.userdata",
@@ -67,9 +68,20 @@ var _ = Describe("Config_SetCloudInitUserData", func() {
 	)
 })
 
+var _ = Describe("Config_SetIgnitionUserData", func() {
+	ConfigInitFnTester(
+		func(config *Config, s string) error {
+			return config.SetIgnitionUserData([]byte(s), 0)
+		},
+		"SetIgnitionUserData",
+		"guestinfo.ignition.config.data",
+		"guestinfo.ignition.config.data.encoding",
+	)
+})
+
 var _ = Describe("Config_SetCloudInitMetadata", func() {
 	ConfigInitFnTester(func(config *Config, s string) error {
-		return config.SetCloudInitMetadata([]byte(s))
+		return config.SetCloudInitMetadata([]byte(s), 0)
 	},
 		"SetCloudInitMetadata",
 		"guestinfo.metadata",
@@ -77,6 +89,58 @@ var _ = Describe("Config_SetCloudInitMetadata", func() {
 	)
 })
 
+var _ = Describe("Config_SetTalosConfig", func() {
+	Context("we set a talos machine config", func() {
+		var config Config
+		err := config.SetTalosConfig([]byte("version: v1alpha1"))
+
+		It("sets the config without an encoding key", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).To(Equal(Config{&types.OptionValue{
+				Key:   "guestinfo.talos.config",
+				Value: base64Encode("version: v1alpha1"),
+			}}))
+		})
+	})
+})
+
+var _ = Describe("Config_Compression", func() {
+	const threshold = 64
+	largeData := strings.Repeat("some sample data, ", 10)
+
+	Context("we set data smaller than the compression threshold", func() {
+		var config Config
+		err := config.SetCloudInitUserData([]byte("some sample data, "), threshold)
+
+		It("does not compress the data", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).To(ContainElement(&types.OptionValue{
+				Key:   "guestinfo.userdata.encoding",
+				Value: "base64",
+			}))
+		})
+	})
+
+	Context("we set data larger than the compression threshold", func() {
+		var config Config
+		err := config.SetCloudInitMetadata([]byte(base64Encode(largeData)), threshold)
+
+		It("compresses the plain-text data", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).To(ContainElement(&types.OptionValue{
+				Key:   "guestinfo.metadata.encoding",
+				Value: "gzip+base64",
+			}))
+			value := config[0].GetOptionValue().Value.(string)
+			Expect(len(value)).To(BeNumerically("<", len(base64Encode(largeData))))
+
+			decoded, err := Decode(value, GzipBase64Encoding)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(decoded)).To(Equal(largeData))
+		})
+	})
+})
+
 func base64Encode(s string) string {
 	return base64.StdEncoding.EncodeToString([]byte(s))
 }
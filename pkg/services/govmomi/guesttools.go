@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// guestToolsProperties are the properties of a VM reporting the status of its
+// VMware Tools.
+var guestToolsProperties = []string{
+	"guest.toolsRunningStatus",
+	"guest.toolsVersion",
+	"guest.toolsVersionStatus2",
+}
+
+// reconcileGuestTools reports the status of the VMware Tools of the VM in the
+// status and the GuestToolsRunning condition of the VSphereVM, and emits a
+// warning Event whenever the VMware Tools are found missing or outdated.
+func reconcileGuestTools(ctx *virtualMachineContext) error {
+	var guest *types.GuestInfo
+	if ctx.vmState != nil {
+		guest = ctx.vmState.Guest
+	} else {
+		var obj mo.VirtualMachine
+		if err := ctx.Obj.Properties(ctx, ctx.Ref, guestToolsProperties, &obj); err != nil {
+			return errors.Wrapf(err, "unable to get VMware Tools status for vm %s", ctx)
+		}
+		guest = obj.Guest
+	}
+
+	status := &infrav1.GuestToolsStatus{}
+	if guest != nil {
+		status.RunningStatus = guest.ToolsRunningStatus
+		status.VersionStatus = guest.ToolsVersionStatus2
+		status.Version = guest.ToolsVersion
+	}
+	previous := ctx.VSphereVM.Status.GuestTools
+	ctx.VSphereVM.Status.GuestTools = status
+
+	switch {
+	case isGuestToolsRunning(status):
+		conditions.MarkTrue(ctx.VSphereVM, infrav1.GuestToolsRunningCondition)
+	case status.VersionStatus == string(types.VirtualMachineToolsVersionStatusGuestToolsNotInstalled):
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.GuestToolsRunningCondition, infrav1.GuestToolsNotInstalledReason, clusterv1.ConditionSeverityWarning,
+			"VMware Tools are not installed")
+	default:
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.GuestToolsRunningCondition, infrav1.GuestToolsNotRunningReason, clusterv1.ConditionSeverityInfo,
+			"VMware Tools are not running")
+	}
+
+	if previous != nil && previous.VersionStatus == status.VersionStatus {
+		return nil
+	}
+	switch types.VirtualMachineToolsVersionStatus(status.VersionStatus) {
+	case types.VirtualMachineToolsVersionStatusGuestToolsNotInstalled:
+		ctx.Recorder.Warnf(ctx.VSphereVM, infrav1.GuestToolsNotInstalledReason,
+			"VMware Tools are not installed in VM %s, which does not report its IP addresses", ctx.Ref.Value)
+	case types.VirtualMachineToolsVersionStatusGuestToolsNeedUpgrade,
+		types.VirtualMachineToolsVersionStatusGuestToolsSupportedOld,
+		types.VirtualMachineToolsVersionStatusGuestToolsTooOld,
+		types.VirtualMachineToolsVersionStatusGuestToolsBlacklisted:
+		ctx.Recorder.Warnf(ctx.VSphereVM, infrav1.GuestToolsOutdatedReason,
+			"VMware Tools version %s of VM %s is outdated: %s", status.Version, ctx.Ref.Value, status.VersionStatus)
+	}
+	return nil
+}
+
+// isGuestToolsRunning returns whether the VMware Tools are running, including
+// while they execute the scripts of a power operation.
+func isGuestToolsRunning(status *infrav1.GuestToolsStatus) bool {
+	switch types.VirtualMachineToolsRunningStatus(status.RunningStatus) {
+	case types.VirtualMachineToolsRunningStatusGuestToolsRunning, types.VirtualMachineToolsRunningStatusGuestToolsExecutingScripts:
+		return true
+	default:
+		return false
+	}
+}
+
+// isWaitingForGuestTools returns whether the VSphereVM requires the VMware
+// Tools of its VM to be running while they are not.
+func isWaitingForGuestTools(vm *infrav1.VSphereVM) bool {
+	if vm.Spec.GuestTools == nil || !vm.Spec.GuestTools.RequireRunning {
+		return false
+	}
+	return vm.Status.GuestTools == nil || !isGuestToolsRunning(vm.Status.GuestTools)
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestReconcileGuestTools(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	vmCtx.VSphereVM.Spec.GuestTools = &infrav1.GuestToolsSpec{RequireRunning: true}
+	simVM := simulator.Map.Get(vmCtx.Ref).(*simulator.VirtualMachine) //nolint:forcetypeassert
+	simVM.Guest.ToolsVersion = "11360"
+	simVM.Guest.ToolsVersionStatus2 = string(types.VirtualMachineToolsVersionStatusGuestToolsNeedUpgrade)
+
+	g.Expect(reconcileGuestTools(vmCtx)).To(Succeed())
+	g.Expect(vmCtx.VSphereVM.Status.GuestTools).To(Equal(&infrav1.GuestToolsStatus{
+		RunningStatus: string(types.VirtualMachineToolsRunningStatusGuestToolsRunning),
+		VersionStatus: string(types.VirtualMachineToolsVersionStatusGuestToolsNeedUpgrade),
+		Version:       "11360",
+	}))
+	g.Expect(conditions.IsTrue(vmCtx.VSphereVM, infrav1.GuestToolsRunningCondition)).To(BeTrue())
+	g.Expect(isWaitingForGuestTools(vmCtx.VSphereVM)).To(BeFalse())
+
+	simVM.Guest.ToolsRunningStatus = string(types.VirtualMachineToolsRunningStatusGuestToolsNotRunning)
+	simVM.Guest.ToolsVersionStatus2 = string(types.VirtualMachineToolsVersionStatusGuestToolsNotInstalled)
+	g.Expect(reconcileGuestTools(vmCtx)).To(Succeed())
+	g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.GuestToolsRunningCondition)).To(Equal(infrav1.GuestToolsNotInstalledReason))
+	g.Expect(isWaitingForGuestTools(vmCtx.VSphereVM)).To(BeTrue())
+
+	vmCtx.VSphereVM.Spec.GuestTools = nil
+	g.Expect(isWaitingForGuestTools(vmCtx.VSphereVM)).To(BeFalse())
+}
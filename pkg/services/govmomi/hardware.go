@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// reconcileHardwareVersion upgrades the hardware version of the VM to the one
+// of the spec. Powered off VMs, e.g. newly cloned ones, are upgraded right
+// away, while the upgrade of running VMs is scheduled for the next restart of
+// their guest OS once requested with the VMHardwareUpgradeAnnotation.
+func (vms *VMService) reconcileHardwareVersion(ctx *virtualMachineContext) (bool, error) {
+	desired := ctx.VSphereVM.Spec.HardwareVersion
+	if desired == "" {
+		return true, nil
+	}
+
+	var obj mo.VirtualMachine
+	props := []string{"config.version", "config.scheduledHardwareUpgradeInfo", "runtime.powerState"}
+	if err := ctx.Obj.Properties(ctx, ctx.Ref, props, &obj); err != nil {
+		return false, errors.Wrapf(err, "unable to fetch props %v for vm %s", props, ctx)
+	}
+	if obj.Config == nil {
+		return true, nil
+	}
+
+	_, upgradeRequested := ctx.VSphereVM.Annotations[infrav1.VMHardwareUpgradeAnnotation]
+	if hardwareVersionNumber(obj.Config.Version) >= hardwareVersionNumber(desired) {
+		if upgradeRequested {
+			delete(ctx.VSphereVM.Annotations, infrav1.VMHardwareUpgradeAnnotation)
+		}
+		return true, nil
+	}
+
+	if obj.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOff {
+		task, err := ctx.Obj.UpgradeVM(ctx, desired)
+		if err != nil {
+			return false, errors.Wrapf(err, "unable to upgrade hardware version of vm %s to %s", ctx, desired)
+		}
+		delete(ctx.VSphereVM.Annotations, infrav1.VMHardwareUpgradeAnnotation)
+		ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+		ctx.Logger.Info("wait for VM hardware version to be upgraded", "from", obj.Config.Version, "to", desired)
+		return false, nil
+	}
+
+	if !upgradeRequested {
+		ctx.Logger.V(4).Info("skipping hardware version upgrade of running VM",
+			"version", obj.Config.Version, "hardwareVersion", desired, "reason", "no-annotation")
+		return true, nil
+	}
+
+	task, err := ctx.Obj.Reconfigure(ctx, types.VirtualMachineConfigSpec{
+		ScheduledHardwareUpgradeInfo: &types.ScheduledHardwareUpgradeInfo{
+			UpgradePolicy: string(types.ScheduledHardwareUpgradeInfoHardwareUpgradePolicyAlways),
+			VersionKey:    desired,
+		},
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to schedule hardware version upgrade of vm %s to %s", ctx, desired)
+	}
+	delete(ctx.VSphereVM.Annotations, infrav1.VMHardwareUpgradeAnnotation)
+	ctx.Recorder.Eventf(ctx.VSphereVM, "HardwareUpgradeScheduled",
+		"Hardware version upgrade from %s to %s scheduled for the next restart of the guest OS", obj.Config.Version, desired)
+	ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+	return false, nil
+}
+
+// hardwareVersionNumber returns the number of a vmx-NN hardware version, or 0
+// if the version is malformed.
+func hardwareVersionNumber(version string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(version, "vmx-"))
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/simulator/esx"
+	"github.com/vmware/govmomi/vim25/types"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestReconcileHardwareVersion(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vms := &VMService{}
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	simVM := simulator.Map.Get(vmCtx.Ref).(*simulator.VirtualMachine) //nolint:forcetypeassert
+	simVM.Config.Version = "vmx-10"
+	vmCtx.VSphereVM.Spec.HardwareVersion = esx.HardwareVersion
+
+	// A running VM is not upgraded without the annotation.
+	ok, err := vms.reconcileHardwareVersion(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(simVM.Config.Version).To(Equal("vmx-10"))
+
+	// The upgrade of a running VM is scheduled with the annotation.
+	vmCtx.VSphereVM.Annotations = map[string]string{infrav1.VMHardwareUpgradeAnnotation: ""}
+	ok, err = vms.reconcileHardwareVersion(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeFalse())
+	g.Expect(vmCtx.VSphereVM.Annotations).NotTo(HaveKey(infrav1.VMHardwareUpgradeAnnotation))
+	g.Expect(vmCtx.VSphereVM.Status.TaskRef).NotTo(BeEmpty())
+
+	// A powered off VM is upgraded right away.
+	task, err := vmCtx.Obj.PowerOff(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(task.Wait(vmCtx)).To(Succeed())
+	ok, err = vms.reconcileHardwareVersion(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeFalse())
+	task = object.NewTask(vmCtx.Session.Client.Client, types.ManagedObjectReference{Type: "Task", Value: vmCtx.VSphereVM.Status.TaskRef})
+	g.Expect(task.Wait(vmCtx)).To(Succeed())
+	g.Expect(simVM.Config.Version).To(Equal(esx.HardwareVersion))
+
+	ok, err = vms.reconcileHardwareVersion(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+
+	// Downgrades are not supported.
+	vmCtx.VSphereVM.Spec.HardwareVersion = "vmx-8"
+	ok, err = vms.reconcileHardwareVersion(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// reconcileHostHealth sets the UnhealthyHost condition of the VSphereVM from
+// the state of the ESXi host its VM runs on, and monitors the host so the
+// VSphereVM is reconciled again every time the state of the host changes.
+func reconcileHostHealth(ctx *virtualMachineContext) error {
+	var vm mo.VirtualMachine
+	if err := ctx.Obj.Properties(ctx, ctx.Ref, []string{"runtime.host"}, &vm); err != nil {
+		return errors.Wrapf(err, "unable to get host of vm %s", ctx)
+	}
+	if vm.Runtime.Host == nil {
+		conditions.Delete(ctx.VSphereVM, infrav1.UnhealthyHostCondition)
+		return nil
+	}
+
+	var host mo.HostSystem
+	hostRef := *vm.Runtime.Host
+	if err := object.NewHostSystem(ctx.Session.Client.Client, hostRef).Properties(ctx, hostRef, []string{"name", "runtime"}, &host); err != nil {
+		return errors.Wrapf(err, "unable to get state of host %s of vm %s", hostRef.Value, ctx)
+	}
+
+	if reason, message := hostHealth(&host); reason != "" {
+		if !conditions.IsTrue(ctx.VSphereVM, infrav1.UnhealthyHostCondition) {
+			ctx.Logger.Info("host of vm is unhealthy", "host", host.Name, "reason", reason)
+			ctx.Recorder.Warnf(ctx.VSphereVM, reason, message)
+		}
+		conditions.Set(ctx.VSphereVM, &clusterv1.Condition{
+			Type:    infrav1.UnhealthyHostCondition,
+			Status:  corev1.ConditionTrue,
+			Reason:  reason,
+			Message: message,
+		})
+	} else {
+		conditions.Delete(ctx.VSphereVM, infrav1.UnhealthyHostCondition)
+	}
+
+	return reconcileVSphereVMOnHostChange(ctx, hostRef)
+}
+
+// hostHealth returns the reason and the message of the UnhealthyHost
+// condition for the host, or an empty reason if the host is healthy.
+func hostHealth(host *mo.HostSystem) (string, string) {
+	runtime := host.Runtime
+	switch {
+	case runtime.ConnectionState != types.HostSystemConnectionStateConnected:
+		return infrav1.HostDisconnectedReason, fmt.Sprintf("host %s is %s", host.Name, runtime.ConnectionState)
+	case runtime.InMaintenanceMode:
+		return infrav1.HostInMaintenanceModeReason, fmt.Sprintf("host %s is in maintenance mode", host.Name)
+	case runtime.InQuarantineMode != nil && *runtime.InQuarantineMode:
+		return infrav1.HostHardwareFaultReason, fmt.Sprintf("host %s is quarantined because of a predicted hardware failure", host.Name)
+	}
+
+	if runtime.HealthSystemRuntime == nil || runtime.HealthSystemRuntime.SystemHealthInfo == nil {
+		return "", ""
+	}
+	var faulty []string
+	for _, sensor := range runtime.HealthSystemRuntime.SystemHealthInfo.NumericSensorInfo {
+		if sensor.HealthState == nil {
+			continue
+		}
+		if strings.EqualFold(sensor.HealthState.GetElementDescription().Key, string(types.HostNumericSensorHealthStateRed)) {
+			faulty = append(faulty, sensor.Name)
+		}
+	}
+	if len(faulty) > 0 {
+		return infrav1.HostHardwareFaultReason, fmt.Sprintf("host %s reports hardware faults: %s", host.Name, strings.Join(faulty, ", "))
+	}
+	return "", ""
+}
+
+// reconcileVSphereVMOnHostChange triggers a reconcile of the VSphereVM every
+// time the connection state, the maintenance or quarantine mode, or the
+// hardware health of the host its VM runs on changes.
+func reconcileVSphereVMOnHostChange(ctx *virtualMachineContext, hostRef types.ManagedObjectReference) error {
+	obj := ctx.VSphereVM.DeepCopy()
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	eventChannel := ctx.GetGenericEventChannelFor(gvk)
+	logger := ctx.Logger
+
+	return ctx.Session.WatchHost(ctx, ctx.Ref.Value, hostRef, func() {
+		// The handler must not block the session's subscription.
+		go func() {
+			logger.Info("triggering GenericEvent", "reason", "host-change")
+			eventChannel <- event.GenericEvent{
+				Object: obj,
+			}
+		}()
+	})
+}
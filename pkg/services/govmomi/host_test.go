@@ -0,0 +1,114 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/utils/pointer"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+func TestHostHealth(t *testing.T) {
+	sensor := func(name string, state types.HostNumericSensorHealthState) types.HostNumericSensorInfo {
+		return types.HostNumericSensorInfo{
+			Name:        name,
+			HealthState: &types.ElementDescription{Key: string(state)},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		runtime types.HostRuntimeInfo
+		reason  string
+	}{
+		{
+			name:    "connected host",
+			runtime: types.HostRuntimeInfo{ConnectionState: types.HostSystemConnectionStateConnected},
+		},
+		{
+			name:    "disconnected host",
+			runtime: types.HostRuntimeInfo{ConnectionState: types.HostSystemConnectionStateNotResponding},
+			reason:  infrav1.HostDisconnectedReason,
+		},
+		{
+			name: "host in maintenance mode",
+			runtime: types.HostRuntimeInfo{
+				ConnectionState:   types.HostSystemConnectionStateConnected,
+				InMaintenanceMode: true,
+			},
+			reason: infrav1.HostInMaintenanceModeReason,
+		},
+		{
+			name: "quarantined host",
+			runtime: types.HostRuntimeInfo{
+				ConnectionState:  types.HostSystemConnectionStateConnected,
+				InQuarantineMode: pointer.Bool(true),
+			},
+			reason: infrav1.HostHardwareFaultReason,
+		},
+		{
+			name: "host with a red sensor",
+			runtime: types.HostRuntimeInfo{
+				ConnectionState: types.HostSystemConnectionStateConnected,
+				HealthSystemRuntime: &types.HealthSystemRuntime{
+					SystemHealthInfo: &types.HostSystemHealthInfo{
+						NumericSensorInfo: []types.HostNumericSensorInfo{
+							sensor("fan-1", types.HostNumericSensorHealthStateGreen),
+							sensor("psu-2", types.HostNumericSensorHealthStateRed),
+						},
+					},
+				},
+			},
+			reason: infrav1.HostHardwareFaultReason,
+		},
+		{
+			name: "host with yellow sensors",
+			runtime: types.HostRuntimeInfo{
+				ConnectionState: types.HostSystemConnectionStateConnected,
+				HealthSystemRuntime: &types.HealthSystemRuntime{
+					SystemHealthInfo: &types.HostSystemHealthInfo{
+						NumericSensorInfo: []types.HostNumericSensorInfo{
+							sensor("fan-1", types.HostNumericSensorHealthStateYellow),
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			host := &mo.HostSystem{
+				ManagedEntity: mo.ManagedEntity{Name: "esx-1"},
+				Runtime:       tt.runtime,
+			}
+
+			reason, message := hostHealth(host)
+			g.Expect(reason).To(Equal(tt.reason))
+			if tt.reason == "" {
+				g.Expect(message).To(BeEmpty())
+			} else {
+				g.Expect(message).To(ContainSubstring("esx-1"))
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"archive/tar"
+	"bytes"
+	goctx "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/nfc"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/ovf"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/audit"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+)
+
+// ErrImageChecksumMismatch is returned when the OVA of a VSphereMachineImage
+// does not match its checksum.
+var ErrImageChecksumMismatch = errors.New("checksum mismatch")
+
+// ImageService imports the OVAs of VSphereMachineImages as templates.
+type ImageService struct{}
+
+// ImportOVA imports the OVA of the image as a template named after the image
+// and returns the inventory path of the template. The OVA is streamed from
+// its URL to vCenter without being stored by the controller, so its checksum
+// is only verified once its disks are uploaded; the import is aborted when it
+// does not match. An imported template is returned without downloading the
+// OVA again, while a VM left over by an interrupted import is destroyed and
+// the OVA is imported again.
+func (s *ImageService) ImportOVA(ctx goctx.Context, sess *session.Session, image *infrav1.VSphereMachineImage) (string, error) {
+	logger := ctrl.LoggerFrom(ctx).WithValues("image", image.Name)
+
+	folder, err := sess.Finder.FolderOrDefault(ctx, image.Spec.Folder)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to find folder %q of image %s", image.Spec.Folder, image.Name)
+	}
+	templatePath := path.Join(folder.InventoryPath, image.Name)
+
+	imported, err := s.findTemplate(ctx, sess, templatePath)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to find template %q of image %s", templatePath, image.Name)
+	}
+	if imported {
+		return templatePath, nil
+	}
+
+	pool, err := sess.Finder.ResourcePoolOrDefault(ctx, image.Spec.ResourcePool)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to find resource pool %q of image %s", image.Spec.ResourcePool, image.Name)
+	}
+	datastore, err := sess.Finder.DatastoreOrDefault(ctx, image.Spec.Datastore)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to find datastore %q of image %s", image.Spec.Datastore, image.Name)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, image.Spec.URL, nil)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid URL %q of image %s", image.Spec.URL, image.Name)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to download OVA of image %s", image.Name)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("unable to download OVA of image %s: %s", image.Name, resp.Status)
+	}
+
+	hash := sha256.New()
+	body := io.TeeReader(resp.Body, hash)
+	archive := tar.NewReader(body)
+
+	// The OVF descriptor is the first file of an OVA.
+	header, err := archive.Next()
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to read OVA of image %s", image.Name)
+	}
+	if path.Ext(header.Name) != ".ovf" {
+		return "", errors.Errorf("unable to read OVA of image %s: %s is not an OVF descriptor", image.Name, header.Name)
+	}
+	descriptor, err := ioutil.ReadAll(archive)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to read OVF descriptor of image %s", image.Name)
+	}
+
+	params := types.OvfCreateImportSpecParams{EntityName: image.Name}
+	if image.Spec.NetworkName != "" {
+		mapping, err := s.networkMapping(ctx, sess.Finder, image.Spec.NetworkName, descriptor)
+		if err != nil {
+			return "", errors.Wrapf(err, "unable to map networks of image %s", image.Name)
+		}
+		params.NetworkMapping = mapping
+	}
+	spec, err := ovf.NewManager(sess.Client.Client).CreateImportSpec(ctx, string(descriptor), pool, datastore, params)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to create import spec of image %s", image.Name)
+	}
+	if len(spec.Error) > 0 {
+		return "", errors.Errorf("unable to create import spec of image %s: %s", image.Name, spec.Error[0].LocalizedMessage)
+	}
+
+	logger.Info("importing OVA", "url", image.Spec.URL, "template", templatePath)
+	lease, err := pool.ImportVApp(ctx, spec.ImportSpec, folder, nil)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to import OVA of image %s", image.Name)
+	}
+	info, err := lease.Wait(ctx, spec.FileItem)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to import OVA of image %s", image.Name)
+	}
+	if err := s.upload(ctx, lease, info, archive); err != nil {
+		_ = lease.Abort(ctx, nil)
+		return "", errors.Wrapf(err, "unable to upload disks of image %s", image.Name)
+	}
+
+	// The rest of the OVA, e.g. its manifest, is read for its checksum.
+	if _, err := io.Copy(ioutil.Discard, body); err != nil {
+		_ = lease.Abort(ctx, nil)
+		return "", errors.Wrapf(err, "unable to download OVA of image %s", image.Name)
+	}
+	checksum := strings.TrimPrefix(image.Spec.Checksum, "sha256:")
+	if actual := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(actual, checksum) {
+		_ = lease.Abort(ctx, nil)
+		return "", errors.Wrapf(ErrImageChecksumMismatch, "OVA of image %s has checksum sha256:%s", image.Name, actual)
+	}
+
+	if err := lease.Complete(ctx); err != nil {
+		return "", errors.Wrapf(err, "unable to import OVA of image %s", image.Name)
+	}
+	if err := object.NewVirtualMachine(sess.Client.Client, info.Entity).MarkAsTemplate(ctx); err != nil {
+		return "", errors.Wrapf(err, "unable to mark vm %s of image %s as template", info.Entity.Value, image.Name)
+	}
+	return templatePath, nil
+}
+
+// findTemplate returns whether the template of an image has been imported.
+// A VM which is not a template is left over by an interrupted import, and
+// is destroyed.
+func (s *ImageService) findTemplate(ctx goctx.Context, sess *session.Session, templatePath string) (bool, error) {
+	vm, err := sess.Finder.VirtualMachine(ctx, templatePath)
+	if err != nil {
+		if _, ok := err.(*find.NotFoundError); ok {
+			return false, nil
+		}
+		return false, err
+	}
+
+	var obj mo.VirtualMachine
+	if err := vm.Properties(ctx, vm.Reference(), []string{"config.template"}, &obj); err != nil {
+		return false, err
+	}
+	if obj.Config != nil && obj.Config.Template {
+		return true, nil
+	}
+
+	ctrl.LoggerFrom(ctx).Info("destroying vm left over by an interrupted import", "vm", templatePath)
+	task, err := vm.Destroy(ctx)
+	if err != nil {
+		return false, err
+	}
+	return false, audit.WaitForTask(ctx, task)
+}
+
+// networkMapping maps the networks of the OVF descriptor to the network.
+func (s *ImageService) networkMapping(ctx goctx.Context, finder *find.Finder, networkName string, descriptor []byte) ([]types.OvfNetworkMapping, error) {
+	envelope, err := ovf.Unmarshal(bytes.NewReader(descriptor))
+	if err != nil {
+		return nil, err
+	}
+	if envelope.Network == nil {
+		return nil, nil
+	}
+	network, err := finder.Network(ctx, networkName)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := make([]types.OvfNetworkMapping, 0, len(envelope.Network.Networks))
+	for _, n := range envelope.Network.Networks {
+		mapping = append(mapping, types.OvfNetworkMapping{Name: n.Name, Network: network.Reference()})
+	}
+	return mapping, nil
+}
+
+// upload streams the files of the import lease from the rest of the OVA.
+func (s *ImageService) upload(ctx goctx.Context, lease *nfc.Lease, info *nfc.LeaseInfo, archive *tar.Reader) error {
+	updater := lease.StartUpdater(ctx, info)
+	defer updater.Done()
+
+	items := make(map[string]nfc.FileItem, len(info.Items))
+	for _, item := range info.Items {
+		items[item.Path] = item
+	}
+	for len(items) > 0 {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		item, ok := items[header.Name]
+		if !ok {
+			continue
+		}
+		if err := lease.Upload(ctx, item, archive, soap.Upload{ContentLength: header.Size}); err != nil {
+			return errors.Wrapf(err, "unable to upload %s", header.Name)
+		}
+		delete(items, header.Name)
+	}
+	if len(items) > 0 {
+		missing := make([]string, 0, len(items))
+		for name := range items {
+			missing = append(missing, name)
+		}
+		sort.Strings(missing)
+		return errors.Errorf("%s missing from the OVA", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"archive/tar"
+	"bytes"
+	goctx "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/mo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+const testOVFDescriptor = `<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1"
+          xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1"
+          xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData"
+          xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData">
+  <References>
+    <File ovf:href="disk.vmdk" ovf:id="file1" ovf:size="16"/>
+  </References>
+  <DiskSection>
+    <Info>Virtual disk information</Info>
+    <Disk ovf:capacity="1" ovf:capacityAllocationUnits="byte * 2^30" ovf:diskId="vmdisk1" ovf:fileRef="file1"/>
+  </DiskSection>
+  <NetworkSection>
+    <Info>The list of logical networks</Info>
+    <Network ovf:name="nat"/>
+  </NetworkSection>
+  <VirtualSystem ovf:id="vm">
+    <Info>A virtual machine</Info>
+    <VirtualHardwareSection>
+      <Info>Virtual hardware requirements</Info>
+      <System>
+        <vssd:VirtualSystemType>vmx-13</vssd:VirtualSystemType>
+      </System>
+      <Item>
+        <rasd:InstanceID>1</rasd:InstanceID>
+        <rasd:ResourceType>5</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:HostResource>ovf:/disk/vmdisk1</rasd:HostResource>
+        <rasd:InstanceID>2</rasd:InstanceID>
+        <rasd:Parent>1</rasd:Parent>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:Connection>nat</rasd:Connection>
+        <rasd:InstanceID>3</rasd:InstanceID>
+        <rasd:ResourceSubType>vmxnet3</rasd:ResourceSubType>
+        <rasd:ResourceType>10</rasd:ResourceType>
+      </Item>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>`
+
+// newTestOVA returns an OVA with the files and its SHA-256 checksum.
+func newTestOVA(t *testing.T, files ...string) ([]byte, string) {
+	t.Helper()
+	g := NewWithT(t)
+
+	contents := map[string]string{
+		"image.ovf": testOVFDescriptor,
+		"disk.vmdk": "not really a vmdk",
+	}
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, name := range files {
+		g.Expect(w.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(contents[name]))})).To(Succeed())
+		_, err := w.Write([]byte(contents[name]))
+		g.Expect(err).NotTo(HaveOccurred())
+	}
+	g.Expect(w.Close()).To(Succeed())
+
+	sum := sha256.Sum256(buf.Bytes())
+	return buf.Bytes(), hex.EncodeToString(sum[:])
+}
+
+func TestImportOVA(t *testing.T) {
+	s := &ImageService{}
+
+	setup := func(t *testing.T, ova []byte) (*session.Session, *httptest.Server, *int32) {
+		t.Helper()
+		g := NewWithT(t)
+		simr, err := vcsim.NewBuilder().Build()
+		g.Expect(err).NotTo(HaveOccurred())
+		t.Cleanup(simr.Destroy)
+
+		sess, err := session.GetOrCreate(goctx.Background(), session.NewParams().
+			WithServer(simr.ServerURL().Host).
+			WithUserInfo(simr.Username(), simr.Password()).
+			WithDatacenter("*"))
+		g.Expect(err).NotTo(HaveOccurred())
+
+		var downloads int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&downloads, 1)
+			_, _ = w.Write(ova)
+		}))
+		t.Cleanup(server.Close)
+		return sess, server, &downloads
+	}
+	newImage := func(url, checksum string) *infrav1.VSphereMachineImage {
+		return &infrav1.VSphereMachineImage{
+			ObjectMeta: metav1.ObjectMeta{Name: "ubuntu-2004"},
+			Spec: infrav1.VSphereMachineImageSpec{
+				URL:          url,
+				Checksum:     checksum,
+				ResourcePool: "/DC0/host/DC0_C0/Resources",
+				NetworkName:  "VM Network",
+			},
+		}
+	}
+	isTemplate := func(g *WithT, sess *session.Session, templatePath string) bool {
+		vm, err := sess.Finder.VirtualMachine(goctx.Background(), templatePath)
+		g.Expect(err).NotTo(HaveOccurred())
+		var obj mo.VirtualMachine
+		g.Expect(vm.Properties(goctx.Background(), vm.Reference(), []string{"config.template"}, &obj)).To(Succeed())
+		return obj.Config.Template
+	}
+
+	t.Run("the OVA is imported once as a template", func(t *testing.T) {
+		g := NewWithT(t)
+		ova, checksum := newTestOVA(t, "image.ovf", "disk.vmdk")
+		sess, server, downloads := setup(t, ova)
+		image := newImage(server.URL+"/ubuntu-2004.ova", "sha256:"+checksum)
+
+		templatePath, err := s.ImportOVA(goctx.Background(), sess, image)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(templatePath).To(Equal("/DC0/vm/ubuntu-2004"))
+		g.Expect(isTemplate(g, sess, templatePath)).To(BeTrue())
+
+		templatePath, err = s.ImportOVA(goctx.Background(), sess, image)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(templatePath).To(Equal("/DC0/vm/ubuntu-2004"))
+		g.Expect(atomic.LoadInt32(downloads)).To(Equal(int32(1)))
+	})
+
+	t.Run("an OVA not matching its checksum is not kept", func(t *testing.T) {
+		g := NewWithT(t)
+		ova, checksum := newTestOVA(t, "image.ovf", "disk.vmdk")
+		sess, server, downloads := setup(t, ova)
+		image := newImage(server.URL+"/ubuntu-2004.ova", "0000000000000000000000000000000000000000000000000000000000000000")
+
+		_, err := s.ImportOVA(goctx.Background(), sess, image)
+		g.Expect(errors.Is(err, ErrImageChecksumMismatch)).To(BeTrue())
+
+		// The VM left over by the aborted import is replaced.
+		image.Spec.Checksum = checksum
+		templatePath, err := s.ImportOVA(goctx.Background(), sess, image)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(isTemplate(g, sess, templatePath)).To(BeTrue())
+		g.Expect(atomic.LoadInt32(downloads)).To(Equal(int32(2)))
+	})
+
+	t.Run("an OVA missing a disk is not imported", func(t *testing.T) {
+		g := NewWithT(t)
+		ova, checksum := newTestOVA(t, "image.ovf")
+		sess, server, _ := setup(t, ova)
+
+		_, err := s.ImportOVA(goctx.Background(), sess, newImage(server.URL+"/ubuntu-2004.ova", checksum))
+		g.Expect(err).To(MatchError(ContainSubstring("disk.vmdk missing from the OVA")))
+	})
+
+	t.Run("an OVA not starting with its descriptor is not imported", func(t *testing.T) {
+		g := NewWithT(t)
+		ova, checksum := newTestOVA(t, "disk.vmdk", "image.ovf")
+		sess, server, _ := setup(t, ova)
+
+		_, err := s.ImportOVA(goctx.Background(), sess, newImage(server.URL+"/ubuntu-2004.ova", checksum))
+		g.Expect(err).To(MatchError(ContainSubstring("disk.vmdk is not an OVF descriptor")))
+	})
+}
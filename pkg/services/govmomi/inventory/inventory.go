@@ -0,0 +1,218 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package inventory tracks the vCenter inventory objects created for a
+// cluster, so they are removed along with the cluster.
+package inventory
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vapi/tags"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/audit"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+)
+
+// OwnerTagName returns the name of the tag attached to the inventory objects
+// created for the cluster.
+func OwnerTagName(namespace, clusterName string) string {
+	return namespace + "/" + clusterName
+}
+
+// MarkOwned attaches the owner tag of the cluster to the inventory object,
+// creating the tag and its category if they do not exist yet.
+func MarkOwned(ctx context.Context, s *session.Session, ownerTag string, ref types.ManagedObjectReference) error {
+	// Standalone ESXi hosts have no tags, their inventory objects are not
+	// cleaned up with the cluster.
+	if !s.Supports(session.TaggingCapability) {
+		return nil
+	}
+	categoryID, err := getCategoryID(ctx, s)
+	if err != nil {
+		return err
+	}
+	if categoryID == "" {
+		categoryID, err = s.TagManager.CreateCategory(ctx, &tags.Category{
+			Name:            infrav1.OwnerTagCategoryName,
+			Description:     "Cluster API Provider vSphere",
+			Cardinality:     "SINGLE",
+			AssociableTypes: []string{"Folder", "ResourcePool"},
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to create tag category %s", infrav1.OwnerTagCategoryName)
+		}
+	}
+
+	tag, err := getTag(ctx, s, categoryID, ownerTag)
+	if err != nil {
+		return err
+	}
+	var tagID string
+	if tag != nil {
+		tagID = tag.ID
+	} else {
+		tagID, err = s.TagManager.CreateTag(ctx, &tags.Tag{
+			Name:        ownerTag,
+			Description: "Inventory object created for the cluster",
+			CategoryID:  categoryID,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to create tag %s", ownerTag)
+		}
+	}
+
+	if err := s.TagManager.AttachTag(ctx, tagID, ref); err != nil {
+		return errors.Wrapf(err, "failed to attach tag %s to %s", ownerTag, ref)
+	}
+	return nil
+}
+
+// Cleanup destroys the folders and resource pools carrying the owner tag of
+// the cluster, then deletes the tag. Objects that are not empty, e.g. because
+// they hold VMs that were not created for the cluster, are left in place.
+// Objects that were not created for the cluster do not carry the tag and are
+// never destroyed.
+func Cleanup(ctx context.Context, s *session.Session, ownerTag string) error {
+	logger := ctrl.LoggerFrom(ctx, "tag", ownerTag)
+
+	categoryID, err := getCategoryID(ctx, s)
+	if err != nil || categoryID == "" {
+		return err
+	}
+	tag, err := getTag(ctx, s, categoryID, ownerTag)
+	if err != nil || tag == nil {
+		return err
+	}
+
+	refs, err := s.TagManager.ListAttachedObjects(ctx, tag.ID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list objects attached to tag %s", ownerTag)
+	}
+
+	// Nested objects are destroyed children first, hence the objects are
+	// visited again as long as one of them is destroyed.
+	pending := refs
+	for destroyed := true; destroyed && len(pending) > 0; {
+		destroyed = false
+		var remaining []mo.Reference
+		for _, ref := range pending {
+			empty, err := isEmpty(ctx, s, ref.Reference())
+			if err != nil {
+				return err
+			}
+			if !empty {
+				remaining = append(remaining, ref)
+				continue
+			}
+			logger.Info("destroying inventory object", "ref", ref.Reference())
+			if err := destroy(ctx, s, ref.Reference()); err != nil {
+				return err
+			}
+			destroyed = true
+		}
+		pending = remaining
+	}
+	for _, ref := range pending {
+		logger.Info("leaving inventory object in place as it is not empty", "ref", ref.Reference())
+	}
+
+	if err := s.TagManager.DeleteTag(ctx, tag); err != nil {
+		return errors.Wrapf(err, "failed to delete tag %s", ownerTag)
+	}
+	return nil
+}
+
+// isEmpty returns whether the folder or resource pool has no children.
+func isEmpty(ctx context.Context, s *session.Session, ref types.ManagedObjectReference) (bool, error) {
+	switch ref.Type {
+	case "Folder":
+		var folder mo.Folder
+		if err := object.NewCommon(s.Client.Client, ref).Properties(ctx, ref, []string{"childEntity"}, &folder); err != nil {
+			return false, errors.Wrapf(err, "failed to get children of folder %s", ref)
+		}
+		return len(folder.ChildEntity) == 0, nil
+	case "ResourcePool":
+		var pool mo.ResourcePool
+		if err := object.NewCommon(s.Client.Client, ref).Properties(ctx, ref, []string{"resourcePool", "vm"}, &pool); err != nil {
+			return false, errors.Wrapf(err, "failed to get children of resource pool %s", ref)
+		}
+		return len(pool.ResourcePool) == 0 && len(pool.Vm) == 0, nil
+	default:
+		return false, nil
+	}
+}
+
+func destroy(ctx context.Context, s *session.Session, ref types.ManagedObjectReference) error {
+	var (
+		task *object.Task
+		err  error
+	)
+	switch ref.Type {
+	case "Folder":
+		task, err = object.NewFolder(s.Client.Client, ref).Destroy(ctx)
+	case "ResourcePool":
+		task, err = object.NewResourcePool(s.Client.Client, ref).Destroy(ctx)
+	default:
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to destroy %s", ref)
+	}
+	if err := audit.WaitForTask(ctx, task); err != nil {
+		return errors.Wrapf(err, "failed to destroy %s", ref)
+	}
+	return nil
+}
+
+// getCategoryID returns the ID of the owner tag category, or an empty string
+// if it does not exist or the session has no tags.
+func getCategoryID(ctx context.Context, s *session.Session) (string, error) {
+	if !s.Supports(session.TaggingCapability) {
+		return "", nil
+	}
+	categories, err := s.TagManager.GetCategories(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get tag categories")
+	}
+	for _, category := range categories {
+		if category.Name == infrav1.OwnerTagCategoryName {
+			return category.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// getTag returns the tag of the category with the name, or nil if it does
+// not exist.
+func getTag(ctx context.Context, s *session.Session, categoryID, name string) (*tags.Tag, error) {
+	categoryTags, err := s.TagManager.GetTagsForCategory(ctx, categoryID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get tags of category %s", infrav1.OwnerTagCategoryName)
+	}
+	for i := range categoryTags {
+		if categoryTags[i].Name == name {
+			return &categoryTags[i], nil
+		}
+	}
+	return nil, nil
+}
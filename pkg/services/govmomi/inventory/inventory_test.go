@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	// run init func to register the tagging API endpoints.
+	_ "github.com/vmware/govmomi/vapi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/extra"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+)
+
+func TestCleanup(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	model := simulator.VPX()
+	model.Host = 0
+	g.Expect(model.Create()).To(Succeed())
+	t.Cleanup(model.Remove)
+	model.Service.TLS = new(tls.Config)
+	model.Service.RegisterEndpoints = true
+	server := model.Service.NewServer()
+	t.Cleanup(server.Close)
+
+	pass, _ := server.URL.User.Password()
+	s, err := session.GetOrCreate(ctx,
+		session.NewParams().
+			WithServer(server.URL.Host).
+			WithUserInfo(server.URL.User.Username(), pass).
+			WithDatacenter("*"))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	ownerTag := OwnerTagName("default", "test-cluster")
+
+	// Cleaning up a cluster without owned objects is a no-op.
+	g.Expect(Cleanup(ctx, s, ownerTag)).To(Succeed())
+
+	vmFolder, err := s.Finder.DefaultFolder(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	owned, err := vmFolder.CreateFolder(ctx, "owned")
+	g.Expect(err).NotTo(HaveOccurred())
+	ownedChild, err := owned.CreateFolder(ctx, "child")
+	g.Expect(err).NotTo(HaveOccurred())
+	preExisting, err := vmFolder.CreateFolder(ctx, "pre-existing")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	rootPool, err := s.Finder.DefaultResourcePool(ctx)
+	g.Expect(err).NotTo(HaveOccurred())
+	ownedPool, err := rootPool.Create(ctx, "owned", types.DefaultResourceConfigSpec())
+	g.Expect(err).NotTo(HaveOccurred())
+	// A pool holding a VM is not empty.
+	busyPool, err := rootPool.Create(ctx, "busy", types.DefaultResourceConfigSpec())
+	g.Expect(err).NotTo(HaveOccurred())
+	vm := simulator.Map.Any("VirtualMachine")
+	simulator.Map.Get(busyPool.Reference()).(*simulator.ResourcePool).Vm = []types.ManagedObjectReference{vm.Reference()} //nolint:forcetypeassert
+
+	// The parent is tagged before its child to check the children are
+	// destroyed first.
+	for _, ref := range []types.ManagedObjectReference{owned.Reference(), ownedChild.Reference(), ownedPool.Reference(), busyPool.Reference()} {
+		g.Expect(MarkOwned(ctx, s, ownerTag, ref)).To(Succeed())
+	}
+
+	g.Expect(Cleanup(ctx, s, ownerTag)).To(Succeed())
+
+	for _, ref := range []types.ManagedObjectReference{owned.Reference(), ownedChild.Reference(), ownedPool.Reference()} {
+		g.Expect(simulator.Map.Get(ref)).To(BeNil(), "expected %s to be destroyed", ref)
+	}
+	g.Expect(simulator.Map.Get(busyPool.Reference())).NotTo(BeNil())
+	g.Expect(simulator.Map.Get(preExisting.Reference())).NotTo(BeNil())
+
+	_, err = s.Finder.Folder(ctx, "owned")
+	g.Expect(err).To(BeAssignableToTypeOf(&find.NotFoundError{}))
+
+	categoryID, err := getCategoryID(ctx, s)
+	g.Expect(err).NotTo(HaveOccurred())
+	tag, err := getTag(ctx, s, categoryID, ownerTag)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(tag).To(BeNil())
+}
+
+func TestFindOwnedVMs(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	model := simulator.VPX()
+	model.Host = 0
+	g.Expect(model.Create()).To(Succeed())
+	t.Cleanup(model.Remove)
+	model.Service.TLS = new(tls.Config)
+	model.Service.RegisterEndpoints = true
+	server := model.Service.NewServer()
+	t.Cleanup(server.Close)
+
+	pass, _ := server.URL.User.Password()
+	s, err := session.GetOrCreate(ctx,
+		session.NewParams().
+			WithServer(server.URL.Host).
+			WithUserInfo(server.URL.User.Username(), pass).
+			WithDatacenter("*"))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	ownerTag := OwnerTagName("default", "test-cluster")
+	locations := []Location{{}}
+
+	vms, err := FindOwnedVMs(ctx, s, ownerTag, locations)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(vms).To(BeEmpty())
+
+	setOwner := func(name, cluster, vsphereVM string) *object.VirtualMachine {
+		vm, err := s.Finder.VirtualMachine(ctx, name)
+		g.Expect(err).NotTo(HaveOccurred())
+		var config extra.Config
+		config.SetOwner(cluster, vsphereVM)
+		task, err := vm.Reconfigure(ctx, types.VirtualMachineConfigSpec{ExtraConfig: config})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(task.Wait(ctx)).To(Succeed())
+		return vm
+	}
+	owned := setOwner("DC0_C0_RP0_VM0", ownerTag, "default/vm-0")
+	setOwner("DC0_C0_RP0_VM1", OwnerTagName("default", "other-cluster"), "default/vm-1")
+
+	// A VM is found once even though it is in both the folder and the
+	// resource pool of the location.
+	vms, err = FindOwnedVMs(ctx, s, ownerTag, append(locations, Location{Datacenter: "DC0"}))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(vms).To(Equal([]OwnedVM{{Ref: owned.Reference(), Name: "DC0_C0_RP0_VM0", VSphereVM: "default/vm-0"}}))
+
+	// Locations which do not exist are skipped.
+	vms, err = FindOwnedVMs(ctx, s, ownerTag, []Location{{Folder: "missing", ResourcePool: "missing"}})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(vms).To(BeEmpty())
+
+	g.Expect(DestroyVM(ctx, s, owned.Reference())).To(Succeed())
+	g.Expect(simulator.Map.Get(owned.Reference())).To(BeNil())
+}
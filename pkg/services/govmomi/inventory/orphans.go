@@ -0,0 +1,204 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/audit"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/extra"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+)
+
+// Location is a folder and a resource pool of a datacenter the VMs of a
+// cluster are created in. Empty values stand for the defaults of the
+// session.
+type Location struct {
+	Datacenter   string
+	Folder       string
+	ResourcePool string
+}
+
+// OwnedVM is a VM created for a cluster.
+type OwnedVM struct {
+	Ref  types.ManagedObjectReference
+	Name string
+	// VSphereVM is the namespace and the name of the VSphereVM the VM was
+	// created for.
+	VSphereVM string
+}
+
+// FindOwnedVMs returns the VMs created for the cluster with the owner tag,
+// directly in the folders and the resource pools of the locations or in
+// those carrying the owner tag. Locations which do not exist anymore are
+// skipped.
+func FindOwnedVMs(ctx context.Context, s *session.Session, ownerTag string, locations []Location) ([]OwnedVM, error) {
+	containers, err := taggedContainers(ctx, s, ownerTag)
+	if err != nil {
+		return nil, err
+	}
+	for _, location := range locations {
+		refs, err := locationContainers(ctx, s, location)
+		if err != nil {
+			return nil, err
+		}
+		containers = append(containers, refs...)
+	}
+
+	found := map[types.ManagedObjectReference]OwnedVM{}
+	manager := view.NewManager(s.Client.Client)
+	for _, container := range containers {
+		v, err := manager.CreateContainerView(ctx, container, []string{"VirtualMachine"}, false)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create view of %s", container)
+		}
+		var vms []mo.VirtualMachine
+		err = v.Retrieve(ctx, []string{"VirtualMachine"}, []string{"name", "config.extraConfig"}, &vms)
+		_ = v.Destroy(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get VMs of %s", container)
+		}
+		for i := range vms {
+			if vm, ok := ownedVM(&vms[i], ownerTag); ok {
+				found[vm.Ref] = vm
+			}
+		}
+	}
+
+	vms := make([]OwnedVM, 0, len(found))
+	for _, vm := range found {
+		vms = append(vms, vm)
+	}
+	sort.Slice(vms, func(i, j int) bool { return vms[i].Ref.Value < vms[j].Ref.Value })
+	return vms, nil
+}
+
+// DestroyVM powers the VM off and destroys it.
+func DestroyVM(ctx context.Context, s *session.Session, ref types.ManagedObjectReference) error {
+	vm := object.NewVirtualMachine(s.Client.Client, ref)
+	state, err := vm.PowerState(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get power state of %s", ref)
+	}
+	if state == types.VirtualMachinePowerStatePoweredOn {
+		task, err := vm.PowerOff(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "failed to power off %s", ref)
+		}
+		if err := audit.WaitForTask(ctx, task); err != nil {
+			return errors.Wrapf(err, "failed to power off %s", ref)
+		}
+	}
+	task, err := vm.Destroy(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "failed to destroy %s", ref)
+	}
+	if err := audit.WaitForTask(ctx, task); err != nil {
+		return errors.Wrapf(err, "failed to destroy %s", ref)
+	}
+	return nil
+}
+
+// ownedVM returns the VM if its owner metadata names the cluster.
+func ownedVM(vm *mo.VirtualMachine, ownerTag string) (OwnedVM, bool) {
+	if vm.Config == nil {
+		return OwnedVM{}, false
+	}
+	var cluster, vsphereVM string
+	for _, option := range vm.Config.ExtraConfig {
+		value := option.GetOptionValue()
+		switch value.Key {
+		case extra.OwnerClusterKey:
+			cluster, _ = value.Value.(string)
+		case extra.OwnerVSphereVMKey:
+			vsphereVM, _ = value.Value.(string)
+		}
+	}
+	if cluster != ownerTag || vsphereVM == "" {
+		return OwnedVM{}, false
+	}
+	return OwnedVM{Ref: vm.Reference(), Name: vm.Name, VSphereVM: vsphereVM}, true
+}
+
+// taggedContainers returns the folders and the resource pools carrying the
+// owner tag.
+func taggedContainers(ctx context.Context, s *session.Session, ownerTag string) ([]types.ManagedObjectReference, error) {
+	categoryID, err := getCategoryID(ctx, s)
+	if err != nil || categoryID == "" {
+		return nil, err
+	}
+	tag, err := getTag(ctx, s, categoryID, ownerTag)
+	if err != nil || tag == nil {
+		return nil, err
+	}
+	objects, err := s.TagManager.ListAttachedObjects(ctx, tag.ID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list objects attached to tag %s", ownerTag)
+	}
+	var refs []types.ManagedObjectReference
+	for _, obj := range objects {
+		if ref := obj.Reference(); ref.Type == "Folder" || ref.Type == "ResourcePool" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// locationContainers returns the folder and the resource pool of the
+// location which exist.
+func locationContainers(ctx context.Context, s *session.Session, location Location) ([]types.ManagedObjectReference, error) {
+	finder := find.NewFinder(s.Client.Client, false)
+	datacenter, err := finder.DatacenterOrDefault(ctx, location.Datacenter)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to find datacenter %q", location.Datacenter)
+	}
+	finder.SetDatacenter(datacenter)
+
+	var refs []types.ManagedObjectReference
+	folder, err := finder.FolderOrDefault(ctx, location.Folder)
+	switch {
+	case err == nil:
+		refs = append(refs, folder.Reference())
+	case !isNotFound(err):
+		return nil, errors.Wrapf(err, "failed to find folder %q", location.Folder)
+	}
+	pool, err := finder.ResourcePoolOrDefault(ctx, location.ResourcePool)
+	switch {
+	case err == nil:
+		refs = append(refs, pool.Reference())
+	case !isNotFound(err):
+		return nil, errors.Wrapf(err, "failed to find resource pool %q", location.ResourcePool)
+	}
+	return refs, nil
+}
+
+func isNotFound(err error) bool {
+	var notFoundErr *find.NotFoundError
+	var defaultNotFoundErr *find.DefaultNotFoundError
+	return errors.As(err, &notFoundErr) || errors.As(err, &defaultNotFoundErr)
+}
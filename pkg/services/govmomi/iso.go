@@ -0,0 +1,222 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	isoSectorSize = 2048
+
+	// isoSystemAreaSectors is the number of sectors preceding the volume
+	// descriptors.
+	isoSystemAreaSectors = 16
+)
+
+// isoFile is a file in the root directory of an ISO image.
+type isoFile struct {
+	name string
+	data []byte
+}
+
+// newISO returns an ISO 9660 image with the given files in its root
+// directory. The image has a Joliet supplementary volume descriptor so the
+// names of the files are preserved, e.g. the user-data and meta-data files
+// of a NoCloud datasource.
+//
+// The layout of the image is:
+//
+//	16: primary volume descriptor
+//	17: Joliet supplementary volume descriptor
+//	18: volume descriptor set terminator
+//	19-22: little and big endian path tables of both descriptors
+//	23: root directory of the primary descriptor
+//	24: root directory of the Joliet descriptor
+//	25-: file data shared by both directories
+func newISO(volumeID string, files []isoFile) []byte {
+	files = append([]isoFile(nil), files...)
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+
+	const (
+		primaryPathTableSector = 19
+		jolietPathTableSector  = 21
+		primaryRootSector      = 23
+		jolietRootSector       = 24
+		firstFileSector        = 25
+	)
+
+	extents := make([]uint32, len(files))
+	sector := uint32(firstFileSector)
+	for i, file := range files {
+		extents[i] = sector
+		sector += sectors(len(file.data))
+	}
+	totalSectors := sector
+
+	primaryRoot := isoDirectory(primaryRootSector, extents, files, isoPrimaryName)
+	jolietRoot := isoDirectory(jolietRootSector, extents, files, isoJolietName)
+
+	image := make([]byte, int(totalSectors)*isoSectorSize)
+	writeSector := func(n uint32, data []byte) {
+		copy(image[int(n)*isoSectorSize:], data)
+	}
+	writeSector(isoSystemAreaSectors, isoVolumeDescriptor(false, volumeID, totalSectors, primaryPathTableSector, primaryRootSector))
+	writeSector(isoSystemAreaSectors+1, isoVolumeDescriptor(true, volumeID, totalSectors, jolietPathTableSector, jolietRootSector))
+	writeSector(isoSystemAreaSectors+2, append([]byte{255}, "CD001\x01"...))
+	writeSector(primaryPathTableSector, isoPathTable(binary.LittleEndian, primaryRootSector))
+	writeSector(primaryPathTableSector+1, isoPathTable(binary.BigEndian, primaryRootSector))
+	writeSector(jolietPathTableSector, isoPathTable(binary.LittleEndian, jolietRootSector))
+	writeSector(jolietPathTableSector+1, isoPathTable(binary.BigEndian, jolietRootSector))
+	writeSector(primaryRootSector, primaryRoot)
+	writeSector(jolietRootSector, jolietRoot)
+	for i, file := range files {
+		writeSector(extents[i], file.data)
+	}
+	return image
+}
+
+func sectors(size int) uint32 {
+	return uint32((size + isoSectorSize - 1) / isoSectorSize)
+}
+
+// isoPrimaryName returns the identifier of a file in the primary volume
+// descriptor, restricted to upper case letters, digits and underscores.
+func isoPrimaryName(name string) []byte {
+	mapped := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		default:
+			return '_'
+		}
+	}, name)
+	return []byte(mapped + ".;1")
+}
+
+// isoJolietName returns the identifier of a file in the Joliet volume
+// descriptor, encoded as UCS-2.
+func isoJolietName(name string) []byte {
+	return ucs2(name + ";1")
+}
+
+func ucs2(s string) []byte {
+	var buf bytes.Buffer
+	for _, c := range utf16.Encode([]rune(s)) {
+		_ = binary.Write(&buf, binary.BigEndian, c)
+	}
+	return buf.Bytes()
+}
+
+// isoVolumeDescriptor returns the primary volume descriptor, or the Joliet
+// supplementary volume descriptor.
+func isoVolumeDescriptor(joliet bool, volumeID string, totalSectors, pathTableSector, rootSector uint32) []byte {
+	d := make([]byte, isoSectorSize)
+	d[0] = 1
+	copy(d[1:], "CD001\x01")
+	text := func(offset, length int, s string) {
+		if joliet {
+			field := bytes.Repeat([]byte{0, ' '}, length/2)
+			copy(field, ucs2(s))
+			copy(d[offset:offset+length], field)
+			return
+		}
+		copy(d[offset:offset+length], []byte(s+strings.Repeat(" ", length-len(s))))
+	}
+	text(8, 32, "")
+	text(40, 32, volumeID)
+	putBothEndian32(d[80:], totalSectors)
+	if joliet {
+		d[0] = 2
+		// The escape sequence of UCS-2 level 3.
+		copy(d[88:], "%/E")
+	}
+	putBothEndian16(d[120:], 1)
+	putBothEndian16(d[124:], 1)
+	putBothEndian16(d[128:], isoSectorSize)
+	putBothEndian32(d[132:], uint32(len(isoPathTable(binary.LittleEndian, rootSector))))
+	binary.LittleEndian.PutUint32(d[140:], pathTableSector)
+	binary.BigEndian.PutUint32(d[148:], pathTableSector+1)
+	copy(d[156:], isoDirectoryRecord(rootSector, isoSectorSize, true, []byte{0}))
+	text(190, 128, "")
+	text(318, 128, "")
+	text(446, 128, "")
+	text(574, 128, "")
+	text(702, 37, "")
+	text(739, 37, "")
+	text(776, 37, "")
+	for _, offset := range []int{813, 830, 847, 864} {
+		copy(d[offset:], "0000000000000000")
+	}
+	d[881] = 1
+	return d
+}
+
+// isoPathTable returns the path table of a volume holding only a root
+// directory.
+func isoPathTable(order binary.ByteOrder, rootSector uint32) []byte {
+	t := make([]byte, 10)
+	t[0] = 1
+	order.PutUint32(t[2:], rootSector)
+	order.PutUint16(t[6:], 1)
+	return t
+}
+
+// isoDirectory returns the root directory holding the files.
+func isoDirectory(sector uint32, extents []uint32, files []isoFile, name func(string) []byte) []byte {
+	var dir bytes.Buffer
+	dir.Write(isoDirectoryRecord(sector, isoSectorSize, true, []byte{0}))
+	dir.Write(isoDirectoryRecord(sector, isoSectorSize, true, []byte{1}))
+	for i, file := range files {
+		dir.Write(isoDirectoryRecord(extents[i], uint32(len(file.data)), false, name(file.name)))
+	}
+	return dir.Bytes()
+}
+
+func isoDirectoryRecord(extent, size uint32, isDir bool, identifier []byte) []byte {
+	length := 33 + len(identifier)
+	if length%2 != 0 {
+		length++
+	}
+	r := make([]byte, length)
+	r[0] = byte(length)
+	putBothEndian32(r[2:], extent)
+	putBothEndian32(r[10:], size)
+	if isDir {
+		r[25] = 2
+	}
+	putBothEndian16(r[28:], 1)
+	r[32] = byte(len(identifier))
+	copy(r[33:], identifier)
+	return r
+}
+
+func putBothEndian16(b []byte, v uint16) {
+	binary.LittleEndian.PutUint16(b, v)
+	binary.BigEndian.PutUint16(b[2:], v)
+}
+
+func putBothEndian32(b []byte, v uint32) {
+	binary.LittleEndian.PutUint32(b, v)
+	binary.BigEndian.PutUint32(b[4:], v)
+}
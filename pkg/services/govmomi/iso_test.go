@@ -0,0 +1,68 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestNewISO(t *testing.T) {
+	g := NewWithT(t)
+
+	userData := []byte("#cloud-config\n" + strings.Repeat("#", 3*isoSectorSize))
+	image := newISO("CIDATA", []isoFile{
+		{name: "user-data", data: userData},
+		{name: "meta-data", data: []byte("instance-id: vm\n")},
+	})
+	g.Expect(len(image) % isoSectorSize).To(BeZero())
+
+	sector := func(n uint32) []byte {
+		return image[int(n)*isoSectorSize : int(n+1)*isoSectorSize]
+	}
+	primary, joliet := sector(16), sector(17)
+	g.Expect(string(primary[1:6])).To(Equal("CD001"))
+	g.Expect(primary[0]).To(BeEquivalentTo(1))
+	g.Expect(strings.TrimSpace(string(primary[40:72]))).To(Equal("CIDATA"))
+	g.Expect(binary.LittleEndian.Uint32(primary[80:])).To(BeEquivalentTo(len(image) / isoSectorSize))
+	g.Expect(joliet[0]).To(BeEquivalentTo(2))
+	g.Expect(string(joliet[88:91])).To(Equal("%/E"))
+	g.Expect(sector(18)[0]).To(BeEquivalentTo(255))
+
+	// Read the files back from the Joliet root directory.
+	files := map[string][]byte{}
+	root := sector(binary.LittleEndian.Uint32(joliet[156+2:]))
+	for offset := 0; offset < len(root) && root[offset] != 0; offset += int(root[offset]) {
+		record := root[offset : offset+int(root[offset])]
+		identifier := record[33 : 33+int(record[32])]
+		if record[25]&2 != 0 {
+			continue
+		}
+		var name []rune
+		for i := 0; i < len(identifier); i += 2 {
+			name = append(name, rune(binary.BigEndian.Uint16(identifier[i:])))
+		}
+		extent, size := binary.LittleEndian.Uint32(record[2:]), binary.LittleEndian.Uint32(record[10:])
+		files[string(name)] = image[int(extent)*isoSectorSize : int(extent)*isoSectorSize+int(size)]
+	}
+	g.Expect(files).To(HaveLen(2))
+	g.Expect(files).To(HaveKeyWithValue("meta-data;1", []byte("instance-id: vm\n")))
+	g.Expect(files).To(HaveKeyWithValue("user-data;1", userData))
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+)
+
+var (
+	outstandingTasksGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "capv",
+		Subsystem: "vcenter",
+		Name:      "outstanding_tasks",
+		Help:      "Number of vCenter tasks the VSphereVMs are waiting on.",
+	}, []string{"server"})
+
+	// outstandingTasks holds the servers of the VSphereVMs with an
+	// outstanding task, keyed by the namespaced name of the VSphereVM.
+	outstandingTasks   = map[string]string{}
+	outstandingTasksMu sync.Mutex
+)
+
+func init() {
+	metrics.Registry.MustRegister(outstandingTasksGauge)
+}
+
+// recordOutstandingTask updates the outstanding task count with the task
+// the VSphereVM is waiting on, if any.
+func recordOutstandingTask(ctx *context.VMContext) {
+	key := ctx.VSphereVM.Namespace + "/" + ctx.VSphereVM.Name
+	server := ctx.VSphereVM.Spec.Server
+
+	outstandingTasksMu.Lock()
+	defer outstandingTasksMu.Unlock()
+
+	if prevServer, ok := outstandingTasks[key]; ok {
+		delete(outstandingTasks, key)
+		outstandingTasksGauge.WithLabelValues(prevServer).Dec()
+	}
+	if ctx.VSphereVM.Status.TaskRef != "" {
+		outstandingTasks[key] = server
+		outstandingTasksGauge.WithLabelValues(server).Inc()
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// maxMigrations is the number of migrations kept in the status of a
+// VSphereVM.
+const maxMigrations = 10
+
+// reconcileMigration records the host, the compute cluster, the resource pool
+// and the datastore the VM runs on in the status of the VSphereVM, along with
+// a migration and an event every time the VM moves to another host or
+// datastore.
+func reconcileMigration(ctx *virtualMachineContext) error {
+	var vm mo.VirtualMachine
+	if err := ctx.Obj.Properties(ctx, ctx.Ref, []string{"runtime.host", "resourcePool", "config.files.vmPathName"}, &vm); err != nil {
+		return errors.Wrapf(err, "unable to get host and datastore of vm %s", ctx)
+	}
+
+	if vm.Runtime.Host != nil {
+		var host mo.HostSystem
+		if err := ctx.Obj.Properties(ctx, *vm.Runtime.Host, []string{"name", "parent"}, &host); err != nil {
+			return errors.Wrapf(err, "unable to get name of host %s of vm %s", vm.Runtime.Host.Value, ctx)
+		}
+		recordMigration(ctx, infrav1.HostMigration, ctx.VSphereVM.Status.Host, host.Name)
+		ctx.VSphereVM.Status.Host = host.Name
+
+		// The parent of standalone hosts is a ComputeResource rather than a
+		// cluster.
+		computeCluster := ""
+		if host.Parent != nil && host.Parent.Type == "ClusterComputeResource" {
+			name, err := object.NewClusterComputeResource(ctx.Session.Client.Client, *host.Parent).ObjectName(ctx)
+			if err != nil {
+				return errors.Wrapf(err, "unable to get name of compute cluster %s of vm %s", host.Parent.Value, ctx)
+			}
+			computeCluster = name
+		}
+		ctx.VSphereVM.Status.ComputeCluster = computeCluster
+	}
+
+	if vm.ResourcePool != nil {
+		pool, err := object.NewResourcePool(ctx.Session.Client.Client, *vm.ResourcePool).ObjectName(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "unable to get name of resource pool %s of vm %s", vm.ResourcePool.Value, ctx)
+		}
+		ctx.VSphereVM.Status.CurrentResourcePool = pool
+	}
+
+	if vm.Config != nil {
+		var path object.DatastorePath
+		if path.FromString(vm.Config.Files.VmPathName) {
+			recordMigration(ctx, infrav1.StorageMigration, ctx.VSphereVM.Status.Datastore, path.Datastore)
+			ctx.VSphereVM.Status.Datastore = path.Datastore
+		}
+	}
+
+	return nil
+}
+
+// recordMigration records a migration of the VM from the source to the target
+// host or datastore, unless the VM has not moved or its previous location is
+// unknown.
+func recordMigration(ctx *virtualMachineContext, migrationType infrav1.MigrationType, source, target string) {
+	if source == "" || source == target {
+		return
+	}
+
+	ctx.Logger.Info("vm migrated", "type", migrationType, "source", source, "target", target)
+	if migrationType == infrav1.HostMigration {
+		ctx.Recorder.Eventf(ctx.VSphereVM, "Migrated", "vm migrated from host %s to %s", source, target)
+	} else {
+		ctx.Recorder.Eventf(ctx.VSphereVM, "Migrated", "vm files migrated from datastore %s to %s", source, target)
+	}
+
+	migrations := append(ctx.VSphereVM.Status.Migrations, infrav1.VirtualMachineMigration{
+		Type:   migrationType,
+		Source: source,
+		Target: target,
+		Time:   metav1.Now(),
+	})
+	if len(migrations) > maxMigrations {
+		migrations = migrations[len(migrations)-maxMigrations:]
+	}
+	ctx.VSphereVM.Status.Migrations = migrations
+}
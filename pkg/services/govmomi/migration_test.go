@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/simulator"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestReconcileMigration(t *testing.T) {
+	g := NewWithT(t)
+	model := simulator.VPX()
+	model.Host = 2
+	simr, err := vcsim.NewBuilder().WithModel(model).Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	simVM := simulator.Map.Get(vmCtx.Ref).(*simulator.VirtualMachine) //nolint:forcetypeassert
+	host := simulator.Map.Get(*simVM.Runtime.Host).(*simulator.HostSystem) //nolint:forcetypeassert
+
+	// The first reconcile records the location of the VM.
+	g.Expect(reconcileMigration(vmCtx)).To(Succeed())
+	g.Expect(vmCtx.VSphereVM.Status.Host).To(Equal(host.Name))
+	g.Expect(vmCtx.VSphereVM.Status.Datastore).To(Equal("LocalDS_0"))
+	// The VM is either on a host of the cluster or on a standalone host.
+	if host.Parent.Type == "ClusterComputeResource" {
+		g.Expect(vmCtx.VSphereVM.Status.ComputeCluster).To(Equal("DC0_C0"))
+	} else {
+		g.Expect(vmCtx.VSphereVM.Status.ComputeCluster).To(BeEmpty())
+	}
+	g.Expect(vmCtx.VSphereVM.Status.CurrentResourcePool).To(Equal("Resources"))
+	g.Expect(vmCtx.VSphereVM.Status.Migrations).To(BeEmpty())
+
+	// vMotion the VM to another host.
+	var target *simulator.HostSystem
+	for _, obj := range simulator.Map.All("HostSystem") {
+		if obj.Reference() != host.Reference() {
+			target = simulator.Map.Get(obj.Reference()).(*simulator.HostSystem) //nolint:forcetypeassert
+			break
+		}
+	}
+	g.Expect(target).NotTo(BeNil())
+	targetRef := target.Reference()
+	simVM.Runtime.Host = &targetRef
+
+	g.Expect(reconcileMigration(vmCtx)).To(Succeed())
+	g.Expect(vmCtx.VSphereVM.Status.Host).To(Equal(target.Name))
+	g.Expect(vmCtx.VSphereVM.Status.Migrations).To(HaveLen(1))
+	g.Expect(vmCtx.VSphereVM.Status.Migrations[0].Type).To(Equal(infrav1.HostMigration))
+	g.Expect(vmCtx.VSphereVM.Status.Migrations[0].Source).To(Equal(host.Name))
+	g.Expect(vmCtx.VSphereVM.Status.Migrations[0].Target).To(Equal(target.Name))
+
+	// Storage vMotion the VM to another datastore.
+	simVM.Config.Files.VmPathName = fmt.Sprintf("[other-ds] %s/%s.vmx", simVM.Name, simVM.Name)
+
+	g.Expect(reconcileMigration(vmCtx)).To(Succeed())
+	g.Expect(vmCtx.VSphereVM.Status.Datastore).To(Equal("other-ds"))
+	g.Expect(vmCtx.VSphereVM.Status.Migrations).To(HaveLen(2))
+	g.Expect(vmCtx.VSphereVM.Status.Migrations[1].Type).To(Equal(infrav1.StorageMigration))
+	g.Expect(vmCtx.VSphereVM.Status.Migrations[1].Source).To(Equal("LocalDS_0"))
+
+	// Reconciling an unmoved VM records nothing.
+	g.Expect(reconcileMigration(vmCtx)).To(Succeed())
+	g.Expect(vmCtx.VSphereVM.Status.Migrations).To(HaveLen(2))
+
+	// Only the most recent migrations are kept.
+	for i := 0; i < maxMigrations; i++ {
+		recordMigration(vmCtx, infrav1.HostMigration, fmt.Sprintf("esx-%d", i), fmt.Sprintf("esx-%d", i+1))
+	}
+	g.Expect(vmCtx.VSphereVM.Status.Migrations).To(HaveLen(maxMigrations))
+	g.Expect(vmCtx.VSphereVM.Status.Migrations[0].Source).To(Equal("esx-0"))
+}
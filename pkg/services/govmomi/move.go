@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"github.com/vmware/govmomi/vim25/types"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+)
+
+// RecordForMove records the state of the VSphereVM which clusterctl move does
+// not carry over, i.e. its in-flight vCenter task and its UID, reports with
+// the VMSafeToMoveAnnotation whether the task is in flight and blocks the
+// move until it completes. It is called while the cluster is not
+// paused, so that the VSphereVM is not changed once clusterctl pauses it.
+func RecordForMove(ctx *context.VMContext) {
+	vsphereVM := ctx.VSphereVM
+	if vsphereVM.Annotations == nil {
+		vsphereVM.Annotations = map[string]string{}
+	}
+	if vsphereVM.Status.TaskRef == "" {
+		delete(vsphereVM.Annotations, infrav1.VMTaskRefAnnotation)
+		delete(vsphereVM.Annotations, infrav1.BlockMoveAnnotation)
+		vsphereVM.Annotations[infrav1.VMSafeToMoveAnnotation] = "true"
+		return
+	}
+
+	// The VM is found by the UID of the VSphereVM until its BIOS UUID is
+	// known, and the move assigns the VSphereVM a new UID.
+	if vsphereVM.Spec.BiosUUID == "" && vsphereVM.Spec.InstanceUUID == "" {
+		vsphereVM.Spec.InstanceUUID = string(vsphereVM.UID)
+	}
+
+	vsphereVM.Annotations[infrav1.VMTaskRefAnnotation] = vsphereVM.Status.TaskRef
+	vsphereVM.Annotations[infrav1.BlockMoveAnnotation] = ""
+	vsphereVM.Annotations[infrav1.VMSafeToMoveAnnotation] = "false"
+}
+
+// PrepareMove removes the BlockMoveAnnotation of the VSphereVM of a paused
+// cluster and reports it safe to move once the vCenter task of its VM
+// completes, which is the only change made to a paused VSphereVM. It returns whether the move is still blocked.
+func PrepareMove(ctx *context.VMContext) bool {
+	if _, ok := ctx.VSphereVM.Annotations[infrav1.BlockMoveAnnotation]; !ok {
+		return false
+	}
+	if task, _ := getTask(ctx); task != nil {
+		if state := task.Info.State; state == types.TaskInfoStateQueued || state == types.TaskInfoStateRunning {
+			return true
+		}
+	}
+	delete(ctx.VSphereVM.Annotations, infrav1.BlockMoveAnnotation)
+	ctx.VSphereVM.Annotations[infrav1.VMSafeToMoveAnnotation] = "true"
+	return false
+}
+
+// ResumeAfterMove tracks the vCenter task recorded by RecordForMove again
+// once the cluster is unpaused, as the VSphereVM may have been moved without
+// its status.
+func ResumeAfterMove(ctx *context.VMContext) {
+	vsphereVM := ctx.VSphereVM
+	taskRef, ok := vsphereVM.Annotations[infrav1.VMTaskRefAnnotation]
+	if ok && vsphereVM.Status.TaskRef == "" {
+		ctx.Logger.Info("tracking vCenter task recorded before the cluster was paused", "task-ref", taskRef)
+		vsphereVM.Status.TaskRef = taskRef
+	}
+}
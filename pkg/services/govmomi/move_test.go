@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestMove(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	task, err := vmCtx.Obj.PowerOff(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(task.Wait(vmCtx)).To(Succeed())
+	simTask := simulator.Map.Get(task.Reference()).(*simulator.Task) //nolint:forcetypeassert
+	simTask.Info.State = types.TaskInfoStateRunning
+
+	// The task is recorded and blocks the move while the cluster is not
+	// paused.
+	vmCtx.VSphereVM.Status.TaskRef = task.Reference().Value
+	RecordForMove(&vmCtx.VMContext)
+	g.Expect(vmCtx.VSphereVM.Spec.InstanceUUID).To(Equal(string(vmCtx.VSphereVM.UID)))
+	g.Expect(vmCtx.VSphereVM.Annotations).To(HaveKeyWithValue(infrav1.VMTaskRefAnnotation, task.Reference().Value))
+	g.Expect(vmCtx.VSphereVM.Annotations).To(HaveKey(infrav1.BlockMoveAnnotation))
+	g.Expect(vmCtx.VSphereVM.Annotations).To(HaveKeyWithValue(infrav1.VMSafeToMoveAnnotation, "false"))
+
+	// The paused VSphereVM is unchanged while its task is in flight.
+	paused := vmCtx.VSphereVM.DeepCopy()
+	g.Expect(PrepareMove(&vmCtx.VMContext)).To(BeTrue())
+	g.Expect(vmCtx.VSphereVM).To(Equal(paused))
+
+	simTask.Info.State = types.TaskInfoStateSuccess
+	g.Expect(PrepareMove(&vmCtx.VMContext)).To(BeFalse())
+	g.Expect(vmCtx.VSphereVM.Annotations).NotTo(HaveKey(infrav1.BlockMoveAnnotation))
+	g.Expect(vmCtx.VSphereVM.Annotations).To(HaveKeyWithValue(infrav1.VMSafeToMoveAnnotation, "true"))
+	g.Expect(vmCtx.VSphereVM.Annotations).To(HaveKeyWithValue(infrav1.VMTaskRefAnnotation, task.Reference().Value))
+
+	// The target of the move tracks the task again without the status.
+	moved := vmCtx.VSphereVM.DeepCopy()
+	moved.UID = "moved"
+	moved.Status = infrav1.VSphereVMStatus{}
+	vmCtx.VSphereVM = moved
+	ResumeAfterMove(&vmCtx.VMContext)
+	g.Expect(moved.Status.TaskRef).To(Equal(task.Reference().Value))
+	g.Expect(moved.Spec.InstanceUUID).NotTo(Equal("moved"))
+
+	moved.Status.TaskRef = ""
+	RecordForMove(&vmCtx.VMContext)
+	g.Expect(moved.Annotations).NotTo(HaveKey(infrav1.VMTaskRefAnnotation))
+	g.Expect(moved.Annotations).NotTo(HaveKey(infrav1.BlockMoveAnnotation))
+	g.Expect(moved.Annotations).To(HaveKeyWithValue(infrav1.VMSafeToMoveAnnotation, "true"))
+}
@@ -47,6 +47,8 @@ type NetworkStatus struct {
 }
 
 // GetNetworkStatus returns the network information for the specified VM.
+// The connection state and network name of each device are read from its
+// virtual hardware and superseded by the values reported by vm-tools, if any.
 func GetNetworkStatus(
 	ctx context.Context,
 	client *vim25.Client,
@@ -58,6 +60,7 @@ func GetNetworkStatus(
 		props = []string{
 			"config.hardware.device",
 			"guest.net",
+			"network",
 		}
 	)
 
@@ -68,19 +71,40 @@ func GetNetworkStatus(
 		return nil, errors.New("config.hardware.device is nil")
 	}
 
+	networkNames, err := GetNetworkNames(ctx, pc, obj.Network)
+	if err != nil {
+		return nil, err
+	}
+	return NetworkStatusOf(&obj, networkNames)
+}
+
+// NetworkStatusOf returns the network information of the VM from its
+// config.hardware.device, guest.net and network properties, given the names
+// of its networks indexed by the value of their managed object reference.
+func NetworkStatusOf(obj *mo.VirtualMachine, networkNames map[string]string) ([]NetworkStatus, error) {
+	if obj.Config == nil {
+		return nil, errors.New("config.hardware.device is nil")
+	}
+
 	var allNetStatus []NetworkStatus
 
 	for _, device := range obj.Config.Hardware.Device {
 		if dev, ok := device.(types.BaseVirtualEthernetCard); ok {
 			nic := dev.GetVirtualEthernetCard()
 			netStatus := NetworkStatus{
-				MACAddr: nic.MacAddress,
+				MACAddr:     nic.MacAddress,
+				NetworkName: getBackingNetworkName(nic.Backing, networkNames),
+			}
+			if nic.Connectable != nil {
+				netStatus.Connected = nic.Connectable.Connected
 			}
 			if obj.Guest != nil {
 				for _, i := range obj.Guest.Net {
 					if strings.EqualFold(nic.MacAddress, i.MacAddress) {
 						netStatus.IPAddrs = i.IpAddress
-						netStatus.NetworkName = i.Network
+						if i.Network != "" {
+							netStatus.NetworkName = i.Network
+						}
 						netStatus.Connected = i.Connected
 					}
 				}
@@ -92,6 +116,72 @@ func GetNetworkStatus(
 	return allNetStatus, nil
 }
 
+// GuestHostNames returns the host name of the guest of the VM and its fully
+// qualified domain names from the guest.hostName and guest.ipStack
+// properties reported by vm-tools.
+func GuestHostNames(obj *mo.VirtualMachine) (string, []string) {
+	if obj.Guest == nil || obj.Guest.HostName == "" {
+		return "", nil
+	}
+
+	var dnsNames []string
+	addDNSName := func(name string) {
+		for _, n := range dnsNames {
+			if strings.EqualFold(n, name) {
+				return
+			}
+		}
+		dnsNames = append(dnsNames, name)
+	}
+
+	hostname := obj.Guest.HostName
+	if i := strings.Index(hostname, "."); i > 0 {
+		addDNSName(strings.TrimSuffix(hostname, "."))
+		hostname = hostname[:i]
+	}
+	for _, stack := range obj.Guest.IpStack {
+		if stack.DnsConfig != nil && stack.DnsConfig.DomainName != "" {
+			addDNSName(hostname + "." + strings.TrimSuffix(stack.DnsConfig.DomainName, "."))
+		}
+	}
+	return hostname, dnsNames
+}
+
+// GetNetworkNames returns the names of the given networks indexed by the
+// value of their managed object reference.
+func GetNetworkNames(ctx context.Context, pc *property.Collector, refs []types.ManagedObjectReference) (map[string]string, error) {
+	names := map[string]string{}
+	if len(refs) == 0 {
+		return names, nil
+	}
+	var networks []mo.Network
+	if err := pc.Retrieve(ctx, refs, []string{"name"}, &networks); err != nil {
+		return nil, errors.Wrapf(err, "unable to fetch network names for %v", refs)
+	}
+	for _, network := range networks {
+		names[network.Reference().Value] = network.Name
+	}
+	return names, nil
+}
+
+// getBackingNetworkName returns the name of the network a device is backed by.
+// The key of a distributed port group is the value of its managed object
+// reference.
+func getBackingNetworkName(backing types.BaseVirtualDeviceBackingInfo, networkNames map[string]string) string {
+	switch b := backing.(type) {
+	case *types.VirtualEthernetCardNetworkBackingInfo:
+		if b.Network != nil {
+			if name, ok := networkNames[b.Network.Value]; ok {
+				return name
+			}
+		}
+		return b.DeviceName
+	case *types.VirtualEthernetCardDistributedVirtualPortBackingInfo:
+		return networkNames[b.Port.PortgroupKey]
+	}
+	return ""
+}
+
 // ErrOnLocalOnlyIPAddr returns an error if the provided IP address is
 // accessible only on the VM's guest OS.
 func ErrOnLocalOnlyIPAddr(addr string) error {
@@ -17,8 +17,15 @@ limitations under the License.
 package net_test
 
 import (
+	"context"
+	"reflect"
 	"testing"
 
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/net"
 )
 
@@ -78,3 +85,92 @@ func TestErrOnLocalOnlyIPAddr(t *testing.T) {
 		})
 	}
 }
+
+func TestGetNetworkStatus(t *testing.T) {
+	ctx := context.Background()
+	model := simulator.VPX()
+	if err := model.Create(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(model.Remove)
+	server := model.Service.NewServer()
+	t.Cleanup(server.Close)
+
+	client, err := govmomi.NewClient(ctx, server.URL, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without guest information the status is read from the virtual hardware.
+	vm := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine) //nolint:forcetypeassert
+	vm.Guest.Net = nil
+
+	allNetStatus, err := net.GetNetworkStatus(ctx, client.Client, vm.Reference())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(allNetStatus) == 0 {
+		t.Fatal("Expected the network status of at least one device")
+	}
+	for _, netStatus := range allNetStatus {
+		if netStatus.MACAddr == "" {
+			t.Errorf("Expected a MAC address, got: %+v", netStatus)
+		}
+		if netStatus.NetworkName == "" {
+			t.Errorf("Expected a network name, got: %+v", netStatus)
+		}
+	}
+}
+
+func TestGuestHostNames(t *testing.T) {
+	testCases := []struct {
+		name             string
+		guest            *types.GuestInfo
+		expectedHostname string
+		expectedDNSNames []string
+	}{
+		{
+			name: "no-guest",
+		},
+		{
+			name: "hostname",
+			guest: &types.GuestInfo{
+				HostName: "vm-0",
+			},
+			expectedHostname: "vm-0",
+		},
+		{
+			name: "fqdn",
+			guest: &types.GuestInfo{
+				HostName: "vm-0.example.com",
+			},
+			expectedHostname: "vm-0",
+			expectedDNSNames: []string{"vm-0.example.com"},
+		},
+		{
+			name: "domain-names",
+			guest: &types.GuestInfo{
+				HostName: "vm-0.example.com",
+				IpStack: []types.GuestStackInfo{
+					{DnsConfig: &types.NetDnsConfigInfo{DomainName: "example.com"}},
+					{DnsConfig: &types.NetDnsConfigInfo{DomainName: "corp.example.com."}},
+					{},
+				},
+			},
+			expectedHostname: "vm-0",
+			expectedDNSNames: []string{"vm-0.example.com", "vm-0.corp.example.com"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hostname, dnsNames := net.GuestHostNames(&mo.VirtualMachine{Guest: tc.guest})
+			if hostname != tc.expectedHostname {
+				t.Errorf("Expected hostname %q, got: %q", tc.expectedHostname, hostname)
+			}
+			if !reflect.DeepEqual(dnsNames, tc.expectedDNSNames) {
+				t.Errorf("Expected DNS names %v, got: %v", tc.expectedDNSNames, dnsNames)
+			}
+		})
+	}
+}
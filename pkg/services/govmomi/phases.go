@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+)
+
+// markPhaseCompleted marks the provisioning phase of the condition as
+// completed, and emits an Event the first time it is.
+func markPhaseCompleted(ctx *context.VMContext, t clusterv1.ConditionType, messageFormat string, messageArgs ...interface{}) {
+	if conditions.IsTrue(ctx.VSphereVM, t) {
+		return
+	}
+	conditions.MarkTrue(ctx.VSphereVM, t)
+	ctx.Recorder.Eventf(ctx.VSphereVM, string(t), messageFormat, messageArgs...)
+}
+
+// markPhasePending marks the provisioning phase of the condition as in
+// progress for the reason.
+func markPhasePending(ctx *context.VMContext, t clusterv1.ConditionType, reason, messageFormat string, messageArgs ...interface{}) {
+	conditions.MarkFalse(ctx.VSphereVM, t, reason, clusterv1.ConditionSeverityInfo, messageFormat, messageArgs...)
+}
+
+// markPhaseFailed marks the provisioning phase of the condition as failed
+// for the reason, and emits a warning Event whenever the failure changes.
+func markPhaseFailed(ctx *context.VMContext, t clusterv1.ConditionType, reason string, severity clusterv1.ConditionSeverity, messageFormat string, messageArgs ...interface{}) {
+	message := fmt.Sprintf(messageFormat, messageArgs...)
+	if conditions.GetReason(ctx.VSphereVM, t) == reason && conditions.GetMessage(ctx.VSphereVM, t) == message {
+		return
+	}
+	conditions.MarkFalse(ctx.VSphereVM, t, reason, severity, "%s", message)
+	ctx.Recorder.Warn(ctx.VSphereVM, reason, message)
+}
+
+// isWaitingForCloneSource returns whether the clone of the VM waits for its
+// source to be ready, i.e. for the snapshot of a linked clone to be created,
+// for the parent VM of an instant clone to be frozen or for the disks of the
+// template to be copied to a standalone ESXi host.
+func isWaitingForCloneSource(ctx *context.VMContext) bool {
+	switch conditions.GetReason(ctx.VSphereVM, infrav1.CloneStartedCondition) {
+	case infrav1.CreatingLinkedCloneSnapshotReason, infrav1.WaitingForInstantCloneParentReason, infrav1.CopyingTemplateDisksReason:
+		return true
+	default:
+		return false
+	}
+}
+
+// reconcileBootstrapData delivers the bootstrap data and the metadata which
+// are not part of the clone spec to the VM, and reports it with the
+// BootstrapDataDelivered condition.
+func (vms *VMService) reconcileBootstrapData(ctx *virtualMachineContext) (bool, error) {
+	for _, reconcile := range []func(*virtualMachineContext) (bool, error){
+		vms.reconcileMetadata,
+		vms.reconcileIgnition,
+		vms.reconcileTalosConfig,
+		vms.reconcileAdoptedVMUserData,
+		vms.reconcileBootstrapDataTransport,
+	} {
+		ok, err := reconcile(ctx)
+		// The metadata is updated whenever the IP addresses of the VM change,
+		// which is not reported once the bootstrap data is delivered.
+		if conditions.IsTrue(ctx.VSphereVM, infrav1.BootstrapDataDeliveredCondition) {
+			if err != nil || !ok {
+				return ok, err
+			}
+			continue
+		}
+		if err != nil {
+			markPhaseFailed(&ctx.VMContext, infrav1.BootstrapDataDeliveredCondition, infrav1.BootstrapDataDeliveryFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+			return false, err
+		}
+		if !ok {
+			markPhasePending(&ctx.VMContext, infrav1.BootstrapDataDeliveredCondition, infrav1.DeliveringBootstrapDataReason, "")
+			return false, nil
+		}
+	}
+	markPhaseCompleted(&ctx.VMContext, infrav1.BootstrapDataDeliveredCondition, "Delivered the bootstrap data to VM %s", ctx.Ref.Value)
+	return true, nil
+}
+
+// reconcileCustomization reports the status of the guest customization of
+// the VMs cloned with a customization spec with the CustomizationApplied
+// condition. It is not reported by vCenters older than 7.0 U2.
+func (vms *VMService) reconcileCustomization(ctx *virtualMachineContext) error {
+	if ctx.VSphereVM.Spec.Template == "" || ctx.VSphereVM.Spec.OS != infrav1.Windows ||
+		conditions.IsTrue(ctx.VSphereVM, infrav1.CustomizationAppliedCondition) ||
+		!ctx.Session.Supports(session.CustomizationStatusCapability) {
+		return nil
+	}
+
+	var obj mo.VirtualMachine
+	if err := ctx.Obj.Properties(ctx, ctx.Ref, []string{"guest"}, &obj); err != nil {
+		return errors.Wrapf(err, "unable to get customization status of vm %s", ctx)
+	}
+	if obj.Guest == nil || obj.Guest.CustomizationInfo == nil {
+		return nil
+	}
+
+	info := obj.Guest.CustomizationInfo
+	switch info.CustomizationStatus {
+	case string(types.GuestInfoCustomizationStatusTOOLSDEPLOYPKG_SUCCEEDED):
+		markPhaseCompleted(&ctx.VMContext, infrav1.CustomizationAppliedCondition, "Applied the guest customization of VM %s", ctx.Ref.Value)
+	case string(types.GuestInfoCustomizationStatusTOOLSDEPLOYPKG_FAILED):
+		markPhaseFailed(&ctx.VMContext, infrav1.CustomizationAppliedCondition, infrav1.CustomizationFailedReason, clusterv1.ConditionSeverityError,
+			"guest customization failed: %s", info.ErrorMsg)
+	default:
+		markPhasePending(&ctx.VMContext, infrav1.CustomizationAppliedCondition, infrav1.CustomizationPendingReason, "")
+	}
+	return nil
+}
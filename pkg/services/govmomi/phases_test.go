@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientrecord "k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestMarkPhase(t *testing.T) {
+	g := NewWithT(t)
+	vmCtx := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+	events := clientrecord.NewFakeRecorder(10)
+	vmCtx.Recorder = record.New(events)
+
+	markPhasePending(vmCtx, infrav1.PoweredOnCondition, infrav1.PoweringOnReason, "")
+	g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.PoweredOnCondition)).To(Equal(infrav1.PoweringOnReason))
+	g.Expect(events.Events).To(BeEmpty())
+
+	// A failure is only emitted once.
+	markPhaseFailed(vmCtx, infrav1.PoweredOnCondition, infrav1.PoweringOnFailedReason, clusterv1.ConditionSeverityWarning, "no host")
+	markPhaseFailed(vmCtx, infrav1.PoweredOnCondition, infrav1.PoweringOnFailedReason, clusterv1.ConditionSeverityWarning, "no host")
+	g.Expect(*conditions.GetSeverity(vmCtx.VSphereVM, infrav1.PoweredOnCondition)).To(Equal(clusterv1.ConditionSeverityWarning))
+	g.Expect(events.Events).To(HaveLen(1))
+	g.Expect(<-events.Events).To(Equal("Warning PoweringOnFailed no host"))
+
+	// So is the completion of the phase.
+	markPhaseCompleted(vmCtx, infrav1.PoweredOnCondition, "Powered on VM %s", "vm-42")
+	markPhaseCompleted(vmCtx, infrav1.PoweredOnCondition, "Powered on VM %s", "vm-42")
+	g.Expect(conditions.IsTrue(vmCtx.VSphereVM, infrav1.PoweredOnCondition)).To(BeTrue())
+	g.Expect(events.Events).To(HaveLen(1))
+	g.Expect(<-events.Events).To(Equal("Normal PoweredOn Powered on VM vm-42"))
+}
+
+func TestReconcilePoweredOnPhase(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vms := &VMService{}
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	ok, err := vms.reconcilePowerState(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(conditions.IsTrue(vmCtx.VSphereVM, infrav1.PoweredOnCondition)).To(BeTrue())
+}
+
+func TestReconcileBootstrapData(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vms := &VMService{}
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: vmCtx.VSphereVM.Namespace,
+			Name:      "bootstrap-data",
+		},
+		Data: map[string][]byte{
+			"value": []byte("#cloud-config"),
+		},
+	}
+	g.Expect(vmCtx.Client.Create(vmCtx, secret)).To(Succeed())
+	vmCtx.VSphereVM.Spec.BootstrapRef = &corev1.ObjectReference{
+		Namespace: secret.Namespace,
+		Name:      secret.Name,
+	}
+
+	// The metadata and the user data of the adopted VM are set one after
+	// the other.
+	ok, err := vms.reconcileBootstrapData(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeFalse())
+	g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.BootstrapDataDeliveredCondition)).To(Equal(infrav1.DeliveringBootstrapDataReason))
+
+	g.Eventually(func() bool {
+		vmCtx.VSphereVM.Status.TaskRef = ""
+		ok, err := vms.reconcileBootstrapData(vmCtx)
+		g.Expect(err).NotTo(HaveOccurred())
+		return ok
+	}).Should(BeTrue())
+	g.Expect(conditions.IsTrue(vmCtx.VSphereVM, infrav1.BootstrapDataDeliveredCondition)).To(BeTrue())
+
+	// A missing bootstrap data secret fails the delivery.
+	vmCtx.VSphereVM.Spec.BootstrapRef.Name = "missing"
+	conditions.Delete(vmCtx.VSphereVM, infrav1.BootstrapDataDeliveredCondition)
+	_, err = vms.reconcileBootstrapData(vmCtx)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.BootstrapDataDeliveredCondition)).To(Equal(infrav1.BootstrapDataDeliveryFailedReason))
+}
+
+func TestReconcileCustomization(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vms := &VMService{}
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	simVM := simulator.Map.Get(vmCtx.Ref).(*simulator.VirtualMachine) //nolint:forcetypeassert
+
+	// Linux VMs are not customized.
+	simVM.Guest.CustomizationInfo = &types.GuestInfoCustomizationInfo{CustomizationStatus: string(types.GuestInfoCustomizationStatusTOOLSDEPLOYPKG_RUNNING)}
+	vmCtx.VSphereVM.Spec.Template = "windows-template"
+	g.Expect(vms.reconcileCustomization(vmCtx)).To(Succeed())
+	g.Expect(conditions.Has(vmCtx.VSphereVM, infrav1.CustomizationAppliedCondition)).To(BeFalse())
+
+	vmCtx.VSphereVM.Spec.OS = infrav1.Windows
+	// Neither are they on vCenters older than 7.0 U2, which do not report
+	// the customization status.
+	g.Expect(vms.reconcileCustomization(vmCtx)).To(Succeed())
+	g.Expect(conditions.Has(vmCtx.VSphereVM, infrav1.CustomizationAppliedCondition)).To(BeFalse())
+
+	vmCtx.Session.Client.ServiceContent.About.Version = "7.0.3"
+	g.Expect(vms.reconcileCustomization(vmCtx)).To(Succeed())
+	g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.CustomizationAppliedCondition)).To(Equal(infrav1.CustomizationPendingReason))
+
+	simVM.Guest.CustomizationInfo = &types.GuestInfoCustomizationInfo{
+		CustomizationStatus: string(types.GuestInfoCustomizationStatusTOOLSDEPLOYPKG_FAILED),
+		ErrorMsg:            "sysprep failed",
+	}
+	g.Expect(vms.reconcileCustomization(vmCtx)).To(Succeed())
+	g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.CustomizationAppliedCondition)).To(Equal(infrav1.CustomizationFailedReason))
+	g.Expect(conditions.GetMessage(vmCtx.VSphereVM, infrav1.CustomizationAppliedCondition)).To(ContainSubstring("sysprep failed"))
+
+	simVM.Guest.CustomizationInfo = &types.GuestInfoCustomizationInfo{CustomizationStatus: string(types.GuestInfoCustomizationStatusTOOLSDEPLOYPKG_SUCCEEDED)}
+	g.Expect(vms.reconcileCustomization(vmCtx)).To(Succeed())
+	g.Expect(conditions.IsTrue(vmCtx.VSphereVM, infrav1.CustomizationAppliedCondition)).To(BeTrue())
+}
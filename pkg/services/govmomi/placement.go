@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"github.com/pkg/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+)
+
+// reconcilePlacement chooses the resource pool the VM is cloned into when the
+// VSphereVM has a placement. The VMs of a cluster are spread round-robin
+// across the resource pools of the placement by choosing the resource pool
+// with the fewest VMs of the cluster, the first one in the list on a tie.
+// The choice is recorded in the status and kept once made.
+func reconcilePlacement(ctx *context.VMContext) error {
+	placement := ctx.VSphereVM.Spec.Placement
+	if placement == nil || len(placement.ResourcePools) == 0 || ctx.VSphereVM.Status.ResourcePool != "" {
+		return nil
+	}
+
+	vsphereVMList := &infrav1.VSphereVMList{}
+	opts := []client.ListOption{client.InNamespace(ctx.VSphereVM.Namespace)}
+	if clusterName := ctx.VSphereVM.Labels[clusterv1.ClusterLabelName]; clusterName != "" {
+		opts = append(opts, client.MatchingLabels{clusterv1.ClusterLabelName: clusterName})
+	}
+	if err := ctx.Client.List(ctx, vsphereVMList, opts...); err != nil {
+		return errors.Wrapf(err, "unable to list VSphereVMs to place %s", ctx)
+	}
+
+	usage := map[string]int{}
+	for i := range vsphereVMList.Items {
+		vsphereVM := &vsphereVMList.Items[i]
+		if vsphereVM.UID == ctx.VSphereVM.UID || !vsphereVM.DeletionTimestamp.IsZero() ||
+			vsphereVM.Spec.Server != ctx.VSphereVM.Spec.Server {
+			continue
+		}
+		usage[vsphereVM.Status.ResourcePool]++
+	}
+
+	pool := placement.ResourcePools[0]
+	for _, candidate := range placement.ResourcePools[1:] {
+		if usage[candidate] < usage[pool] {
+			pool = candidate
+		}
+	}
+	ctx.Logger.Info("placing VM", "resourcePool", pool)
+	ctx.VSphereVM.Status.ResourcePool = pool
+	return nil
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+)
+
+func TestReconcilePlacement(t *testing.T) {
+	placedVM := func(name, cluster, pool string) *infrav1.VSphereVM {
+		return &infrav1.VSphereVM{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: fake.Namespace,
+				Name:      name,
+				UID:       apitypes.UID(name),
+				Labels:    map[string]string{clusterv1.ClusterLabelName: cluster},
+			},
+			Spec: infrav1.VSphereVMSpec{
+				VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{Server: "10.10.10.10"},
+			},
+			Status: infrav1.VSphereVMStatus{ResourcePool: pool},
+		}
+	}
+	placement := &infrav1.VirtualMachinePlacement{
+		ResourcePools: []string{"pool-a", "pool-b", "pool-c"},
+	}
+
+	tests := []struct {
+		name         string
+		placement    *infrav1.VirtualMachinePlacement
+		resourcePool string
+		vms          []*infrav1.VSphereVM
+		expected     string
+	}{
+		{
+			name:     "no placement",
+			expected: "",
+		},
+		{
+			name:      "first resource pool when none is used",
+			placement: placement,
+			expected:  "pool-a",
+		},
+		{
+			name:      "least used resource pool",
+			placement: placement,
+			vms: []*infrav1.VSphereVM{
+				placedVM("vm-1", "test-cluster", "pool-a"),
+				placedVM("vm-2", "test-cluster", "pool-b"),
+			},
+			expected: "pool-c",
+		},
+		{
+			name:      "first least used resource pool on a tie",
+			placement: placement,
+			vms: []*infrav1.VSphereVM{
+				placedVM("vm-1", "test-cluster", "pool-a"),
+				placedVM("vm-2", "test-cluster", "pool-a"),
+				placedVM("vm-3", "test-cluster", "pool-c"),
+			},
+			expected: "pool-b",
+		},
+		{
+			name:      "VMs of other clusters are ignored",
+			placement: placement,
+			vms: []*infrav1.VSphereVM{
+				placedVM("vm-1", "other-cluster", "pool-a"),
+			},
+			expected: "pool-a",
+		},
+		{
+			name:         "chosen resource pool is kept",
+			placement:    placement,
+			resourcePool: "pool-c",
+			expected:     "pool-c",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			vmContext := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+			vmContext.VSphereVM.Labels = map[string]string{clusterv1.ClusterLabelName: "test-cluster"}
+			vmContext.VSphereVM.Spec.Placement = tt.placement
+			vmContext.VSphereVM.Status.ResourcePool = tt.resourcePool
+			for _, vm := range tt.vms {
+				g.Expect(vmContext.Client.Create(vmContext, vm)).To(Succeed())
+				g.Expect(vmContext.Client.Status().Update(vmContext, vm)).To(Succeed())
+			}
+
+			g.Expect(reconcilePlacement(vmContext)).To(Succeed())
+			g.Expect(vmContext.VSphereVM.Status.ResourcePool).To(Equal(tt.expected))
+		})
+	}
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/template"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+)
+
+// preflightTemplate checks the template of the VM before it is cloned, and
+// returns the reasons why the VM cloned from it would fail to provision,
+// e.g. a node which never joins the cluster. The bootstrap format is the one
+// of the bootstrap data of the VM, if any.
+func preflightTemplate(ctx *context.VMContext, format bootstrapv1.Format) ([]string, error) {
+	tpl, err := template.FindTemplate(ctx, ctx.VSphereVM.Spec.Template)
+	if err != nil {
+		switch errors.Cause(err).(type) {
+		case *find.NotFoundError:
+			return []string{fmt.Sprintf("template %q not found", ctx.VSphereVM.Spec.Template)}, nil
+		case *find.MultipleFoundError:
+			return []string{fmt.Sprintf("template %q matches several VMs, use its inventory path or instance UUID", ctx.VSphereVM.Spec.Template)}, nil
+		}
+		return nil, err
+	}
+
+	var obj mo.VirtualMachine
+	if err := tpl.Properties(ctx, tpl.Reference(), []string{"config.guestId", "config.extraConfig", "config.hardware.device", "guest.toolsVersionStatus2"}, &obj); err != nil {
+		return nil, errors.Wrapf(err, "unable to get properties of template %q", ctx.VSphereVM.Spec.Template)
+	}
+	if obj.Config == nil {
+		return nil, errors.Errorf("unable to get config of template %q", ctx.VSphereVM.Spec.Template)
+	}
+	return checkTemplate(ctx.VSphereVM, obj.Config, obj.Guest, format), nil
+}
+
+// unsupportedCapabilities returns the capabilities required by the VM which
+// the vCenter of its session does not provide.
+func unsupportedCapabilities(ctx *context.VMContext) []string {
+	var required []session.Capability
+	if ctx.VSphereVM.Spec.SecureBoot {
+		required = append(required, session.SecureBootCapability)
+	}
+	if ctx.VSphereVM.Spec.VTPM {
+		required = append(required, session.VTPMCapability)
+	}
+	switch ctx.VSphereVM.Spec.CloneMode {
+	case infrav1.InstantClone:
+		required = append(required, session.InstantCloneCapability)
+	case infrav1.LinkedClone:
+		required = append(required, session.LinkedCloneCapability)
+	}
+	if ctx.VSphereVM.Spec.OS == infrav1.Windows {
+		required = append(required, session.GuestCustomizationCapability)
+	}
+	if usesStoragePolicies(&ctx.VSphereVM.Spec.VirtualMachineCloneSpec) {
+		required = append(required, session.StoragePolicyCapability)
+	}
+	if usesTags(ctx.VSphereVM) {
+		required = append(required, session.TaggingCapability)
+	}
+
+	var unsupported []string
+	for _, capability := range required {
+		if !ctx.Session.Supports(capability) {
+			unsupported = append(unsupported, capability.String())
+		}
+	}
+	return unsupported
+}
+
+// usesStoragePolicies returns whether the VM or one of its data disks is
+// placed according to a storage policy.
+func usesStoragePolicies(spec *infrav1.VirtualMachineCloneSpec) bool {
+	if spec.StoragePolicyName != "" {
+		return true
+	}
+	for _, disk := range spec.Disks {
+		if disk.StoragePolicyName != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// usesTags returns whether tags are attached to the VM, either directly or
+// by the propagation of its metadata.
+func usesTags(vsphereVM *infrav1.VSphereVM) bool {
+	if len(vsphereVM.Spec.TagIDs) > 0 {
+		return true
+	}
+	for _, mapping := range vsphereVM.Spec.MetadataPropagation {
+		if mapping.TagCategory != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTemplate returns the reasons why the template does not fit the
+// VSphereVM.
+func checkTemplate(vsphereVM *infrav1.VSphereVM, config *types.VirtualMachineConfigInfo, guest *types.GuestInfo, format bootstrapv1.Format) []string {
+	var failures []string
+
+	// The guestinfo datasource and the IP addresses of the VM are provided
+	// by VMware Tools.
+	if guest != nil && guest.ToolsVersionStatus2 == string(types.VirtualMachineToolsVersionStatusGuestToolsNotInstalled) {
+		failures = append(failures, "VMware Tools are not installed in the template, install open-vm-tools in the image")
+	}
+
+	windows := strings.HasPrefix(config.GuestId, "win")
+	switch {
+	case vsphereVM.Spec.OS == infrav1.Windows && !windows:
+		failures = append(failures, fmt.Sprintf("guest OS %q of the template is not Windows, as requested by the os of the VSphereVM", config.GuestId))
+	case vsphereVM.Spec.OS != infrav1.Windows && windows:
+		failures = append(failures, fmt.Sprintf("guest OS %q of the template is Windows, set the os of the VSphereVM to Windows", config.GuestId))
+	}
+
+	if format != "" {
+		for _, option := range config.ExtraConfig {
+			value := option.GetOptionValue()
+			if value.Key != infrav1.TemplateBootstrapFormatsKey {
+				continue
+			}
+			formats, _ := value.Value.(string)
+			if !containsFormat(formats, string(format)) {
+				failures = append(failures, fmt.Sprintf("template only supports the %q bootstrap formats, not %q", formats, format))
+			}
+		}
+	}
+
+	devices := object.VirtualDeviceList(config.Hardware.Device)
+	disks := devices.SelectByType((*types.VirtualDisk)(nil))
+	if len(disks) == 0 {
+		failures = append(failures, "template has no disk")
+	} else if capacityKB := disks[0].(*types.VirtualDisk).CapacityInKB; (vsphereVM.Spec.CloneMode == infrav1.FullClone || vsphereVM.Spec.CloneMode == infrav1.InstantClone) && vsphereVM.Spec.DiskGiB > 0 && int64(vsphereVM.Spec.DiskGiB)*1024*1024 < capacityKB { //nolint:forcetypeassert
+		templateGiB := (capacityKB + 1024*1024 - 1) / (1024 * 1024)
+		failures = append(failures, fmt.Sprintf("diskGiB %d is smaller than the %d GiB disk of the template, which cannot be shrunk", vsphereVM.Spec.DiskGiB, templateGiB))
+	}
+	if len(vsphereVM.Spec.Disks) > 0 {
+		if _, err := devices.FindDiskController("scsi"); err != nil {
+			failures = append(failures, "template has no SCSI controller to attach the data disks to")
+		}
+	}
+	return failures
+}
+
+func containsFormat(formats, format string) bool {
+	for _, f := range strings.Split(formats, ",") {
+		if strings.TrimSpace(f) == format {
+			return true
+		}
+	}
+	return false
+}
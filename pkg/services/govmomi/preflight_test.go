@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestPreflightTemplate(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	simVM := simulator.Map.Get(vmCtx.Ref).(*simulator.VirtualMachine) //nolint:forcetypeassert
+
+	vmCtx.VSphereVM.Spec.Template = "missing"
+	failures, err := preflightTemplate(&vmCtx.VMContext, bootstrapv1.CloudConfig)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(failures).To(ConsistOf(`template "missing" not found`))
+
+	vmCtx.VSphereVM.Spec.Template = simVM.Name
+	failures, err = preflightTemplate(&vmCtx.VMContext, bootstrapv1.CloudConfig)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(failures).To(BeEmpty())
+
+	simVM.Guest.ToolsVersionStatus2 = string(types.VirtualMachineToolsVersionStatusGuestToolsNotInstalled)
+	failures, err = preflightTemplate(&vmCtx.VMContext, bootstrapv1.CloudConfig)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(failures).To(ConsistOf(ContainSubstring("VMware Tools are not installed")))
+}
+
+func TestUnsupportedCapabilities(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	// vcsim reports vCenter 6.5.0.
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	vmCtx.VSphereVM.Spec.SecureBoot = true
+	g.Expect(unsupportedCapabilities(&vmCtx.VMContext)).To(BeEmpty())
+
+	vmCtx.VSphereVM.Spec.VTPM = true
+	vmCtx.VSphereVM.Spec.CloneMode = infrav1.InstantClone
+	g.Expect(unsupportedCapabilities(&vmCtx.VMContext)).To(ConsistOf("vTPM (vCenter 6.7.0 or later)", "instant clone (vCenter 6.7.0 or later)"))
+
+	vmCtx.Session.Client.ServiceContent.About.Version = "7.0.3"
+	g.Expect(unsupportedCapabilities(&vmCtx.VMContext)).To(BeEmpty())
+
+	// Standalone ESXi hosts provide none of the capabilities of vCenter.
+	vmCtx.Session.Client.ServiceContent.About.ApiType = "HostAgent"
+	vmCtx.VSphereVM.Spec.CloneMode = infrav1.LinkedClone
+	vmCtx.VSphereVM.Spec.OS = infrav1.Windows
+	vmCtx.VSphereVM.Spec.StoragePolicyName = "vSAN Default Storage Policy"
+	vmCtx.VSphereVM.Spec.MetadataPropagation = []infrav1.MetadataPropagationSpec{{Label: "team", TagCategory: "team"}}
+	g.Expect(unsupportedCapabilities(&vmCtx.VMContext)).To(ConsistOf("vTPM (vCenter 6.7.0 or later)", "linked clone (vCenter only)",
+		"guest customization (vCenter only)", "storage policies (vCenter only)", "tags (vCenter only)"))
+	vmCtx.Session.Client.ServiceContent.About.ApiType = "VirtualCenter"
+}
+
+func TestCheckTemplate(t *testing.T) {
+	disk := &types.VirtualDisk{CapacityInKB: 20 * 1024 * 1024}
+	scsi := &types.ParaVirtualSCSIController{}
+	marker := func(formats string) []types.BaseOptionValue {
+		return []types.BaseOptionValue{&types.OptionValue{Key: infrav1.TemplateBootstrapFormatsKey, Value: formats}}
+	}
+
+	tests := []struct {
+		name     string
+		spec     infrav1.VSphereVMSpec
+		config   types.VirtualMachineConfigInfo
+		guest    *types.GuestInfo
+		format   bootstrapv1.Format
+		expected []string
+	}{
+		{
+			name:   "compatible template",
+			config: types.VirtualMachineConfigInfo{GuestId: "ubuntu64Guest", ExtraConfig: marker("cloud-config, ignition"), Hardware: types.VirtualHardware{Device: []types.BaseVirtualDevice{disk}}},
+			guest:  &types.GuestInfo{ToolsVersionStatus2: string(types.VirtualMachineToolsVersionStatusGuestToolsCurrent)},
+			format: bootstrapv1.Ignition,
+		},
+		{
+			name:     "template without VMware Tools",
+			config:   types.VirtualMachineConfigInfo{GuestId: "ubuntu64Guest", Hardware: types.VirtualHardware{Device: []types.BaseVirtualDevice{disk}}},
+			guest:    &types.GuestInfo{ToolsVersionStatus2: string(types.VirtualMachineToolsVersionStatusGuestToolsNotInstalled)},
+			expected: []string{"VMware Tools are not installed in the template, install open-vm-tools in the image"},
+		},
+		{
+			name:     "Windows template of a Linux VM",
+			config:   types.VirtualMachineConfigInfo{GuestId: "windows2019srv_64Guest", Hardware: types.VirtualHardware{Device: []types.BaseVirtualDevice{disk}}},
+			expected: []string{`guest OS "windows2019srv_64Guest" of the template is Windows, set the os of the VSphereVM to Windows`},
+		},
+		{
+			name:     "Linux template of a Windows VM",
+			spec:     infrav1.VSphereVMSpec{VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{OS: infrav1.Windows}},
+			config:   types.VirtualMachineConfigInfo{GuestId: "ubuntu64Guest", Hardware: types.VirtualHardware{Device: []types.BaseVirtualDevice{disk}}},
+			expected: []string{`guest OS "ubuntu64Guest" of the template is not Windows, as requested by the os of the VSphereVM`},
+		},
+		{
+			name:     "bootstrap format not supported by the template",
+			config:   types.VirtualMachineConfigInfo{GuestId: "ubuntu64Guest", ExtraConfig: marker("cloud-config"), Hardware: types.VirtualHardware{Device: []types.BaseVirtualDevice{disk}}},
+			format:   bootstrapv1.Ignition,
+			expected: []string{`template only supports the "cloud-config" bootstrap formats, not "ignition"`},
+		},
+		{
+			name:     "template without disk",
+			config:   types.VirtualMachineConfigInfo{GuestId: "ubuntu64Guest"},
+			expected: []string{"template has no disk"},
+		},
+		{
+			name:     "full clone with a disk smaller than the template",
+			spec:     infrav1.VSphereVMSpec{VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{CloneMode: infrav1.FullClone, DiskGiB: 10}},
+			config:   types.VirtualMachineConfigInfo{GuestId: "ubuntu64Guest", Hardware: types.VirtualHardware{Device: []types.BaseVirtualDevice{disk}}},
+			expected: []string{"diskGiB 10 is smaller than the 20 GiB disk of the template, which cannot be shrunk"},
+		},
+		{
+			name:     "data disks without SCSI controller",
+			spec:     infrav1.VSphereVMSpec{VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{Disks: []infrav1.DiskSpec{{SizeGiB: 10}}}},
+			config:   types.VirtualMachineConfigInfo{GuestId: "ubuntu64Guest", Hardware: types.VirtualHardware{Device: []types.BaseVirtualDevice{disk}}},
+			expected: []string{"template has no SCSI controller to attach the data disks to"},
+		},
+		{
+			name:   "data disks with SCSI controller",
+			spec:   infrav1.VSphereVMSpec{VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{Disks: []infrav1.DiskSpec{{SizeGiB: 10}}}},
+			config: types.VirtualMachineConfigInfo{GuestId: "ubuntu64Guest", Hardware: types.VirtualHardware{Device: []types.BaseVirtualDevice{disk, scsi}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			vsphereVM := &infrav1.VSphereVM{Spec: tt.spec}
+			g.Expect(checkTemplate(vsphereVM, &tt.config, tt.guest, tt.format)).To(Equal(tt.expected))
+		})
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vapi/tags"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+)
+
+// reconcileMetadataPropagation sets the vSphere tags and custom attributes of
+// the VM mapped to the labels and annotations of its VSphereVM, which are
+// copied from its Machine. Mappings whose label or annotation is not set
+// remove the tags of their category, or clear their custom attribute.
+func (vms *VMService) reconcileMetadataPropagation(ctx *virtualMachineContext) error {
+	mappings := ctx.VSphereVM.Spec.MetadataPropagation
+	if len(mappings) == 0 {
+		return nil
+	}
+
+	var (
+		attachedTags          []tags.Tag
+		attachedTagsRetrieved bool
+		customValues          []types.BaseCustomFieldValue
+		customValuesRetrieved bool
+	)
+	for _, mapping := range mappings {
+		value := ctx.VSphereVM.Labels[mapping.Label]
+		if mapping.Annotation != "" {
+			value = ctx.VSphereVM.Annotations[mapping.Annotation]
+		}
+
+		if mapping.TagCategory != "" {
+			if !ctx.Session.Supports(session.TaggingCapability) {
+				ctx.Logger.Info("tags are not supported, skipping metadata propagation", "category", mapping.TagCategory)
+				continue
+			}
+			if !attachedTagsRetrieved {
+				var err error
+				if attachedTags, err = ctx.Session.TagManager.GetAttachedTags(ctx, ctx.Ref); err != nil {
+					return errors.Wrapf(err, "failed to get tags of VM %s", ctx)
+				}
+				attachedTagsRetrieved = true
+			}
+			if err := propagateTag(ctx, mapping.TagCategory, value, attachedTags); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !customValuesRetrieved {
+			var obj mo.VirtualMachine
+			if err := ctx.Obj.Properties(ctx, ctx.Ref, []string{"customValue"}, &obj); err != nil {
+				return errors.Wrapf(err, "failed to get custom attributes of VM %s", ctx)
+			}
+			customValues = obj.CustomValue
+			customValuesRetrieved = true
+		}
+		if err := propagateCustomAttribute(ctx, mapping.CustomAttribute, value, customValues); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// propagateTag attaches the tag named after the value in the category to the
+// VM, and detaches the other tags of the category. The category and the tag
+// are created if they do not exist.
+func propagateTag(ctx *virtualMachineContext, categoryName, value string, attachedTags []tags.Tag) error {
+	manager := ctx.Session.TagManager
+	var categoryID string
+	if category, err := manager.GetCategory(ctx, categoryName); err == nil {
+		categoryID = category.ID
+	} else {
+		if value == "" {
+			return nil
+		}
+		ctx.Logger.Info("creating tag category for metadata propagation", "category", categoryName)
+		categoryID, err = manager.CreateCategory(ctx, &tags.Category{
+			Name:            categoryName,
+			Description:     "Cluster API Provider vSphere",
+			Cardinality:     "SINGLE",
+			AssociableTypes: []string{"VirtualMachine"},
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to create tag category %s", categoryName)
+		}
+	}
+
+	attached := false
+	for _, tag := range attachedTags {
+		if tag.CategoryID != categoryID {
+			continue
+		}
+		if tag.Name == value {
+			attached = true
+			continue
+		}
+		if err := manager.DetachTag(ctx, tag.ID, ctx.Ref); err != nil {
+			return errors.Wrapf(err, "failed to detach tag %s of category %s from VM %s", tag.Name, categoryName, ctx)
+		}
+	}
+	if attached || value == "" {
+		return nil
+	}
+
+	var tagID string
+	if tag, err := manager.GetTagForCategory(ctx, value, categoryID); err == nil {
+		tagID = tag.ID
+	} else {
+		tagID, err = manager.CreateTag(ctx, &tags.Tag{
+			Name:        value,
+			Description: "Cluster API Provider vSphere",
+			CategoryID:  categoryID,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to create tag %s of category %s", value, categoryName)
+		}
+	}
+	if err := manager.AttachTag(ctx, tagID, ctx.Ref); err != nil {
+		return errors.Wrapf(err, "failed to attach tag %s of category %s to VM %s", value, categoryName, ctx)
+	}
+	return nil
+}
+
+// propagateCustomAttribute sets the custom attribute of the VM to the value.
+// The custom attribute is created if it does not exist.
+func propagateCustomAttribute(ctx *virtualMachineContext, name, value string, customValues []types.BaseCustomFieldValue) error {
+	manager := object.NewCustomFieldsManager(ctx.Session.Client.Client)
+	key, err := manager.FindKey(ctx, name)
+	switch {
+	case errors.Is(err, object.ErrKeyNameNotFound):
+		if value == "" {
+			return nil
+		}
+		ctx.Logger.Info("creating custom attribute for metadata propagation", "customAttribute", name)
+		def, err := manager.Add(ctx, name, "VirtualMachine", nil, nil)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create custom attribute %s", name)
+		}
+		key = def.Key
+	case err != nil:
+		return errors.Wrapf(err, "failed to get custom attribute %s", name)
+	}
+
+	current := ""
+	for _, customValue := range customValues {
+		if stringValue, ok := customValue.(*types.CustomFieldStringValue); ok && stringValue.Key == key {
+			current = stringValue.Value
+		}
+	}
+	if current == value {
+		return nil
+	}
+	if err := manager.Set(ctx, ctx.Ref, key, value); err != nil {
+		return errors.Wrapf(err, "failed to set custom attribute %s of VM %s", name, ctx)
+	}
+	return nil
+}
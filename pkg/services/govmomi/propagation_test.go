@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestReconcileMetadataPropagation(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vms := &VMService{}
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	vmCtx.VSphereVM.Spec.MetadataPropagation = []infrav1.MetadataPropagationSpec{
+		{Label: "team", TagCategory: "team"},
+		{Annotation: "example.com/cost-center", CustomAttribute: "cost-center"},
+	}
+
+	teamTags := func() []string {
+		attached, err := vmCtx.Session.TagManager.GetAttachedTags(vmCtx, vmCtx.Ref)
+		g.Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, tag := range attached {
+			names = append(names, tag.Name)
+		}
+		return names
+	}
+	costCenter := func() string {
+		key, err := object.NewCustomFieldsManager(vmCtx.Session.Client.Client).FindKey(vmCtx, "cost-center")
+		g.Expect(err).NotTo(HaveOccurred())
+		var obj mo.VirtualMachine
+		g.Expect(vmCtx.Obj.Properties(vmCtx, vmCtx.Ref, []string{"customValue"}, &obj)).To(Succeed())
+		// vcsim appends the values set rather than replacing them.
+		current := ""
+		for _, value := range obj.CustomValue {
+			if value, ok := value.(*types.CustomFieldStringValue); ok && value.Key == key {
+				current = value.Value
+			}
+		}
+		return current
+	}
+
+	// Nothing is created for labels and annotations which are not set.
+	g.Expect(vms.reconcileMetadataPropagation(vmCtx)).To(Succeed())
+	_, err = vmCtx.Session.TagManager.GetCategory(vmCtx, "team")
+	g.Expect(err).To(HaveOccurred())
+
+	// The category, the tag and the custom attribute are created.
+	vmCtx.VSphereVM.Labels = map[string]string{"team": "storage"}
+	vmCtx.VSphereVM.Annotations = map[string]string{"example.com/cost-center": "1234"}
+	g.Expect(vms.reconcileMetadataPropagation(vmCtx)).To(Succeed())
+	g.Expect(teamTags()).To(ConsistOf("storage"))
+	g.Expect(costCenter()).To(Equal("1234"))
+
+	// Changes of the values are kept in sync.
+	vmCtx.VSphereVM.Labels["team"] = "network"
+	vmCtx.VSphereVM.Annotations["example.com/cost-center"] = "5678"
+	g.Expect(vms.reconcileMetadataPropagation(vmCtx)).To(Succeed())
+	g.Expect(teamTags()).To(ConsistOf("network"))
+	g.Expect(costCenter()).To(Equal("5678"))
+
+	// Removed labels and annotations remove the tag and clear the custom
+	// attribute.
+	delete(vmCtx.VSphereVM.Labels, "team")
+	delete(vmCtx.VSphereVM.Annotations, "example.com/cost-center")
+	g.Expect(vms.reconcileMetadataPropagation(vmCtx)).To(Succeed())
+	g.Expect(teamTags()).To(BeEmpty())
+	g.Expect(costCenter()).To(BeEmpty())
+}
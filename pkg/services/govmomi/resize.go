@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// reconcileResize applies changes of the number of CPUs and the memory size
+// of the spec to the VM. Powered off VMs are resized in place, while running
+// VMs are only when the resources are increased and hot-add is enabled;
+// otherwise the VMResized condition reports a rolling replacement of the
+// machine is required.
+func (vms *VMService) reconcileResize(ctx *virtualMachineContext) (bool, error) {
+	var obj mo.VirtualMachine
+	props := []string{
+		"config.hardware.numCPU",
+		"config.hardware.numCoresPerSocket",
+		"config.hardware.memoryMB",
+		"config.cpuHotAddEnabled",
+		"config.memoryHotAddEnabled",
+		"runtime.powerState",
+	}
+	if err := ctx.Obj.Properties(ctx, ctx.Ref, props, &obj); err != nil {
+		return false, errors.Wrapf(err, "unable to fetch props %v for vm %s", props, ctx)
+	}
+	if obj.Config == nil {
+		return true, nil
+	}
+	hardware := obj.Config.Hardware
+	poweredOff := obj.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOff
+
+	spec := types.VirtualMachineConfigSpec{}
+	var blocked []string
+
+	// The number of CPUs is set the same way as when the VM is cloned.
+	numCPUs := ctx.VSphereVM.Spec.NumCPUs
+	if numCPUs > 0 && numCPUs < 2 {
+		numCPUs = 2
+	}
+	if numCPUs > 0 && numCPUs != hardware.NumCPU {
+		switch {
+		case poweredOff:
+			spec.NumCPUs = numCPUs
+			spec.NumCoresPerSocket = ctx.VSphereVM.Spec.NumCoresPerSocket
+			if spec.NumCoresPerSocket == 0 {
+				spec.NumCoresPerSocket = numCPUs
+			}
+		case numCPUs < hardware.NumCPU:
+			blocked = append(blocked, fmt.Sprintf("the number of CPUs cannot be decreased from %d to %d while the VM is running", hardware.NumCPU, numCPUs))
+		case obj.Config.CpuHotAddEnabled == nil || !*obj.Config.CpuHotAddEnabled:
+			blocked = append(blocked, "CPU hot-add is not enabled")
+		case hardware.NumCoresPerSocket > 0 && numCPUs%hardware.NumCoresPerSocket != 0:
+			blocked = append(blocked, fmt.Sprintf("%d CPUs are not a multiple of the %d cores per socket", numCPUs, hardware.NumCoresPerSocket))
+		default:
+			spec.NumCPUs = numCPUs
+		}
+	}
+
+	memoryMiB := ctx.VSphereVM.Spec.MemoryMiB
+	if memoryMiB > 0 && memoryMiB != int64(hardware.MemoryMB) {
+		switch {
+		case poweredOff:
+			spec.MemoryMB = memoryMiB
+		case memoryMiB < int64(hardware.MemoryMB):
+			blocked = append(blocked, fmt.Sprintf("the memory cannot be decreased from %d MiB to %d MiB while the VM is running", hardware.MemoryMB, memoryMiB))
+		case obj.Config.MemoryHotAddEnabled == nil || !*obj.Config.MemoryHotAddEnabled:
+			blocked = append(blocked, "memory hot-add is not enabled")
+		default:
+			spec.MemoryMB = memoryMiB
+		}
+	}
+
+	if len(blocked) > 0 {
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.VMResizedCondition, infrav1.VMResizeRequiresReplacementReason, clusterv1.ConditionSeverityWarning,
+			"%s; a rolling replacement of the machine is required", strings.Join(blocked, ", "))
+	}
+
+	if spec.NumCPUs == 0 && spec.MemoryMB == 0 {
+		if len(blocked) == 0 && conditions.Has(ctx.VSphereVM, infrav1.VMResizedCondition) {
+			conditions.MarkTrue(ctx.VSphereVM, infrav1.VMResizedCondition)
+		}
+		return true, nil
+	}
+
+	task, err := ctx.Obj.Reconfigure(ctx, spec)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to resize vm %s", ctx)
+	}
+
+	ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+	ctx.Logger.Info("wait for VM to be resized", "numCPUs", spec.NumCPUs, "memoryMiB", spec.MemoryMB)
+	return false, nil
+}
+
+// reconcileDiskResize extends the OS disk of the VM when the size of the spec
+// is larger. Linked and instant clones are skipped as their disks cannot be
+// extended. Once the disk is extended, an event hints that the file system of
+// the guest is grown at the next boot.
+func (vms *VMService) reconcileDiskResize(ctx *virtualMachineContext) (bool, error) {
+	if ctx.VSphereVM.Spec.DiskGiB == 0 || ctx.VSphereVM.Status.CloneMode == infrav1.LinkedClone || ctx.VSphereVM.Status.CloneMode == infrav1.InstantClone {
+		return true, nil
+	}
+
+	devices, err := ctx.Obj.Device(ctx)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get devices for %q", ctx)
+	}
+	disks := devices.SelectByType((*types.VirtualDisk)(nil))
+	if len(disks) == 0 {
+		return true, nil
+	}
+	disk := disks[0].(*types.VirtualDisk) //nolint:forcetypeassert
+
+	desiredKB := int64(ctx.VSphereVM.Spec.DiskGiB) * 1024 * 1024
+	actualGiB := disk.CapacityInKB / (1024 * 1024)
+	switch {
+	case disk.CapacityInKB == desiredKB:
+		if conditions.GetReason(ctx.VSphereVM, infrav1.DiskResizedCondition) == infrav1.DiskResizingReason {
+			ctx.Recorder.Eventf(ctx.VSphereVM, "DiskResized",
+				"OS disk extended to %d GiB; the file system of the guest is grown by cloud-init at the next boot, or with growpart and resize2fs", ctx.VSphereVM.Spec.DiskGiB)
+		}
+		if conditions.Has(ctx.VSphereVM, infrav1.DiskResizedCondition) {
+			conditions.MarkTrue(ctx.VSphereVM, infrav1.DiskResizedCondition)
+		}
+		return true, nil
+	case disk.CapacityInKB > desiredKB:
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.DiskResizedCondition, infrav1.DiskShrinkNotSupportedReason, clusterv1.ConditionSeverityWarning,
+			"the OS disk cannot be shrunk from %d GiB to %d GiB; a rolling replacement of the machine is required", actualGiB, ctx.VSphereVM.Spec.DiskGiB)
+		return true, nil
+	}
+
+	disk.CapacityInKB = desiredKB
+	disk.CapacityInBytes = desiredKB * 1024
+	task, err := ctx.Obj.Reconfigure(ctx, types.VirtualMachineConfigSpec{
+		DeviceChange: []types.BaseVirtualDeviceConfigSpec{
+			&types.VirtualDeviceConfigSpec{
+				Operation: types.VirtualDeviceConfigSpecOperationEdit,
+				Device:    disk,
+			},
+		},
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to extend OS disk of vm %s", ctx)
+	}
+
+	conditions.MarkFalse(ctx.VSphereVM, infrav1.DiskResizedCondition, infrav1.DiskResizingReason, clusterv1.ConditionSeverityInfo,
+		"extending the OS disk from %d GiB to %d GiB", actualGiB, ctx.VSphereVM.Spec.DiskGiB)
+	ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+	ctx.Logger.Info("wait for OS disk to be extended", "diskGiB", ctx.VSphereVM.Spec.DiskGiB)
+	return false, nil
+}
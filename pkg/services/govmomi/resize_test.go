@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestReconcileResize(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vms := &VMService{}
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	simVM := simulator.Map.Get(vmCtx.Ref).(*simulator.VirtualMachine) //nolint:forcetypeassert
+	simVM.Config.Hardware.NumCPU = 2
+	simVM.Config.Hardware.NumCoresPerSocket = 1
+	simVM.Config.Hardware.MemoryMB = 2048
+
+	waitForTask := func() {
+		t.Helper()
+		task := object.NewTask(vmCtx.Session.Client.Client, types.ManagedObjectReference{Type: "Task", Value: vmCtx.VSphereVM.Status.TaskRef})
+		g.Expect(task.Wait(vmCtx)).To(Succeed())
+		vmCtx.VSphereVM.Status.TaskRef = ""
+	}
+
+	// The VM matches the spec.
+	vmCtx.VSphereVM.Spec.NumCPUs = 2
+	vmCtx.VSphereVM.Spec.MemoryMiB = 2048
+	ok, err := vms.reconcileResize(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(conditions.Has(vmCtx.VSphereVM, infrav1.VMResizedCondition)).To(BeFalse())
+
+	// A running VM without hot-add cannot be resized in place.
+	vmCtx.VSphereVM.Spec.NumCPUs = 4
+	vmCtx.VSphereVM.Spec.MemoryMiB = 4096
+	ok, err = vms.reconcileResize(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(conditions.IsFalse(vmCtx.VSphereVM, infrav1.VMResizedCondition)).To(BeTrue())
+	g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.VMResizedCondition)).To(Equal(infrav1.VMResizeRequiresReplacementReason))
+	g.Expect(conditions.GetMessage(vmCtx.VSphereVM, infrav1.VMResizedCondition)).To(ContainSubstring("CPU hot-add is not enabled"))
+	g.Expect(conditions.GetMessage(vmCtx.VSphereVM, infrav1.VMResizedCondition)).To(ContainSubstring("memory hot-add is not enabled"))
+
+	// A running VM with hot-add is resized in place.
+	simVM.Config.CpuHotAddEnabled = pointer.Bool(true)
+	simVM.Config.MemoryHotAddEnabled = pointer.Bool(true)
+	ok, err = vms.reconcileResize(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeFalse())
+	waitForTask()
+	g.Expect(simVM.Config.Hardware.NumCPU).To(Equal(int32(4)))
+	g.Expect(simVM.Config.Hardware.MemoryMB).To(Equal(int32(4096)))
+
+	ok, err = vms.reconcileResize(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(conditions.IsTrue(vmCtx.VSphereVM, infrav1.VMResizedCondition)).To(BeTrue())
+
+	// The resources of a running VM cannot be decreased.
+	vmCtx.VSphereVM.Spec.NumCPUs = 2
+	ok, err = vms.reconcileResize(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(conditions.GetMessage(vmCtx.VSphereVM, infrav1.VMResizedCondition)).To(ContainSubstring("cannot be decreased from 4 to 2"))
+
+	// A powered off VM is resized in place.
+	task, err := vmCtx.Obj.PowerOff(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(task.Wait(vmCtx)).To(Succeed())
+	ok, err = vms.reconcileResize(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeFalse())
+	waitForTask()
+	g.Expect(simVM.Config.Hardware.NumCPU).To(Equal(int32(2)))
+	g.Expect(simVM.Config.Hardware.NumCoresPerSocket).To(Equal(int32(2)))
+
+	ok, err = vms.reconcileResize(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(conditions.IsTrue(vmCtx.VSphereVM, infrav1.VMResizedCondition)).To(BeTrue())
+}
+
+func TestReconcileDiskResize(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vms := &VMService{}
+	vmCtx := newTestVirtualMachineContext(t, simr)
+
+	diskCapacityKB := func() int64 {
+		t.Helper()
+		devices, err := vmCtx.Obj.Device(vmCtx)
+		g.Expect(err).NotTo(HaveOccurred())
+		disks := devices.SelectByType((*types.VirtualDisk)(nil))
+		g.Expect(disks).NotTo(BeEmpty())
+		return disks[0].(*types.VirtualDisk).CapacityInKB //nolint:forcetypeassert
+	}
+	setDiskCapacityGiB := func(capacityGiB int64) {
+		t.Helper()
+		devices, err := vmCtx.Obj.Device(vmCtx)
+		g.Expect(err).NotTo(HaveOccurred())
+		disk := devices.SelectByType((*types.VirtualDisk)(nil))[0].(*types.VirtualDisk) //nolint:forcetypeassert
+		disk.CapacityInKB = capacityGiB * 1024 * 1024
+		disk.CapacityInBytes = disk.CapacityInKB * 1024
+		g.Expect(vmCtx.Obj.EditDevice(vmCtx, disk)).To(Succeed())
+	}
+	setDiskCapacityGiB(10)
+
+	// The disk matches the spec.
+	vmCtx.VSphereVM.Spec.DiskGiB = 10
+	ok, err := vms.reconcileDiskResize(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(conditions.Has(vmCtx.VSphereVM, infrav1.DiskResizedCondition)).To(BeFalse())
+
+	// The disk is extended.
+	vmCtx.VSphereVM.Spec.DiskGiB = 20
+	ok, err = vms.reconcileDiskResize(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeFalse())
+	g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.DiskResizedCondition)).To(Equal(infrav1.DiskResizingReason))
+	task := object.NewTask(vmCtx.Session.Client.Client, types.ManagedObjectReference{Type: "Task", Value: vmCtx.VSphereVM.Status.TaskRef})
+	g.Expect(task.Wait(vmCtx)).To(Succeed())
+	g.Expect(diskCapacityKB()).To(Equal(int64(20 * 1024 * 1024)))
+
+	ok, err = vms.reconcileDiskResize(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(conditions.IsTrue(vmCtx.VSphereVM, infrav1.DiskResizedCondition)).To(BeTrue())
+
+	// The disk cannot be shrunk.
+	vmCtx.VSphereVM.Spec.DiskGiB = 15
+	ok, err = vms.reconcileDiskResize(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.DiskResizedCondition)).To(Equal(infrav1.DiskShrinkNotSupportedReason))
+
+	// The disks of linked clones are left unchanged.
+	vmCtx.VSphereVM.Spec.DiskGiB = 30
+	vmCtx.VSphereVM.Status.CloneMode = infrav1.LinkedClone
+	ok, err = vms.reconcileDiskResize(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(diskCapacityKB()).To(Equal(int64(20 * 1024 * 1024)))
+}
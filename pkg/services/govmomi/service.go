@@ -17,28 +17,33 @@ limitations under the License.
 package govmomi
 
 import (
-	"encoding/base64"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/vmware/govmomi/object"
-	"github.com/vmware/govmomi/pbm"
 	pbmTypes "github.com/vmware/govmomi/pbm/types"
 	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vapi/tags"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 	corev1 "k8s.io/api/core/v1"
 	apitypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
 	capierrors "sigs.k8s.io/cluster-api/errors"
 	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/yaml"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/cluster"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/extra"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/net"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/vcenter"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
 )
 
@@ -50,24 +55,39 @@ type VMService struct{}
 //   2. Updating the VM with the bootstrap data, such as the cloud-init meta and user data, before...
 //   3. Powering on the VM, and finally...
 //   4. Returning the real-time state of the VM to the caller
-func (vms *VMService) ReconcileVM(ctx *context.VMContext) (vm infrav1.VirtualMachine, _ error) {
+func (vms *VMService) ReconcileVM(ctx *context.VMContext) (vm infrav1.VirtualMachine, reterr error) {
+	_, endSpan := ctx.StartSpan("VMService.ReconcileVM")
+	defer func() {
+		endSpan(reterr)
+	}()
+
 	// Initialize the result.
 	vm = infrav1.VirtualMachine{
 		Name:  ctx.VSphereVM.Name,
 		State: infrav1.VirtualMachineStatePending,
 	}
 
+	// Keep track of the task the VM is waiting on once reconciled.
+	defer recordOutstandingTask(ctx)
+
+	// The VM changed if a task completed since it was last reconciled.
+	taskCompleted := ctx.VSphereVM.Status.TaskRef != ""
+
 	// If there is an in-flight task associated with this VM then do not
 	// reconcile the VM until the task is completed.
 	if inFlight, err := reconcileInFlightTask(ctx); err != nil || inFlight {
 		return vm, err
 	}
 
+	// Any clone of the VM is complete at this point.
+	clones.release(ctx.VSphereVM.Namespace + "/" + ctx.VSphereVM.Name)
+
 	// This deferred function will trigger a reconcile event for the
 	// VSphereVM resource once its associated task completes. If
 	// there is no task for the VSphereVM resource then no reconcile
 	// event is triggered.
 	defer reconcileVSphereVMOnTaskCompletion(ctx)
+	defer reconcileVSphereVMOnTaskProgress(ctx)
 
 	// Before going further, we need the VM's managed object reference.
 	vmRef, err := findVM(ctx)
@@ -77,6 +97,13 @@ func (vms *VMService) ReconcileVM(ctx *context.VMContext) (vm infrav1.VirtualMac
 			return vm, err
 		}
 
+		// VSphereVMs without a template adopt a pre-existing VM instead of
+		// cloning one.
+		if ctx.VSphereVM.Spec.Template == "" {
+			conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.VMNotFoundReason, clusterv1.ConditionSeverityError, err.Error())
+			return vm, errors.Wrapf(err, "unable to find vm to adopt for %s", ctx)
+		}
+
 		// If the machine was not found by BIOS UUID it means that it got deleted from vcenter directly
 		if wasNotFoundByBIOSUUID(err) {
 			ctx.VSphereVM.Status.FailureReason = capierrors.MachineStatusErrorPtr(capierrors.UpdateMachineError)
@@ -92,20 +119,108 @@ func (vms *VMService) ReconcileVM(ctx *context.VMContext) (vm infrav1.VirtualMac
 		}
 
 		// Get the bootstrap data.
-		bootstrapData, err := vms.getBootstrapData(ctx)
+		bootstrapData, format, err := vms.getBootstrapData(ctx)
 		if err != nil {
 			conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.CloningFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+			markPhaseFailed(ctx, infrav1.CloneStartedCondition, infrav1.CloningFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+			return vm, err
+		}
+
+		// Check vCenter provides the features of the VM before the first
+		// clone, rather than failing the clone task.
+		if unsupported := unsupportedCapabilities(ctx); len(unsupported) > 0 {
+			vcenterVersion, vcenterBuild := ctx.Session.VCenterVersion()
+			endpoint := "vCenter"
+			if ctx.Session.IsStandaloneHost() {
+				endpoint = "ESXi host"
+			}
+			message := fmt.Sprintf("%s %s build %s does not support %s", endpoint, vcenterVersion, vcenterBuild, strings.Join(unsupported, ", "))
+			conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.UnsupportedByVCenterReason, clusterv1.ConditionSeverityError, message)
+			markPhaseFailed(ctx, infrav1.CloneStartedCondition, infrav1.UnsupportedByVCenterReason, clusterv1.ConditionSeverityError, message)
+			return vm, errors.Errorf("unable to clone %s: %s", ctx, message)
+		}
+
+		// Check the template before the first clone, so a VM which would
+		// never become a node is not created.
+		failures, err := preflightTemplate(ctx, format)
+		if err != nil {
 			return vm, err
 		}
+		if len(failures) > 0 {
+			conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.TemplatePreflightFailedReason, clusterv1.ConditionSeverityError, strings.Join(failures, "; "))
+			markPhaseFailed(ctx, infrav1.CloneStartedCondition, infrav1.TemplatePreflightFailedReason, clusterv1.ConditionSeverityError, strings.Join(failures, "; "))
+			return vm, errors.Errorf("template %q of %s failed preflight checks: %s", ctx.VSphereVM.Spec.Template, ctx, strings.Join(failures, "; "))
+		}
+
+		// Ignition and Talos configs are set once the VM is created, see
+		// reconcileIgnition and reconcileTalosConfig. So is bootstrap data not
+		// delivered through guestinfo variables, see
+		// reconcileBootstrapDataTransport.
+		if format == bootstrapv1.Ignition || format == talosFormat || getBootstrapDataTransport(ctx.VSphereVM) != infrav1.GuestInfoTransport {
+			bootstrapData = nil
+		}
+
+		// Wait for a clone slot of the template to be available.
+		vmKey := ctx.VSphereVM.Namespace + "/" + ctx.VSphereVM.Name
+		templateKey := ctx.VSphereVM.Spec.Server + "/" + ctx.VSphereVM.Spec.Template
+		if !clones.tryAcquire(templateKey, vmKey, ctx.MaxConcurrentClonesPerTemplate) {
+			ctx.Logger.Info("waiting for in-flight clones of the template to complete", "template", ctx.VSphereVM.Spec.Template)
+			conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.CloneQueuedReason, clusterv1.ConditionSeverityInfo,
+				"waiting for in-flight clones of template %s to complete", ctx.VSphereVM.Spec.Template)
+			markPhasePending(ctx, infrav1.CloneStartedCondition, infrav1.CloneQueuedReason,
+				"waiting for in-flight clones of template %s to complete", ctx.VSphereVM.Spec.Template)
+			return vm, nil
+		}
+		if reason := conditions.GetReason(ctx.VSphereVM, infrav1.VMProvisionedCondition); reason == infrav1.CloneQueuedReason || reason == infrav1.InsufficientCapacityReason {
+			conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.CloningReason, clusterv1.ConditionSeverityInfo, "")
+		}
 
-		// Create the VM.
+		// Choose the resource pool of the VM when it is spread across
+		// several ones.
+		if err := reconcilePlacement(ctx); err != nil {
+			clones.release(vmKey)
+			conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.CloningFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+			markPhaseFailed(ctx, infrav1.CloneStartedCondition, infrav1.CloningFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+			return vm, err
+		}
+
+		// Create the VM. The linked clone of a template without snapshot is
+		// started once a snapshot of the template is created by a first task,
+		// and the instant clone once its parent VM is frozen, in which case
+		// the CloneStarted condition is marked accordingly.
+		if isWaitingForCloneSource(ctx) {
+			markPhasePending(ctx, infrav1.CloneStartedCondition, infrav1.CloningReason, "")
+		}
 		err = createVM(ctx, bootstrapData)
 		if err != nil {
+			clones.release(vmKey)
 			conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.CloningFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+			markPhaseFailed(ctx, infrav1.CloneStartedCondition, infrav1.CloningFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+			return vm, err
+		}
+		// The clone is not started when the hosts or the datastores of the
+		// VM lack the free capacity for it, so that its slot is released.
+		if conditions.GetReason(ctx.VSphereVM, infrav1.CloneStartedCondition) == infrav1.InsufficientCapacityReason {
+			clones.release(vmKey)
+			return vm, nil
+		}
+		if isWaitingForCloneSource(ctx) {
+			ctx.Logger.Info("wait for source of clone to be ready", "template", ctx.VSphereVM.Spec.Template, "reason", conditions.GetReason(ctx.VSphereVM, infrav1.CloneStartedCondition))
+			return vm, nil
 		}
+		markPhaseCompleted(ctx, infrav1.CloneStartedCondition, "Started cloning template %s", ctx.VSphereVM.Spec.Template)
+		markPhasePending(ctx, infrav1.CloneCompletedCondition, infrav1.CloningReason, "")
 		return vm, nil
 	}
 
+	// The clone of the VM is complete once it is found.
+	if ctx.VSphereVM.Spec.Template != "" {
+		if !conditions.Has(ctx.VSphereVM, infrav1.CloneStartedCondition) {
+			conditions.MarkTrue(ctx.VSphereVM, infrav1.CloneStartedCondition)
+		}
+		markPhaseCompleted(ctx, infrav1.CloneCompletedCondition, "Cloned template %s to VM %s", ctx.VSphereVM.Spec.Template, vmRef.Value)
+	}
+
 	//
 	// At this point we know the VM exists, so it needs to be updated.
 	//
@@ -118,13 +233,33 @@ func (vms *VMService) ReconcileVM(ctx *context.VMContext) (vm infrav1.VirtualMac
 		State:     &vm,
 	}
 
+	// Reconcile the VSphereVM whenever its VM is powered on or off, its IP
+	// addresses change or it is migrated.
+	if err := reconcileVSphereVMOnVMChange(vmCtx); err != nil {
+		return vm, err
+	}
+
+	// Read the guest network and the power state of the VM from the states
+	// of the VMs of its cluster, retrieved at once for all of them.
+	vmCtx.vmState = vmStates.get(vmCtx, taskCompleted)
+
+	if err := reconcileMigration(vmCtx); err != nil {
+		return vm, err
+	}
+
+	// Mark the VSphereVM when the host its VM runs on is unhealthy, and
+	// reconcile it whenever the state of the host changes.
+	if err := reconcileHostHealth(vmCtx); err != nil {
+		return vm, err
+	}
+
 	vms.reconcileUUID(vmCtx)
 
 	if err := vms.reconcileNetworkStatus(vmCtx); err != nil {
 		return vm, err
 	}
 
-	if ok, err := vms.reconcileMetadata(vmCtx); err != nil || !ok {
+	if ok, err := vms.reconcileBootstrapData(vmCtx); err != nil || !ok {
 		return vm, err
 	}
 
@@ -132,41 +267,105 @@ func (vms *VMService) ReconcileVM(ctx *context.VMContext) (vm infrav1.VirtualMac
 		return vm, err
 	}
 
+	if err := vms.reconcilePCIDevices(vmCtx); err != nil {
+		return vm, err
+	}
+
+	if ok, err := vms.reconcileResourceAllocation(vmCtx); err != nil || !ok {
+		return vm, err
+	}
+
+	if ok, err := vms.reconcileResize(vmCtx); err != nil || !ok {
+		return vm, err
+	}
+
+	if ok, err := vms.reconcileDiskResize(vmCtx); err != nil || !ok {
+		return vm, err
+	}
+
+	if ok, err := vms.reconcileHardwareVersion(vmCtx); err != nil || !ok {
+		return vm, err
+	}
+
 	if ok, err := vms.reconcileVMGroupInfo(vmCtx); err != nil || !ok {
 		return vm, err
 	}
 
+	if ok, err := vms.reconcileAntiAffinityRule(vmCtx); err != nil || !ok {
+		return vm, err
+	}
+
 	if ok, err := vms.reconcilePowerState(vmCtx); err != nil || !ok {
 		return vm, err
 	}
 
+	if err := reconcileGuestTools(vmCtx); err != nil {
+		return vm, err
+	}
+
+	if err := vms.reconcileCustomization(vmCtx); err != nil {
+		return vm, err
+	}
+
+	if err := vms.reconcileRestart(vmCtx); err != nil {
+		return vm, err
+	}
+
 	if err := vms.reconcileTags(vmCtx); err != nil {
 		conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.TagsAttachmentFailedReason, clusterv1.ConditionSeverityError, err.Error())
 		return vm, err
 	}
 
+	if err := vms.reconcileMetadataPropagation(vmCtx); err != nil {
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.MetadataPropagationFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		return vm, err
+	}
+
+	if ok, err := vms.reconcileDrift(vmCtx); err != nil || !ok {
+		return vm, err
+	}
+
+	if isWaitingForGuestTools(ctx.VSphereVM) {
+		ctx.Logger.Info("wait for VMware Tools to be running", "running-status", ctx.VSphereVM.Status.GuestTools.RunningStatus)
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.WaitingForGuestToolsReason, clusterv1.ConditionSeverityInfo,
+			"waiting for VMware Tools to be running")
+		return vm, nil
+	}
+
 	vm.State = infrav1.VirtualMachineStateReady
 	return vm, nil
 }
 
 // DestroyVM powers off and destroys a virtual machine.
-func (vms *VMService) DestroyVM(ctx *context.VMContext) (infrav1.VirtualMachine, error) {
+func (vms *VMService) DestroyVM(ctx *context.VMContext) (_ infrav1.VirtualMachine, reterr error) {
+	_, endSpan := ctx.StartSpan("VMService.DestroyVM")
+	defer func() {
+		endSpan(reterr)
+	}()
+
 	vm := infrav1.VirtualMachine{
 		Name:  ctx.VSphereVM.Name,
 		State: infrav1.VirtualMachineStatePending,
 	}
 
+	// Keep track of the task the VM is waiting on once reconciled.
+	defer recordOutstandingTask(ctx)
+
 	// If there is an in-flight task associated with this VM then do not
 	// reconcile the VM until the task is completed.
 	if inFlight, err := reconcileInFlightTask(ctx); err != nil || inFlight {
 		return vm, err
 	}
 
+	// Any clone of the VM is complete at this point.
+	clones.release(ctx.VSphereVM.Namespace + "/" + ctx.VSphereVM.Name)
+
 	// This deferred function will trigger a reconcile event for the
 	// VSphereVM resource once its associated task completes. If
 	// there is no task for the VSphereVM resource then no reconcile
 	// event is triggered.
 	defer reconcileVSphereVMOnTaskCompletion(ctx)
+	defer reconcileVSphereVMOnTaskProgress(ctx)
 
 	// Before going further, we need the VM's managed object reference.
 	vmRef, err := findVM(ctx)
@@ -192,12 +391,26 @@ func (vms *VMService) DestroyVM(ctx *context.VMContext) (infrav1.VirtualMachine,
 		State:     &vm,
 	}
 
+	// The state of the VM is not collected with the VMs of its cluster
+	// anymore.
+	vmStates.forget(vmStateKey(vmCtx), vmRef)
+
+	// Reconcile the VSphereVM once its VM is powered off.
+	if err := reconcileVSphereVMOnVMChange(vmCtx); err != nil {
+		return vm, err
+	}
+
 	// Power off the VM.
 	powerState, err := vms.getPowerState(vmCtx)
 	if err != nil {
 		return vm, err
 	}
 	if powerState == infrav1.VirtualMachinePowerStatePoweredOn {
+		pending, err := vms.triggerSoftPowerOff(vmCtx)
+		if err != nil || pending {
+			return vm, err
+		}
+
 		task, err := vmCtx.Obj.PowerOff(ctx)
 		if err != nil {
 			return vm, err
@@ -211,6 +424,63 @@ func (vms *VMService) DestroyVM(ctx *context.VMContext) (infrav1.VirtualMachine,
 		return vm, nil
 	}
 
+	if conditions.GetReason(ctx.VSphereVM, infrav1.GuestSoftPowerOffSucceededCondition) == infrav1.GuestSoftPowerOffInProgressReason {
+		conditions.MarkTrue(ctx.VSphereVM, infrav1.GuestSoftPowerOffSucceededCondition)
+	}
+
+	// Remove the VM from the control plane anti-affinity rule before it is
+	// destroyed so the rule is deleted along with the last control plane VM.
+	if isAntiAffinityCandidate(ctx.VSphereVM) {
+		task, err := reconcileControlPlaneAntiAffinityRule(vmCtx)
+		if err != nil {
+			return vm, err
+		}
+		if task != nil {
+			ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+			ctx.Logger.Info("wait for VM to be removed from anti-affinity rule")
+			return vm, nil
+		}
+	}
+
+	// Back up the powered off VM before it is destroyed, if requested.
+	if ctx.VSphereVM.Spec.DeletionPolicy != infrav1.VirtualMachineDeletionPolicyRetain {
+		if done, err := vms.reconcilePreDeleteBackup(vmCtx); err != nil || !done {
+			return vm, err
+		}
+	}
+
+	// The VM is powered off and its changes are no longer of interest.
+	if err := ctx.Session.UnwatchVM(ctx, vmRef); err != nil {
+		return vm, err
+	}
+	if err := ctx.Session.UnwatchHost(ctx, vmRef.Value); err != nil {
+		return vm, err
+	}
+
+	switch ctx.VSphereVM.Spec.DeletionPolicy {
+	case infrav1.VirtualMachineDeletionPolicyRetain:
+		if err := vms.retainVM(vmCtx); err != nil {
+			return vm, err
+		}
+		// The retained VM is no longer managed by the VSphereVM.
+		vm.State = infrav1.VirtualMachineStateNotFound
+		return vm, nil
+	case infrav1.VirtualMachineDeletionPolicyRetainDisks:
+		task, err := vms.detachDataDisks(vmCtx)
+		if err != nil {
+			return vm, err
+		}
+		if task != nil {
+			ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+			ctx.Logger.Info("wait for data disks to be detached")
+			return vm, nil
+		}
+	}
+
+	if err := deleteBootstrapISO(vmCtx); err != nil {
+		return vm, err
+	}
+
 	// At this point the VM is not powered on and can be destroyed. Store the
 	// destroy task's reference and return a requeue error.
 	ctx.Logger.Info("destroying vm")
@@ -223,16 +493,122 @@ func (vms *VMService) DestroyVM(ctx *context.VMContext) (infrav1.VirtualMachine,
 	return vm, nil
 }
 
+// retainVM attaches the retained tag to the VM, creating the tag and its
+// category if they do not exist yet.
+func (vms *VMService) retainVM(ctx *virtualMachineContext) error {
+	// Standalone ESXi hosts have no tags, the retained VM is only left in
+	// place.
+	if !ctx.Session.Supports(session.TaggingCapability) {
+		ctx.Logger.Info("tags are not supported, retaining VM without the retained tag")
+		return nil
+	}
+	categories, err := ctx.Session.TagManager.GetCategories(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get tag categories")
+	}
+	var categoryID string
+	for _, category := range categories {
+		if category.Name == infrav1.RetainedVMTagCategoryName {
+			categoryID = category.ID
+			break
+		}
+	}
+	if categoryID == "" {
+		categoryID, err = ctx.Session.TagManager.CreateCategory(ctx, &tags.Category{
+			Name:            infrav1.RetainedVMTagCategoryName,
+			Description:     "Cluster API Provider vSphere",
+			Cardinality:     "MULTIPLE",
+			AssociableTypes: []string{"VirtualMachine"},
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to create tag category %s", infrav1.RetainedVMTagCategoryName)
+		}
+	}
+
+	tag, err := ctx.Session.TagManager.GetTagForCategory(ctx, infrav1.RetainedVMTagName, categoryID)
+	var tagID string
+	if err == nil {
+		tagID = tag.ID
+	} else {
+		tagID, err = ctx.Session.TagManager.CreateTag(ctx, &tags.Tag{
+			Name:        infrav1.RetainedVMTagName,
+			Description: "VM retained on deletion of its VSphereVM",
+			CategoryID:  categoryID,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to create tag %s", infrav1.RetainedVMTagName)
+		}
+	}
+
+	if err := ctx.Session.TagManager.AttachTag(ctx, tagID, ctx.Ref); err != nil {
+		return errors.Wrapf(err, "failed to attach tag %s to VM %s", infrav1.RetainedVMTagName, ctx)
+	}
+	ctx.Logger.Info("retained vm", "moref", ctx.Ref.Value)
+	ctx.Recorder.Eventf(ctx.VSphereVM, "Retained", "Retained VM %s", ctx.Ref.Value)
+	return nil
+}
+
+// detachDataDisks detaches all disks but the first one from the VM without
+// deleting their backing files. It returns a nil task if there are no data
+// disks attached to the VM.
+func (vms *VMService) detachDataDisks(ctx *virtualMachineContext) (*object.Task, error) {
+	devices, err := ctx.Obj.Device(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get devices for VM %s", ctx)
+	}
+	disks := devices.SelectByType((*types.VirtualDisk)(nil))
+	if len(disks) <= 1 {
+		return nil, nil
+	}
+
+	var (
+		changes []types.BaseVirtualDeviceConfigSpec
+		files   []string
+	)
+	for _, disk := range disks[1:] {
+		changes = append(changes, &types.VirtualDeviceConfigSpec{
+			Operation: types.VirtualDeviceConfigSpecOperationRemove,
+			Device:    disk,
+		})
+		if backing, ok := disk.GetVirtualDevice().Backing.(types.BaseVirtualDeviceFileBackingInfo); ok {
+			files = append(files, backing.GetVirtualDeviceFileBackingInfo().FileName)
+		}
+	}
+
+	task, err := ctx.Obj.Reconfigure(ctx, types.VirtualMachineConfigSpec{
+		DeviceChange: changes,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to detach data disks from VM %s", ctx)
+	}
+	ctx.Logger.Info("detaching data disks", "files", files)
+	ctx.Recorder.Eventf(ctx.VSphereVM, "RetainDisks", "Detaching data disks %s", strings.Join(files, ", "))
+	return task, nil
+}
+
 func (vms *VMService) reconcileNetworkStatus(ctx *virtualMachineContext) error {
 	netStatus, err := vms.getNetworkStatus(ctx)
 	if err != nil {
 		return err
 	}
 	ctx.State.Network = netStatus
-	return nil
+	ctx.State.Hostname, ctx.State.DNSNames, err = vms.getGuestHostNames(ctx)
+	return err
 }
 
 func (vms *VMService) reconcileMetadata(ctx *virtualMachineContext) (bool, error) {
+	// The metadata is delivered with the bootstrap data, see
+	// reconcileBootstrapDataTransport.
+	if getBootstrapDataTransport(ctx.VSphereVM) != infrav1.GuestInfoTransport {
+		return true, nil
+	}
+
+	// Talos configures the hostname and the network of the VM from its
+	// machine config, which the metadata would conflict with.
+	if _, format, err := vms.getBootstrapData(&ctx.VMContext); err != nil || format == talosFormat {
+		return err == nil, err
+	}
+
 	existingMetadata, err := vms.getMetadata(ctx)
 	if err != nil {
 		return false, err
@@ -259,20 +635,170 @@ func (vms *VMService) reconcileMetadata(ctx *virtualMachineContext) (bool, error
 	return false, nil
 }
 
+// reconcileAdoptedVMUserData sets the cloud-init user data of adopted VMs,
+// which is otherwise part of the clone spec.
+func (vms *VMService) reconcileAdoptedVMUserData(ctx *virtualMachineContext) (bool, error) {
+	if ctx.VSphereVM.Spec.Template != "" || conditions.IsTrue(ctx.VSphereVM, infrav1.VMProvisionedCondition) ||
+		getBootstrapDataTransport(ctx.VSphereVM) != infrav1.GuestInfoTransport {
+		return true, nil
+	}
+
+	bootstrapData, format, err := vms.getBootstrapData(&ctx.VMContext)
+	if err != nil {
+		return false, err
+	}
+	// Ignition and Talos configs are set by reconcileIgnition and
+	// reconcileTalosConfig.
+	if len(bootstrapData) == 0 || format == bootstrapv1.Ignition || format == talosFormat {
+		return true, nil
+	}
+
+	existingUserData, err := vms.getGuestInfo(ctx, guestInfoKeyUserdata)
+	if err != nil {
+		return false, err
+	}
+	if string(bootstrapData) == existingUserData {
+		return true, nil
+	}
+
+	ctx.Logger.Info("updating user data of adopted vm")
+	var extraConfig extra.Config
+	if err := extraConfig.SetCloudInitUserData(bootstrapData, ctx.BootstrapDataCompressionThreshold); err != nil {
+		return false, errors.Wrapf(err, "unable to set user data on vm %s", ctx)
+	}
+	task, err := ctx.Obj.Reconfigure(ctx, types.VirtualMachineConfigSpec{
+		ExtraConfig: extraConfig,
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to set user data on vm %s", ctx)
+	}
+
+	ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+	ctx.Logger.Info("wait for VM user data to be updated")
+	return false, nil
+}
+
+// reconcileIgnition sets the Ignition config of VMs bootstrapped with Ignition.
+// Unlike cloud-init user data, the Ignition config is not part of the clone
+// spec as it embeds the network configuration of the VM, which is matched by
+// MAC addresses only known once the VM is created.
+func (vms *VMService) reconcileIgnition(ctx *virtualMachineContext) (bool, error) {
+	// The Ignition config is only applied on first boot.
+	if conditions.IsTrue(ctx.VSphereVM, infrav1.VMProvisionedCondition) {
+		return true, nil
+	}
+
+	bootstrapData, format, err := vms.getBootstrapData(&ctx.VMContext)
+	if err != nil {
+		return false, err
+	}
+	if format != bootstrapv1.Ignition {
+		return true, nil
+	}
+
+	existingConfig, err := vms.getGuestInfo(ctx, guestInfoKeyIgnition)
+	if err != nil {
+		return false, err
+	}
+
+	newConfig, err := util.GetIgnitionConfig(bootstrapData, ctx.VSphereVM.Name, *ctx.VSphereVM, ctx.State.Network...)
+	if err != nil {
+		return false, err
+	}
+
+	existingMetadata, err := vms.getGuestInfo(ctx, guestInfoKeyAfterburn)
+	if err != nil {
+		return false, err
+	}
+	newMetadata := util.GetAfterburnMetadata(ctx.VSphereVM.Name, *ctx.VSphereVM, ctx.State.Network...)
+
+	// If the Ignition config and the Afterburn metadata are the same then
+	// return early.
+	if string(newConfig) == existingConfig && string(newMetadata) == existingMetadata {
+		return true, nil
+	}
+
+	ctx.Logger.Info("updating ignition config")
+	var extraConfig extra.Config
+	if err := extraConfig.SetIgnitionUserData(newConfig, ctx.BootstrapDataCompressionThreshold); err != nil {
+		return false, errors.Wrapf(err, "unable to set ignition config on vm %s", ctx)
+	}
+	if err := extraConfig.SetAfterburnMetadata(newMetadata); err != nil {
+		return false, errors.Wrapf(err, "unable to set afterburn metadata on vm %s", ctx)
+	}
+	task, err := ctx.Obj.Reconfigure(ctx, types.VirtualMachineConfigSpec{
+		ExtraConfig: extraConfig,
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to set ignition config on vm %s", ctx)
+	}
+
+	ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+	ctx.Logger.Info("wait for VM ignition config to be updated")
+	return false, nil
+}
+
+// reconcileTalosConfig sets the machine config of VMs bootstrapped with Talos.
+// The config is set as is, as Talos neither reads the metadata of the VM nor
+// accepts a config with an injected hostname or network configuration.
+func (vms *VMService) reconcileTalosConfig(ctx *virtualMachineContext) (bool, error) {
+	// The machine config is only applied on first boot.
+	if conditions.IsTrue(ctx.VSphereVM, infrav1.VMProvisionedCondition) {
+		return true, nil
+	}
+
+	bootstrapData, format, err := vms.getBootstrapData(&ctx.VMContext)
+	if err != nil {
+		return false, err
+	}
+	if format != talosFormat {
+		return true, nil
+	}
+
+	existingConfig, err := vms.getGuestInfo(ctx, guestInfoKeyTalosConfig)
+	if err != nil {
+		return false, err
+	}
+	if string(bootstrapData) == existingConfig {
+		return true, nil
+	}
+
+	ctx.Logger.Info("updating talos config")
+	var extraConfig extra.Config
+	if err := extraConfig.SetTalosConfig(bootstrapData); err != nil {
+		return false, errors.Wrapf(err, "unable to set talos config on vm %s", ctx)
+	}
+	task, err := ctx.Obj.Reconfigure(ctx, types.VirtualMachineConfigSpec{
+		ExtraConfig: extraConfig,
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to set talos config on vm %s", ctx)
+	}
+
+	ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+	ctx.Logger.Info("wait for VM talos config to be updated")
+	return false, nil
+}
+
 func (vms *VMService) reconcilePowerState(ctx *virtualMachineContext) (bool, error) {
 	powerState, err := vms.getPowerState(ctx)
 	if err != nil {
 		return false, err
 	}
 	switch powerState {
-	case infrav1.VirtualMachinePowerStatePoweredOff:
+	case infrav1.VirtualMachinePowerStatePoweredOff, infrav1.VirtualMachinePowerStateSuspended:
+		if !reconcilePowerRecovery(ctx, powerState) {
+			return false, nil
+		}
 		ctx.Logger.Info("powering on")
 		task, err := ctx.Obj.PowerOn(ctx)
 		if err != nil {
 			conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.PoweringOnFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+			markPhaseFailed(&ctx.VMContext, infrav1.PoweredOnCondition, infrav1.PoweringOnFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
 			return false, errors.Wrapf(err, "failed to trigger power on op for vm %s", ctx)
 		}
 		conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.PoweringOnReason, clusterv1.ConditionSeverityInfo, "")
+		markPhasePending(&ctx.VMContext, infrav1.PoweredOnCondition, infrav1.PoweringOnReason, "")
 
 		// Update the VSphereVM.Status.TaskRef to track the power-on task.
 		ctx.VSphereVM.Status.TaskRef = task.Reference().Value
@@ -281,20 +807,112 @@ func (vms *VMService) reconcilePowerState(ctx *virtualMachineContext) (bool, err
 			return false, err
 		}
 
-		// Once the VM is successfully powered on, a reconcile request should be
-		// triggered once the VM reports IP addresses are available.
-		reconcileVSphereVMWhenNetworkIsReady(ctx, task)
-
 		ctx.Logger.Info("wait for VM to be powered on")
 		return false, nil
 	case infrav1.VirtualMachinePowerStatePoweredOn:
 		ctx.Logger.Info("powered on")
+		markPhaseCompleted(&ctx.VMContext, infrav1.PoweredOnCondition, "Powered on VM %s", ctx.Ref.Value)
 		return true, nil
 	default:
 		return false, errors.Errorf("unexpected power state %q for vm %s", powerState, ctx)
 	}
 }
 
+// reconcilePowerRecovery reports a VM found powered off, or suspended, after
+// it was powered on, and returns whether the VM is powered back on according
+// to the PowerRecoveryPolicy of the VSphereVM.
+func reconcilePowerRecovery(ctx *virtualMachineContext, powerState infrav1.VirtualMachinePowerState) bool {
+	if !conditions.IsTrue(ctx.VSphereVM, infrav1.PoweredOnCondition) &&
+		conditions.GetReason(ctx.VSphereVM, infrav1.PoweredOnCondition) != infrav1.PoweredOffReason {
+		return true
+	}
+
+	markPhaseFailed(&ctx.VMContext, infrav1.PoweredOnCondition, infrav1.PoweredOffReason, clusterv1.ConditionSeverityWarning,
+		"VM %s was found %s outside of Cluster API", ctx.Ref.Value, powerState)
+	policy := ctx.VSphereVM.Spec.PowerRecoveryPolicy
+	if policy == infrav1.VirtualMachinePowerRecoveryPolicyReport {
+		ctx.Logger.Info("VM is not powered on, leaving it as is", "power-state", powerState, "power-recovery-policy", policy)
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.PoweredOffReason, clusterv1.ConditionSeverityWarning,
+			"VM is %s and the power recovery policy is %s", powerState, policy)
+		return false
+	}
+	return true
+}
+
+// triggerSoftPowerOff shuts down the guest OS of the VM when the VSphereVM's
+// power off mode is soft or trySoft. It returns true while the guest shutdown
+// is in progress, and false when the VM should be powered off forcibly.
+func (vms *VMService) triggerSoftPowerOff(ctx *virtualMachineContext) (bool, error) {
+	mode := ctx.VSphereVM.Spec.PowerOffMode
+	if mode != infrav1.VirtualMachinePowerOpModeSoft && mode != infrav1.VirtualMachinePowerOpModeTrySoft {
+		return false, nil
+	}
+
+	switch conditions.GetReason(ctx.VSphereVM, infrav1.GuestSoftPowerOffSucceededCondition) {
+	case infrav1.GuestSoftPowerOffInProgressReason:
+		if mode == infrav1.VirtualMachinePowerOpModeSoft {
+			ctx.Logger.Info("wait for guest OS to shut down")
+			return true, nil
+		}
+
+		timeout := infrav1.GuestSoftPowerOffDefaultTimeout
+		if ctx.VSphereVM.Spec.GuestSoftPowerOffTimeout != nil {
+			timeout = ctx.VSphereVM.Spec.GuestSoftPowerOffTimeout.Duration
+		}
+		lastTransitionTime := conditions.GetLastTransitionTime(ctx.VSphereVM, infrav1.GuestSoftPowerOffSucceededCondition)
+		if lastTransitionTime != nil && time.Now().Before(lastTransitionTime.Add(timeout)) {
+			ctx.Logger.Info("wait for guest OS to shut down", "timeout", timeout)
+			return true, nil
+		}
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.GuestSoftPowerOffSucceededCondition, infrav1.GuestSoftPowerOffFailedReason, clusterv1.ConditionSeverityWarning,
+			"guest OS did not shut down within %s", timeout)
+		return false, nil
+	case infrav1.GuestSoftPowerOffFailedReason:
+		if mode == infrav1.VirtualMachinePowerOpModeTrySoft {
+			return false, nil
+		}
+	}
+
+	var obj mo.VirtualMachine
+	if err := ctx.Obj.Properties(ctx, ctx.Ref, []string{"guest.toolsRunningStatus"}, &obj); err != nil {
+		return false, errors.Wrapf(err, "unable to get VMware Tools status for vm %s", ctx)
+	}
+	if obj.Guest == nil || obj.Guest.ToolsRunningStatus != string(types.VirtualMachineToolsRunningStatusGuestToolsRunning) {
+		err := errors.Errorf("unable to shut down guest OS of vm %s: VMware Tools are not running", ctx)
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.GuestSoftPowerOffSucceededCondition, infrav1.GuestSoftPowerOffFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		if mode == infrav1.VirtualMachinePowerOpModeTrySoft {
+			return false, nil
+		}
+		return true, err
+	}
+
+	ctx.Logger.Info("shutting down guest OS")
+	if err := ctx.Obj.ShutdownGuest(ctx); err != nil {
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.GuestSoftPowerOffSucceededCondition, infrav1.GuestSoftPowerOffFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		if mode == infrav1.VirtualMachinePowerOpModeTrySoft {
+			return false, nil
+		}
+		return true, errors.Wrapf(err, "failed to shut down guest OS of vm %s", ctx)
+	}
+	conditions.MarkFalse(ctx.VSphereVM, infrav1.GuestSoftPowerOffSucceededCondition, infrav1.GuestSoftPowerOffInProgressReason, clusterv1.ConditionSeverityInfo, "")
+	return true, nil
+}
+
+// reconcileRestart restarts the guest OS of the VM when the VSphereVM has the
+// restart annotation. The annotation is removed once the restart is issued.
+func (vms *VMService) reconcileRestart(ctx *virtualMachineContext) error {
+	if _, ok := ctx.VSphereVM.Annotations[infrav1.VMRestartAnnotation]; !ok {
+		return nil
+	}
+
+	ctx.Logger.Info("restarting guest OS")
+	if err := ctx.Obj.RebootGuest(ctx); err != nil {
+		return errors.Wrapf(err, "failed to restart guest OS of vm %s", ctx)
+	}
+	delete(ctx.VSphereVM.Annotations, infrav1.VMRestartAnnotation)
+	return nil
+}
+
 func (vms *VMService) reconcileStoragePolicy(ctx *virtualMachineContext) error {
 	if ctx.VSphereVM.Spec.StoragePolicyName == "" {
 		ctx.Logger.Info("storage policy not defined. skipping reconcile storage policy")
@@ -311,12 +929,13 @@ func (vms *VMService) reconcileStoragePolicy(ctx *virtualMachineContext) error {
 		return nil
 	}
 
-	pbmClient, err := pbm.NewClient(ctx, ctx.Session.Client.Client)
+	pbmClient, err := ctx.Session.NewPbmClient(ctx)
 	if err != nil {
 		return errors.Wrap(err, "unable to create pbm client")
 	}
 	storageProfileID, err := pbmClient.ProfileIDByName(ctx, ctx.VSphereVM.Spec.StoragePolicyName)
 	if err != nil {
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.StoragePolicyAvailableCondition, infrav1.StoragePolicyNotFoundReason, clusterv1.ConditionSeverityError, err.Error())
 		return errors.Wrap(err, "unable to retrieve storage profile ID")
 	}
 	entities, err := pbmClient.QueryAssociatedEntity(ctx, pbmTypes.PbmProfileId{UniqueId: storageProfileID}, "virtualDiskId")
@@ -324,6 +943,23 @@ func (vms *VMService) reconcileStoragePolicy(ctx *virtualMachineContext) error {
 		return err
 	}
 
+	// data disks with their own storage policy keep it
+	for _, disk := range ctx.VSphereVM.Spec.Disks {
+		if disk.StoragePolicyName == "" || disk.StoragePolicyName == ctx.VSphereVM.Spec.StoragePolicyName {
+			continue
+		}
+		diskProfileID, err := pbmClient.ProfileIDByName(ctx, disk.StoragePolicyName)
+		if err != nil {
+			conditions.MarkFalse(ctx.VSphereVM, infrav1.StoragePolicyAvailableCondition, infrav1.StoragePolicyNotFoundReason, clusterv1.ConditionSeverityError, err.Error())
+			return errors.Wrap(err, "unable to retrieve storage profile ID of data disk")
+		}
+		diskEntities, err := pbmClient.QueryAssociatedEntity(ctx, pbmTypes.PbmProfileId{UniqueId: diskProfileID}, "virtualDiskId")
+		if err != nil {
+			return err
+		}
+		entities = append(entities, diskEntities...)
+	}
+
 	var changes []types.BaseVirtualDeviceConfigSpec
 	devices, err := ctx.Obj.Device(ctx)
 	if err != nil {
@@ -371,14 +1007,107 @@ func (vms *VMService) reconcileStoragePolicy(ctx *virtualMachineContext) error {
 	return nil
 }
 
+// reconcilePCIDevices reports whether the PCI passthrough and vGPU devices
+// requested for the VM are attached to it.
+func (vms *VMService) reconcilePCIDevices(ctx *virtualMachineContext) error {
+	expected := ctx.VSphereVM.Spec.PciDevices
+	if len(expected) == 0 {
+		conditions.Delete(ctx.VSphereVM, infrav1.PCIDevicesAttachedCondition)
+		return nil
+	}
+
+	devices, err := ctx.Obj.Device(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get devices for %q", ctx)
+	}
+	attached := devices.SelectByType((*types.VirtualPCIPassthrough)(nil))
+	if missing := countMissingPCIDevices(expected, attached); missing > 0 {
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.PCIDevicesAttachedCondition, infrav1.PCIDevicesMissingReason, clusterv1.ConditionSeverityWarning,
+			"%d of %d PCI devices are not attached", missing, len(expected))
+		return nil
+	}
+	conditions.MarkTrue(ctx.VSphereVM, infrav1.PCIDevicesAttachedCondition)
+	return nil
+}
+
+// reconcileResourceAllocation restores the CPU and memory allocation of the VM
+// when it drifts from the resource allocation of the spec.
+func (vms *VMService) reconcileResourceAllocation(ctx *virtualMachineContext) (bool, error) {
+	cpu, memory := vcenter.ResourceAllocation(ctx.VSphereVM.Spec.ResourceAllocation)
+	if cpu == nil && memory == nil {
+		return true, nil
+	}
+
+	var obj mo.VirtualMachine
+	props := []string{"config.cpuAllocation", "config.memoryAllocation", "config.memoryReservationLockedToMax"}
+	if err := ctx.Obj.Properties(ctx, ctx.Ref, props, &obj); err != nil {
+		return false, errors.Wrapf(err, "unable to fetch props %v for vm %s", props, ctx)
+	}
+	if obj.Config == nil {
+		return true, nil
+	}
+
+	// The memory reservation of VMs with PCI devices is locked to their
+	// memory size.
+	if memory != nil && obj.Config.MemoryReservationLockedToMax != nil && *obj.Config.MemoryReservationLockedToMax {
+		memory.Reservation = nil
+	}
+
+	spec := types.VirtualMachineConfigSpec{}
+	if isAllocationDrifted(cpu, obj.Config.CpuAllocation) {
+		spec.CpuAllocation = cpu
+	}
+	if isAllocationDrifted(memory, obj.Config.MemoryAllocation) {
+		spec.MemoryAllocation = memory
+	}
+	if spec.CpuAllocation == nil && spec.MemoryAllocation == nil {
+		return true, nil
+	}
+
+	task, err := ctx.Obj.Reconfigure(ctx, spec)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to set resource allocation on vm %s", ctx)
+	}
+
+	ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+	ctx.Logger.Info("wait for VM resource allocation to be updated")
+	return false, nil
+}
+
+// isAllocationDrifted returns whether a value set in the desired allocation
+// differs from the actual allocation.
+func isAllocationDrifted(desired, actual *types.ResourceAllocationInfo) bool {
+	if desired == nil {
+		return false
+	}
+	if actual == nil {
+		return true
+	}
+	if desired.Reservation != nil && (actual.Reservation == nil || *desired.Reservation != *actual.Reservation) {
+		return true
+	}
+	if desired.Limit != nil && (actual.Limit == nil || *desired.Limit != *actual.Limit) {
+		return true
+	}
+	if desired.Shares != nil && (actual.Shares == nil || desired.Shares.Level != actual.Shares.Level) {
+		return true
+	}
+	return false
+}
+
 func (vms *VMService) reconcileUUID(ctx *virtualMachineContext) {
 	ctx.State.BiosUUID = ctx.Obj.UUID(ctx)
 }
 
 func (vms *VMService) getPowerState(ctx *virtualMachineContext) (infrav1.VirtualMachinePowerState, error) {
-	powerState, err := ctx.Obj.PowerState(ctx)
-	if err != nil {
-		return "", err
+	var powerState types.VirtualMachinePowerState
+	if ctx.vmState != nil {
+		powerState = ctx.vmState.Runtime.PowerState
+	} else {
+		var err error
+		if powerState, err = ctx.Obj.PowerState(ctx); err != nil {
+			return "", err
+		}
 	}
 
 	switch powerState {
@@ -394,6 +1123,11 @@ func (vms *VMService) getPowerState(ctx *virtualMachineContext) (infrav1.Virtual
 }
 
 func (vms *VMService) getMetadata(ctx *virtualMachineContext) (string, error) {
+	return vms.getGuestInfo(ctx, guestInfoKeyMetadata)
+}
+
+// getGuestInfo returns the decoded value of the guestinfo key of the VM.
+func (vms *VMService) getGuestInfo(ctx *virtualMachineContext, key string) (string, error) {
 	var (
 		obj mo.VirtualMachine
 
@@ -408,38 +1142,37 @@ func (vms *VMService) getMetadata(ctx *virtualMachineContext) (string, error) {
 		return "", nil
 	}
 
-	var metadataBase64 string
+	var valueBase64, encoding string
 	for _, ec := range obj.Config.ExtraConfig {
 		if optVal := ec.GetOptionValue(); optVal != nil {
-			// TODO(akutz) Using a switch instead of if in case we ever
-			//             want to check the metadata encoding as well.
-			//             Since the image stamped images always use
-			//             base64, it should be okay to not check.
-			//nolint:gocritic
+			v, ok := optVal.Value.(string)
+			if !ok {
+				continue
+			}
 			switch optVal.Key {
-			case guestInfoKeyMetadata:
-				if v, ok := optVal.Value.(string); ok {
-					metadataBase64 = v
-				}
+			case key:
+				valueBase64 = v
+			case key + ".encoding":
+				encoding = v
 			}
 		}
 	}
 
-	if metadataBase64 == "" {
+	if valueBase64 == "" {
 		return "", nil
 	}
 
-	metadataBuf, err := base64.StdEncoding.DecodeString(metadataBase64)
+	valueBuf, err := extra.Decode(valueBase64, encoding)
 	if err != nil {
-		return "", errors.Wrapf(err, "unable to decode metadata for %s", ctx)
+		return "", errors.Wrapf(err, "unable to decode %s for %s", key, ctx)
 	}
 
-	return string(metadataBuf), nil
+	return string(valueBuf), nil
 }
 
 func (vms *VMService) setMetadata(ctx *virtualMachineContext, metadata []byte) (string, error) {
 	var extraConfig extra.Config
-	if err := extraConfig.SetCloudInitMetadata(metadata); err != nil {
+	if err := extraConfig.SetCloudInitMetadata(metadata, ctx.BootstrapDataCompressionThreshold); err != nil {
 		return "", errors.Wrapf(err, "unable to set metadata on vm %s", ctx)
 	}
 
@@ -454,7 +1187,13 @@ func (vms *VMService) setMetadata(ctx *virtualMachineContext, metadata []byte) (
 }
 
 func (vms *VMService) getNetworkStatus(ctx *virtualMachineContext) ([]infrav1.NetworkStatus, error) {
-	allNetStatus, err := net.GetNetworkStatus(ctx, ctx.Session.Client.Client, ctx.Ref)
+	var allNetStatus []net.NetworkStatus
+	var err error
+	if ctx.vmState != nil {
+		allNetStatus, err = net.NetworkStatusOf(&ctx.vmState.VirtualMachine, ctx.vmState.networkNames)
+	} else {
+		allNetStatus, err = net.GetNetworkStatus(ctx, ctx.Session.Client.Client, ctx.Ref)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -471,10 +1210,27 @@ func (vms *VMService) getNetworkStatus(ctx *virtualMachineContext) ([]infrav1.Ne
 	return apiNetStatus, nil
 }
 
-func (vms *VMService) getBootstrapData(ctx *context.VMContext) ([]byte, error) {
+// getGuestHostNames returns the host name and the fully qualified domain
+// names of the guest of the VM reported by vm-tools.
+func (vms *VMService) getGuestHostNames(ctx *virtualMachineContext) (string, []string, error) {
+	if ctx.vmState != nil {
+		hostname, dnsNames := net.GuestHostNames(&ctx.vmState.VirtualMachine)
+		return hostname, dnsNames, nil
+	}
+
+	var obj mo.VirtualMachine
+	props := []string{"guest.hostName", "guest.ipStack"}
+	if err := ctx.Obj.Properties(ctx, ctx.Ref, props, &obj); err != nil {
+		return "", nil, errors.Wrapf(err, "unable to fetch props %v for vm %s", props, ctx)
+	}
+	hostname, dnsNames := net.GuestHostNames(&obj)
+	return hostname, dnsNames, nil
+}
+
+func (vms *VMService) getBootstrapData(ctx *context.VMContext) ([]byte, bootstrapv1.Format, error) {
 	if ctx.VSphereVM.Spec.BootstrapRef == nil {
 		ctx.Logger.Info("VM has no bootstrap data")
-		return nil, nil
+		return nil, "", nil
 	}
 
 	secret := &corev1.Secret{}
@@ -483,15 +1239,36 @@ func (vms *VMService) getBootstrapData(ctx *context.VMContext) ([]byte, error) {
 		Name:      ctx.VSphereVM.Spec.BootstrapRef.Name,
 	}
 	if err := ctx.Client.Get(ctx, secretKey, secret); err != nil {
-		return nil, errors.Wrapf(err, "failed to retrieve bootstrap data secret for %s", ctx)
+		return nil, "", errors.Wrapf(err, "failed to retrieve bootstrap data secret for %s", ctx)
 	}
 
 	value, ok := secret.Data["value"]
 	if !ok {
-		return nil, errors.New("error retrieving bootstrap data: secret value key is missing")
+		return nil, "", errors.New("error retrieving bootstrap data: secret value key is missing")
+	}
+
+	format := bootstrapv1.CloudConfig
+	if f, ok := secret.Data["format"]; ok && len(f) > 0 {
+		format = bootstrapv1.Format(f)
+	} else if isTalosConfig(value) {
+		format = talosFormat
 	}
+	return value, format, nil
+}
 
-	return value, nil
+// isTalosConfig returns whether the bootstrap data is a Talos machine config,
+// for bootstrap providers not setting the format of their data.
+func isTalosConfig(data []byte) bool {
+	var config struct {
+		Version string                 `json:"version"`
+		Machine map[string]interface{} `json:"machine"`
+	}
+	// Only the first document of multi-document configs is parsed, which is
+	// the machine config.
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return false
+	}
+	return strings.HasPrefix(config.Version, "v1alpha") && config.Machine != nil
 }
 
 func (vms *VMService) reconcileVMGroupInfo(ctx *virtualMachineContext) (bool, error) {
@@ -523,11 +1300,32 @@ func (vms *VMService) reconcileVMGroupInfo(ctx *virtualMachineContext) (bool, er
 	return true, nil
 }
 
+func (vms *VMService) reconcileAntiAffinityRule(ctx *virtualMachineContext) (bool, error) {
+	if !isAntiAffinityCandidate(ctx.VSphereVM) {
+		return true, nil
+	}
+
+	task, err := reconcileControlPlaneAntiAffinityRule(ctx)
+	if err != nil {
+		return false, err
+	}
+	if task != nil {
+		ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+		ctx.Logger.Info("wait for anti-affinity rule to be updated")
+		return false, nil
+	}
+	return true, nil
+}
+
 func (vms *VMService) reconcileTags(ctx *virtualMachineContext) error {
 	if len(ctx.VSphereVM.Spec.TagIDs) == 0 {
 		ctx.Logger.Info("no tags defined. skipping tags reconciliation")
 		return nil
 	}
+	if !ctx.Session.Supports(session.TaggingCapability) {
+		ctx.Logger.Info("tags are not supported, skipping tags reconciliation")
+		return nil
+	}
 
 	err := ctx.Session.TagManager.AttachMultipleTagsToObject(ctx, ctx.VSphereVM.Spec.TagIDs, ctx.Ref)
 	if err != nil {
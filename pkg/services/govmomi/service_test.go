@@ -0,0 +1,411 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func newTestVirtualMachineContext(t *testing.T, simr *vcsim.Simulator) *virtualMachineContext {
+	t.Helper()
+	g := NewWithT(t)
+
+	vmContext := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+	vmContext.VSphereVM.Spec.Server = simr.ServerURL().Host
+
+	authSession, err := session.GetOrCreate(
+		vmContext.Context,
+		session.NewParams().
+			WithServer(vmContext.VSphereVM.Spec.Server).
+			WithUserInfo(simr.Username(), simr.Password()).
+			WithDatacenter("*"))
+	g.Expect(err).NotTo(HaveOccurred())
+	vmContext.Session = authSession
+
+	// Guest operations require the VM to report running VMware Tools.
+	simVM := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine) //nolint:forcetypeassert
+	simVM.Guest.ToolsRunningStatus = string(types.VirtualMachineToolsRunningStatusGuestToolsRunning)
+	vmRef := simVM.Reference()
+	return &virtualMachineContext{
+		VMContext: *vmContext,
+		Obj:       object.NewVirtualMachine(authSession.Client.Client, vmRef),
+		Ref:       vmRef,
+		State:     &infrav1.VirtualMachine{},
+	}
+}
+
+func TestTriggerSoftPowerOff(t *testing.T) {
+	vms := &VMService{}
+
+	t.Run("hard power off mode does not shut down the guest", func(t *testing.T) {
+		g := NewWithT(t)
+		simr, err := vcsim.NewBuilder().Build()
+		g.Expect(err).NotTo(HaveOccurred())
+		defer simr.Destroy()
+
+		vmCtx := newTestVirtualMachineContext(t, simr)
+		pending, err := vms.triggerSoftPowerOff(vmCtx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(pending).To(BeFalse())
+		g.Expect(conditions.Has(vmCtx.VSphereVM, infrav1.GuestSoftPowerOffSucceededCondition)).To(BeFalse())
+	})
+
+	t.Run("soft power off mode shuts down the guest", func(t *testing.T) {
+		g := NewWithT(t)
+		simr, err := vcsim.NewBuilder().Build()
+		g.Expect(err).NotTo(HaveOccurred())
+		defer simr.Destroy()
+
+		vmCtx := newTestVirtualMachineContext(t, simr)
+		vmCtx.VSphereVM.Spec.PowerOffMode = infrav1.VirtualMachinePowerOpModeSoft
+		pending, err := vms.triggerSoftPowerOff(vmCtx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(pending).To(BeTrue())
+		g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.GuestSoftPowerOffSucceededCondition)).To(Equal(infrav1.GuestSoftPowerOffInProgressReason))
+
+		// A soft power off never falls back to a hard power off.
+		pending, err = vms.triggerSoftPowerOff(vmCtx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(pending).To(BeTrue())
+	})
+
+	t.Run("trySoft power off mode falls back to hard power off without VMware Tools", func(t *testing.T) {
+		g := NewWithT(t)
+		simr, err := vcsim.NewBuilder().Build()
+		g.Expect(err).NotTo(HaveOccurred())
+		defer simr.Destroy()
+
+		vmCtx := newTestVirtualMachineContext(t, simr)
+		simulator.Map.Get(vmCtx.Ref).(*simulator.VirtualMachine).Guest.ToolsRunningStatus = string(types.VirtualMachineToolsRunningStatusGuestToolsNotRunning) //nolint:forcetypeassert
+		vmCtx.VSphereVM.Spec.PowerOffMode = infrav1.VirtualMachinePowerOpModeTrySoft
+		pending, err := vms.triggerSoftPowerOff(vmCtx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(pending).To(BeFalse())
+		g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.GuestSoftPowerOffSucceededCondition)).To(Equal(infrav1.GuestSoftPowerOffFailedReason))
+	})
+
+	t.Run("trySoft power off mode falls back to hard power off after the timeout", func(t *testing.T) {
+		g := NewWithT(t)
+		simr, err := vcsim.NewBuilder().Build()
+		g.Expect(err).NotTo(HaveOccurred())
+		defer simr.Destroy()
+
+		vmCtx := newTestVirtualMachineContext(t, simr)
+		vmCtx.VSphereVM.Spec.PowerOffMode = infrav1.VirtualMachinePowerOpModeTrySoft
+		vmCtx.VSphereVM.Spec.GuestSoftPowerOffTimeout = &metav1.Duration{Duration: time.Minute}
+		conditions.Set(vmCtx.VSphereVM, &clusterv1.Condition{
+			Type:               infrav1.GuestSoftPowerOffSucceededCondition,
+			Status:             "False",
+			Severity:           clusterv1.ConditionSeverityInfo,
+			Reason:             infrav1.GuestSoftPowerOffInProgressReason,
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-2 * time.Minute)),
+		})
+
+		pending, err := vms.triggerSoftPowerOff(vmCtx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(pending).To(BeFalse())
+		g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.GuestSoftPowerOffSucceededCondition)).To(Equal(infrav1.GuestSoftPowerOffFailedReason))
+	})
+}
+
+func TestReconcileRestart(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vms := &VMService{}
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	g.Expect(vms.reconcileRestart(vmCtx)).To(Succeed())
+
+	vmCtx.VSphereVM.Annotations = map[string]string{infrav1.VMRestartAnnotation: ""}
+	g.Expect(vms.reconcileRestart(vmCtx)).To(Succeed())
+	g.Expect(vmCtx.VSphereVM.Annotations).NotTo(HaveKey(infrav1.VMRestartAnnotation))
+}
+
+func TestReconcilePowerRecovery(t *testing.T) {
+	vms := &VMService{}
+	poweredOffVM := func(t *testing.T, simr *vcsim.Simulator, policy infrav1.VirtualMachinePowerRecoveryPolicy) *virtualMachineContext {
+		t.Helper()
+		g := NewWithT(t)
+		vmCtx := newTestVirtualMachineContext(t, simr)
+		vmCtx.VSphereVM.Spec.PowerRecoveryPolicy = policy
+		conditions.MarkTrue(vmCtx.VSphereVM, infrav1.PoweredOnCondition)
+		task, err := vmCtx.Obj.PowerOff(vmCtx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(task.Wait(vmCtx)).To(Succeed())
+		return vmCtx
+	}
+
+	t.Run("PowerOn policy powers the VM back on", func(t *testing.T) {
+		g := NewWithT(t)
+		simr, err := vcsim.NewBuilder().Build()
+		g.Expect(err).NotTo(HaveOccurred())
+		defer simr.Destroy()
+
+		vmCtx := poweredOffVM(t, simr, infrav1.VirtualMachinePowerRecoveryPolicyPowerOn)
+		ok, err := vms.reconcilePowerState(vmCtx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(ok).To(BeFalse())
+		g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.PoweredOnCondition)).To(Equal(infrav1.PoweringOnReason))
+		g.Expect(vmCtx.VSphereVM.Status.TaskRef).NotTo(BeEmpty())
+	})
+
+	t.Run("Report policy leaves the VM powered off", func(t *testing.T) {
+		g := NewWithT(t)
+		simr, err := vcsim.NewBuilder().Build()
+		g.Expect(err).NotTo(HaveOccurred())
+		defer simr.Destroy()
+
+		vmCtx := poweredOffVM(t, simr, infrav1.VirtualMachinePowerRecoveryPolicyReport)
+		for i := 0; i < 2; i++ {
+			ok, err := vms.reconcilePowerState(vmCtx)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(ok).To(BeFalse())
+			g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.PoweredOnCondition)).To(Equal(infrav1.PoweredOffReason))
+			g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.VMProvisionedCondition)).To(Equal(infrav1.PoweredOffReason))
+			g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(BeEmpty())
+		}
+		powerState, err := vmCtx.Obj.PowerState(vmCtx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(powerState).To(Equal(types.VirtualMachinePowerStatePoweredOff))
+	})
+}
+
+func TestReconcileAdoptedVMUserData(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vms := &VMService{}
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	// The user data is compressed.
+	vmCtx.BootstrapDataCompressionThreshold = 8
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: vmCtx.VSphereVM.Namespace,
+			Name:      "bootstrap-data",
+		},
+		Data: map[string][]byte{
+			"value": []byte("#cloud-config"),
+		},
+	}
+	g.Expect(vmCtx.Client.Create(vmCtx, secret)).To(Succeed())
+	vmCtx.VSphereVM.Spec.BootstrapRef = &corev1.ObjectReference{
+		Namespace: secret.Namespace,
+		Name:      secret.Name,
+	}
+
+	// The user data of the adopted VM is set.
+	ok, err := vms.reconcileAdoptedVMUserData(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeFalse())
+	g.Expect(vmCtx.VSphereVM.Status.TaskRef).NotTo(BeEmpty())
+
+	// The user data is only set once.
+	ok, err = vms.reconcileAdoptedVMUserData(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	userData, err := vms.getGuestInfo(vmCtx, guestInfoKeyUserdata)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(userData).To(Equal("#cloud-config"))
+
+	// Cloned VMs get their user data from the clone spec.
+	vmCtx.VSphereVM.Spec.Template = "ubuntu-template"
+	vmCtx.VSphereVM.Status.TaskRef = ""
+	ok, err = vms.reconcileAdoptedVMUserData(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(BeEmpty())
+}
+
+func TestReconcileTalosConfig(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vms := &VMService{}
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	talosConfig := "version: v1alpha1\nmachine:\n  type: worker\ncluster:\n  clusterName: test\n"
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: vmCtx.VSphereVM.Namespace,
+			Name:      "bootstrap-data",
+		},
+		Data: map[string][]byte{
+			"value": []byte(talosConfig),
+		},
+	}
+	g.Expect(vmCtx.Client.Create(vmCtx, secret)).To(Succeed())
+	vmCtx.VSphereVM.Spec.BootstrapRef = &corev1.ObjectReference{
+		Namespace: secret.Namespace,
+		Name:      secret.Name,
+	}
+
+	// The metadata is not set on Talos VMs.
+	ok, err := vms.reconcileMetadata(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(BeEmpty())
+
+	// The machine config is set as is.
+	ok, err = vms.reconcileTalosConfig(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeFalse())
+	g.Expect(vmCtx.VSphereVM.Status.TaskRef).NotTo(BeEmpty())
+	config, err := vms.getGuestInfo(vmCtx, guestInfoKeyTalosConfig)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(config).To(Equal(talosConfig))
+
+	// The machine config is only set once.
+	ok, err = vms.reconcileTalosConfig(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+}
+
+func TestIsTalosConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{name: "talos machine config", data: "version: v1alpha1\nmachine:\n  type: controlplane\n", want: true},
+		{name: "multi-document talos config", data: "version: v1alpha1\nmachine:\n  type: worker\n---\napiVersion: v1alpha1\nkind: KmsgLogConfig\n", want: true},
+		{name: "cloud-init user data", data: "#cloud-config\nruncmd:\n- kubeadm join\n"},
+		{name: "ignition config", data: `{"ignition":{"version":"3.1.0"}}`},
+		{name: "shell script", data: "#!/bin/sh\necho hello\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(isTalosConfig([]byte(tt.data))).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestRetainVM(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vms := &VMService{}
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	g.Expect(vms.retainVM(vmCtx)).To(Succeed())
+	// The existing tag is reused.
+	g.Expect(vms.retainVM(vmCtx)).To(Succeed())
+
+	attached, err := vmCtx.Session.TagManager.GetAttachedTags(vmCtx, vmCtx.Ref)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(attached).To(HaveLen(1))
+	g.Expect(attached[0].Name).To(Equal(infrav1.RetainedVMTagName))
+}
+
+func TestDetachDataDisks(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vms := &VMService{}
+	vmCtx := newTestVirtualMachineContext(t, simr)
+
+	// There is no data disk to detach.
+	task, err := vms.detachDataDisks(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(task).To(BeNil())
+
+	devices, err := vmCtx.Obj.Device(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	controller, err := devices.FindDiskController("")
+	g.Expect(err).NotTo(HaveOccurred())
+	disk := devices.CreateDisk(controller, types.ManagedObjectReference{}, "")
+	disk.CapacityInKB = 1024
+	g.Expect(vmCtx.Obj.AddDevice(vmCtx, disk)).To(Succeed())
+
+	task, err = vms.detachDataDisks(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(task).NotTo(BeNil())
+	g.Expect(task.Wait(vmCtx)).To(Succeed())
+
+	devices, err = vmCtx.Obj.Device(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(devices.SelectByType((*types.VirtualDisk)(nil))).To(HaveLen(1))
+}
+
+func TestReconcileResourceAllocation(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vms := &VMService{}
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	simVM := simulator.Map.Get(vmCtx.Ref).(*simulator.VirtualMachine) //nolint:forcetypeassert
+
+	// Nothing is reconciled without a resource allocation.
+	ok, err := vms.reconcileResourceAllocation(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+
+	vmCtx.VSphereVM.Spec.ResourceAllocation = &infrav1.ResourceAllocation{
+		CPUReservationMHz: pointer.Int64(1000),
+		MemoryLimitMiB:    pointer.Int64(4096),
+		MemoryShares:      infrav1.SharesLevelHigh,
+	}
+	ok, err = vms.reconcileResourceAllocation(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeFalse())
+	g.Expect(vmCtx.VSphereVM.Status.TaskRef).NotTo(BeEmpty())
+	task := object.NewTask(vmCtx.Session.Client.Client, types.ManagedObjectReference{Type: "Task", Value: vmCtx.VSphereVM.Status.TaskRef})
+	g.Expect(task.Wait(vmCtx)).To(Succeed())
+
+	g.Expect(*simVM.Config.CpuAllocation.Reservation).To(Equal(int64(1000)))
+	g.Expect(*simVM.Config.MemoryAllocation.Limit).To(Equal(int64(4096)))
+	g.Expect(simVM.Config.MemoryAllocation.Shares.Level).To(Equal(types.SharesLevelHigh))
+
+	// The allocation matches the spec.
+	vmCtx.VSphereVM.Status.TaskRef = ""
+	ok, err = vms.reconcileResourceAllocation(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(BeEmpty())
+
+	// The allocation is restored once it drifts.
+	simVM.Config.CpuAllocation.Reservation = pointer.Int64(0)
+	ok, err = vms.reconcileResourceAllocation(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeFalse())
+}
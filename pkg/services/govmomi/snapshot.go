@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/audit"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+)
+
+// SnapshotService creates and removes snapshots of the VM of a VSphereVM.
+type SnapshotService struct{}
+
+// CreateSnapshot creates a crash-consistent snapshot of the VM, which
+// includes neither the memory of the VM nor quiesces its file systems, and
+// returns the managed object reference value of the snapshot. A snapshot of
+// the VM with the same name is returned instead of creating another one, so
+// a snapshot created by a reconciliation whose status update failed is not
+// taken twice.
+func (s *SnapshotService) CreateSnapshot(ctx *context.VMContext, name, description string) (string, error) {
+	ref, err := findVM(ctx)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to find vm %s", ctx)
+	}
+	vm := object.NewVirtualMachine(ctx.Session.Client.Client, ref)
+
+	if snapshot, err := vm.FindSnapshot(ctx, name); err == nil {
+		return snapshot.Value, nil
+	}
+
+	ctx.Logger.Info("creating snapshot", "snapshot", name)
+	task, err := vm.CreateSnapshot(ctx, name, description, false, false)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to create snapshot %q of vm %s", name, ctx)
+	}
+	info, err := task.WaitForResult(ctx)
+	audit.RecordTaskOutcome(ctx, task.Client().URL().Host, task.Reference(), info, err)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to create snapshot %q of vm %s", name, ctx)
+	}
+	snapshot, ok := info.Result.(types.ManagedObjectReference)
+	if !ok {
+		return "", errors.Errorf("unexpected result %T of the snapshot task of vm %s", info.Result, ctx)
+	}
+	return snapshot.Value, nil
+}
+
+// RemoveSnapshot removes the snapshot with the managed object reference
+// value from the VM, consolidating its disks. A snapshot or a VM that no
+// longer exists is not an error.
+func (s *SnapshotService) RemoveSnapshot(ctx *context.VMContext, snapshotRef string) error {
+	if snapshotRef == "" {
+		return nil
+	}
+	if _, err := findVM(ctx); err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "unable to find vm %s", ctx)
+	}
+
+	ctx.Logger.Info("removing snapshot", "snapshot", snapshotRef)
+	res, err := methods.RemoveSnapshot_Task(ctx, ctx.Session.Client.Client, &types.RemoveSnapshot_Task{
+		This:           types.ManagedObjectReference{Type: "VirtualMachineSnapshot", Value: snapshotRef},
+		RemoveChildren: false,
+		Consolidate:    types.NewBool(true),
+	})
+	if err == nil {
+		err = audit.WaitForTask(ctx, object.NewTask(ctx.Session.Client.Client, res.Returnval))
+	}
+	if err != nil && !isManagedObjectNotFound(err) {
+		return errors.Wrapf(err, "unable to remove snapshot %s of vm %s", snapshotRef, ctx)
+	}
+	return nil
+}
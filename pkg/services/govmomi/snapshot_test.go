@@ -0,0 +1,58 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/simulator"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestSnapshotService(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	s := &SnapshotService{}
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	simVM := simulator.Map.Get(vmCtx.Ref).(*simulator.VirtualMachine) //nolint:forcetypeassert
+	vmCtx.VSphereVM.Spec.BiosUUID = simVM.Config.Uuid
+
+	snapshotRef, err := s.CreateSnapshot(&vmCtx.VMContext, "before-upgrade", "taken before the upgrade")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(snapshotRef).NotTo(BeEmpty())
+	g.Expect(simVM.Snapshot).NotTo(BeNil())
+	g.Expect(simVM.Snapshot.RootSnapshotList).To(HaveLen(1))
+	g.Expect(simVM.Snapshot.RootSnapshotList[0].Snapshot.Value).To(Equal(snapshotRef))
+
+	// A snapshot with the same name is not taken twice.
+	again, err := s.CreateSnapshot(&vmCtx.VMContext, "before-upgrade", "taken before the upgrade")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(again).To(Equal(snapshotRef))
+	g.Expect(simVM.Snapshot.RootSnapshotList).To(HaveLen(1))
+
+	g.Expect(s.RemoveSnapshot(&vmCtx.VMContext, snapshotRef)).To(Succeed())
+	g.Expect(simVM.Snapshot).To(BeNil())
+
+	// Removing a snapshot that no longer exists is not an error.
+	g.Expect(s.RemoveSnapshot(&vmCtx.VMContext, snapshotRef)).To(Succeed())
+	g.Expect(s.RemoveSnapshot(&vmCtx.VMContext, "")).To(Succeed())
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package task watches the progress of vCenter tasks.
+package task
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// watches holds the tasks being watched, keyed by the value of their
+// managed object reference.
+var watches sync.Map
+
+// Watch watches the progress of the task with the property collector in a
+// background goroutine. The info of the task is sent on the returned channel
+// every time the progress of the running task advances by at least step
+// percents. The channel is closed once the task completes or the context is
+// done.
+//
+// A nil channel is returned if the task is already being watched.
+func Watch(ctx context.Context, client *vim25.Client, ref types.ManagedObjectReference, step int32) <-chan types.TaskInfo {
+	if _, loaded := watches.LoadOrStore(ref.Value, struct{}{}); loaded {
+		return nil
+	}
+
+	progress := make(chan types.TaskInfo)
+	go func() {
+		defer watches.Delete(ref.Value)
+		defer close(progress)
+
+		reported := int32(-1)
+		_ = property.Wait(ctx, property.DefaultCollector(client), ref, []string{"info"}, func(changes []types.PropertyChange) bool {
+			for _, change := range changes {
+				info, ok := change.Val.(types.TaskInfo)
+				if !ok {
+					continue
+				}
+				if info.State == types.TaskInfoStateSuccess || info.State == types.TaskInfoStateError {
+					return true
+				}
+				if info.State != types.TaskInfoStateRunning || (reported >= 0 && info.Progress-reported < step) {
+					continue
+				}
+				reported = info.Progress
+				select {
+				case progress <- info:
+				case <-ctx.Done():
+					return true
+				}
+			}
+			return false
+		})
+	}()
+	return progress
+}
+
+// Message returns a human readable description of the state of the task,
+// including its progress while it runs and its error once it failed.
+func Message(info types.TaskInfo) string {
+	name := info.DescriptionId
+	if info.Description != nil && info.Description.Message != "" {
+		name = info.Description.Message
+	}
+
+	switch info.State {
+	case types.TaskInfoStateQueued:
+		return fmt.Sprintf("task %s is queued", name)
+	case types.TaskInfoStateRunning:
+		return fmt.Sprintf("task %s is running (%d%%)", name, info.Progress)
+	case types.TaskInfoStateError:
+		if info.Error != nil && info.Error.LocalizedMessage != "" {
+			return fmt.Sprintf("task %s failed: %s", name, info.Error.LocalizedMessage)
+		}
+		return fmt.Sprintf("task %s failed", name)
+	default:
+		return fmt.Sprintf("task %s succeeded", name)
+	}
+}
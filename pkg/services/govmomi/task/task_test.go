@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package task
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestWatch(t *testing.T) {
+	simulator.Test(func(ctx context.Context, c *vim25.Client) {
+		g := NewWithT(t)
+
+		simVM := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine) //nolint:forcetypeassert
+		vm := object.NewVirtualMachine(c, simVM.Reference())
+		powerOff, err := vm.PowerOff(ctx)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(powerOff.Wait(ctx)).To(Succeed())
+
+		progress := Watch(ctx, c, powerOff.Reference(), 10)
+		g.Expect(progress).NotTo(BeNil())
+		// The channel is closed once the task is complete.
+		g.Eventually(progress, 10*time.Second).Should(BeClosed())
+
+		// A task is only watched once at a time.
+		watches.Store("task-1", struct{}{})
+		defer watches.Delete("task-1")
+		g.Expect(Watch(ctx, c, types.ManagedObjectReference{Type: "Task", Value: "task-1"}, 10)).To(BeNil())
+	})
+}
+
+func TestMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		info types.TaskInfo
+		want string
+	}{
+		{
+			name: "queued",
+			info: types.TaskInfo{DescriptionId: "VirtualMachine.clone", State: types.TaskInfoStateQueued},
+			want: "task VirtualMachine.clone is queued",
+		},
+		{
+			name: "running",
+			info: types.TaskInfo{DescriptionId: "VirtualMachine.clone", State: types.TaskInfoStateRunning, Progress: 42},
+			want: "task VirtualMachine.clone is running (42%)",
+		},
+		{
+			name: "failed",
+			info: types.TaskInfo{
+				DescriptionId: "VirtualMachine.clone",
+				Description:   &types.LocalizableMessage{Message: "Clone virtual machine"},
+				State:         types.TaskInfoStateError,
+				Error:         &types.LocalizedMethodFault{LocalizedMessage: "insufficient disk space"},
+			},
+			want: "task Clone virtual machine failed: insufficient disk space",
+		},
+		{
+			name: "succeeded",
+			info: types.TaskInfo{DescriptionId: "VirtualMachine.reconfigure", State: types.TaskInfoStateSuccess},
+			want: "task VirtualMachine.reconfigure succeeded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(Message(tt.info)).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,343 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"path"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/yaml"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+const (
+	// bootstrapISOName is the name of the NoCloud ISO image uploaded to the
+	// directory of VMs using the cdrom transport.
+	bootstrapISOName = "capv-cidata.iso"
+
+	// noCloudVolumeID is the volume label the NoCloud datasource of
+	// cloud-init looks for.
+	noCloudVolumeID = "CIDATA"
+
+	// ovfEnvGuestInfoTransport is the transport of the OVF environment read
+	// by the OVF datasource of cloud-init.
+	ovfEnvGuestInfoTransport = "com.vmware.guestInfo"
+)
+
+// getBootstrapDataTransport returns the transport of the metadata and the
+// cloud-init bootstrap data of the VM.
+func getBootstrapDataTransport(vm *infrav1.VSphereVM) infrav1.BootstrapDataTransport {
+	if vm.Spec.BootstrapDataTransport == "" {
+		return infrav1.GuestInfoTransport
+	}
+	return vm.Spec.BootstrapDataTransport
+}
+
+// reconcileBootstrapDataTransport delivers the metadata and the cloud-init
+// bootstrap data of VMs not using guestinfo variables. Like the Ignition
+// config, the data is delivered once the VM is created and before it is
+// powered on, as its network configuration matches the MAC addresses of the
+// VM.
+func (vms *VMService) reconcileBootstrapDataTransport(ctx *virtualMachineContext) (bool, error) {
+	transport := getBootstrapDataTransport(ctx.VSphereVM)
+	if transport == infrav1.GuestInfoTransport || conditions.IsTrue(ctx.VSphereVM, infrav1.VMProvisionedCondition) {
+		return true, nil
+	}
+
+	bootstrapData, format, err := vms.getBootstrapData(&ctx.VMContext)
+	if err != nil {
+		return false, err
+	}
+	// Ignition and Talos configs are set by reconcileIgnition and
+	// reconcileTalosConfig.
+	if format == bootstrapv1.Ignition || format == talosFormat {
+		return true, nil
+	}
+
+	metadata, err := util.GetMachineMetadata(ctx.VSphereVM.Name, *ctx.VSphereVM, ctx.State.Network...)
+	if err != nil {
+		return false, err
+	}
+	metadata, networkConfig, err := splitNetworkConfig(metadata)
+	if err != nil {
+		return false, err
+	}
+
+	switch transport {
+	case infrav1.VAppPropertiesTransport:
+		return vms.reconcileVAppProperties(ctx, map[string]string{
+			"instance-id":    ctx.VSphereVM.Name,
+			"hostname":       ctx.VSphereVM.Name,
+			"user-data":      base64.StdEncoding.EncodeToString(bootstrapData),
+			"network-config": base64.StdEncoding.EncodeToString(networkConfig),
+		})
+	case infrav1.CDROMTransport:
+		return vms.reconcileBootstrapISO(ctx, newISO(noCloudVolumeID, []isoFile{
+			{name: "meta-data", data: metadata},
+			{name: "network-config", data: networkConfig},
+			{name: "user-data", data: bootstrapData},
+		}))
+	default:
+		return false, errors.Errorf("unsupported bootstrap data transport %q", transport)
+	}
+}
+
+// splitNetworkConfig returns the metadata of the VM without its network
+// configuration, and the network configuration.
+func splitNetworkConfig(metadata []byte) ([]byte, []byte, error) {
+	var data map[string]interface{}
+	if err := yaml.Unmarshal(metadata, &data); err != nil {
+		return nil, nil, errors.Wrap(err, "unable to parse metadata")
+	}
+	networkConfig, err := yaml.Marshal(data["network"])
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to encode network config")
+	}
+	delete(data, "network")
+	metadata, err = yaml.Marshal(data)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to encode metadata")
+	}
+	return metadata, networkConfig, nil
+}
+
+// reconcileVAppProperties sets the vApp properties of the VM, which are
+// exposed to the guest in the OVF environment.
+func (vms *VMService) reconcileVAppProperties(ctx *virtualMachineContext, properties map[string]string) (bool, error) {
+	var obj mo.VirtualMachine
+	if err := ctx.Obj.Properties(ctx, ctx.Ref, []string{"config.vAppConfig"}, &obj); err != nil {
+		return false, errors.Wrapf(err, "unable to get vApp properties of vm %s", ctx)
+	}
+	var current types.BaseVmConfigInfo
+	if obj.Config != nil {
+		current = obj.Config.VAppConfig
+	}
+	spec := getVAppConfigSpec(current, properties)
+	if spec == nil {
+		return true, nil
+	}
+
+	ctx.Logger.Info("updating vApp properties")
+	task, err := ctx.Obj.Reconfigure(ctx, types.VirtualMachineConfigSpec{
+		VAppConfig: spec,
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to set vApp properties on vm %s", ctx)
+	}
+
+	ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+	ctx.Logger.Info("wait for VM vApp properties to be updated")
+	return false, nil
+}
+
+// getVAppConfigSpec returns the spec setting the vApp properties to the given
+// values and exposing them through guestinfo, or nil if they are already set.
+func getVAppConfigSpec(current types.BaseVmConfigInfo, properties map[string]string) *types.VmConfigSpec {
+	var existing []types.VAppPropertyInfo
+	var transports []string
+	if current != nil {
+		existing = current.GetVmConfigInfo().Property
+		transports = current.GetVmConfigInfo().OvfEnvironmentTransport
+	}
+
+	byID := map[string]types.VAppPropertyInfo{}
+	nextKey := int32(0)
+	for _, property := range existing {
+		byID[property.Id] = property
+		if property.Key >= nextKey {
+			nextKey = property.Key + 1
+		}
+	}
+
+	ids := make([]string, 0, len(properties))
+	for id := range properties {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	spec := &types.VmConfigSpec{}
+	for _, id := range ids {
+		value := properties[id]
+		if property, ok := byID[id]; ok {
+			if property.Value == value {
+				continue
+			}
+			property.Value = value
+			spec.Property = append(spec.Property, types.VAppPropertySpec{
+				ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: types.ArrayUpdateOperationEdit},
+				Info:            &property,
+			})
+			continue
+		}
+		spec.Property = append(spec.Property, types.VAppPropertySpec{
+			ArrayUpdateSpec: types.ArrayUpdateSpec{Operation: types.ArrayUpdateOperationAdd},
+			Info: &types.VAppPropertyInfo{
+				Key:   nextKey,
+				Id:    id,
+				Type:  "string",
+				Value: value,
+			},
+		})
+		nextKey++
+	}
+
+	hasTransport := false
+	for _, transport := range transports {
+		hasTransport = hasTransport || transport == ovfEnvGuestInfoTransport
+	}
+	if !hasTransport {
+		spec.OvfEnvironmentTransport = append(transports, ovfEnvGuestInfoTransport)
+	}
+
+	if len(spec.Property) == 0 && spec.OvfEnvironmentTransport == nil {
+		return nil
+	}
+	return spec
+}
+
+// reconcileBootstrapISO uploads the NoCloud ISO image to the directory of
+// the VM and inserts it into a CD-ROM drive of the VM.
+func (vms *VMService) reconcileBootstrapISO(ctx *virtualMachineContext, iso []byte) (bool, error) {
+	var obj mo.VirtualMachine
+	if err := ctx.Obj.Properties(ctx, ctx.Ref, []string{"config.files.vmPathName", "config.hardware.device"}, &obj); err != nil {
+		return false, errors.Wrapf(err, "unable to get devices of vm %s", ctx)
+	}
+	isoPath, err := getBootstrapISOPath(&obj)
+	if err != nil {
+		return false, err
+	}
+	devices := object.VirtualDeviceList(obj.Config.Hardware.Device)
+	if findBootstrapCDROM(devices, isoPath) != nil {
+		return true, nil
+	}
+
+	ctx.Logger.Info("uploading bootstrap ISO", "path", isoPath.String())
+	datastore, err := ctx.Session.Finder.Datastore(ctx, isoPath.Datastore)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to find datastore %q of vm %s", isoPath.Datastore, ctx)
+	}
+	if err := datastore.Upload(ctx, bytes.NewReader(iso), isoPath.Path, &soap.DefaultUpload); err != nil {
+		return false, errors.Wrapf(err, "unable to upload bootstrap ISO of vm %s", ctx)
+	}
+
+	// The CD-ROM drive of the template is used, if any.
+	operation := types.VirtualDeviceConfigSpecOperationEdit
+	var cdrom *types.VirtualCdrom
+	if cdroms := devices.SelectByType((*types.VirtualCdrom)(nil)); len(cdroms) > 0 {
+		cdrom = cdroms[0].(*types.VirtualCdrom) //nolint:forcetypeassert
+	} else {
+		operation = types.VirtualDeviceConfigSpecOperationAdd
+		ide, err := devices.FindIDEController("")
+		if err != nil {
+			return false, errors.Wrapf(err, "unable to add CD-ROM drive to vm %s", ctx)
+		}
+		if cdrom, err = devices.CreateCdrom(ide); err != nil {
+			return false, errors.Wrapf(err, "unable to add CD-ROM drive to vm %s", ctx)
+		}
+	}
+	cdrom = devices.InsertIso(cdrom, isoPath.String())
+	cdrom.Connectable = &types.VirtualDeviceConnectInfo{
+		StartConnected:    true,
+		AllowGuestControl: true,
+	}
+
+	task, err := ctx.Obj.Reconfigure(ctx, types.VirtualMachineConfigSpec{
+		DeviceChange: []types.BaseVirtualDeviceConfigSpec{
+			&types.VirtualDeviceConfigSpec{
+				Operation: operation,
+				Device:    cdrom,
+			},
+		},
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to insert bootstrap ISO into vm %s", ctx)
+	}
+
+	ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+	ctx.Logger.Info("wait for VM bootstrap ISO to be inserted")
+	return false, nil
+}
+
+// deleteBootstrapISO deletes the NoCloud ISO image of the VM, if any, so it
+// does not outlive the VM.
+func deleteBootstrapISO(ctx *virtualMachineContext) error {
+	if getBootstrapDataTransport(ctx.VSphereVM) != infrav1.CDROMTransport {
+		return nil
+	}
+
+	var obj mo.VirtualMachine
+	if err := ctx.Obj.Properties(ctx, ctx.Ref, []string{"config.files.vmPathName", "config.hardware.device"}, &obj); err != nil {
+		return errors.Wrapf(err, "unable to get devices of vm %s", ctx)
+	}
+	isoPath, err := getBootstrapISOPath(&obj)
+	if err != nil {
+		return err
+	}
+	if findBootstrapCDROM(object.VirtualDeviceList(obj.Config.Hardware.Device), isoPath) == nil {
+		return nil
+	}
+
+	datacenter, err := ctx.Session.CachedFinder.DatacenterOrDefault(ctx, ctx.VSphereVM.Spec.Datacenter)
+	if err != nil {
+		return errors.Wrapf(err, "unable to find datacenter of vm %s", ctx)
+	}
+	ctx.Logger.Info("deleting bootstrap ISO", "path", isoPath.String())
+	task, err := object.NewFileManager(ctx.Session.Client.Client).DeleteDatastoreFile(ctx, isoPath.String(), datacenter)
+	if err == nil {
+		err = task.Wait(ctx)
+	}
+	if err != nil && !isFileNotFound(err) {
+		return errors.Wrapf(err, "unable to delete bootstrap ISO of vm %s", ctx)
+	}
+	return nil
+}
+
+// getBootstrapISOPath returns the path of the NoCloud ISO image in the
+// directory of the VM.
+func getBootstrapISOPath(obj *mo.VirtualMachine) (*object.DatastorePath, error) {
+	var vmPath object.DatastorePath
+	if obj.Config == nil || !vmPath.FromString(obj.Config.Files.VmPathName) {
+		return nil, errors.Errorf("unable to get directory of vm %s", obj.Reference())
+	}
+	return &object.DatastorePath{
+		Datastore: vmPath.Datastore,
+		Path:      path.Join(path.Dir(vmPath.Path), bootstrapISOName),
+	}, nil
+}
+
+// findBootstrapCDROM returns the CD-ROM drive the NoCloud ISO image is
+// inserted into, if any.
+func findBootstrapCDROM(devices object.VirtualDeviceList, isoPath *object.DatastorePath) *types.VirtualCdrom {
+	for _, device := range devices.SelectByType((*types.VirtualCdrom)(nil)) {
+		cdrom := device.(*types.VirtualCdrom) //nolint:forcetypeassert
+		if backing, ok := cdrom.Backing.(*types.VirtualCdromIsoBackingInfo); ok && backing.FileName == isoPath.String() {
+			return cdrom
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestGetVAppConfigSpec(t *testing.T) {
+	properties := map[string]string{
+		"hostname":  "vm",
+		"user-data": "I2Nsb3VkLWNvbmZpZw==",
+	}
+
+	t.Run("adds the properties and the guestinfo transport", func(t *testing.T) {
+		g := NewWithT(t)
+		spec := getVAppConfigSpec(nil, properties)
+		g.Expect(spec).NotTo(BeNil())
+		g.Expect(spec.OvfEnvironmentTransport).To(Equal([]string{"com.vmware.guestInfo"}))
+		g.Expect(spec.Property).To(HaveLen(2))
+		g.Expect(spec.Property[0].Operation).To(Equal(types.ArrayUpdateOperationAdd))
+		g.Expect(*spec.Property[0].Info).To(Equal(types.VAppPropertyInfo{Key: 0, Id: "hostname", Type: "string", Value: "vm"}))
+		g.Expect(spec.Property[1].Info.Key).To(BeEquivalentTo(1))
+	})
+
+	t.Run("edits the properties of the template", func(t *testing.T) {
+		g := NewWithT(t)
+		current := &types.VmConfigInfo{
+			OvfEnvironmentTransport: []string{"iso", "com.vmware.guestInfo"},
+			Property: []types.VAppPropertyInfo{
+				{Key: 4, Id: "hostname", Type: "string", Value: "template"},
+				{Key: 7, Id: "seedfrom", Type: "string"},
+			},
+		}
+		spec := getVAppConfigSpec(current, properties)
+		g.Expect(spec).NotTo(BeNil())
+		g.Expect(spec.OvfEnvironmentTransport).To(BeNil())
+		g.Expect(spec.Property).To(HaveLen(2))
+		g.Expect(spec.Property[0].Operation).To(Equal(types.ArrayUpdateOperationEdit))
+		g.Expect(*spec.Property[0].Info).To(Equal(types.VAppPropertyInfo{Key: 4, Id: "hostname", Type: "string", Value: "vm"}))
+		g.Expect(spec.Property[1].Operation).To(Equal(types.ArrayUpdateOperationAdd))
+		g.Expect(spec.Property[1].Info.Key).To(BeEquivalentTo(8))
+	})
+
+	t.Run("is nil once the properties are set", func(t *testing.T) {
+		g := NewWithT(t)
+		current := &types.VmConfigInfo{
+			OvfEnvironmentTransport: []string{"com.vmware.guestInfo"},
+			Property: []types.VAppPropertyInfo{
+				{Key: 0, Id: "hostname", Type: "string", Value: "vm"},
+				{Key: 1, Id: "user-data", Type: "string", Value: "I2Nsb3VkLWNvbmZpZw=="},
+			},
+		}
+		g.Expect(getVAppConfigSpec(current, properties)).To(BeNil())
+	})
+}
+
+func TestSplitNetworkConfig(t *testing.T) {
+	g := NewWithT(t)
+	metadata, networkConfig, err := splitNetworkConfig([]byte("instance-id: vm\nlocal-hostname: vm\nnetwork:\n  version: 2\n"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(metadata)).To(Equal("instance-id: vm\nlocal-hostname: vm\n"))
+	g.Expect(string(networkConfig)).To(Equal("version: 2\n"))
+}
+
+func TestReconcileBootstrapISO(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	vms := &VMService{}
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	vmCtx.VSphereVM.Spec.Datacenter = "DC0"
+	vmCtx.VSphereVM.Spec.BootstrapDataTransport = infrav1.CDROMTransport
+	iso := newISO(noCloudVolumeID, []isoFile{{name: "user-data", data: []byte("#cloud-config\n")}})
+
+	ok, err := vms.reconcileBootstrapISO(vmCtx, iso)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeFalse())
+	g.Expect(vmCtx.VSphereVM.Status.TaskRef).NotTo(BeEmpty())
+
+	var obj mo.VirtualMachine
+	g.Expect(vmCtx.Obj.Properties(vmCtx, vmCtx.Ref, []string{"config.files.vmPathName", "config.hardware.device"}, &obj)).To(Succeed())
+	isoPath, err := getBootstrapISOPath(&obj)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(isoPath.Path).To(HaveSuffix("/capv-cidata.iso"))
+	cdrom := findBootstrapCDROM(object.VirtualDeviceList(obj.Config.Hardware.Device), isoPath)
+	g.Expect(cdrom).NotTo(BeNil())
+	g.Expect(cdrom.Connectable.StartConnected).To(BeTrue())
+
+	// The ISO is only inserted once.
+	ok, err = vms.reconcileBootstrapISO(vmCtx, iso)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ok).To(BeTrue())
+
+	g.Expect(deleteBootstrapISO(vmCtx)).To(Succeed())
+	// Deleting again is a no-op.
+	g.Expect(deleteBootstrapISO(vmCtx)).To(Succeed())
+}
@@ -17,23 +17,42 @@ limitations under the License.
 package govmomi
 
 import (
-	gonet "net"
+	"fmt"
 	"path"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/vmware/govmomi/object"
-	"github.com/vmware/govmomi/property"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/audit"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/net"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/task"
+)
+
+const (
+	// cloneTaskDescriptionID is the description ID of vCenter tasks cloning a VM.
+	cloneTaskDescriptionID = "VirtualMachine.clone"
+
+	// powerOnTaskDescriptionID is the description ID of vCenter tasks powering
+	// on a VM.
+	powerOnTaskDescriptionID = "VirtualMachine.powerOn"
+
+	// taskRetryAfter is the time waited before retrying a failed task.
+	taskRetryAfter = 1 * time.Minute
+
+	// quotaTaskRetryAfter is the time waited before retrying a task which
+	// failed for lack of resources.
+	quotaTaskRetryAfter = 5 * time.Minute
 )
 
 func sanitizeIPAddrs(ctx *context.VMContext, ipAddrs []string) []string {
@@ -54,7 +73,9 @@ func sanitizeIPAddrs(ctx *context.VMContext, ipAddrs []string) []string {
 // findVM searches for a VM in one of two ways:
 //   1. If the BIOS UUID is available, then it is used to find the VM.
 //   2. Lacking the BIOS UUID, the VM is queried by its instance UUID,
-//      which was assigned the value of the VSphereVM resource's UID string.
+//      which was assigned the value of the VSphereVM resource's UID string
+//      unless the VSphereVM adopts a VM with another instance UUID or was
+//      moved by clusterctl, see instanceUUID.
 //   3. If it is not found by instance UUID, fallback to an inventory path search
 //      using the vm folder path and the VSphereVM name
 func findVM(ctx *context.VMContext) (types.ManagedObjectReference, error) {
@@ -71,15 +92,18 @@ func findVM(ctx *context.VMContext) (types.ManagedObjectReference, error) {
 		return objRef.Reference(), nil
 	}
 
-	instanceUUID := string(ctx.VSphereVM.UID)
-	objRef, err := ctx.Session.FindByInstanceUUID(ctx, instanceUUID)
+	objRef, err := ctx.Session.FindByInstanceUUID(ctx, instanceUUID(ctx.VSphereVM))
 	if err != nil {
 		return types.ManagedObjectReference{}, err
 	}
 	if objRef == nil {
 		// fallback to use inventory paths
-		folder, err := ctx.Session.Finder.FolderOrDefault(ctx, ctx.VSphereVM.Spec.Folder)
+		folder, err := ctx.Session.CachedFinder.FolderOrDefault(ctx, ctx.VSphereVM.Spec.Folder)
 		if err != nil {
+			// The VM cannot exist in a folder that is yet to be created.
+			if isFolderNotFound(err) && ctx.VSphereVM.Spec.CreateTargetHierarchy {
+				return types.ManagedObjectReference{}, errNotFound{byInventoryPath: path.Join(ctx.VSphereVM.Spec.Folder, ctx.VSphereVM.Name)}
+			}
 			return types.ManagedObjectReference{}, err
 		}
 		inventoryPath := path.Join(folder.InventoryPath, ctx.VSphereVM.Name)
@@ -98,9 +122,20 @@ func findVM(ctx *context.VMContext) (types.ManagedObjectReference, error) {
 	return objRef.Reference(), nil
 }
 
-func getTask(ctx *context.VMContext) *mo.Task {
+// instanceUUID returns the instance UUID of the VM of the VSphereVM. It is
+// the UID of the VSphereVM, unless the VSphereVM adopts a VM or recorded the
+// UID it had before being moved by clusterctl, which assigns a new UID.
+func instanceUUID(vsphereVM *infrav1.VSphereVM) string {
+	if vsphereVM.Spec.InstanceUUID != "" {
+		return vsphereVM.Spec.InstanceUUID
+	}
+	return string(vsphereVM.UID)
+}
+
+// getTask returns the task of the VSphereVM, or nil if it has none.
+func getTask(ctx *context.VMContext) (*mo.Task, error) {
 	if ctx.VSphereVM.Status.TaskRef == "" {
-		return nil
+		return nil, nil
 	}
 	var obj mo.Task
 	moRef := types.ManagedObjectReference{
@@ -108,16 +143,22 @@ func getTask(ctx *context.VMContext) *mo.Task {
 		Value: ctx.VSphereVM.Status.TaskRef,
 	}
 	if err := ctx.Session.RetrieveOne(ctx, moRef, []string{"info"}, &obj); err != nil {
-		return nil
+		return nil, errors.Wrapf(err, "unable to retrieve task %s", moRef.Value)
 	}
-	return &obj
+	return &obj, nil
 }
 
 // reconcileInFlightTask determines if a task associated to the VSphereVM object
 // is in flight or not.
 func reconcileInFlightTask(ctx *context.VMContext) (bool, error) {
 	// Check to see if there is an in-flight task.
-	task := getTask(ctx)
+	task, err := getTask(ctx)
+	if err != nil {
+		// The task is no longer tracked, hence its outcome is recorded as a
+		// failure.
+		ctx.Logger.Error(err, "unable to retrieve task of VSphereVM, no longer tracking it")
+		auditTaskLost(ctx, err)
+	}
 	return checkAndRetryTask(ctx, task)
 }
 
@@ -143,16 +184,23 @@ func checkAndRetryTask(ctx *context.VMContext, task *mo.Task) (bool, error) {
 	switch task.Info.State {
 	case types.TaskInfoStateQueued:
 		logger.Info("task is still pending", "description-id", task.Info.DescriptionId)
+		reportTaskProgress(ctx, task.Info)
+		reportCloneTimeout(ctx, task.Info)
 		return true, nil
 	case types.TaskInfoStateRunning:
 		logger.Info("task is still running", "description-id", task.Info.DescriptionId)
+		reportTaskProgress(ctx, task.Info)
+		reportCloneTimeout(ctx, task.Info)
 		return true, nil
 	case types.TaskInfoStateSuccess:
 		logger.Info("task is a success", "description-id", task.Info.DescriptionId)
+		auditTaskOutcome(ctx, task)
 		ctx.VSphereVM.Status.TaskRef = ""
+		conditions.MarkTrue(ctx.VSphereVM, infrav1.TaskProgressCondition)
 		return false, nil
 	case types.TaskInfoStateError:
 		logger.Info("task failed", "description-id", task.Info.DescriptionId)
+		reportTaskProgress(ctx, task.Info)
 
 		// NOTE: When a task fails there is not simple way to understand which operation is failing (e.g. cloning or powering on)
 		// so we are reporting failures using a dedicated reason until we find a better solution.
@@ -161,12 +209,52 @@ func checkAndRetryTask(ctx *context.VMContext, task *mo.Task) (bool, error) {
 		if task.Info.Description != nil {
 			description = task.Info.Description.Message
 		}
-		conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.TaskFailure, clusterv1.ConditionSeverityInfo, description)
+		message := description
+		class := UnknownError
+		if task.Info.Error != nil {
+			message = task.Info.Error.LocalizedMessage
+			class = classifyFault(task.Info.Error.Fault)
+		}
+		switch class {
+		case QuotaError:
+			conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.InsufficientResourcesReason, clusterv1.ConditionSeverityWarning, message)
+		case PermanentError:
+			// The task fails until the configuration of the VSphereVM is fixed,
+			// so it is not retried.
+			conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.TaskFailure, clusterv1.ConditionSeverityError, message)
+			failureReason := capierrors.UpdateMachineError
+			if !ctx.VSphereVM.Status.Ready {
+				failureReason = capierrors.CreateMachineError
+			}
+			ctx.VSphereVM.Status.FailureReason = capierrors.MachineStatusErrorPtr(failureReason)
+			ctx.VSphereVM.Status.FailureMessage = pointer.StringPtr(fmt.Sprintf("%s failed: %s", description, message))
+		default:
+			conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.TaskFailure, clusterv1.ConditionSeverityInfo, description)
+		}
+		switch task.Info.DescriptionId {
+		case cloneTaskDescriptionID:
+			markPhaseFailed(ctx, infrav1.CloneCompletedCondition, infrav1.CloningFailedReason, clusterv1.ConditionSeverityWarning, message)
+			invalidateInventoryCache(ctx)
+
+			// A failed clone of a VM requesting PCI devices is most likely caused by
+			// no host having the requested devices available.
+			if len(ctx.VSphereVM.Spec.PciDevices) > 0 {
+				conditions.MarkFalse(ctx.VSphereVM, infrav1.PCIDevicesAttachedCondition, infrav1.PCIDevicesAttachFailedReason, clusterv1.ConditionSeverityWarning, message)
+			}
+		case powerOnTaskDescriptionID:
+			markPhaseFailed(ctx, infrav1.PoweredOnCondition, infrav1.PoweringOnFailedReason, clusterv1.ConditionSeverityWarning, message)
+		}
 
 		// Instead of directly requeuing the failed task, wait for the RetryAfter duration to pass
-		// before resetting the taskRef from the VSphereVM status.
+		// before resetting the taskRef from the VSphereVM status. The resources lacking for the
+		// task are unlikely to be freed or added within a minute.
 		if ctx.VSphereVM.Status.RetryAfter.IsZero() {
-			ctx.VSphereVM.Status.RetryAfter = metav1.Time{Time: time.Now().Add(1 * time.Minute)}
+			auditTaskOutcome(ctx, task)
+			retryAfter := taskRetryAfter
+			if class == QuotaError {
+				retryAfter = quotaTaskRetryAfter
+			}
+			ctx.VSphereVM.Status.RetryAfter = metav1.Time{Time: time.Now().Add(retryAfter)}
 		} else {
 			ctx.VSphereVM.Status.TaskRef = ""
 			ctx.VSphereVM.Status.RetryAfter = metav1.Time{}
@@ -177,126 +265,200 @@ func checkAndRetryTask(ctx *context.VMContext, task *mo.Task) (bool, error) {
 	}
 }
 
-func reconcileVSphereVMWhenNetworkIsReady(ctx *virtualMachineContext, powerOnTask *object.Task) {
-	reconcileVSphereVMOnChannel(
-		&ctx.VMContext,
-		func() (<-chan []interface{}, <-chan error, error) {
-			// Wait for the VM to be powered on.
-			powerOnTaskInfo, err := powerOnTask.WaitForResult(ctx)
-			if err != nil && powerOnTaskInfo == nil {
-				return nil, nil, errors.Wrapf(err, "failed to wait for power on op for vm %s", ctx)
-			}
-			powerState, err := ctx.Obj.PowerState(ctx)
-			if err != nil {
-				return nil, nil, errors.Wrapf(err, "failed to get power state for vm %s", ctx)
-			}
-			if powerState != types.VirtualMachinePowerStatePoweredOn {
-				return nil, nil, errors.Errorf(
-					"unexpected power state %v for vm %s",
-					powerState, ctx)
-			}
+// auditTaskOutcome records the outcome of the completed task of the VM with
+// the default auditor, its submission having been recorded by the session.
+func auditTaskOutcome(ctx *context.VMContext, task *mo.Task) {
+	audit.RecordTaskOutcome(ctx, auditServer(ctx), task.Reference(), &task.Info, nil)
+}
 
-			// Wait for all NICs to have valid MAC addresses.
-			if err := waitForMacAddresses(ctx); err != nil {
-				return nil, nil, errors.Wrapf(err, "failed to wait for mac addresses for vm %s", ctx)
-			}
+// auditTaskLost records the task of the VM which could not be retrieved as
+// failed with the default auditor.
+func auditTaskLost(ctx *context.VMContext, err error) {
+	auditor := audit.Default()
+	if auditor == nil {
+		return
+	}
+	auditor.Record(audit.Record{
+		Server:    auditServer(ctx),
+		TaskID:    ctx.VSphereVM.Status.TaskRef,
+		Initiator: audit.InitiatorFrom(ctx),
+		Outcome:   audit.Failed,
+		Error:     err.Error(),
+	})
+}
 
-			// Get all the MAC addresses. This is done separately from waiting
-			// for all NICs to have MAC addresses in order to ensure the order
-			// of the retrieved MAC addresses matches the order of the device
-			// specs, and not the propery change order.
-			_, macToDeviceIndex, deviceToMacIndex, err := getMacAddresses(ctx)
-			if err != nil {
-				return nil, nil, errors.Wrapf(err, "failed to get mac addresses for vm %s", ctx)
-			}
+// auditServer returns the vCenter the operations of the VM are made against.
+func auditServer(ctx *context.VMContext) string {
+	if ctx.Session != nil {
+		return ctx.Session.Client.URL().Host
+	}
+	return ctx.VSphereVM.Spec.Server
+}
 
-			// Wait for the IP addresses to show up for the VM.
-			chanIPAddresses, chanErrs := waitForIPAddresses(ctx, macToDeviceIndex, deviceToMacIndex)
-
-			// Trigger a reconcile every time a new IP is discovered.
-			chanOfLoggerKeysAndValues := make(chan []interface{})
-			go func() {
-				for ip := range chanIPAddresses {
-					chanOfLoggerKeysAndValues <- []interface{}{
-						"reason", "network",
-						"ipAddress", ip,
-					}
-				}
-			}()
-			return chanOfLoggerKeysAndValues, chanErrs, nil
-		})
+// invalidateInventoryCache drops the inventory objects cached by the session
+// of the VM, which may have been moved, renamed or removed when an operation
+// using them failed.
+func invalidateInventoryCache(ctx *context.VMContext) {
+	if ctx.Session != nil {
+		ctx.Session.CachedFinder.Invalidate()
+	}
+}
+
+// reportTaskProgress reports the progress of the in-flight task or its error
+// with the TaskProgress condition and an event whenever it changes.
+func reportTaskProgress(ctx *context.VMContext, info types.TaskInfo) {
+	message := task.Message(info)
+	reason, severity := infrav1.TaskInProgressReason, clusterv1.ConditionSeverityInfo
+	if info.State == types.TaskInfoStateError {
+		reason, severity = infrav1.TaskFailedReason, clusterv1.ConditionSeverityWarning
+	}
+	if conditions.GetReason(ctx.VSphereVM, infrav1.TaskProgressCondition) == reason &&
+		conditions.GetMessage(ctx.VSphereVM, infrav1.TaskProgressCondition) == message {
+		return
+	}
+
+	conditions.MarkFalse(ctx.VSphereVM, infrav1.TaskProgressCondition, reason, severity, message)
+	if info.State == types.TaskInfoStateError {
+		ctx.Recorder.Warnf(ctx.VSphereVM, reason, message)
+	} else {
+		ctx.Recorder.Eventf(ctx.VSphereVM, reason, message)
+	}
+}
+
+// reportCloneTimeout marks the clone of the VM as timed out once its task has
+// not completed within the clone timeout. The task is still tracked, since
+// vCenter may complete it nonetheless.
+func reportCloneTimeout(ctx *context.VMContext, info types.TaskInfo) {
+	if info.DescriptionId != cloneTaskDescriptionID || ctx.CloneTimeout <= 0 || time.Since(info.QueueTime) < ctx.CloneTimeout {
+		return
+	}
+	message := fmt.Sprintf("clone of template %s has not completed within %s", ctx.VSphereVM.Spec.Template, ctx.CloneTimeout)
+	conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.CloneTimedOutReason, clusterv1.ConditionSeverityWarning, message)
+	markPhaseFailed(ctx, infrav1.CloneCompletedCondition, infrav1.CloneTimedOutReason, clusterv1.ConditionSeverityWarning, message)
 }
 
+// reconcileVSphereVMOnVMChange triggers a reconcile of the VSphereVM every
+// time the power state, the guest network, the host or the datastore of its VM
+// changes, e.g. once the VM is powered on, whenever it reports new IP addresses
+// and whenever it is migrated.
+func reconcileVSphereVMOnVMChange(ctx *virtualMachineContext) error {
+	obj := ctx.VSphereVM.DeepCopy()
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	eventChannel := ctx.GetGenericEventChannelFor(gvk)
+	logger := ctx.Logger
+	key, ref := vmStateKey(ctx), ctx.Ref
+
+	return ctx.Session.WatchVM(ctx, ctx.Ref, func() {
+		// The state of the VM collected with the VMs of its cluster is stale.
+		vmStates.invalidate(key, ref)
+		// The handler must not block the session's subscription, hence the
+		// event is dropped when the buffer of the channel is full, and the
+		// VSphereVM is reconciled again by its next resync.
+		select {
+		case eventChannel <- event.GenericEvent{Object: obj}:
+			logger.Info("triggering GenericEvent", "reason", "vm-change")
+		default:
+			logger.V(4).Info("dropping GenericEvent", "reason", "vm-change")
+		}
+	})
+}
+
+// reconcileVSphereVMOnTaskCompletion triggers a reconcile of the VSphereVM
+// once its in-flight task succeeds. The task is watched by the subscription
+// of the session, which replaces the handler of a task watched again, so a
+// task has a single handler however many times the VSphereVM is reconciled
+// while it runs.
 func reconcileVSphereVMOnTaskCompletion(ctx *context.VMContext) {
-	task := getTask(ctx)
-	if task == nil {
+	if ctx.VSphereVM.Status.TaskRef == "" {
 		ctx.Logger.V(4).Info(
 			"skipping reconcile VSphereVM on task completion",
 			"reason", "no-task")
 		return
 	}
-	taskRef := task.Reference()
-	taskHelper := object.NewTask(ctx.Session.Client.Client, taskRef)
-
-	ctx.Logger.Info(
-		"enqueuing reconcile request on task completion",
-		"task-ref", taskRef,
-		"task-name", task.Info.Name,
-		"task-entity-name", task.Info.EntityName,
-		"task-description-id", task.Info.DescriptionId)
-
-	reconcileVSphereVMOnFuncCompletion(ctx, func() ([]interface{}, error) {
-		taskInfo, err := taskHelper.WaitForResult(ctx)
-
-		// An error is only returned if the process of waiting for the result
-		// failed, *not* if the task itself failed.
-		if err != nil && taskInfo == nil {
-			return nil, err
-		}
+	obj := ctx.VSphereVM.DeepCopy()
+	eventChannel := ctx.GetGenericEventChannelFor(obj.GetObjectKind().GroupVersionKind())
+	logger := ctx.Logger
+	taskRef := types.ManagedObjectReference{
+		Type:  morefTypeTask,
+		Value: ctx.VSphereVM.Status.TaskRef,
+	}
+
+	logger.Info("enqueuing reconcile request on task completion", "task-ref", taskRef)
+	if err := ctx.Session.WatchTask(ctx, taskRef, func(state types.TaskInfoState) {
 		// do not queue in the event channel when task fails as we don't
 		// want to retry right away
-		if taskInfo.State == types.TaskInfoStateError {
-			ctx.Logger.Info("async task wait failed")
-			return nil, errors.Errorf("task failed")
+		if state == types.TaskInfoStateError {
+			logger.Info("task failed", "task-ref", taskRef)
+			return
+		}
+		// The handler must not block the session's subscription.
+		select {
+		case eventChannel <- event.GenericEvent{Object: obj}:
+			logger.Info("triggering GenericEvent", "reason", "task", "task-ref", taskRef, "task-state", state)
+		default:
+			logger.V(4).Info("dropping GenericEvent", "reason", "task", "task-ref", taskRef)
 		}
+	}); err != nil {
+		logger.Error(err, "failed to watch task", "task-ref", taskRef)
+	}
+}
 
-		return []interface{}{
-			"reason", "task",
-			"task-ref", taskRef,
-			"task-name", taskInfo.Name,
-			"task-entity-name", taskInfo.EntityName,
-			"task-state", taskInfo.State,
-			"task-description-id", taskInfo.DescriptionId,
-		}, nil
+// reconcileVSphereVMOnTaskProgress triggers a reconcile of the VSphereVM
+// every time the progress of its in-flight task advances, so the progress is
+// reported while the task runs.
+func reconcileVSphereVMOnTaskProgress(ctx *context.VMContext) {
+	if ctx.VSphereVM.Status.TaskRef == "" {
+		return
+	}
+	taskRef := types.ManagedObjectReference{
+		Type:  morefTypeTask,
+		Value: ctx.VSphereVM.Status.TaskRef,
+	}
+	progress := task.Watch(ctx, ctx.Session.Client.Client, taskRef, taskProgressStep)
+	if progress == nil {
+		ctx.Logger.V(4).Info(
+			"skipping reconcile VSphereVM on task progress",
+			"reason", "already-watched")
+		return
+	}
+
+	reconcileVSphereVMOnChannel(ctx, func() (<-chan []interface{}, <-chan error, error) {
+		chanOfLoggerKeysAndValues := make(chan []interface{})
+		go func() {
+			defer close(chanOfLoggerKeysAndValues)
+			for info := range progress {
+				chanOfLoggerKeysAndValues <- []interface{}{
+					"reason", "task-progress",
+					"task-ref", taskRef,
+					"task-description-id", info.DescriptionId,
+					"task-progress", info.Progress,
+				}
+			}
+		}()
+		return chanOfLoggerKeysAndValues, nil, nil
 	})
 }
 
-func reconcileVSphereVMOnFuncCompletion(ctx *context.VMContext, waitFn func() (loggerKeysAndValues []interface{}, _ error)) {
+// reconcileVSphereVMOnCompletion returns a function triggering a reconcile
+// event for the VSphereVM once e.g. a background operation completes.
+func reconcileVSphereVMOnCompletion(ctx *context.VMContext, reason string) func() {
 	obj := ctx.VSphereVM.DeepCopy()
-	gvk := obj.GetObjectKind().GroupVersionKind()
-
-	// Wait on the function to complete in a background goroutine.
-	go func() {
-		loggerKeysAndValues, err := waitFn()
-		if err != nil {
-			ctx.Logger.Error(err, "failed to wait on func")
-			return
+	eventChannel := ctx.GetGenericEventChannelFor(obj.GetObjectKind().GroupVersionKind())
+	logger := ctx.Logger
+
+	return func() {
+		select {
+		case eventChannel <- event.GenericEvent{Object: obj}:
+			logger.Info("triggering GenericEvent", "reason", reason)
+		default:
+			logger.V(4).Info("dropping GenericEvent", "reason", reason)
 		}
-
-		// Once the task has completed (successfully or otherwise), trigger
-		// a reconcile event for the associated resource by sending a
-		// GenericEvent into the event channel for the resource type.
-		ctx.Logger.Info("triggering GenericEvent", loggerKeysAndValues...)
-		eventChannel := ctx.GetGenericEventChannelFor(gvk)
-		eventChannel <- event.GenericEvent{
-			Object: obj,
-		}
-	}()
+	}
 }
 
 func reconcileVSphereVMOnChannel(ctx *context.VMContext, waitFn func() (<-chan []interface{}, <-chan error, error)) {
 	obj := ctx.VSphereVM.DeepCopy()
-	gvk := obj.GetObjectKind().GroupVersionKind()
+	eventChannel := ctx.GetGenericEventChannelFor(obj.GetObjectKind().GroupVersionKind())
 
 	// Send a generic event for every set of logger keys/values received
 	// on the channel.
@@ -312,16 +474,15 @@ func reconcileVSphereVMOnChannel(ctx *context.VMContext, waitFn func() (<-chan [
 				if loggerKeysAndValues == nil {
 					return
 				}
-				go func() {
-					// Trigger a reconcile event for the associated resource by
-					// sending a GenericEvent into the event channel for the resource
-					// type.
+				// Trigger a reconcile event for the associated resource by
+				// sending a GenericEvent into the event channel for the
+				// resource type, unless its buffer is full.
+				select {
+				case eventChannel <- event.GenericEvent{Object: obj}:
 					ctx.Logger.Info("triggering GenericEvent", loggerKeysAndValues...)
-					eventChannel := ctx.GetGenericEventChannelFor(gvk)
-					eventChannel <- event.GenericEvent{
-						Object: obj,
-					}
-				}()
+				default:
+					ctx.Logger.V(4).Info("dropping GenericEvent", loggerKeysAndValues...)
+				}
 			case err := <-chanErrs:
 				if err != nil {
 					ctx.Logger.Error(err, "error occurred while waiting to trigger a generic event")
@@ -334,248 +495,40 @@ func reconcileVSphereVMOnChannel(ctx *context.VMContext, waitFn func() (<-chan [
 	}()
 }
 
-// waitForMacAddresses waits for all configured network devices to have
-// valid MAC addresses.
-func waitForMacAddresses(ctx *virtualMachineContext) error {
-	return property.Wait(
-		ctx, property.DefaultCollector(ctx.Session.Client.Client),
-		ctx.Obj.Reference(), []string{"config.hardware.device"},
-		func(propertyChanges []types.PropertyChange) bool {
-			for _, propChange := range propertyChanges {
-				if propChange.Op != types.PropertyChangeOpAssign {
-					continue
-				}
-				deviceList := object.VirtualDeviceList(propChange.Val.(types.ArrayOfVirtualDevice).VirtualDevice)
-				for _, dev := range deviceList {
-					if nic, ok := dev.(types.BaseVirtualEthernetCard); ok {
-						mac := nic.GetVirtualEthernetCard().MacAddress
-						if mac == "" {
-							return false
-						}
-					}
-				}
+// countMissingPCIDevices returns the number of expected PCI devices which do
+// not match any of the attached PCI passthrough devices.
+func countMissingPCIDevices(expected []infrav1.PCIDeviceSpec, attached object.VirtualDeviceList) int {
+	used := make([]bool, len(attached))
+	missing := 0
+	for _, pciDevice := range expected {
+		found := false
+		for i, device := range attached {
+			if !used[i] && pciDeviceMatches(pciDevice, device) {
+				used[i] = true
+				found = true
+				break
 			}
-			return true
-		})
-}
-
-// getMacAddresses gets the MAC addresses for all network devices.
-// This happens separately from waitForMacAddresses to ensure returned order of
-// devices matches the spec and not order in which the property changes were
-// noticed.
-func getMacAddresses(ctx *virtualMachineContext) ([]string, map[string]int, map[int]string, error) {
-	var (
-		vm                   mo.VirtualMachine
-		macAddresses         []string
-		macToDeviceSpecIndex = map[string]int{}
-		deviceSpecIndexToMac = map[int]string{}
-	)
-	if err := ctx.Obj.Properties(ctx, ctx.Obj.Reference(), []string{"config.hardware.device"}, &vm); err != nil {
-		return nil, nil, nil, err
-	}
-	i := 0
-	for _, device := range vm.Config.Hardware.Device {
-		if nic, ok := device.(types.BaseVirtualEthernetCard); ok {
-			mac := nic.GetVirtualEthernetCard().MacAddress
-			macAddresses = append(macAddresses, mac)
-			macToDeviceSpecIndex[mac] = i
-			deviceSpecIndexToMac[i] = mac
-			i++
+		}
+		if !found {
+			missing++
 		}
 	}
-	return macAddresses, macToDeviceSpecIndex, deviceSpecIndexToMac, nil
+	return missing
 }
 
-// waitForIPAddresses waits for all network devices that should be getting an
-// IP address to have an IP address. This is any network device that specifies a
-// network name and DHCP for v4 or v6 or one or more static IP addresses.
-// The gocyclo detector is disabled for this function as it is difficult to
-// rewrite much simpler due to the maps used to track state and the lambdas
-// that use the maps.
-// nolint:gocyclo,gocognit
-func waitForIPAddresses(
-	ctx *virtualMachineContext,
-	macToDeviceIndex map[string]int,
-	deviceToMacIndex map[int]string) (<-chan string, <-chan error) {
-	var (
-		chanErrs          = make(chan error)
-		chanIPAddresses   = make(chan string)
-		macToHasIPv4Lease = map[string]struct{}{}
-		macToHasIPv6Lease = map[string]struct{}{}
-		macToSkipped      = map[string]map[string]struct{}{}
-		macToHasStaticIP  = map[string]map[string]struct{}{}
-		propCollector     = property.DefaultCollector(ctx.Session.Client.Client)
-	)
-
-	// Initialize the nested maps early.
-	for mac := range macToDeviceIndex {
-		macToSkipped[mac] = map[string]struct{}{}
-		macToHasStaticIP[mac] = map[string]struct{}{}
-	}
-
-	onPropertyChange := func(propertyChanges []types.PropertyChange) bool {
-		for _, propChange := range propertyChanges {
-			if propChange.Op != types.PropertyChangeOpAssign {
-				continue
-			}
-			nics := propChange.Val.(types.ArrayOfGuestNicInfo).GuestNicInfo
-			for _, nic := range nics {
-				mac := nic.MacAddress
-				if mac == "" || nic.IpConfig == nil {
-					continue
-				}
-				// Ignore any that don't correspond to a network
-				// device spec.
-				deviceSpecIndex, ok := macToDeviceIndex[mac]
-				if !ok {
-					chanErrs <- errors.Errorf("unknown device spec index for mac %s while waiting for ip addresses for vm %s", mac, ctx)
-					// Return true to stop the property collector from waiting
-					// on any more changes.
-					return true
-				}
-				if deviceSpecIndex < 0 || deviceSpecIndex >= len(ctx.VSphereVM.Spec.Network.Devices) {
-					chanErrs <- errors.Errorf("invalid device spec index %d for mac %s while waiting for ip addresses for vm %s", deviceSpecIndex, mac, ctx)
-					// Return true to stop the property collector from waiting
-					// on any more changes.
-					return true
-				}
-
-				// Get the network device spec that corresponds to the MAC.
-				deviceSpec := ctx.VSphereVM.Spec.Network.Devices[deviceSpecIndex]
-
-				// Look at each IP and determine whether a reconcile has
-				// been triggered for the IP.
-				for _, discoveredIPInfo := range nic.IpConfig.IpAddress {
-					discoveredIP := discoveredIPInfo.IpAddress
-
-					// Ignore link-local addresses.
-					if err := net.ErrOnLocalOnlyIPAddr(discoveredIP); err != nil {
-						if _, ok := macToSkipped[mac][discoveredIP]; !ok {
-							ctx.Logger.Info("ignoring IP address", "reason", err.Error())
-							macToSkipped[mac][discoveredIP] = struct{}{}
-						}
-						continue
-					}
-
-					// Check to see if the IP is in the list of the device
-					// spec's static IP addresses.
-					isStatic := false
-					for _, specIP := range deviceSpec.IPAddrs {
-						// The static IP assigned to the VM is required in the CIDR format
-						ip, _, _ := gonet.ParseCIDR(specIP)
-						if discoveredIP == ip.String() {
-							isStatic = true
-							break
-						}
-					}
-
-					// If it's a static IP then check to see if the IP has
-					// triggered a reconcile yet.
-					switch {
-					case isStatic:
-						if _, ok := macToHasStaticIP[mac][discoveredIP]; !ok {
-							// No reconcile yet. Record the IP send it to the
-							// channel.
-							ctx.Logger.Info(
-								"discovered IP address",
-								"addressType", "static",
-								"addressValue", discoveredIP)
-							macToHasStaticIP[mac][discoveredIP] = struct{}{}
-							chanIPAddresses <- discoveredIP
-						}
-					case gonet.ParseIP(discoveredIP).To4() != nil:
-						// An IPv4 address...
-						if deviceSpec.DHCP4 {
-							// Has an IPv4 lease been discovered yet?
-							if _, ok := macToHasIPv4Lease[mac]; !ok {
-								ctx.Logger.Info(
-									"discovered IP address",
-									"addressType", "dhcp4",
-									"addressValue", discoveredIP)
-								macToHasIPv4Lease[mac] = struct{}{}
-								chanIPAddresses <- discoveredIP
-							}
-						}
-					default:
-						// An IPv6 address..
-						if deviceSpec.DHCP6 {
-							// Has an IPv6 lease been discovered yet?
-							if _, ok := macToHasIPv6Lease[mac]; !ok {
-								ctx.Logger.Info(
-									"discovered IP address",
-									"addressType", "dhcp6",
-									"addressValue", discoveredIP)
-								macToHasIPv6Lease[mac] = struct{}{}
-								chanIPAddresses <- discoveredIP
-							}
-						}
-					}
-				}
-			}
+func pciDeviceMatches(pciDevice infrav1.PCIDeviceSpec, device types.BaseVirtualDevice) bool {
+	switch backing := device.GetVirtualDevice().Backing.(type) {
+	case *types.VirtualPCIPassthroughVmiopBackingInfo:
+		return pciDevice.VGPUProfile != "" && backing.Vgpu == pciDevice.VGPUProfile
+	case *types.VirtualPCIPassthroughDynamicBackingInfo:
+		if pciDevice.DeviceID == nil || pciDevice.VendorID == nil {
+			return false
 		}
-
-		// Determine whether or not the wait operation is over by whether
-		// or not the VM has all of the requested IP addresses.
-		for i, deviceSpec := range ctx.VSphereVM.Spec.Network.Devices {
-			mac, ok := deviceToMacIndex[i]
-			if !ok {
-				chanErrs <- errors.Errorf("invalid mac index %d waiting for ip addresses for vm %s", i, ctx)
-
-				// Return true to stop the property collector from waiting
-				// on any more changes.
+		for _, allowed := range backing.AllowedDevice {
+			if allowed.DeviceId == *pciDevice.DeviceID && allowed.VendorId == *pciDevice.VendorID {
 				return true
 			}
-			// If the device spec requires DHCP4 then the Wait is not
-			// over if there is no IPv4 lease.
-			if deviceSpec.DHCP4 {
-				if _, ok := macToHasIPv4Lease[mac]; !ok {
-					ctx.Logger.Info(
-						"the VM is missing the requested IP address",
-						"addressType", "dhcp4")
-					return false
-				}
-			}
-			// If the device spec requires DHCP6 then the Wait is not
-			// over if there is no IPv4 lease.
-			if deviceSpec.DHCP6 {
-				if _, ok := macToHasIPv6Lease[mac]; !ok {
-					ctx.Logger.Info(
-						"the VM is missing the requested IP address",
-						"addressType", "dhcp6")
-					return false
-				}
-			}
-			// If the device spec requires static IP addresses, the wait
-			// is not over if the device lacks one of those addresses.
-			for _, specIP := range deviceSpec.IPAddrs {
-				ip, _, _ := gonet.ParseCIDR(specIP)
-				if _, ok := macToHasStaticIP[mac][ip.String()]; !ok {
-					ctx.Logger.Info(
-						"the VM is missing the requested IP address",
-						"addressType", "static",
-						"addressValue", specIP)
-					return false
-				}
-			}
 		}
-
-		ctx.Logger.Info("the VM has all of the requested IP addresses")
-		return true
 	}
-
-	// The wait function will not return true until all the VM's
-	// network devices have IP assignments that match the requested
-	// network device specs. However, every time a new IP is discovered,
-	// a reconcile request will be triggered for the VSphereVM.
-	go func() {
-		if err := property.Wait(
-			ctx, propCollector, ctx.Obj.Reference(),
-			[]string{"guest.net"}, onPropertyChange); err != nil {
-			chanErrs <- errors.Wrapf(err, "failed to wait for ip addresses for vm %s", ctx)
-		}
-		close(chanIPAddresses)
-		close(chanErrs)
-	}()
-
-	return chanIPAddresses, chanErrs
+	return false
 }
@@ -17,19 +17,27 @@ limitations under the License.
 package govmomi
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/go-logr/logr"
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
 	"sigs.k8s.io/cluster-api/util/conditions"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/audit"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
 )
 
 func Test_ShouldRetryTask(t *testing.T) {
@@ -65,7 +73,8 @@ func Test_ShouldRetryTask(t *testing.T) {
 		g := NewWithT(t)
 
 		vmCtx := &context.VMContext{
-			Logger: logr.Discard(),
+			ControllerContext: fake.NewControllerContext(fake.NewControllerManagerContext()),
+			Logger:            logr.Discard(),
 			VSphereVM: &infrav1.VSphereVM{Status: infrav1.VSphereVMStatus{
 				TaskRef:    "task-123",
 				RetryAfter: metav1.Time{Time: time.Now().Add(-1 * time.Minute)},
@@ -89,9 +98,11 @@ func Test_ShouldRetryTask(t *testing.T) {
 					if tt.isRefEmpty {
 						g.Expect(reconciled).To(BeFalse())
 						g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(BeEmpty())
+						g.Expect(conditions.IsTrue(vmCtx.VSphereVM, infrav1.TaskProgressCondition)).To(BeTrue())
 					} else {
 						g.Expect(reconciled).To(BeTrue())
 						g.Expect(vmCtx.VSphereVM.Status.TaskRef).NotTo(BeEmpty())
+						g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.TaskProgressCondition)).To(Equal(infrav1.TaskInProgressReason))
 					}
 				})
 			}
@@ -112,7 +123,8 @@ func Test_ShouldRetryTask(t *testing.T) {
 	t.Run("when failed task was previously not checked", func(t *testing.T) {
 		g := NewWithT(t)
 		vmCtx := &context.VMContext{
-			Logger: logr.Discard(),
+			ControllerContext: fake.NewControllerContext(fake.NewControllerManagerContext()),
+			Logger:            logr.Discard(),
 			VSphereVM: &infrav1.VSphereVM{Status: infrav1.VSphereVMStatus{
 				// RetryAfter is not set since this is the first reconcile
 				TaskRef: "task-123",
@@ -126,6 +138,209 @@ func Test_ShouldRetryTask(t *testing.T) {
 		g.Expect(conditions.IsFalse(vmCtx.VSphereVM, infrav1.VMProvisionedCondition))
 		g.Expect(vmCtx.VSphereVM.Status.RetryAfter.Unix()).To(BeNumerically("<=", metav1.Now().Add(1*time.Minute).Unix()))
 	})
+
+	t.Run("when clone task requesting PCI devices failed", func(t *testing.T) {
+		g := NewWithT(t)
+		vmCtx := &context.VMContext{
+			ControllerContext: fake.NewControllerContext(fake.NewControllerManagerContext()),
+			Logger:            logr.Discard(),
+			VSphereVM: &infrav1.VSphereVM{
+				Spec: infrav1.VSphereVMSpec{
+					VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+						PciDevices: []infrav1.PCIDeviceSpec{{VGPUProfile: "grid_t4-4q"}},
+					},
+				},
+				Status: infrav1.VSphereVMStatus{TaskRef: "task-123"},
+			},
+		}
+		task := baseTask(types.TaskInfoStateError, "clone failed")
+		task.Info.DescriptionId = cloneTaskDescriptionID
+		task.Info.Error = &types.LocalizedMethodFault{LocalizedMessage: "no host is compatible"}
+
+		_, err := checkAndRetryTask(vmCtx, &task)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.TaskProgressCondition)).To(Equal(infrav1.TaskFailedReason))
+		g.Expect(conditions.GetMessage(vmCtx.VSphereVM, infrav1.TaskProgressCondition)).To(ContainSubstring("no host is compatible"))
+		g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.PCIDevicesAttachedCondition)).To(Equal(infrav1.PCIDevicesAttachFailedReason))
+		g.Expect(conditions.GetMessage(vmCtx.VSphereVM, infrav1.PCIDevicesAttachedCondition)).To(Equal("no host is compatible"))
+	})
+
+	t.Run("when task failed for lack of resources", func(t *testing.T) {
+		g := NewWithT(t)
+		vmCtx := &context.VMContext{
+			ControllerContext: fake.NewControllerContext(fake.NewControllerManagerContext()),
+			Logger:            logr.Discard(),
+			VSphereVM:         &infrav1.VSphereVM{Status: infrav1.VSphereVMStatus{TaskRef: "task-123"}},
+		}
+		task := baseTask(types.TaskInfoStateError, "clone failed")
+		task.Info.Error = &types.LocalizedMethodFault{Fault: &types.InsufficientMemoryResourcesFault{}, LocalizedMessage: "insufficient memory"}
+
+		_, err := checkAndRetryTask(vmCtx, &task)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.VMProvisionedCondition)).To(Equal(infrav1.InsufficientResourcesReason))
+		g.Expect(vmCtx.VSphereVM.Status.RetryAfter.Time).To(BeTemporally(">", time.Now().Add(taskRetryAfter)))
+		g.Expect(vmCtx.VSphereVM.Status.FailureReason).To(BeNil())
+	})
+
+	t.Run("when task failed with an invalid configuration", func(t *testing.T) {
+		g := NewWithT(t)
+		vmCtx := &context.VMContext{
+			ControllerContext: fake.NewControllerContext(fake.NewControllerManagerContext()),
+			Logger:            logr.Discard(),
+			VSphereVM:         &infrav1.VSphereVM{Status: infrav1.VSphereVMStatus{TaskRef: "task-123"}},
+		}
+		task := baseTask(types.TaskInfoStateError, "clone failed")
+		task.Info.Error = &types.LocalizedMethodFault{Fault: &types.InvalidDeviceSpec{}, LocalizedMessage: "invalid device configuration"}
+
+		_, err := checkAndRetryTask(vmCtx, &task)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(*conditions.GetSeverity(vmCtx.VSphereVM, infrav1.VMProvisionedCondition)).To(Equal(clusterv1.ConditionSeverityError))
+		g.Expect(*vmCtx.VSphereVM.Status.FailureReason).To(Equal(capierrors.CreateMachineError))
+		g.Expect(*vmCtx.VSphereVM.Status.FailureMessage).To(ContainSubstring("invalid device configuration"))
+	})
+
+	t.Run("when clone task runs longer than the clone timeout", func(t *testing.T) {
+		g := NewWithT(t)
+		vmCtx := &context.VMContext{
+			ControllerContext: fake.NewControllerContext(fake.NewControllerManagerContext()),
+			Logger:            logr.Discard(),
+			VSphereVM: &infrav1.VSphereVM{
+				Spec: infrav1.VSphereVMSpec{
+					VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2004"},
+				},
+				Status: infrav1.VSphereVMStatus{TaskRef: "task-123"},
+			},
+		}
+		vmCtx.CloneTimeout = 30 * time.Minute
+		task := baseTask(types.TaskInfoStateRunning, "")
+		task.Info.DescriptionId = cloneTaskDescriptionID
+		task.Info.QueueTime = time.Now().Add(-10 * time.Minute)
+
+		reconciled, err := checkAndRetryTask(vmCtx, &task)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(reconciled).To(BeTrue())
+		g.Expect(conditions.Has(vmCtx.VSphereVM, infrav1.CloneCompletedCondition)).To(BeFalse())
+
+		// The timed out task is still tracked.
+		task.Info.QueueTime = time.Now().Add(-time.Hour)
+		reconciled, err = checkAndRetryTask(vmCtx, &task)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(reconciled).To(BeTrue())
+		g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(Equal("task-123"))
+		g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.VMProvisionedCondition)).To(Equal(infrav1.CloneTimedOutReason))
+		g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.CloneCompletedCondition)).To(Equal(infrav1.CloneTimedOutReason))
+		g.Expect(conditions.GetMessage(vmCtx.VSphereVM, infrav1.CloneCompletedCondition)).To(Equal("clone of template ubuntu-2004 has not completed within 30m0s"))
+	})
+}
+
+func Test_AuditTaskOutcome(t *testing.T) {
+	g := NewWithT(t)
+	out := &bytes.Buffer{}
+	audit.SetDefault(audit.New(out, nil))
+	defer audit.SetDefault(nil)
+
+	vmCtx := &context.VMContext{
+		ControllerContext: fake.NewControllerContext(fake.NewControllerManagerContext()),
+		Logger:            logr.Discard(),
+		VSphereVM: &infrav1.VSphereVM{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "vm"},
+			Spec: infrav1.VSphereVMSpec{
+				VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{Server: "vcenter.example.com"},
+			},
+			Status: infrav1.VSphereVMStatus{TaskRef: "task-123"},
+		},
+	}
+	task := baseTask(types.TaskInfoStateError, "")
+	task.Self.Value = "task-123"
+	task.Info.Name = "PowerOnVM_Task"
+	task.Info.Entity = &types.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-42"}
+	task.Info.Error = &types.LocalizedMethodFault{LocalizedMessage: "no host"}
+
+	// The outcome of a failed task is recorded once, when it is first seen.
+	_, err := checkAndRetryTask(vmCtx, &task)
+	g.Expect(err).NotTo(HaveOccurred())
+	vmCtx.VSphereVM.Status.RetryAfter = metav1.Time{Time: time.Now().Add(-time.Minute)}
+	_, err = checkAndRetryTask(vmCtx, &task)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(BeEmpty())
+
+	var records []audit.Record
+	decoder := json.NewDecoder(out)
+	for decoder.More() {
+		var r audit.Record
+		g.Expect(decoder.Decode(&r)).To(Succeed())
+		records = append(records, r)
+	}
+	g.Expect(records).To(HaveLen(1))
+	g.Expect(records[0].Server).To(Equal("vcenter.example.com"))
+	g.Expect(records[0].Operation).To(Equal("PowerOnVM_Task"))
+	g.Expect(records[0].Target).To(Equal("VirtualMachine:vm-42"))
+	g.Expect(records[0].TaskID).To(Equal("task-123"))
+	g.Expect(records[0].Outcome).To(Equal(audit.Failed))
+	g.Expect(records[0].Error).To(Equal("no host"))
+	g.Expect(records[0].Initiator).To(Equal(&audit.ObjectReference{Kind: "VSphereVM", Namespace: "ns", Name: "vm"}))
+}
+
+func Test_AuditTaskLost(t *testing.T) {
+	g := NewWithT(t)
+	out := &bytes.Buffer{}
+	audit.SetDefault(audit.New(out, nil))
+	defer audit.SetDefault(nil)
+
+	vmCtx := &context.VMContext{
+		ControllerContext: fake.NewControllerContext(fake.NewControllerManagerContext()),
+		Logger:            logr.Discard(),
+		VSphereVM: &infrav1.VSphereVM{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "vm"},
+			Spec: infrav1.VSphereVMSpec{
+				VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{Server: "vcenter.example.com"},
+			},
+			Status: infrav1.VSphereVMStatus{TaskRef: "task-123"},
+		},
+	}
+
+	// A task which can no longer be retrieved is not tracked anymore and its
+	// outcome is recorded as a failure.
+	auditTaskLost(vmCtx, errors.New("unable to retrieve task task-123"))
+	_, err := checkAndRetryTask(vmCtx, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(BeEmpty())
+
+	var r audit.Record
+	g.Expect(json.NewDecoder(out).Decode(&r)).To(Succeed())
+	g.Expect(r.Server).To(Equal("vcenter.example.com"))
+	g.Expect(r.TaskID).To(Equal("task-123"))
+	g.Expect(r.Outcome).To(Equal(audit.Failed))
+	g.Expect(r.Error).To(Equal("unable to retrieve task task-123"))
+	g.Expect(r.Initiator).To(Equal(&audit.ObjectReference{Kind: "VSphereVM", Namespace: "ns", Name: "vm"}))
+}
+
+func Test_CountMissingPCIDevices(t *testing.T) {
+	g := NewWithT(t)
+	deviceID, vendorID := int32(4318), int32(7864)
+	otherDeviceID := int32(1234)
+
+	attached := object.VirtualDeviceList{
+		&types.VirtualPCIPassthrough{VirtualDevice: types.VirtualDevice{
+			Backing: &types.VirtualPCIPassthroughDynamicBackingInfo{
+				AllowedDevice: []types.VirtualPCIPassthroughAllowedDevice{{DeviceId: deviceID, VendorId: vendorID}},
+			},
+		}},
+		&types.VirtualPCIPassthrough{VirtualDevice: types.VirtualDevice{
+			Backing: &types.VirtualPCIPassthroughVmiopBackingInfo{Vgpu: "grid_t4-4q"},
+		}},
+	}
+
+	g.Expect(countMissingPCIDevices([]infrav1.PCIDeviceSpec{
+		{DeviceID: &deviceID, VendorID: &vendorID},
+		{VGPUProfile: "grid_t4-4q"},
+	}, attached)).To(Equal(0))
+	g.Expect(countMissingPCIDevices([]infrav1.PCIDeviceSpec{
+		{DeviceID: &deviceID, VendorID: &vendorID},
+		{DeviceID: &deviceID, VendorID: &vendorID},
+		{DeviceID: &otherDeviceID, VendorID: &vendorID},
+		{VGPUProfile: "grid_t4-8q"},
+	}, attached)).To(Equal(3))
 }
 
 func baseTask(state types.TaskInfoState, errorDescription string) mo.Task {
@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/utils/pointer"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// ResourceAllocation returns the CPU and memory allocation of a VM with the
+// resource allocation. Only the values set in the resource allocation are set
+// in the returned allocations, so the other values of the VM are left
+// unchanged when it is cloned or reconfigured. A nil allocation is returned
+// for a resource without any value set.
+func ResourceAllocation(allocation *infrav1.ResourceAllocation) (cpu, memory *types.ResourceAllocationInfo) {
+	if allocation == nil {
+		return nil, nil
+	}
+	cpu = resourceAllocationInfo(allocation.CPUReservationMHz, allocation.CPULimitMHz, allocation.CPUShares)
+	memory = resourceAllocationInfo(allocation.MemoryReservationMiB, allocation.MemoryLimitMiB, allocation.MemoryShares)
+	return cpu, memory
+}
+
+func resourceAllocationInfo(reservation, limit *int64, shares infrav1.SharesLevel) *types.ResourceAllocationInfo {
+	if reservation == nil && limit == nil && shares == "" {
+		return nil
+	}
+	info := &types.ResourceAllocationInfo{}
+	if reservation != nil {
+		info.Reservation = types.NewInt64(*reservation)
+	}
+	if limit != nil {
+		info.Limit = types.NewInt64(*limit)
+	}
+	if shares != "" {
+		info.Shares = &types.SharesInfo{Level: types.SharesLevel(shares)}
+	}
+	return info
+}
+
+// setLatencyTuning sets the latency sensitivity, the NUMA node affinity and
+// the CPU affinity of the VM spec on the config spec of the clone. The high
+// latency sensitivity requires all the memory of the VM to be reserved.
+func setLatencyTuning(spec *infrav1.VirtualMachineCloneSpec, config *types.VirtualMachineConfigSpec) {
+	if spec.LatencySensitivity != "" {
+		config.LatencySensitivity = &types.LatencySensitivity{
+			Level: types.LatencySensitivitySensitivityLevel(spec.LatencySensitivity),
+		}
+		if spec.LatencySensitivity == infrav1.LatencySensitivityHigh {
+			config.MemoryReservationLockedToMax = pointer.Bool(true)
+		}
+	}
+	if len(spec.NumaNodeAffinity) > 0 {
+		nodes := make([]string, len(spec.NumaNodeAffinity))
+		for i, node := range spec.NumaNodeAffinity {
+			nodes[i] = strconv.Itoa(int(node))
+		}
+		config.ExtraConfig = append(config.ExtraConfig, &types.OptionValue{
+			Key:   "numa.nodeAffinity",
+			Value: strings.Join(nodes, ","),
+		})
+	}
+	if len(spec.CPUPinning) > 0 {
+		config.CpuAffinity = &types.VirtualMachineAffinityInfo{AffinitySet: spec.CPUPinning}
+	}
+}
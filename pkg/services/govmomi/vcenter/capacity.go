@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+)
+
+// checkCapacity returns the reasons why the VM cloned with the spec would not
+// fit in the free capacity of the hosts of the compute resource of its
+// resource pool and of its datastores, keeping the headroom of the
+// controller free. The check is disabled with a negative headroom.
+func checkCapacity(ctx *context.VMContext, pool *object.ResourcePool, spec types.VirtualMachineCloneSpec, datastoreRef types.ManagedObjectReference) ([]string, error) {
+	headroom := int64(ctx.CapacityHeadroomPercent)
+	if headroom < 0 {
+		return nil, nil
+	}
+
+	pc := property.DefaultCollector(ctx.Session.Client.Client)
+	var failures []string
+	hostFailure, err := checkHostCapacity(ctx, pc, pool, spec.Config, headroom)
+	if err != nil {
+		return nil, err
+	}
+	if hostFailure != "" {
+		failures = append(failures, hostFailure)
+	}
+
+	// The disks of the spec are stored on the datastore of the VM unless
+	// their backing is placed on another one. The swap file of the VM takes
+	// the part of its memory which is not reserved.
+	requiredKB := map[types.ManagedObjectReference]int64{}
+	if spec.Config.MemoryReservationLockedToMax == nil || !*spec.Config.MemoryReservationLockedToMax {
+		requiredKB[datastoreRef] += spec.Config.MemoryMB * 1024
+	}
+	for _, change := range spec.Config.DeviceChange {
+		disk, ok := change.GetVirtualDeviceConfigSpec().Device.(*types.VirtualDisk)
+		if !ok {
+			continue
+		}
+		ref := datastoreRef
+		if backing, ok := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo); ok && backing.Datastore != nil {
+			ref = *backing.Datastore
+		}
+		requiredKB[ref] += disk.CapacityInKB
+	}
+	refs := make([]types.ManagedObjectReference, 0, len(requiredKB))
+	for ref := range requiredKB {
+		// Storage DRS places the VM on a datastore of the datastore cluster.
+		if ref.Type == "Datastore" {
+			refs = append(refs, ref)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Value < refs[j].Value })
+	for _, ref := range refs {
+		kb := requiredKB[ref]
+		var datastore mo.Datastore
+		if err := pc.RetrieveOne(ctx, ref, []string{"name", "summary"}, &datastore); err != nil {
+			return nil, errors.Wrapf(err, "unable to get capacity of datastore %s", ref.Value)
+		}
+		free, capacity := datastore.Summary.FreeSpace, datastore.Summary.Capacity
+		if required := kb * 1024; free-required < capacity*headroom/100 {
+			failures = append(failures, fmt.Sprintf("datastore %s has %d GiB free for %d GiB of disks and swap with %d%% headroom",
+				datastore.Name, free>>30, (required+(1<<30)-1)>>30, headroom))
+		}
+	}
+	return failures, nil
+}
+
+// checkHostCapacity returns why none of the hosts of the compute resource of
+// the resource pool has the free memory and the free CPU for the VM, or an
+// empty string if one has. The VM takes its memory and its CPU reservation,
+// as it only takes the CPU it uses beyond it.
+func checkHostCapacity(ctx *context.VMContext, pc *property.Collector, pool *object.ResourcePool, config *types.VirtualMachineConfigSpec, headroom int64) (string, error) {
+	owner, err := pool.Owner(ctx)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to get compute resource of resource pool %s", pool.Reference().Value)
+	}
+	var computeResource mo.ComputeResource
+	if err := pc.RetrieveOne(ctx, owner.Reference(), []string{"name", "host"}, &computeResource); err != nil {
+		return "", errors.Wrapf(err, "unable to get hosts of compute resource %s", owner.Reference().Value)
+	}
+	if len(computeResource.Host) == 0 {
+		return fmt.Sprintf("compute resource %s has no host", computeResource.Name), nil
+	}
+	var hosts []mo.HostSystem
+	if err := pc.Retrieve(ctx, computeResource.Host, []string{"name", "runtime", "summary"}, &hosts); err != nil {
+		return "", errors.Wrapf(err, "unable to get capacity of the hosts of compute resource %s", computeResource.Name)
+	}
+
+	var cpuMhz int64
+	if config.CpuAllocation != nil && config.CpuAllocation.Reservation != nil {
+		cpuMhz = *config.CpuAllocation.Reservation
+	}
+	var reasons []string
+	for i := range hosts {
+		host := &hosts[i]
+		hardware, stats := host.Summary.Hardware, host.Summary.QuickStats
+		switch {
+		case host.Runtime.ConnectionState != types.HostSystemConnectionStateConnected || host.Runtime.InMaintenanceMode:
+			reasons = append(reasons, fmt.Sprintf("host %s is unavailable", host.Name))
+			continue
+		case hardware == nil:
+			continue
+		case int32(hardware.NumCpuThreads) < config.NumCPUs:
+			reasons = append(reasons, fmt.Sprintf("host %s has %d CPU threads for %d vCPUs", host.Name, hardware.NumCpuThreads, config.NumCPUs))
+			continue
+		}
+
+		memoryMB := hardware.MemorySize >> 20
+		if free := memoryMB - int64(stats.OverallMemoryUsage); free-config.MemoryMB < memoryMB*headroom/100 {
+			reasons = append(reasons, fmt.Sprintf("host %s has %d MiB of memory free for %d MiB", host.Name, free, config.MemoryMB))
+			continue
+		}
+		totalCPUMhz := int64(hardware.CpuMhz) * int64(hardware.NumCpuCores)
+		if free := totalCPUMhz - int64(stats.OverallCpuUsage); free-cpuMhz < totalCPUMhz*headroom/100 {
+			reasons = append(reasons, fmt.Sprintf("host %s has %d MHz of CPU free", host.Name, free))
+			continue
+		}
+		return "", nil
+	}
+	return fmt.Sprintf("no host of compute resource %s has the capacity for the VM with %d%% headroom: %s",
+		computeResource.Name, headroom, strings.Join(reasons, ", ")), nil
+}
+
+// markInsufficientCapacity marks the VM as waiting for enough free capacity
+// before it is cloned, and emits a warning Event when it starts waiting.
+func markInsufficientCapacity(ctx *context.VMContext, failures []string) {
+	message := strings.Join(failures, "; ")
+	ctx.Logger.Info("waiting for free capacity before cloning", "reason", message)
+	if conditions.GetReason(ctx.VSphereVM, infrav1.CloneStartedCondition) != infrav1.InsufficientCapacityReason {
+		ctx.Recorder.Warn(ctx.VSphereVM, infrav1.InsufficientCapacityReason, message)
+	}
+	conditions.MarkFalse(ctx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.InsufficientCapacityReason, clusterv1.ConditionSeverityWarning, message)
+	conditions.MarkFalse(ctx.VSphereVM, infrav1.CloneStartedCondition, infrav1.InsufficientCapacityReason, clusterv1.ConditionSeverityWarning, message)
+	if err := ctx.Patch(); err != nil {
+		ctx.Logger.Error(err, "patch failed", "vspherevm", ctx.VSphereVM)
+	}
+}
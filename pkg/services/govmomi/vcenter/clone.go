@@ -18,49 +18,82 @@ package vcenter
 
 import (
 	"fmt"
-	"math/rand"
-	"time"
+	"net"
+	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 	"github.com/vmware/govmomi/object"
-	"github.com/vmware/govmomi/pbm"
-	pbmTypes "github.com/vmware/govmomi/pbm/types"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/feature"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/extra"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/inventory"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/template"
 )
 
 const (
 	fullCloneDiskMoveType = types.VirtualMachineRelocateDiskMoveOptionsMoveAllDiskBackingsAndConsolidate
 	linkCloneDiskMoveType = types.VirtualMachineRelocateDiskMoveOptionsCreateNewChildDiskBacking
+
+	// defaultLinkedCloneSnapshotName is the name of the snapshot created for
+	// linked clones when no snapshot name is specified.
+	defaultLinkedCloneSnapshotName = "capv-linked-clone"
+
+	// windowsTimeZone is the Microsoft time zone index of the GMT Standard
+	// Time zone used for Windows VMs.
+	windowsTimeZone = 85
+	// windowsWorkgroup is the workgroup Windows VMs join.
+	windowsWorkgroup = "WORKGROUP"
+	// windowsOwnerName is the name of the owner and the organization of
+	// Windows VMs.
+	windowsOwnerName = "capv"
 )
 
 // Clone kicks off a clone operation on vCenter to create a new virtual machine. This function does not wait for
 // the virtual machine to be created on the vCenter, which can be resolved by waiting on the task reference stored
-// in VMContext.VSphereVM.Status.TaskRef.
+// in VMContext.VSphereVM.Status.TaskRef. When a linked clone requires a snapshot of the template to be created
+// first, the stored task creates the snapshot instead, and the clone operation is kicked off by the next call
+// once it has completed. Instant clones similarly wait for their parent VM, see instantClone, and the VMs of
+// standalone ESXi hosts for the disks of their template to be copied, see hostClone.
 // nolint:gocognit,gocyclo
-func Clone(ctx *context.VMContext, bootstrapData []byte) error {
+func Clone(ctx *context.VMContext, bootstrapData []byte) (reterr error) {
 	ctx = &context.VMContext{
 		ControllerContext: ctx.ControllerContext,
 		VSphereVM:         ctx.VSphereVM,
 		Session:           ctx.Session,
 		Logger:            ctx.Logger.WithName("vcenter"),
 		PatchHelper:       ctx.PatchHelper,
+		RequestContext:    ctx.RequestContext,
 	}
 	ctx.Logger.Info("starting clone process")
 
+	// The inventory objects looked up for a clone which failed may have been
+	// moved, renamed or removed since they were cached.
+	defer func() {
+		if reterr != nil {
+			ctx.Session.CachedFinder.Invalidate()
+		}
+	}()
+
 	var extraConfig extra.Config
 	if len(bootstrapData) > 0 {
 		ctx.Logger.Info("applied bootstrap data to VM clone spec")
-		if err := extraConfig.SetCloudInitUserData(bootstrapData); err != nil {
+		if err := extraConfig.SetCloudInitUserData(bootstrapData, ctx.BootstrapDataCompressionThreshold); err != nil {
 			return err
 		}
 	}
+	// The VMs of a cluster are found by their owner when they are not
+	// backed by a VSphereVM anymore.
+	if clusterName := ctx.VSphereVM.Labels[clusterv1.ClusterLabelName]; clusterName != "" {
+		extraConfig.SetOwner(inventory.OwnerTagName(ctx.VSphereVM.Namespace, clusterName), ctx.VSphereVM.Namespace+"/"+ctx.VSphereVM.Name)
+	}
 	if ctx.VSphereVM.Spec.CustomVMXKeys != nil {
 		ctx.Logger.Info("applied custom vmx keys o VM clone spec")
 		if err := extraConfig.SetCustomVMXKeys(ctx.VSphereVM.Spec.CustomVMXKeys); err != nil {
@@ -75,8 +108,9 @@ func Clone(ctx *context.VMContext, bootstrapData []byte) error {
 	// If a linked clone is requested then a MoRef for a snapshot must be
 	// found with which to perform the linked clone.
 	var snapshotRef *types.ManagedObjectReference
+	// Standalone ESXi hosts only create full copies of the template.
 	//nolint:nestif
-	if ctx.VSphereVM.Spec.CloneMode == "" || ctx.VSphereVM.Spec.CloneMode == infrav1.LinkedClone {
+	if (ctx.VSphereVM.Spec.CloneMode == "" || ctx.VSphereVM.Spec.CloneMode == infrav1.LinkedClone) && !ctx.Session.IsStandaloneHost() {
 		ctx.Logger.Info("linked clone requested")
 		// If the name of a snapshot was not provided then find the template's
 		// current snapshot.
@@ -94,10 +128,30 @@ func Clone(ctx *context.VMContext, bootstrapData []byte) error {
 			var err error
 			snapshotRef, err = tpl.FindSnapshot(ctx, snapshotName)
 			if err != nil {
-				ctx.Logger.Info("failed to find snapshot", "snapshotName", snapshotName)
+				// Falling back to a full clone would ignore the snapshot
+				// explicitly requested.
+				return errors.Wrapf(err, "unable to find snapshot %q of template %s for a linked clone", snapshotName, ctx.VSphereVM.Spec.Template)
 			}
 		}
 	}
+	if snapshotRef == nil && ctx.VSphereVM.Spec.CloneMode == infrav1.LinkedClone && feature.Gates.Enabled(feature.LinkedCloneSnapshotCreation) {
+		taskRef, ref, err := createLinkedCloneSnapshot(ctx, tpl)
+		if err != nil {
+			return err
+		}
+		snapshotRef = ref
+		// The clone is started once the snapshot is created, as the current
+		// snapshot of the template.
+		if taskRef != nil {
+			ctx.VSphereVM.Status.TaskRef = taskRef.Value
+			conditions.MarkFalse(ctx.VSphereVM, infrav1.CloneStartedCondition, infrav1.CreatingLinkedCloneSnapshotReason, clusterv1.ConditionSeverityInfo,
+				"creating a snapshot of template %s for linked clones", ctx.VSphereVM.Spec.Template)
+			if err := ctx.Patch(); err != nil {
+				ctx.Logger.Error(err, "patch failed", "vspherevm", ctx.VSphereVM)
+			}
+			return nil
+		}
+	}
 
 	// The type of clone operation depends on whether or not there is a snapshot
 	// from which to do a linked clone.
@@ -111,12 +165,17 @@ func Clone(ctx *context.VMContext, bootstrapData []byte) error {
 		diskMoveType = linkCloneDiskMoveType
 	}
 
-	folder, err := ctx.Session.Finder.FolderOrDefault(ctx, ctx.VSphereVM.Spec.Folder)
+	folder, err := getFolder(ctx)
 	if err != nil {
 		return errors.Wrapf(err, "unable to get folder for %q", ctx)
 	}
 
-	pool, err := ctx.Session.Finder.ResourcePoolOrDefault(ctx, ctx.VSphereVM.Spec.ResourcePool)
+	// The resource pool chosen by the placement takes precedence.
+	resourcePool := ctx.VSphereVM.Spec.ResourcePool
+	if ctx.VSphereVM.Status.ResourcePool != "" {
+		resourcePool = ctx.VSphereVM.Status.ResourcePool
+	}
+	pool, err := getResourcePool(ctx, resourcePool)
 	if err != nil {
 		return errors.Wrapf(err, "unable to get resource pool for %q", ctx)
 	}
@@ -150,13 +209,22 @@ func Clone(ctx *context.VMContext, bootstrapData []byte) error {
 	}
 
 	if len(ctx.VSphereVM.Spec.VirtualMachineCloneSpec.PciDevices) != 0 {
-		gpuSpecs, _ := getGpuSpecs(ctx)
+		gpuSpecs, err := getGpuSpecs(ctx)
 		if err != nil {
+			conditions.MarkFalse(ctx.VSphereVM, infrav1.PCIDevicesAttachedCondition, infrav1.PCIDevicesAttachFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
 			return errors.Wrapf(err, "error getting gpu specs for %q", ctx)
 		}
 		deviceSpecs = append(deviceSpecs, gpuSpecs...)
 	}
 
+	if ctx.VSphereVM.Spec.VTPM {
+		vtpmSpec, err := getVTPMSpec(ctx)
+		if err != nil {
+			return err
+		}
+		deviceSpecs = append(deviceSpecs, vtpmSpec)
+	}
+
 	numCPUs := ctx.VSphereVM.Spec.NumCPUs
 	if numCPUs < 2 {
 		numCPUs = 2
@@ -170,12 +238,17 @@ func Clone(ctx *context.VMContext, bootstrapData []byte) error {
 		memMiB = 2048
 	}
 
+	// Assign the clone's InstanceUUID the value of the Kubernetes Machine
+	// object's UID. This allows lookup of the cloned VM prior to knowing
+	// the VM's UUID. The UID recorded before the VSphereVM was moved by
+	// clusterctl is used instead, as the move assigns it a new UID.
+	instanceUUID := string(ctx.VSphereVM.UID)
+	if ctx.VSphereVM.Spec.InstanceUUID != "" {
+		instanceUUID = ctx.VSphereVM.Spec.InstanceUUID
+	}
 	spec := types.VirtualMachineCloneSpec{
 		Config: &types.VirtualMachineConfigSpec{
-			// Assign the clone's InstanceUUID the value of the Kubernetes Machine
-			// object's UID. This allows lookup of the cloned VM prior to knowing
-			// the VM's UUID.
-			InstanceUuid:      string(ctx.VSphereVM.UID),
+			InstanceUuid:      instanceUUID,
 			Flags:             newVMFlagInfo(),
 			DeviceChange:      deviceSpecs,
 			ExtraConfig:       extraConfig,
@@ -196,6 +269,27 @@ func Clone(ctx *context.VMContext, bootstrapData []byte) error {
 		Snapshot: snapshotRef,
 	}
 
+	// Windows VMs are generalized with Sysprep. Their bootstrap data is
+	// still read from guestinfo by cloudbase-init.
+	if ctx.VSphereVM.Spec.OS == infrav1.Windows {
+		customization, err := getWindowsCustomizationSpec(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "error getting customization spec for %q", ctx)
+		}
+		spec.Customization = customization
+	}
+
+	setFirmware(&ctx.VSphereVM.Spec.VirtualMachineCloneSpec, spec.Config)
+
+	spec.Config.CpuAllocation, spec.Config.MemoryAllocation = ResourceAllocation(ctx.VSphereVM.Spec.ResourceAllocation)
+	setLatencyTuning(&ctx.VSphereVM.Spec.VirtualMachineCloneSpec, spec.Config)
+
+	if ctx.VSphereVM.Spec.EnableHotAdd {
+		spec.Config.CpuHotAddEnabled = pointer.Bool(true)
+		spec.Config.MemoryHotAddEnabled = pointer.Bool(true)
+	}
+	setGuestToolsUpgradePolicy(&ctx.VSphereVM.Spec.VirtualMachineCloneSpec, spec.Config)
+
 	// For PCI devices, the memory for the VM needs to be reserved
 	// We can replace this once we have another way of reserving memory option
 	// exposed via the API types.
@@ -203,86 +297,203 @@ func Clone(ctx *context.VMContext, bootstrapData []byte) error {
 		spec.Config.MemoryReservationLockedToMax = pointer.Bool(true)
 	}
 
-	var datastoreRef *types.ManagedObjectReference
-	if ctx.VSphereVM.Spec.Datastore != "" {
-		datastore, err := ctx.Session.Finder.Datastore(ctx, ctx.VSphereVM.Spec.Datastore)
-		if err != nil {
-			return errors.Wrapf(err, "unable to get datastore %s for %q", ctx.VSphereVM.Spec.Datastore, ctx)
-		}
-		datastoreRef = types.NewReference(datastore.Reference())
-		spec.Location.Datastore = datastoreRef
+	policies, err := getStoragePolicies(ctx)
+	if err != nil {
+		return err
 	}
 
-	var storageProfileID string
-	//nolint:nestif
-	if ctx.VSphereVM.Spec.StoragePolicyName != "" {
-		pbmClient, err := pbm.NewClient(ctx, ctx.Session.Client.Client)
+	// The VM is placed on the first of its candidate datastores with the
+	// free capacity for it. Do not start a clone which would fail, or whose
+	// VM could not be powered on, for lack of free capacity.
+	deviceChange := spec.Config.DeviceChange
+	candidates := getDatastoreCandidates(ctx)
+	var datastoreRef types.ManagedObjectReference
+	var failures []string
+	for i, candidate := range candidates {
+		spec.Config.DeviceChange = deviceChange[:len(deviceChange):len(deviceChange)]
+		datastoreRef, err = placeOnDatastore(ctx, &spec, devices, policies, snapshotRef, candidate)
 		if err != nil {
-			return errors.Wrapf(err, "unable to create pbm client for %q", ctx)
+			return err
 		}
-
-		storageProfileID, err = pbmClient.ProfileIDByName(ctx, ctx.VSphereVM.Spec.StoragePolicyName)
+		candidateFailures, err := checkCapacity(ctx, pool, spec, datastoreRef)
 		if err != nil {
-			return errors.Wrapf(err, "unable to get storageProfileID from name %s for %q", ctx.VSphereVM.Spec.StoragePolicyName, ctx)
+			return errors.Wrapf(err, "unable to check the free capacity for %q", ctx)
+		}
+		if len(candidateFailures) == 0 {
+			recordSelectedDatastore(ctx, candidate, i)
+			failures = nil
+			break
 		}
+		failures = appendFailures(failures, candidateFailures)
+		if i < len(candidates)-1 {
+			ctx.Logger.Info("datastore lacks free capacity, trying the next datastore", "datastore", candidate, "reason", strings.Join(candidateFailures, "; "))
+		}
+	}
+	if len(failures) > 0 {
+		markInsufficientCapacity(ctx, failures)
+		return nil
+	}
 
-		var constraints []pbmTypes.BasePbmPlacementRequirement
-		constraints = append(constraints, &pbmTypes.PbmPlacementCapabilityProfileRequirement{ProfileId: pbmTypes.PbmProfileId{UniqueId: storageProfileID}})
-		result, err := pbmClient.CheckRequirements(ctx, nil, nil, constraints)
+	// Instant clones are forked from a parent VM created with the spec.
+	if ctx.VSphereVM.Spec.CloneMode == infrav1.InstantClone {
+		return instantClone(ctx, tpl, folder, spec, datastoreRef, extraConfig)
+	}
+	// Standalone ESXi hosts create the VM from copies of the disks of the
+	// template.
+	if ctx.Session.IsStandaloneHost() {
+		return hostClone(ctx, tpl, folder, pool, spec, datastoreRef)
+	}
+
+	ctx.Logger.Info("cloning machine", "namespace", ctx.VSphereVM.Namespace, "name", ctx.VSphereVM.Name, "cloneType", ctx.VSphereVM.Status.CloneMode)
+	task, err := tpl.Clone(ctx, folder, ctx.VSphereVM.Name, spec)
+	if err != nil {
+		return errors.Wrapf(err, "error trigging clone op for machine %s", ctx)
+	}
+
+	ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+
+	// patch the vsphereVM early to ensure that the task is
+	// reflected in the status right away, this avoid situations
+	// of concurrent clones
+	if err := ctx.Patch(); err != nil {
+		ctx.Logger.Error(err, "patch failed", "vspherevm", ctx.VSphereVM)
+	}
+	return nil
+}
+
+// placeOnDatastore places the VM cloned with the spec on the datastore, or
+// on the datastore cluster of its failure domain when the name is empty,
+// unless its storage policy requires another datastore, and returns the
+// datastore of the VM. Its disks are placed on the datastore of the VM,
+// except for data disks with their own datastore or storage policy.
+func placeOnDatastore(ctx *context.VMContext, spec *types.VirtualMachineCloneSpec, devices object.VirtualDeviceList, policies *storagePolicies, snapshotRef *types.ManagedObjectReference, datastoreName string) (types.ManagedObjectReference, error) {
+	var datastoreRef *types.ManagedObjectReference
+	var err error
+	if datastoreName != "" {
+		datastore, err := ctx.Session.CachedFinder.Datastore(ctx, datastoreName)
 		if err != nil {
-			return errors.Wrapf(err, "unable to check requirements for storage policy")
+			return types.ManagedObjectReference{}, errors.Wrapf(err, "unable to get datastore %s for %q", datastoreName, ctx)
 		}
-
-		if len(result.CompatibleDatastores()) == 0 {
-			return fmt.Errorf("no compatible datastores found for storage policy: %s", ctx.VSphereVM.Spec.StoragePolicyName)
+		datastoreRef = types.NewReference(datastore.Reference())
+		spec.Location.Datastore = datastoreRef
+	} else {
+		// VMs without a datastore are created in the datastore cluster of
+		// their failure domain, if any.
+		datastoreRef, err = getFailureDomainDatastore(ctx)
+		if err != nil {
+			return types.ManagedObjectReference{}, err
 		}
+		spec.Location.Datastore = datastoreRef
+	}
 
+	var vmProfile []types.BaseVirtualMachineProfileSpec
+	if policyName := ctx.VSphereVM.Spec.StoragePolicyName; policyName != "" {
 		if datastoreRef != nil {
 			ctx.Logger.Info("datastore and storagepolicy defined; searching for datastore in storage policy compatible datastores")
-			found := false
-			for _, ds := range result.CompatibleDatastores() {
-				compatibleRef := types.ManagedObjectReference{Type: ds.HubType, Value: ds.HubId}
-				if compatibleRef.String() == datastoreRef.String() {
-					found = true
-				}
-			}
-			if !found {
-				return fmt.Errorf("couldn't find specified datastore: %s in compatible list of datastores for storage policy", ctx.VSphereVM.Spec.Datastore)
-			}
-		} else {
-			rand.Seed(time.Now().UnixNano())
-			ds := result.CompatibleDatastores()[rand.Intn(len(result.CompatibleDatastores()))] //nolint:gosec
-			datastoreRef = &types.ManagedObjectReference{Type: ds.HubType, Value: ds.HubId}
 		}
+		datastoreRef, err = policies.datastore(ctx, policyName, datastoreRef)
+		if err != nil {
+			return types.ManagedObjectReference{}, errors.Wrapf(err, "unable to place %q according to storage policy", ctx)
+		}
+		spec.Location.Datastore = datastoreRef
+		vmProfile = policies.profileSpec(policyName)
+		spec.Location.Profile = vmProfile
 	}
 
 	if datastoreRef == nil {
 		// if no datastore defined through VM spec or storage policy, use default
 		datastore, err := ctx.Session.Finder.DefaultDatastore(ctx)
 		if err != nil {
-			return errors.Wrapf(err, "unable to get default datastore for %q", ctx)
+			return types.ManagedObjectReference{}, errors.Wrapf(err, "unable to get default datastore for %q", ctx)
 		}
 		datastoreRef = types.NewReference(datastore.Reference())
 	}
 
 	disks := devices.SelectByType((*types.VirtualDisk)(nil))
 	spec.Location.Disk = getDiskLocators(disks, *datastoreRef)
+	for i := range spec.Location.Disk {
+		spec.Location.Disk[i].Profile = vmProfile
+	}
 
-	ctx.Logger.Info("cloning machine", "namespace", ctx.VSphereVM.Namespace, "name", ctx.VSphereVM.Name, "cloneType", ctx.VSphereVM.Status.CloneMode)
-	task, err := tpl.Clone(ctx, folder, ctx.VSphereVM.Name, spec)
+	provisioningType, err := getDiskProvisioningType(ctx, snapshotRef, *datastoreRef)
 	if err != nil {
-		return errors.Wrapf(err, "error trigging clone op for machine %s", ctx)
+		return types.ManagedObjectReference{}, err
+	}
+	ctx.VSphereVM.Status.DiskProvisioningType = provisioningType
+	if provisioningType != "" {
+		setDiskLocatorsProvisioningMode(spec.Location.Disk, provisioningType)
 	}
 
-	ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+	if len(ctx.VSphereVM.Spec.Disks) > 0 {
+		dataDiskSpecs, err := getDataDiskSpecs(ctx, devices, policies, *datastoreRef)
+		if err != nil {
+			return types.ManagedObjectReference{}, errors.Wrapf(err, "error getting data disk specs for %q", ctx)
+		}
+		spec.Config.DeviceChange = append(spec.Config.DeviceChange, dataDiskSpecs...)
+	}
 
-	// patch the vsphereVM early to ensure that the task is
-	// reflected in the status right away, this avoid situations
-	// of concurrent clones
-	if err := ctx.Patch(); err != nil {
-		ctx.Logger.Error(err, "patch failed", "vspherevm", ctx.VSphereVM)
+	return *datastoreRef, nil
+}
+
+// linkedCloneSnapshotTasks tracks the tasks creating the snapshots of the
+// clone sources for linked clones, keyed by server and clone source, so the
+// VMs cloned from the same source concurrently wait for the same snapshot
+// instead of each creating one with the same name.
+var linkedCloneSnapshotTasks = &snapshotTasks{tasks: map[string]types.ManagedObjectReference{}}
+
+type snapshotTasks struct {
+	// mu is held while a snapshot is looked up and created.
+	mu    sync.Mutex
+	tasks map[string]types.ManagedObjectReference
+}
+
+// createLinkedCloneSnapshot starts creating a snapshot of the clone source
+// from which linked clones can be created, and returns its task. The task
+// creating the snapshot for another VM is returned while it is in progress,
+// and the snapshot itself once it exists. vSphere does not support snapshots
+// of VMs marked as templates, in which case no snapshot is created and the
+// clone falls back to a full clone.
+func createLinkedCloneSnapshot(ctx *context.VMContext, tpl *object.VirtualMachine) (*types.ManagedObjectReference, *types.ManagedObjectReference, error) {
+	var vm mo.VirtualMachine
+	if err := tpl.Properties(ctx, tpl.Reference(), []string{"config.template"}, &vm); err != nil {
+		return nil, nil, errors.Wrapf(err, "error getting template information for %s", ctx.VSphereVM.Spec.Template)
+	}
+	if vm.Config != nil && vm.Config.Template {
+		ctx.Logger.Info("unable to create snapshot of a template, falling back to full clone", "template", ctx.VSphereVM.Spec.Template)
+		return nil, nil, nil
+	}
+
+	snapshotName := ctx.VSphereVM.Spec.Snapshot
+	if snapshotName == "" {
+		snapshotName = defaultLinkedCloneSnapshotName
+	}
+	key := ctx.VSphereVM.Spec.Server + "/" + tpl.Reference().Value
+
+	linkedCloneSnapshotTasks.mu.Lock()
+	defer linkedCloneSnapshotTasks.mu.Unlock()
+
+	if taskRef, ok := linkedCloneSnapshotTasks.tasks[key]; ok {
+		// Tasks which cannot be retrieved anymore are complete.
+		var task mo.Task
+		err := ctx.Session.RetrieveOne(ctx, taskRef, []string{"info.state"}, &task)
+		if err == nil && (task.Info.State == types.TaskInfoStateQueued || task.Info.State == types.TaskInfoStateRunning) {
+			ctx.Logger.Info("waiting for snapshot for linked clone", "snapshotName", snapshotName, "task", taskRef.Value)
+			return &taskRef, nil, nil
+		}
+		delete(linkedCloneSnapshotTasks.tasks, key)
 	}
-	return nil
+	if snapshotRef, err := tpl.FindSnapshot(ctx, snapshotName); err == nil {
+		return nil, snapshotRef, nil
+	}
+
+	ctx.Logger.Info("creating snapshot for linked clone", "snapshotName", snapshotName)
+	task, err := tpl.CreateSnapshot(ctx, snapshotName, "Created by Cluster API Provider vSphere for linked clones", false, false)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error creating snapshot %s of %s", snapshotName, ctx.VSphereVM.Spec.Template)
+	}
+	taskRef := task.Reference()
+	linkedCloneSnapshotTasks.tasks[key] = taskRef
+	return &taskRef, nil, nil
 }
 
 func newVMFlagInfo() *types.VirtualMachineFlagInfo {
@@ -320,6 +531,10 @@ func getDiskSpec(ctx *context.VMContext, devices object.VirtualDeviceList) ([]ty
 	var diskSpecs []types.BaseVirtualDeviceConfigSpec
 	primaryDisk := disks[0].(*types.VirtualDisk) //nolint:forcetypeassert
 	primaryCloneCapacityKB := int64(ctx.VSphereVM.Spec.DiskGiB) * 1024 * 1024
+	if primaryDisk.CapacityInKB > primaryCloneCapacityKB {
+		templateGiB := (primaryDisk.CapacityInKB + 1024*1024 - 1) / (1024 * 1024)
+		return nil, errors.Errorf("diskGiB %d is smaller than the %d GiB disk of template %q, which cannot be shrunk", ctx.VSphereVM.Spec.DiskGiB, templateGiB, ctx.VSphereVM.Spec.Template)
+	}
 	primaryDiskConfigSpec, err := getDiskConfigSpec(primaryDisk, primaryCloneCapacityKB)
 	if err != nil {
 		return nil, errors.Wrap(err, "Error getting disk config spec for primary disk")
@@ -348,6 +563,217 @@ func getDiskSpec(ctx *context.VMContext, devices object.VirtualDeviceList) ([]ty
 	return diskSpecs, nil
 }
 
+// getDataDiskSpecs returns the device specs that create the additional data
+// disks of the VM. The disks are attached to the first SCSI controller of the
+// template. Disks without a datastore override are created in the VM's home
+// directory, unless the VM's datastore is not compatible with the storage
+// policy of the disk.
+func getDataDiskSpecs(ctx *context.VMContext, devices object.VirtualDeviceList, policies *storagePolicies, vmDatastore types.ManagedObjectReference) ([]types.BaseVirtualDeviceConfigSpec, error) {
+	controller, err := devices.FindDiskController("scsi")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to find a disk controller for data disks")
+	}
+
+	// The new disks are appended to a copy of the device list so each
+	// disk is assigned a free unit number on the controller.
+	deviceList := append(object.VirtualDeviceList{}, devices...)
+	diskSpecs := make([]types.BaseVirtualDeviceConfigSpec, 0, len(ctx.VSphereVM.Spec.Disks))
+	for i, diskSpec := range ctx.VSphereVM.Spec.Disks {
+		disk := deviceList.CreateDisk(controller, types.ManagedObjectReference{}, "")
+		backing := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo) //nolint:forcetypeassert
+		backing.Datastore = nil
+
+		var datastoreRef *types.ManagedObjectReference
+		var datastoreName string
+		if diskSpec.Datastore != "" {
+			datastore, err := ctx.Session.CachedFinder.Datastore(ctx, diskSpec.Datastore)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to get datastore %s for data disk %d", diskSpec.Datastore, i)
+			}
+			datastoreRef = types.NewReference(datastore.Reference())
+			datastoreName = datastore.Name()
+		}
+		if diskSpec.StoragePolicyName != "" {
+			placementRef := datastoreRef
+			if placementRef == nil {
+				placementRef = &vmDatastore
+			}
+			compatibleRef, err := policies.datastore(ctx, diskSpec.StoragePolicyName, placementRef)
+			if err != nil && datastoreRef == nil {
+				// The VM's datastore is not compatible with the storage policy,
+				// place the disk on any compatible datastore instead.
+				compatibleRef, err = policies.datastore(ctx, diskSpec.StoragePolicyName, nil)
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to place data disk %d according to storage policy", i)
+			}
+			if datastoreRef == nil && *compatibleRef != vmDatastore {
+				datastoreName, err = object.NewDatastore(ctx.Session.Client.Client, *compatibleRef).ObjectName(ctx)
+				if err != nil {
+					return nil, errors.Wrapf(err, "unable to get name of datastore %s for data disk %d", compatibleRef.Value, i)
+				}
+				datastoreRef = compatibleRef
+			}
+		}
+		if datastoreRef != nil {
+			backing.Datastore = datastoreRef
+			backing.FileName = fmt.Sprintf("[%s]", datastoreName)
+		}
+		provisioningMode := diskSpec.ProvisioningMode
+		if provisioningMode == "" {
+			provisioningMode = ctx.VSphereVM.Spec.DiskProvisioningType
+		}
+		setDiskProvisioningMode(backing, provisioningMode)
+		disk.CapacityInKB = int64(diskSpec.SizeGiB) * 1024 * 1024
+		deviceList = append(deviceList, disk)
+
+		diskSpecs = append(diskSpecs, &types.VirtualDeviceConfigSpec{
+			Operation:     types.VirtualDeviceConfigSpecOperationAdd,
+			FileOperation: types.VirtualDeviceConfigSpecFileOperationCreate,
+			Device:        disk,
+			Profile:       policies.profileSpec(diskSpec.StoragePolicyName),
+		})
+	}
+
+	return diskSpecs, nil
+}
+
+// getDiskProvisioningType returns the provisioning type applied to the disks
+// cloned from the template. The disks keep the provisioning type of the
+// template, and an empty type is returned, for linked clones, whose disks
+// are backed by the disks of the template, and on vSAN datastores, which
+// ignore the provisioning type of disks in favor of their storage policy.
+func getDiskProvisioningType(ctx *context.VMContext, snapshotRef *types.ManagedObjectReference, datastoreRef types.ManagedObjectReference) (infrav1.DiskProvisioningMode, error) {
+	provisioningType := ctx.VSphereVM.Spec.DiskProvisioningType
+	if provisioningType == "" {
+		return "", nil
+	}
+	if snapshotRef != nil {
+		ctx.Logger.Info("ignoring disk provisioning type of linked clone", "diskProvisioningType", provisioningType)
+		return "", nil
+	}
+
+	var datastore mo.Datastore
+	if err := ctx.Session.RetrieveOne(ctx, datastoreRef, []string{"summary.type"}, &datastore); err != nil {
+		return "", errors.Wrapf(err, "unable to get type of datastore %s for %q", datastoreRef.Value, ctx)
+	}
+	if datastore.Summary.Type == string(types.HostFileSystemVolumeFileSystemTypeVsan) {
+		ctx.Logger.Info("ignoring disk provisioning type on vSAN datastore", "diskProvisioningType", provisioningType, "datastore", datastoreRef.Value)
+		return "", nil
+	}
+	return provisioningType, nil
+}
+
+// setDiskLocatorsProvisioningMode sets the provisioning mode of the disks
+// relocated by a clone. The disk backings are copied, as they are shared with
+// the devices of the template.
+func setDiskLocatorsProvisioningMode(locators []types.VirtualMachineRelocateSpecDiskLocator, mode infrav1.DiskProvisioningMode) {
+	for i := range locators {
+		backing, ok := locators[i].DiskBackingInfo.(*types.VirtualDiskFlatVer2BackingInfo)
+		if !ok {
+			continue
+		}
+		b := *backing
+		setDiskProvisioningMode(&b, mode)
+		locators[i].DiskBackingInfo = &b
+	}
+}
+
+// setDiskProvisioningMode sets the provisioning mode of a disk backing. The
+// backing is left unchanged if the mode is empty.
+func setDiskProvisioningMode(backing *types.VirtualDiskFlatVer2BackingInfo, mode infrav1.DiskProvisioningMode) {
+	switch mode {
+	case infrav1.ThinProvisioningMode:
+		backing.ThinProvisioned = pointer.Bool(true)
+		backing.EagerlyScrub = pointer.Bool(false)
+	case infrav1.ThickProvisioningMode:
+		backing.ThinProvisioned = pointer.Bool(false)
+		backing.EagerlyScrub = pointer.Bool(false)
+	case infrav1.EagerZeroedThickProvisioningMode:
+		backing.ThinProvisioned = pointer.Bool(false)
+		backing.EagerlyScrub = pointer.Bool(true)
+	}
+}
+
+// getWindowsCustomizationSpec returns the Sysprep customization spec of a
+// Windows VM, which sets the computer name of the VM to its hostname and
+// configures its network devices.
+func getWindowsCustomizationSpec(ctx *context.VMContext) (*types.CustomizationSpec, error) {
+	var dnsSuffixes []string
+	adapters := make([]types.CustomizationAdapterMapping, 0, len(ctx.VSphereVM.Spec.Network.Devices))
+	for i, device := range ctx.VSphereVM.Spec.Network.Devices {
+		adapter := types.CustomizationIPSettings{
+			Ip:            &types.CustomizationDhcpIpGenerator{},
+			DnsServerList: device.Nameservers,
+		}
+		for _, addr := range device.IPAddrs {
+			ip, ipNet, err := net.ParseCIDR(addr)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid ip address %s for network device %d", addr, i)
+			}
+			if ip.To4() != nil {
+				// Sysprep supports a single IPv4 address per device.
+				if _, ok := adapter.Ip.(*types.CustomizationFixedIp); ok || device.DHCP4 {
+					continue
+				}
+				adapter.Ip = &types.CustomizationFixedIp{IpAddress: ip.String()}
+				adapter.SubnetMask = net.IP(ipNet.Mask).String()
+				if device.Gateway4 != "" {
+					adapter.Gateway = []string{device.Gateway4}
+				}
+				continue
+			}
+			if adapter.IpV6Spec == nil {
+				adapter.IpV6Spec = &types.CustomizationIPSettingsIpV6AddressSpec{}
+				if device.Gateway6 != "" {
+					adapter.IpV6Spec.Gateway = []string{device.Gateway6}
+				}
+			}
+			prefix, _ := ipNet.Mask.Size()
+			adapter.IpV6Spec.Ip = append(adapter.IpV6Spec.Ip, &types.CustomizationFixedIpV6{
+				IpAddress:  ip.String(),
+				SubnetMask: int32(prefix),
+			})
+		}
+		if device.DHCP6 && adapter.IpV6Spec == nil {
+			adapter.IpV6Spec = &types.CustomizationIPSettingsIpV6AddressSpec{
+				Ip: []types.BaseCustomizationIpV6Generator{&types.CustomizationDhcpIpV6Generator{}},
+			}
+		}
+		if device.SLAAC && adapter.IpV6Spec == nil {
+			adapter.IpV6Spec = &types.CustomizationIPSettingsIpV6AddressSpec{
+				Ip: []types.BaseCustomizationIpV6Generator{&types.CustomizationAutoIpV6Generator{}},
+			}
+		}
+
+		adapters = append(adapters, types.CustomizationAdapterMapping{
+			MacAddress: device.MACAddr,
+			Adapter:    adapter,
+		})
+		dnsSuffixes = append(dnsSuffixes, device.SearchDomains...)
+	}
+
+	return &types.CustomizationSpec{
+		Identity: &types.CustomizationSysprep{
+			GuiUnattended: types.CustomizationGuiUnattended{
+				TimeZone: windowsTimeZone,
+			},
+			UserData: types.CustomizationUserData{
+				FullName:     windowsOwnerName,
+				OrgName:      windowsOwnerName,
+				ComputerName: &types.CustomizationFixedName{Name: ctx.VSphereVM.Name},
+			},
+			Identification: types.CustomizationIdentification{
+				JoinWorkgroup: windowsWorkgroup,
+			},
+		},
+		GlobalIPSettings: types.CustomizationGlobalIPSettings{
+			DnsSuffixList: dnsSuffixes,
+		},
+		NicSettingMap: adapters,
+	}, nil
+}
+
 func getDiskConfigSpec(disk *types.VirtualDisk, diskCloneCapacityKB int64) (types.BaseVirtualDeviceConfigSpec, error) {
 	if disk.CapacityInKB > diskCloneCapacityKB {
 		return nil, errors.Errorf(
@@ -364,6 +790,19 @@ func getDiskConfigSpec(disk *types.VirtualDisk, diskCloneCapacityKB int64) (type
 
 const ethCardType = "vmxnet3"
 
+// getNetwork returns the network the network device is connected to, which
+// is created first for a distributed port group.
+func getNetwork(ctx *context.VMContext, netSpec *infrav1.NetworkDeviceSpec) (object.NetworkReference, error) {
+	if netSpec.SwitchName != "" {
+		return getPortGroup(ctx, netSpec)
+	}
+	ref, err := ctx.Session.CachedFinder.Network(ctx, netSpec.NetworkName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to find network %q", netSpec.NetworkName)
+	}
+	return ref, nil
+}
+
 func getNetworkSpecs(ctx *context.VMContext, devices object.VirtualDeviceList) ([]types.BaseVirtualDeviceConfigSpec, error) {
 	deviceSpecs := []types.BaseVirtualDeviceConfigSpec{}
 
@@ -379,9 +818,9 @@ func getNetworkSpecs(ctx *context.VMContext, devices object.VirtualDeviceList) (
 	key := int32(-100)
 	for i := range ctx.VSphereVM.Spec.Network.Devices {
 		netSpec := &ctx.VSphereVM.Spec.Network.Devices[i]
-		ref, err := ctx.Session.Finder.Network(ctx, netSpec.NetworkName)
+		ref, err := getNetwork(ctx, netSpec)
 		if err != nil {
-			return nil, errors.Wrapf(err, "unable to find network %q", netSpec.NetworkName)
+			return nil, err
 		}
 		backing, err := ref.EthernetCardBackingInfo(ctx)
 		if err != nil {
@@ -439,21 +878,41 @@ func getGpuSpecs(ctx *context.VMContext) ([]types.BaseVirtualDeviceConfigSpec, e
 		return nil, errors.Errorf("Invalid pci device count count: %d", len(expectedPciDevices))
 	}
 
-	for _, pciDevice := range expectedPciDevices {
-		backingInfo := &types.VirtualPCIPassthroughDynamicBackingInfo{
-			AllowedDevice: []types.VirtualPCIPassthroughAllowedDevice{
-				{
-					VendorId: *pciDevice.VendorID,
-					DeviceId: *pciDevice.DeviceID,
+	for i, pciDevice := range expectedPciDevices {
+		var backingInfo types.BaseVirtualDeviceBackingInfo
+		switch {
+		case pciDevice.VGPUProfile != "":
+			backingInfo = &types.VirtualPCIPassthroughVmiopBackingInfo{
+				Vgpu: pciDevice.VGPUProfile,
+			}
+		case pciDevice.DeviceID != nil && pciDevice.VendorID != nil:
+			backingInfo = &types.VirtualPCIPassthroughDynamicBackingInfo{
+				AllowedDevice: []types.VirtualPCIPassthroughAllowedDevice{
+					{
+						VendorId: *pciDevice.VendorID,
+						DeviceId: *pciDevice.DeviceID,
+					},
 				},
-			},
+			}
+		default:
+			return nil, errors.Errorf("pci device %d requires either a vGPU profile or a device and vendor ID", i)
 		}
-		dynamicDirectPathDevice := createPCIPassThroughDevice(deviceKey, backingInfo)
+		pciPassthroughDevice := createPCIPassThroughDevice(deviceKey, backingInfo)
 		deviceSpecs = append(deviceSpecs, &types.VirtualDeviceConfigSpec{
-			Device:    dynamicDirectPathDevice,
+			Device:    pciPassthroughDevice,
 			Operation: types.VirtualDeviceConfigSpecOperationAdd,
 		})
 		deviceKey--
 	}
 	return deviceSpecs, nil
 }
+
+// setGuestToolsUpgradePolicy sets the upgrade policy of the VMware Tools of
+// the VM spec on the config spec of the clone. The upgrade policy of the
+// template is kept when none is specified.
+func setGuestToolsUpgradePolicy(spec *infrav1.VirtualMachineCloneSpec, config *types.VirtualMachineConfigSpec) {
+	if spec.GuestTools == nil || spec.GuestTools.UpgradePolicy == "" {
+		return
+	}
+	config.Tools = &types.ToolsConfigInfo{ToolsUpgradePolicy: string(spec.GuestTools.UpgradePolicy)}
+}
@@ -19,17 +19,28 @@ package vcenter
 import (
 	ctx "context"
 	"crypto/tls"
+	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/vmware/govmomi/object"
+	// run init func to register the storage policy API endpoints.
+	_ "github.com/vmware/govmomi/pbm/simulator"
 	"github.com/vmware/govmomi/simulator"
 
 	// run init func to register the tagging API endpoints.
 	_ "github.com/vmware/govmomi/vapi/simulator"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/soap"
 	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
 
 	"sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/inventory"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
 )
 
@@ -80,7 +91,7 @@ func TestGetDiskSpec(t *testing.T) {
 			name:          "Fail to clone template with lower disk requirements then on template",
 			disks:         defaultDisks,
 			cloneDiskSize: defaultSizeGiB - 1,
-			err:           "Error getting disk config spec for primary disk: can't resize template disk down, initial capacity is larger: 6291456KiB > 4194304KiB",
+			err:           `diskGiB 4 is smaller than the 6 GiB disk of template "", which cannot be shrunk`,
 		},
 		{
 			name:  "Fail to clone template without disk devices",
@@ -141,6 +152,400 @@ func TestGetDiskSpec(t *testing.T) {
 	}
 }
 
+func TestGetDataDiskSpecs(t *testing.T) {
+	model, session, server := initSimulator(t)
+	t.Cleanup(model.Remove)
+	t.Cleanup(server.Close)
+	vm := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine) //nolint:forcetypeassert
+	machine := object.NewVirtualMachine(session.Client.Client, vm.Reference())
+
+	devices, err := machine.Device(ctx.TODO())
+	if err != nil {
+		t.Fatalf("Failed to obtain vm devices: %v", err)
+	}
+	datastore, err := session.Finder.Datastore(ctx.TODO(), "LocalDS_0")
+	if err != nil {
+		t.Fatalf("Failed to obtain vm datastore: %v", err)
+	}
+
+	testCases := []struct {
+		name             string
+		disks            []v1beta1.DiskSpec
+		provisioningType v1beta1.DiskProvisioningMode
+		err              string
+	}{
+		{
+			name: "Successfully create thin provisioned data disks",
+			disks: []v1beta1.DiskSpec{
+				{SizeGiB: 10},
+				{SizeGiB: 20, ProvisioningMode: v1beta1.ThinProvisioningMode},
+			},
+		},
+		{
+			name: "Successfully create thick provisioned data disk on a datastore",
+			disks: []v1beta1.DiskSpec{
+				{SizeGiB: 10, ProvisioningMode: v1beta1.ThickProvisioningMode, Datastore: "LocalDS_0"},
+			},
+		},
+		{
+			name: "Successfully create data disks with the provisioning type of the VM",
+			disks: []v1beta1.DiskSpec{
+				{SizeGiB: 10},
+				{SizeGiB: 10, ProvisioningMode: v1beta1.ThinProvisioningMode},
+			},
+			provisioningType: v1beta1.EagerZeroedThickProvisioningMode,
+		},
+		{
+			name: "Fail to create data disk on a missing datastore",
+			disks: []v1beta1.DiskSpec{
+				{SizeGiB: 10, Datastore: "missing"},
+			},
+			err: "unable to get datastore missing for data disk 0: datastore 'missing' not found",
+		},
+		{
+			name: "Successfully create data disk with a storage policy",
+			disks: []v1beta1.DiskSpec{
+				{SizeGiB: 10, StoragePolicyName: "vSAN Default Storage Policy"},
+				{SizeGiB: 10, StoragePolicyName: "vSAN Default Storage Policy", Datastore: "LocalDS_0"},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			vmContext := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+			vmContext.Session = session
+			vmContext.VSphereVM.Spec.Disks = tc.disks
+			vmContext.VSphereVM.Spec.DiskProvisioningType = tc.provisioningType
+
+			policies, err := getStoragePolicies(vmContext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			deviceSpecs, err := getDataDiskSpecs(vmContext, devices, policies, datastore.Reference())
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("Expected to get '%v' error from getDataDiskSpecs, got: '%v'", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(deviceSpecs) != len(tc.disks) {
+				t.Fatalf("Expected number of deviceSpecs: %d, but got: '%d'", len(tc.disks), len(deviceSpecs))
+			}
+
+			unitNumbers := map[int32]struct{}{}
+			for _, device := range devices.SelectByType((*types.VirtualDisk)(nil)) {
+				unitNumbers[*device.GetVirtualDevice().UnitNumber] = struct{}{}
+			}
+			for i, deviceSpec := range deviceSpecs {
+				diskSpec := tc.disks[i]
+				if diskSpec.ProvisioningMode == "" {
+					diskSpec.ProvisioningMode = tc.provisioningType
+				}
+				validateDataDiskSpec(t, deviceSpec, diskSpec)
+				unitNumber := *deviceSpec.GetVirtualDeviceConfigSpec().Device.GetVirtualDevice().UnitNumber
+				if _, ok := unitNumbers[unitNumber]; ok {
+					t.Errorf("Data disk %d reuses unit number %d", i, unitNumber)
+				}
+				unitNumbers[unitNumber] = struct{}{}
+			}
+		})
+	}
+}
+
+func TestGetDiskProvisioningType(t *testing.T) {
+	model, session, server := initSimulator(t)
+	t.Cleanup(model.Remove)
+	t.Cleanup(server.Close)
+	datastore, err := session.Finder.Datastore(ctx.TODO(), "LocalDS_0")
+	if err != nil {
+		t.Fatalf("Failed to obtain datastore: %v", err)
+	}
+	simDatastore := simulator.Map.Get(datastore.Reference()).(*simulator.Datastore) //nolint:forcetypeassert
+	snapshotRef := &types.ManagedObjectReference{Type: "VirtualMachineSnapshot", Value: "snapshot-1"}
+
+	testCases := []struct {
+		name             string
+		provisioningType v1beta1.DiskProvisioningMode
+		snapshotRef      *types.ManagedObjectReference
+		datastoreType    types.HostFileSystemVolumeFileSystemType
+		expected         v1beta1.DiskProvisioningMode
+	}{
+		{
+			name:          "Keep the provisioning type of the template by default",
+			datastoreType: types.HostFileSystemVolumeFileSystemTypeVMFS,
+		},
+		{
+			name:             "Apply the provisioning type to full clones",
+			provisioningType: v1beta1.EagerZeroedThickProvisioningMode,
+			datastoreType:    types.HostFileSystemVolumeFileSystemTypeVMFS,
+			expected:         v1beta1.EagerZeroedThickProvisioningMode,
+		},
+		{
+			name:             "Ignore the provisioning type of linked clones",
+			provisioningType: v1beta1.ThickProvisioningMode,
+			snapshotRef:      snapshotRef,
+			datastoreType:    types.HostFileSystemVolumeFileSystemTypeVMFS,
+		},
+		{
+			name:             "Ignore the provisioning type on vSAN datastores",
+			provisioningType: v1beta1.ThickProvisioningMode,
+			datastoreType:    types.HostFileSystemVolumeFileSystemTypeVsan,
+		},
+	}
+
+	for _, test := range testCases {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			simDatastore.Summary.Type = string(tc.datastoreType)
+			vmContext := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+			vmContext.Session = session
+			vmContext.VSphereVM.Spec.DiskProvisioningType = tc.provisioningType
+
+			provisioningType, err := getDiskProvisioningType(vmContext, tc.snapshotRef, datastore.Reference())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if provisioningType != tc.expected {
+				t.Errorf("Expected disk provisioning type %q, got %q", tc.expected, provisioningType)
+			}
+		})
+	}
+
+	locators := []types.VirtualMachineRelocateSpecDiskLocator{{DiskBackingInfo: &types.VirtualDiskFlatVer2BackingInfo{ThinProvisioned: pointer.Bool(true)}}}
+	template := locators[0].DiskBackingInfo
+	setDiskLocatorsProvisioningMode(locators, v1beta1.EagerZeroedThickProvisioningMode)
+	backing := locators[0].DiskBackingInfo.(*types.VirtualDiskFlatVer2BackingInfo) //nolint:forcetypeassert
+	if *backing.ThinProvisioned || !*backing.EagerlyScrub {
+		t.Errorf("Expected an eager zeroed thick disk, got thin %t and eagerly scrub %t", *backing.ThinProvisioned, *backing.EagerlyScrub)
+	}
+	if !*template.(*types.VirtualDiskFlatVer2BackingInfo).ThinProvisioned {
+		t.Error("Expected the disk backing of the template to be left unchanged")
+	}
+}
+
+func TestGetStoragePolicies(t *testing.T) {
+	model, session, server := initSimulator(t)
+	t.Cleanup(model.Remove)
+	t.Cleanup(server.Close)
+
+	vmContext := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+	vmContext.Session = session
+
+	policies, err := getStoragePolicies(vmContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policies != nil {
+		t.Errorf("Expected no storage policies to be looked up, got %v", policies.profileIDs)
+	}
+
+	vmContext.VSphereVM.Spec.StoragePolicyName = "vSAN Default Storage Policy"
+	vmContext.VSphereVM.Spec.Disks = []v1beta1.DiskSpec{{SizeGiB: 10, StoragePolicyName: "missing"}}
+	if _, err := getStoragePolicies(vmContext); err == nil {
+		t.Fatal("Expected an error for a missing storage policy")
+	}
+	if reason := conditions.GetReason(vmContext.VSphereVM, v1beta1.StoragePolicyAvailableCondition); reason != v1beta1.StoragePolicyNotFoundReason {
+		t.Errorf("Expected condition reason %s, got %q", v1beta1.StoragePolicyNotFoundReason, reason)
+	}
+
+	vmContext.VSphereVM.Spec.Disks = nil
+	policies, err = getStoragePolicies(vmContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !conditions.IsTrue(vmContext.VSphereVM, v1beta1.StoragePolicyAvailableCondition) {
+		t.Error("Expected the storage policy condition to be true")
+	}
+	datastoreRef, err := policies.datastore(vmContext, "vSAN Default Storage Policy", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if datastoreRef.Type != "Datastore" {
+		t.Errorf("Expected a compatible datastore, got %v", datastoreRef)
+	}
+}
+
+func TestGetFailureDomainDatastore(t *testing.T) {
+	model, session, server := initSimulator(t)
+	t.Cleanup(model.Remove)
+	t.Cleanup(server.Close)
+
+	vmContext := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+	vmContext.Session = session
+
+	datastoreRef, err := getFailureDomainDatastore(vmContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if datastoreRef != nil {
+		t.Errorf("Expected no datastore without failure domain, got %v", datastoreRef)
+	}
+
+	vmContext.VSphereFailureDomain = &v1beta1.VSphereFailureDomain{
+		Spec: v1beta1.VSphereFailureDomainSpec{Topology: v1beta1.Topology{DatastoreCluster: "pod-a"}},
+	}
+	if _, err := getFailureDomainDatastore(vmContext); err == nil {
+		t.Fatal("Expected an error for a missing datastore cluster")
+	}
+
+	datacenter, err := session.Finder.DefaultDatacenter(ctx.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	folders, err := datacenter.Folders(ctx.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod, err := folders.DatastoreFolder.CreateStoragePod(ctx.TODO(), "pod-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getFailureDomainDatastore(vmContext); err == nil {
+		t.Fatal("Expected an error for an empty datastore cluster")
+	}
+
+	datastore, err := session.Finder.Datastore(ctx.TODO(), "LocalDS_0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	task, err := pod.MoveInto(ctx.TODO(), []types.ManagedObjectReference{datastore.Reference()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := task.Wait(ctx.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	datastoreRef, err = getFailureDomainDatastore(vmContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if datastoreRef == nil || *datastoreRef != datastore.Reference() {
+		t.Errorf("Expected datastore %v, got %v", datastore.Reference(), datastoreRef)
+	}
+}
+
+func TestCreateTargetHierarchy(t *testing.T) {
+	model, session, server := initSimulator(t)
+	t.Cleanup(model.Remove)
+	t.Cleanup(server.Close)
+
+	vmContext := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+	vmContext.Session = session
+	vmContext.VSphereVM.Spec.Folder = "/DC0/vm/capv/workload"
+	vmContext.VSphereVM.Spec.ResourcePool = "capv/workload"
+
+	if _, err := getFolder(vmContext); err == nil {
+		t.Fatal("Expected an error for a missing folder")
+	}
+	if _, err := getResourcePool(vmContext, vmContext.VSphereVM.Spec.ResourcePool); err == nil {
+		t.Fatal("Expected an error for a missing resource pool")
+	}
+
+	cpuLimit, memoryReservation := int64(2000), int64(1024)
+	vmContext.VSphereVM.Labels = map[string]string{clusterv1.ClusterLabelName: "test-cluster"}
+	vmContext.VSphereVM.Spec.CreateTargetHierarchy = true
+	vmContext.VSphereVM.Spec.ResourcePoolLimits = &v1beta1.ResourcePoolLimits{
+		CPULimitMHz:          &cpuLimit,
+		MemoryReservationMiB: &memoryReservation,
+	}
+	// The second pass finds the hierarchy created by the first one.
+	var pool *object.ResourcePool
+	for i := 0; i < 2; i++ {
+		folder, err := getFolder(vmContext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if folder.InventoryPath != "/DC0/vm/capv/workload" {
+			t.Errorf("Expected folder /DC0/vm/capv/workload, got %s", folder.InventoryPath)
+		}
+		pool, err = getResourcePool(vmContext, vmContext.VSphereVM.Spec.ResourcePool)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pool.InventoryPath != "/DC0/host/DC0_C0/Resources/capv/workload" {
+			t.Errorf("Expected resource pool /DC0/host/DC0_C0/Resources/capv/workload, got %s", pool.InventoryPath)
+		}
+	}
+
+	// The created folders and resource pools are owned by the cluster.
+	tag, err := session.TagManager.GetTagForCategory(ctx.TODO(), inventory.OwnerTagName(vmContext.VSphereVM.Namespace, "test-cluster"), v1beta1.OwnerTagCategoryName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owned, err := session.TagManager.ListAttachedObjects(ctx.TODO(), tag.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(owned) != 4 {
+		t.Errorf("Expected 4 inventory objects to be owned by the cluster, got %v", owned)
+	}
+
+	config := simulator.Map.Get(pool.Reference()).(*simulator.ResourcePool).Config //nolint:forcetypeassert
+	if limit := config.CpuAllocation.Limit; limit == nil || *limit != cpuLimit {
+		t.Errorf("Expected a CPU limit of %d, got %v", cpuLimit, limit)
+	}
+	if reservation := config.MemoryAllocation.Reservation; reservation == nil || *reservation != memoryReservation {
+		t.Errorf("Expected a memory reservation of %d, got %v", memoryReservation, reservation)
+	}
+}
+
+func TestGetWindowsCustomizationSpec(t *testing.T) {
+	vmContext := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+	vmContext.VSphereVM.Name = "win-vm-1"
+	vmContext.VSphereVM.Spec.OS = v1beta1.Windows
+	vmContext.VSphereVM.Spec.Network.Devices = []v1beta1.NetworkDeviceSpec{
+		{
+			NetworkName:   "VM Network",
+			IPAddrs:       []string{"192.168.4.21/24", "fd00::21/64"},
+			Gateway4:      "192.168.4.1",
+			Gateway6:      "fd00::1",
+			Nameservers:   []string{"8.8.8.8"},
+			SearchDomains: []string{"example.com"},
+		},
+		{
+			NetworkName: "DC0_DVPG0",
+			DHCP4:       true,
+		},
+	}
+
+	spec, err := getWindowsCustomizationSpec(vmContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sysprep, ok := spec.Identity.(*types.CustomizationSysprep)
+	if !ok {
+		t.Fatalf("Expected a Sysprep identity, got %T", spec.Identity)
+	}
+	if name := sysprep.UserData.ComputerName.(*types.CustomizationFixedName).Name; name != "win-vm-1" {
+		t.Errorf("Expected computer name win-vm-1, got %s", name)
+	}
+	if len(spec.NicSettingMap) != 2 {
+		t.Fatalf("Expected 2 adapters, got %d", len(spec.NicSettingMap))
+	}
+
+	static := spec.NicSettingMap[0].Adapter
+	if ip, ok := static.Ip.(*types.CustomizationFixedIp); !ok || ip.IpAddress != "192.168.4.21" {
+		t.Errorf("Expected fixed ip 192.168.4.21, got %#v", static.Ip)
+	}
+	if static.SubnetMask != "255.255.255.0" {
+		t.Errorf("Expected subnet mask 255.255.255.0, got %s", static.SubnetMask)
+	}
+	if static.IpV6Spec == nil || len(static.IpV6Spec.Ip) != 1 || static.IpV6Spec.Ip[0].(*types.CustomizationFixedIpV6).SubnetMask != 64 {
+		t.Errorf("Expected fixed ipv6 address with prefix 64, got %#v", static.IpV6Spec)
+	}
+	if _, ok := spec.NicSettingMap[1].Adapter.Ip.(*types.CustomizationDhcpIpGenerator); !ok {
+		t.Errorf("Expected dhcp for the second adapter, got %#v", spec.NicSettingMap[1].Adapter.Ip)
+	}
+	if len(spec.GlobalIPSettings.DnsSuffixList) != 1 || spec.GlobalIPSettings.DnsSuffixList[0] != "example.com" {
+		t.Errorf("Expected dns suffix example.com, got %v", spec.GlobalIPSettings.DnsSuffixList)
+	}
+}
+
 func TestPCISpec(t *testing.T) {
 	defaultVendorID := int32(7864)
 	defaultDeviceID := int32(4318)
@@ -175,6 +580,23 @@ func TestPCISpec(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "vGPU device",
+			deviceSpecs: []v1beta1.PCIDeviceSpec{
+				{
+					VGPUProfile: "grid_t4-4q",
+				},
+			},
+		},
+		{
+			name: "device without IDs or vGPU profile",
+			deviceSpecs: []v1beta1.PCIDeviceSpec{
+				{
+					DeviceID: &defaultDeviceID,
+				},
+			},
+			err: "pci device 0 requires either a vGPU profile or a device and vendor ID",
+		},
 	}
 
 	for _, test := range testCases {
@@ -189,22 +611,362 @@ func TestPCISpec(t *testing.T) {
 			}
 			vmContext := &context.VMContext{VSphereVM: vsphereVM}
 			deviceSpecs, err := getGpuSpecs(vmContext)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("Expected to get '%v' error from getGpuSpecs, got: '%v'", tc.err, err)
+				}
+				return
+			}
 			if err != nil {
 				t.Fatal(err)
 			}
 			if len(deviceSpecs) != len(tc.deviceSpecs) {
 				t.Fatalf("Expected number of deviceSpecs: %d, but got: '%d'", len(deviceSpecs), len(tc.deviceSpecs))
 			}
-			for _, deviceSpec := range deviceSpecs {
+			for i, deviceSpec := range deviceSpecs {
 				if deviceSpec.GetVirtualDeviceConfigSpec().Operation != types.VirtualDeviceConfigSpecOperationAdd {
 					t.Fatalf("incorrect operation: %s", deviceSpec.GetVirtualDeviceConfigSpec().Operation)
 				}
+				if profile := tc.deviceSpecs[i].VGPUProfile; profile != "" {
+					backing, ok := deviceSpec.GetVirtualDeviceConfigSpec().Device.GetVirtualDevice().Backing.(*types.VirtualPCIPassthroughVmiopBackingInfo)
+					if !ok || backing.Vgpu != profile {
+						t.Fatalf("Expected vGPU backing with profile %s, got: %#v", profile, deviceSpec.GetVirtualDeviceConfigSpec().Device.GetVirtualDevice().Backing)
+					}
+				}
 			}
 			validatePCISpec(t, vmContext.VSphereVM.Spec.PciDevices, tc.deviceSpecs)
 		})
 	}
 }
 
+func TestCreateLinkedCloneSnapshot(t *testing.T) {
+	model, session, server := initSimulator(t)
+	t.Cleanup(model.Remove)
+	t.Cleanup(server.Close)
+	vm := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine) //nolint:forcetypeassert
+	machine := object.NewVirtualMachine(session.Client.Client, vm.Reference())
+
+	vmContext := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+	vmContext.Session = session
+	vmContext.VSphereVM.Spec.Template = vm.Name
+	vmContext.VSphereVM.Spec.Snapshot = "linked-clone"
+
+	// The snapshot is created by a task which is not waited on.
+	taskRef, snapshotRef, err := createLinkedCloneSnapshot(vmContext, machine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if taskRef == nil || snapshotRef != nil {
+		t.Fatal("Expected a snapshot to be created")
+	}
+
+	// The VMs cloned concurrently wait for the same snapshot.
+	otherTaskRef, otherSnapshotRef, err := createLinkedCloneSnapshot(vmContext, machine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otherSnapshotRef == nil && (otherTaskRef == nil || *otherTaskRef != *taskRef) {
+		t.Errorf("Expected to wait for task %v, got %v", *taskRef, otherTaskRef)
+	}
+
+	info, err := object.NewTask(session.Client.Client, *taskRef).WaitForResult(ctx.TODO(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskRef, snapshotRef, err = createLinkedCloneSnapshot(vmContext, machine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if taskRef != nil {
+		t.Fatalf("Expected the created snapshot to be used, got task %v", *taskRef)
+	}
+	if snapshotRef == nil || *snapshotRef != info.Result.(types.ManagedObjectReference) { //nolint:forcetypeassert
+		t.Errorf("Expected snapshot %v, got %v", info.Result, snapshotRef)
+	}
+	if _, err := machine.FindSnapshot(ctx.TODO(), "linked-clone"); err != nil {
+		t.Fatalf("Expected a single snapshot to be created: %v", err)
+	}
+
+	// Snapshots of templates are not supported.
+	vm.Config.Template = true
+	taskRef, snapshotRef, err = createLinkedCloneSnapshot(vmContext, machine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if taskRef != nil || snapshotRef != nil {
+		t.Errorf("Expected no snapshot to be created for a template, got task %v", taskRef)
+	}
+}
+
+// instantCloneVirtualMachine is a simulated VM which supports instant
+// clones, as full clones powered on.
+type instantCloneVirtualMachine struct {
+	simulator.VirtualMachine
+}
+
+func (vm *instantCloneVirtualMachine) InstantCloneTask(ctx *simulator.Context, req *types.InstantClone_Task) soap.HasFault {
+	res := vm.CloneVMTask(ctx, &types.CloneVM_Task{
+		This:   req.This,
+		Folder: *req.Spec.Location.Folder,
+		Name:   req.Spec.Name,
+		Spec: types.VirtualMachineCloneSpec{
+			Location: req.Spec.Location,
+			Config:   &types.VirtualMachineConfigSpec{ExtraConfig: req.Spec.Config},
+			PowerOn:  true,
+		},
+	})
+	if res.Fault() != nil {
+		return res
+	}
+	return &methods.InstantClone_TaskBody{
+		Res: &types.InstantClone_TaskResponse{Returnval: res.(*methods.CloneVM_TaskBody).Res.Returnval}, //nolint:forcetypeassert
+	}
+}
+
+func TestInstantClone(t *testing.T) {
+	model, session, server := initSimulator(t)
+	t.Cleanup(model.Remove)
+	t.Cleanup(server.Close)
+	vm := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine) //nolint:forcetypeassert
+
+	vmContext := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+	vmContext.Session = session
+	vmContext.VSphereVM.Spec.Template = vm.Name
+	vmContext.VSphereVM.Spec.CloneMode = v1beta1.InstantClone
+
+	waitForTask := func() types.AnyType {
+		t.Helper()
+		task := object.NewTask(session.Client.Client, types.ManagedObjectReference{Type: "Task", Value: vmContext.VSphereVM.Status.TaskRef})
+		info, err := task.WaitForResult(ctx.TODO(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vmContext.VSphereVM.Status.TaskRef = ""
+		return info.Result
+	}
+
+	// The parent VM is created and powered on first.
+	if err := Clone(vmContext, []byte("bootstrap")); err != nil {
+		t.Fatal(err)
+	}
+	if reason := conditions.GetReason(vmContext.VSphereVM, v1beta1.CloneStartedCondition); reason != v1beta1.WaitingForInstantCloneParentReason {
+		t.Fatalf("Expected reason %s, got %s", v1beta1.WaitingForInstantCloneParentReason, reason)
+	}
+	parent := simulator.Map.Get(waitForTask().(types.ManagedObjectReference)).(*simulator.VirtualMachine) //nolint:forcetypeassert
+	if parent.Config.InstanceUuid == string(vmContext.VSphereVM.UID) {
+		t.Error("Expected the parent vm not to have the instance uuid of the VSphereVM")
+	}
+
+	// vcsim does not power on clones, so the parent VM is powered on by
+	// another task.
+	if err := Clone(vmContext, []byte("bootstrap")); err != nil {
+		t.Fatal(err)
+	}
+	waitForTask()
+	if parent.Runtime.PowerState != types.VirtualMachinePowerStatePoweredOn {
+		t.Errorf("Expected the parent vm to be powered on, got %s", parent.Runtime.PowerState)
+	}
+
+	// The clone waits for the guest OS to freeze the parent VM.
+	if err := Clone(vmContext, []byte("bootstrap")); err != nil {
+		t.Fatal(err)
+	}
+	if vmContext.VSphereVM.Status.TaskRef != "" {
+		t.Fatalf("Expected no task before the parent vm is frozen, got %s", vmContext.VSphereVM.Status.TaskRef)
+	}
+
+	frozen := true
+	parent.Runtime.InstantCloneFrozen = &frozen
+	simulator.Map.Put(&instantCloneVirtualMachine{*parent})
+	if err := Clone(vmContext, []byte("bootstrap")); err != nil {
+		t.Fatal(err)
+	}
+	if vmContext.VSphereVM.Status.CloneMode != v1beta1.InstantClone {
+		t.Errorf("Expected clone mode %s, got %s", v1beta1.InstantClone, vmContext.VSphereVM.Status.CloneMode)
+	}
+	child := simulator.Map.Get(waitForTask().(types.ManagedObjectReference)).(*simulator.VirtualMachine) //nolint:forcetypeassert
+	if child.Name != vmContext.VSphereVM.Name {
+		t.Errorf("Expected vm %s, got %s", vmContext.VSphereVM.Name, child.Name)
+	}
+	for _, option := range child.Config.ExtraConfig {
+		if value := option.GetOptionValue(); value.Key == InstantCloneParentKey && value.Value != "false" {
+			t.Errorf("Expected %s to be false, got %v", InstantCloneParentKey, value.Value)
+		}
+	}
+}
+
+func TestHostClone(t *testing.T) {
+	model := simulator.ESX()
+	if err := model.Create(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(model.Remove)
+	model.Service.TLS = new(tls.Config)
+	server := model.Service.NewServer()
+	t.Cleanup(server.Close)
+	pass, _ := server.URL.User.Password()
+	session, err := session.GetOrCreate(
+		ctx.TODO(),
+		session.NewParams().
+			WithServer(server.URL.Host).
+			WithUserInfo(server.URL.User.Username(), pass).
+			WithDatacenter("*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine) //nolint:forcetypeassert
+	templateDisks := object.VirtualDeviceList(vm.Config.Hardware.Device).SelectByType((*types.VirtualDisk)(nil))
+
+	vmContext := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+	vmContext.Session = session
+	vmContext.VSphereVM.Spec.Datacenter = "ha-datacenter"
+	vmContext.VSphereVM.Spec.Template = vm.Name
+	vmContext.VSphereVM.Spec.Network.Devices = []v1beta1.NetworkDeviceSpec{{NetworkName: "VM Network"}}
+
+	// The disks of the template are copied one at a time.
+	for i := range templateDisks {
+		if err := Clone(vmContext, []byte("bootstrap")); err != nil {
+			t.Fatal(err)
+		}
+		if reason := conditions.GetReason(vmContext.VSphereVM, v1beta1.CloneStartedCondition); reason != v1beta1.CopyingTemplateDisksReason {
+			t.Fatalf("Expected reason %s for disk %d, got %s", v1beta1.CopyingTemplateDisksReason, i, reason)
+		}
+		task := object.NewTask(session.Client.Client, types.ManagedObjectReference{Type: "Task", Value: vmContext.VSphereVM.Status.TaskRef})
+		if err := task.Wait(ctx.TODO()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The VM is created from the copies once all of them exist.
+	conditions.Delete(vmContext.VSphereVM, v1beta1.CloneStartedCondition)
+	if err := Clone(vmContext, []byte("bootstrap")); err != nil {
+		t.Fatal(err)
+	}
+	if vmContext.VSphereVM.Status.CloneMode != v1beta1.FullClone {
+		t.Errorf("Expected clone mode %s, got %s", v1beta1.FullClone, vmContext.VSphereVM.Status.CloneMode)
+	}
+	task := object.NewTask(session.Client.Client, types.ManagedObjectReference{Type: "Task", Value: vmContext.VSphereVM.Status.TaskRef})
+	info, err := task.WaitForResult(ctx.TODO(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := simulator.Map.Get(info.Result.(types.ManagedObjectReference)).(*simulator.VirtualMachine) //nolint:forcetypeassert
+	if created.Config.InstanceUuid != string(vmContext.VSphereVM.UID) {
+		t.Errorf("Expected instance uuid %s, got %s", vmContext.VSphereVM.UID, created.Config.InstanceUuid)
+	}
+	devices := object.VirtualDeviceList(created.Config.Hardware.Device)
+	disks := devices.SelectByType((*types.VirtualDisk)(nil))
+	if len(disks) != len(templateDisks) {
+		t.Fatalf("Expected %d disks, got %d", len(templateDisks), len(disks))
+	}
+	fileName := disks[0].GetVirtualDevice().Backing.(types.BaseVirtualDeviceFileBackingInfo).GetVirtualDeviceFileBackingInfo().FileName //nolint:forcetypeassert
+	if expected := fmt.Sprintf("[LocalDS_0] %s/%s.vmdk", vmContext.VSphereVM.Name, vmContext.VSphereVM.Name); fileName != expected {
+		t.Errorf("Expected disk %s, got %s", expected, fileName)
+	}
+	if nics := devices.SelectByType((*types.VirtualEthernetCard)(nil)); len(nics) != 1 {
+		t.Errorf("Expected 1 network device, got %d", len(nics))
+	}
+}
+
+func TestSetFirmware(t *testing.T) {
+	config := &types.VirtualMachineConfigSpec{}
+	setFirmware(&v1beta1.VirtualMachineCloneSpec{}, config)
+	if config.Firmware != "" || config.BootOptions != nil {
+		t.Errorf("Expected the firmware of the template to be kept, got %q", config.Firmware)
+	}
+
+	setFirmware(&v1beta1.VirtualMachineCloneSpec{Firmware: v1beta1.FirmwareEFI, SecureBoot: true}, config)
+	if config.Firmware != string(types.GuestOsDescriptorFirmwareTypeEfi) {
+		t.Errorf("Expected firmware %q, got %q", types.GuestOsDescriptorFirmwareTypeEfi, config.Firmware)
+	}
+	if config.BootOptions == nil || !*config.BootOptions.EfiSecureBootEnabled {
+		t.Error("Expected secure boot to be enabled")
+	}
+}
+
+func TestSetGuestToolsUpgradePolicy(t *testing.T) {
+	config := &types.VirtualMachineConfigSpec{}
+	setGuestToolsUpgradePolicy(&v1beta1.VirtualMachineCloneSpec{GuestTools: &v1beta1.GuestToolsSpec{RequireRunning: true}}, config)
+	if config.Tools != nil {
+		t.Errorf("Expected the upgrade policy of the template to be kept, got %+v", config.Tools)
+	}
+
+	setGuestToolsUpgradePolicy(&v1beta1.VirtualMachineCloneSpec{GuestTools: &v1beta1.GuestToolsSpec{UpgradePolicy: v1beta1.GuestToolsUpgradePolicyUpgradeAtPowerCycle}}, config)
+	if config.Tools == nil || config.Tools.ToolsUpgradePolicy != string(types.UpgradePolicyUpgradeAtPowerCycle) {
+		t.Errorf("Expected upgrade policy %q, got %+v", types.UpgradePolicyUpgradeAtPowerCycle, config.Tools)
+	}
+}
+
+func TestSetLatencyTuning(t *testing.T) {
+	config := &types.VirtualMachineConfigSpec{}
+	setLatencyTuning(&v1beta1.VirtualMachineCloneSpec{}, config)
+	if config.LatencySensitivity != nil || config.CpuAffinity != nil || len(config.ExtraConfig) > 0 {
+		t.Errorf("Expected the latency tuning of the template to be kept, got %+v", config)
+	}
+
+	setLatencyTuning(&v1beta1.VirtualMachineCloneSpec{
+		LatencySensitivity: v1beta1.LatencySensitivityHigh,
+		NumaNodeAffinity:   []int32{0, 1},
+		CPUPinning:         []int32{4, 5},
+	}, config)
+	if config.LatencySensitivity == nil || config.LatencySensitivity.Level != types.LatencySensitivitySensitivityLevelHigh {
+		t.Errorf("Expected a high latency sensitivity, got %+v", config.LatencySensitivity)
+	}
+	if config.MemoryReservationLockedToMax == nil || !*config.MemoryReservationLockedToMax {
+		t.Error("Expected the memory of the VM to be reserved")
+	}
+	if len(config.ExtraConfig) != 1 || config.ExtraConfig[0].GetOptionValue().Value != "0,1" {
+		t.Errorf("Expected numa.nodeAffinity 0,1, got %+v", config.ExtraConfig)
+	}
+	if config.CpuAffinity == nil || len(config.CpuAffinity.AffinitySet) != 2 {
+		t.Errorf("Expected the CPU affinity 4,5, got %+v", config.CpuAffinity)
+	}
+}
+
+func TestGetVTPMSpec(t *testing.T) {
+	model, session, server := initSimulator(t)
+	t.Cleanup(model.Remove)
+	t.Cleanup(server.Close)
+
+	vmContext := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+	vmContext.Session = session
+	vmContext.VSphereVM.Spec.VTPM = true
+
+	// vcsim has no key provider.
+	if _, err := getVTPMSpec(vmContext); err == nil {
+		t.Fatal("Expected an error without a key provider")
+	}
+}
+
+func TestGetPortGroup(t *testing.T) {
+	model, session, server := initSimulator(t)
+	t.Cleanup(model.Remove)
+	t.Cleanup(server.Close)
+
+	vmContext := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+	vmContext.Session = session
+
+	netSpec := &v1beta1.NetworkDeviceSpec{NetworkName: "capv-vlan-100", SwitchName: "DVS0", VLANID: 100}
+	created, err := getPortGroup(vmContext, netSpec)
+	if err != nil {
+		t.Fatalf("Expected the port group to be created: %v", err)
+	}
+	existing, err := getPortGroup(vmContext, netSpec)
+	if err != nil {
+		t.Fatalf("Expected the existing port group to be returned: %v", err)
+	}
+	if created.Reference() != existing.Reference() {
+		t.Errorf("Expected port group %s, got %s", created.Reference(), existing.Reference())
+	}
+
+	if _, err := getPortGroup(vmContext, &v1beta1.NetworkDeviceSpec{NetworkName: "capv-vlan-100", SwitchName: "DVS0", VLANID: 200}); err == nil {
+		t.Error("Expected an error for a port group with another VLAN ID")
+	}
+	if _, err := getPortGroup(vmContext, &v1beta1.NetworkDeviceSpec{NetworkName: "capv-vlan-100", SwitchName: "VM Network", VLANID: 100}); err == nil {
+		t.Error("Expected an error for a network that is not a distributed switch")
+	}
+}
+
 func validateDiskSpec(t *testing.T, device types.BaseVirtualDeviceConfigSpec, cloneDiskSize int32) {
 	t.Helper()
 	disk := device.GetVirtualDeviceConfigSpec().Device.(*types.VirtualDisk)
@@ -218,14 +980,50 @@ func validateDiskSpec(t *testing.T, device types.BaseVirtualDeviceConfigSpec, cl
 	}
 }
 
+func validateDataDiskSpec(t *testing.T, device types.BaseVirtualDeviceConfigSpec, diskSpec v1beta1.DiskSpec) {
+	t.Helper()
+	configSpec := device.GetVirtualDeviceConfigSpec()
+	if configSpec.Operation != types.VirtualDeviceConfigSpecOperationAdd {
+		t.Errorf("Disk operation does not match '%s', got: %s", types.VirtualDeviceConfigSpecOperationAdd, configSpec.Operation)
+	}
+	if configSpec.FileOperation != types.VirtualDeviceConfigSpecFileOperationCreate {
+		t.Errorf("Disk file operation does not match '%s', got: %s", types.VirtualDeviceConfigSpecFileOperationCreate, configSpec.FileOperation)
+	}
+	disk := configSpec.Device.(*types.VirtualDisk)
+	expectedSizeKB := int64(diskSpec.SizeGiB) * 1024 * 1024
+	if disk.CapacityInKB != expectedSizeKB {
+		t.Errorf("Disk size does not match: expected %d, got %d", expectedSizeKB, disk.CapacityInKB)
+	}
+	backing := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo)
+	thin := diskSpec.ProvisioningMode == "" || diskSpec.ProvisioningMode == v1beta1.ThinProvisioningMode
+	if *backing.ThinProvisioned != thin {
+		t.Errorf("Disk thin provisioning does not match: expected %t, got %t", thin, *backing.ThinProvisioned)
+	}
+	if eagerlyScrub := diskSpec.ProvisioningMode == v1beta1.EagerZeroedThickProvisioningMode; (backing.EagerlyScrub != nil && *backing.EagerlyScrub) != eagerlyScrub {
+		t.Errorf("Disk eager zeroing does not match: expected %t, got %v", eagerlyScrub, backing.EagerlyScrub)
+	}
+	if diskSpec.Datastore != "" && backing.FileName != "["+diskSpec.Datastore+"]" {
+		t.Errorf("Disk file name does not match: expected [%s], got %s", diskSpec.Datastore, backing.FileName)
+	}
+	if hasProfile := len(configSpec.Profile) > 0; hasProfile != (diskSpec.StoragePolicyName != "") {
+		t.Errorf("Disk storage policy does not match: expected %q, got profile %v", diskSpec.StoragePolicyName, configSpec.Profile)
+	}
+}
+
 func validatePCISpec(t *testing.T, devices []v1beta1.PCIDeviceSpec, expectedDevices []v1beta1.PCIDeviceSpec) {
 	t.Helper()
 	expectedDeviceMap := make(map[int32]int32, len(expectedDevices))
 	for _, expected := range expectedDevices {
+		if expected.VGPUProfile != "" {
+			continue
+		}
 		expectedDeviceMap[*expected.DeviceID] = *expected.VendorID
 	}
 
 	for _, device := range devices {
+		if device.VGPUProfile != "" {
+			continue
+		}
 		val, ok := expectedDeviceMap[*device.DeviceID]
 		if !ok {
 			t.Errorf("expected to found device with deviceID %d", *device.DeviceID)
@@ -262,3 +1060,91 @@ func initSimulator(t *testing.T) (*simulator.Model, *session.Session, *simulator
 
 	return model, authSession, server
 }
+
+func TestCheckCapacity(t *testing.T) {
+	model, session, server := initSimulator(t)
+	t.Cleanup(model.Remove)
+	t.Cleanup(server.Close)
+
+	vmContext := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+	vmContext.Session = session
+	pool, err := getResourcePool(vmContext, "/DC0/host/DC0_C0/Resources")
+	if err != nil {
+		t.Fatal(err)
+	}
+	datastore, err := session.Finder.DefaultDatastore(ctx.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name         string
+		headroom     int
+		numCPUs      int32
+		memoryMiB    int64
+		diskGiB      int64
+		wantFailures int
+	}{
+		{name: "fits", headroom: 10, numCPUs: 2, memoryMiB: 1024, diskGiB: 20},
+		{name: "too many vCPUs", headroom: 10, numCPUs: 64, memoryMiB: 1024, diskGiB: 20, wantFailures: 1},
+		{name: "too much memory", headroom: 10, numCPUs: 2, memoryMiB: 1 << 20, diskGiB: 20, wantFailures: 1},
+		{name: "too large disk", headroom: 10, numCPUs: 2, memoryMiB: 1024, diskGiB: 1 << 20, wantFailures: 1},
+		{name: "no capacity left for the headroom", headroom: 100, numCPUs: 2, memoryMiB: 1024, diskGiB: 20, wantFailures: 2},
+		{name: "check disabled", headroom: -1, numCPUs: 64, memoryMiB: 1 << 20, diskGiB: 1 << 20},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vmContext.CapacityHeadroomPercent = tc.headroom
+			spec := types.VirtualMachineCloneSpec{
+				Config: &types.VirtualMachineConfigSpec{
+					NumCPUs:  tc.numCPUs,
+					MemoryMB: tc.memoryMiB,
+					DeviceChange: []types.BaseVirtualDeviceConfigSpec{
+						&types.VirtualDeviceConfigSpec{
+							Device: &types.VirtualDisk{
+								VirtualDevice: types.VirtualDevice{Backing: &types.VirtualDiskFlatVer2BackingInfo{}},
+								CapacityInKB:  tc.diskGiB << 20,
+							},
+						},
+					},
+				},
+			}
+			failures, err := checkCapacity(vmContext, pool, spec, datastore.Reference())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(failures) != tc.wantFailures {
+				t.Errorf("Expected %d capacity failures, got %v", tc.wantFailures, failures)
+			}
+		})
+	}
+}
+
+func TestGetDatastoreCandidates(t *testing.T) {
+	testCases := []struct {
+		name       string
+		datastore  string
+		datastores []string
+		expected   []string
+	}{
+		{name: "no datastore", expected: []string{""}},
+		{name: "datastore", datastore: "ds0", expected: []string{"ds0"}},
+		{name: "fallback datastores", datastores: []string{"ds1", "ds2"}, expected: []string{"ds1", "ds2"}},
+		{name: "datastore before fallback datastores", datastore: "ds0", datastores: []string{"ds1", "ds0", "ds2"}, expected: []string{"ds0", "ds1", "ds2"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vmContext := fake.NewVMContext(fake.NewControllerContext(fake.NewControllerManagerContext()))
+			vmContext.VSphereVM.Spec.Datastore = tc.datastore
+			vmContext.VSphereVM.Spec.Datastores = tc.datastores
+			if candidates := getDatastoreCandidates(vmContext); !reflect.DeepEqual(candidates, tc.expected) {
+				t.Errorf("Expected datastore candidates %v, got %v", tc.expected, candidates)
+			}
+		})
+	}
+
+	failures := appendFailures([]string{"no host", "datastore ds0 is full"}, []string{"no host", "datastore ds1 is full"})
+	if expected := []string{"no host", "datastore ds0 is full", "datastore ds1 is full"}; !reflect.DeepEqual(failures, expected) {
+		t.Errorf("Expected capacity failures %v, got %v", expected, failures)
+	}
+}
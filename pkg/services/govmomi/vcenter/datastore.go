@@ -0,0 +1,75 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+)
+
+// getDatastoreCandidates returns the names of the datastores the VM may be
+// placed on, in order of preference: its datastore, followed by its fallback
+// datastores. A single empty name is returned for a VM without datastores,
+// which is placed on the datastore cluster of its failure domain, according
+// to its storage policy or on the default datastore.
+func getDatastoreCandidates(ctx *context.VMContext) []string {
+	var candidates []string
+	seen := map[string]bool{}
+	for _, name := range append([]string{ctx.VSphereVM.Spec.Datastore}, ctx.VSphereVM.Spec.Datastores...) {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		candidates = append(candidates, name)
+	}
+	if len(candidates) == 0 {
+		return []string{""}
+	}
+	return candidates
+}
+
+// recordSelectedDatastore records the candidate datastore the VM is placed on
+// in the status of a VSphereVM with fallback datastores, and emits an Event
+// when the preceding candidates lacked free capacity.
+func recordSelectedDatastore(ctx *context.VMContext, datastore string, index int) {
+	if len(ctx.VSphereVM.Spec.Datastores) == 0 {
+		return
+	}
+	ctx.VSphereVM.Status.SelectedDatastore = datastore
+	if index > 0 {
+		ctx.Logger.Info("placing vm on fallback datastore", "datastore", datastore)
+		ctx.Recorder.Eventf(ctx.VSphereVM, "DatastoreFallback",
+			"placing vm on datastore %s, as the preceding datastores lack free capacity", datastore)
+	}
+}
+
+// appendFailures appends the capacity failures which are not reported yet,
+// e.g. the failures of the hosts repeated for every candidate datastore.
+func appendFailures(failures, candidateFailures []string) []string {
+	for _, failure := range candidateFailures {
+		found := false
+		for _, f := range failures {
+			if f == failure {
+				found = true
+				break
+			}
+		}
+		if !found {
+			failures = append(failures, failure)
+		}
+	}
+	return failures
+}
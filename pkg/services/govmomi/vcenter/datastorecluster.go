@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+)
+
+// getFailureDomainDatastore returns the datastore of the datastore cluster
+// of the failure domain of the VM with the most free space. It returns nil
+// if the failure domain has no datastore cluster.
+func getFailureDomainDatastore(ctx *context.VMContext) (*types.ManagedObjectReference, error) {
+	if ctx.VSphereFailureDomain == nil || ctx.VSphereFailureDomain.Spec.Topology.DatastoreCluster == "" {
+		return nil, nil
+	}
+	path := ctx.VSphereFailureDomain.Spec.Topology.DatastoreCluster
+
+	pod, err := ctx.Session.Finder.DatastoreCluster(ctx, path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get datastore cluster %s for %q", path, ctx)
+	}
+	children, err := pod.Children(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list datastores of datastore cluster %s for %q", path, ctx)
+	}
+	var refs []types.ManagedObjectReference
+	for _, child := range children {
+		if ref := child.Reference(); ref.Type == "Datastore" {
+			refs = append(refs, ref)
+		}
+	}
+	if len(refs) == 0 {
+		return nil, errors.Errorf("datastore cluster %s has no datastores", path)
+	}
+
+	var datastores []mo.Datastore
+	if err := property.DefaultCollector(ctx.Session.Client.Client).Retrieve(ctx, refs, []string{"summary"}, &datastores); err != nil {
+		return nil, errors.Wrapf(err, "unable to get datastores of datastore cluster %s for %q", path, ctx)
+	}
+
+	// Datastores entering maintenance mode are not used for new VMs.
+	var best *mo.Datastore
+	for i := range datastores {
+		summary := datastores[i].Summary
+		if !summary.Accessible ||
+			(summary.MaintenanceMode != "" && summary.MaintenanceMode != string(types.DatastoreSummaryMaintenanceModeStateNormal)) {
+			continue
+		}
+		if best == nil || summary.FreeSpace > best.Summary.FreeSpace {
+			best = &datastores[i]
+		}
+	}
+	if best == nil {
+		return nil, errors.Errorf("datastore cluster %s has no accessible datastores", path)
+	}
+	ctx.Logger.Info("placing VM on datastore of datastore cluster", "datastoreCluster", path, "datastore", best.Summary.Name)
+	return types.NewReference(best.Reference()), nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/utils/pointer"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+)
+
+// vtpmDeviceKey is the temporary device key of the virtual TPM added to
+// cloned VMs.
+const vtpmDeviceKey = int32(-300)
+
+// setFirmware sets the firmware and the secure boot flag of the VM spec on
+// the config spec of the clone. The firmware of the template is kept when
+// none is specified.
+func setFirmware(spec *infrav1.VirtualMachineCloneSpec, config *types.VirtualMachineConfigSpec) {
+	if spec.Firmware != "" {
+		config.Firmware = string(spec.Firmware)
+	}
+	if spec.SecureBoot {
+		config.BootOptions = &types.VirtualMachineBootOptions{
+			EfiSecureBootEnabled: pointer.Bool(true),
+		}
+	}
+}
+
+// getVTPMSpec returns the device spec that adds a virtual TPM to the VM,
+// after checking that a key provider is available to encrypt the VM files
+// that hold the state of the TPM.
+func getVTPMSpec(ctx *context.VMContext) (types.BaseVirtualDeviceConfigSpec, error) {
+	if err := checkKeyProvider(ctx); err != nil {
+		return nil, err
+	}
+	return &types.VirtualDeviceConfigSpec{
+		Operation: types.VirtualDeviceConfigSpecOperationAdd,
+		Device: &types.VirtualTPM{
+			VirtualDevice: types.VirtualDevice{
+				Key: vtpmDeviceKey,
+			},
+		},
+	}, nil
+}
+
+// checkKeyProvider returns an error if no key provider, either a KMS cluster
+// or a native key provider, is configured in vCenter.
+func checkKeyProvider(ctx *context.VMContext) error {
+	ref := ctx.Session.Client.ServiceContent.CryptoManager
+	if ref == nil {
+		return errors.Errorf("unable to add vTPM to %q: vCenter does not support encryption", ctx)
+	}
+	var cryptoManager mo.CryptoManagerKmip
+	pc := property.DefaultCollector(ctx.Session.Client.Client)
+	if err := pc.RetrieveOne(ctx, *ref, []string{"kmipServers"}, &cryptoManager); err != nil {
+		return errors.Wrapf(err, "unable to get key providers for vTPM of %q", ctx)
+	}
+	if len(cryptoManager.KmipServers) == 0 {
+		if !ctx.Session.Supports(session.NativeKeyProviderCapability) {
+			return errors.Errorf("unable to add vTPM to %q: no key provider is configured in vCenter, configure a KMS cluster as %s is not available", ctx, session.NativeKeyProviderCapability)
+		}
+		return errors.Errorf("unable to add vTPM to %q: no key provider is configured in vCenter", ctx)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"path"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/inventory"
+)
+
+// getFolder returns the folder the VM is cloned into. The folder and its
+// missing parents are created when the VSphereVM opts in to
+// CreateTargetHierarchy.
+func getFolder(ctx *context.VMContext) (*object.Folder, error) {
+	folderPath := ctx.VSphereVM.Spec.Folder
+	folder, err := ctx.Session.CachedFinder.FolderOrDefault(ctx, folderPath)
+	if !ctx.VSphereVM.Spec.CreateTargetHierarchy || !isNotFound(err) {
+		return folder, err
+	}
+
+	// Relative paths are created in the default folder so they are found
+	// by their absolute path afterwards.
+	if !path.IsAbs(folderPath) {
+		parent, err := ctx.Session.Finder.DefaultFolder(ctx)
+		if err != nil {
+			return nil, err
+		}
+		folderPath = path.Join(parent.InventoryPath, folderPath)
+	}
+	return ensureFolder(ctx, folderPath)
+}
+
+func ensureFolder(ctx *context.VMContext, folderPath string) (*object.Folder, error) {
+	folder, err := ctx.Session.Finder.Folder(ctx, folderPath)
+	parentPath := path.Dir(folderPath)
+	if !isNotFound(err) || parentPath == folderPath {
+		return folder, err
+	}
+
+	parent, err := ensureFolder(ctx, parentPath)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Logger.Info("creating folder", "path", folderPath)
+	folder, err = parent.CreateFolder(ctx, path.Base(folderPath))
+	switch {
+	case err == nil:
+		if err := markOwned(ctx, folder.Reference()); err != nil {
+			return nil, err
+		}
+	case !isDuplicateName(err):
+		return nil, errors.Wrapf(err, "unable to create folder %s", folderPath)
+	}
+	return ctx.Session.Finder.Folder(ctx, folderPath)
+}
+
+// getResourcePool returns the resource pool the VM is cloned into. The
+// resource pool and its missing parents are created when the VSphereVM opts
+// in to CreateTargetHierarchy, the resource pool with the ResourcePoolLimits.
+func getResourcePool(ctx *context.VMContext, poolPath string) (*object.ResourcePool, error) {
+	pool, err := ctx.Session.CachedFinder.ResourcePoolOrDefault(ctx, poolPath)
+	if !ctx.VSphereVM.Spec.CreateTargetHierarchy || !isNotFound(err) {
+		return pool, err
+	}
+
+	// Relative paths are created in the default resource pool so they are
+	// found by their absolute path afterwards.
+	if !path.IsAbs(poolPath) {
+		parent, err := ctx.Session.Finder.DefaultResourcePool(ctx)
+		if err != nil {
+			return nil, err
+		}
+		poolPath = path.Join(parent.InventoryPath, poolPath)
+	}
+	return ensureResourcePool(ctx, poolPath, resourcePoolConfigSpec(ctx.VSphereVM.Spec.ResourcePoolLimits))
+}
+
+func ensureResourcePool(ctx *context.VMContext, poolPath string, spec types.ResourceConfigSpec) (*object.ResourcePool, error) {
+	pool, err := ctx.Session.Finder.ResourcePool(ctx, poolPath)
+	parentPath := path.Dir(poolPath)
+	if !isNotFound(err) || parentPath == poolPath {
+		return pool, err
+	}
+
+	// The limits only apply to the resource pool of the VM, its parents
+	// are created with the default allocation.
+	parent, err := ensureResourcePool(ctx, parentPath, types.DefaultResourceConfigSpec())
+	if err != nil {
+		return nil, err
+	}
+	ctx.Logger.Info("creating resource pool", "path", poolPath)
+	pool, err = parent.Create(ctx, path.Base(poolPath), spec)
+	switch {
+	case err == nil:
+		if err := markOwned(ctx, pool.Reference()); err != nil {
+			return nil, err
+		}
+	case !isDuplicateName(err):
+		return nil, errors.Wrapf(err, "unable to create resource pool %s", poolPath)
+	}
+	return ctx.Session.Finder.ResourcePool(ctx, poolPath)
+}
+
+// markOwned tags the inventory object created for the VM with the owner tag
+// of its cluster, so the object is destroyed along with the cluster.
+func markOwned(ctx *context.VMContext, ref types.ManagedObjectReference) error {
+	clusterName := ctx.VSphereVM.Labels[clusterv1.ClusterLabelName]
+	if clusterName == "" {
+		return nil
+	}
+	return inventory.MarkOwned(ctx, ctx.Session, inventory.OwnerTagName(ctx.VSphereVM.Namespace, clusterName), ref)
+}
+
+// resourcePoolConfigSpec returns the allocation of a resource pool created
+// with the limits.
+func resourcePoolConfigSpec(limits *infrav1.ResourcePoolLimits) types.ResourceConfigSpec {
+	spec := types.DefaultResourceConfigSpec()
+	if limits == nil {
+		return spec
+	}
+	if limits.CPUReservationMHz != nil {
+		spec.CpuAllocation.Reservation = types.NewInt64(*limits.CPUReservationMHz)
+	}
+	if limits.CPULimitMHz != nil {
+		spec.CpuAllocation.Limit = types.NewInt64(*limits.CPULimitMHz)
+	}
+	if limits.MemoryReservationMiB != nil {
+		spec.MemoryAllocation.Reservation = types.NewInt64(*limits.MemoryReservationMiB)
+	}
+	if limits.MemoryLimitMiB != nil {
+		spec.MemoryAllocation.Limit = types.NewInt64(*limits.MemoryLimitMiB)
+	}
+	return spec
+}
+
+func isNotFound(err error) bool {
+	_, ok := err.(*find.NotFoundError)
+	return ok
+}
+
+// isDuplicateName returns whether the inventory object was created
+// concurrently, e.g. by the reconcile of another VM.
+func isDuplicateName(err error) bool {
+	if !soap.IsSoapFault(err) {
+		return false
+	}
+	switch soap.ToSoapFault(err).VimFault().(type) {
+	case types.DuplicateName, *types.DuplicateName:
+		return true
+	default:
+		return false
+	}
+}
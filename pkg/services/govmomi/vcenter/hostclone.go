@@ -0,0 +1,228 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+)
+
+const (
+	// hostCloneControllerKey and hostCloneDiskKey are the first temporary
+	// device keys of the disk controllers and the disks of the template
+	// added to VMs created on standalone ESXi hosts.
+	hostCloneControllerKey = int32(-1000)
+	hostCloneDiskKey       = int32(-2000)
+)
+
+// hostClone creates the VM on a standalone ESXi host, which cannot clone
+// VMs. The disks of the template are copied to the directory of the VM on
+// its datastore one at a time by the stored task, and the VM is created with
+// the disk controllers of the template and the hardware of the spec by the
+// call once all of them are copied. The OS disk is grown to the size of the
+// VM once it is created.
+func hostClone(ctx *context.VMContext, tpl *object.VirtualMachine, folder *object.Folder, pool *object.ResourcePool, spec types.VirtualMachineCloneSpec, datastoreRef types.ManagedObjectReference) error {
+	var obj mo.VirtualMachine
+	if err := tpl.Properties(ctx, tpl.Reference(), []string{"config.guestId", "config.version", "config.firmware", "config.hardware.device"}, &obj); err != nil {
+		return errors.Wrapf(err, "unable to get properties of template %s", ctx.VSphereVM.Spec.Template)
+	}
+	if obj.Config == nil {
+		return errors.Errorf("unable to get config of template %s", ctx.VSphereVM.Spec.Template)
+	}
+
+	datacenter, err := ctx.Session.CachedFinder.DatacenterOrDefault(ctx, ctx.VSphereVM.Spec.Datacenter)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get datacenter for %q", ctx)
+	}
+	datastoreName, err := object.NewDatastore(ctx.Session.Client.Client, datastoreRef).ObjectName(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get name of datastore %s for %q", datastoreRef.Value, ctx)
+	}
+	datastore, err := ctx.Session.CachedFinder.Datastore(ctx, datastoreName)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get datastore %s for %q", datastoreName, ctx)
+	}
+
+	devices := object.VirtualDeviceList(obj.Config.Hardware.Device)
+	disks := devices.SelectByType((*types.VirtualDisk)(nil))
+	copies := make([]string, len(disks))
+	for i, disk := range disks {
+		backing, ok := disk.(*types.VirtualDisk).Backing.(*types.VirtualDiskFlatVer2BackingInfo)
+		if !ok {
+			return errors.Errorf("unable to copy disk %d of template %s: only flat disks can be copied", i, ctx.VSphereVM.Spec.Template)
+		}
+		file := path.Join(ctx.VSphereVM.Name, hostCloneDiskName(ctx.VSphereVM.Name, i))
+		copies[i] = datastore.Path(file)
+
+		_, err := datastore.Stat(ctx, file)
+		switch err.(type) {
+		case nil:
+			continue
+		case object.DatastoreNoSuchDirectoryError:
+			if err := object.NewFileManager(ctx.Session.Client.Client).MakeDirectory(ctx, datastore.Path(ctx.VSphereVM.Name), datacenter, true); err != nil {
+				return errors.Wrapf(err, "unable to create directory of %q on datastore %s", ctx, datastoreName)
+			}
+		case object.DatastoreNoSuchFileError:
+		default:
+			return errors.Wrapf(err, "unable to check disk %s of %q", copies[i], ctx)
+		}
+
+		ctx.Logger.Info("copying disk of template", "disk", backing.FileName, "copy", copies[i])
+		task, err := object.NewVirtualDiskManager(ctx.Session.Client.Client).CopyVirtualDisk(ctx, backing.FileName, datacenter, copies[i], datacenter, hostCloneDiskSpec(ctx, backing), false)
+		if err != nil {
+			return errors.Wrapf(err, "error copying disk %s of template %s", backing.FileName, ctx.VSphereVM.Spec.Template)
+		}
+		ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.CloneStartedCondition, infrav1.CopyingTemplateDisksReason, clusterv1.ConditionSeverityInfo,
+			"copying disk %d of %d of template %s", i+1, len(disks), ctx.VSphereVM.Spec.Template)
+		if err := ctx.Patch(); err != nil {
+			ctx.Logger.Error(err, "patch failed", "vspherevm", ctx.VSphereVM)
+		}
+		return nil
+	}
+
+	config := *spec.Config
+	config.Name = ctx.VSphereVM.Name
+	config.GuestId = obj.Config.GuestId
+	config.Version = obj.Config.Version
+	if config.Firmware == "" {
+		config.Firmware = obj.Config.Firmware
+	}
+	config.Files = &types.VirtualMachineFileInfo{
+		VmPathName: datastore.Path(path.Join(ctx.VSphereVM.Name, ctx.VSphereVM.Name+".vmx")),
+	}
+	config.DeviceChange = getHostCloneDeviceSpecs(devices, copies, datastoreRef, spec.Config.DeviceChange)
+
+	ctx.Logger.Info("creating machine from copy of template", "namespace", ctx.VSphereVM.Namespace, "name", ctx.VSphereVM.Name)
+	task, err := folder.CreateVM(ctx, config, pool, nil)
+	if err != nil {
+		return errors.Wrapf(err, "error creating vm for machine %s", ctx)
+	}
+	ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+	if err := ctx.Patch(); err != nil {
+		ctx.Logger.Error(err, "patch failed", "vspherevm", ctx.VSphereVM)
+	}
+	return nil
+}
+
+// hostCloneDiskName returns the name of the file of the copy of the disk of
+// the template at the index, named after the VM like the disks of the VMs
+// cloned by vCenter.
+func hostCloneDiskName(vmName string, index int) string {
+	if index == 0 {
+		return vmName + ".vmdk"
+	}
+	return fmt.Sprintf("%s_%d.vmdk", vmName, index)
+}
+
+// hostCloneDiskSpec returns the spec of the copy of the disk of the template.
+// The copy keeps the provisioning type of the disk unless the VM requests
+// another one. ESXi defaults to thick provisioned copies otherwise.
+func hostCloneDiskSpec(ctx *context.VMContext, backing *types.VirtualDiskFlatVer2BackingInfo) *types.VirtualDiskSpec {
+	diskType := types.VirtualDiskTypePreallocated
+	switch {
+	case ctx.VSphereVM.Status.DiskProvisioningType == infrav1.ThinProvisioningMode:
+		diskType = types.VirtualDiskTypeThin
+	case ctx.VSphereVM.Status.DiskProvisioningType == infrav1.EagerZeroedThickProvisioningMode:
+		diskType = types.VirtualDiskTypeEagerZeroedThick
+	case ctx.VSphereVM.Status.DiskProvisioningType != "":
+	case backing.ThinProvisioned != nil && *backing.ThinProvisioned:
+		diskType = types.VirtualDiskTypeThin
+	case backing.EagerlyScrub != nil && *backing.EagerlyScrub:
+		diskType = types.VirtualDiskTypeEagerZeroedThick
+	}
+	// The adapter type is only recorded in the descriptor of the disk, the
+	// disk is attached to the controller of the template.
+	return &types.VirtualDiskSpec{
+		DiskType:    string(diskType),
+		AdapterType: string(types.VirtualDiskAdapterTypeLsiLogic),
+	}
+}
+
+// getHostCloneDeviceSpecs returns the device specs that add the disk
+// controllers of the template, the copies of its disks and the devices added
+// by the clone spec to the VM created on a standalone ESXi host. The specs of
+// the clone spec which edit or remove the devices of the template do not
+// apply to the new VM. The IDE controllers are created with the VM.
+func getHostCloneDeviceSpecs(devices object.VirtualDeviceList, copies []string, datastoreRef types.ManagedObjectReference, cloneSpecs []types.BaseVirtualDeviceConfigSpec) []types.BaseVirtualDeviceConfigSpec {
+	var deviceSpecs []types.BaseVirtualDeviceConfigSpec
+	controllerKeys := map[int32]int32{}
+	for _, device := range devices {
+		var controller *types.VirtualController
+		switch c := device.(type) {
+		case types.BaseVirtualSCSIController:
+			controller = c.GetVirtualSCSIController().GetVirtualController()
+		case types.BaseVirtualSATAController:
+			controller = c.GetVirtualSATAController().GetVirtualController()
+		case *types.VirtualNVMEController:
+			controller = c.GetVirtualController()
+		default:
+			continue
+		}
+		key := hostCloneControllerKey - int32(len(controllerKeys))
+		controllerKeys[controller.Key] = key
+		controller.Key = key
+		controller.Device = nil
+		deviceSpecs = append(deviceSpecs, &types.VirtualDeviceConfigSpec{
+			Operation: types.VirtualDeviceConfigSpecOperationAdd,
+			Device:    device,
+		})
+	}
+
+	for i, device := range devices.SelectByType((*types.VirtualDisk)(nil)) {
+		disk := device.(*types.VirtualDisk)                             //nolint:forcetypeassert
+		backing := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo) //nolint:forcetypeassert
+		disk.Key = hostCloneDiskKey - int32(i)
+		if key, ok := controllerKeys[disk.ControllerKey]; ok {
+			disk.ControllerKey = key
+		}
+		disk.Backing = &types.VirtualDiskFlatVer2BackingInfo{
+			VirtualDeviceFileBackingInfo: types.VirtualDeviceFileBackingInfo{
+				FileName:  copies[i],
+				Datastore: types.NewReference(datastoreRef),
+			},
+			DiskMode: backing.DiskMode,
+		}
+		deviceSpecs = append(deviceSpecs, &types.VirtualDeviceConfigSpec{
+			Operation: types.VirtualDeviceConfigSpecOperationAdd,
+			Device:    disk,
+		})
+	}
+
+	for _, spec := range cloneSpecs {
+		deviceSpec := spec.GetVirtualDeviceConfigSpec()
+		if deviceSpec.Operation != types.VirtualDeviceConfigSpecOperationAdd {
+			continue
+		}
+		device := deviceSpec.Device.GetVirtualDevice()
+		if key, ok := controllerKeys[device.ControllerKey]; ok {
+			device.ControllerKey = key
+		}
+		deviceSpecs = append(deviceSpecs, spec)
+	}
+	return deviceSpecs
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/extra"
+)
+
+const (
+	// InstantCloneParentKey is the guestinfo key set to true on the parent
+	// VMs of instant clones, and to false on the instant clones themselves,
+	// so the guest OS of the template knows when to freeze the VM.
+	InstantCloneParentKey = "guestinfo.capv.instantclone.parent"
+)
+
+// parentTasks tracks the tasks creating or powering on the parent VMs of
+// instant clones, keyed by server and inventory path of the parent, so the
+// VMs cloned from the same parent do not start them again. vCenter only
+// registers a cloned VM once its clone completes.
+var parentTasks = &instantCloneParentTasks{tasks: map[string]types.ManagedObjectReference{}}
+
+type instantCloneParentTasks struct {
+	mu    sync.Mutex
+	tasks map[string]types.ManagedObjectReference
+}
+
+func (p *instantCloneParentTasks) get(key string) (types.ManagedObjectReference, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	task, ok := p.tasks[key]
+	return task, ok
+}
+
+func (p *instantCloneParentTasks) set(key string, task types.ManagedObjectReference) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tasks[key] = task
+}
+
+func (p *instantCloneParentTasks) delete(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.tasks, key)
+}
+
+// instantClone kicks off the instant clone of the VM from the parent VM of
+// its template and placement, from which it inherits its virtual hardware.
+// When the parent VM does not exist or is powered off, the stored task
+// creates or powers it on instead, and the instant clone is kicked off by a
+// later call once the guest OS of the parent VM has frozen it.
+func instantClone(ctx *context.VMContext, tpl *object.VirtualMachine, folder *object.Folder, spec types.VirtualMachineCloneSpec, datastoreRef types.ManagedObjectReference, extraConfig extra.Config) error {
+	parentName := instantCloneParentName(ctx, spec, datastoreRef)
+	parentPath := path.Join(folder.InventoryPath, parentName)
+	key := ctx.VSphereVM.Spec.Server + parentPath
+
+	if taskRef, ok := parentTasks.get(key); ok {
+		// Tasks which cannot be retrieved anymore are complete.
+		var task mo.Task
+		err := ctx.Session.RetrieveOne(ctx, taskRef, []string{"info.state"}, &task)
+		if err == nil && (task.Info.State == types.TaskInfoStateQueued || task.Info.State == types.TaskInfoStateRunning) {
+			return waitForInstantCloneParent(ctx, nil, "waiting for parent vm %s to be created or powered on", parentPath)
+		}
+		parentTasks.delete(key)
+	}
+
+	parent, err := ctx.Session.Finder.VirtualMachine(ctx, parentPath)
+	if err != nil {
+		if !isNotFound(err) {
+			return errors.Wrapf(err, "unable to get parent vm %s", parentPath)
+		}
+
+		// The parent VM is a full clone of the template with the hardware
+		// of the VM, but neither its identity nor its bootstrap data.
+		var parentConfig extra.Config
+		if err := parentConfig.SetCustomVMXKeys(ctx.VSphereVM.Spec.CustomVMXKeys); err != nil {
+			return err
+		}
+		parentConfig = append(parentConfig, &types.OptionValue{Key: InstantCloneParentKey, Value: "true"})
+		spec.Config.InstanceUuid = ""
+		spec.Config.ExtraConfig = parentConfig
+		spec.PowerOn = true
+
+		ctx.Logger.Info("creating parent vm for instant clones", "parent", parentPath)
+		task, err := tpl.Clone(ctx, folder, parentName, spec)
+		if err != nil {
+			return errors.Wrapf(err, "error creating parent vm %s", parentPath)
+		}
+		parentTasks.set(key, task.Reference())
+		return waitForInstantCloneParent(ctx, task, "creating parent vm %s", parentPath)
+	}
+
+	var obj mo.VirtualMachine
+	if err := parent.Properties(ctx, parent.Reference(), []string{"runtime.powerState", "runtime.instantCloneFrozen"}, &obj); err != nil {
+		return errors.Wrapf(err, "unable to get runtime information of parent vm %s", parentPath)
+	}
+	if obj.Runtime.PowerState != types.VirtualMachinePowerStatePoweredOn {
+		ctx.Logger.Info("powering on parent vm for instant clones", "parent", parentPath)
+		task, err := parent.PowerOn(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "error powering on parent vm %s", parentPath)
+		}
+		parentTasks.set(key, task.Reference())
+		return waitForInstantCloneParent(ctx, task, "powering on parent vm %s", parentPath)
+	}
+	if obj.Runtime.InstantCloneFrozen == nil || !*obj.Runtime.InstantCloneFrozen {
+		return waitForInstantCloneParent(ctx, nil, "waiting for the guest OS to freeze parent vm %s", parentPath)
+	}
+
+	extraConfig = append(extraConfig, &types.OptionValue{Key: InstantCloneParentKey, Value: "false"})
+	cloneSpec := types.VirtualMachineInstantCloneSpec{
+		Name: ctx.VSphereVM.Name,
+		Location: types.VirtualMachineRelocateSpec{
+			Folder:    spec.Location.Folder,
+			Pool:      spec.Location.Pool,
+			Datastore: &datastoreRef,
+		},
+		Config: extraConfig,
+	}
+	ctx.VSphereVM.Status.CloneMode = infrav1.InstantClone
+	ctx.VSphereVM.Status.Snapshot = ""
+
+	ctx.Logger.Info("cloning machine", "namespace", ctx.VSphereVM.Namespace, "name", ctx.VSphereVM.Name, "cloneType", ctx.VSphereVM.Status.CloneMode, "parent", parentPath)
+	task, err := parent.InstantClone(ctx, cloneSpec)
+	if err != nil {
+		return errors.Wrapf(err, "error trigging instant clone op for machine %s", ctx)
+	}
+	ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+	if err := ctx.Patch(); err != nil {
+		ctx.Logger.Error(err, "patch failed", "vspherevm", ctx.VSphereVM)
+	}
+	return nil
+}
+
+// waitForInstantCloneParent marks the VM as waiting for its parent VM, and
+// tracks the task creating or powering on the parent VM, if any.
+func waitForInstantCloneParent(ctx *context.VMContext, task *object.Task, format string, args ...interface{}) error {
+	if task != nil {
+		ctx.VSphereVM.Status.TaskRef = task.Reference().Value
+	}
+	conditions.MarkFalse(ctx.VSphereVM, infrav1.CloneStartedCondition, infrav1.WaitingForInstantCloneParentReason, clusterv1.ConditionSeverityInfo, format, args...)
+	if err := ctx.Patch(); err != nil {
+		ctx.Logger.Error(err, "patch failed", "vspherevm", ctx.VSphereVM)
+	}
+	return nil
+}
+
+// instantCloneParentName returns the name of the parent VM the VM is
+// instant cloned from. VMs share a parent VM when they are cloned from the
+// same template, in the same resource pool and datastore, with the same
+// virtual hardware.
+func instantCloneParentName(ctx *context.VMContext, spec types.VirtualMachineCloneSpec, datastoreRef types.ManagedObjectReference) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s/%s/%s/%s", ctx.VSphereVM.Spec.Server, ctx.VSphereVM.Spec.Template, spec.Location.Pool.Value, datastoreRef.Value)
+	fmt.Fprintf(h, "/%d/%d/%d/%d/%v", spec.Config.NumCPUs, spec.Config.NumCoresPerSocket, spec.Config.MemoryMB, ctx.VSphereVM.Spec.DiskGiB, ctx.VSphereVM.Spec.AdditionalDisksGiB)
+	if provisioningType := ctx.VSphereVM.Spec.DiskProvisioningType; provisioningType != "" {
+		fmt.Fprintf(h, "/%s", provisioningType)
+	}
+	for _, disk := range ctx.VSphereVM.Spec.Disks {
+		fmt.Fprintf(h, "/%d:%s:%s", disk.SizeGiB, disk.ProvisioningMode, disk.Datastore)
+	}
+	for _, device := range ctx.VSphereVM.Spec.Network.Devices {
+		fmt.Fprintf(h, "/%s", device.NetworkName)
+	}
+	return fmt.Sprintf("%s-parent-%s", path.Base(ctx.VSphereVM.Spec.Template), hex.EncodeToString(h.Sum(nil))[:10])
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+)
+
+// getPortGroup returns the distributed port group of the network device on
+// the distributed switch of the device, creating it with the VLAN ID of the
+// device if it does not exist yet.
+func getPortGroup(ctx *context.VMContext, netSpec *infrav1.NetworkDeviceSpec) (object.NetworkReference, error) {
+	ref, err := ctx.Session.Finder.Network(ctx, netSpec.SwitchName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to find distributed switch %q", netSpec.SwitchName)
+	}
+	dvs, ok := ref.(*object.DistributedVirtualSwitch)
+	if !ok {
+		return nil, errors.Errorf("network %q is not a distributed switch", netSpec.SwitchName)
+	}
+
+	portGroup, err := findPortGroup(ctx, dvs, netSpec)
+	if err != nil || portGroup != nil {
+		return portGroup, err
+	}
+
+	ctx.Logger.Info("creating distributed port group", "switch", netSpec.SwitchName, "portgroup", netSpec.NetworkName, "vlan", netSpec.VLANID)
+	task, err := dvs.AddPortgroup(ctx, []types.DVPortgroupConfigSpec{{
+		Name:       netSpec.NetworkName,
+		Type:       string(types.DistributedVirtualPortgroupPortgroupTypeEarlyBinding),
+		AutoExpand: types.NewBool(true),
+		DefaultPortConfig: &types.VMwareDVSPortSetting{
+			Vlan: &types.VmwareDistributedVirtualSwitchVlanIdSpec{
+				VlanId: netSpec.VLANID,
+			},
+		},
+	}})
+	if err == nil {
+		err = task.Wait(ctx)
+	}
+	// The port group may have been created for another VM in the meantime.
+	portGroup, findErr := findPortGroup(ctx, dvs, netSpec)
+	if findErr != nil {
+		return nil, findErr
+	}
+	if portGroup == nil {
+		return nil, errors.Wrapf(err, "unable to create distributed port group %q on %q", netSpec.NetworkName, netSpec.SwitchName)
+	}
+	return portGroup, nil
+}
+
+// findPortGroup returns the port group of the network device on the
+// distributed switch, or nil if it does not exist. An error is returned if
+// the VLAN ID of the port group does not match the one of the device.
+func findPortGroup(ctx *context.VMContext, dvs *object.DistributedVirtualSwitch, netSpec *infrav1.NetworkDeviceSpec) (object.NetworkReference, error) {
+	var switchMo mo.DistributedVirtualSwitch
+	if err := dvs.Properties(ctx, dvs.Reference(), []string{"portgroup"}, &switchMo); err != nil {
+		return nil, errors.Wrapf(err, "unable to get port groups of distributed switch %q", netSpec.SwitchName)
+	}
+	if len(switchMo.Portgroup) == 0 {
+		return nil, nil
+	}
+
+	var portGroups []mo.DistributedVirtualPortgroup
+	pc := property.DefaultCollector(ctx.Session.Client.Client)
+	if err := pc.Retrieve(ctx, switchMo.Portgroup, []string{"name", "config.defaultPortConfig"}, &portGroups); err != nil {
+		return nil, errors.Wrapf(err, "unable to get port groups of distributed switch %q", netSpec.SwitchName)
+	}
+	for i := range portGroups {
+		portGroup := &portGroups[i]
+		if portGroup.Name != netSpec.NetworkName {
+			continue
+		}
+		if vlanID := getPortGroupVLANID(portGroup); vlanID != netSpec.VLANID {
+			return nil, errors.Errorf("distributed port group %q on %q has VLAN ID %d instead of %d", netSpec.NetworkName, netSpec.SwitchName, vlanID, netSpec.VLANID)
+		}
+		return object.NewDistributedVirtualPortgroup(ctx.Session.Client.Client, portGroup.Reference()), nil
+	}
+	return nil, nil
+}
+
+// getPortGroupVLANID returns the VLAN ID of the port group, which is 0 for
+// untagged port groups and those using trunking or private VLANs.
+func getPortGroupVLANID(portGroup *mo.DistributedVirtualPortgroup) int32 {
+	setting, ok := portGroup.Config.DefaultPortConfig.(*types.VMwareDVSPortSetting)
+	if !ok {
+		return 0
+	}
+	vlan, ok := setting.Vlan.(*types.VmwareDistributedVirtualSwitchVlanIdSpec)
+	if !ok {
+		return 0
+	}
+	return vlan.VlanId
+}
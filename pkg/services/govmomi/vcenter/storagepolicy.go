@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/pbm"
+	pbmTypes "github.com/vmware/govmomi/pbm/types"
+	"github.com/vmware/govmomi/vim25/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+)
+
+// storagePolicies resolves the storage policies requested for a VSphereVM
+// and its data disks.
+type storagePolicies struct {
+	client     *pbm.Client
+	profileIDs map[string]string
+}
+
+// getStoragePolicies looks up the storage policies requested for the VM and
+// its data disks, and marks the StoragePolicyAvailable condition false if
+// any of them does not exist. It returns nil if no storage policy is
+// requested.
+func getStoragePolicies(ctx *context.VMContext) (*storagePolicies, error) {
+	var names []string
+	if ctx.VSphereVM.Spec.StoragePolicyName != "" {
+		names = append(names, ctx.VSphereVM.Spec.StoragePolicyName)
+	}
+	for _, disk := range ctx.VSphereVM.Spec.Disks {
+		if disk.StoragePolicyName != "" {
+			names = append(names, disk.StoragePolicyName)
+		}
+	}
+	if len(names) == 0 {
+		conditions.Delete(ctx.VSphereVM, infrav1.StoragePolicyAvailableCondition)
+		return nil, nil
+	}
+
+	pbmClient, err := ctx.Session.NewPbmClient(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to create pbm client for %q", ctx)
+	}
+	resourceType := pbmTypes.PbmProfileResourceType{
+		ResourceType: string(pbmTypes.PbmProfileResourceTypeEnumSTORAGE),
+	}
+	ids, err := pbmClient.QueryProfile(ctx, resourceType, string(pbmTypes.PbmProfileCategoryEnumREQUIREMENT))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to query storage profiles for %q", ctx)
+	}
+	profiles, err := pbmClient.RetrieveContent(ctx, ids)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to retrieve storage profiles for %q", ctx)
+	}
+
+	policies := &storagePolicies{
+		client:     pbmClient,
+		profileIDs: make(map[string]string, len(profiles)),
+	}
+	for _, p := range profiles {
+		profile := p.GetPbmProfile()
+		policies.profileIDs[profile.Name] = profile.ProfileId.UniqueId
+	}
+
+	var missing []string
+	for _, name := range names {
+		if _, ok := policies.profileIDs[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		msg := strings.Join(missing, ", ")
+		conditions.MarkFalse(ctx.VSphereVM, infrav1.StoragePolicyAvailableCondition, infrav1.StoragePolicyNotFoundReason, clusterv1.ConditionSeverityError,
+			"storage policies not found: %s", msg)
+		return nil, errors.Errorf("storage policies not found for %q: %s", ctx, msg)
+	}
+	conditions.MarkTrue(ctx.VSphereVM, infrav1.StoragePolicyAvailableCondition)
+	return policies, nil
+}
+
+// profileSpec returns the profile spec which applies the storage policy to a
+// virtual machine or disk, or nil if no storage policy is given.
+func (p *storagePolicies) profileSpec(name string) []types.BaseVirtualMachineProfileSpec {
+	if name == "" {
+		return nil
+	}
+	return []types.BaseVirtualMachineProfileSpec{
+		&types.VirtualMachineDefinedProfileSpec{ProfileId: p.profileIDs[name]},
+	}
+}
+
+// datastore returns a datastore compatible with the storage policy. If a
+// datastore is given it is returned when compatible, otherwise a compatible
+// datastore is picked at random.
+func (p *storagePolicies) datastore(ctx *context.VMContext, name string, datastoreRef *types.ManagedObjectReference) (*types.ManagedObjectReference, error) {
+	constraints := []pbmTypes.BasePbmPlacementRequirement{
+		&pbmTypes.PbmPlacementCapabilityProfileRequirement{ProfileId: pbmTypes.PbmProfileId{UniqueId: p.profileIDs[name]}},
+	}
+	result, err := p.client.CheckRequirements(ctx, nil, nil, constraints)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to check requirements for storage policy %s", name)
+	}
+
+	compatible := result.CompatibleDatastores()
+	if len(compatible) == 0 {
+		return nil, errors.Errorf("no compatible datastores found for storage policy: %s", name)
+	}
+	if datastoreRef != nil {
+		for _, ds := range compatible {
+			if ds.HubType == datastoreRef.Type && ds.HubId == datastoreRef.Value {
+				return datastoreRef, nil
+			}
+		}
+		return nil, errors.Errorf("couldn't find datastore %s in compatible list of datastores for storage policy: %s", datastoreRef.Value, name)
+	}
+
+	rand.Seed(time.Now().UnixNano())
+	ds := compatible[rand.Intn(len(compatible))] //nolint:gosec
+	return &types.ManagedObjectReference{Type: ds.HubType, Value: ds.HubId}, nil
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/net"
+)
+
+// vmStateProperties are the properties of the VMs of a cluster retrieved at
+// once for all of them.
+var vmStateProperties = []string{
+	"config.hardware.device",
+	"guest.hostName",
+	"guest.ipStack",
+	"guest.net",
+	"guest.toolsRunningStatus",
+	"guest.toolsVersion",
+	"guest.toolsVersionStatus2",
+	"network",
+	"runtime.powerState",
+}
+
+// vmStates collects the state of the VMs of each cluster.
+var vmStates = newVMStateCollector()
+
+// vmState is the state of a VM retrieved along with the VMs of its cluster.
+type vmState struct {
+	mo.VirtualMachine
+	// networkNames are the names of the networks of the VMs of the cluster,
+	// indexed by the value of their managed object reference.
+	networkNames map[string]string
+}
+
+// vmStateCollector retrieves the guest network, the power state and the
+// VMware Tools status of all the VMs of a cluster with a single
+// PropertyCollector query, which the reconciliations of the VSphereVMs of
+// the cluster share, rather than querying them VM by VM.
+type vmStateCollector struct {
+	mu       sync.Mutex
+	clusters map[string]*clusterVMStates
+}
+
+// clusterVMStates are the states of the VMs of a cluster.
+type clusterVMStates struct {
+	mu sync.Mutex
+	// refs are the VMs of the cluster, registered by the reconciliation of
+	// their VSphereVM.
+	refs map[types.ManagedObjectReference]struct{}
+	// states are the states of the VMs as of retrieved.
+	states    map[types.ManagedObjectReference]*vmState
+	retrieved time.Time
+}
+
+func newVMStateCollector() *vmStateCollector {
+	return &vmStateCollector{clusters: map[string]*clusterVMStates{}}
+}
+
+// get returns the state of the VM from the last retrieval of the states of
+// the VMs of its cluster, which are retrieved again if it is older than the
+// VM state TTL, misses the VM, or the VM changed since, or if refresh is set.
+// It returns nil when the states of VMs are not collected by cluster, or when
+// their retrieval fails, in which case the VM is to be queried on its own.
+func (c *vmStateCollector) get(ctx *virtualMachineContext, refresh bool) *vmState {
+	key := vmStateKey(ctx)
+	if ctx.VMStateTTL <= 0 || key == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	cluster, ok := c.clusters[key]
+	if !ok {
+		cluster = &clusterVMStates{refs: map[types.ManagedObjectReference]struct{}{}}
+		c.clusters[key] = cluster
+	}
+	c.mu.Unlock()
+
+	// The reconciliations of the VSphereVMs of the cluster wait for a single
+	// retrieval of the states.
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	cluster.refs[ctx.Ref] = struct{}{}
+	if state, ok := cluster.states[ctx.Ref]; ok && !refresh && time.Since(cluster.retrieved) < ctx.VMStateTTL {
+		return state
+	}
+
+	states, err := retrieveVMStates(ctx, cluster.refs)
+	if err != nil {
+		// The VMs which do not exist anymore fail the retrieval, the VMs of
+		// the cluster are registered again by their next reconciliation.
+		ctx.Logger.V(4).Info("unable to retrieve the states of the VMs of the cluster", "error", err.Error())
+		cluster.refs = map[types.ManagedObjectReference]struct{}{ctx.Ref: {}}
+		cluster.states = nil
+		return nil
+	}
+	cluster.states = states
+	cluster.retrieved = time.Now()
+	return states[ctx.Ref]
+}
+
+// invalidate drops the state of the VM, so the states of the VMs of its
+// cluster are retrieved again the next time it is reconciled.
+func (c *vmStateCollector) invalidate(key string, ref types.ManagedObjectReference) {
+	c.mu.Lock()
+	cluster, ok := c.clusters[key]
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	delete(cluster.states, ref)
+}
+
+// forget stops collecting the state of the VM, e.g. once it is destroyed.
+func (c *vmStateCollector) forget(key string, ref types.ManagedObjectReference) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cluster, ok := c.clusters[key]
+	if !ok {
+		return
+	}
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	delete(cluster.refs, ref)
+	delete(cluster.states, ref)
+	if len(cluster.refs) == 0 {
+		delete(c.clusters, key)
+	}
+}
+
+// retrieveVMStates retrieves the states of the VMs, and the names of their
+// networks, with a query each.
+func retrieveVMStates(ctx *virtualMachineContext, refs map[types.ManagedObjectReference]struct{}) (map[types.ManagedObjectReference]*vmState, error) {
+	objs := make([]types.ManagedObjectReference, 0, len(refs))
+	for ref := range refs {
+		objs = append(objs, ref)
+	}
+	pc := property.DefaultCollector(ctx.Session.Client.Client)
+	var vms []mo.VirtualMachine
+	if err := pc.Retrieve(ctx, objs, vmStateProperties, &vms); err != nil {
+		return nil, errors.Wrapf(err, "unable to fetch props %v for %d vms", vmStateProperties, len(objs))
+	}
+
+	seen := map[types.ManagedObjectReference]bool{}
+	var networks []types.ManagedObjectReference
+	for i := range vms {
+		for _, network := range vms[i].Network {
+			if !seen[network] {
+				seen[network] = true
+				networks = append(networks, network)
+			}
+		}
+	}
+	networkNames, err := net.GetNetworkNames(ctx, pc, networks)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[types.ManagedObjectReference]*vmState, len(vms))
+	for i := range vms {
+		states[vms[i].Reference()] = &vmState{VirtualMachine: vms[i], networkNames: networkNames}
+	}
+	ctx.Logger.V(4).Info("retrieved the states of the VMs of the cluster", "count", len(states))
+	return states, nil
+}
+
+// vmStateKey returns the key of the cluster of the VM, or an empty string if
+// the VSphereVM is not part of a cluster.
+func vmStateKey(ctx *virtualMachineContext) string {
+	clusterName := ctx.VSphereVM.Labels[clusterv1.ClusterLabelName]
+	if clusterName == "" {
+		return ""
+	}
+	return ctx.VSphereVM.Spec.Server + "/" + ctx.VSphereVM.Namespace + "/" + clusterName
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestVMStateCollector(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	collector := newVMStateCollector()
+	vmCtx := newTestVirtualMachineContext(t, simr)
+	vmCtx.VSphereVM.Labels = map[string]string{clusterv1.ClusterLabelName: "my-cluster"}
+	key := vmStateKey(vmCtx)
+
+	// The states are not collected by cluster without a TTL.
+	g.Expect(collector.get(vmCtx, false)).To(BeNil())
+
+	vmCtx.VMStateTTL = time.Minute
+	state := collector.get(vmCtx, false)
+	g.Expect(state).NotTo(BeNil())
+	g.Expect(state.Runtime.PowerState).To(Equal(types.VirtualMachinePowerStatePoweredOn))
+
+	// The states of the VMs of the cluster are retrieved along with the
+	// state of the VM reconciled next.
+	var other types.ManagedObjectReference
+	for _, ref := range simulator.Map.All("VirtualMachine") {
+		if ref.Reference() != vmCtx.Ref {
+			other = ref.Reference()
+			break
+		}
+	}
+	otherCtx := &virtualMachineContext{
+		VMContext: vmCtx.VMContext,
+		Obj:       object.NewVirtualMachine(vmCtx.Session.Client.Client, other),
+		Ref:       other,
+		State:     &infrav1.VirtualMachine{},
+	}
+	g.Expect(collector.get(otherCtx, false)).NotTo(BeNil())
+	g.Expect(collector.clusters[key].states).To(HaveLen(2))
+	g.Expect(collector.get(vmCtx, false)).NotTo(BeIdenticalTo(state))
+
+	// The states are shared until they expire.
+	state = collector.get(vmCtx, false)
+	g.Expect(collector.get(vmCtx, false)).To(BeIdenticalTo(state))
+
+	// The states are retrieved again once the VM changed.
+	task, err := vmCtx.Obj.PowerOff(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(task.Wait(vmCtx)).To(Succeed())
+	g.Expect(collector.get(vmCtx, false).Runtime.PowerState).To(Equal(types.VirtualMachinePowerStatePoweredOn))
+	collector.invalidate(key, vmCtx.Ref)
+	g.Expect(collector.get(vmCtx, false).Runtime.PowerState).To(Equal(types.VirtualMachinePowerStatePoweredOff))
+
+	// The reconciliations of the VSphereVMs read the states.
+	vmCtx.vmState = collector.get(vmCtx, true)
+	powerState, err := (&VMService{}).getPowerState(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(powerState).To(BeEquivalentTo(infrav1.VirtualMachinePowerStatePoweredOff))
+	network, err := (&VMService{}).getNetworkStatus(vmCtx)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(network).NotTo(BeEmpty())
+
+	// The states of the VMs which are destroyed are not collected anymore.
+	collector.forget(key, vmCtx.Ref)
+	collector.forget(key, other)
+	g.Expect(collector.clusters).NotTo(HaveKey(key))
+}
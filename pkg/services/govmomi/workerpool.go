@@ -0,0 +1,121 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"sync"
+)
+
+// operations runs the long-running vCenter operations of the VSphereVMs,
+// e.g. the OVF export of their VM, in the background so that the reconcile
+// workers are not blocked until they complete.
+var operations = newWorkerPool()
+
+// workerPool runs operations keyed by VSphereVM in background workers, up
+// to a maximum number at once. The operations in excess are queued in the
+// order they are requested.
+type workerPool struct {
+	mu      sync.Mutex
+	running int
+	queue   []*operation
+	// operations is the set of the queued, running, and completed operations
+	// whose result was not collected yet.
+	operations map[string]*operation
+}
+
+type operation struct {
+	key          string
+	fn           func() error
+	onCompletion func()
+	done         bool
+	forgotten    bool
+	err          error
+}
+
+func newWorkerPool() *workerPool {
+	return &workerPool{operations: map[string]*operation{}}
+}
+
+// run queues the operation unless an operation with the same key is already
+// queued or running, and returns whether it completed and its error. The
+// result of a completed operation is returned once, after which the
+// operation is forgotten. onCompletion is called in the background once the
+// operation completes. A max of 0 or less means the number of operations
+// running at once is unlimited.
+func (p *workerPool) run(key string, max int, fn func() error, onCompletion func()) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if op, ok := p.operations[key]; ok {
+		if !op.done {
+			return false, nil
+		}
+		delete(p.operations, key)
+		return true, op.err
+	}
+
+	op := &operation{key: key, fn: fn, onCompletion: onCompletion}
+	p.operations[key] = op
+	p.queue = append(p.queue, op)
+	p.startLocked(max)
+	return false, nil
+}
+
+// startLocked starts the queued operations while fewer than max are running.
+func (p *workerPool) startLocked(max int) {
+	for len(p.queue) > 0 && (max <= 0 || p.running < max) {
+		op := p.queue[0]
+		p.queue = p.queue[1:]
+		p.running++
+		go p.work(op, max)
+	}
+}
+
+func (p *workerPool) work(op *operation, max int) {
+	err := op.fn()
+
+	p.mu.Lock()
+	op.done, op.err = true, err
+	onCompletion := op.onCompletion
+	if op.forgotten {
+		delete(p.operations, op.key)
+		onCompletion = nil
+	}
+	p.running--
+	p.startLocked(max)
+	p.mu.Unlock()
+
+	// The worker is released before onCompletion is called, which may block
+	// e.g. until a GenericEvent is received.
+	if onCompletion != nil {
+		onCompletion()
+	}
+}
+
+// forget forgets the operation with the key once it completes, regardless
+// of its result.
+func (p *workerPool) forget(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if op, ok := p.operations[key]; ok {
+		op.forgotten = true
+		if op.done {
+			delete(p.operations, key)
+		}
+	}
+}
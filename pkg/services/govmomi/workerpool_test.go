@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+func TestWorkerPool(t *testing.T) {
+	t.Run("limits the running operations", func(t *testing.T) {
+		g := NewWithT(t)
+		p := newWorkerPool()
+
+		release := make(chan struct{})
+		started := make(chan string, 3)
+		completed := make(chan string, 3)
+		for _, key := range []string{"vm-1", "vm-2", "vm-3"} {
+			key := key
+			done, err := p.run(key, 2, func() error {
+				started <- key
+				<-release
+				return nil
+			}, func() { completed <- key })
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(done).To(BeFalse())
+		}
+		g.Eventually(started).Should(HaveLen(2))
+		g.Consistently(started).Should(HaveLen(2))
+		g.Expect([]string{<-started, <-started}).To(ConsistOf("vm-1", "vm-2"))
+
+		// The queued operation starts once a running one completes.
+		release <- struct{}{}
+		g.Eventually(started).Should(Receive(Equal("vm-3")))
+		close(release)
+		g.Eventually(completed).Should(HaveLen(3))
+	})
+
+	t.Run("the result of an operation is returned once", func(t *testing.T) {
+		g := NewWithT(t)
+		p := newWorkerPool()
+
+		release := make(chan struct{})
+		runs := 0
+		fn := func() error {
+			runs++
+			<-release
+			return errors.New("export failed")
+		}
+		completed := make(chan struct{})
+		done, err := p.run("vm-1", 0, fn, func() { close(completed) })
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(done).To(BeFalse())
+
+		// The running operation is not started again.
+		done, err = p.run("vm-1", 0, fn, nil)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(done).To(BeFalse())
+
+		close(release)
+		g.Eventually(completed).Should(BeClosed())
+		done, err = p.run("vm-1", 0, fn, nil)
+		g.Expect(err).To(MatchError("export failed"))
+		g.Expect(done).To(BeTrue())
+		g.Expect(p.operations).To(BeEmpty())
+		g.Expect(runs).To(Equal(1))
+	})
+
+	t.Run("a forgotten operation is not reported", func(t *testing.T) {
+		g := NewWithT(t)
+		p := newWorkerPool()
+
+		release := make(chan struct{})
+		completed := make(chan struct{}, 1)
+		_, err := p.run("vm-1", 0, func() error {
+			<-release
+			return nil
+		}, func() { completed <- struct{}{} })
+		g.Expect(err).NotTo(HaveOccurred())
+
+		p.forget("vm-1")
+		close(release)
+		g.Eventually(func() int {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			return len(p.operations)
+		}).Should(BeZero())
+		g.Consistently(completed).ShouldNot(Receive())
+	})
+}
@@ -17,6 +17,9 @@ limitations under the License.
 package services
 
 import (
+	goctx "context"
+	"net"
+
 	vmoprv1 "github.com/vmware-tanzu/vm-operator-api/api/v1alpha1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -26,6 +29,7 @@ import (
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/vmware"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
 )
 
 // VSphereMachineService is used for vsphere VM lifecycle and syncing with VSphereMachine types.
@@ -47,6 +51,59 @@ type VirtualMachineService interface {
 	DestroyVM(ctx *context.VMContext) (infrav1.VirtualMachine, error)
 }
 
+// VirtualMachineSnapshotService is a service for creating and removing
+// snapshots of virtual machines on vSphere.
+type VirtualMachineSnapshotService interface {
+	// CreateSnapshot creates a snapshot of a VM and returns its managed
+	// object reference value.
+	CreateSnapshot(ctx *context.VMContext, name, description string) (string, error)
+
+	// RemoveSnapshot removes a snapshot from a VM.
+	RemoveSnapshot(ctx *context.VMContext, snapshotRef string) error
+}
+
+// MachineImageService is a service for importing the OVAs of
+// VSphereMachineImages as templates on vSphere.
+type MachineImageService interface {
+	// ImportOVA imports the OVA of an image as a template and returns the
+	// inventory path of the template.
+	ImportOVA(ctx goctx.Context, sess *session.Session, image *infrav1.VSphereMachineImage) (string, error)
+}
+
+// IPAllocator allocates the IP addresses of the network devices of VMs from
+// the IPAM of record of a VSphereIPPool, along with their DNS records.
+type IPAllocator interface {
+	// AllocateIP allocates an IP address to the host, or returns the address
+	// already allocated to it, along with the reference of the allocation.
+	// The address is in the CIDR format.
+	AllocateIP(ctx goctx.Context, hostname string) (address string, ref string, err error)
+
+	// ReleaseIP releases the allocation with the reference, along with its
+	// DNS records. Allocations which no longer exist are ignored.
+	ReleaseIP(ctx goctx.Context, ref string) error
+}
+
+// DNSProvider manages the address records of a DNS zone, such as the record
+// of the control plane endpoint of a VSphereCluster.
+type DNSProvider interface {
+	// UpsertRecord sets the address of the record of the name, an A or AAAA
+	// record according to the family of the address.
+	UpsertRecord(ctx goctx.Context, name string, address net.IP, ttl int32) error
+
+	// DeleteRecord deletes the record of the name with the address. Records
+	// which no longer exist are ignored.
+	DeleteRecord(ctx goctx.Context, name string, address net.IP) error
+}
+
+// ControlPlaneEndpointProvider provisions the control plane endpoint of a
+// VSphereCluster, as selected by its control plane endpoint provider annotation.
+type ControlPlaneEndpointProvider interface {
+	// ReconcileControlPlaneEndpoint returns the control plane endpoint of the
+	// cluster once it is provisioned, or nil and a message explaining what the
+	// provider is waiting for.
+	ReconcileControlPlaneEndpoint(ctx *context.ClusterContext) (*infrav1.APIEndpoint, string, error)
+}
+
 // ControlPlaneEndpointService is a service for reconciling load balanced control plane endpoints.
 type ControlPlaneEndpointService interface {
 	// ReconcileControlPlaneEndpointService manages the lifecycle of a
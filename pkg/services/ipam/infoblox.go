@@ -0,0 +1,216 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+// Infoblox allocates the next available addresses of a network of an
+// Infoblox grid through the WAPI, with a host record named after the host
+// each, which also registers the address in DNS when the pool sets a DNS
+// zone.
+type Infoblox struct {
+	spec       infrav1.InfobloxIPPoolSpec
+	network    *net.IPNet
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// hostRecord is the subset of the fields of a WAPI record:host object used
+// by the allocator.
+type hostRecord struct {
+	Ref       string        `json:"_ref,omitempty"`
+	Name      string        `json:"name"`
+	View      string        `json:"view,omitempty"`
+	ConfigDNS *bool         `json:"configure_for_dns,omitempty"`
+	IPv4Addrs []hostAddress `json:"ipv4addrs"`
+}
+
+type hostAddress struct {
+	IPv4Addr string `json:"ipv4addr"`
+}
+
+// NewInfoblox returns an allocator of the addresses of the network of the
+// spec, which authenticates with the given credentials. The certificate of
+// the grid master is only checked against the thumbprint of the spec, if any.
+func NewInfoblox(spec *infrav1.InfobloxIPPoolSpec, username, password string) (*Infoblox, error) {
+	_, network, err := net.ParseCIDR(spec.Network)
+	if err != nil || network.IP.To4() == nil {
+		return nil, errors.Errorf("invalid Infoblox network %q, must be an IPv4 CIDR", spec.Network)
+	}
+	s := *spec
+	if s.WAPIVersion == "" {
+		s.WAPIVersion = infrav1.DefaultInfobloxWAPIVersion
+	}
+	if s.NetworkView == "" {
+		s.NetworkView = infrav1.DefaultInfobloxView
+	}
+	if s.DNSView == "" {
+		s.DNSView = infrav1.DefaultInfobloxView
+	}
+	server := s.Server
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	return &Infoblox{
+		spec:     s,
+		network:  network,
+		baseURL:  strings.TrimSuffix(server, "/") + "/wapi/" + s.WAPIVersion + "/",
+		username: username,
+		password: password,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: util.ThumbprintTLSConfig(s.Thumbprint, "Infoblox grid master"),
+			},
+		},
+	}, nil
+}
+
+// AllocateIP returns the address of the host record of the host in the
+// network, or creates one with the next available address of the network.
+func (i *Infoblox) AllocateIP(ctx context.Context, hostname string) (string, string, error) {
+	name := i.recordName(hostname)
+	query := url.Values{"name": {name}, "_return_fields": {"name,ipv4addrs"}}
+	var records []hostRecord
+	if err := i.do(ctx, http.MethodGet, "record:host?"+query.Encode(), nil, &records); err != nil {
+		return "", "", errors.Wrapf(err, "failed to look up host record %s", name)
+	}
+	for _, record := range records {
+		if address := i.address(record); address != "" {
+			return address, record.Ref, nil
+		}
+	}
+
+	configDNS := i.spec.DNSZone != ""
+	record := hostRecord{Name: name, ConfigDNS: &configDNS}
+	if configDNS {
+		record.View = i.spec.DNSView
+	}
+	record.IPv4Addrs = []hostAddress{{IPv4Addr: fmt.Sprintf("func:nextavailableip:%s,%s", i.network, i.spec.NetworkView)}}
+	query = url.Values{"_return_fields": {"name,ipv4addrs"}}
+	var created hostRecord
+	if err := i.do(ctx, http.MethodPost, "record:host?"+query.Encode(), record, &created); err != nil {
+		return "", "", errors.Wrapf(err, "failed to allocate an address of network %s to host record %s", i.network, name)
+	}
+	address := i.address(created)
+	if address == "" {
+		return "", "", errors.Errorf("host record %s has no address in network %s", name, i.network)
+	}
+	return address, created.Ref, nil
+}
+
+// ReleaseIP deletes the host record, which releases its address and removes
+// its DNS records.
+func (i *Infoblox) ReleaseIP(ctx context.Context, ref string) error {
+	if err := i.do(ctx, http.MethodDelete, ref, nil, nil); err != nil && !isNotFound(err) {
+		return errors.Wrapf(err, "failed to delete host record %s", ref)
+	}
+	return nil
+}
+
+// recordName returns the name of the host record of the host, which is its
+// FQDN when the addresses are registered in DNS.
+func (i *Infoblox) recordName(hostname string) string {
+	if i.spec.DNSZone == "" {
+		return hostname
+	}
+	return hostname + "." + strings.TrimSuffix(i.spec.DNSZone, ".")
+}
+
+// address returns the address of the host record in the network of the
+// pool, in the CIDR format, or an empty string if it has none.
+func (i *Infoblox) address(record hostRecord) string {
+	prefixLength, _ := i.network.Mask.Size()
+	for _, addr := range record.IPv4Addrs {
+		if ip := net.ParseIP(addr.IPv4Addr); ip != nil && i.network.Contains(ip) {
+			return fmt.Sprintf("%s/%d", ip, prefixLength)
+		}
+	}
+	return ""
+}
+
+// statusError is returned for the responses of the grid master with an
+// unexpected status.
+type statusError struct {
+	code    int
+	status  string
+	message string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %s: %s", e.status, e.message)
+}
+
+func isNotFound(err error) bool {
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && statusErr.code == http.StatusNotFound
+}
+
+func (i *Infoblox) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, i.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(i.username, i.password)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errRes struct {
+			Text string `json:"text"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&errRes)
+		return &statusError{code: resp.StatusCode, status: resp.Status, message: errRes.Text}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// fakeGrid is a minimal Infoblox grid master allocating the addresses of a
+// /24 network to host records.
+type fakeGrid struct {
+	mu      sync.Mutex
+	records map[string]hostRecord
+	next    int
+}
+
+func (g *fakeGrid) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"Error":"AdmConProtoError: Authorization Required","text":"Authorization Required"}`))
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/wapi/"+infrav1.DefaultInfobloxWAPIVersion+"/")
+	switch {
+	case r.Method == http.MethodGet && path == "record:host":
+		records := []hostRecord{}
+		for _, record := range g.records {
+			if record.Name == r.URL.Query().Get("name") {
+				records = append(records, record)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(records)
+	case r.Method == http.MethodPost && path == "record:host":
+		record := hostRecord{}
+		_ = json.NewDecoder(r.Body).Decode(&record)
+		if len(record.IPv4Addrs) != 1 || record.IPv4Addrs[0].IPv4Addr != "func:nextavailableip:192.168.10.0/24,default" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"text":"invalid ipv4addrs"}`))
+			return
+		}
+		g.next++
+		record.Ref = fmt.Sprintf("record:host/%d:%s/%s", g.next, record.Name, record.View)
+		record.IPv4Addrs = []hostAddress{{IPv4Addr: fmt.Sprintf("192.168.10.%d", 10+g.next)}}
+		g.records[record.Ref] = record
+		_ = json.NewEncoder(w).Encode(record)
+	case r.Method == http.MethodDelete:
+		if _, ok := g.records[path]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(g.records, path)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestInfobloxAllocation(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	grid := &fakeGrid{records: map[string]hostRecord{}}
+	server := httptest.NewTLSServer(grid)
+	defer server.Close()
+
+	sum := sha256.Sum256(server.Certificate().Raw)
+	spec := &infrav1.InfobloxIPPoolSpec{
+		Server:     server.URL,
+		Thumbprint: fmt.Sprintf("%X", sum),
+		SecretName: "infoblox",
+		Network:    "192.168.10.0/24",
+		DNSZone:    "example.com.",
+	}
+	allocator, err := NewInfoblox(spec, "admin", "secret")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// The next available address is allocated with a host record registered
+	// in DNS.
+	address, ref, err := allocator.AllocateIP(ctx, "vm-0")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(address).To(Equal("192.168.10.11/24"))
+	record := grid.records[ref]
+	g.Expect(record.Name).To(Equal("vm-0.example.com"))
+	g.Expect(record.View).To(Equal(infrav1.DefaultInfobloxView))
+	g.Expect(*record.ConfigDNS).To(BeTrue())
+
+	// The address of the host record is returned again.
+	again, againRef, err := allocator.AllocateIP(ctx, "vm-0")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(again).To(Equal(address))
+	g.Expect(againRef).To(Equal(ref))
+	g.Expect(grid.records).To(HaveLen(1))
+
+	// The host record is deleted once released, which is idempotent.
+	g.Expect(allocator.ReleaseIP(ctx, ref)).To(Succeed())
+	g.Expect(grid.records).To(BeEmpty())
+	g.Expect(allocator.ReleaseIP(ctx, ref)).To(Succeed())
+
+	// The addresses are not registered in DNS without a zone.
+	spec.DNSZone = ""
+	allocator, err = NewInfoblox(spec, "admin", "secret")
+	g.Expect(err).NotTo(HaveOccurred())
+	_, ref, err = allocator.AllocateIP(ctx, "vm-1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(grid.records[ref].Name).To(Equal("vm-1"))
+	g.Expect(*grid.records[ref].ConfigDNS).To(BeFalse())
+
+	// The errors of the grid master are reported.
+	allocator, err = NewInfoblox(spec, "admin", "wrong")
+	g.Expect(err).NotTo(HaveOccurred())
+	_, _, err = allocator.AllocateIP(ctx, "vm-2")
+	g.Expect(err).To(MatchError(ContainSubstring("Authorization Required")))
+
+	// The certificate of the grid master must match the thumbprint.
+	spec.Thumbprint = strings.Repeat("00:", 31) + "00"
+	allocator, err = NewInfoblox(spec, "admin", "secret")
+	g.Expect(err).NotTo(HaveOccurred())
+	_, _, err = allocator.AllocateIP(ctx, "vm-2")
+	g.Expect(err).To(MatchError(ContainSubstring("does not match")))
+
+	// Only IPv4 networks are supported.
+	spec.Network = "fd00::/64"
+	_, err = NewInfoblox(spec, "admin", "secret")
+	g.Expect(err).To(HaveOccurred())
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ipam allocates the IP addresses of the network devices of VMs from
+// the IPAM of record configured by their VSphereIPPool.
+package ipam
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/identity"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services"
+)
+
+// NewAllocator returns the IPAllocator of the IPAM provider of the pool,
+// which authenticates with the credentials of the Secret referenced by the
+// provider.
+func NewAllocator(ctx context.Context, c client.Client, pool *infrav1.VSphereIPPool) (services.IPAllocator, error) {
+	switch {
+	case pool.Spec.Infoblox != nil:
+		username, password, err := getCredentials(ctx, c, pool.Namespace, pool.Spec.Infoblox.SecretName)
+		if err != nil {
+			return nil, err
+		}
+		return NewInfoblox(pool.Spec.Infoblox, username, password)
+	default:
+		return nil, errors.Errorf("VSphereIPPool %s/%s configures no IPAM provider", pool.Namespace, pool.Name)
+	}
+}
+
+// getCredentials returns the username and password of the Secret.
+func getCredentials(ctx context.Context, c client.Client, namespace, name string) (string, string, error) {
+	secret := &corev1.Secret{}
+	secretKey := client.ObjectKey{Namespace: namespace, Name: name}
+	if err := c.Get(ctx, secretKey, secret); err != nil {
+		return "", "", errors.Wrapf(err, "failed to get IPAM credentials secret %s", secretKey)
+	}
+	username, password := string(secret.Data[identity.UsernameKey]), string(secret.Data[identity.PasswordKey])
+	if username == "" || password == "" {
+		return "", "", errors.Errorf("IPAM credentials secret %s does not contain a %s and a %s", secretKey, identity.UsernameKey, identity.PasswordKey)
+	}
+	return username, password, nil
+}
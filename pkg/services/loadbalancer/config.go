@@ -0,0 +1,279 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package loadbalancer provides the bootstrap data of the VMs of a
+// VSphereLoadBalancerVM, which load balance the control plane of a cluster
+// with haproxy and hold its virtual IP with keepalived.
+package loadbalancer
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+const (
+	// MembersKey is the guestinfo variable publishing the addresses of the
+	// control plane machines to the VMs of a VSphereLoadBalancerVM, which
+	// poll it and reload haproxy when it changes.
+	MembersKey = infrav1.LoadBalancerGuestInfoPrefix + "members"
+
+	// PeerKey is the guestinfo variable publishing the address of the other
+	// VM of the pair to a VM of a VSphereLoadBalancerVM, which keepalived
+	// sends its VRRP advertisements to.
+	PeerKey = infrav1.LoadBalancerGuestInfoPrefix + "peer"
+
+	// APIServerPort is the port of the API servers of the control plane
+	// machines the virtual IP is load balanced to.
+	APIServerPort = 6443
+
+	// Replicas is the number of VMs of a VSphereLoadBalancerVM, the active
+	// and the passive VM of the pair.
+	Replicas = 2
+)
+
+// Members returns the value of the MembersKey guestinfo variable publishing
+// the addresses, which is the sorted, comma-separated list of the addresses.
+func Members(addresses []string) string {
+	sorted := append([]string{}, addresses...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// PeerAddress returns the address of a VM of the pair its peer sends its VRRP
+// advertisements to, which is the first of its addresses of the IP family of
+// the virtual IP, other than the virtual IP and link-local addresses. It is
+// empty when the VM has no such address yet.
+func PeerAddress(virtualIP string, addresses []string) string {
+	vip := net.ParseIP(virtualIP)
+	if vip == nil {
+		return ""
+	}
+	for _, address := range addresses {
+		ip := net.ParseIP(address)
+		if ip == nil || ip.Equal(vip) || ip.IsLinkLocalUnicast() || (ip.To4() != nil) != (vip.To4() != nil) {
+			continue
+		}
+		return address
+	}
+	return ""
+}
+
+// CloudConfig returns the cloud-config of the VM of the pair with the given
+// index. Both VMs run haproxy and keepalived, and the VM of index 0 has the
+// highest VRRP priority, so it holds the virtual IP while it and its haproxy
+// are up. The VRRP advertisements of the pair are authenticated with the
+// password, of at most 8 characters.
+func CloudConfig(loadBalancer *infrav1.VSphereLoadBalancerVM, index int, password string) ([]byte, error) {
+	port := loadBalancer.Spec.Port
+	if port == 0 {
+		port = infrav1.DefaultLoadBalancerVMPort
+	}
+	virtualRouterID := loadBalancer.Spec.VirtualRouterID
+	if virtualRouterID == 0 {
+		virtualRouterID = infrav1.DefaultLoadBalancerVMVirtualRouterID
+	}
+
+	buf := &bytes.Buffer{}
+	tpl := template.Must(template.New("t").Funcs(
+		template.FuncMap{
+			"quote": strconv.Quote,
+		}).Parse(cloudConfigFormat))
+	if err := tpl.Execute(buf, struct {
+		VirtualIP         string
+		Port              int32
+		VirtualRouterID   int32
+		Priority          int
+		Interface         string
+		SSHAuthorizedKeys []string
+		MembersKey        string
+		PeerKey           string
+		APIServerPort     int
+		Password          string
+	}{
+		VirtualIP:         loadBalancer.Spec.VirtualIP,
+		Port:              port,
+		VirtualRouterID:   virtualRouterID,
+		Priority:          100 + Replicas - index,
+		Interface:         loadBalancer.Spec.Interface,
+		SSHAuthorizedKeys: loadBalancer.Spec.SSHAuthorizedKeys,
+		MembersKey:        MembersKey,
+		PeerKey:           PeerKey,
+		APIServerPort:     APIServerPort,
+		Password:          password,
+	}); err != nil {
+		return nil, errors.Wrapf(err,
+			"error getting cloud-config of VSphereLoadBalancerVM %s/%s",
+			loadBalancer.Namespace, loadBalancer.Name)
+	}
+	return buf.Bytes(), nil
+}
+
+// cloudConfigFormat is the cloud-config of the VMs of a
+// VSphereLoadBalancerVM. The servers of haproxy and the unicast peer of
+// keepalived are regenerated by capv-lb-sync, run by a timer, from the
+// addresses published in the MembersKey and PeerKey guestinfo variables. The
+// variables are kept as is when they cannot be read, so the servers are not
+// removed while the VM tools are restarted. Until the peer is known,
+// keepalived advertises over multicast.
+const cloudConfigFormat = `#cloud-config
+{{- if .SSHAuthorizedKeys }}
+users:
+- name: capv
+  sudo: ALL=(ALL) NOPASSWD:ALL
+  ssh_authorized_keys:
+{{- range .SSHAuthorizedKeys }}
+  - {{ quote . }}
+{{- end }}
+{{- end }}
+packages:
+- haproxy
+- keepalived
+write_files:
+- path: /etc/haproxy/capv-haproxy.cfg
+  permissions: "0644"
+  content: |
+    global
+      log /dev/log local0
+      maxconn 20000
+    defaults
+      mode tcp
+      log global
+      option tcplog
+      option dontlognull
+      timeout connect 5s
+      timeout client 4h
+      timeout server 4h
+    frontend kube-apiserver
+      bind :::{{ .Port }} v4v6
+      default_backend kube-apiserver
+    backend kube-apiserver
+      balance roundrobin
+      option httpchk GET /healthz
+      http-check expect status 200
+      default-server inter 5s fall 3 rise 2 check check-ssl verify none
+- path: /etc/keepalived/capv-keepalived.conf
+  permissions: "0600"
+  content: |
+    global_defs {
+      enable_script_security
+      script_user root
+    }
+    vrrp_script chk_haproxy {
+      script "/usr/bin/systemctl is-active --quiet haproxy"
+      interval 2
+      fall 2
+      rise 2
+    }
+    vrrp_instance capv {
+      state BACKUP
+      interface @INTERFACE@
+      virtual_router_id {{ .VirtualRouterID }}
+      priority {{ .Priority }}
+      advert_int 1
+      authentication {
+        auth_type PASS
+        auth_pass {{ .Password }}
+      }
+      unicast_peer {
+        @PEER@
+      }
+      virtual_ipaddress {
+        {{ .VirtualIP }}
+      }
+      track_script {
+        chk_haproxy
+      }
+    }
+- path: /usr/local/sbin/capv-lb-sync
+  permissions: "0755"
+  content: |
+    #!/bin/sh
+    set -e
+    if peer=$(vmware-rpctool "info-get {{ .PeerKey }}" 2>/dev/null) && [ "$peer" != "$(cat /etc/keepalived/capv-peer 2>/dev/null)" ]; then
+      if [ -n "$peer" ]; then
+        sed "s/@PEER@/$peer/" /etc/keepalived/capv-keepalived.conf > /etc/keepalived/keepalived.conf.capv
+      else
+        sed "/unicast_peer {/,/}/d" /etc/keepalived/capv-keepalived.conf > /etc/keepalived/keepalived.conf.capv
+      fi
+      chmod 0600 /etc/keepalived/keepalived.conf.capv
+      mv /etc/keepalived/keepalived.conf.capv /etc/keepalived/keepalived.conf
+      echo "$peer" > /etc/keepalived/capv-peer
+      systemctl try-reload-or-restart keepalived
+    fi
+    members=$(vmware-rpctool "info-get {{ .MembersKey }}" 2>/dev/null) || exit 0
+    if [ -f /etc/haproxy/capv-members ] && [ "$members" = "$(cat /etc/haproxy/capv-members)" ]; then
+      exit 0
+    fi
+    cp /etc/haproxy/capv-haproxy.cfg /etc/haproxy/haproxy.cfg.capv
+    for member in $(echo "$members" | tr ',' ' '); do
+      echo "  server $(echo "$member" | tr ':' '-') $member:{{ .APIServerPort }}" >> /etc/haproxy/haproxy.cfg.capv
+    done
+    mv /etc/haproxy/haproxy.cfg.capv /etc/haproxy/haproxy.cfg
+    echo "$members" > /etc/haproxy/capv-members
+    systemctl reload-or-restart haproxy
+- path: /usr/local/sbin/capv-lb-init
+  permissions: "0755"
+  content: |
+    #!/bin/sh
+    set -e
+    interface="{{ .Interface }}"
+    if [ -z "$interface" ]; then
+      interface=$(ip -o route get {{ .VirtualIP }} | sed -n 's/.* dev \([^ ]*\).*/\1/p')
+    fi
+    sed -i "s/@INTERFACE@/$interface/" /etc/keepalived/capv-keepalived.conf
+    sed "/unicast_peer {/,/}/d" /etc/keepalived/capv-keepalived.conf > /etc/keepalived/keepalived.conf
+    chmod 0600 /etc/keepalived/keepalived.conf
+    rm -f /etc/keepalived/capv-peer
+    cp /etc/haproxy/capv-haproxy.cfg /etc/haproxy/haproxy.cfg
+    rm -f /etc/haproxy/capv-members
+    systemctl daemon-reload
+    systemctl enable --now haproxy
+    /usr/local/sbin/capv-lb-sync
+    systemctl enable --now keepalived capv-lb-sync.timer
+- path: /etc/systemd/system/capv-lb-sync.service
+  permissions: "0644"
+  content: |
+    [Unit]
+    Description=Sync the servers of haproxy and the peer of keepalived
+    After=haproxy.service
+
+    [Service]
+    Type=oneshot
+    ExecStart=/usr/local/sbin/capv-lb-sync
+- path: /etc/systemd/system/capv-lb-sync.timer
+  permissions: "0644"
+  content: |
+    [Unit]
+    Description=Sync the servers of haproxy and the peer of keepalived periodically
+
+    [Timer]
+    OnBootSec=10s
+    OnUnitActiveSec=10s
+    AccuracySec=1s
+
+    [Install]
+    WantedBy=timers.target
+runcmd:
+- /usr/local/sbin/capv-lb-init
+`
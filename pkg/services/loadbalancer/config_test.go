@@ -0,0 +1,118 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+func TestMembers(t *testing.T) {
+	g := NewWithT(t)
+	addresses := []string{"192.168.10.12", "192.168.10.11"}
+	g.Expect(Members(addresses)).To(Equal("192.168.10.11,192.168.10.12"))
+	g.Expect(addresses).To(Equal([]string{"192.168.10.12", "192.168.10.11"}))
+	g.Expect(Members(nil)).To(BeEmpty())
+}
+
+func TestPeerAddress(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(PeerAddress("192.168.10.5", []string{"fe80::1", "192.168.10.5", "fd00::11", "192.168.10.11"})).To(Equal("192.168.10.11"))
+	g.Expect(PeerAddress("fd00::5", []string{"192.168.10.11", "fe80::1", "fd00::11"})).To(Equal("fd00::11"))
+	g.Expect(PeerAddress("192.168.10.5", []string{"fd00::11"})).To(BeEmpty())
+	g.Expect(PeerAddress("192.168.10.5", nil)).To(BeEmpty())
+}
+
+func TestCloudConfig(t *testing.T) {
+	type cloudConfig struct {
+		Users []struct {
+			Name              string   `json:"name"`
+			SSHAuthorizedKeys []string `json:"ssh_authorized_keys"`
+		} `json:"users"`
+		Packages   []string `json:"packages"`
+		WriteFiles []struct {
+			Path    string `json:"path"`
+			Content string `json:"content"`
+		} `json:"write_files"`
+		RunCmd []string `json:"runcmd"`
+	}
+	parse := func(g *WithT, data []byte) (cloudConfig, map[string]string) {
+		g.Expect(strings.HasPrefix(string(data), "#cloud-config\n")).To(BeTrue())
+		var config cloudConfig
+		g.Expect(yaml.Unmarshal(data, &config)).To(Succeed())
+		files := map[string]string{}
+		for _, file := range config.WriteFiles {
+			files[file.Path] = file.Content
+		}
+		return config, files
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		g := NewWithT(t)
+		loadBalancer := &infrav1.VSphereLoadBalancerVM{
+			Spec: infrav1.VSphereLoadBalancerVMSpec{VirtualIP: "192.168.10.5"},
+		}
+
+		data, err := CloudConfig(loadBalancer, 0, "0a1b2c3d")
+		g.Expect(err).NotTo(HaveOccurred())
+		config, files := parse(g, data)
+		g.Expect(config.Users).To(BeEmpty())
+		g.Expect(config.Packages).To(ConsistOf("haproxy", "keepalived"))
+		g.Expect(config.RunCmd).To(Equal([]string{"/usr/local/sbin/capv-lb-init"}))
+		g.Expect(files["/etc/haproxy/capv-haproxy.cfg"]).To(ContainSubstring("bind :::6443 v4v6\n"))
+		g.Expect(files["/etc/keepalived/capv-keepalived.conf"]).To(ContainSubstring("virtual_router_id 51\n"))
+		g.Expect(files["/etc/keepalived/capv-keepalived.conf"]).To(ContainSubstring("priority 102\n"))
+		g.Expect(files["/etc/keepalived/capv-keepalived.conf"]).To(ContainSubstring("    192.168.10.5\n"))
+		g.Expect(files["/etc/keepalived/capv-keepalived.conf"]).To(ContainSubstring("auth_pass 0a1b2c3d\n"))
+		g.Expect(files["/etc/keepalived/capv-keepalived.conf"]).To(ContainSubstring("unicast_peer {\n    @PEER@\n  }\n"))
+		g.Expect(files["/usr/local/sbin/capv-lb-sync"]).To(ContainSubstring(`"info-get ` + PeerKey + `"`))
+		g.Expect(files["/usr/local/sbin/capv-lb-sync"]).To(ContainSubstring("systemctl try-reload-or-restart keepalived\n"))
+		g.Expect(files["/usr/local/sbin/capv-lb-sync"]).To(ContainSubstring(`"info-get ` + MembersKey + `"`))
+		g.Expect(files["/usr/local/sbin/capv-lb-sync"]).To(ContainSubstring("$member:6443"))
+		g.Expect(files["/usr/local/sbin/capv-lb-init"]).To(ContainSubstring("ip -o route get 192.168.10.5"))
+	})
+
+	t.Run("spec", func(t *testing.T) {
+		g := NewWithT(t)
+		loadBalancer := &infrav1.VSphereLoadBalancerVM{
+			Spec: infrav1.VSphereLoadBalancerVMSpec{
+				VirtualIP:         "fd00::5",
+				Port:              443,
+				VirtualRouterID:   7,
+				Interface:         "ens192",
+				SSHAuthorizedKeys: []string{"ssh-ed25519 AAAA capv@example.com"},
+			},
+		}
+
+		data, err := CloudConfig(loadBalancer, 1, "0a1b2c3d")
+		g.Expect(err).NotTo(HaveOccurred())
+		config, files := parse(g, data)
+		g.Expect(config.Users).To(HaveLen(1))
+		g.Expect(config.Users[0].Name).To(Equal("capv"))
+		g.Expect(config.Users[0].SSHAuthorizedKeys).To(Equal([]string{"ssh-ed25519 AAAA capv@example.com"}))
+		g.Expect(files["/etc/haproxy/capv-haproxy.cfg"]).To(ContainSubstring("bind :::443 v4v6\n"))
+		g.Expect(files["/etc/keepalived/capv-keepalived.conf"]).To(ContainSubstring("virtual_router_id 7\n"))
+		g.Expect(files["/etc/keepalived/capv-keepalived.conf"]).To(ContainSubstring("priority 101\n"))
+		g.Expect(files["/etc/keepalived/capv-keepalived.conf"]).To(ContainSubstring("    fd00::5\n"))
+		g.Expect(files["/usr/local/sbin/capv-lb-init"]).To(ContainSubstring(`interface="ens192"`))
+	})
+}
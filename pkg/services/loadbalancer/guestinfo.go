@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/audit"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+)
+
+// PublishGuestInfo reconfigures the running VM of a VSphereVM of a
+// VSphereLoadBalancerVM with the guestinfo variables whose values differ, so
+// its VMs pick up a change of the members or of the peer without waiting for
+// the drift of the VSphereVM to be reverted. It does nothing until the VM is
+// created.
+func PublishGuestInfo(ctx context.Context, s *session.Session, vsphereVM *infrav1.VSphereVM, values map[string]string) error {
+	if vsphereVM.Spec.BiosUUID == "" {
+		return nil
+	}
+	ref, err := s.FindByBIOSUUID(ctx, vsphereVM.Spec.BiosUUID)
+	if err != nil {
+		return err
+	}
+	if ref == nil {
+		return nil
+	}
+	vm := object.NewVirtualMachine(s.Client.Client, ref.Reference())
+
+	var obj mo.VirtualMachine
+	if err := vm.Properties(ctx, vm.Reference(), []string{"config.extraConfig"}, &obj); err != nil {
+		return errors.Wrapf(err, "unable to fetch the extra config of VSphereVM %s/%s", vsphereVM.Namespace, vsphereVM.Name)
+	}
+	current := map[string]string{}
+	if obj.Config != nil {
+		for _, option := range obj.Config.ExtraConfig {
+			if value := option.GetOptionValue(); value != nil {
+				if str, ok := value.Value.(string); ok {
+					current[value.Key] = str
+				}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var extraConfig []types.BaseOptionValue
+	for _, key := range keys {
+		if current[key] != values[key] {
+			extraConfig = append(extraConfig, &types.OptionValue{Key: key, Value: values[key]})
+		}
+	}
+	if len(extraConfig) == 0 {
+		return nil
+	}
+
+	task, err := vm.Reconfigure(ctx, types.VirtualMachineConfigSpec{ExtraConfig: extraConfig})
+	if err != nil {
+		return errors.Wrapf(err, "unable to publish the guestinfo of VSphereVM %s/%s", vsphereVM.Namespace, vsphereVM.Name)
+	}
+	if err := audit.WaitForTask(ctx, task); err != nil {
+		return errors.Wrapf(err, "unable to publish the guestinfo of VSphereVM %s/%s", vsphereVM.Namespace, vsphereVM.Name)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/vmware/govmomi/simulator"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestPublishGuestInfo(t *testing.T) {
+	g := NewWithT(t)
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).NotTo(HaveOccurred())
+	defer simr.Destroy()
+
+	ctx := context.Background()
+	s, err := session.GetOrCreate(ctx,
+		session.NewParams().
+			WithServer(simr.ServerURL().Host).
+			WithUserInfo(simr.Username(), simr.Password()).
+			WithDatacenter("*"))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	simVM := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine) //nolint:forcetypeassert
+	extraConfig := func() map[string]string {
+		values := map[string]string{}
+		for _, option := range simVM.Config.ExtraConfig {
+			if value := option.GetOptionValue(); value != nil {
+				if str, ok := value.Value.(string); ok {
+					values[value.Key] = str
+				}
+			}
+		}
+		return values
+	}
+	values := map[string]string{
+		MembersKey: "192.168.10.11,192.168.10.12",
+		PeerKey:    "192.168.10.21",
+	}
+
+	// Nothing is published until the VM is created.
+	vsphereVM := &infrav1.VSphereVM{}
+	g.Expect(PublishGuestInfo(ctx, s, vsphereVM, values)).To(Succeed())
+	g.Expect(extraConfig()).NotTo(HaveKey(MembersKey))
+
+	vsphereVM.Spec.BiosUUID = simVM.Config.Uuid
+	g.Expect(PublishGuestInfo(ctx, s, vsphereVM, values)).To(Succeed())
+	g.Expect(extraConfig()).To(HaveKeyWithValue(MembersKey, "192.168.10.11,192.168.10.12"))
+	g.Expect(extraConfig()).To(HaveKeyWithValue(PeerKey, "192.168.10.21"))
+
+	values[MembersKey] = "192.168.10.11"
+	g.Expect(PublishGuestInfo(ctx, s, vsphereVM, values)).To(Succeed())
+	g.Expect(extraConfig()).To(HaveKeyWithValue(MembersKey, "192.168.10.11"))
+	g.Expect(extraConfig()).To(HaveKeyWithValue(PeerKey, "192.168.10.21"))
+}
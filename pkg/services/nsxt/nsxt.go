@@ -0,0 +1,232 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nsxt manages the NSX-T segment created for the nodes of a cluster,
+// along with its optional Tier-1 gateway and SNAT rule, through the NSX-T
+// Policy API.
+package nsxt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+const (
+	policyAPIPath = "/policy/api/v1"
+
+	// localeServicesID is the ID of the locale services of the Tier-1
+	// gateway, which hold its edge cluster.
+	localeServicesID = "default"
+)
+
+// SegmentName returns the name of the segment created for the cluster, which
+// is also the ID of the segment, Tier-1 gateway and SNAT rule in NSX-T and
+// the name of the network of the segment in vCenter.
+func SegmentName(namespace, clusterName string) string {
+	return fmt.Sprintf("capv-%s-%s", namespace, clusterName)
+}
+
+// Client is a client of the NSX-T Policy API.
+type Client struct {
+	server     string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// NewClient returns a client of the NSX-T manager of the spec, which
+// authenticates with the given credentials. The certificate of the manager
+// is only checked against the thumbprint of the spec, if any.
+func NewClient(spec *infrav1.NSXTSpec, username, password string) *Client {
+	server := spec.Server
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	return &Client{
+		server:   strings.TrimSuffix(server, "/"),
+		username: username,
+		password: password,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: util.ThumbprintTLSConfig(spec.Thumbprint, "NSX-T manager"),
+			},
+		},
+	}
+}
+
+// ReconcileSegment creates or updates the segment of the cluster and, when
+// the spec configures a gateway, the Tier-1 gateway the segment is connected
+// to and its SNAT rule.
+func (c *Client) ReconcileSegment(ctx context.Context, spec *infrav1.NSXTSpec, name string) error {
+	_, subnet, err := net.ParseCIDR(spec.GatewayCIDR)
+	if err != nil {
+		return errors.Wrapf(err, "invalid gateway CIDR %q of segment %s", spec.GatewayCIDR, name)
+	}
+
+	segment := map[string]interface{}{
+		"display_name":        name,
+		"transport_zone_path": spec.TransportZonePath,
+		"subnets": []map[string]interface{}{
+			{"gateway_address": spec.GatewayCIDR},
+		},
+	}
+	if spec.DHCPConfigPath != "" {
+		segment["dhcp_config_path"] = spec.DHCPConfigPath
+	}
+
+	if gateway := spec.Gateway; gateway != nil {
+		tier1Path := tier1Path(name)
+		if err := c.patch(ctx, tier1Path, map[string]interface{}{
+			"display_name":              name,
+			"tier0_path":                gateway.Tier0Path,
+			"route_advertisement_types": []string{"TIER1_CONNECTED", "TIER1_NAT"},
+		}); err != nil {
+			return errors.Wrapf(err, "failed to reconcile Tier-1 gateway %s", name)
+		}
+		if gateway.EdgeClusterPath != "" {
+			if err := c.patch(ctx, localeServicesPath(name), map[string]interface{}{
+				"edge_cluster_path": gateway.EdgeClusterPath,
+			}); err != nil {
+				return errors.Wrapf(err, "failed to reconcile edge cluster of Tier-1 gateway %s", name)
+			}
+		}
+		if gateway.SNATIP != "" {
+			if err := c.patch(ctx, natRulePath(name), map[string]interface{}{
+				"display_name":       name,
+				"action":             "SNAT",
+				"source_network":     subnet.String(),
+				"translated_network": gateway.SNATIP,
+				"enabled":            true,
+			}); err != nil {
+				return errors.Wrapf(err, "failed to reconcile SNAT rule of Tier-1 gateway %s", name)
+			}
+		}
+		segment["connectivity_path"] = tier1Path
+	}
+
+	if err := c.patch(ctx, segmentPath(name), segment); err != nil {
+		return errors.Wrapf(err, "failed to reconcile segment %s", name)
+	}
+	return nil
+}
+
+// DeleteSegment deletes the segment of the cluster, then its Tier-1 gateway
+// and SNAT rule. Objects that do not exist are ignored, hence the Tier-1
+// gateway is deleted even if the gateway was removed from the spec.
+func (c *Client) DeleteSegment(ctx context.Context, name string) error {
+	logger := ctrl.LoggerFrom(ctx, "segment", name)
+
+	for _, path := range []string{
+		segmentPath(name),
+		natRulePath(name),
+		localeServicesPath(name),
+		tier1Path(name),
+	} {
+		logger.Info("deleting NSX-T object", "path", path)
+		if err := c.delete(ctx, path); err != nil {
+			return errors.Wrapf(err, "failed to delete %s", path)
+		}
+	}
+	return nil
+}
+
+func segmentPath(name string) string {
+	return "/infra/segments/" + name
+}
+
+func tier1Path(name string) string {
+	return "/infra/tier-1s/" + name
+}
+
+func localeServicesPath(name string) string {
+	return tier1Path(name) + "/locale-services/" + localeServicesID
+}
+
+func natRulePath(name string) string {
+	return tier1Path(name) + "/nat/USER/nat-rules/" + name
+}
+
+func (c *Client) patch(ctx context.Context, path string, obj interface{}) error {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPatch, path, body)
+}
+
+func (c *Client) delete(ctx context.Context, path string) error {
+	err := c.do(ctx, http.MethodDelete, path, nil)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// statusError is returned for the responses of the manager with an
+// unexpected status.
+type statusError struct {
+	code    int
+	status  string
+	message string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %s: %s", e.status, e.message)
+}
+
+func isNotFound(err error) bool {
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && statusErr.code == http.StatusNotFound
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.server+policyAPIPath+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.username, c.password)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errRes struct {
+			ErrorMessage string `json:"error_message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&errRes)
+		return &statusError{code: resp.StatusCode, status: resp.Status, message: errRes.ErrorMessage}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nsxt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// fakeManager is a minimal NSX-T manager storing the objects patched
+// through the Policy API by path.
+type fakeManager struct {
+	mu      sync.Mutex
+	objects map[string]map[string]interface{}
+	deletes []string
+}
+
+func (m *fakeManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error_message":"The credentials were incorrect"}`))
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, policyAPIPath)
+	switch r.Method {
+	case http.MethodPatch:
+		obj := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&obj)
+		m.objects[path] = obj
+	case http.MethodDelete:
+		if _, ok := m.objects[path]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(m.objects, path)
+		m.deletes = append(m.deletes, path)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestSegmentLifecycle(t *testing.T) {
+	manager := &fakeManager{objects: map[string]map[string]interface{}{}}
+	server := httptest.NewTLSServer(manager)
+	defer server.Close()
+
+	sum := sha256.Sum256(server.Certificate().Raw)
+	spec := &infrav1.NSXTSpec{
+		Server:            server.URL,
+		Thumbprint:        fmt.Sprintf("%X", sum),
+		TransportZonePath: "/infra/sites/default/enforcement-points/default/transport-zones/overlay",
+		GatewayCIDR:       "192.168.10.1/24",
+		DHCPConfigPath:    "/infra/dhcp-server-configs/capv",
+	}
+	name := SegmentName("default", "test")
+
+	t.Run("creates an isolated segment", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(NewClient(spec, "admin", "secret").ReconcileSegment(context.Background(), spec, name)).To(Succeed())
+
+		g.Expect(manager.objects).To(HaveLen(1))
+		segment := manager.objects["/infra/segments/capv-default-test"]
+		g.Expect(segment).To(HaveKeyWithValue("transport_zone_path", spec.TransportZonePath))
+		g.Expect(segment).To(HaveKeyWithValue("dhcp_config_path", spec.DHCPConfigPath))
+		g.Expect(segment).ToNot(HaveKey("connectivity_path"))
+	})
+
+	t.Run("connects the segment to a Tier-1 gateway with a SNAT rule", func(t *testing.T) {
+		g := NewWithT(t)
+		spec := spec.DeepCopy()
+		spec.Gateway = &infrav1.NSXTGatewaySpec{
+			Tier0Path:       "/infra/tier-0s/t0",
+			EdgeClusterPath: "/infra/sites/default/enforcement-points/default/edge-clusters/edge",
+			SNATIP:          "10.0.0.10",
+		}
+		g.Expect(NewClient(spec, "admin", "secret").ReconcileSegment(context.Background(), spec, name)).To(Succeed())
+
+		g.Expect(manager.objects).To(HaveLen(4))
+		g.Expect(manager.objects["/infra/tier-1s/capv-default-test"]).To(HaveKeyWithValue("tier0_path", "/infra/tier-0s/t0"))
+		g.Expect(manager.objects["/infra/tier-1s/capv-default-test/locale-services/default"]).To(HaveKeyWithValue("edge_cluster_path", spec.Gateway.EdgeClusterPath))
+		rule := manager.objects["/infra/tier-1s/capv-default-test/nat/USER/nat-rules/capv-default-test"]
+		g.Expect(rule).To(HaveKeyWithValue("source_network", "192.168.10.0/24"))
+		g.Expect(rule).To(HaveKeyWithValue("translated_network", "10.0.0.10"))
+		g.Expect(manager.objects["/infra/segments/capv-default-test"]).To(HaveKeyWithValue("connectivity_path", "/infra/tier-1s/capv-default-test"))
+	})
+
+	t.Run("deletes the segment before the gateway", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(NewClient(spec, "admin", "secret").DeleteSegment(context.Background(), name)).To(Succeed())
+
+		g.Expect(manager.objects).To(BeEmpty())
+		g.Expect(manager.deletes).To(Equal([]string{
+			"/infra/segments/capv-default-test",
+			"/infra/tier-1s/capv-default-test/nat/USER/nat-rules/capv-default-test",
+			"/infra/tier-1s/capv-default-test/locale-services/default",
+			"/infra/tier-1s/capv-default-test",
+		}))
+
+		// Deleting again is a no-op.
+		g.Expect(NewClient(spec, "admin", "secret").DeleteSegment(context.Background(), name)).To(Succeed())
+	})
+
+	t.Run("fails with wrong credentials", func(t *testing.T) {
+		g := NewWithT(t)
+		err := NewClient(spec, "admin", "wrong").ReconcileSegment(context.Background(), spec, name)
+		g.Expect(err).To(MatchError(ContainSubstring("The credentials were incorrect")))
+	})
+
+	t.Run("fails with a certificate not matching the thumbprint", func(t *testing.T) {
+		g := NewWithT(t)
+		spec := spec.DeepCopy()
+		spec.Thumbprint = "00:11:22"
+		err := NewClient(spec, "admin", "secret").ReconcileSegment(context.Background(), spec, name)
+		g.Expect(err).To(MatchError(ContainSubstring("does not match")))
+	})
+
+	t.Run("fails with an invalid gateway CIDR", func(t *testing.T) {
+		g := NewWithT(t)
+		spec := spec.DeepCopy()
+		spec.GatewayCIDR = "192.168.10.1"
+		err := NewClient(spec, "admin", "secret").ReconcileSegment(context.Background(), spec, name)
+		g.Expect(err).To(MatchError(ContainSubstring("invalid gateway CIDR")))
+	})
+}
@@ -28,8 +28,11 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/utils/integer"
+	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controllers/remote"
 	clusterutilv1 "sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -37,10 +40,15 @@ import (
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/nsxt"
 	infrautilv1 "sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
 )
 
-type VimMachineService struct{}
+type VimMachineService struct {
+	// RemoteClientGetter returns a client of the workload cluster of a
+	// machine. Defaults to remote.NewClusterClient.
+	RemoteClientGetter remote.ClusterClientGetter
+}
 
 func (v *VimMachineService) FetchVSphereMachine(c client.Client, name types.NamespacedName) (context.MachineContext, error) {
 	vsphereMachine := &infrav1.VSphereMachine{}
@@ -102,9 +110,14 @@ func (v *VimMachineService) SyncFailureReason(c context.MachineContext) (bool, e
 		return false, err
 	}
 	if vsphereVM != nil {
-		// Reconcile VSphereMachine's failures
+		// Reconcile VSphereMachine's failures, except the ones which are
+		// retried since the Machine does not recover from a failure.
 		ctx.VSphereMachine.Status.FailureReason = vsphereVM.Status.FailureReason
 		ctx.VSphereMachine.Status.FailureMessage = vsphereVM.Status.FailureMessage
+		if infrautilv1.IsFailureRetried(vsphereVM) {
+			ctx.VSphereMachine.Status.FailureReason = nil
+			ctx.VSphereMachine.Status.FailureMessage = nil
+		}
 	}
 
 	return ctx.VSphereMachine.Status.FailureReason != nil || ctx.VSphereMachine.Status.FailureMessage != nil, err
@@ -120,6 +133,77 @@ func (v *VimMachineService) ReconcileNormal(c context.MachineContext) (bool, err
 		return false, err
 	}
 
+	// The VM of a machine referencing a VSphereMachineImageMapping is cloned
+	// from the template, or the VSphereMachineImage, mapped to its
+	// Kubernetes version.
+	spec := ctx.VSphereMachine.Spec
+	image := spec.Image
+	if vsphereVM == nil && spec.ImageMapping != "" {
+		template, mappedImage, err := infrautilv1.ResolveImageMapping(ctx, ctx.Client, ctx.VSphereMachine.Namespace, spec.ImageMapping, ctx.Machine.Spec.Version)
+		if err != nil {
+			return false, err
+		}
+		if template == "" && mappedImage == "" {
+			ctx.Logger.Info("waiting for the image mapping to map the version", "imageMapping", spec.ImageMapping, "version", ctx.Machine.Spec.Version)
+			conditions.MarkFalse(ctx.VSphereMachine, infrav1.VMProvisionedCondition, infrav1.WaitingForImageMappingReason, clusterv1.ConditionSeverityWarning,
+				"waiting for VSphereMachineImageMapping %s to map Kubernetes version %s", spec.ImageMapping, pointer.StringDeref(ctx.Machine.Spec.Version, ""))
+			return true, nil
+		}
+		image = mappedImage
+	}
+
+	// The VM of a machine referencing a VSphereMachineImage is cloned once
+	// the image is imported.
+	if vsphereVM == nil && image != "" {
+		template, err := v.imageTemplate(ctx, image)
+		if err != nil {
+			return false, err
+		}
+		if template == "" {
+			ctx.Logger.Info("waiting for image to be imported", "image", image)
+			conditions.MarkFalse(ctx.VSphereMachine, infrav1.VMProvisionedCondition, infrav1.WaitingForImageReason, clusterv1.ConditionSeverityInfo,
+				"waiting for VSphereMachineImage %s to be imported", image)
+			return true, nil
+		}
+	}
+
+	// The VM of a machine which sets neither a template, an image nor an
+	// image mapping, and does not adopt a VM, is cloned from the default
+	// template of the VSphereProviderConfig for its Kubernetes version.
+	if vsphereVM == nil && spec.Template == "" && spec.Image == "" && spec.ImageMapping == "" && spec.ProviderID == nil {
+		providerConfig, err := infrautilv1.GetProviderConfig(ctx, ctx.Client)
+		if err != nil {
+			return false, err
+		}
+		if infrautilv1.DefaultTemplate(providerConfig, ctx.Machine.Spec.Version) == "" {
+			ctx.Logger.Info("waiting for a default template", "version", ctx.Machine.Spec.Version)
+			conditions.MarkFalse(ctx.VSphereMachine, infrav1.VMProvisionedCondition, infrav1.WaitingForDefaultTemplateReason, clusterv1.ConditionSeverityWarning,
+				"waiting for VSphereProviderConfig %s to set a default template for Kubernetes version %s", infrav1.ProviderConfigName, pointer.StringDeref(ctx.Machine.Spec.Version, ""))
+			return true, nil
+		}
+	}
+
+	// The VM of the machine is only created within the resource quota of the
+	// cluster.
+	if vsphereVM == nil {
+		message, err := infrautilv1.CheckResourceQuota(ctx, ctx.Client, ctx.VSphereCluster, ctx.Cluster.Name, &ctx.VSphereMachine.Spec.VirtualMachineCloneSpec, 1)
+		if err != nil {
+			return false, err
+		}
+		if message != "" {
+			ctx.Logger.Info("waiting for resources of the quota to be released", "reason", message)
+			conditions.MarkFalse(ctx.VSphereMachine, infrav1.VMProvisionedCondition, infrav1.QuotaExceededReason, clusterv1.ConditionSeverityWarning, message)
+			return true, nil
+		}
+	}
+
+	// The deployment zone of the machine is chosen before its VM is created.
+	if vsphereVM == nil {
+		if err := v.reconcileDeploymentZone(ctx); err != nil {
+			return false, err
+		}
+	}
+
 	vm, err := v.createOrUpdateVSPhereVM(ctx, vsphereVM)
 
 	if err != nil && !apierrors.IsAlreadyExists(err) {
@@ -138,6 +222,38 @@ func (v *VimMachineService) ReconcileNormal(c context.MachineContext) (bool, err
 	vmObj.SetAPIVersion(vm.GetObjectKind().GroupVersionKind().GroupVersion().String())
 	vmObj.SetKind(vm.GetObjectKind().GroupVersionKind().Kind)
 
+	// Report the health of the host the VM runs on to the machine and its
+	// node.
+	if err := v.reconcileHostHealth(ctx, vmObj); err != nil {
+		return false, errors.Wrapf(err, "unexpected error while reconciling host health for %s", ctx)
+	}
+
+	// Report whether the VM was changed outside of Cluster API to the
+	// machine, and to its node with the Revert drift policy.
+	if err := v.reconcileSpecSynced(ctx, vmObj); err != nil {
+		return false, errors.Wrapf(err, "unexpected error while reconciling spec drift for %s", ctx)
+	}
+
+	// Report whether the VM could be resized in place after the number of
+	// CPUs, the memory size or the disk size of the machine changed, and the
+	// phases of the provisioning of the VM.
+	for _, t := range []clusterv1.ConditionType{
+		infrav1.VMResizedCondition,
+		infrav1.DiskResizedCondition,
+		infrav1.CloneStartedCondition,
+		infrav1.CloneCompletedCondition,
+		infrav1.CustomizationAppliedCondition,
+		infrav1.PoweredOnCondition,
+		infrav1.IPAssignedCondition,
+		infrav1.BootstrapDataDeliveredCondition,
+	} {
+		if condition := conditions.Get(conditions.UnstructuredGetter(vmObj), t); condition != nil {
+			conditions.Set(ctx.VSphereMachine, condition)
+		} else {
+			conditions.Delete(ctx.VSphereMachine, t)
+		}
+	}
+
 	// Waits the VM's ready state.
 	if ok, err := v.waitReadyState(ctx, vmObj); !ok {
 		if err != nil {
@@ -170,6 +286,11 @@ func (v *VimMachineService) ReconcileNormal(c context.MachineContext) (bool, err
 	}
 
 	ctx.VSphereMachine.Status.Ready = true
+
+	// Label the node of the machine with the location of its VM in vSphere.
+	if err := v.reconcileNodeTopology(ctx, vmObj); err != nil {
+		return false, errors.Wrapf(err, "unexpected error while reconciling node topology for %s", ctx)
+	}
 	return false, nil
 }
 
@@ -187,6 +308,27 @@ func (v *VimMachineService) findVMPre7(ctx *context.VIMMachineContext) (*infrav1
 	return vm, nil
 }
 
+// imageTemplate returns the inventory path of the template of the
+// VSphereMachineImage in the namespace of the machine, or an empty string
+// while the image is not imported.
+func (v *VimMachineService) imageTemplate(ctx *context.VIMMachineContext, name string) (string, error) {
+	image := &infrav1.VSphereMachineImage{}
+	imageKey := types.NamespacedName{
+		Namespace: ctx.VSphereMachine.Namespace,
+		Name:      name,
+	}
+	if err := ctx.Client.Get(ctx, imageKey, image); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "failed to get VSphereMachineImage %s for %s", imageKey, ctx)
+	}
+	if !image.Status.Ready {
+		return "", nil
+	}
+	return image.Status.TemplatePath, nil
+}
+
 func (v *VimMachineService) waitReadyState(ctx *context.VIMMachineContext, vm *unstructured.Unstructured) (bool, error) {
 	ready, ok, err := unstructured.NestedBool(vm.Object, "status", "ready")
 	if !ok {
@@ -290,11 +432,27 @@ func (v *VimMachineService) reconcileNetwork(ctx *context.VIMMachineContext, vm
 
 	if addresses, ok, _ := unstructured.NestedStringSlice(vm.Object, "status", "addresses"); ok {
 		var machineAddresses []clusterv1.MachineAddress
-		for _, addr := range addresses {
-			machineAddresses = append(machineAddresses, clusterv1.MachineAddress{
-				Type:    clusterv1.MachineExternalIP,
-				Address: addr,
-			})
+		if typedAddresses, ok, _ := unstructured.NestedSlice(vm.Object, "status", "machineAddresses"); ok {
+			buf, err := json.Marshal(typedAddresses)
+			if err == nil {
+				err = json.Unmarshal(buf, &machineAddresses)
+			}
+			if err != nil {
+				ctx.Logger.Error(err,
+					"unsupported data for status.machineAddresses",
+					"data", string(buf))
+				errs = append(errs, err)
+			}
+		}
+		// The addresses of the VSphereVMs last reconciled by a previous
+		// version of CAPV are not typed, and are published as external IPs.
+		if len(machineAddresses) == 0 {
+			for _, addr := range addresses {
+				machineAddresses = append(machineAddresses, clusterv1.MachineAddress{
+					Type:    clusterv1.MachineExternalIP,
+					Address: addr,
+				})
+			}
 		}
 		ctx.VSphereMachine.Status.Addresses = machineAddresses
 	}
@@ -350,10 +508,83 @@ func (v *VimMachineService) createOrUpdateVSPhereVM(ctx *context.VIMMachineConte
 			vm.Labels[clusterv1.MachineControlPlaneLabelName] = val
 		}
 
+		// The backup of the VM before it is destroyed may be requested on the
+		// VSphereMachine, e.g. through the metadata of its template.
+		if val, ok := ctx.VSphereMachine.Annotations[infrav1.VMPreDeleteBackupAnnotation]; ok {
+			if vm.Annotations == nil {
+				vm.Annotations = map[string]string{}
+			}
+			vm.Annotations[infrav1.VMPreDeleteBackupAnnotation] = val
+		}
+
+		// The labels and annotations of the Machine propagated to the tags
+		// and custom attributes of the VM are kept in sync with the Machine.
+		for _, mapping := range ctx.VSphereMachine.Spec.MetadataPropagation {
+			if mapping.Label != "" {
+				if val, ok := ctx.Machine.Labels[mapping.Label]; ok {
+					vm.Labels[mapping.Label] = val
+				} else {
+					delete(vm.Labels, mapping.Label)
+				}
+			}
+			if mapping.Annotation != "" {
+				if val, ok := ctx.Machine.Annotations[mapping.Annotation]; ok {
+					if vm.Annotations == nil {
+						vm.Annotations = map[string]string{}
+					}
+					vm.Annotations[mapping.Annotation] = val
+				} else {
+					delete(vm.Annotations, mapping.Annotation)
+				}
+			}
+		}
+
 		// Copy the VSphereMachine's VM clone spec into the VSphereVM's
 		// clone spec.
 		ctx.VSphereMachine.Spec.VirtualMachineCloneSpec.DeepCopyInto(&vm.Spec.VirtualMachineCloneSpec)
 
+		// The VM of a machine referencing a VSphereMachineImageMapping is
+		// cloned from the template, or the VSphereMachineImage, mapped to
+		// the Kubernetes version of the machine, and the one of a machine
+		// referencing a VSphereMachineImage from the template of the image.
+		// The template of an existing VSphereVM is kept.
+		image := ctx.VSphereMachine.Spec.Image
+		if imageMapping := ctx.VSphereMachine.Spec.ImageMapping; imageMapping != "" {
+			if vsphereVM != nil {
+				vm.Spec.Template = vsphereVM.Spec.Template
+			} else {
+				template, mappedImage, err := infrautilv1.ResolveImageMapping(ctx, ctx.Client, ctx.VSphereMachine.Namespace, imageMapping, ctx.Machine.Spec.Version)
+				if err != nil {
+					return err
+				}
+				if template == "" && mappedImage == "" {
+					return errors.Errorf("VSphereMachineImageMapping %s does not map Kubernetes version %s", imageMapping, pointer.StringDeref(ctx.Machine.Spec.Version, ""))
+				}
+				vm.Spec.Template = template
+				image = mappedImage
+			}
+		}
+		if image != "" {
+			if vsphereVM != nil {
+				vm.Spec.Template = vsphereVM.Spec.Template
+			} else {
+				template, err := v.imageTemplate(ctx, image)
+				if err != nil {
+					return err
+				}
+				if template == "" {
+					return errors.Errorf("VSphereMachineImage %s is not imported", image)
+				}
+				vm.Spec.Template = template
+			}
+		}
+
+		// The addresses allocated to the network devices of an existing
+		// VSphereVM from their VSphereIPPool are kept.
+		if vsphereVM != nil {
+			keepAllocatedAddresses(vm.Spec.Network.Devices, vsphereVM.Spec.Network.Devices)
+		}
+
 		// If Failure Domain is present on CAPI machine, use that to override the vm clone spec.
 		if overrideFunc, ok := v.generateOverrideFunc(ctx); ok {
 			overrideFunc(vm)
@@ -362,7 +593,8 @@ func (v *VimMachineService) createOrUpdateVSPhereVM(ctx *context.VIMMachineConte
 		// Several of the VSphereVM's clone spec properties can be derived
 		// from multiple places. The order is:
 		//
-		//   1. From the Machine.Spec.FailureDomain
+		//   1. From the Machine.Spec.FailureDomain, or the deployment zone
+		//      chosen for the VSphereMachine
 		//   2. From the VSphereMachine.Spec (the DeepCopyInto above)
 		//   3. From the VSphereCluster.Spec
 		if vm.Spec.Server == "" {
@@ -371,9 +603,47 @@ func (v *VimMachineService) createOrUpdateVSPhereVM(ctx *context.VIMMachineConte
 		if vm.Spec.Thumbprint == "" {
 			vm.Spec.Thumbprint = ctx.VSphereCluster.Spec.Thumbprint
 		}
+		// The network devices without a network are attached to the NSX-T
+		// segment of the cluster.
+		if ctx.VSphereCluster.Spec.NSXT != nil {
+			segmentName := nsxt.SegmentName(ctx.Cluster.Namespace, ctx.Cluster.Name)
+			for i := range vm.Spec.Network.Devices {
+				if vm.Spec.Network.Devices[i].NetworkName == "" {
+					vm.Spec.Network.Devices[i].NetworkName = segmentName
+				}
+			}
+		}
+		// The template, the datastore and the networks left unset are
+		// defaulted by the VSphereProviderConfig when the VSphereVM is
+		// created, and kept afterwards, as the spec of a VSphereVM is
+		// immutable.
+		if vsphereVM != nil {
+			infrautilv1.KeepProviderConfigDefaults(&vm.Spec.VirtualMachineCloneSpec, &vsphereVM.Spec.VirtualMachineCloneSpec)
+		} else {
+			providerConfig, err := infrautilv1.GetProviderConfig(ctx, ctx.Client)
+			if err != nil {
+				return err
+			}
+			if vm.Spec.Template == "" && ctx.VSphereMachine.Spec.ProviderID == nil {
+				vm.Spec.Template = infrautilv1.DefaultTemplate(providerConfig, ctx.Machine.Spec.Version)
+			}
+			infrautilv1.ApplyProviderConfig(&vm.Spec.VirtualMachineCloneSpec, providerConfig)
+		}
 		if vsphereVM != nil {
 			vm.Spec.BiosUUID = vsphereVM.Spec.BiosUUID
 		}
+		// Machines adopting a pre-existing VM identify it by their provider ID.
+		if vm.Spec.BiosUUID == "" && vm.Spec.Template == "" && ctx.VSphereMachine.Spec.ProviderID != nil {
+			vm.Spec.BiosUUID = infrautilv1.ConvertProviderIDToUUID(ctx.VSphereMachine.Spec.ProviderID)
+		}
+		vm.Spec.PowerOffMode = ctx.VSphereMachine.Spec.PowerOffMode
+		vm.Spec.GuestSoftPowerOffTimeout = ctx.VSphereMachine.Spec.GuestSoftPowerOffTimeout
+		vm.Spec.DeletionPolicy = ctx.VSphereMachine.Spec.DeletionPolicy
+		vm.Spec.DriftPolicy = ctx.VSphereMachine.Spec.DriftPolicy
+		vm.Spec.PowerRecoveryPolicy = ctx.VSphereMachine.Spec.PowerRecoveryPolicy
+		vm.Spec.SnapshotSchedule = ctx.VSphereMachine.Spec.SnapshotSchedule
+		vm.Spec.FailureRetryPolicy = ctx.VSphereMachine.Spec.FailureRetryPolicy
+		vm.Spec.Placement = ctx.VSphereMachine.Spec.Placement
 		return nil
 	}
 	if _, err := ctrlutil.CreateOrUpdate(ctx, ctx.Client, vm, mutateFn); err != nil {
@@ -389,11 +659,71 @@ func (v *VimMachineService) createOrUpdateVSPhereVM(ctx *context.VIMMachineConte
 	return vm, nil
 }
 
+// reconcileDeploymentZone chooses the deployment zone of a machine without
+// failure domain among the deployment zones of the VSphereMachine. The
+// machines of a MachineDeployment are distributed across the zones in
+// proportion to their weights by choosing the zone with the fewest machines
+// per weight, the first one in the list on a tie. The choice is recorded in
+// the failure domain of the VSphereMachine, which Cluster API copies to the
+// Machine.
+func (v *VimMachineService) reconcileDeploymentZone(ctx *context.VIMMachineContext) error {
+	zones := ctx.VSphereMachine.Spec.DeploymentZones
+	if len(zones) == 0 || ctx.VSphereMachine.Spec.FailureDomain != nil {
+		return nil
+	}
+	// The failure domain of the Machine, e.g. chosen by the control plane,
+	// takes precedence.
+	if failureDomain := ctx.Machine.Spec.FailureDomain; failureDomain != nil {
+		ctx.VSphereMachine.Spec.FailureDomain = pointer.String(*failureDomain)
+		return nil
+	}
+
+	vsphereMachineList := &infrav1.VSphereMachineList{}
+	labels := client.MatchingLabels{clusterv1.ClusterLabelName: ctx.Cluster.Name}
+	if deploymentName := ctx.Machine.Labels[clusterv1.MachineDeploymentLabelName]; deploymentName != "" {
+		labels[clusterv1.MachineDeploymentLabelName] = deploymentName
+	}
+	if err := ctx.Client.List(ctx, vsphereMachineList, client.InNamespace(ctx.VSphereMachine.Namespace), labels); err != nil {
+		return errors.Wrapf(err, "unable to list VSphereMachines to choose the deployment zone of %s", ctx)
+	}
+
+	usage := map[string]int64{}
+	for i := range vsphereMachineList.Items {
+		vsphereMachine := &vsphereMachineList.Items[i]
+		if vsphereMachine.UID == ctx.VSphereMachine.UID || !vsphereMachine.DeletionTimestamp.IsZero() ||
+			vsphereMachine.Spec.FailureDomain == nil {
+			continue
+		}
+		usage[*vsphereMachine.Spec.FailureDomain]++
+	}
+
+	weight := func(zone infrav1.DeploymentZoneWeight) int64 {
+		if zone.Weight <= 0 {
+			return 1
+		}
+		return int64(zone.Weight)
+	}
+	zone := zones[0]
+	for _, candidate := range zones[1:] {
+		// usage[candidate]/weight(candidate) < usage[zone]/weight(zone)
+		if usage[candidate.Name]*weight(zone) < usage[zone.Name]*weight(candidate) {
+			zone = candidate
+		}
+	}
+	ctx.Logger.Info("choosing deployment zone", "deploymentZone", zone.Name)
+	ctx.VSphereMachine.Spec.FailureDomain = pointer.String(zone.Name)
+	return nil
+}
+
 // generateOverrideFunc returns a function which can override the values in the VSphereVM Spec
-// with the values from the FailureDomain (if any) set on the owner CAPI machine.
+// with the values from the FailureDomain (if any) set on the owner CAPI machine, or
+// chosen among the deployment zones of the VSphereMachine.
 //nolint:nestif
 func (v *VimMachineService) generateOverrideFunc(ctx *context.VIMMachineContext) (func(vm *infrav1.VSphereVM), bool) {
 	failureDomainName := ctx.Machine.Spec.FailureDomain
+	if failureDomainName == nil {
+		failureDomainName = ctx.VSphereMachine.Spec.FailureDomain
+	}
 	if failureDomainName == nil {
 		return nil, false
 	}
@@ -411,8 +741,18 @@ func (v *VimMachineService) generateOverrideFunc(ctx *context.VIMMachineContext)
 		return nil, false
 	}
 
+	// The machines of the deployment zones of an additional vCenter of the
+	// cluster are cloned in it.
+	server, thumbprint := vsphereDeploymentZone.Spec.Server, ""
+	if vcenter := deploymentZoneVCenter(ctx.VSphereCluster, vsphereDeploymentZone.Name, server); vcenter != nil {
+		server, thumbprint = vcenter.Server, vcenter.Thumbprint
+	}
+
 	overrideWithFailureDomainFunc := func(vm *infrav1.VSphereVM) {
-		vm.Spec.Server = vsphereDeploymentZone.Spec.Server
+		vm.Spec.Server = server
+		if thumbprint != "" {
+			vm.Spec.Thumbprint = thumbprint
+		}
 		vm.Spec.Datacenter = vsphereFailureDomain.Spec.Topology.Datacenter
 		if vsphereDeploymentZone.Spec.PlacementConstraint.Folder != "" {
 			vm.Spec.Folder = vsphereDeploymentZone.Spec.PlacementConstraint.Folder
@@ -422,6 +762,18 @@ func (v *VimMachineService) generateOverrideFunc(ctx *context.VIMMachineContext)
 		}
 		if vsphereFailureDomain.Spec.Topology.Datastore != "" {
 			vm.Spec.Datastore = vsphereFailureDomain.Spec.Topology.Datastore
+			vm.Spec.Datastores = nil
+		}
+		// The VM is created in the datastore cluster of the failure domain
+		// rather than the datastores of the machine.
+		if vsphereFailureDomain.Spec.Topology.DatastoreCluster != "" {
+			vm.Spec.Datastore = ""
+			vm.Spec.Datastores = nil
+		}
+		// The data of the VM is kept on the site of the vSAN stretched
+		// cluster by the storage policy of the site.
+		if site := vsphereFailureDomain.Spec.Topology.VSANStretchedCluster; site != nil {
+			vm.Spec.StoragePolicyName = site.StoragePolicyName
 		}
 		if len(vsphereFailureDomain.Spec.Topology.Networks) > 0 {
 			vm.Spec.Network.Devices = overrideNetworkDeviceSpecs(vm.Spec.Network.Devices, vsphereFailureDomain.Spec.Topology.Networks)
@@ -430,6 +782,29 @@ func (v *VimMachineService) generateOverrideFunc(ctx *context.VIMMachineContext)
 	return overrideWithFailureDomainFunc, true
 }
 
+// deploymentZoneVCenter returns the additional vCenter of the cluster the
+// machines of the deployment zone are cloned in, i.e. the one listing the
+// zone or, if none does, the one with the server of the zone. Nil is returned
+// when the machines are cloned in the vCenter of the cluster.
+func deploymentZoneVCenter(vsphereCluster *infrav1.VSphereCluster, zone, server string) *infrav1.VCenterSpec {
+	if vsphereCluster == nil {
+		return nil
+	}
+	var serverVCenter *infrav1.VCenterSpec
+	for i := range vsphereCluster.Spec.VCenters {
+		vcenter := &vsphereCluster.Spec.VCenters[i]
+		for _, name := range vcenter.Zones {
+			if name == zone {
+				return vcenter
+			}
+		}
+		if vcenter.Server == server && serverVCenter == nil {
+			serverVCenter = vcenter
+		}
+	}
+	return serverVCenter
+}
+
 // overrideNetworkDeviceSpecs updates the network devices with the network definitions from the PlacementConstraint.
 // The substitution is done based on the order in which the network devices have been defined.
 //
@@ -456,3 +831,242 @@ func overrideNetworkDeviceSpecs(deviceSpecs []infrav1.NetworkDeviceSpec, network
 
 	return devices
 }
+
+// reconcileHostHealth mirrors the UnhealthyHost condition of the VSphereVM
+// to the VSphereMachine. Every time the condition changes, it is also set on
+// the node of the machine in the workload cluster, so a MachineHealthCheck
+// with an UnhealthyHost unhealthy condition remediates the machine.
+func (v *VimMachineService) reconcileHostHealth(ctx *context.VIMMachineContext, vm *unstructured.Unstructured) error {
+	vmCondition := conditions.Get(conditions.UnstructuredGetter(vm), infrav1.UnhealthyHostCondition)
+	machineCondition := conditions.Get(ctx.VSphereMachine, infrav1.UnhealthyHostCondition)
+
+	if vmCondition == nil && machineCondition == nil {
+		return nil
+	}
+	if vmCondition != nil && machineCondition != nil &&
+		vmCondition.Status == machineCondition.Status &&
+		vmCondition.Reason == machineCondition.Reason &&
+		vmCondition.Message == machineCondition.Message {
+		return nil
+	}
+
+	// The machine condition is only updated once the node is, so a failed
+	// update of the node is retried.
+	if err := v.setNodeHostCondition(ctx, vmCondition); err != nil {
+		return err
+	}
+	if vmCondition == nil {
+		conditions.Delete(ctx.VSphereMachine, infrav1.UnhealthyHostCondition)
+	} else {
+		conditions.Set(ctx.VSphereMachine, vmCondition)
+	}
+	return nil
+}
+
+// setNodeHostCondition sets the UnhealthyHost condition of the node of the
+// machine from the condition of its VSphereVM, which is nil once the host is
+// healthy.
+func (v *VimMachineService) setNodeHostCondition(ctx *context.VIMMachineContext, vmCondition *clusterv1.Condition) error {
+	nodeCondition := corev1.NodeCondition{
+		Type:   corev1.NodeConditionType(infrav1.UnhealthyHostCondition),
+		Status: corev1.ConditionFalse,
+		Reason: "HostHealthy",
+	}
+	if vmCondition != nil {
+		nodeCondition.Status = vmCondition.Status
+		nodeCondition.Reason = vmCondition.Reason
+		nodeCondition.Message = vmCondition.Message
+	}
+	return v.setNodeCondition(ctx, nodeCondition)
+}
+
+// reconcileSpecSynced mirrors the SpecSynced condition of the VSphereVM to
+// the VSphereMachine. With the Revert drift policy, every time the condition
+// changes it is also set on the node of the machine, where it is only false
+// for a drift that cannot be reverted in place, so a MachineHealthCheck with
+// a SpecSynced unhealthy condition replaces the machine.
+func (v *VimMachineService) reconcileSpecSynced(ctx *context.VIMMachineContext, vm *unstructured.Unstructured) error {
+	vmCondition := conditions.Get(conditions.UnstructuredGetter(vm), infrav1.VMSpecSyncedCondition)
+	machineCondition := conditions.Get(ctx.VSphereMachine, infrav1.VMSpecSyncedCondition)
+
+	if vmCondition == nil && machineCondition == nil {
+		return nil
+	}
+	if vmCondition != nil && machineCondition != nil &&
+		vmCondition.Status == machineCondition.Status &&
+		vmCondition.Reason == machineCondition.Reason &&
+		vmCondition.Message == machineCondition.Message {
+		return nil
+	}
+
+	if ctx.VSphereMachine.Spec.DriftPolicy == infrav1.VirtualMachineDriftPolicyRevert {
+		nodeCondition := corev1.NodeCondition{
+			Type:   corev1.NodeConditionType(infrav1.VMSpecSyncedCondition),
+			Status: corev1.ConditionTrue,
+			Reason: string(infrav1.VMSpecSyncedCondition),
+		}
+		if vmCondition != nil && vmCondition.Reason == infrav1.VMSpecDriftRequiresReplacementReason {
+			nodeCondition.Status = corev1.ConditionFalse
+			nodeCondition.Reason = vmCondition.Reason
+			nodeCondition.Message = vmCondition.Message
+		}
+		// The machine condition is only updated once the node is, so a
+		// failed update of the node is retried.
+		if err := v.setNodeCondition(ctx, nodeCondition); err != nil {
+			return err
+		}
+	}
+
+	if vmCondition == nil {
+		conditions.Delete(ctx.VSphereMachine, infrav1.VMSpecSyncedCondition)
+	} else {
+		conditions.Set(ctx.VSphereMachine, vmCondition)
+	}
+	return nil
+}
+
+// setNodeCondition sets the condition on the node of the machine in the
+// workload cluster, if the machine has a node.
+func (v *VimMachineService) setNodeCondition(ctx *context.VIMMachineContext, nodeCondition corev1.NodeCondition) error {
+	if ctx.Machine.Status.NodeRef == nil {
+		return nil
+	}
+
+	remoteClient, err := v.remoteClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	node := &corev1.Node{}
+	if err := remoteClient.Get(ctx, client.ObjectKey{Name: ctx.Machine.Status.NodeRef.Name}, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "unable to get node %s", ctx.Machine.Status.NodeRef.Name)
+	}
+
+	nodeCondition.LastHeartbeatTime = metav1.Now()
+	nodeCondition.LastTransitionTime = metav1.Now()
+	patch := client.StrategicMergeFrom(node.DeepCopy())
+	found := false
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == nodeCondition.Type {
+			if node.Status.Conditions[i].Status == nodeCondition.Status {
+				nodeCondition.LastTransitionTime = node.Status.Conditions[i].LastTransitionTime
+			}
+			node.Status.Conditions[i] = nodeCondition
+			found = true
+		}
+	}
+	if !found {
+		node.Status.Conditions = append(node.Status.Conditions, nodeCondition)
+	}
+	if err := remoteClient.Status().Patch(ctx, node, patch); err != nil {
+		return errors.Wrapf(err, "unable to set condition %s of node %s", nodeCondition.Type, node.Name)
+	}
+	ctx.Logger.Info("updated node condition", "node", node.Name, "type", nodeCondition.Type, "status", nodeCondition.Status, "reason", nodeCondition.Reason)
+	return nil
+}
+
+// reconcileNodeTopology sets the labels and annotations of the node of the
+// machine in the workload cluster to the host, the compute cluster, the
+// resource pool and the datastore of its VM, e.g. for topology-aware
+// scheduling, and updates them every time the VM is migrated. Annotations
+// always hold the names, while labels are omitted for names which are not
+// valid label values. The node is only updated when the topology differs from
+// the one last set, which is recorded in the status of the VSphereMachine.
+func (v *VimMachineService) reconcileNodeTopology(ctx *context.VIMMachineContext, vm *unstructured.Unstructured) error {
+	if ctx.Machine.Status.NodeRef == nil {
+		return nil
+	}
+
+	topology := infrav1.VirtualMachineTopology{}
+	topology.Host, _, _ = unstructured.NestedString(vm.Object, "status", "host")
+	topology.ComputeCluster, _, _ = unstructured.NestedString(vm.Object, "status", "computeCluster")
+	topology.ResourcePool, _, _ = unstructured.NestedString(vm.Object, "status", "currentResourcePool")
+	topology.Datastore, _, _ = unstructured.NestedString(vm.Object, "status", "datastore")
+	if topology.Host == "" {
+		return nil
+	}
+	if ctx.VSphereMachine.Status.NodeTopology != nil && *ctx.VSphereMachine.Status.NodeTopology == topology {
+		return nil
+	}
+
+	remoteClient, err := v.remoteClient(ctx)
+	if err != nil {
+		return err
+	}
+	node := &corev1.Node{}
+	if err := remoteClient.Get(ctx, client.ObjectKey{Name: ctx.Machine.Status.NodeRef.Name}, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "unable to get node %s", ctx.Machine.Status.NodeRef.Name)
+	}
+
+	patch := client.MergeFrom(node.DeepCopy())
+	if node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	for key, value := range map[string]string{
+		infrav1.NodeHostLabel:           topology.Host,
+		infrav1.NodeComputeClusterLabel: topology.ComputeCluster,
+		infrav1.NodeResourcePoolLabel:   topology.ResourcePool,
+		infrav1.NodeDatastoreLabel:      topology.Datastore,
+	} {
+		if value == "" {
+			delete(node.Annotations, key)
+		} else {
+			node.Annotations[key] = value
+		}
+		if value == "" || len(validation.IsValidLabelValue(value)) > 0 {
+			delete(node.Labels, key)
+		} else {
+			node.Labels[key] = value
+		}
+	}
+	if err := remoteClient.Patch(ctx, node, patch); err != nil {
+		return errors.Wrapf(err, "unable to set topology labels of node %s", node.Name)
+	}
+	ctx.Logger.Info("updated node topology", "node", node.Name, "host", topology.Host, "computeCluster", topology.ComputeCluster,
+		"resourcePool", topology.ResourcePool, "datastore", topology.Datastore)
+	ctx.VSphereMachine.Status.NodeTopology = &topology
+	return nil
+}
+
+// remoteClient returns a client of the workload cluster of the machine.
+func (v *VimMachineService) remoteClient(ctx *context.VIMMachineContext) (client.Client, error) {
+	getRemoteClient := v.RemoteClientGetter
+	if getRemoteClient == nil {
+		getRemoteClient = remote.NewClusterClient
+	}
+	remoteClient, err := getRemoteClient(ctx, ctx.Name, ctx.Client, client.ObjectKeyFromObject(ctx.Cluster))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get client of workload cluster %s", ctx.Cluster.Name)
+	}
+	return remoteClient, nil
+}
+
+// keepAllocatedAddresses copies the addresses, gateway and nameservers set on
+// the existing network devices allocating their address from a VSphereIPPool
+// to the devices.
+func keepAllocatedAddresses(devices, existing []infrav1.NetworkDeviceSpec) {
+	if len(devices) != len(existing) {
+		return
+	}
+	for i := range devices {
+		if devices[i].IPPool == "" || len(devices[i].IPAddrs) > 0 {
+			continue
+		}
+		devices[i].IPAddrs = existing[i].IPAddrs
+		if devices[i].Gateway4 == "" {
+			devices[i].Gateway4 = existing[i].Gateway4
+		}
+		if len(devices[i].Nameservers) == 0 {
+			devices[i].Nameservers = existing[i].Nameservers
+		}
+	}
+}
@@ -17,12 +17,21 @@ limitations under the License.
 package services
 
 import (
+	goctx "context"
 	"fmt"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
@@ -76,6 +85,18 @@ var _ = Describe("VimMachineService_GenerateOverrideFunc", func() {
 		})
 	})
 
+	Context("When the deployment zone is chosen for the VSphereMachine", func() {
+		It("uses the chosen deployment zone for VM values", func() {
+			machineCtx.VSphereMachine.Spec.FailureDomain = pointer.String("zone-two")
+			overrideFunc, ok := vimMachineService.generateOverrideFunc(machineCtx)
+			Expect(ok).To(BeTrue())
+
+			vm := &infrav1.VSphereVM{Spec: infrav1.VSphereVMSpec{}}
+			overrideFunc(vm)
+			Expect(vm.Spec.Server).To(Equal("server-two"))
+		})
+	})
+
 	Context("When Failure Domain is present", func() {
 		BeforeEach(func() {
 			machineCtx.Machine.Spec.FailureDomain = pointer.String("zone-one")
@@ -100,6 +121,65 @@ var _ = Describe("VimMachineService_GenerateOverrideFunc", func() {
 			Expect(vm.Spec.Datacenter).To(Equal("dc-one"))
 		})
 
+		It("clones the VM in the vCenter of the cluster listing the deployment zone", func() {
+			machineCtx.VSphereCluster.Spec.VCenters = []infrav1.VCenterSpec{
+				{Server: "server-one", Thumbprint: "thumbprint-one"},
+				{Server: "vcenter-two", Thumbprint: "thumbprint-two", Zones: []string{"zone-one"}},
+			}
+			overrideFunc, ok := vimMachineService.generateOverrideFunc(machineCtx)
+			Expect(ok).To(BeTrue())
+
+			vm := &infrav1.VSphereVM{Spec: infrav1.VSphereVMSpec{}}
+			overrideFunc(vm)
+			Expect(vm.Spec.Server).To(Equal("vcenter-two"))
+			Expect(vm.Spec.Thumbprint).To(Equal("thumbprint-two"))
+		})
+
+		It("uses the thumbprint of the vCenter of the cluster with the server of the deployment zone", func() {
+			machineCtx.VSphereCluster.Spec.VCenters = []infrav1.VCenterSpec{{Server: "server-one", Thumbprint: "thumbprint-one"}}
+			overrideFunc, ok := vimMachineService.generateOverrideFunc(machineCtx)
+			Expect(ok).To(BeTrue())
+
+			vm := &infrav1.VSphereVM{Spec: infrav1.VSphereVMSpec{}}
+			overrideFunc(vm)
+			Expect(vm.Spec.Server).To(Equal("server-one"))
+			Expect(vm.Spec.Thumbprint).To(Equal("thumbprint-one"))
+		})
+
+		It("ignores the datastore of the machine with a datastore cluster in the topology", func() {
+			fd := failureDomain("three")
+			fd.Spec.Topology.Datastore = ""
+			fd.Spec.Topology.DatastoreCluster = "pod-three"
+			zone := deplZone("three")
+			Expect(machineCtx.Client.Create(machineCtx, fd)).To(Succeed())
+			Expect(machineCtx.Client.Create(machineCtx, zone)).To(Succeed())
+			machineCtx.Machine.Spec.FailureDomain = pointer.String("zone-three")
+
+			overrideFunc, ok := vimMachineService.generateOverrideFunc(machineCtx)
+			Expect(ok).To(BeTrue())
+
+			vm := &infrav1.VSphereVM{Spec: infrav1.VSphereVMSpec{VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{Datastore: "ds-global", Datastores: []string{"ds-fallback"}}}}
+			overrideFunc(vm)
+			Expect(vm.Spec.Datastore).To(BeEmpty())
+			Expect(vm.Spec.Datastores).To(BeEmpty())
+		})
+
+		It("uses the storage policy of the vSAN stretched cluster site in the topology", func() {
+			fd := failureDomain("four")
+			fd.Spec.Topology.VSANStretchedCluster = &infrav1.VSANStretchedClusterSite{Site: infrav1.SecondaryVSANSite, StoragePolicyName: "keep-data-on-secondary"}
+			zone := deplZone("four")
+			Expect(machineCtx.Client.Create(machineCtx, fd)).To(Succeed())
+			Expect(machineCtx.Client.Create(machineCtx, zone)).To(Succeed())
+			machineCtx.Machine.Spec.FailureDomain = pointer.String("zone-four")
+
+			overrideFunc, ok := vimMachineService.generateOverrideFunc(machineCtx)
+			Expect(ok).To(BeTrue())
+
+			vm := &infrav1.VSphereVM{Spec: infrav1.VSphereVMSpec{VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{StoragePolicyName: "global-policy"}}}
+			overrideFunc(vm)
+			Expect(vm.Spec.StoragePolicyName).To(Equal("keep-data-on-secondary"))
+		})
+
 		Context("for non-existent failure domain value", func() {
 			BeforeEach(func() {
 				machineCtx.Machine.Spec.FailureDomain = pointer.String("non-existent-zone")
@@ -183,3 +263,479 @@ var _ = Describe("VimMachineService_GenerateOverrideFunc", func() {
 		})
 	})
 })
+
+var _ = Describe("VimMachineService_CreateOrUpdateVSphereVM", func() {
+	var (
+		machineCtx        *context.VIMMachineContext
+		vimMachineService *VimMachineService
+	)
+
+	BeforeEach(func() {
+		machineCtx = fake.NewMachineContext(fake.NewClusterContext(fake.NewControllerContext(fake.NewControllerManagerContext())))
+		machineCtx.Machine.Spec.Bootstrap.DataSecretName = pointer.String("bootstrap-data")
+		machineCtx.VSphereMachine.Spec.Network.Devices = []infrav1.NetworkDeviceSpec{
+			{NetworkName: "vm-network", DHCP4: true},
+			{DHCP4: true},
+		}
+		vimMachineService = &VimMachineService{}
+	})
+
+	It("keeps the network devices without a network unchanged without NSX-T", func() {
+		obj, err := vimMachineService.createOrUpdateVSPhereVM(machineCtx, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		devices := obj.(*infrav1.VSphereVM).Spec.Network.Devices
+		Expect(devices[0].NetworkName).To(Equal("vm-network"))
+		Expect(devices[1].NetworkName).To(BeEmpty())
+	})
+
+	It("attaches the network devices without a network to the NSX-T segment of the cluster", func() {
+		machineCtx.VSphereCluster.Spec.NSXT = &infrav1.NSXTSpec{Server: "nsx.example.com"}
+		obj, err := vimMachineService.createOrUpdateVSPhereVM(machineCtx, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		devices := obj.(*infrav1.VSphereVM).Spec.Network.Devices
+		Expect(devices[0].NetworkName).To(Equal("vm-network"))
+		Expect(devices[1].NetworkName).To(Equal(fmt.Sprintf("capv-%s-%s", machineCtx.Cluster.Namespace, machineCtx.Cluster.Name)))
+	})
+
+	It("copies the pre-delete backup annotation of the machine", func() {
+		machineCtx.VSphereMachine.Annotations = map[string]string{infrav1.VMPreDeleteBackupAnnotation: infrav1.VMPreDeleteBackupSnapshot}
+		obj, err := vimMachineService.createOrUpdateVSPhereVM(machineCtx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obj.(*infrav1.VSphereVM).Annotations).To(HaveKeyWithValue(infrav1.VMPreDeleteBackupAnnotation, infrav1.VMPreDeleteBackupSnapshot))
+	})
+
+	It("copies the labels of the machine propagated to vSphere", func() {
+		machineCtx.VSphereMachine.Spec.MetadataPropagation = []infrav1.MetadataPropagationSpec{{Label: "team", TagCategory: "team"}}
+		machineCtx.Machine.Labels = map[string]string{"team": "storage"}
+		obj, err := vimMachineService.createOrUpdateVSPhereVM(machineCtx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obj.(*infrav1.VSphereVM).Labels).To(HaveKeyWithValue("team", "storage"))
+	})
+
+	It("clones the VM from the template of the image of the machine", func() {
+		machineCtx.VSphereMachine.Spec.Image = "ubuntu-2004"
+		_, err := vimMachineService.createOrUpdateVSPhereVM(machineCtx, nil)
+		Expect(err).To(MatchError(ContainSubstring("is not imported")))
+
+		image := &infrav1.VSphereMachineImage{
+			ObjectMeta: metav1.ObjectMeta{Namespace: machineCtx.VSphereMachine.Namespace, Name: "ubuntu-2004"},
+			Status:     infrav1.VSphereMachineImageStatus{Ready: true, TemplatePath: "/dc0/vm/ubuntu-2004"},
+		}
+		Expect(machineCtx.Client.Create(machineCtx, image)).To(Succeed())
+		obj, err := vimMachineService.createOrUpdateVSPhereVM(machineCtx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obj.(*infrav1.VSphereVM).Spec.Template).To(Equal("/dc0/vm/ubuntu-2004"))
+	})
+
+	It("clones the VM from the template mapped to the Kubernetes version of the machine", func() {
+		machineCtx.VSphereMachine.Spec.Template = ""
+		machineCtx.VSphereMachine.Spec.ImageMapping = "ubuntu"
+		machineCtx.Machine.Spec.Version = pointer.String("v1.24.1")
+		_, err := vimMachineService.createOrUpdateVSPhereVM(machineCtx, nil)
+		Expect(err).To(MatchError(ContainSubstring("does not map Kubernetes version v1.24.1")))
+
+		imageMapping := &infrav1.VSphereMachineImageMapping{
+			ObjectMeta: metav1.ObjectMeta{Namespace: machineCtx.VSphereMachine.Namespace, Name: "ubuntu"},
+			Spec: infrav1.VSphereMachineImageMappingSpec{
+				Mappings: []infrav1.KubernetesVersionImage{
+					{KubernetesVersion: "v1.23", Template: "ubuntu-2004-kube-v1.23"},
+					{KubernetesVersion: "v1.24", Image: "ubuntu-2004-kube-v1.24"},
+				},
+			},
+		}
+		image := &infrav1.VSphereMachineImage{
+			ObjectMeta: metav1.ObjectMeta{Namespace: machineCtx.VSphereMachine.Namespace, Name: "ubuntu-2004-kube-v1.24"},
+			Status:     infrav1.VSphereMachineImageStatus{Ready: true, TemplatePath: "/dc0/vm/ubuntu-2004-kube-v1.24"},
+		}
+		Expect(machineCtx.Client.Create(machineCtx, imageMapping)).To(Succeed())
+		Expect(machineCtx.Client.Create(machineCtx, image)).To(Succeed())
+		obj, err := vimMachineService.createOrUpdateVSPhereVM(machineCtx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		vm := obj.(*infrav1.VSphereVM)
+		Expect(vm.Spec.Template).To(Equal("/dc0/vm/ubuntu-2004-kube-v1.24"))
+
+		machineCtx.Machine.Spec.Version = pointer.String("v1.23.5")
+		obj, err = vimMachineService.createOrUpdateVSPhereVM(machineCtx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obj.(*infrav1.VSphereVM).Spec.Template).To(Equal("ubuntu-2004-kube-v1.23"))
+
+		// The template of an existing VSphereVM is kept.
+		obj, err = vimMachineService.createOrUpdateVSPhereVM(machineCtx, vm)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obj.(*infrav1.VSphereVM).Spec.Template).To(Equal("/dc0/vm/ubuntu-2004-kube-v1.24"))
+	})
+
+	It("defaults the template, the datastore and the networks from the VSphereProviderConfig", func() {
+		machineCtx.VSphereMachine.Spec.Template = ""
+		machineCtx.Machine.Spec.Version = pointer.String("v1.23.5")
+		providerConfig := &infrav1.VSphereProviderConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: infrav1.ProviderConfigName},
+			Spec: infrav1.VSphereProviderConfigSpec{
+				Templates: []infrav1.KubernetesVersionTemplate{{KubernetesVersion: "v1.23", Template: "ubuntu-2004-kube-v1.23"}},
+				Datastore: "ds0",
+				Network:   "default-network",
+			},
+		}
+		Expect(machineCtx.Client.Create(machineCtx, providerConfig)).To(Succeed())
+		obj, err := vimMachineService.createOrUpdateVSPhereVM(machineCtx, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		vm := obj.(*infrav1.VSphereVM)
+		Expect(vm.Spec.Template).To(Equal("ubuntu-2004-kube-v1.23"))
+		Expect(vm.Spec.Datastore).To(Equal("ds0"))
+		Expect(vm.Spec.Network.Devices[0].NetworkName).To(Equal("vm-network"))
+		Expect(vm.Spec.Network.Devices[1].NetworkName).To(Equal("default-network"))
+
+		// The defaults of an existing VSphereVM are kept.
+		providerConfig.Spec.Datastore = "ds1"
+		Expect(machineCtx.Client.Update(machineCtx, providerConfig)).To(Succeed())
+		obj, err = vimMachineService.createOrUpdateVSPhereVM(machineCtx, vm)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obj.(*infrav1.VSphereVM).Spec.Datastore).To(Equal("ds0"))
+	})
+})
+
+var _ = Describe("VimMachineService_ReconcileDeploymentZone", func() {
+	var (
+		machineCtx        *context.VIMMachineContext
+		vimMachineService *VimMachineService
+	)
+
+	vsphereMachineInZone := func(name, deploymentName, zone string) *infrav1.VSphereMachine {
+		return &infrav1.VSphereMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: machineCtx.VSphereMachine.Namespace,
+				Name:      name,
+				Labels: map[string]string{
+					clusterv1.ClusterLabelName:           machineCtx.Cluster.Name,
+					clusterv1.MachineDeploymentLabelName: deploymentName,
+				},
+			},
+			Spec: infrav1.VSphereMachineSpec{FailureDomain: pointer.String(zone)},
+		}
+	}
+
+	BeforeEach(func() {
+		machineCtx = fake.NewMachineContext(fake.NewClusterContext(fake.NewControllerContext(fake.NewControllerManagerContext())))
+		machineCtx.Machine.Labels = map[string]string{clusterv1.MachineDeploymentLabelName: "md-0"}
+		machineCtx.VSphereMachine.Spec.DeploymentZones = []infrav1.DeploymentZoneWeight{
+			{Name: "zone-a", Weight: 2},
+			{Name: "zone-b"},
+		}
+		vimMachineService = &VimMachineService{}
+	})
+
+	It("distributes the machines of a MachineDeployment in proportion to the weights of the zones", func() {
+		Expect(machineCtx.Client.Create(machineCtx, vsphereMachineInZone("md-0-a", "md-0", "zone-a"))).To(Succeed())
+		Expect(machineCtx.Client.Create(machineCtx, vsphereMachineInZone("md-1-a", "md-1", "zone-b"))).To(Succeed())
+		Expect(vimMachineService.reconcileDeploymentZone(machineCtx)).To(Succeed())
+		Expect(machineCtx.VSphereMachine.Spec.FailureDomain).To(Equal(pointer.String("zone-b")))
+
+		machineCtx.VSphereMachine.Spec.FailureDomain = nil
+		Expect(machineCtx.Client.Create(machineCtx, vsphereMachineInZone("md-0-b", "md-0", "zone-b"))).To(Succeed())
+		Expect(machineCtx.Client.Create(machineCtx, vsphereMachineInZone("md-0-c", "md-0", "zone-a"))).To(Succeed())
+		Expect(vimMachineService.reconcileDeploymentZone(machineCtx)).To(Succeed())
+		Expect(machineCtx.VSphereMachine.Spec.FailureDomain).To(Equal(pointer.String("zone-a")))
+	})
+
+	It("keeps the failure domain of the Machine", func() {
+		machineCtx.Machine.Spec.FailureDomain = pointer.String("zone-b")
+		Expect(vimMachineService.reconcileDeploymentZone(machineCtx)).To(Succeed())
+		Expect(machineCtx.VSphereMachine.Spec.FailureDomain).To(Equal(pointer.String("zone-b")))
+	})
+})
+
+var _ = Describe("VimMachineService_ReconcileHostHealth", func() {
+	var (
+		machineCtx        *context.VIMMachineContext
+		vimMachineService *VimMachineService
+		remoteClient      client.Client
+		vmObj             *unstructured.Unstructured
+	)
+
+	unhealthyHost := &clusterv1.Condition{
+		Type:    infrav1.UnhealthyHostCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  infrav1.HostInMaintenanceModeReason,
+		Message: "host esx-1 is in maintenance mode",
+	}
+
+	toUnstructured := func(vm *infrav1.VSphereVM) *unstructured.Unstructured {
+		data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(vm)
+		Expect(err).NotTo(HaveOccurred())
+		return &unstructured.Unstructured{Object: data}
+	}
+
+	nodeCondition := func() *corev1.NodeCondition {
+		node := &corev1.Node{}
+		Expect(remoteClient.Get(machineCtx, client.ObjectKey{Name: "node-1"}, node)).To(Succeed())
+		for i := range node.Status.Conditions {
+			if node.Status.Conditions[i].Type == corev1.NodeConditionType(infrav1.UnhealthyHostCondition) {
+				return &node.Status.Conditions[i]
+			}
+		}
+		return nil
+	}
+
+	BeforeEach(func() {
+		controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext())
+		machineCtx = fake.NewMachineContext(fake.NewClusterContext(controllerCtx))
+		machineCtx.Machine.Status.NodeRef = &corev1.ObjectReference{Name: "node-1"}
+
+		remoteClient = ctrlfake.NewClientBuilder().WithObjects(&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			},
+		}).Build()
+		vimMachineService = &VimMachineService{
+			RemoteClientGetter: func(goctx.Context, string, client.Client, client.ObjectKey) (client.Client, error) {
+				return remoteClient, nil
+			},
+		}
+
+		vm := &infrav1.VSphereVM{}
+		conditions.Set(vm, unhealthyHost)
+		vmObj = toUnstructured(vm)
+	})
+
+	It("mirrors the condition to the machine and its node", func() {
+		Expect(vimMachineService.reconcileHostHealth(machineCtx, vmObj)).To(Succeed())
+
+		Expect(conditions.IsTrue(machineCtx.VSphereMachine, infrav1.UnhealthyHostCondition)).To(BeTrue())
+		Expect(conditions.GetReason(machineCtx.VSphereMachine, infrav1.UnhealthyHostCondition)).To(Equal(infrav1.HostInMaintenanceModeReason))
+
+		condition := nodeCondition()
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(infrav1.HostInMaintenanceModeReason))
+	})
+
+	It("clears the condition once the host is healthy", func() {
+		Expect(vimMachineService.reconcileHostHealth(machineCtx, vmObj)).To(Succeed())
+		Expect(vimMachineService.reconcileHostHealth(machineCtx, toUnstructured(&infrav1.VSphereVM{}))).To(Succeed())
+
+		Expect(conditions.Has(machineCtx.VSphereMachine, infrav1.UnhealthyHostCondition)).To(BeFalse())
+		condition := nodeCondition()
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+	})
+
+	It("does not contact the workload cluster when the condition is unchanged", func() {
+		conditions.Set(machineCtx.VSphereMachine, unhealthyHost)
+		vimMachineService.RemoteClientGetter = func(goctx.Context, string, client.Client, client.ObjectKey) (client.Client, error) {
+			return nil, errors.New("unexpected call")
+		}
+		Expect(vimMachineService.reconcileHostHealth(machineCtx, vmObj)).To(Succeed())
+	})
+
+	It("does not update the machine when the node cannot be updated", func() {
+		vimMachineService.RemoteClientGetter = func(goctx.Context, string, client.Client, client.ObjectKey) (client.Client, error) {
+			return nil, errors.New("workload cluster unreachable")
+		}
+		Expect(vimMachineService.reconcileHostHealth(machineCtx, vmObj)).NotTo(Succeed())
+		Expect(conditions.Has(machineCtx.VSphereMachine, infrav1.UnhealthyHostCondition)).To(BeFalse())
+	})
+})
+
+var _ = Describe("VimMachineService_ReconcileSpecSynced", func() {
+	var (
+		machineCtx        *context.VIMMachineContext
+		vimMachineService *VimMachineService
+		remoteClient      client.Client
+	)
+
+	vmObj := func(condition *clusterv1.Condition) *unstructured.Unstructured {
+		vm := &infrav1.VSphereVM{}
+		if condition != nil {
+			conditions.Set(vm, condition)
+		}
+		data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(vm)
+		Expect(err).NotTo(HaveOccurred())
+		return &unstructured.Unstructured{Object: data}
+	}
+
+	nodeCondition := func() *corev1.NodeCondition {
+		node := &corev1.Node{}
+		Expect(remoteClient.Get(machineCtx, client.ObjectKey{Name: "node-1"}, node)).To(Succeed())
+		for i := range node.Status.Conditions {
+			if node.Status.Conditions[i].Type == corev1.NodeConditionType(infrav1.VMSpecSyncedCondition) {
+				return &node.Status.Conditions[i]
+			}
+		}
+		return nil
+	}
+
+	requiresReplacement := &clusterv1.Condition{
+		Type:     infrav1.VMSpecSyncedCondition,
+		Status:   corev1.ConditionFalse,
+		Severity: clusterv1.ConditionSeverityWarning,
+		Reason:   infrav1.VMSpecDriftRequiresReplacementReason,
+		Message:  "the VM no longer matches the spec and cannot be reverted in place: numCPUs",
+	}
+
+	BeforeEach(func() {
+		controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext())
+		machineCtx = fake.NewMachineContext(fake.NewClusterContext(controllerCtx))
+		machineCtx.Machine.Status.NodeRef = &corev1.ObjectReference{Name: "node-1"}
+
+		remoteClient = ctrlfake.NewClientBuilder().WithObjects(&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		}).Build()
+		vimMachineService = &VimMachineService{
+			RemoteClientGetter: func(goctx.Context, string, client.Client, client.ObjectKey) (client.Client, error) {
+				return remoteClient, nil
+			},
+		}
+	})
+
+	It("only mirrors the condition to the machine with the Warn drift policy", func() {
+		Expect(vimMachineService.reconcileSpecSynced(machineCtx, vmObj(requiresReplacement))).To(Succeed())
+
+		Expect(conditions.GetReason(machineCtx.VSphereMachine, infrav1.VMSpecSyncedCondition)).To(Equal(infrav1.VMSpecDriftRequiresReplacementReason))
+		Expect(nodeCondition()).To(BeNil())
+	})
+
+	It("reports a drift that cannot be reverted to the node with the Revert drift policy", func() {
+		machineCtx.VSphereMachine.Spec.DriftPolicy = infrav1.VirtualMachineDriftPolicyRevert
+		Expect(vimMachineService.reconcileSpecSynced(machineCtx, vmObj(requiresReplacement))).To(Succeed())
+
+		Expect(conditions.IsFalse(machineCtx.VSphereMachine, infrav1.VMSpecSyncedCondition)).To(BeTrue())
+		condition := nodeCondition()
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(infrav1.VMSpecDriftRequiresReplacementReason))
+
+		// A drift being reverted does not mark the node for remediation.
+		Expect(vimMachineService.reconcileSpecSynced(machineCtx, vmObj(conditions.FalseCondition(infrav1.VMSpecSyncedCondition,
+			infrav1.VMSpecRevertingReason, clusterv1.ConditionSeverityInfo, "reverting the VM to the spec: customVMXKeys[\"foo\"]")))).To(Succeed())
+		Expect(nodeCondition().Status).To(Equal(corev1.ConditionTrue))
+
+		Expect(vimMachineService.reconcileSpecSynced(machineCtx, vmObj(conditions.TrueCondition(infrav1.VMSpecSyncedCondition)))).To(Succeed())
+		Expect(conditions.IsTrue(machineCtx.VSphereMachine, infrav1.VMSpecSyncedCondition)).To(BeTrue())
+		Expect(nodeCondition().Status).To(Equal(corev1.ConditionTrue))
+	})
+})
+
+var _ = Describe("VimMachineService_ReconcileNodeTopology", func() {
+	var (
+		machineCtx        *context.VIMMachineContext
+		vimMachineService *VimMachineService
+		remoteClient      client.Client
+	)
+
+	vmObj := func(status infrav1.VSphereVMStatus) *unstructured.Unstructured {
+		data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&infrav1.VSphereVM{Status: status})
+		Expect(err).NotTo(HaveOccurred())
+		return &unstructured.Unstructured{Object: data}
+	}
+
+	node := func() *corev1.Node {
+		node := &corev1.Node{}
+		Expect(remoteClient.Get(machineCtx, client.ObjectKey{Name: "node-1"}, node)).To(Succeed())
+		return node
+	}
+
+	BeforeEach(func() {
+		machineCtx = fake.NewMachineContext(fake.NewClusterContext(fake.NewControllerContext(fake.NewControllerManagerContext())))
+		machineCtx.Machine.Status.NodeRef = &corev1.ObjectReference{Name: "node-1"}
+
+		remoteClient = ctrlfake.NewClientBuilder().WithObjects(&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		}).Build()
+		vimMachineService = &VimMachineService{
+			RemoteClientGetter: func(goctx.Context, string, client.Client, client.ObjectKey) (client.Client, error) {
+				return remoteClient, nil
+			},
+		}
+	})
+
+	It("labels the node with the location of the VM and updates it on migration", func() {
+		Expect(vimMachineService.reconcileNodeTopology(machineCtx, vmObj(infrav1.VSphereVMStatus{
+			Host:                "esxi-1.example.com",
+			ComputeCluster:      "cluster-1",
+			CurrentResourcePool: "Team Pool",
+			Datastore:           "ds-1",
+		}))).To(Succeed())
+
+		labels, annotations := node().Labels, node().Annotations
+		Expect(labels).To(HaveKeyWithValue(infrav1.NodeHostLabel, "esxi-1.example.com"))
+		Expect(labels).To(HaveKeyWithValue(infrav1.NodeComputeClusterLabel, "cluster-1"))
+		Expect(labels).To(HaveKeyWithValue(infrav1.NodeDatastoreLabel, "ds-1"))
+		// Names which are not valid label values are only annotated.
+		Expect(labels).NotTo(HaveKey(infrav1.NodeResourcePoolLabel))
+		Expect(annotations).To(HaveKeyWithValue(infrav1.NodeResourcePoolLabel, "Team Pool"))
+		Expect(machineCtx.VSphereMachine.Status.NodeTopology).NotTo(BeNil())
+
+		Expect(vimMachineService.reconcileNodeTopology(machineCtx, vmObj(infrav1.VSphereVMStatus{
+			Host:                "esxi-2.example.com",
+			CurrentResourcePool: "Resources",
+			Datastore:           "ds-1",
+		}))).To(Succeed())
+
+		labels, annotations = node().Labels, node().Annotations
+		Expect(labels).To(HaveKeyWithValue(infrav1.NodeHostLabel, "esxi-2.example.com"))
+		Expect(labels).To(HaveKeyWithValue(infrav1.NodeResourcePoolLabel, "Resources"))
+		Expect(labels).NotTo(HaveKey(infrav1.NodeComputeClusterLabel))
+		Expect(annotations).NotTo(HaveKey(infrav1.NodeComputeClusterLabel))
+	})
+
+	It("does not update the node while the topology is unchanged", func() {
+		status := infrav1.VSphereVMStatus{Host: "esxi-1.example.com", Datastore: "ds-1"}
+		Expect(vimMachineService.reconcileNodeTopology(machineCtx, vmObj(status))).To(Succeed())
+
+		vimMachineService.RemoteClientGetter = func(goctx.Context, string, client.Client, client.ObjectKey) (client.Client, error) {
+			return nil, errors.New("workload cluster unreachable")
+		}
+		Expect(vimMachineService.reconcileNodeTopology(machineCtx, vmObj(status))).To(Succeed())
+		Expect(vimMachineService.reconcileNodeTopology(machineCtx, vmObj(infrav1.VSphereVMStatus{Host: "esxi-2.example.com"}))).NotTo(Succeed())
+		Expect(machineCtx.VSphereMachine.Status.NodeTopology.Host).To(Equal("esxi-1.example.com"))
+	})
+})
+
+var _ = Describe("VimMachineService_ReconcileNetwork", func() {
+	var (
+		machineCtx        *context.VIMMachineContext
+		vimMachineService *VimMachineService
+	)
+
+	BeforeEach(func() {
+		machineCtx = fake.NewMachineContext(fake.NewClusterContext(fake.NewControllerContext(fake.NewControllerManagerContext())))
+		vimMachineService = &VimMachineService{}
+	})
+
+	vmObj := func(status infrav1.VSphereVMStatus) *unstructured.Unstructured {
+		data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&infrav1.VSphereVM{Status: status})
+		Expect(err).NotTo(HaveOccurred())
+		return &unstructured.Unstructured{Object: data}
+	}
+
+	It("publishes the typed addresses of the VM", func() {
+		machineAddresses := []clusterv1.MachineAddress{
+			{Type: clusterv1.MachineInternalIP, Address: "10.0.0.1"},
+			{Type: clusterv1.MachineExternalIP, Address: "192.168.0.1"},
+			{Type: clusterv1.MachineHostName, Address: "vm-0"},
+			{Type: clusterv1.MachineInternalDNS, Address: "vm-0.example.com"},
+		}
+		ok, err := vimMachineService.reconcileNetwork(machineCtx, vmObj(infrav1.VSphereVMStatus{
+			Addresses:        []string{"10.0.0.1", "192.168.0.1"},
+			MachineAddresses: machineAddresses,
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(machineCtx.VSphereMachine.Status.Addresses).To(Equal(machineAddresses))
+	})
+
+	It("publishes the addresses of the VM without typed addresses as external IPs", func() {
+		ok, err := vimMachineService.reconcileNetwork(machineCtx, vmObj(infrav1.VSphereVMStatus{
+			Addresses: []string{"10.0.0.1"},
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(machineCtx.VSphereMachine.Status.Addresses).To(Equal([]clusterv1.MachineAddress{
+			{Type: clusterv1.MachineExternalIP, Address: "10.0.0.1"},
+		}))
+	})
+})
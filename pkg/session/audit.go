@@ -0,0 +1,215 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/audit"
+)
+
+// auditedMethods are the vCenter API methods changing the inventory which
+// are audited.
+var auditedMethods = map[string]bool{
+	"CloneVM_Task":                    true,
+	"CreateFolder":                    true,
+	"CreateResourcePool":              true,
+	"CreateSnapshot_Task":             true,
+	"Destroy_Task":                    true,
+	"ImportVApp":                      true,
+	"MarkAsTemplate":                  true,
+	"MoveIntoFolder_Task":             true,
+	"PowerOffVM_Task":                 true,
+	"PowerOnVM_Task":                  true,
+	"RebootGuest":                     true,
+	"ReconfigVM_Task":                 true,
+	"ReconfigureComputeResource_Task": true,
+	"RelocateVM_Task":                 true,
+	"RemoveSnapshot_Task":             true,
+	"ResetVM_Task":                    true,
+	"ShutdownGuest":                   true,
+	"UnregisterVM":                    true,
+	"UpgradeVM_Task":                  true,
+}
+
+// auditRoundTripper records the vCenter API calls changing the inventory
+// going through it with the default auditor. The calls starting a task are
+// recorded as submitted, the outcome of the task being recorded by the
+// reconciliation tracking it or by the caller waiting for it.
+type auditRoundTripper struct {
+	soap.RoundTripper
+	server string
+}
+
+func (rt auditRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	err := rt.RoundTripper.RoundTrip(ctx, req, res)
+	auditor := audit.Default()
+	method := requestMethod(req)
+	if auditor == nil || !auditedMethods[method] {
+		return err
+	}
+
+	r := audit.Record{
+		Server:    rt.server,
+		Operation: method,
+		Initiator: audit.InitiatorFrom(ctx),
+		Outcome:   audit.Succeeded,
+	}
+	if this, ok := bodyField(req, "Req", "This").(types.ManagedObjectReference); ok {
+		r.Target = this.String()
+	}
+	if err == nil && res.Fault() != nil {
+		err = soap.WrapSoapFault(res.Fault())
+	}
+	if err != nil {
+		r.Outcome = audit.Failed
+		r.Error = err.Error()
+		auditor.Record(r)
+		return err
+	}
+
+	taskRef, ok := bodyField(res, "Res", "Returnval").(types.ManagedObjectReference)
+	if !ok || taskRef.Type != "Task" {
+		auditor.Record(r)
+		return nil
+	}
+	r.TaskID = taskRef.Value
+	r.Outcome = audit.Submitted
+	auditor.Record(r)
+	return nil
+}
+
+// bodyField returns the value of the field of the struct pointed to by the
+// field of the body, e.g. the This of the Req of a request body, or nil if
+// either does not exist.
+func bodyField(body interface{}, name, field string) interface{} {
+	v := reflect.Indirect(reflect.ValueOf(body))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	v = v.FieldByName(name)
+	if !v.IsValid() {
+		return nil
+	}
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	v = v.FieldByName(field)
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// taggingPath is the path of the tagging API of vCenter.
+const taggingPath = "/com/vmware/cis/tagging/"
+
+// auditTransport records the calls to the tagging API of vCenter changing
+// tags, categories or their associations going through it with the default
+// auditor.
+type auditTransport struct {
+	http.RoundTripper
+	server string
+}
+
+func (t auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.RoundTripper.RoundTrip(req)
+	auditor := audit.Default()
+	if auditor == nil {
+		return res, err
+	}
+	operation, target := taggingOperation(req)
+	if operation == "" {
+		return res, err
+	}
+
+	r := audit.Record{
+		Server:    t.server,
+		Operation: operation,
+		Target:    target,
+		Initiator: audit.InitiatorFrom(req.Context()),
+		Outcome:   audit.Succeeded,
+	}
+	switch {
+	case err != nil:
+		r.Outcome = audit.Failed
+		r.Error = err.Error()
+	case res.StatusCode >= http.StatusBadRequest:
+		r.Outcome = audit.Failed
+		r.Error = res.Status
+	}
+	auditor.Record(r)
+	return res, err
+}
+
+// taggingOperation returns the operation and the ID of the target of the
+// request to the tagging API, e.g. AttachTag and urn:vmomi:InventoryServiceTag:1,
+// or an empty operation if the request does not change anything.
+func taggingOperation(req *http.Request) (string, string) {
+	i := strings.Index(req.URL.Path, taggingPath)
+	if i < 0 {
+		return "", ""
+	}
+	resource := strings.TrimPrefix(req.URL.Path[i:], taggingPath)
+	target := ""
+	if j := strings.Index(resource, "/id:"); j >= 0 {
+		resource, target = resource[:j], resource[j+len("/id:"):]
+	}
+	var kind string
+	switch resource {
+	case "tag", "tag-association":
+		kind = "Tag"
+	case "category":
+		kind = "Category"
+	default:
+		return "", ""
+	}
+
+	action := req.URL.Query().Get("~action")
+	switch {
+	case action != "":
+		if strings.HasPrefix(action, "list-") {
+			return "", ""
+		}
+		// e.g. attach-multiple-tags-to-object is AttachMultipleTagsToObject.
+		var operation string
+		title := cases.Title(language.Und)
+		for _, word := range strings.Split(action, "-") {
+			operation += title.String(word)
+		}
+		if resource == "tag-association" && !strings.Contains(strings.ToLower(operation), "tag") {
+			operation += kind
+		}
+		return operation, target
+	case req.Method == http.MethodPost:
+		return "Create" + kind, target
+	case req.Method == http.MethodPatch:
+		return "Update" + kind, target
+	case req.Method == http.MethodDelete:
+		return "Delete" + kind, target
+	}
+	return "", ""
+}
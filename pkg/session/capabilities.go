@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// Capability is a feature of vCenter which is only available from a
+// version on, or which standalone ESXi hosts do not provide.
+type Capability struct {
+	// Name is the name of the feature, as reported to users.
+	Name string
+
+	// MinVersion is the first version of vCenter providing the feature.
+	MinVersion string
+
+	// VCenterOnly is whether the feature requires vCenter, i.e. is not
+	// provided by standalone ESXi hosts.
+	VCenterOnly bool
+}
+
+var (
+	// SecureBootCapability is the support of UEFI Secure Boot in VMs.
+	SecureBootCapability = Capability{Name: "Secure Boot", MinVersion: "6.5.0"}
+
+	// VTPMCapability is the support of virtual TPMs in VMs.
+	VTPMCapability = Capability{Name: "vTPM", MinVersion: "6.7.0", VCenterOnly: true}
+
+	// InstantCloneCapability is the instant clone of running VMs.
+	InstantCloneCapability = Capability{Name: "instant clone", MinVersion: "6.7.0", VCenterOnly: true}
+
+	// NativeKeyProviderCapability is the support of key providers built in
+	// vCenter, without an external KMS.
+	NativeKeyProviderCapability = Capability{Name: "native key provider", MinVersion: "7.0.2", VCenterOnly: true}
+
+	// CustomizationStatusCapability is the report of the status of the
+	// guest customization of VMs.
+	CustomizationStatusCapability = Capability{Name: "guest customization status", MinVersion: "7.0.2", VCenterOnly: true}
+
+	// LinkedCloneCapability is the clone of VMs backed by a snapshot of
+	// their template. ESXi hosts copy the disks of the template instead.
+	LinkedCloneCapability = Capability{Name: "linked clone", VCenterOnly: true}
+
+	// GuestCustomizationCapability is the customization of the guest OS of
+	// VMs when they are cloned, used by Windows VMs.
+	GuestCustomizationCapability = Capability{Name: "guest customization", VCenterOnly: true}
+
+	// TaggingCapability is the tagging of inventory objects.
+	TaggingCapability = Capability{Name: "tags", VCenterOnly: true}
+
+	// StoragePolicyCapability is the placement of VMs and disks according to
+	// storage policies.
+	StoragePolicyCapability = Capability{Name: "storage policies", VCenterOnly: true}
+)
+
+func (c Capability) String() string {
+	if c.MinVersion == "" {
+		return fmt.Sprintf("%s (vCenter only)", c.Name)
+	}
+	return fmt.Sprintf("%s (vCenter %s or later)", c.Name, c.MinVersion)
+}
+
+// VCenterVersion returns the version and the build of the vCenter of the
+// session, e.g. 7.0.3 and 19234570.
+func (s *Session) VCenterVersion() (string, string) {
+	if s.Client == nil {
+		return "", ""
+	}
+	about := s.Client.ServiceContent.About
+	return about.Version, about.Build
+}
+
+// IsStandaloneHost returns whether the endpoint of the session is a
+// standalone ESXi host rather than vCenter.
+func (s *Session) IsStandaloneHost() bool {
+	return s.Client != nil && !s.Client.IsVC()
+}
+
+// Supports returns whether the vCenter of the session provides the
+// capability. Versions which cannot be parsed are assumed to provide it, so
+// the operations using it fail in vCenter rather than being rejected.
+// Standalone ESXi hosts provide none of the capabilities requiring vCenter.
+func (s *Session) Supports(c Capability) bool {
+	if c.VCenterOnly && s.IsStandaloneHost() {
+		return false
+	}
+	if c.MinVersion == "" {
+		return true
+	}
+	current, _ := s.VCenterVersion()
+	v, err := version.ParseGeneric(current)
+	if err != nil {
+		return true
+	}
+	return v.AtLeast(version.MustParseGeneric(c.MinVersion))
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/pbm"
+	pbmmethods "github.com/vmware/govmomi/pbm/methods"
+	pbmtypes "github.com/vmware/govmomi/pbm/types"
+	"github.com/vmware/govmomi/vim25/soap"
+	cliflag "k8s.io/component-base/cli/flag"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// configureTransport configures the transport of the SOAP client of the
+// vCenter at vcURL with the TLS policy, the proxy and the CA bundle of the
+// params.
+func configureTransport(soapClient *soap.Client, vcURL *url.URL, params *Params) error {
+	t := soapClient.DefaultTransport()
+	if err := applyTLSPolicy(t.TLSClientConfig, params.feature.TLS); err != nil {
+		return err
+	}
+	if len(params.caBundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(params.caBundle) {
+			return errors.New("no valid certificate found in the CA bundle")
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+
+	if params.proxy == "" {
+		return nil
+	}
+	proxyURL, err := url.Parse(params.proxy)
+	if err != nil {
+		return errors.Wrapf(err, "error parsing proxy URL %q", params.proxy)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return errors.Errorf("unsupported scheme %q of proxy URL, expected http, https or socks5", proxyURL.Scheme)
+	}
+	t.Proxy = http.ProxyURL(proxyURL)
+
+	// The TLS connections through the proxy are not established by the
+	// dialer of the SOAP client, which falls back to the thumbprints of the
+	// hosts when their certificate is not trusted. The same fallback is
+	// done when the connections are verified instead. The server name of
+	// the connections to IP addresses is not known, they are checked
+	// against the thumbprint of vCenter.
+	if params.thumbprint != "" {
+		config := t.TLSClientConfig
+		rootCAs := config.RootCAs
+		config.InsecureSkipVerify = true
+		config.VerifyConnection = func(cs tls.ConnectionState) error {
+			thumbprint := ""
+			if cs.ServerName != "" {
+				thumbprint = soapClient.Thumbprint(cs.ServerName)
+			}
+			if thumbprint == "" && (cs.ServerName == "" || cs.ServerName == vcURL.Hostname()) {
+				thumbprint = params.thumbprint
+			}
+			return verifyConnection(cs, rootCAs, thumbprint)
+		}
+	}
+	return nil
+}
+
+// ValidateTLSPolicy returns an error if the TLS version or one of the cipher
+// suites of the policy is unknown.
+func ValidateTLSPolicy(policy v1beta1.VCenterTLSPolicy) error {
+	return applyTLSPolicy(&tls.Config{}, policy)
+}
+
+// applyTLSPolicy sets the minimum TLS version and the cipher suites of the
+// policy in the TLS config.
+func applyTLSPolicy(config *tls.Config, policy v1beta1.VCenterTLSPolicy) error {
+	if policy.MinVersion != "" {
+		version, err := cliflag.TLSVersion(policy.MinVersion)
+		if err != nil {
+			return errors.Wrapf(err, "invalid minimum TLS version")
+		}
+		config.MinVersion = version
+	}
+	if len(policy.CipherSuites) > 0 {
+		cipherSuites, err := cliflag.TLSCipherSuites(policy.CipherSuites)
+		if err != nil {
+			return errors.Wrapf(err, "invalid TLS cipher suites")
+		}
+		config.CipherSuites = cipherSuites
+	}
+	return nil
+}
+
+// verifyConnection verifies the certificate of the connection against the
+// root CAs, or the system ones when nil, and against the thumbprint when it is
+// not trusted.
+func verifyConnection(cs tls.ConnectionState, rootCAs *x509.CertPool, thumbprint string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("no certificate presented by the server")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         rootCAs,
+		DNSName:       cs.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	if err == nil {
+		return nil
+	}
+	if thumbprint == "" {
+		return err
+	}
+	if peer := soap.ThumbprintSHA1(cs.PeerCertificates[0]); peer != thumbprint {
+		return errors.Errorf("host %q thumbprint does not match %q", cs.ServerName, thumbprint)
+	}
+	return nil
+}
+
+// NewPbmClient returns a client of the storage policy API of vCenter, which
+// connects through the proxy of the session.
+func (s *Session) NewPbmClient(ctx context.Context) (*pbm.Client, error) {
+	sc := s.Client.Client.NewServiceClient(pbm.Path, pbm.Namespace)
+	sc.DefaultTransport().Proxy = s.Client.Client.DefaultTransport().Proxy
+
+	req := pbmtypes.PbmRetrieveServiceContent{
+		This: pbm.ServiceInstance,
+	}
+	res, err := pbmmethods.PbmRetrieveServiceContent(ctx, sc, &req)
+	if err != nil {
+		return nil, err
+	}
+	return &pbm.Client{Client: sc, ServiceContent: res.Returnval, RoundTripper: sc}, nil
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	evictionReasonIdle     = "idle"
+	evictionReasonOverflow = "overflow"
+)
+
+// CacheOptions are the limits of the session cache.
+type CacheOptions struct {
+	// MaxEntries is the maximum number of sessions kept in the cache, beyond
+	// which the least recently used ones are logged out. A value of 0 does
+	// not limit the number of sessions.
+	MaxEntries int
+
+	// IdleTTL is the time after which a session which was not retrieved from
+	// the cache is logged out. A value of 0 keeps idle sessions.
+	IdleTTL time.Duration
+
+	// InventoryTTL is the time the inventory objects found by the
+	// CachedFinder of a session are cached for. A value of 0 disables the
+	// cache of the inventory objects.
+	InventoryTTL time.Duration
+}
+
+var (
+	cacheOptionsMu sync.RWMutex
+	cacheOptions   CacheOptions
+)
+
+// SetCacheOptions sets the limits of the session cache.
+func SetCacheOptions(opts CacheOptions) {
+	cacheOptionsMu.Lock()
+	defer cacheOptionsMu.Unlock()
+	cacheOptions = opts
+}
+
+func getCacheOptions() CacheOptions {
+	cacheOptionsMu.RLock()
+	defer cacheOptionsMu.RUnlock()
+	return cacheOptions
+}
+
+// touch records that the session was retrieved from the cache.
+func (s *Session) touch() {
+	atomic.StoreInt64(&s.lastUsed, time.Now().UnixNano())
+}
+
+// lastUsedTime returns when the session was last retrieved from the cache.
+func (s *Session) lastUsedTime() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&s.lastUsed))
+}
+
+// EvictIdleSessions logs out the cached sessions which were not retrieved
+// for the idle TTL of the cache, and removes them from the cache.
+func EvictIdleSessions(ctx context.Context) {
+	ttl := getCacheOptions().IdleTTL
+	if ttl <= 0 {
+		return
+	}
+	logger := ctrl.LoggerFrom(ctx).WithName("session")
+	sessionCache.Range(func(key, value interface{}) bool {
+		s := value.(*Session)
+		if time.Since(s.lastUsedTime()) >= ttl {
+			evict(logger, key.(string), s, evictionReasonIdle)
+		}
+		return true
+	})
+}
+
+// evictOverflow logs out the least recently used sessions beyond the
+// maximum number of entries of the cache, and removes them from the cache.
+func evictOverflow(logger logr.Logger) {
+	maxEntries := getCacheOptions().MaxEntries
+	if maxEntries <= 0 {
+		return
+	}
+	type entry struct {
+		key      string
+		session  *Session
+		lastUsed time.Time
+	}
+	var entries []entry
+	sessionCache.Range(func(key, value interface{}) bool {
+		s := value.(*Session)
+		entries = append(entries, entry{key: key.(string), session: s, lastUsed: s.lastUsedTime()})
+		return true
+	})
+	if len(entries) <= maxEntries {
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].lastUsed.Before(entries[j].lastUsed) })
+	for _, e := range entries[:len(entries)-maxEntries] {
+		evict(logger, e.key, e.session, evictionReasonOverflow)
+	}
+}
+
+// evict logs the session out and removes it from the cache, unless it was
+// replaced in the meantime.
+func evict(logger logr.Logger, sessionKey string, s *Session, reason string) {
+	if cachedSession, ok := sessionCache.Load(sessionKey); !ok || cachedSession != s {
+		return
+	}
+	logger.V(2).Info("evicting vSphere client session", "server", s.server, "reason", reason)
+	clearCache(logger, sessionKey)
+	sessionEvictions.WithLabelValues(s.server, reason).Inc()
+}
+
+// cacheSize returns the number of sessions in the cache.
+func cacheSize() float64 {
+	size := 0
+	sessionCache.Range(func(_, _ interface{}) bool {
+		size++
+		return true
+	})
+	return float64(size)
+}
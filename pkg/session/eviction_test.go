@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vmware/govmomi/simulator"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestSessionCacheEviction(t *testing.T) {
+	g := NewWithT(t)
+
+	idleTimeout := simulator.SessionIdleTimeout
+	simulator.SessionIdleTimeout = 0
+	defer func() {
+		simulator.SessionIdleTimeout = idleTimeout
+		SetCacheOptions(CacheOptions{})
+	}()
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	newParams := func(keepAlive time.Duration) *Params {
+		return NewParams().
+			WithServer(simr.ServerURL().Host).
+			WithUserInfo(simr.Username(), simr.Password()).
+			WithDatacenter("*").
+			WithFeatures(Feature{KeepAliveDuration: keepAlive})
+	}
+	server := simr.ServerURL().Host
+
+	// The least recently used sessions are evicted beyond the maximum number
+	// of entries.
+	SetCacheOptions(CacheOptions{MaxEntries: 1})
+	first := newParams(time.Minute)
+	_, err = GetOrCreate(context.Background(), first)
+	g.Expect(err).ToNot(HaveOccurred())
+	second := newParams(2 * time.Minute)
+	_, err = GetOrCreate(context.Background(), second)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, ok := sessionCache.Load(first.sessionKey())
+	g.Expect(ok).To(BeFalse())
+	_, ok = sessionCache.Load(second.sessionKey())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(testutil.ToFloat64(sessionEvictions.WithLabelValues(server, evictionReasonOverflow))).To(BeEquivalentTo(1))
+	g.Expect(testutil.ToFloat64(sessionCacheEntries)).To(BeEquivalentTo(1))
+	assertSessionCountEqualTo(g, simr, 1)
+
+	// Sessions used within the idle TTL are kept.
+	SetCacheOptions(CacheOptions{IdleTTL: time.Hour})
+	s, err := GetOrCreate(context.Background(), second)
+	g.Expect(err).ToNot(HaveOccurred())
+	EvictIdleSessions(context.Background())
+	_, ok = sessionCache.Load(second.sessionKey())
+	g.Expect(ok).To(BeTrue())
+
+	// Idle sessions are logged out.
+	atomic.StoreInt64(&s.lastUsed, time.Now().Add(-2*time.Hour).UnixNano())
+	EvictIdleSessions(context.Background())
+	_, ok = sessionCache.Load(second.sessionKey())
+	g.Expect(ok).To(BeFalse())
+	g.Expect(testutil.ToFloat64(sessionEvictions.WithLabelValues(server, evictionReasonIdle))).To(BeEquivalentTo(1))
+	assertSessionCountEqualTo(g, simr, 0)
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+)
+
+const (
+	finderKindDatacenter   = "datacenter"
+	finderKindFolder       = "folder"
+	finderKindResourcePool = "resourcePool"
+	finderKindNetwork      = "network"
+	finderKindDatastore    = "datastore"
+)
+
+// CachedFinder looks up the datacenters, the folders, the resource pools,
+// the networks and the datastores of the inventory with the finder of a
+// session, and caches the objects it finds for the inventory TTL of the
+// session cache. Lookups which fail are not cached.
+type CachedFinder struct {
+	finder *find.Finder
+
+	mu      sync.Mutex
+	entries map[finderCacheKey]finderCacheEntry
+}
+
+type finderCacheKey struct {
+	kind string
+	path string
+}
+
+type finderCacheEntry struct {
+	obj     interface{}
+	expires time.Time
+}
+
+// NewCachedFinder returns a CachedFinder looking up the objects with the
+// finder.
+func NewCachedFinder(finder *find.Finder) *CachedFinder {
+	return &CachedFinder{
+		finder:  finder,
+		entries: map[finderCacheKey]finderCacheEntry{},
+	}
+}
+
+// DatacenterOrDefault returns the datacenter at the path, or the default
+// datacenter if the path is empty.
+func (f *CachedFinder) DatacenterOrDefault(ctx context.Context, path string) (*object.Datacenter, error) {
+	obj, err := f.lookup(finderKindDatacenter, path, func() (interface{}, error) {
+		return f.finder.DatacenterOrDefault(ctx, path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*object.Datacenter), nil
+}
+
+// FolderOrDefault returns the folder at the path, or the default folder if
+// the path is empty.
+func (f *CachedFinder) FolderOrDefault(ctx context.Context, path string) (*object.Folder, error) {
+	obj, err := f.lookup(finderKindFolder, path, func() (interface{}, error) {
+		return f.finder.FolderOrDefault(ctx, path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*object.Folder), nil
+}
+
+// ResourcePoolOrDefault returns the resource pool at the path, or the
+// default resource pool if the path is empty.
+func (f *CachedFinder) ResourcePoolOrDefault(ctx context.Context, path string) (*object.ResourcePool, error) {
+	obj, err := f.lookup(finderKindResourcePool, path, func() (interface{}, error) {
+		return f.finder.ResourcePoolOrDefault(ctx, path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*object.ResourcePool), nil
+}
+
+// Network returns the network at the path.
+func (f *CachedFinder) Network(ctx context.Context, path string) (object.NetworkReference, error) {
+	obj, err := f.lookup(finderKindNetwork, path, func() (interface{}, error) {
+		return f.finder.Network(ctx, path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(object.NetworkReference), nil
+}
+
+// Datastore returns the datastore at the path.
+func (f *CachedFinder) Datastore(ctx context.Context, path string) (*object.Datastore, error) {
+	obj, err := f.lookup(finderKindDatastore, path, func() (interface{}, error) {
+		return f.finder.Datastore(ctx, path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*object.Datastore), nil
+}
+
+// Invalidate drops the cached objects, e.g. when an operation using them
+// failed as they may have been moved, renamed or removed since they were
+// looked up.
+func (f *CachedFinder) Invalidate() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries = map[finderCacheKey]finderCacheEntry{}
+}
+
+// lookup returns the cached object of the kind at the path, or looks it up
+// and caches it.
+func (f *CachedFinder) lookup(kind, path string, lookup func() (interface{}, error)) (interface{}, error) {
+	ttl := getCacheOptions().InventoryTTL
+	key := finderCacheKey{kind: kind, path: path}
+	if ttl > 0 {
+		f.mu.Lock()
+		entry, ok := f.entries[key]
+		f.mu.Unlock()
+		if ok && time.Now().Before(entry.expires) {
+			finderCacheHits.WithLabelValues(kind).Inc()
+			return entry.obj, nil
+		}
+	}
+
+	finderCacheMisses.WithLabelValues(kind).Inc()
+	obj, err := lookup()
+	if err != nil || ttl <= 0 {
+		return obj, err
+	}
+	f.mu.Lock()
+	f.entries[key] = finderCacheEntry{obj: obj, expires: time.Now().Add(ttl)}
+	f.mu.Unlock()
+	return obj, nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestCachedFinder(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	s, err := GetOrCreate(ctx, NewParams().
+		WithServer(simr.ServerURL().Host).
+		WithUserInfo(simr.Username(), simr.Password()).
+		WithDatacenter("*"))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	hits := func() float64 { return testutil.ToFloat64(finderCacheHits.WithLabelValues(finderKindDatastore)) }
+	misses := func() float64 { return testutil.ToFloat64(finderCacheMisses.WithLabelValues(finderKindDatastore)) }
+
+	// Nothing is cached without an inventory TTL.
+	hitsBefore, missesBefore := hits(), misses()
+	_, err = s.CachedFinder.Datastore(ctx, "LocalDS_0")
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = s.CachedFinder.Datastore(ctx, "LocalDS_0")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(hits() - hitsBefore).To(BeEquivalentTo(0))
+	g.Expect(misses() - missesBefore).To(BeEquivalentTo(2))
+
+	SetCacheOptions(CacheOptions{InventoryTTL: time.Hour})
+	defer SetCacheOptions(CacheOptions{})
+
+	// The objects found are cached.
+	hitsBefore, missesBefore = hits(), misses()
+	datastore, err := s.CachedFinder.Datastore(ctx, "LocalDS_0")
+	g.Expect(err).ToNot(HaveOccurred())
+	cached, err := s.CachedFinder.Datastore(ctx, "LocalDS_0")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(cached).To(BeIdenticalTo(datastore))
+	g.Expect(hits() - hitsBefore).To(BeEquivalentTo(1))
+	g.Expect(misses() - missesBefore).To(BeEquivalentTo(1))
+
+	// The objects which are not found are not cached.
+	_, err = s.CachedFinder.Datastore(ctx, "missing")
+	g.Expect(err).To(HaveOccurred())
+	_, ok := s.CachedFinder.entries[finderCacheKey{kind: finderKindDatastore, path: "missing"}]
+	g.Expect(ok).To(BeFalse())
+
+	// The objects are looked up again once invalidated.
+	s.CachedFinder.Invalidate()
+	missesBefore = misses()
+	found, err := s.CachedFinder.Datastore(ctx, "LocalDS_0")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found).ToNot(BeIdenticalTo(datastore))
+	g.Expect(found.Reference()).To(Equal(datastore.Reference()))
+	g.Expect(misses() - missesBefore).To(BeEquivalentTo(1))
+
+	// The other kinds of objects are cached as well.
+	folder, err := s.CachedFinder.FolderOrDefault(ctx, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s.CachedFinder.FolderOrDefault(ctx, "")).To(BeIdenticalTo(folder))
+	pool, err := s.CachedFinder.ResourcePoolOrDefault(ctx, "DC0_C0/Resources")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s.CachedFinder.ResourcePoolOrDefault(ctx, "DC0_C0/Resources")).To(BeIdenticalTo(pool))
+	network, err := s.CachedFinder.Network(ctx, "VM Network")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s.CachedFinder.Network(ctx, "VM Network")).To(BeIdenticalTo(network))
+	datacenter, err := s.CachedFinder.DatacenterOrDefault(ctx, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s.CachedFinder.DatacenterOrDefault(ctx, "")).To(BeIdenticalTo(datacenter))
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// errRESTSessionExpired is returned by the keepalive of a REST client whose
+// session expired.
+var errRESTSessionExpired = errors.New("rest client session expired")
+
+// keepAliveBackoff is the backoff of the retries of a keepalive failing with
+// a transient error, jittered so the keepalives of the sessions of a vCenter
+// which failed together are not retried at once.
+var keepAliveBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.5,
+}
+
+// sessionCheckError is returned when it cannot be told whether a session
+// expired, e.g. on a network error, in which case the session is kept rather
+// than logged in again.
+type sessionCheckError struct {
+	error
+}
+
+func (e sessionCheckError) Unwrap() error {
+	return e.error
+}
+
+// keepAlive calls the keepalive, retrying it up to retries times with the
+// jittered keepAliveBackoff while it fails with a transient error. The
+// keepalive of an expired session is not retried, as it is logged in again
+// on its next retrieval from the cache.
+func keepAlive(retries int, keepalive func() error) error {
+	backoff := keepAliveBackoff
+	backoff.Steps = retries + 1
+
+	var lastErr error
+	err := wait.ExponentialBackoff(backoff, func() (bool, error) {
+		lastErr = keepalive()
+		switch {
+		case lastErr == nil:
+			return true, nil
+		case isSessionExpired(lastErr):
+			return false, lastErr
+		default:
+			return false, nil
+		}
+	})
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		return lastErr
+	}
+	return err
+}
+
+// isSessionExpired returns whether the error is returned for a session which
+// is not authenticated anymore, rather than a transient error.
+func isSessionExpired(err error) bool {
+	if errors.Is(err, errRESTSessionExpired) {
+		return true
+	}
+	if soap.IsSoapFault(err) {
+		_, ok := soap.ToSoapFault(err).VimFault().(types.NotAuthenticated)
+		return ok
+	}
+	if soap.IsVimFault(err) {
+		_, ok := soap.ToVimFault(err).(*types.NotAuthenticated)
+		return ok
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestKeepAlive(t *testing.T) {
+	backoff := keepAliveBackoff
+	keepAliveBackoff.Duration = time.Millisecond
+	defer func() { keepAliveBackoff = backoff }()
+
+	notAuthenticated := &soap.Fault{}
+	notAuthenticated.Detail.Fault = types.NotAuthenticated{}
+	transient := errors.New("connection reset by peer")
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			name:      "succeeds",
+			wantCalls: 1,
+		},
+		{
+			name:      "retries transient errors",
+			errs:      []error{transient, transient},
+			wantCalls: 3,
+		},
+		{
+			name:      "gives up after the retries",
+			errs:      []error{transient, transient, transient, transient},
+			wantErr:   transient,
+			wantCalls: 4,
+		},
+		{
+			name:      "does not retry expired SOAP sessions",
+			errs:      []error{transient, soap.WrapSoapFault(notAuthenticated)},
+			wantErr:   soap.WrapSoapFault(notAuthenticated),
+			wantCalls: 2,
+		},
+		{
+			name:      "does not retry expired REST sessions",
+			errs:      []error{errRESTSessionExpired},
+			wantErr:   errRESTSessionExpired,
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			calls := 0
+			err := keepAlive(3, func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if tt.wantErr == nil {
+				g.Expect(err).ToNot(HaveOccurred())
+			} else {
+				g.Expect(err).To(MatchError(tt.wantErr.Error()))
+			}
+			g.Expect(calls).To(Equal(tt.wantCalls))
+		})
+	}
+}
+
+func TestIsSessionExpired(t *testing.T) {
+	g := NewWithT(t)
+
+	notAuthenticated := &soap.Fault{}
+	notAuthenticated.Detail.Fault = types.NotAuthenticated{}
+	g.Expect(isSessionExpired(soap.WrapSoapFault(notAuthenticated))).To(BeTrue())
+	g.Expect(isSessionExpired(soap.WrapVimFault(&types.NotAuthenticated{}))).To(BeTrue())
+	g.Expect(isSessionExpired(errRESTSessionExpired)).To(BeTrue())
+
+	notFound := &soap.Fault{}
+	notFound.Detail.Fault = types.ManagedObjectNotFound{}
+	g.Expect(isSessionExpired(soap.WrapSoapFault(notFound))).To(BeFalse())
+	g.Expect(isSessionExpired(errors.New("i/o timeout"))).To(BeFalse())
+}
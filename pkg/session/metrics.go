@@ -0,0 +1,160 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vmware/govmomi/vim25/soap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	metricsNamespace = "capv"
+	metricsSubsystem = "vcenter"
+
+	keepAliveClientSOAP = "soap"
+	keepAliveClientREST = "rest"
+)
+
+var (
+	sessionCreations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "session_creations_total",
+		Help:      "Number of vCenter sessions created.",
+	}, []string{"server"})
+
+	sessionCacheHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "session_cache_hits_total",
+		Help:      "Number of times an active vCenter session was found in the session cache.",
+	}, []string{"server"})
+
+	sessionCacheMisses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "session_cache_misses_total",
+		Help:      "Number of times no active vCenter session was found in the session cache.",
+	}, []string{"server"})
+
+	keepAliveFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "session_keepalive_failures_total",
+		Help:      "Number of failed vCenter session keepalives by client.",
+	}, []string{"server", "client"})
+
+	sessionRelogins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "session_relogins_total",
+		Help:      "Number of times a cached vCenter session was logged in again by client.",
+	}, []string{"server", "client"})
+
+	sessionEvictions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "session_evictions_total",
+		Help:      "Number of vCenter sessions evicted from the session cache by reason.",
+	}, []string{"server", "reason"})
+
+	sessionCacheEntries = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "session_cache_entries",
+		Help:      "Number of vCenter sessions in the session cache.",
+	}, cacheSize)
+
+	finderCacheHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "inventory_cache_hits_total",
+		Help:      "Number of inventory lookups served from the inventory cache of a vCenter session by kind of object.",
+	}, []string{"kind"})
+
+	finderCacheMisses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "inventory_cache_misses_total",
+		Help:      "Number of inventory lookups made against vCenter by kind of object.",
+	}, []string{"kind"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "request_duration_seconds",
+		Help:      "Latency of vCenter API calls by method.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"server", "method"})
+
+	requestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "request_errors_total",
+		Help:      "Number of failed vCenter API calls by method.",
+	}, []string{"server", "method"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		sessionCreations,
+		sessionCacheHits,
+		sessionCacheMisses,
+		keepAliveFailures,
+		sessionRelogins,
+		sessionEvictions,
+		sessionCacheEntries,
+		finderCacheHits,
+		finderCacheMisses,
+		requestDuration,
+		requestErrors,
+	)
+}
+
+// metricsRoundTripper records the latency and the errors of the vCenter
+// API calls going through it.
+type metricsRoundTripper struct {
+	soap.RoundTripper
+	server string
+}
+
+func (rt metricsRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	method := requestMethod(req)
+	start := time.Now()
+	err := rt.RoundTripper.RoundTrip(ctx, req, res)
+	requestDuration.WithLabelValues(rt.server, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		requestErrors.WithLabelValues(rt.server, method).Inc()
+	}
+	return err
+}
+
+// requestMethod returns the name of the vCenter API method of the request,
+// e.g. RetrieveProperties for a *methods.RetrievePropertiesBody.
+func requestMethod(req soap.HasFault) string {
+	t := reflect.TypeOf(req)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return strings.TrimSuffix(t.Name(), "Body")
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// MissingPrivileges returns the privileges the user of the session does not
+// hold on the entity, e.g. a datastore, among the given ones.
+func (s *Session) MissingPrivileges(ctx context.Context, entity types.ManagedObjectReference, privileges []string) ([]string, error) {
+	if s.Client == nil {
+		return nil, errors.New("vSphere client is not initialized")
+	}
+	userSession, err := s.SessionManager.UserSession(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get user session")
+	}
+	if userSession == nil {
+		return nil, errors.New("user session is not logged in")
+	}
+
+	// The privileges are matched by ID, as the privileges which are not
+	// granted may be omitted from the response of HasPrivilegeOnEntity.
+	res, err := methods.HasPrivilegeOnEntities(ctx, s.Client.Client, &types.HasPrivilegeOnEntities{
+		This:      *s.Client.ServiceContent.AuthorizationManager,
+		Entity:    []types.ManagedObjectReference{entity},
+		SessionId: userSession.Key,
+		PrivId:    privileges,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to check privileges on %s", entity)
+	}
+
+	granted := map[string]bool{}
+	for _, entityPrivilege := range res.Returnval {
+		for _, availability := range entityPrivilege.PrivAvailability {
+			granted[availability.PrivId] = availability.IsGranted
+		}
+	}
+	var missing []string
+	for _, privilege := range privileges {
+		if !granted[privilege] {
+			missing = append(missing, privilege)
+		}
+	}
+	return missing, nil
+}
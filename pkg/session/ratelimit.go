@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/soap"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// rateLimiters holds the rate limiters shared by all the sessions of a
+// vCenter endpoint, keyed by the server and the limits.
+var rateLimiters sync.Map
+
+// rateLimiterFor returns the rate limiter of the vCenter endpoint for the
+// given limits.
+func rateLimiterFor(server string, qps float32, burst int) flowcontrol.RateLimiter {
+	if burst <= 0 {
+		burst = int(qps)
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	key := fmt.Sprintf("%s/%v/%d", server, qps, burst)
+	if limiter, ok := rateLimiters.Load(key); ok {
+		return limiter.(flowcontrol.RateLimiter)
+	}
+	limiter, _ := rateLimiters.LoadOrStore(key, flowcontrol.NewTokenBucketRateLimiter(qps, burst))
+	return limiter.(flowcontrol.RateLimiter)
+}
+
+// rateLimitedRoundTripper throttles the vCenter API calls going through it.
+type rateLimitedRoundTripper struct {
+	soap.RoundTripper
+	limiter flowcontrol.RateLimiter
+}
+
+func (rt rateLimitedRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	if err := rt.limiter.Wait(ctx); err != nil {
+		return errors.Wrapf(err, "rate limit exceeded for %s", requestMethod(req))
+	}
+	return rt.RoundTripper.RoundTrip(ctx, req, res)
+}
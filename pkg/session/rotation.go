@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"context"
+	"sync"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var (
+	// ownerCredentials holds the server and the hash of the credentials
+	// last used by each owner of sessions, keyed by the owner.
+	ownerCredentials   = map[string]serverCredentials{}
+	ownerCredentialsMu sync.Mutex
+)
+
+type serverCredentials struct {
+	server      string
+	credentials string
+}
+
+// RotateCredentials records the credentials of the params as the ones used by
+// the owner, e.g. a VSphereCluster. When the owner used other credentials for
+// the same server before, the cached sessions of the server created with the
+// previous credentials are cleared, and true is returned.
+func RotateCredentials(ctx context.Context, owner string, params *Params) bool {
+	current := serverCredentials{
+		server:      params.server,
+		credentials: params.credentialsHash(),
+	}
+	ownerCredentialsMu.Lock()
+	stale, ok := ownerCredentials[owner]
+	ownerCredentials[owner] = current
+	ownerCredentialsMu.Unlock()
+
+	if !ok || stale.server != current.server || stale.credentials == current.credentials {
+		return false
+	}
+
+	logger := ctrl.LoggerFrom(ctx).WithName("session")
+	sessionCache.Range(func(key, value interface{}) bool {
+		s := value.(*Session)
+		if s.server == stale.server && s.credentials == stale.credentials {
+			logger.V(2).Info("clearing session with rotated credentials", "server", s.server)
+			clearCache(logger, key.(string))
+		}
+		return true
+	})
+	return true
+}
+
+// ForgetCredentials drops the credentials recorded for the owner, e.g. once
+// it is deleted.
+func ForgetCredentials(owner string) {
+	ownerCredentialsMu.Lock()
+	defer ownerCredentialsMu.Unlock()
+	delete(ownerCredentials, owner)
+}
@@ -18,8 +18,12 @@ package session
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -34,36 +38,158 @@ import (
 	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/methods"
 	"github.com/vmware/govmomi/vim25/soap"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/util/wait"
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 )
 
 // global Session map against sessionKeys
-// in map[sessionKey]Session.
+// in map[sessionKey]Session, see Params.sessionKey.
 var sessionCache sync.Map
 
-// Session is a vSphere session with a configured Finder.
+// closed is set once the cached sessions are logged out on shutdown.
+var closed int32
+
+// Session is a vSphere session with a Finder configured for a datacenter.
+// The sessions of the datacenters of a vCenter share its login, so that the
+// machines of a cluster spread across datacenters do not log in once per
+// datacenter.
 type Session struct {
-	*govmomi.Client
+	*vcenterSession
 	Finder     *find.Finder
 	datacenter *object.Datacenter
+
+	// CachedFinder looks up the inventory objects which rarely change, such
+	// as datacenters, folders, resource pools, networks and datastores, with
+	// the Finder and caches them.
+	CachedFinder *CachedFinder
+}
+
+// vcenterSession is the login to a vCenter shared by the sessions of its
+// datacenters.
+type vcenterSession struct {
+	*govmomi.Client
 	TagManager *tags.Manager
+
+	logger      logr.Logger
+	watcherMu   sync.Mutex
+	vmWatcher   *watcher
+	hostWatcher *watcher
+	taskWatcher *watcher
+
+	// server and credentials are the server and the hash of the
+	// credentials the session was created for.
+	server      string
+	credentials string
+
+	// userinfo and restClient are used to log the SOAP and REST clients in
+	// again when their sessions expire, each attempt of which is serialized
+	// by loginMu.
+	userinfo   *url.Userinfo
+	restClient *rest.Client
+	loginMu    sync.Mutex
+
+	// lastUsed is the time in nanoseconds the session was last retrieved
+	// from the cache, which is accessed atomically.
+	lastUsed int64
+
+	// datacenters holds the sessions of the datacenters of the vCenter,
+	// keyed by the path of the datacenter, which is guarded by
+	// datacentersMu.
+	datacenters   map[string]*Session
+	datacentersMu sync.Mutex
+}
+
+// reloginBackoff is the backoff of the attempts to log a cached session in
+// again, jittered so the sessions of a vCenter which expired together are not
+// logged in at once.
+var reloginBackoff = wait.Backoff{
+	Duration: 500 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.5,
+	Steps:    3,
 }
 
 type Feature struct {
 	KeepAliveDuration time.Duration
+
+	// KeepAliveRetries is the number of times a keepalive failing with a
+	// transient error, e.g. a network error, is retried with a jittered
+	// backoff before the keepalive stops. The keepalive of an expired session
+	// is not retried.
+	KeepAliveRetries int
+
+	// QPS is the maximum number of vCenter API calls per second made against
+	// the server. A value of 0 disables the rate limit.
+	QPS float32
+
+	// Burst is the maximum number of vCenter API calls which can be made at
+	// once against the server. Defaults to QPS.
+	Burst int
+
+	// TLS is the TLS policy of the connections to the server.
+	TLS v1beta1.VCenterTLSPolicy
 }
 
 func DefaultFeature() Feature {
 	return Feature{}
 }
 
+// WithRateLimit returns a copy of the feature with the rate limit overridden
+// by the given one, if any.
+func (f Feature) WithRateLimit(rateLimit *v1beta1.VCenterRateLimit) Feature {
+	if rateLimit != nil {
+		f.QPS = float32(rateLimit.QPS)
+		f.Burst = int(rateLimit.Burst)
+	}
+	return f
+}
+
+// WithProviderConfig returns a copy of the feature with the keepalive and the
+// rate limit overridden by the ones of the VSphereProviderConfig, if any.
+func (f Feature) WithProviderConfig(config *v1beta1.VSphereProviderConfig) Feature {
+	if config == nil {
+		return f
+	}
+	if keepAlive := config.Spec.KeepAlive; keepAlive != nil {
+		if keepAlive.Duration != nil {
+			f.KeepAliveDuration = keepAlive.Duration.Duration
+		}
+		if keepAlive.Retries != nil {
+			f.KeepAliveRetries = int(*keepAlive.Retries)
+		}
+	}
+	return f.WithRateLimit(config.Spec.RateLimit)
+}
+
+// WithTLSPolicy returns a copy of the feature with the fields of the TLS
+// policy overridden by the ones set in the given policy, if any. The strict
+// mode cannot be turned off.
+func (f Feature) WithTLSPolicy(policy *v1beta1.VCenterTLSPolicy) Feature {
+	if policy == nil {
+		return f
+	}
+	if policy.MinVersion != "" {
+		f.TLS.MinVersion = policy.MinVersion
+	}
+	if len(policy.CipherSuites) > 0 {
+		f.TLS.CipherSuites = policy.CipherSuites
+	}
+	f.TLS.Strict = f.TLS.Strict || policy.Strict
+	return f
+}
+
 type Params struct {
 	server     string
 	datacenter string
 	userinfo   *url.Userinfo
 	thumbprint string
+	proxy      string
+	caBundle   []byte
 	feature    Feature
 }
 
@@ -93,36 +219,84 @@ func (p *Params) WithThumbprint(thumbprint string) *Params {
 	return p
 }
 
+// WithConnection sets the proxy and the CA bundle of the connection, unless
+// it is nil, overriding the ones set before.
+func (p *Params) WithConnection(connection *v1beta1.VCenterConnectionSpec) *Params {
+	if connection != nil {
+		p.proxy = connection.Proxy
+		p.caBundle = connection.CABundle
+	}
+	return p
+}
+
 func (p *Params) WithFeatures(feature Feature) *Params {
 	p.feature = feature
 	return p
 }
 
+// sessionKey returns the key of the session cache for the params. The key is
+// a hash of the server, the credentials, the connection and the features, so
+// clusters using distinct credentials or settings for the same vCenter do not
+// share a session, and the credentials are not kept in the clear. The
+// datacenter is not part of the key, as the sessions of the datacenters of a
+// vCenter share its login.
+func (p *Params) sessionKey() string {
+	password, _ := p.userinfo.Password()
+	h := sha256.New()
+	fmt.Fprintf(h, "%q %q %q %q %q %q %+v", p.server, p.userinfo.Username(), password, p.thumbprint, p.proxy, p.caBundle, p.feature)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// credentialsHash returns a hash of the credentials of the params.
+func (p *Params) credentialsHash() string {
+	password, _ := p.userinfo.Password()
+	h := sha256.New()
+	fmt.Fprintf(h, "%q %q", p.userinfo.Username(), password)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // GetOrCreate gets a cached session or creates a new one if one does not
 // already exist.
 func GetOrCreate(ctx context.Context, params *Params) (*Session, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "session.GetOrCreate", trace.WithAttributes(
+		attribute.String("vcenter.server", params.server),
+		attribute.String("vcenter.datacenter", params.datacenter),
+	))
+	s, err := getOrCreate(ctx, params)
+	tracing.End(span, err)
+	return s, err
+}
+
+func getOrCreate(ctx context.Context, params *Params) (*Session, error) {
 	logger := ctrl.LoggerFrom(ctx).WithName("session")
+	if atomic.LoadInt32(&closed) == 1 {
+		return nil, errors.New("vSphere client sessions are closed as the controller is shutting down")
+	}
 
-	sessionKey := params.server + params.userinfo.Username() + params.datacenter
+	sessionKey := params.sessionKey()
 	if cachedSession, ok := sessionCache.Load(sessionKey); ok {
 		s := cachedSession.(*Session)
+		s.touch()
 		logger = logger.WithValues("server", params.server, "datacenter", params.datacenter)
 
-		vimSessionActive, err := s.SessionManager.SessionIsActive(ctx)
-		if err != nil {
-			logger.Error(err, "unable to check if vim session is active")
-		}
-
-		tagManagerSession, err := s.TagManager.Session(ctx)
-		if err != nil {
-			logger.Error(err, "unable to check if rest session is active")
-		}
-
-		if vimSessionActive && tagManagerSession != nil {
+		// The SOAP and REST sessions expire independently, and are logged
+		// in again rather than tearing down the whole cached session.
+		err := s.ensureLoggedIn(ctx, logger)
+		if err == nil {
 			logger.V(2).Info("found active cached vSphere client session")
-			return s, nil
+			sessionCacheHits.WithLabelValues(params.server).Inc()
+			return s.forDatacenter(ctx, params.datacenter)
 		}
+		// A session which could not be checked, e.g. on a flaky network,
+		// is kept rather than replaced by a new one, which would fail the
+		// same way.
+		var checkErr sessionCheckError
+		if errors.As(err, &checkErr) {
+			return nil, errors.Wrap(err, "unable to check cached vSphere client session")
+		}
+		logger.Error(err, "unable to log cached vSphere client session in again, creating a new session")
 	}
+	sessionCacheMisses.WithLabelValues(params.server).Inc()
 
 	clearCache(logger, sessionKey)
 	soapURL, err := soap.ParseURL(params.server)
@@ -134,45 +308,90 @@ func GetOrCreate(ctx context.Context, params *Params) (*Session, error) {
 	}
 
 	soapURL.User = params.userinfo
-	client, err := newClient(ctx, logger, sessionKey, soapURL, params.thumbprint, params.feature)
+	client, err := newClient(ctx, logger, soapURL, params)
 	if err != nil {
 		return nil, err
 	}
 
-	session := Session{Client: client}
+	session := Session{vcenterSession: &vcenterSession{
+		Client:      client,
+		logger:      logger,
+		server:      params.server,
+		credentials: params.credentialsHash(),
+		userinfo:    soapURL.User,
+		datacenters: map[string]*Session{},
+	}}
 	session.UserAgent = v1beta1.GroupVersion.String()
 
-	// Assign the finder to the session.
+	// Assign the finder to the session, which looks up the objects in the
+	// default datacenter.
 	session.Finder = find.NewFinder(session.Client.Client, false)
-	// Assign tag manager to the session.
-	manager, err := newManager(ctx, logger, sessionKey, client.Client, soapURL.User, params.feature)
-	if err != nil {
-		return nil, errors.Wrap(err, "unable to create tags manager")
-	}
-	session.TagManager = manager
-
-	// Assign the datacenter if one was specified.
-	if params.datacenter != "" {
-		dc, err := session.Finder.Datacenter(ctx, params.datacenter)
+	// Assign tag manager to the session. Standalone ESXi hosts have no
+	// REST API, and thus no tags.
+	if client.IsVC() {
+		restClient, err := newRestClient(ctx, logger, client.Client, soapURL.User, params.feature)
 		if err != nil {
-			return nil, errors.Wrapf(err, "unable to find datacenter %q", params.datacenter)
+			return nil, errors.Wrap(err, "unable to create tags manager")
 		}
-		session.datacenter = dc
-		session.Finder.SetDatacenter(dc)
+		session.restClient = restClient
+		session.TagManager = tags.NewManager(restClient)
 	}
+	session.CachedFinder = NewCachedFinder(session.Finder)
 	// Cache the session.
+	session.touch()
 	sessionCache.Store(sessionKey, &session)
+	sessionCreations.WithLabelValues(params.server).Inc()
+	evictOverflow(logger)
 
-	logger.V(2).Info("cached vSphere client session", "server", params.server, "datacenter", params.datacenter)
+	vcenterVersion, vcenterBuild := session.VCenterVersion()
+	logger.V(2).Info("cached vSphere client session", "server", params.server,
+		"version", vcenterVersion, "build", vcenterBuild)
 
-	return &session, nil
+	return session.forDatacenter(ctx, params.datacenter)
 }
 
-func newClient(ctx context.Context, logger logr.Logger, sessionKey string, url *url.URL, thumbprint string, feature Feature) (*govmomi.Client, error) {
-	insecure := thumbprint == ""
+// forDatacenter returns the session whose Finder looks up the objects in the
+// datacenter at the path, which shares the login of the session, or the
+// session itself if the path is empty. The sessions of the datacenters are
+// kept for the lifetime of the login, so that they keep the objects cached
+// by their CachedFinder.
+func (s *Session) forDatacenter(ctx context.Context, path string) (*Session, error) {
+	if path == "" {
+		return s, nil
+	}
+	s.datacentersMu.Lock()
+	defer s.datacentersMu.Unlock()
+	if dcSession, ok := s.datacenters[path]; ok {
+		return dcSession, nil
+	}
+
+	finder := find.NewFinder(s.Client.Client, false)
+	dc, err := finder.Datacenter(ctx, path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to find datacenter %q", path)
+	}
+	finder.SetDatacenter(dc)
+	dcSession := &Session{
+		vcenterSession: s.vcenterSession,
+		Finder:         finder,
+		datacenter:     dc,
+		CachedFinder:   NewCachedFinder(finder),
+	}
+	s.datacenters[path] = dcSession
+	return dcSession, nil
+}
+
+func newClient(ctx context.Context, logger logr.Logger, url *url.URL, params *Params) (*govmomi.Client, error) {
+	feature := params.feature
+	// The certificate of vCenter is only verified when the params trust it
+	// by thumbprint or by CA, or when the TLS policy is strict.
+	insecure := params.thumbprint == "" && len(params.caBundle) == 0 && !feature.TLS.Strict
 	soapClient := soap.NewClient(url, insecure)
-	if !insecure {
-		soapClient.SetThumbprint(url.Host, thumbprint)
+	if params.thumbprint != "" {
+		soapClient.SetThumbprint(url.Host, params.thumbprint)
+	}
+	if err := configureTransport(soapClient, url, params); err != nil {
+		return nil, err
 	}
 
 	vimClient, err := vim25.NewClient(ctx, soapClient)
@@ -185,21 +404,29 @@ func newClient(ctx context.Context, logger logr.Logger, sessionKey string, url *
 		SessionManager: session.NewManager(vimClient),
 	}
 
-	vimClient.RoundTripper = session.KeepAliveHandler(vimClient.RoundTripper, feature.KeepAliveDuration, func(tripper soap.RoundTripper) error {
-		// we tried implementing
-		// c.Login here but the client once logged out
-		// keeps errong in invalid username or password
-		// we tried with cached username and password in session still the error persisted
-		// hence we just clear the cache and expect the client to
-		// be recreated in next GetOrCreate call
-		_, err := methods.GetCurrentTime(ctx, tripper)
-		if err != nil {
-			logger.Error(err, "failed to keep alive govmomi client")
-			clearCache(logger, sessionKey)
+	vimClient.RoundTripper = metricsRoundTripper{RoundTripper: vimClient.RoundTripper, server: url.Host}
+	vimClient.RoundTripper = auditRoundTripper{RoundTripper: vimClient.RoundTripper, server: url.Host}
+	if feature.QPS > 0 {
+		vimClient.RoundTripper = rateLimitedRoundTripper{
+			RoundTripper: vimClient.RoundTripper,
+			limiter:      rateLimiterFor(url.Host, feature.QPS, feature.Burst),
 		}
-		return err
+	}
+	vimClient.RoundTripper = session.KeepAliveHandler(vimClient.RoundTripper, feature.KeepAliveDuration, func(tripper soap.RoundTripper) error {
+		// The keepalive stops on error, and is started again when the
+		// session is logged in again on its next retrieval from the cache.
+		return keepAlive(feature.KeepAliveRetries, func() error {
+			_, err := methods.GetCurrentTime(ctx, tripper)
+			if err != nil {
+				logger.Error(err, "failed to keep alive govmomi client")
+				keepAliveFailures.WithLabelValues(url.Host, keepAliveClientSOAP).Inc()
+			}
+			return err
+		})
 	})
 
+	vimClient.RoundTripper = tracingRoundTripper{RoundTripper: vimClient.RoundTripper, server: url.Host}
+
 	if err := c.Login(ctx, url.User); err != nil {
 		return nil, err
 	}
@@ -210,55 +437,179 @@ func newClient(ctx context.Context, logger logr.Logger, sessionKey string, url *
 func clearCache(logger logr.Logger, sessionKey string) {
 	if cachedSession, ok := sessionCache.Load(sessionKey); ok {
 		s := cachedSession.(*Session)
+		s.stopWatchers()
+		s.logout(context.Background(), logger)
+	}
+	sessionCache.Delete(sessionKey)
+}
 
-		// check for the presence of tagmanager session
-		// since calling Logout on an expired session blocks
-		session, err := s.TagManager.Session(context.Background())
+// logout logs the REST and the SOAP sessions out, which also stops their
+// keepalive handlers.
+func (s *Session) logout(ctx context.Context, logger logr.Logger) {
+	// check for the presence of tagmanager session
+	// since calling Logout on an expired session blocks
+	if s.TagManager != nil {
+		session, err := s.TagManager.Session(ctx)
 		if err != nil {
 			logger.Error(err, "unable to get tag manager session")
 		}
 		if session != nil {
 			logger.V(6).Info("found active tag manager session, logging out")
-			err := s.TagManager.Logout(context.Background())
+			err := s.TagManager.Logout(ctx)
 			if err != nil {
 				logger.Error(err, "unable to logout tag manager session")
 			}
 		}
+	}
 
-		vimSessionActive, err := s.SessionManager.SessionIsActive(context.Background())
+	vimSessionActive, err := s.sessionIsActive(ctx)
+	if err != nil {
+		logger.Error(err, "unable to get vim client session")
+	} else if vimSessionActive {
+		logger.V(6).Info("found active vim session, logging out")
+		err := s.SessionManager.Logout(ctx)
 		if err != nil {
-			logger.Error(err, "unable to get vim client session")
-		} else if vimSessionActive {
-			logger.V(6).Info("found active vim session, logging out")
-			err := s.SessionManager.Logout(context.Background())
-			if err != nil {
-				logger.Error(err, "unable to logout vim session")
-			}
+			logger.Error(err, "unable to logout vim session")
 		}
 	}
-	sessionCache.Delete(sessionKey)
 }
 
-// newManager creates a Manager that encompasses the REST Client for the VSphere tagging API.
-func newManager(ctx context.Context, logger logr.Logger, sessionKey string, client *vim25.Client, user *url.Userinfo, feature Feature) (*tags.Manager, error) {
+// LogoutAll drains the watchers of all the cached sessions and logs them out
+// in parallel, until ctx is done. No session is created afterwards, as it is
+// called when the controller manager stops.
+func LogoutAll(ctx context.Context) {
+	atomic.StoreInt32(&closed, 1)
+	logger := ctrl.LoggerFrom(ctx).WithName("session")
+
+	var wg sync.WaitGroup
+	sessionCache.Range(func(key, value interface{}) bool {
+		wg.Add(1)
+		go func(sessionKey interface{}, s *Session) {
+			defer wg.Done()
+			logger := logger.WithValues("server", s.server)
+			s.drainWatchers(ctx)
+			s.logout(ctx, logger)
+			sessionCache.Delete(sessionKey)
+			logger.V(4).Info("logged out vSphere client session")
+		}(key, value.(*Session))
+		return true
+	})
+	wg.Wait()
+}
+
+// newRestClient creates the REST client of the vSphere tagging API.
+func newRestClient(ctx context.Context, logger logr.Logger, client *vim25.Client, user *url.Userinfo, feature Feature) (*rest.Client, error) {
 	rc := rest.NewClient(client)
+	rc.Client.DefaultTransport().Proxy = client.Client.DefaultTransport().Proxy
+	rc.Transport = auditTransport{RoundTripper: rc.Transport, server: rc.URL().Host}
 	rc.Transport = keepalive.NewHandlerREST(rc, feature.KeepAliveDuration, func() error {
-		s, err := rc.Session(ctx)
-		if err != nil {
-			return err
-		}
-		if s != nil {
-			return nil
-		}
+		return keepAlive(feature.KeepAliveRetries, func() error {
+			s, err := rc.Session(tracing.Untraced(ctx))
+			if err != nil {
+				keepAliveFailures.WithLabelValues(rc.URL().Host, keepAliveClientREST).Inc()
+				return err
+			}
+			if s != nil {
+				return nil
+			}
 
-		logger.V(6).Info("rest client session expired, clearing cache")
-		clearCache(logger, sessionKey)
-		return errors.New("rest client session expired")
+			logger.V(6).Info("rest client session expired")
+			keepAliveFailures.WithLabelValues(rc.URL().Host, keepAliveClientREST).Inc()
+			return errRESTSessionExpired
+		})
 	})
+	rc.Transport = tracingTransport{RoundTripper: rc.Transport, server: rc.URL().Host}
 	if err := rc.Login(ctx, user); err != nil {
 		return nil, err
 	}
-	return tags.NewManager(rc), nil
+	return rc, nil
+}
+
+// ensureLoggedIn checks the SOAP and REST sessions of the session, and logs
+// the clients whose session expired in again, retrying with the jittered
+// reloginBackoff. A sessionCheckError is returned when the sessions could
+// not be checked, but none of them failed to log in again.
+func (s *Session) ensureLoggedIn(ctx context.Context, logger logr.Logger) error {
+	var soapErr, restErr error
+	err := wait.ExponentialBackoff(reloginBackoff, func() (bool, error) {
+		// The lock is only held by each attempt, so that concurrent callers
+		// are not blocked for the whole backoff, and find the clients
+		// logged in by the attempt of another caller.
+		s.loginMu.Lock()
+		defer s.loginMu.Unlock()
+		soapErr = s.ensureSOAPLoggedIn(ctx, logger)
+		restErr = s.ensureRESTLoggedIn(ctx, logger)
+		return soapErr == nil && restErr == nil, nil
+	})
+	if err == nil {
+		return nil
+	}
+	var checkErr sessionCheckError
+	if soapErr != nil && !errors.As(soapErr, &checkErr) {
+		return errors.Wrap(soapErr, "unable to log vim client in again")
+	}
+	if restErr != nil && !errors.As(restErr, &checkErr) {
+		return errors.Wrap(restErr, "unable to log rest client in again")
+	}
+	return checkErr
+}
+
+func (s *Session) ensureSOAPLoggedIn(ctx context.Context, logger logr.Logger) error {
+	active, err := s.sessionIsActive(ctx)
+	if err != nil {
+		if !isSessionExpired(err) {
+			return sessionCheckError{errors.Wrap(err, "unable to check if vim session is active")}
+		}
+		logger.V(4).Info("vim session is not authenticated", "error", err.Error())
+	}
+	if active {
+		return nil
+	}
+
+	// The subscriptions of the expired session are gone, the objects are
+	// watched again by new ones the next time they are reconciled.
+	s.stopWatchers()
+
+	logger.V(2).Info("vim session expired, logging in again")
+	if err := s.Login(ctx, s.userinfo); err != nil {
+		return err
+	}
+	sessionRelogins.WithLabelValues(s.server, keepAliveClientSOAP).Inc()
+	return nil
+}
+
+// sessionIsActive returns whether the vim session is logged in. Standalone
+// ESXi hosts do not implement SessionIsActive, their session is active as
+// long as it has a user session.
+func (s *Session) sessionIsActive(ctx context.Context) (bool, error) {
+	if s.Client.IsVC() {
+		return s.SessionManager.SessionIsActive(ctx)
+	}
+	userSession, err := s.SessionManager.UserSession(ctx)
+	if err != nil {
+		return false, err
+	}
+	return userSession != nil, nil
+}
+
+func (s *Session) ensureRESTLoggedIn(ctx context.Context, logger logr.Logger) error {
+	if s.restClient == nil {
+		return nil
+	}
+	restSession, err := s.restClient.Session(ctx)
+	if err != nil {
+		return sessionCheckError{errors.Wrap(err, "unable to check if rest session is active")}
+	}
+	if restSession != nil {
+		return nil
+	}
+
+	logger.V(2).Info("rest session expired, logging in again")
+	if err := s.restClient.Login(ctx, s.userinfo); err != nil {
+		return err
+	}
+	sessionRelogins.WithLabelValues(s.server, keepAliveClientREST).Inc()
+	return nil
 }
 
 // FindByBIOSUUID finds an object by its BIOS UUID.
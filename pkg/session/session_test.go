@@ -19,18 +19,44 @@ package session
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vapi/tags"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2/klogr"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
 
 	"sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/audit"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
 )
 
@@ -84,6 +110,81 @@ func TestGetSession(t *testing.T) {
 	assertSessionCountEqualTo(g, simr, 1)
 }
 
+func TestGetSessionMetrics(t *testing.T) {
+	g := NewWithT(t)
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	server := simr.ServerURL().Host
+	params := NewParams().
+		WithServer(server).
+		WithUserInfo(simr.Username(), simr.Password()).
+		WithDatacenter("*")
+
+	// The first session is created, the second one is served from the cache.
+	_, err = GetOrCreate(context.Background(), params)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = GetOrCreate(context.Background(), params)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(testutil.ToFloat64(sessionCreations.WithLabelValues(server))).To(Equal(1.0))
+	g.Expect(testutil.ToFloat64(sessionCacheMisses.WithLabelValues(server))).To(Equal(1.0))
+	g.Expect(testutil.ToFloat64(sessionCacheHits.WithLabelValues(server))).To(Equal(1.0))
+	g.Expect(testutil.CollectAndCount(requestDuration)).To(BeNumerically(">", 0))
+	g.Expect(testutil.ToFloat64(requestErrors.WithLabelValues(server, "FindByInventoryPath"))).To(BeZero())
+}
+
+func TestGetSessionWithRateLimit(t *testing.T) {
+	g := NewWithT(t)
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	feature := Feature{QPS: 1000}.WithRateLimit(&v1beta1.VCenterRateLimit{QPS: 100, Burst: 200})
+	g.Expect(feature.QPS).To(Equal(float32(100)))
+	g.Expect(feature.Burst).To(Equal(200))
+
+	params := NewParams().
+		WithServer(simr.ServerURL().Host).
+		WithUserInfo(simr.Username(), simr.Password()).
+		WithFeatures(feature).
+		WithDatacenter("*")
+
+	_, err = GetOrCreate(context.Background(), params)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, ok := rateLimiters.Load(fmt.Sprintf("%s/%v/%d", simr.ServerURL().Host, 100, 200))
+	g.Expect(ok).To(BeTrue())
+
+	// The sessions of a vCenter endpoint share the same rate limiter.
+	g.Expect(rateLimiterFor(simr.ServerURL().Host, 100, 200)).To(BeIdenticalTo(rateLimiterFor(simr.ServerURL().Host, 100, 200)))
+	g.Expect(rateLimiterFor(simr.ServerURL().Host, 100, 0)).To(BeIdenticalTo(rateLimiterFor(simr.ServerURL().Host, 100, 100)))
+}
+
+func TestFeatureWithProviderConfig(t *testing.T) {
+	g := NewWithT(t)
+	feature := Feature{KeepAliveDuration: 5 * time.Minute, KeepAliveRetries: 3, QPS: 1000}
+
+	g.Expect(feature.WithProviderConfig(nil)).To(Equal(feature))
+
+	retries := int32(5)
+	feature = feature.WithProviderConfig(&v1beta1.VSphereProviderConfig{
+		Spec: v1beta1.VSphereProviderConfigSpec{
+			KeepAlive: &v1beta1.VCenterKeepAlive{Duration: &metav1.Duration{Duration: time.Minute}, Retries: &retries},
+			RateLimit: &v1beta1.VCenterRateLimit{QPS: 100},
+		},
+	})
+	g.Expect(feature.KeepAliveDuration).To(Equal(time.Minute))
+	g.Expect(feature.KeepAliveRetries).To(Equal(5))
+	g.Expect(feature.QPS).To(Equal(float32(100)))
+}
+
 func sessionCount(stdout io.Reader) (int, error) {
 	buf := make([]byte, 1024)
 	count := 0
@@ -113,6 +214,116 @@ func assertSessionCountEqualTo(g *WithT, simr *vcsim.Simulator, count int) {
 	}, 30*time.Second).Should(BeTrue())
 }
 
+func TestGetSessionPerIdentity(t *testing.T) {
+	g := NewWithT(t)
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	newParams := func(password string, feature Feature) *Params {
+		return NewParams().
+			WithServer(simr.ServerURL().Host).
+			WithUserInfo(simr.Username(), password).
+			WithDatacenter("*").
+			WithFeatures(feature)
+	}
+
+	// Distinct credentials for the same server use distinct sessions.
+	g.Expect(newParams("password-1", Feature{}).sessionKey()).ToNot(Equal(newParams("password-2", Feature{}).sessionKey()))
+	g.Expect(newParams("password-1", Feature{}).sessionKey()).ToNot(ContainSubstring("password-1"))
+
+	s1, err := GetOrCreate(context.Background(), newParams(simr.Password(), Feature{}))
+	g.Expect(err).ToNot(HaveOccurred())
+	s2, err := GetOrCreate(context.Background(), newParams(simr.Password(), Feature{KeepAliveDuration: time.Minute}))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s2).ToNot(BeIdenticalTo(s1))
+
+	s, err := GetOrCreate(context.Background(), newParams(simr.Password(), Feature{}))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s).To(BeIdenticalTo(s1))
+	s, err = GetOrCreate(context.Background(), newParams(simr.Password(), Feature{KeepAliveDuration: time.Minute}))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s).To(BeIdenticalTo(s2))
+}
+
+func TestGetSessionPerDatacenter(t *testing.T) {
+	g := NewWithT(t)
+
+	model := simulator.VPX()
+	model.Datacenter = 2
+
+	simr, err := vcsim.NewBuilder().
+		WithModel(model).Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	newParams := func(datacenter string) *Params {
+		return NewParams().
+			WithServer(simr.ServerURL().Host).
+			WithUserInfo(simr.Username(), simr.Password()).
+			WithDatacenter(datacenter)
+	}
+
+	// The sessions of the datacenters share the login to the vCenter.
+	g.Expect(newParams("DC0").sessionKey()).To(Equal(newParams("DC1").sessionKey()))
+	s0, err := GetOrCreate(context.Background(), newParams("DC0"))
+	g.Expect(err).ToNot(HaveOccurred())
+	s1, err := GetOrCreate(context.Background(), newParams("DC1"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s1).ToNot(BeIdenticalTo(s0))
+	g.Expect(s1.Client).To(BeIdenticalTo(s0.Client))
+	assertSessionCountEqualTo(g, simr, 1)
+
+	// The Finder of each session looks up the objects in its datacenter.
+	folder, err := s0.Finder.DefaultFolder(context.Background())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(folder.InventoryPath).To(Equal("/DC0/vm"))
+	folder, err = s1.CachedFinder.FolderOrDefault(context.Background(), "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(folder.InventoryPath).To(Equal("/DC1/vm"))
+
+	s, err := GetOrCreate(context.Background(), newParams("DC1"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s).To(BeIdenticalTo(s1))
+
+	_, err = GetOrCreate(context.Background(), newParams("DC2"))
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestGetSessionStandaloneHost(t *testing.T) {
+	g := NewWithT(t)
+
+	simr, err := vcsim.NewBuilder().
+		WithModel(simulator.ESX()).Build()
+	if err != nil {
+		t.Fatalf("failed to create ESX simulator")
+	}
+	defer simr.Destroy()
+
+	params := NewParams().
+		WithServer(simr.ServerURL().Host).
+		WithUserInfo(simr.Username(), simr.Password())
+
+	// Standalone hosts have no REST API, and thus no tags.
+	s, err := GetOrCreate(context.Background(), params)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s.IsStandaloneHost()).To(BeTrue())
+	g.Expect(s.TagManager).To(BeNil())
+	g.Expect(s.Supports(TaggingCapability)).To(BeFalse())
+	g.Expect(s.Supports(InstantCloneCapability)).To(BeFalse())
+	g.Expect(s.Supports(SecureBootCapability)).To(BeTrue())
+
+	// The session is checked without the REST client.
+	s2, err := GetOrCreate(context.Background(), params)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s2).To(BeIdenticalTo(s))
+}
+
 func TestGetSessionWithKeepAlive(t *testing.T) {
 	g := NewWithT(t)
 	log := klogr.New()
@@ -236,3 +447,741 @@ func TestGetSessionWithKeepAliveTagManagerLogout(t *testing.T) {
 	g.Expect(sessionInfo.Key).ToNot(BeEquivalentTo(sessionKey))
 	assertSessionCountEqualTo(g, simr, 1)
 }
+
+func TestGetSessionRelogin(t *testing.T) {
+	g := NewWithT(t)
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	server := simr.ServerURL().Host
+	params := NewParams().
+		WithServer(server).
+		WithUserInfo(simr.Username(), simr.Password()).
+		WithDatacenter("*")
+
+	s1, err := GetOrCreate(context.Background(), params)
+	g.Expect(err).ToNot(HaveOccurred())
+	sessionInfo, err := s1.SessionManager.UserSession(context.Background())
+	g.Expect(err).ToNot(HaveOccurred())
+	firstSession := sessionInfo.Key
+
+	// The rest session expires on its own, only the rest client is logged
+	// in again and the vim session is kept.
+	g.Expect(s1.TagManager.Logout(context.Background())).To(Succeed())
+	s2, err := GetOrCreate(context.Background(), params)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s2).To(BeIdenticalTo(s1))
+	restSession, err := s2.TagManager.Session(context.Background())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(restSession).ToNot(BeNil())
+	sessionInfo, err = s2.SessionManager.UserSession(context.Background())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(sessionInfo.Key).To(Equal(firstSession))
+	g.Expect(testutil.ToFloat64(sessionRelogins.WithLabelValues(server, keepAliveClientREST))).To(Equal(1.0))
+
+	// The vim session expires, the vim client is logged in again in place.
+	g.Expect(simr.Run(fmt.Sprintf("session.rm %s", firstSession))).To(Succeed())
+	s3, err := GetOrCreate(context.Background(), params)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s3).To(BeIdenticalTo(s1))
+	sessionInfo, err = s3.SessionManager.UserSession(context.Background())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(sessionInfo).ToNot(BeNil())
+	g.Expect(sessionInfo.Key).ToNot(Equal(firstSession))
+	g.Expect(testutil.ToFloat64(sessionRelogins.WithLabelValues(server, keepAliveClientSOAP))).To(Equal(1.0))
+	g.Expect(testutil.ToFloat64(sessionCreations.WithLabelValues(server))).To(Equal(1.0))
+}
+
+func TestEnsureLoggedInReleasesLock(t *testing.T) {
+	g := NewWithT(t)
+
+	simr, err := vcsim.NewBuilder().Build()
+	g.Expect(err).ToNot(HaveOccurred())
+	defer simr.Destroy()
+
+	params := NewParams().
+		WithServer(simr.ServerURL().Host).
+		WithUserInfo(simr.Username(), simr.Password()).
+		WithDatacenter("*")
+	s, err := GetOrCreate(context.Background(), params)
+	g.Expect(err).ToNot(HaveOccurred())
+	defer s.Logout(context.Background()) //nolint:errcheck
+
+	defer func(backoff wait.Backoff) { reloginBackoff = backoff }(reloginBackoff)
+	reloginBackoff = wait.Backoff{Duration: time.Second, Factor: 1, Steps: 2}
+
+	// The vim session expires and cannot be logged in again.
+	sessionInfo, err := s.SessionManager.UserSession(context.Background())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(simr.Run(fmt.Sprintf("session.rm %s", sessionInfo.Key))).To(Succeed())
+	s.userinfo = url.User(simr.Username())
+
+	done := make(chan error, 1)
+	go func() { done <- s.ensureLoggedIn(context.Background(), klogr.New()) }()
+
+	// The lock is released while the attempts back off.
+	time.Sleep(300 * time.Millisecond)
+	start := time.Now()
+	s.loginMu.Lock()
+	g.Expect(time.Since(start)).To(BeNumerically("<", 500*time.Millisecond))
+	s.loginMu.Unlock()
+
+	select {
+	case err := <-done:
+		g.Expect(err).To(HaveOccurred())
+	case <-time.After(10 * time.Second):
+		t.Fatal("ensureLoggedIn did not return")
+	}
+}
+
+func TestGetSessionWithCABundle(t *testing.T) {
+	g := NewWithT(t)
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	params := func(caBundle []byte) *Params {
+		return NewParams().
+			WithServer(simr.ServerURL().Host).
+			WithUserInfo(simr.Username(), simr.Password()).
+			WithConnection(&v1beta1.VCenterConnectionSpec{CABundle: caBundle})
+	}
+
+	// The certificate of the simulator is verified against the CA bundle
+	// even without thumbprint.
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: simr.Certificate().Raw})
+	s, err := GetOrCreate(context.Background(), params(caBundle))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s).ToNot(BeNil())
+
+	// A bundle with another certificate does not trust the simulator.
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).ToNot(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "other"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	other, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = GetOrCreate(context.Background(), params(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: other})))
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = GetOrCreate(context.Background(), params([]byte("not a certificate")))
+	g.Expect(err).To(MatchError(ContainSubstring("no valid certificate")))
+}
+
+func TestGetSessionWithTLSPolicy(t *testing.T) {
+	g := NewWithT(t)
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	params := func(policy v1beta1.VCenterTLSPolicy) *Params {
+		return NewParams().
+			WithServer(simr.ServerURL().Host).
+			WithUserInfo(simr.Username(), simr.Password()).
+			WithFeatures(Feature{TLS: policy})
+	}
+
+	// The certificate of the simulator is not trusted without thumbprint
+	// in strict mode.
+	_, err = GetOrCreate(context.Background(), params(v1beta1.VCenterTLSPolicy{Strict: true}))
+	g.Expect(err).To(HaveOccurred())
+
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: simr.Certificate().Raw})
+	s, err := GetOrCreate(context.Background(), params(v1beta1.VCenterTLSPolicy{Strict: true}).
+		WithConnection(&v1beta1.VCenterConnectionSpec{CABundle: caBundle}))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s).ToNot(BeNil())
+
+	s, err = GetOrCreate(context.Background(), params(v1beta1.VCenterTLSPolicy{
+		MinVersion:   "VersionTLS12",
+		CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+	}))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s).ToNot(BeNil())
+
+	_, err = GetOrCreate(context.Background(), params(v1beta1.VCenterTLSPolicy{CipherSuites: []string{"TLS_UNKNOWN"}}))
+	g.Expect(err).To(MatchError(ContainSubstring("invalid TLS cipher suites")))
+}
+
+func TestFeatureWithTLSPolicy(t *testing.T) {
+	g := NewWithT(t)
+
+	feature := Feature{TLS: v1beta1.VCenterTLSPolicy{MinVersion: "VersionTLS12", Strict: true}}
+	g.Expect(feature.WithTLSPolicy(nil)).To(Equal(feature))
+
+	feature = feature.WithTLSPolicy(&v1beta1.VCenterTLSPolicy{
+		MinVersion:   "VersionTLS13",
+		CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+	})
+	g.Expect(feature.TLS).To(Equal(v1beta1.VCenterTLSPolicy{
+		MinVersion:   "VersionTLS13",
+		CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+		Strict:       true,
+	}))
+}
+
+func TestGetSessionThroughProxy(t *testing.T) {
+	g := NewWithT(t)
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	var connects int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		atomic.AddInt32(&connects, 1)
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			_ = upstream.Close()
+			return
+		}
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go func() {
+			_, _ = io.Copy(upstream, conn)
+			_ = upstream.Close()
+		}()
+		go func() {
+			_, _ = io.Copy(conn, upstream)
+			_ = conn.Close()
+		}()
+	}))
+	defer proxy.Close()
+
+	params := func(thumbprint string) *Params {
+		return NewParams().
+			WithServer(simr.ServerURL().Host).
+			WithUserInfo(simr.Username(), simr.Password()).
+			WithThumbprint(thumbprint).
+			WithConnection(&v1beta1.VCenterConnectionSpec{Proxy: proxy.URL})
+	}
+
+	// The thumbprint of the simulator is checked through the proxy.
+	s, err := GetOrCreate(context.Background(), params(soap.ThumbprintSHA1(simr.Certificate())))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s).ToNot(BeNil())
+	g.Expect(atomic.LoadInt32(&connects)).ToNot(BeZero())
+
+	_, err = GetOrCreate(context.Background(), params("AA:BB:CC"))
+	g.Expect(err).To(MatchError(ContainSubstring("thumbprint does not match")))
+}
+
+func TestWatchVM(t *testing.T) {
+	g := NewWithT(t)
+
+	// The session must not expire while the subscription is idle.
+	idleTimeout := simulator.SessionIdleTimeout
+	simulator.SessionIdleTimeout = 0
+	defer func() {
+		simulator.SessionIdleTimeout = idleTimeout
+	}()
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	params := NewParams().
+		WithServer(simr.ServerURL().Host).
+		WithUserInfo(simr.Username(), simr.Password()).
+		WithDatacenter("*")
+
+	s, err := GetOrCreate(context.Background(), params)
+	g.Expect(err).ToNot(HaveOccurred())
+	// The subscription has to end before the simulator can be destroyed, and
+	// the session is not left in the cache for the other tests.
+	defer clearCache(klogr.New(), params.sessionKey())
+
+	vm, err := s.Finder.VirtualMachine(context.Background(), "DC0_H0_VM0")
+	g.Expect(err).ToNot(HaveOccurred())
+
+	changes := make(chan struct{}, 10)
+	g.Expect(s.WatchVM(context.Background(), vm.Reference(), func() {
+		changes <- struct{}{}
+	})).To(Succeed())
+	received := func(timeout time.Duration) bool {
+		select {
+		case <-changes:
+			return true
+		case <-time.After(timeout):
+			return false
+		}
+	}
+
+	task, err := vm.PowerOff(context.Background())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(task.Wait(context.Background())).To(Succeed())
+	g.Expect(received(10*time.Second)).To(BeTrue(), "no change reported for the powered off VM")
+
+	// No changes are reported once the VM is unwatched, after the changes
+	// collected before are drained.
+	g.Expect(s.UnwatchVM(context.Background(), vm.Reference())).To(Succeed())
+	for received(500 * time.Millisecond) {
+	}
+	task, err = vm.PowerOn(context.Background())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(task.Wait(context.Background())).To(Succeed())
+	g.Expect(received(time.Second)).To(BeFalse(), "change reported for the unwatched VM")
+}
+
+// syncBuffer is a buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// records returns the audit records written to the buffer.
+func (b *syncBuffer) records() []audit.Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var records []audit.Record
+	decoder := json.NewDecoder(bytes.NewReader(b.buf.Bytes()))
+	for decoder.More() {
+		var r audit.Record
+		if err := decoder.Decode(&r); err != nil {
+			break
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestAudit(t *testing.T) {
+	g := NewWithT(t)
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	out := &syncBuffer{}
+	audit.SetDefault(audit.New(out, nil))
+	defer audit.SetDefault(nil)
+
+	params := NewParams().
+		WithServer(simr.ServerURL().Host).
+		WithUserInfo(simr.Username(), simr.Password()).
+		WithDatacenter("*")
+	s, err := GetOrCreate(context.Background(), params)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	vsphereVM := &v1beta1.VSphereVM{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "vm"}}
+	ctx := audit.WithInitiator(context.Background(), vsphereVM)
+
+	// Reading the inventory is not audited.
+	vm, err := s.Finder.VirtualMachine(ctx, "DC0_H0_VM0")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(out.records()).To(BeEmpty())
+
+	task, err := vm.PowerOff(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(task.Wait(ctx)).To(Succeed())
+	// Powering the VM off again fails.
+	task, err = vm.PowerOff(ctx)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(task.Wait(ctx)).ToNot(Succeed())
+
+	categoryID, err := s.TagManager.CreateCategory(ctx, &tags.Category{Name: "category", Cardinality: "SINGLE"})
+	g.Expect(err).ToNot(HaveOccurred())
+	tagID, err := s.TagManager.CreateTag(ctx, &tags.Tag{Name: "tag", CategoryID: categoryID})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s.TagManager.AttachTag(ctx, tagID, vm.Reference())).To(Succeed())
+
+	// The outcome of the tasks is recorded by the reconciliations tracking
+	// them.
+	g.Expect(out.records()).To(HaveLen(5))
+	records := out.records()
+	for _, r := range records {
+		g.Expect(r.Server).To(Equal(simr.ServerURL().Host))
+		g.Expect(r.Initiator).To(Equal(&audit.ObjectReference{Kind: "VSphereVM", Namespace: "ns", Name: "vm"}))
+	}
+
+	var powerOffs []audit.Record
+	var tagging []string
+	for _, r := range records {
+		if r.Operation == "PowerOffVM_Task" {
+			g.Expect(r.Target).To(Equal(vm.Reference().String()))
+			g.Expect(r.TaskID).ToNot(BeEmpty())
+			powerOffs = append(powerOffs, r)
+			continue
+		}
+		tagging = append(tagging, r.Operation)
+		g.Expect(r.Outcome).To(Equal(audit.Succeeded))
+	}
+	g.Expect(tagging).To(Equal([]string{"CreateCategory", "CreateTag", "AttachTag"}))
+	outcomes := map[audit.Outcome]int{}
+	for _, r := range powerOffs {
+		outcomes[r.Outcome]++
+	}
+	g.Expect(outcomes).To(Equal(map[audit.Outcome]int{audit.Submitted: 2}))
+}
+
+func TestTaggingOperation(t *testing.T) {
+	tests := []struct {
+		method    string
+		path      string
+		operation string
+		target    string
+	}{
+		{http.MethodPost, "/rest/com/vmware/cis/tagging/category", "CreateCategory", ""},
+		{http.MethodDelete, "/rest/com/vmware/cis/tagging/category/id:c1", "DeleteCategory", "c1"},
+		{http.MethodPatch, "/rest/com/vmware/cis/tagging/tag/id:t1", "UpdateTag", "t1"},
+		{http.MethodGet, "/rest/com/vmware/cis/tagging/tag/id:t1", "", ""},
+		{http.MethodPost, "/rest/com/vmware/cis/tagging/tag-association/id:t1?~action=attach", "AttachTag", "t1"},
+		{http.MethodPost, "/rest/com/vmware/cis/tagging/tag-association/id:t1?~action=detach", "DetachTag", "t1"},
+		{http.MethodPost, "/rest/com/vmware/cis/tagging/tag-association?~action=attach-multiple-tags-to-object", "AttachMultipleTagsToObject", ""},
+		{http.MethodPost, "/rest/com/vmware/cis/tagging/tag-association?~action=list-attached-tags", "", ""},
+		{http.MethodPost, "/rest/com/vmware/cis/session", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			g := NewWithT(t)
+			req := httptest.NewRequest(tt.method, "https://vcenter"+tt.path, nil)
+			operation, target := taggingOperation(req)
+			g.Expect(operation).To(Equal(tt.operation))
+			g.Expect(target).To(Equal(tt.target))
+		})
+	}
+}
+
+func TestWatchHost(t *testing.T) {
+	g := NewWithT(t)
+
+	// The session must not expire while the subscription is idle.
+	idleTimeout := simulator.SessionIdleTimeout
+	simulator.SessionIdleTimeout = 0
+	defer func() {
+		simulator.SessionIdleTimeout = idleTimeout
+	}()
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	params := NewParams().
+		WithServer(simr.ServerURL().Host).
+		WithUserInfo(simr.Username(), simr.Password()).
+		WithDatacenter("*")
+
+	s, err := GetOrCreate(context.Background(), params)
+	g.Expect(err).ToNot(HaveOccurred())
+	// The subscription has to end before the simulator can be destroyed.
+	defer s.stopWatchers()
+
+	hosts, err := s.Finder.HostSystemList(context.Background(), "*/*")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(len(hosts)).To(BeNumerically(">=", 2))
+
+	changes := make(chan string, 10)
+	for _, key := range []string{"vm-1", "vm-2"} {
+		key := key
+		g.Expect(s.WatchHost(context.Background(), key, hosts[0].Reference(), func() {
+			changes <- key
+		})).To(Succeed())
+	}
+	// vm-2 migrates to another host.
+	g.Expect(s.WatchHost(context.Background(), "vm-2", hosts[1].Reference(), func() {
+		changes <- "vm-2"
+	})).To(Succeed())
+
+	// The simulator does not report the maintenance mode changes made by
+	// its tasks, hence the host is updated directly.
+	setMaintenanceMode := func(inMaintenanceMode bool) {
+		host := simulator.Map.Get(hosts[0].Reference())
+		simulator.Map.Update(host, []types.PropertyChange{{Name: "runtime.inMaintenanceMode", Val: inMaintenanceMode}})
+	}
+	setMaintenanceMode(true)
+	g.Eventually(changes, 10*time.Second).Should(Receive(Equal("vm-1")))
+	g.Consistently(changes, time.Second).ShouldNot(Receive())
+
+	// No changes are reported once the host is unwatched.
+	g.Expect(s.UnwatchHost(context.Background(), "vm-1")).To(Succeed())
+	setMaintenanceMode(false)
+	g.Consistently(changes, time.Second).ShouldNot(Receive())
+}
+
+func TestWatchTask(t *testing.T) {
+	g := NewWithT(t)
+
+	// The session must not expire while the subscription is idle.
+	idleTimeout := simulator.SessionIdleTimeout
+	simulator.SessionIdleTimeout = 0
+	defer func() {
+		simulator.SessionIdleTimeout = idleTimeout
+	}()
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	params := NewParams().
+		WithServer(simr.ServerURL().Host).
+		WithUserInfo(simr.Username(), simr.Password()).
+		WithDatacenter("*")
+
+	s, err := GetOrCreate(context.Background(), params)
+	g.Expect(err).ToNot(HaveOccurred())
+	// The subscription has to end before the simulator can be destroyed, and
+	// the session is not left in the cache for the other tests.
+	defer clearCache(klogr.New(), params.sessionKey())
+
+	vm, err := s.Finder.VirtualMachine(context.Background(), "DC0_H0_VM0")
+	g.Expect(err).ToNot(HaveOccurred())
+	watched := func() int {
+		s.watcherMu.Lock()
+		w := s.taskWatcher
+		s.watcherMu.Unlock()
+		if w == nil {
+			return 0
+		}
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return len(w.handlers)
+	}
+
+	// A task completed before being watched is reported, once however many
+	// times it is watched, and is no longer watched afterwards.
+	task, err := vm.PowerOff(context.Background())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(task.Wait(context.Background())).To(Succeed())
+	states := make(chan types.TaskInfoState, 10)
+	for i := 0; i < 2; i++ {
+		g.Expect(s.WatchTask(context.Background(), task.Reference(), func(state types.TaskInfoState) {
+			states <- state
+		})).To(Succeed())
+	}
+	g.Eventually(states, 10*time.Second).Should(Receive(Equal(types.TaskInfoStateSuccess)))
+	g.Consistently(states, time.Second).ShouldNot(Receive())
+	g.Eventually(watched, 10*time.Second).Should(BeZero())
+
+	// Powering the VM off again fails.
+	task, err = vm.PowerOff(context.Background())
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s.WatchTask(context.Background(), task.Reference(), func(state types.TaskInfoState) {
+		states <- state
+	})).To(Succeed())
+	g.Eventually(states, 10*time.Second).Should(Receive(Equal(types.TaskInfoStateError)))
+	g.Eventually(watched, 10*time.Second).Should(BeZero())
+}
+
+func TestRotateCredentials(t *testing.T) {
+	g := NewWithT(t)
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	newParams := func(password string) *Params {
+		return NewParams().
+			WithServer(simr.ServerURL().Host).
+			WithUserInfo(simr.Username(), password).
+			WithDatacenter("*")
+	}
+	owner := "default/cluster"
+	defer ForgetCredentials(owner)
+
+	// The first credentials of the owner are not a rotation.
+	params := newParams(simr.Password())
+	g.Expect(RotateCredentials(context.Background(), owner, params)).To(BeFalse())
+	_, err = GetOrCreate(context.Background(), params)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, ok := sessionCache.Load(params.sessionKey())
+	g.Expect(ok).To(BeTrue())
+	g.Expect(RotateCredentials(context.Background(), owner, params)).To(BeFalse())
+
+	// The sessions of the previous credentials are cleared on rotation.
+	g.Expect(RotateCredentials(context.Background(), owner, newParams("rotated"))).To(BeTrue())
+	_, ok = sessionCache.Load(params.sessionKey())
+	g.Expect(ok).To(BeFalse())
+	g.Expect(RotateCredentials(context.Background(), owner, newParams("rotated"))).To(BeFalse())
+}
+
+func TestTracing(t *testing.T) {
+	g := NewWithT(t)
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	ctx, root := tracing.Tracer().Start(context.Background(), "Reconcile VSphereVM")
+	params := NewParams().
+		WithServer(simr.ServerURL().Host).
+		WithUserInfo(simr.Username(), simr.Password()).
+		WithDatacenter("*")
+	s, err := GetOrCreate(ctx, params)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = s.Finder.VirtualMachine(ctx, "DC0_H0_VM0")
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = s.TagManager.CreateCategory(ctx, &tags.Category{Name: "category", Cardinality: "SINGLE"})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// The calls made without a span are not traced.
+	traced := len(recorder.Ended())
+	_, err = s.Finder.VirtualMachine(context.Background(), "DC0_H0_VM0")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(recorder.Ended()).To(HaveLen(traced))
+	root.End()
+
+	children := map[trace.SpanID][]string{}
+	var getOrCreate sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		g.Expect(span.SpanContext().TraceID()).To(Equal(root.SpanContext().TraceID()))
+		children[span.Parent().SpanID()] = append(children[span.Parent().SpanID()], span.Name())
+		if span.Name() == "session.GetOrCreate" {
+			getOrCreate = span
+		}
+	}
+
+	// The session is acquired as part of the request, and logged in as part
+	// of the acquisition.
+	g.Expect(getOrCreate).ToNot(BeNil())
+	g.Expect(getOrCreate.Attributes()).To(ContainElement(attribute.String("vcenter.server", simr.ServerURL().Host)))
+	g.Expect(children[root.SpanContext().SpanID()]).To(ContainElement("session.GetOrCreate"))
+	g.Expect(children[getOrCreate.SpanContext().SpanID()]).To(ContainElements("vcenter.Login", "vcenter.rest POST"))
+
+	// The vCenter API calls made with the request are its children.
+	g.Expect(children[root.SpanContext().SpanID()]).To(ContainElements("vcenter.RetrieveProperties", "vcenter.rest POST"))
+}
+
+func TestMissingPrivileges(t *testing.T) {
+	g := NewWithT(t)
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	s, err := GetOrCreate(context.Background(), NewParams().
+		WithServer(simr.ServerURL().Host).
+		WithUserInfo(simr.Username(), simr.Password()).
+		WithDatacenter("*"))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// The simulator grants all the privileges.
+	missing, err := s.MissingPrivileges(context.Background(), s.Client.ServiceContent.RootFolder, []string{"System.Read", "VirtualMachine.Inventory.CreateFromExisting"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(missing).To(BeEmpty())
+}
+
+func TestSupports(t *testing.T) {
+	g := NewWithT(t)
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	s, err := GetOrCreate(context.Background(), NewParams().
+		WithServer(simr.ServerURL().Host).
+		WithUserInfo(simr.Username(), simr.Password()).
+		WithDatacenter("*"))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	version, build := s.VCenterVersion()
+	g.Expect(version).To(Equal("6.5.0"))
+	g.Expect(build).ToNot(BeEmpty())
+	g.Expect(s.Supports(SecureBootCapability)).To(BeTrue())
+	g.Expect(s.Supports(VTPMCapability)).To(BeFalse())
+
+	s.Client.ServiceContent.About.Version = "7.0.3"
+	g.Expect(s.Supports(VTPMCapability)).To(BeTrue())
+	g.Expect(s.Supports(NativeKeyProviderCapability)).To(BeTrue())
+
+	// Unknown versions are assumed to provide all the capabilities.
+	s.Client.ServiceContent.About.Version = "unknown"
+	g.Expect(s.Supports(NativeKeyProviderCapability)).To(BeTrue())
+}
+
+func TestLogoutAll(t *testing.T) {
+	g := NewWithT(t)
+
+	idleTimeout := simulator.SessionIdleTimeout
+	simulator.SessionIdleTimeout = 0
+	defer func() {
+		simulator.SessionIdleTimeout = idleTimeout
+		atomic.StoreInt32(&closed, 0)
+	}()
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	params := NewParams().
+		WithServer(simr.ServerURL().Host).
+		WithUserInfo(simr.Username(), simr.Password()).
+		WithDatacenter("*").
+		WithFeatures(Feature{KeepAliveDuration: time.Minute})
+
+	s, err := GetOrCreate(context.Background(), params)
+	g.Expect(err).ToNot(HaveOccurred())
+	vm, err := s.Finder.VirtualMachine(context.Background(), "DC0_H0_VM0")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(s.WatchVM(context.Background(), vm.Reference(), func() {})).To(Succeed())
+	assertSessionCountEqualTo(g, simr, 1)
+
+	// The watchers are drained before the sessions are logged out.
+	s.watcherMu.Lock()
+	w := s.vmWatcher
+	s.watcherMu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	LogoutAll(ctx)
+	g.Expect(w.done).To(BeClosed())
+	assertSessionCountEqualTo(g, simr, 0)
+
+	// No session is created once the sessions are logged out.
+	_, err = GetOrCreate(context.Background(), params)
+	g.Expect(err).To(MatchError(ContainSubstring("shutting down")))
+	_, ok := sessionCache.Load(params.sessionKey())
+	g.Expect(ok).To(BeFalse())
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/vmware/govmomi/vim25/soap"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
+)
+
+// tracingRoundTripper traces the vCenter API calls going through it, made
+// as part of a traced operation.
+type tracingRoundTripper struct {
+	soap.RoundTripper
+	server string
+}
+
+func (rt tracingRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return rt.RoundTripper.RoundTrip(ctx, req, res)
+	}
+	method := requestMethod(req)
+	ctx, span := tracing.Tracer().Start(ctx, "vcenter."+method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("vcenter.server", rt.server),
+		attribute.String("vcenter.method", method),
+	))
+	err := rt.RoundTripper.RoundTrip(ctx, req, res)
+	if err == nil && res.Fault() != nil {
+		tracing.End(span, soap.WrapSoapFault(res.Fault()))
+		return nil
+	}
+	tracing.End(span, err)
+	return err
+}
+
+// tracingTransport traces the calls to the REST API of vCenter going through
+// it, made as part of a traced operation.
+type tracingTransport struct {
+	http.RoundTripper
+	server string
+}
+
+func (t tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !trace.SpanContextFromContext(req.Context()).IsValid() {
+		return t.RoundTripper.RoundTrip(req)
+	}
+	ctx, span := tracing.Tracer().Start(req.Context(), "vcenter.rest "+req.Method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("vcenter.server", t.server),
+		attribute.String("http.method", req.Method),
+		attribute.String("http.target", req.URL.Path),
+	))
+	res, err := t.RoundTripper.RoundTrip(req.WithContext(ctx))
+	if err == nil {
+		span.SetAttributes(attribute.Int("http.status_code", res.StatusCode))
+		if res.StatusCode >= http.StatusBadRequest {
+			span.SetStatus(codes.Error, res.Status)
+		}
+	}
+	tracing.End(span, err)
+	return res, err
+}
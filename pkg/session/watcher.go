@@ -0,0 +1,381 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+var (
+	// vmWatchProperties are the properties of the watched VMs whose changes
+	// are reported to the handlers.
+	vmWatchProperties = []string{
+		"runtime.powerState",
+		"guest.net",
+		"runtime.host",
+		"config.files.vmPathName",
+	}
+
+	// hostWatchProperties are the properties of the watched hosts whose
+	// changes are reported to the handlers.
+	hostWatchProperties = []string{
+		"runtime.connectionState",
+		"runtime.inMaintenanceMode",
+		"runtime.inQuarantineMode",
+		"runtime.healthSystemRuntime.systemHealthInfo",
+	}
+
+	// taskWatchProperties are the properties of the watched tasks whose
+	// changes are reported to the handlers.
+	taskWatchProperties = []string{
+		"info.state",
+	}
+)
+
+// watchHandler is called with the updates of the watched object.
+type watchHandler func(update types.ObjectUpdate)
+
+// onModify returns the handler calling onChange every time the watched
+// object changes. The objects entering the view are reconciled already, and
+// the objects leaving it are no longer of interest.
+func onModify(onChange func()) watchHandler {
+	return func(update types.ObjectUpdate) {
+		if update.Kind == types.ObjectUpdateKindModify {
+			onChange()
+		}
+	}
+}
+
+// watcher reports the changes of the properties of a set of objects of the
+// same kind using a single property collector subscription. Each handler is
+// registered under a key and watches a single object, while an object can be
+// watched by several handlers.
+type watcher struct {
+	mu        sync.Mutex
+	view      *view.ListView
+	collector *property.Collector
+	handlers  map[types.ManagedObjectReference]map[string]watchHandler
+	refs      map[string]types.ManagedObjectReference
+	cancel    context.CancelFunc
+	stopped   bool
+	// done is closed once the subscription and its handlers have exited.
+	done chan struct{}
+}
+
+// newWatcher creates a list view of the objects to watch and starts waiting
+// for updates of the properties of its objects in the background until stop
+// is called or the subscription fails.
+func newWatcher(ctx context.Context, logger logr.Logger, client *vim25.Client, kind string, props []string, onExit func()) (*watcher, error) {
+	listView, err := view.NewManager(client).CreateListView(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to create %s list view", kind)
+	}
+
+	// A dedicated collector is used as the default one is shared with all
+	// the other property retrievals of the session.
+	collector, err := property.DefaultCollector(client).Create(ctx)
+	if err != nil {
+		_ = listView.Destroy(ctx)
+		return nil, errors.Wrap(err, "unable to create property collector")
+	}
+
+	filter := new(property.WaitFilter).Add(listView.Reference(), kind, props, &types.TraversalSpec{
+		Type: "ListView",
+		Path: "view",
+	})
+	// Only the objects in the view are of interest, not the view itself.
+	filter.Spec.ObjectSet[0].Skip = types.NewBool(true)
+	if err := collector.CreateFilter(ctx, filter.CreateFilter); err != nil {
+		_ = collector.Destroy(ctx)
+		_ = listView.Destroy(ctx)
+		return nil, errors.Wrap(err, "unable to create property filter")
+	}
+
+	watchCtx, cancel := context.WithCancel(context.Background())
+	w := &watcher{
+		view:      listView,
+		collector: collector,
+		handlers:  map[types.ManagedObjectReference]map[string]watchHandler{},
+		refs:      map[string]types.ManagedObjectReference{},
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go w.run(watchCtx, logger.WithValues("kind", kind), onExit)
+
+	return w, nil
+}
+
+func (w *watcher) run(ctx context.Context, logger logr.Logger, onExit func()) {
+	defer close(w.done)
+	defer onExit()
+	defer func() {
+		_ = w.collector.Destroy(context.Background())
+		_ = w.view.Destroy(context.Background())
+	}()
+
+	version := ""
+	for {
+		set, err := w.collector.WaitForUpdates(ctx, version)
+		if err != nil {
+			if !w.isStopped() {
+				logger.Error(err, "watch failed")
+			}
+			return
+		}
+		if set == nil {
+			continue
+		}
+		version = set.Version
+
+		for _, fs := range set.FilterSet {
+			for _, update := range fs.ObjectSet {
+				for _, onUpdate := range w.objectHandlers(update.Obj) {
+					onUpdate(update)
+				}
+			}
+		}
+	}
+}
+
+func (w *watcher) isStopped() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stopped
+}
+
+func (w *watcher) objectHandlers(ref types.ManagedObjectReference) []watchHandler {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	handlers := make([]watchHandler, 0, len(w.handlers[ref]))
+	for _, onUpdate := range w.handlers[ref] {
+		handlers = append(handlers, onUpdate)
+	}
+	return handlers
+}
+
+// watch registers the handler under the key for the object, adding the
+// object to the view if it is not watched yet. The key stops watching the
+// object it watched before, if any.
+func (w *watcher) watch(ctx context.Context, key string, ref types.ManagedObjectReference, onUpdate watchHandler) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if prev, ok := w.refs[key]; ok && prev != ref {
+		if err := w.unwatchLocked(ctx, key); err != nil {
+			return err
+		}
+	}
+	if _, ok := w.handlers[ref]; !ok {
+		if err := w.view.Add(ctx, []types.ManagedObjectReference{ref}); err != nil {
+			return errors.Wrapf(err, "unable to watch %s", ref)
+		}
+		w.handlers[ref] = map[string]watchHandler{}
+	}
+	w.handlers[ref][key] = onUpdate
+	w.refs[key] = ref
+	return nil
+}
+
+// unwatch removes the handler registered under the key, removing the object
+// it watches from the view if no other handler watches it.
+func (w *watcher) unwatch(ctx context.Context, key string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.unwatchLocked(ctx, key)
+}
+
+func (w *watcher) unwatchLocked(ctx context.Context, key string) error {
+	ref, ok := w.refs[key]
+	if !ok {
+		return nil
+	}
+	delete(w.refs, key)
+	delete(w.handlers[ref], key)
+	if len(w.handlers[ref]) > 0 {
+		return nil
+	}
+	delete(w.handlers, ref)
+	if err := w.view.Remove(ctx, []types.ManagedObjectReference{ref}); err != nil {
+		return errors.Wrapf(err, "unable to unwatch %s", ref)
+	}
+	return nil
+}
+
+// stop cancels the subscription.
+func (w *watcher) stop() {
+	w.mu.Lock()
+	w.stopped = true
+	w.mu.Unlock()
+
+	// Canceling the context only aborts the pending wait on the client side,
+	// hence the wait is canceled on the server side first.
+	_ = w.collector.CancelWaitForUpdates(context.Background())
+	w.cancel()
+}
+
+// getWatcher returns the watcher of the session stored in field, starting
+// it if it is not running. It must be called with watcherMu held.
+func (s *Session) getWatcher(ctx context.Context, field **watcher, kind string, props []string) (*watcher, error) {
+	if *field != nil {
+		return *field, nil
+	}
+	var (
+		w   *watcher
+		err error
+	)
+	w, err = newWatcher(ctx, s.logger, s.Client.Client, kind, props, func() {
+		s.watcherMu.Lock()
+		defer s.watcherMu.Unlock()
+		// The objects are watched again by a new subscription the next
+		// time they are reconciled.
+		if *field == w {
+			*field = nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	*field = w
+	return w, nil
+}
+
+// WatchVM calls onChange every time the power state or the guest network of
+// the VM changes, until UnwatchVM is called or the session is cleared from
+// the cache. Watching an already watched VM replaces its handler.
+func (s *Session) WatchVM(ctx context.Context, ref types.ManagedObjectReference, onChange func()) error {
+	s.watcherMu.Lock()
+	defer s.watcherMu.Unlock()
+
+	w, err := s.getWatcher(ctx, &s.vmWatcher, "VirtualMachine", vmWatchProperties)
+	if err != nil {
+		return err
+	}
+	return w.watch(ctx, ref.Value, ref, onModify(onChange))
+}
+
+// UnwatchVM stops reporting the changes of the VM.
+func (s *Session) UnwatchVM(ctx context.Context, ref types.ManagedObjectReference) error {
+	s.watcherMu.Lock()
+	defer s.watcherMu.Unlock()
+
+	if s.vmWatcher == nil {
+		return nil
+	}
+	return s.vmWatcher.unwatch(ctx, ref.Value)
+}
+
+// WatchHost calls onChange every time the connection state, the maintenance
+// or quarantine mode, or the hardware health of the host changes, until
+// UnwatchHost is called with the same key or the session is cleared from the
+// cache. A key watches a single host, hence watching another host with the
+// same key, e.g. after a VM migrated, stops watching the previous one.
+func (s *Session) WatchHost(ctx context.Context, key string, ref types.ManagedObjectReference, onChange func()) error {
+	s.watcherMu.Lock()
+	defer s.watcherMu.Unlock()
+
+	w, err := s.getWatcher(ctx, &s.hostWatcher, "HostSystem", hostWatchProperties)
+	if err != nil {
+		return err
+	}
+	return w.watch(ctx, key, ref, onModify(onChange))
+}
+
+// UnwatchHost stops reporting the changes of the host watched with the key.
+func (s *Session) UnwatchHost(ctx context.Context, key string) error {
+	s.watcherMu.Lock()
+	defer s.watcherMu.Unlock()
+
+	if s.hostWatcher == nil {
+		return nil
+	}
+	return s.hostWatcher.unwatch(ctx, key)
+}
+
+// WatchTask calls onComplete with the state of the task once it succeeds or
+// fails, including when it completed before being watched, and stops watching
+// it. Watching an already watched task replaces its handler, so a task has a
+// single handler however many times it is watched.
+func (s *Session) WatchTask(ctx context.Context, ref types.ManagedObjectReference, onComplete func(types.TaskInfoState)) error {
+	s.watcherMu.Lock()
+	defer s.watcherMu.Unlock()
+
+	w, err := s.getWatcher(ctx, &s.taskWatcher, "Task", taskWatchProperties)
+	if err != nil {
+		return err
+	}
+	var once sync.Once
+	return w.watch(ctx, ref.Value, ref, func(update types.ObjectUpdate) {
+		for _, change := range update.ChangeSet {
+			state, ok := change.Val.(types.TaskInfoState)
+			if !ok || (state != types.TaskInfoStateSuccess && state != types.TaskInfoStateError) {
+				continue
+			}
+			once.Do(func() {
+				onComplete(state)
+				if err := w.unwatch(context.Background(), ref.Value); err != nil {
+					s.logger.V(4).Info("unable to unwatch completed task", "task", ref.Value, "error", err.Error())
+				}
+			})
+		}
+	})
+}
+
+// stopWatchers stops the subscriptions of the session, if any.
+func (s *Session) stopWatchers() {
+	for _, w := range s.takeWatchers() {
+		w.stop()
+	}
+}
+
+// drainWatchers stops the subscriptions of the session, if any, and waits
+// for them and the handlers they are running to exit, or for ctx to be done.
+func (s *Session) drainWatchers(ctx context.Context) {
+	watchers := s.takeWatchers()
+	for _, w := range watchers {
+		w.stop()
+	}
+	for _, w := range watchers {
+		select {
+		case <-w.done:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// takeWatchers detaches the running watchers from the session.
+func (s *Session) takeWatchers() []*watcher {
+	s.watcherMu.Lock()
+	defer s.watcherMu.Unlock()
+
+	var watchers []*watcher
+	for _, w := range []*watcher{s.vmWatcher, s.hostWatcher, s.taskWatcher} {
+		if w != nil {
+			watchers = append(watchers, w)
+		}
+	}
+	s.vmWatcher, s.hostWatcher, s.taskWatcher = nil, nil, nil
+	return watchers
+}
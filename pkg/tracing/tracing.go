@@ -0,0 +1,150 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing traces the reconciliations of the controllers and the
+// vCenter API calls they make with OpenTelemetry.
+package tracing
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/version"
+)
+
+const (
+	// instrumentationName is the name of the tracer of CAPV.
+	instrumentationName = "sigs.k8s.io/cluster-api-provider-vsphere"
+
+	// serviceName is the name of the service of the spans of CAPV.
+	serviceName = "capv-controller-manager"
+)
+
+// Options describes how the spans are exported.
+type Options struct {
+	// OTLPEndpoint is the host and port of the OTLP/HTTP endpoint the spans
+	// are exported to, e.g. otel-collector:4318. The spans are not exported
+	// when it is empty.
+	OTLPEndpoint string
+
+	// OTLPInsecure exports the spans over HTTP rather than HTTPS.
+	OTLPInsecure bool
+
+	// SamplingRatio is the ratio of the traces started by CAPV which are
+	// sampled, between 0 and 1.
+	SamplingRatio float64
+}
+
+// Setup registers the tracer provider exporting the spans to the OTLP
+// endpoint of the options, and returns the function flushing and stopping
+// it. Nothing is exported when the endpoint is not set.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	if opts.OTLPEndpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	if opts.SamplingRatio < 0 || opts.SamplingRatio > 1 {
+		return nil, errors.Errorf("invalid sampling ratio %v, must be between 0 and 1", opts.SamplingRatio)
+	}
+
+	exporterOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(opts.OTLPEndpoint)}
+	if opts.OTLPInsecure {
+		exporterOpts = append(exporterOpts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create OTLP exporter for %s", opts.OTLPEndpoint)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String(serviceName),
+		semconv.ServiceVersionKey.String(version.Get().GitVersion),
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create tracing resource")
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SamplingRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of CAPV, which does not record anything until a
+// tracer provider is registered.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// ContextWithSpanOf returns a copy of parent with the span of ctx, so the
+// operations made with a long-lived context as part of a reconcile request,
+// e.g. the login of vCenter sessions, are traced with the request.
+func ContextWithSpanOf(parent, ctx context.Context) context.Context {
+	return trace.ContextWithSpan(parent, trace.SpanFromContext(ctx))
+}
+
+// Untraced returns a copy of the context without span, for the operations
+// made in the background, e.g. keepalives, which are not traced.
+func Untraced(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(ctx, trace.SpanContext{})
+}
+
+// End records the error, if any, on the span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Reconciler returns a reconciler tracing the reconciliations of the objects
+// of the kind by r.
+func Reconciler(kind string, r reconcile.Reconciler) reconcile.Reconciler {
+	return reconciler{Reconciler: r, kind: kind}
+}
+
+type reconciler struct {
+	reconcile.Reconciler
+	kind string
+}
+
+func (r reconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	ctx, span := Tracer().Start(ctx, "Reconcile "+r.kind, trace.WithAttributes(
+		attribute.String("k8s.object.kind", r.kind),
+		attribute.String("k8s.namespace.name", req.Namespace),
+		attribute.String("k8s.object.name", req.Name),
+	))
+	defer func() {
+		End(span, reterr)
+	}()
+	return r.Reconciler.Reconcile(ctx, req)
+}
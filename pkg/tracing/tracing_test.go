@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconciler(t *testing.T) {
+	g := NewWithT(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	var reconcileErr error
+	r := Reconciler("VSphereVM", reconcile.Func(func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+		// The operations of the reconciliation are children of its span.
+		_, span := Tracer().Start(ctx, "child")
+		End(span, nil)
+		return ctrl.Result{}, reconcileErr
+	}))
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "vm"}}
+
+	_, err := r.Reconcile(context.Background(), req)
+	g.Expect(err).ToNot(HaveOccurred())
+	reconcileErr = errors.New("failed")
+	_, err = r.Reconcile(context.Background(), req)
+	g.Expect(err).To(MatchError("failed"))
+
+	spans := recorder.Ended()
+	g.Expect(spans).To(HaveLen(4))
+	g.Expect(spans[0].Name()).To(Equal("child"))
+	g.Expect(spans[1].Name()).To(Equal("Reconcile VSphereVM"))
+	g.Expect(spans[0].Parent().SpanID()).To(Equal(spans[1].SpanContext().SpanID()))
+	g.Expect(spans[1].Attributes()).To(ContainElements(
+		attribute.String("k8s.object.kind", "VSphereVM"),
+		attribute.String("k8s.namespace.name", "ns"),
+		attribute.String("k8s.object.name", "vm"),
+	))
+	g.Expect(spans[1].Status().Code).To(Equal(codes.Unset))
+	g.Expect(spans[3].Status().Code).To(Equal(codes.Error))
+	g.Expect(spans[3].Status().Description).To(Equal("failed"))
+}
+
+func TestUntraced(t *testing.T) {
+	g := NewWithT(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	ctx, span := Tracer().Start(context.Background(), "request")
+	defer span.End()
+	g.Expect(trace.SpanContextFromContext(ctx).IsValid()).To(BeTrue())
+	g.Expect(trace.SpanContextFromContext(Untraced(ctx)).IsValid()).To(BeFalse())
+
+	// The long-lived context gets the span of the request.
+	parent, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g.Expect(trace.SpanContextFromContext(ContextWithSpanOf(parent, ctx))).To(Equal(span.SpanContext()))
+}
+
+func TestSetup(t *testing.T) {
+	g := NewWithT(t)
+
+	// Nothing is exported without endpoint.
+	shutdown, err := Setup(context.Background(), Options{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(shutdown(context.Background())).To(Succeed())
+
+	_, err = Setup(context.Background(), Options{OTLPEndpoint: "localhost:4318", SamplingRatio: 2})
+	g.Expect(err).To(HaveOccurred())
+}
@@ -39,6 +39,9 @@ network:
       dhcp4: {{ $net.DHCP4 }}
       dhcp6: {{ $net.DHCP6 }}
       {{- end }}
+      {{- if $net.SLAAC }}
+      accept-ra: true
+      {{- end }}
       {{- if $net.IPAddrs }}
       addresses:
       {{- range $net.IPAddrs }}
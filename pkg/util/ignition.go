@@ -0,0 +1,358 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+const (
+	ignitionHostnamePath        = "/etc/hostname"
+	ignitionNetworkdPathFormat  = "/etc/systemd/network/10-capv-id%d.network"
+	ignitionKeyfilePathFormat   = "/etc/NetworkManager/system-connections/capv-id%d.nmconnection"
+	ignitionDefaultFileMode     = 0644
+	ignitionKeyfileFileMode     = 0600
+	ignitionMaxSupportedMinorV2 = 3
+	ignitionMaxSupportedMinorV3 = 4
+
+	// ignitionAfterburnDropinName is the name of the drop-in of the metadata
+	// units of Afterburn reading the metadata from the guestinfo variables.
+	ignitionAfterburnDropinName = "10-capv-guestinfo.conf"
+)
+
+// ignitionAfterburnUnits are the units fetching the metadata of the VM with
+// Afterburn, on Fedora CoreOS and Flatcar Container Linux respectively.
+var ignitionAfterburnUnits = []string{"afterburn.service", "coreos-metadata.service"}
+
+// ignitionAfterburnDropin replaces the metadata fetch of Afterburn, which
+// has no metadata on VMware, with the metadata of the
+// "guestinfo.afterburn.metadata" variable. The metadata is written both with
+// the AFTERBURN_ prefix read by Fedora CoreOS units, and the COREOS_ prefix
+// read by Flatcar Container Linux units.
+const ignitionAfterburnDropin = `[Unit]
+ConditionKernelCommandLine=
+
+[Service]
+ExecStart=
+ExecStart=/bin/sh -c 'PATH=$$PATH:/usr/share/oem/bin:/oem/bin; mkdir -p /run/metadata && vmtoolsd --cmd "info-get guestinfo.afterburn.metadata" | base64 -d > /run/metadata/afterburn && sed "s/^AFTERBURN_/COREOS_/" /run/metadata/afterburn > /run/metadata/flatcar'
+`
+
+// IgnitionVersion returns the major and minor spec version of an Ignition
+// config. Only the spec versions 2.0 to 2.3 and 3.0 to 3.4 are supported.
+func IgnitionVersion(data []byte) (int, int, error) {
+	config := struct {
+		Ignition struct {
+			Version string `json:"version"`
+		} `json:"ignition"`
+	}{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return 0, 0, errors.Wrap(err, "error parsing ignition config")
+	}
+
+	parts := strings.Split(config.Ignition.Version, ".")
+	if len(parts) < 2 {
+		return 0, 0, errors.Errorf("invalid ignition config version %q", config.Ignition.Version)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "invalid ignition config version %q", config.Ignition.Version)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "invalid ignition config version %q", config.Ignition.Version)
+	}
+
+	switch {
+	case major == 2 && minor <= ignitionMaxSupportedMinorV2,
+		major == 3 && minor <= ignitionMaxSupportedMinorV3:
+		return major, minor, nil
+	default:
+		return 0, 0, errors.Errorf("unsupported ignition config version %q", config.Ignition.Version)
+	}
+}
+
+// GetIgnitionConfig returns the Ignition config of the bootstrap data with
+// the hostname and the network configuration of the vsphereVM added to it.
+// The network configuration is written both as systemd-networkd units and
+// as NetworkManager keyfiles so it applies to Flatcar Container Linux as
+// well as Fedora CoreOS. Files already present in the bootstrap data are
+// left untouched. The Afterburn units get a drop-in reading the metadata of
+// the VM from guestinfo, see GetAfterburnMetadata.
+func GetIgnitionConfig(data []byte, hostname string, vsphereVM infrav1.VSphereVM, networkStatuses ...infrav1.NetworkStatus) ([]byte, error) {
+	major, _, err := IgnitionVersion(data)
+	if err != nil {
+		return nil, err
+	}
+
+	config := map[string]interface{}{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, errors.Wrap(err, "error parsing ignition config")
+	}
+	storage, ok := config["storage"].(map[string]interface{})
+	if !ok {
+		storage = map[string]interface{}{}
+		config["storage"] = storage
+	}
+	files, _ := storage["files"].([]interface{})
+
+	addFile := func(path, contents string, mode int) {
+		for _, f := range files {
+			if file, ok := f.(map[string]interface{}); ok && file["path"] == path {
+				return
+			}
+		}
+		file := map[string]interface{}{
+			"path": path,
+			"mode": mode,
+			"contents": map[string]interface{}{
+				"source": "data:;base64," + base64.StdEncoding.EncodeToString([]byte(contents)),
+			},
+		}
+		// Ignition v2 requires files to reference a filesystem, while
+		// Ignition v3 refuses to overwrite existing files by default.
+		if major == 2 {
+			file["filesystem"] = "root"
+		} else {
+			file["overwrite"] = true
+		}
+		files = append(files, file)
+	}
+
+	addFile(ignitionHostnamePath, hostname+"\n", ignitionDefaultFileMode)
+	for i, device := range vsphereVM.Spec.Network.Devices {
+		// The devices are matched by their MAC addresses which are only known
+		// once the network status of the VM is available.
+		macAddr := device.MACAddr
+		if i < len(networkStatuses) {
+			macAddr = networkStatuses[i].MACAddr
+		}
+		if macAddr == "" {
+			continue
+		}
+		routes := append(append([]infrav1.NetworkRouteSpec{}, device.Routes...), vsphereVM.Spec.Network.Routes...)
+		addFile(fmt.Sprintf(ignitionNetworkdPathFormat, i), networkdUnit(device, macAddr, routes), ignitionDefaultFileMode)
+		addFile(fmt.Sprintf(ignitionKeyfilePathFormat, i), networkManagerKeyfile(i, device, macAddr, routes), ignitionKeyfileFileMode)
+	}
+	storage["files"] = files
+
+	systemd, ok := config["systemd"].(map[string]interface{})
+	if !ok {
+		systemd = map[string]interface{}{}
+		config["systemd"] = systemd
+	}
+	units, _ := systemd["units"].([]interface{})
+	for _, name := range ignitionAfterburnUnits {
+		units = addIgnitionDropin(units, name, ignitionAfterburnDropinName, ignitionAfterburnDropin)
+	}
+	systemd["units"] = units
+
+	out, err := json.Marshal(config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting ignition config for vsphereVM %s/%s", vsphereVM.Namespace, vsphereVM.Name)
+	}
+	return out, nil
+}
+
+// addIgnitionDropin adds the drop-in to the unit, unless the unit already has
+// a drop-in of that name.
+func addIgnitionDropin(units []interface{}, unitName, name, contents string) []interface{} {
+	dropin := map[string]interface{}{
+		"name":     name,
+		"contents": contents,
+	}
+	for _, u := range units {
+		unit, ok := u.(map[string]interface{})
+		if !ok || unit["name"] != unitName {
+			continue
+		}
+		dropins, _ := unit["dropins"].([]interface{})
+		for _, d := range dropins {
+			if d, ok := d.(map[string]interface{}); ok && d["name"] == name {
+				return units
+			}
+		}
+		unit["dropins"] = append(dropins, dropin)
+		return units
+	}
+	return append(units, map[string]interface{}{
+		"name":    unitName,
+		"dropins": []interface{}{dropin},
+	})
+}
+
+// GetAfterburnMetadata returns the metadata of the vsphereVM in the format of
+// the environment files written by Afterburn, for the Afterburn units of
+// Ignition based images, see GetIgnitionConfig. The private IP addresses are
+// the first static ones of the devices, or the first ones reported by the VM.
+func GetAfterburnMetadata(hostname string, vsphereVM infrav1.VSphereVM, networkStatuses ...infrav1.NetworkStatus) []byte {
+	instanceID := vsphereVM.Spec.BiosUUID
+	if instanceID == "" {
+		instanceID = vsphereVM.Name
+	}
+
+	var addrs []string
+	for _, device := range vsphereVM.Spec.Network.Devices {
+		for _, addr := range device.IPAddrs {
+			if ip, _, err := net.ParseCIDR(addr); err == nil {
+				addrs = append(addrs, ip.String())
+			}
+		}
+	}
+	for _, status := range networkStatuses {
+		addrs = append(addrs, status.IPAddrs...)
+	}
+	var ipv4, ipv6 string
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		switch {
+		case ip == nil || ip.IsLinkLocalUnicast():
+		case ip.To4() != nil && ipv4 == "":
+			ipv4 = ip.String()
+		case ip.To4() == nil && ipv6 == "":
+			ipv6 = ip.String()
+		}
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "AFTERBURN_VMWARE_HOSTNAME=%s\n", hostname)
+	fmt.Fprintf(b, "AFTERBURN_VMWARE_INSTANCE_ID=%s\n", instanceID)
+	if ipv4 != "" {
+		fmt.Fprintf(b, "AFTERBURN_VMWARE_IPV4_PRIVATE=%s\n", ipv4)
+	}
+	if ipv6 != "" {
+		fmt.Fprintf(b, "AFTERBURN_VMWARE_IPV6_PRIVATE=%s\n", ipv6)
+	}
+	return []byte(b.String())
+}
+
+// networkdUnit returns the systemd-networkd unit configuring the device.
+func networkdUnit(device infrav1.NetworkDeviceSpec, macAddr string, routes []infrav1.NetworkRouteSpec) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "[Match]\nMACAddress=%s\n", macAddr)
+	if device.MTU != nil {
+		fmt.Fprintf(b, "\n[Link]\nMTUBytes=%d\n", *device.MTU)
+	}
+
+	b.WriteString("\n[Network]\n")
+	switch {
+	case device.DHCP4 && device.DHCP6:
+		b.WriteString("DHCP=yes\n")
+	case device.DHCP4:
+		b.WriteString("DHCP=ipv4\n")
+	case device.DHCP6:
+		b.WriteString("DHCP=ipv6\n")
+	}
+	if device.SLAAC {
+		b.WriteString("IPv6AcceptRA=yes\n")
+	}
+	for _, addr := range device.IPAddrs {
+		fmt.Fprintf(b, "Address=%s\n", addr)
+	}
+	for _, gateway := range []string{device.Gateway4, device.Gateway6} {
+		if gateway != "" {
+			fmt.Fprintf(b, "Gateway=%s\n", gateway)
+		}
+	}
+	for _, nameserver := range device.Nameservers {
+		fmt.Fprintf(b, "DNS=%s\n", nameserver)
+	}
+	if len(device.SearchDomains) > 0 {
+		fmt.Fprintf(b, "Domains=%s\n", strings.Join(device.SearchDomains, " "))
+	}
+
+	for _, route := range routes {
+		fmt.Fprintf(b, "\n[Route]\nDestination=%s\nGateway=%s\nMetric=%d\n", route.To, route.Via, route.Metric)
+	}
+	return b.String()
+}
+
+// networkManagerKeyfile returns the NetworkManager keyfile configuring the
+// device.
+func networkManagerKeyfile(index int, device infrav1.NetworkDeviceSpec, macAddr string, routes []infrav1.NetworkRouteSpec) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "[connection]\nid=capv-id%d\ntype=ethernet\n", index)
+	fmt.Fprintf(b, "\n[ethernet]\nmac-address=%s\n", macAddr)
+	if device.MTU != nil {
+		fmt.Fprintf(b, "mtu=%d\n", *device.MTU)
+	}
+
+	for _, family := range []struct {
+		name    string
+		dhcp    bool
+		gateway string
+		isIPv4  bool
+	}{
+		{name: "ipv4", dhcp: device.DHCP4, gateway: device.Gateway4, isIPv4: true},
+		{name: "ipv6", dhcp: device.DHCP6 || device.SLAAC, gateway: device.Gateway6},
+	} {
+		var addrs, nameservers []string
+		for _, addr := range device.IPAddrs {
+			if ip, _, err := net.ParseCIDR(addr); err == nil && (ip.To4() != nil) == family.isIPv4 {
+				addrs = append(addrs, addr)
+			}
+		}
+		for _, nameserver := range device.Nameservers {
+			if ip := net.ParseIP(nameserver); ip != nil && (ip.To4() != nil) == family.isIPv4 {
+				nameservers = append(nameservers, nameserver)
+			}
+		}
+
+		fmt.Fprintf(b, "\n[%s]\n", family.name)
+		switch {
+		case family.dhcp:
+			b.WriteString("method=auto\n")
+		case len(addrs) > 0:
+			b.WriteString("method=manual\n")
+		case family.isIPv4:
+			b.WriteString("method=disabled\n")
+		default:
+			b.WriteString("method=ignore\n")
+		}
+		for i, addr := range addrs {
+			fmt.Fprintf(b, "address%d=%s", i+1, addr)
+			if i == 0 && family.gateway != "" {
+				fmt.Fprintf(b, ",%s", family.gateway)
+			}
+			b.WriteString("\n")
+		}
+		if len(nameservers) > 0 {
+			fmt.Fprintf(b, "dns=%s;\n", strings.Join(nameservers, ";"))
+		}
+		if len(device.SearchDomains) > 0 {
+			fmt.Fprintf(b, "dns-search=%s;\n", strings.Join(device.SearchDomains, ";"))
+		}
+
+		i := 0
+		for _, route := range routes {
+			if ip, _, err := net.ParseCIDR(route.To); err != nil || (ip.To4() != nil) != family.isIPv4 {
+				continue
+			}
+			i++
+			fmt.Fprintf(b, "route%d=%s,%s,%d\n", i, route.To, route.Via, route.Metric)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+func Test_IgnitionVersion(t *testing.T) {
+	testCases := []struct {
+		name    string
+		data    string
+		major   int
+		minor   int
+		wantErr bool
+	}{
+		{name: "v2.3", data: `{"ignition":{"version":"2.3.0"}}`, major: 2, minor: 3},
+		{name: "v3.0", data: `{"ignition":{"version":"3.0.0"}}`, major: 3, minor: 0},
+		{name: "v3.4", data: `{"ignition":{"version":"3.4.0"}}`, major: 3, minor: 4},
+		{name: "v3.5 is unsupported", data: `{"ignition":{"version":"3.5.0"}}`, wantErr: true},
+		{name: "missing version", data: `{"ignition":{}}`, wantErr: true},
+		{name: "invalid config", data: `#cloud-config`, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			major, minor, err := util.IgnitionVersion([]byte(tc.data))
+			if tc.wantErr {
+				g.Expect(err).To(gomega.HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(major).To(gomega.Equal(tc.major))
+			g.Expect(minor).To(gomega.Equal(tc.minor))
+		})
+	}
+}
+
+func Test_GetIgnitionConfig(t *testing.T) {
+	vsphereVM := infrav1.VSphereVM{
+		Spec: infrav1.VSphereVMSpec{
+			VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+				Network: infrav1.NetworkSpec{
+					Devices: []infrav1.NetworkDeviceSpec{
+						{
+							NetworkName: "network1",
+							IPAddrs:     []string{"192.168.4.21/24"},
+							Gateway4:    "192.168.4.1",
+							Nameservers: []string{"8.8.8.8"},
+							MTU:         mtu(9000),
+							SLAAC:       true,
+						},
+						{
+							NetworkName: "network2",
+							DHCP4:       true,
+						},
+					},
+				},
+			},
+		},
+	}
+	networkStatuses := []infrav1.NetworkStatus{
+		{MACAddr: "00:00:00:00:00:01"},
+	}
+
+	testCases := []struct {
+		name       string
+		data       string
+		filesystem string
+	}{
+		{
+			name:       "v2.3",
+			data:       `{"ignition":{"version":"2.3.0"},"storage":{"files":[{"filesystem":"root","path":"/etc/motd","contents":{"source":"data:,hello"},"mode":420}]}}`,
+			filesystem: "root",
+		},
+		{
+			name: "v3.3",
+			data: `{"ignition":{"version":"3.3.0"},"storage":{"files":[{"path":"/etc/hostname","contents":{"source":"data:,custom"},"mode":420}]},"systemd":{"units":[{"name":"coreos-metadata.service","dropins":[{"name":"10-custom.conf","contents":"[Unit]"}]}]}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			out, err := util.GetIgnitionConfig([]byte(tc.data), "vm-1", vsphereVM, networkStatuses...)
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+
+			config := struct {
+				Systemd struct {
+					Units []struct {
+						Name    string `json:"name"`
+						Dropins []struct {
+							Name     string `json:"name"`
+							Contents string `json:"contents"`
+						} `json:"dropins"`
+					} `json:"units"`
+				} `json:"systemd"`
+				Storage struct {
+					Files []struct {
+						Filesystem string `json:"filesystem"`
+						Path       string `json:"path"`
+						Contents   struct {
+							Source string `json:"source"`
+						} `json:"contents"`
+					} `json:"files"`
+				} `json:"storage"`
+			}{}
+			g.Expect(json.Unmarshal(out, &config)).To(gomega.Succeed())
+
+			files := map[string]string{}
+			for _, file := range config.Storage.Files {
+				g.Expect(files).NotTo(gomega.HaveKey(file.Path))
+				contents := file.Contents.Source
+				if strings.HasPrefix(contents, "data:;base64,") {
+					decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(contents, "data:;base64,"))
+					g.Expect(err).NotTo(gomega.HaveOccurred())
+					contents = string(decoded)
+					g.Expect(file.Filesystem).To(gomega.Equal(tc.filesystem))
+				}
+				files[file.Path] = contents
+			}
+
+			if tc.filesystem != "" {
+				g.Expect(files).To(gomega.HaveKeyWithValue("/etc/motd", "data:,hello"))
+				g.Expect(files).To(gomega.HaveKeyWithValue("/etc/hostname", "vm-1\n"))
+			} else {
+				g.Expect(files).To(gomega.HaveKeyWithValue("/etc/hostname", "data:,custom"))
+			}
+			g.Expect(files).To(gomega.HaveKey("/etc/systemd/network/10-capv-id0.network"))
+			g.Expect(files["/etc/systemd/network/10-capv-id0.network"]).To(gomega.And(
+				gomega.ContainSubstring("MACAddress=00:00:00:00:00:01"),
+				gomega.ContainSubstring("MTUBytes=9000"),
+				gomega.ContainSubstring("Address=192.168.4.21/24"),
+				gomega.ContainSubstring("Gateway=192.168.4.1"),
+				gomega.ContainSubstring("DNS=8.8.8.8"),
+				gomega.ContainSubstring("IPv6AcceptRA=yes"),
+			))
+			g.Expect(files).To(gomega.HaveKey("/etc/NetworkManager/system-connections/capv-id0.nmconnection"))
+			g.Expect(files["/etc/NetworkManager/system-connections/capv-id0.nmconnection"]).To(gomega.And(
+				gomega.ContainSubstring("mac-address=00:00:00:00:00:01"),
+				gomega.ContainSubstring("method=manual"),
+				gomega.ContainSubstring("address1=192.168.4.21/24,192.168.4.1"),
+				gomega.ContainSubstring("dns=8.8.8.8;"),
+				gomega.ContainSubstring("[ipv6]\nmethod=auto"),
+			))
+			// The MAC address of the second device is not known yet.
+			g.Expect(files).NotTo(gomega.HaveKey("/etc/systemd/network/10-capv-id1.network"))
+
+			// The Afterburn units read the metadata from guestinfo.
+			dropins := map[string][]string{}
+			for _, unit := range config.Systemd.Units {
+				for _, dropin := range unit.Dropins {
+					dropins[unit.Name] = append(dropins[unit.Name], dropin.Name)
+					if dropin.Name == "10-capv-guestinfo.conf" {
+						g.Expect(dropin.Contents).To(gomega.ContainSubstring(`info-get guestinfo.afterburn.metadata`))
+					}
+				}
+			}
+			g.Expect(dropins).To(gomega.HaveKeyWithValue("afterburn.service", []string{"10-capv-guestinfo.conf"}))
+			if tc.filesystem != "" {
+				g.Expect(dropins).To(gomega.HaveKeyWithValue("coreos-metadata.service", []string{"10-capv-guestinfo.conf"}))
+			} else {
+				g.Expect(dropins).To(gomega.HaveKeyWithValue("coreos-metadata.service", []string{"10-custom.conf", "10-capv-guestinfo.conf"}))
+			}
+		})
+	}
+}
+
+func Test_GetAfterburnMetadata(t *testing.T) {
+	g := gomega.NewWithT(t)
+	vsphereVM := infrav1.VSphereVM{
+		Spec: infrav1.VSphereVMSpec{
+			BiosUUID: "42000000-0000-0000-0000-000000000001",
+			VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+				Network: infrav1.NetworkSpec{
+					Devices: []infrav1.NetworkDeviceSpec{
+						{NetworkName: "network1", DHCP4: true},
+						{NetworkName: "network2", IPAddrs: []string{"2001:db8::10/64"}},
+					},
+				},
+			},
+		},
+	}
+	networkStatuses := []infrav1.NetworkStatus{
+		{MACAddr: "00:00:00:00:00:01", IPAddrs: []string{"fe80::1", "192.168.4.21"}},
+	}
+
+	g.Expect(string(util.GetAfterburnMetadata("vm-1", vsphereVM, networkStatuses...))).To(gomega.Equal(
+		"AFTERBURN_VMWARE_HOSTNAME=vm-1\n" +
+			"AFTERBURN_VMWARE_INSTANCE_ID=42000000-0000-0000-0000-000000000001\n" +
+			"AFTERBURN_VMWARE_IPV4_PRIVATE=192.168.4.21\n" +
+			"AFTERBURN_VMWARE_IPV6_PRIVATE=2001:db8::10\n"))
+}
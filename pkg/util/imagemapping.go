@@ -0,0 +1,51 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// ResolveImageMapping returns the template, or the name of the
+// VSphereMachineImage, the VSphereMachineImageMapping maps the Kubernetes
+// version to. Both are empty if the VSphereMachineImageMapping does not exist
+// or does not map the version.
+func ResolveImageMapping(ctx context.Context, c client.Client, namespace, name string, version *string) (string, string, error) {
+	imageMapping := &infrav1.VSphereMachineImageMapping{}
+	key := client.ObjectKey{Namespace: namespace, Name: name}
+	if err := c.Get(ctx, key, imageMapping); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", "", nil
+		}
+		return "", "", errors.Wrapf(err, "failed to get VSphereMachineImageMapping %s", key)
+	}
+	versions := make([]string, len(imageMapping.Spec.Mappings))
+	for i, mapping := range imageMapping.Spec.Mappings {
+		versions[i] = mapping.KubernetesVersion
+	}
+	i := kubernetesVersionIndex(versions, version)
+	if i < 0 {
+		return "", "", nil
+	}
+	return imageMapping.Spec.Mappings[i].Template, imageMapping.Spec.Mappings[i].Image, nil
+}
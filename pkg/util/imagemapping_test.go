@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+func TestResolveImageMapping(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := infrav1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&infrav1.VSphereMachineImageMapping{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "ubuntu"},
+		Spec: infrav1.VSphereMachineImageMappingSpec{
+			Mappings: []infrav1.KubernetesVersionImage{
+				{KubernetesVersion: "v1.23", Template: "ubuntu-2004-kube-v1.23"},
+				{KubernetesVersion: "v1.24.1", Image: "ubuntu-2004-kube-v1.24.1"},
+			},
+		},
+	}).Build()
+
+	tests := []struct {
+		name             string
+		imageMapping     string
+		version          *string
+		expectedTemplate string
+		expectedImage    string
+	}{
+		{
+			name:             "template of the minor version",
+			imageMapping:     "ubuntu",
+			version:          pointer.String("v1.23.5"),
+			expectedTemplate: "ubuntu-2004-kube-v1.23",
+		},
+		{
+			name:          "image of the exact version",
+			imageMapping:  "ubuntu",
+			version:       pointer.String("v1.24.1"),
+			expectedImage: "ubuntu-2004-kube-v1.24.1",
+		},
+		{
+			name:         "version is not mapped",
+			imageMapping: "ubuntu",
+			version:      pointer.String("v1.24.2"),
+		},
+		{
+			name:         "image mapping does not exist",
+			imageMapping: "photon",
+			version:      pointer.String("v1.23.5"),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			template, image, err := ResolveImageMapping(context.Background(), c, "ns", tc.imageMapping, tc.version)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(template).To(Equal(tc.expectedTemplate))
+			g.Expect(image).To(Equal(tc.expectedImage))
+		})
+	}
+}
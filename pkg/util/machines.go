@@ -131,7 +131,7 @@ func GetMachinePreferredIPAddress(machine *infrav1.VSphereMachine) (string, erro
 	}
 
 	for _, machineAddr := range machine.Status.Addresses {
-		if machineAddr.Type != clusterv1.MachineExternalIP {
+		if machineAddr.Type != clusterv1.MachineExternalIP && machineAddr.Type != clusterv1.MachineInternalIP {
 			continue
 		}
 		if cidr == nil {
@@ -145,6 +145,73 @@ func GetMachinePreferredIPAddress(machine *infrav1.VSphereMachine) (string, erro
 	return "", ErrNoMachineIPAddr
 }
 
+// FilterIPAddrs returns the IP addresses which do not belong to the excluded
+// network CIDRs of the network spec, the ones which belong to its preferred
+// node IP CIDR first, so the primary node IP of a machine with several network
+// devices is not merely the first address discovered.
+func FilterIPAddrs(ipAddrs []string, network infrav1.NetworkSpec) []string {
+	var preferred *net.IPNet
+	if network.PreferredNodeIPCIDR != "" {
+		_, preferred, _ = net.ParseCIDR(network.PreferredNodeIPCIDR)
+	}
+	excluded := make([]*net.IPNet, 0, len(network.ExcludeNetworkCIDRs))
+	for _, cidr := range network.ExcludeNetworkCIDRs {
+		if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+			excluded = append(excluded, ipNet)
+		}
+	}
+	if preferred == nil && len(excluded) == 0 {
+		return ipAddrs
+	}
+
+	var preferredAddrs, otherAddrs []string
+addrs:
+	for _, addr := range ipAddrs {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			otherAddrs = append(otherAddrs, addr)
+			continue
+		}
+		for _, ipNet := range excluded {
+			if ipNet.Contains(ip) {
+				continue addrs
+			}
+		}
+		if preferred != nil && preferred.Contains(ip) {
+			preferredAddrs = append(preferredAddrs, addr)
+		} else {
+			otherAddrs = append(otherAddrs, addr)
+		}
+	}
+	return append(preferredAddrs, otherAddrs...)
+}
+
+// GetMachineAddresses returns the Machine addresses of a VM from its IP
+// addresses, which are internal IPs if they belong to one of the internal
+// CIDRs and external IPs otherwise, its host name and its DNS names.
+func GetMachineAddresses(ipAddrs []string, internalCIDRs []*net.IPNet, hostname string, dnsNames []string) []clusterv1.MachineAddress {
+	addresses := make([]clusterv1.MachineAddress, 0, len(ipAddrs)+len(dnsNames)+1)
+	for _, addr := range ipAddrs {
+		addrType := clusterv1.MachineExternalIP
+		if ip := net.ParseIP(addr); ip != nil {
+			for _, cidr := range internalCIDRs {
+				if cidr.Contains(ip) {
+					addrType = clusterv1.MachineInternalIP
+					break
+				}
+			}
+		}
+		addresses = append(addresses, clusterv1.MachineAddress{Type: addrType, Address: addr})
+	}
+	if hostname != "" {
+		addresses = append(addresses, clusterv1.MachineAddress{Type: clusterv1.MachineHostName, Address: hostname})
+	}
+	for _, dnsName := range dnsNames {
+		addresses = append(addresses, clusterv1.MachineAddress{Type: clusterv1.MachineInternalDNS, Address: dnsName})
+	}
+	return addresses
+}
+
 // IsControlPlaneMachine returns true if the provided resource is
 // a member of the control plane.
 func IsControlPlaneMachine(machine metav1.Object) bool {
@@ -168,7 +235,11 @@ func GetMachineMetadata(hostname string, vsphereVM infrav1.VSphereVM, networkSta
 		}
 		// check static IPs
 		for _, ipStr := range vsphereVM.Spec.Network.Devices[i].IPAddrs {
-			ip := net.ParseIP(ipStr)
+			// addresses are in the CIDR format, but may also be plain IPs
+			ip, _, err := net.ParseCIDR(ipStr)
+			if err != nil {
+				ip = net.ParseIP(ipStr)
+			}
 			// check the IP family
 			if ip != nil {
 				if ip.To4() == nil {
@@ -182,7 +253,7 @@ func GetMachineMetadata(hostname string, vsphereVM infrav1.VSphereVM, networkSta
 		if vsphereVM.Spec.Network.Devices[i].DHCP4 {
 			waitForIPv4 = true
 		}
-		if vsphereVM.Spec.Network.Devices[i].DHCP6 {
+		if vsphereVM.Spec.Network.Devices[i].DHCP6 || vsphereVM.Spec.Network.Devices[i].SLAAC {
 			waitForIPv6 = true
 		}
 	}
@@ -233,6 +304,54 @@ func GetOwnerVSphereMachine(ctx context.Context, c client.Client, obj metav1.Obj
 	return nil, nil
 }
 
+// IsOwnedByVSphereMachinePool returns whether the object is owned by a
+// VSphereMachinePool.
+func IsOwnedByVSphereMachinePool(obj metav1.ObjectMeta) bool {
+	for _, ref := range obj.OwnerReferences {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			continue
+		}
+		if ref.Kind == "VSphereMachinePool" && gv.Group == infrav1.GroupVersion.Group {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFailureRetried returns whether the failure of the VSphereVM is retried
+// according to its FailureRetryPolicy.
+func IsFailureRetried(vm *infrav1.VSphereVM) bool {
+	policy := vm.Spec.FailureRetryPolicy
+	if policy == nil || vm.Status.FailureReason == nil || vm.Status.FailureRetries >= policy.MaxAttempts {
+		return false
+	}
+	if len(policy.Reasons) == 0 {
+		return true
+	}
+	for _, reason := range policy.Reasons {
+		if reason == *vm.Status.FailureReason {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOwnedByVSphereLoadBalancerVM returns whether the object is owned by a
+// VSphereLoadBalancerVM.
+func IsOwnedByVSphereLoadBalancerVM(obj metav1.ObjectMeta) bool {
+	for _, ref := range obj.OwnerReferences {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			continue
+		}
+		if ref.Kind == "VSphereLoadBalancerVM" && gv.Group == infrav1.GroupVersion.Group {
+			return true
+		}
+	}
+	return false
+}
+
 func getVSphereMachineByName(ctx context.Context, c client.Client, namespace, name string) (*infrav1.VSphereMachine, error) {
 	m := &infrav1.VSphereMachine{}
 	key := client.ObjectKey{Name: name, Namespace: namespace}
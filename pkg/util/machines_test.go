@@ -17,6 +17,7 @@ limitations under the License.
 package util_test
 
 import (
+	"net"
 	"testing"
 
 	"github.com/onsi/gomega"
@@ -206,6 +207,29 @@ func Test_GetMachinePreferredIPAddress(t *testing.T) {
 			ipAddr:      "",
 			expectedErr: util.ErrNoMachineIPAddr,
 		},
+		{
+			name: "internal and external addresses, no preferred CIDR",
+			machine: &infrav1.VSphereMachine{
+				Status: infrav1.VSphereMachineStatus{
+					Addresses: []clusterv1.MachineAddress{
+						{
+							Type:    clusterv1.MachineHostName,
+							Address: "vm-0",
+						},
+						{
+							Type:    clusterv1.MachineInternalIP,
+							Address: "10.0.0.1",
+						},
+						{
+							Type:    clusterv1.MachineExternalIP,
+							Address: "192.168.0.1",
+						},
+					},
+				},
+			},
+			ipAddr:      "10.0.0.1",
+			expectedErr: nil,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -227,6 +251,68 @@ func Test_GetMachinePreferredIPAddress(t *testing.T) {
 	}
 }
 
+func Test_FilterIPAddrs(t *testing.T) {
+	ipAddrs := []string{"10.0.0.1", "172.16.0.1", "192.168.0.1", "fd00::1"}
+	testCases := []struct {
+		name     string
+		network  infrav1.NetworkSpec
+		expected []string
+	}{
+		{
+			name:     "no filters",
+			expected: ipAddrs,
+		},
+		{
+			name:     "preferred node IP CIDR",
+			network:  infrav1.NetworkSpec{PreferredNodeIPCIDR: "192.168.0.0/16"},
+			expected: []string{"192.168.0.1", "10.0.0.1", "172.16.0.1", "fd00::1"},
+		},
+		{
+			name:     "excluded network CIDRs",
+			network:  infrav1.NetworkSpec{ExcludeNetworkCIDRs: []string{"10.0.0.0/8", "fd00::/8"}},
+			expected: []string{"172.16.0.1", "192.168.0.1"},
+		},
+		{
+			name: "preferred and excluded network CIDRs",
+			network: infrav1.NetworkSpec{
+				PreferredNodeIPCIDR: "172.16.0.0/12",
+				ExcludeNetworkCIDRs: []string{"10.0.0.0/8"},
+			},
+			expected: []string{"172.16.0.1", "192.168.0.1", "fd00::1"},
+		},
+		{
+			name:    "all addresses excluded",
+			network: infrav1.NetworkSpec{ExcludeNetworkCIDRs: []string{"0.0.0.0/0", "::/0"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			g.Expect(util.FilterIPAddrs(ipAddrs, tc.network)).To(gomega.Equal(tc.expected))
+		})
+	}
+}
+
+func Test_GetMachineAddresses(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	_, internal, err := net.ParseCIDR("10.0.0.0/8")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	g.Expect(util.GetMachineAddresses([]string{"10.0.0.1", "192.168.0.1"}, nil, "", nil)).To(gomega.Equal([]clusterv1.MachineAddress{
+		{Type: clusterv1.MachineExternalIP, Address: "10.0.0.1"},
+		{Type: clusterv1.MachineExternalIP, Address: "192.168.0.1"},
+	}))
+	g.Expect(util.GetMachineAddresses([]string{"10.0.0.1", "192.168.0.1"}, []*net.IPNet{internal}, "vm-0", []string{"vm-0.example.com"})).To(gomega.Equal([]clusterv1.MachineAddress{
+		{Type: clusterv1.MachineInternalIP, Address: "10.0.0.1"},
+		{Type: clusterv1.MachineExternalIP, Address: "192.168.0.1"},
+		{Type: clusterv1.MachineHostName, Address: "vm-0"},
+		{Type: clusterv1.MachineInternalDNS, Address: "vm-0.example.com"},
+	}))
+}
+
 func Test_GetMachineMetadata(t *testing.T) {
 	testCases := []struct {
 		name            string
@@ -414,6 +500,83 @@ network:
       addresses:
       - "192.168.4.21"
       gateway4: "192.168.4.1"
+`,
+		},
+		{
+			name: "dhcp4+static6",
+			machine: &infrav1.VSphereVM{
+				Spec: infrav1.VSphereVMSpec{
+					VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+						Network: infrav1.NetworkSpec{
+							Devices: []infrav1.NetworkDeviceSpec{
+								{
+									NetworkName: "network1",
+									MACAddr:     "00:00:00:00:00",
+									DHCP4:       true,
+									IPAddrs:     []string{"fd00::21/64"},
+									Gateway6:    "fd00::1",
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: `
+instance-id: "test-vm"
+local-hostname: "test-vm"
+wait-on-network:
+  ipv4: true
+  ipv6: true
+network:
+  version: 2
+  ethernets:
+    id0:
+      match:
+        macaddress: "00:00:00:00:00"
+      set-name: "eth0"
+      wakeonlan: true
+      dhcp4: true
+      dhcp6: false
+      addresses:
+      - "fd00::21/64"
+      gateway6: "fd00::1"
+`,
+		},
+		{
+			name: "dhcp4+slaac",
+			machine: &infrav1.VSphereVM{
+				Spec: infrav1.VSphereVMSpec{
+					VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+						Network: infrav1.NetworkSpec{
+							Devices: []infrav1.NetworkDeviceSpec{
+								{
+									NetworkName: "network1",
+									MACAddr:     "00:00:00:00:00",
+									DHCP4:       true,
+									SLAAC:       true,
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: `
+instance-id: "test-vm"
+local-hostname: "test-vm"
+wait-on-network:
+  ipv4: true
+  ipv6: true
+network:
+  version: 2
+  ethernets:
+    id0:
+      match:
+        macaddress: "00:00:00:00:00"
+      set-name: "eth0"
+      wakeonlan: true
+      dhcp4: true
+      dhcp6: false
+      accept-ra: true
 `,
 		},
 		{
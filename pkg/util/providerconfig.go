@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// GetProviderConfig returns the VSphereProviderConfig of the controller
+// manager, or nil if it does not exist.
+func GetProviderConfig(ctx context.Context, c client.Client) (*infrav1.VSphereProviderConfig, error) {
+	config := &infrav1.VSphereProviderConfig{}
+	if err := c.Get(ctx, client.ObjectKey{Name: infrav1.ProviderConfigName}, config); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to get VSphereProviderConfig %s", infrav1.ProviderConfigName)
+	}
+	return config, nil
+}
+
+// DefaultTemplate returns the default template of the VSphereProviderConfig
+// for the Kubernetes version, or an empty string if it has none. The entry of
+// the exact version takes precedence over the one of its minor version.
+func DefaultTemplate(config *infrav1.VSphereProviderConfig, version *string) string {
+	if config == nil {
+		return ""
+	}
+	versions := make([]string, len(config.Spec.Templates))
+	for i, template := range config.Spec.Templates {
+		versions[i] = template.KubernetesVersion
+	}
+	if i := kubernetesVersionIndex(versions, version); i >= 0 {
+		return config.Spec.Templates[i].Template
+	}
+	return ""
+}
+
+// kubernetesVersionIndex returns the index of the entry of the Kubernetes
+// versions matching the version, or -1 if none does. The entry of the exact
+// version takes precedence over the one of its minor version.
+func kubernetesVersionIndex(versions []string, version *string) int {
+	if version == nil || *version == "" {
+		return -1
+	}
+	minorIndex := -1
+	for i, v := range versions {
+		if v == *version {
+			return i
+		}
+		if minorIndex < 0 && strings.HasPrefix(*version, v+".") {
+			minorIndex = i
+		}
+	}
+	return minorIndex
+}
+
+// ApplyProviderConfig sets the datastore and the networks of the network
+// devices left empty in the clone spec to the defaults of the
+// VSphereProviderConfig. The datastore is left empty in clone specs with
+// fallback datastores.
+func ApplyProviderConfig(spec *infrav1.VirtualMachineCloneSpec, config *infrav1.VSphereProviderConfig) {
+	if config == nil {
+		return
+	}
+	if spec.Datastore == "" && len(spec.Datastores) == 0 {
+		spec.Datastore = config.Spec.Datastore
+	}
+	if config.Spec.Network != "" {
+		for i := range spec.Network.Devices {
+			if spec.Network.Devices[i].NetworkName == "" {
+				spec.Network.Devices[i].NetworkName = config.Spec.Network
+			}
+		}
+	}
+}
+
+// KeepProviderConfigDefaults sets the template, the datastore and the
+// networks of the network devices left empty in the clone spec to the ones of
+// the existing clone spec, which were defaulted by the VSphereProviderConfig
+// when the VM was created and are kept even if its defaults change.
+func KeepProviderConfigDefaults(spec, existing *infrav1.VirtualMachineCloneSpec) {
+	if spec.Template == "" {
+		spec.Template = existing.Template
+	}
+	if spec.Datastore == "" {
+		spec.Datastore = existing.Datastore
+	}
+	for i := range spec.Network.Devices {
+		if spec.Network.Devices[i].NetworkName == "" && i < len(existing.Network.Devices) {
+			spec.Network.Devices[i].NetworkName = existing.Network.Devices[i].NetworkName
+		}
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+func TestGetProviderConfig(t *testing.T) {
+	g := NewWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(infrav1.AddToScheme(scheme)).To(Succeed())
+
+	config, err := GetProviderConfig(context.Background(), fake.NewClientBuilder().WithScheme(scheme).Build())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(config).To(BeNil())
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&infrav1.VSphereProviderConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: infrav1.ProviderConfigName},
+		Spec:       infrav1.VSphereProviderConfigSpec{Datastore: "ds0"},
+	}).Build()
+	config, err = GetProviderConfig(context.Background(), c)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(config.Spec.Datastore).To(Equal("ds0"))
+}
+
+func TestDefaultTemplate(t *testing.T) {
+	config := &infrav1.VSphereProviderConfig{
+		Spec: infrav1.VSphereProviderConfigSpec{
+			Templates: []infrav1.KubernetesVersionTemplate{
+				{KubernetesVersion: "v1.22", Template: "ubuntu-2004-kube-v1.22"},
+				{KubernetesVersion: "v1.23", Template: "ubuntu-2004-kube-v1.23"},
+				{KubernetesVersion: "v1.23.5", Template: "ubuntu-2004-kube-v1.23.5"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		config   *infrav1.VSphereProviderConfig
+		version  *string
+		expected string
+	}{
+		{
+			name:     "exact version",
+			config:   config,
+			version:  pointer.String("v1.23.5"),
+			expected: "ubuntu-2004-kube-v1.23.5",
+		},
+		{
+			name:     "minor version",
+			config:   config,
+			version:  pointer.String("v1.23.6"),
+			expected: "ubuntu-2004-kube-v1.23",
+		},
+		{
+			name:    "minor version is not a prefix of another minor version",
+			config:  config,
+			version: pointer.String("v1.220.0"),
+		},
+		{
+			name:    "unknown version",
+			config:  config,
+			version: pointer.String("v1.24.0"),
+		},
+		{
+			name:   "no version",
+			config: config,
+		},
+		{
+			name:    "no provider config",
+			version: pointer.String("v1.23.5"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(DefaultTemplate(tt.config, tt.version)).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestApplyProviderConfig(t *testing.T) {
+	g := NewWithT(t)
+	config := &infrav1.VSphereProviderConfig{
+		Spec: infrav1.VSphereProviderConfigSpec{Datastore: "ds0", Network: "vm-network"},
+	}
+	spec := &infrav1.VirtualMachineCloneSpec{
+		Network: infrav1.NetworkSpec{
+			Devices: []infrav1.NetworkDeviceSpec{{}, {NetworkName: "other-network"}},
+		},
+	}
+
+	ApplyProviderConfig(spec, nil)
+	g.Expect(spec.Datastore).To(BeEmpty())
+
+	ApplyProviderConfig(spec, config)
+	g.Expect(spec.Datastore).To(Equal("ds0"))
+	g.Expect(spec.Network.Devices[0].NetworkName).To(Equal("vm-network"))
+	g.Expect(spec.Network.Devices[1].NetworkName).To(Equal("other-network"))
+
+	spec = &infrav1.VirtualMachineCloneSpec{Datastore: "ds1"}
+	ApplyProviderConfig(spec, config)
+	g.Expect(spec.Datastore).To(Equal("ds1"))
+
+	spec = &infrav1.VirtualMachineCloneSpec{Datastores: []string{"ds1", "ds2"}}
+	ApplyProviderConfig(spec, config)
+	g.Expect(spec.Datastore).To(BeEmpty())
+}
+
+func TestKeepProviderConfigDefaults(t *testing.T) {
+	g := NewWithT(t)
+	existing := &infrav1.VirtualMachineCloneSpec{
+		Template:  "ubuntu-2004-kube-v1.23",
+		Datastore: "ds0",
+		Network: infrav1.NetworkSpec{
+			Devices: []infrav1.NetworkDeviceSpec{{NetworkName: "vm-network"}},
+		},
+	}
+	spec := &infrav1.VirtualMachineCloneSpec{
+		Network: infrav1.NetworkSpec{
+			Devices: []infrav1.NetworkDeviceSpec{{}, {}},
+		},
+	}
+
+	KeepProviderConfigDefaults(spec, existing)
+	g.Expect(spec.Template).To(Equal("ubuntu-2004-kube-v1.23"))
+	g.Expect(spec.Datastore).To(Equal("ds0"))
+	g.Expect(spec.Network.Devices[0].NetworkName).To(Equal("vm-network"))
+	g.Expect(spec.Network.Devices[1].NetworkName).To(BeEmpty())
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// CheckResourceQuota returns a message listing the resources of the
+// ResourceQuota of the VSphereCluster which creating count VMs with the clone
+// spec would exceed, or an empty message if the quota allows them. The usage
+// of the cluster is the sum of the resources of its VSphereVMs which are not
+// being deleted.
+func CheckResourceQuota(ctx context.Context, c client.Client, vsphereCluster *infrav1.VSphereCluster, clusterName string, spec *infrav1.VirtualMachineCloneSpec, count int) (string, error) {
+	quota := vsphereCluster.Spec.ResourceQuota
+	if quota == nil {
+		return "", nil
+	}
+
+	vmList := &infrav1.VSphereVMList{}
+	if err := c.List(ctx, vmList, client.InNamespace(vsphereCluster.Namespace), client.MatchingLabels{clusterv1.ClusterLabelName: clusterName}); err != nil {
+		return "", errors.Wrapf(err, "failed to list VSphereVMs of cluster %s/%s", vsphereCluster.Namespace, clusterName)
+	}
+	var cpus, memoryMiB, diskGiB int64
+	for i := range vmList.Items {
+		vm := &vmList.Items[i]
+		if !vm.DeletionTimestamp.IsZero() {
+			continue
+		}
+		vmCPUs, vmMemoryMiB, vmDiskGiB := cloneSpecResources(&vm.Spec.VirtualMachineCloneSpec)
+		cpus += vmCPUs
+		memoryMiB += vmMemoryMiB
+		diskGiB += vmDiskGiB
+	}
+	newCPUs, newMemoryMiB, newDiskGiB := cloneSpecResources(spec)
+	cpus += int64(count) * newCPUs
+	memoryMiB += int64(count) * newMemoryMiB
+	diskGiB += int64(count) * newDiskGiB
+
+	var exceeded []string
+	if quota.CPUs > 0 && cpus > quota.CPUs {
+		exceeded = append(exceeded, fmt.Sprintf("%d of %d vCPUs", cpus, quota.CPUs))
+	}
+	if quota.MemoryMiB > 0 && memoryMiB > quota.MemoryMiB {
+		exceeded = append(exceeded, fmt.Sprintf("%d of %d MiB of memory", memoryMiB, quota.MemoryMiB))
+	}
+	if quota.DiskGiB > 0 && diskGiB > quota.DiskGiB {
+		exceeded = append(exceeded, fmt.Sprintf("%d of %d GiB of disk", diskGiB, quota.DiskGiB))
+	}
+	if len(exceeded) == 0 {
+		return "", nil
+	}
+	return "resource quota of the cluster exceeded: " + strings.Join(exceeded, ", "), nil
+}
+
+// cloneSpecResources returns the number of vCPUs, the memory size in MiB and
+// the size of the disks in GiB of the VMs with the clone spec. Resources the
+// VMs inherit from their template are not counted.
+func cloneSpecResources(spec *infrav1.VirtualMachineCloneSpec) (int64, int64, int64) {
+	diskGiB := int64(spec.DiskGiB)
+	for _, size := range spec.AdditionalDisksGiB {
+		diskGiB += int64(size)
+	}
+	for _, disk := range spec.Disks {
+		diskGiB += int64(disk.SizeGiB)
+	}
+	return int64(spec.NumCPUs), spec.MemoryMiB, diskGiB
+}
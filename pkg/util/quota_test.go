@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+func TestCheckResourceQuota(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := infrav1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	vm := func(name, clusterName string, spec infrav1.VirtualMachineCloneSpec) *infrav1.VSphereVM {
+		return &infrav1.VSphereVM{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns",
+				Name:      name,
+				Labels:    map[string]string{clusterv1.ClusterLabelName: clusterName},
+			},
+			Spec: infrav1.VSphereVMSpec{VirtualMachineCloneSpec: spec},
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		vm("vm-1", "cluster", infrav1.VirtualMachineCloneSpec{NumCPUs: 4, MemoryMiB: 8192, DiskGiB: 40}),
+		vm("vm-2", "cluster", infrav1.VirtualMachineCloneSpec{NumCPUs: 4, MemoryMiB: 8192, DiskGiB: 40,
+			AdditionalDisksGiB: []int32{10}, Disks: []infrav1.DiskSpec{{SizeGiB: 50}}}),
+		vm("other", "other-cluster", infrav1.VirtualMachineCloneSpec{NumCPUs: 64}),
+	).Build()
+	spec := &infrav1.VirtualMachineCloneSpec{NumCPUs: 4, MemoryMiB: 8192, DiskGiB: 40}
+
+	tests := []struct {
+		name     string
+		quota    *infrav1.ResourceQuotaSpec
+		count    int
+		expected string
+	}{
+		{
+			name:  "without quota",
+			count: 10,
+		},
+		{
+			name:  "within quota",
+			quota: &infrav1.ResourceQuotaSpec{CPUs: 12, MemoryMiB: 24576, DiskGiB: 180},
+			count: 1,
+		},
+		{
+			name:     "exceeding quota",
+			quota:    &infrav1.ResourceQuotaSpec{CPUs: 12, DiskGiB: 180},
+			count:    2,
+			expected: "resource quota of the cluster exceeded: 16 of 12 vCPUs, 220 of 180 GiB of disk",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			vsphereCluster := &infrav1.VSphereCluster{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "cluster"},
+				Spec:       infrav1.VSphereClusterSpec{ResourceQuota: tt.quota},
+			}
+			message, err := CheckResourceQuota(context.Background(), c, vsphereCluster, "cluster", spec, tt.count)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(message).To(Equal(tt.expected))
+		})
+	}
+}
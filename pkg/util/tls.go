@@ -0,0 +1,59 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ThumbprintTLSConfig returns the TLS configuration of the clients of the
+// endpoint, e.g. the NSX-T manager, whose certificate is pinned by its
+// colon-separated SHA-256 thumbprint. The certificate is verified against the
+// system CAs when the thumbprint is empty.
+func ThumbprintTLSConfig(thumbprint, endpoint string) *tls.Config {
+	if thumbprint == "" {
+		return &tls.Config{} //nolint:gosec
+	}
+	//nolint:gosec
+	return &tls.Config{
+		// The chain of the certificate is not verified, only its thumbprint.
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: verifyThumbprint(thumbprint, endpoint),
+	}
+}
+
+// verifyThumbprint returns a function verifying the SHA-256 thumbprint of
+// the leaf certificate of the endpoint.
+func verifyThumbprint(thumbprint, endpoint string) func([][]byte, [][]*x509.Certificate) error {
+	want := strings.ToLower(strings.ReplaceAll(thumbprint, ":", ""))
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.Errorf("no certificate presented by the %s", endpoint)
+		}
+		sum := sha256.Sum256(rawCerts[0])
+		if got := fmt.Sprintf("%x", sum); got != want {
+			return errors.Errorf("thumbprint of the %s certificate %s does not match %s", endpoint, got, thumbprint)
+		}
+		return nil
+	}
+}
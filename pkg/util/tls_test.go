@@ -0,0 +1,64 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+func Test_ThumbprintTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer server.Close()
+
+	sum := sha256.Sum256(server.Certificate().Raw)
+	thumbprint := strings.ReplaceAll(fmt.Sprintf("% X", sum), " ", ":")
+
+	testCases := []struct {
+		name       string
+		thumbprint string
+		wantErr    string
+	}{
+		{name: "matching thumbprint", thumbprint: thumbprint},
+		{name: "mismatching thumbprint", thumbprint: strings.Repeat("00:", 31) + "00", wantErr: "does not match"},
+		// The certificate of the test server is self-signed.
+		{name: "without thumbprint", wantErr: "certificate"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: util.ThumbprintTLSConfig(tc.thumbprint, "test server")}}
+			resp, err := client.Get(server.URL)
+			if tc.wantErr != "" {
+				g.Expect(err).To(gomega.HaveOccurred())
+				g.Expect(err.Error()).To(gomega.ContainSubstring(tc.wantErr))
+				return
+			}
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			resp.Body.Close()
+		})
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package vcsim
 
 import (
+	"crypto/x509"
 	"fmt"
 	"net/url"
 
@@ -41,6 +42,11 @@ func (s Simulator) ServerURL() *url.URL {
 	return s.server.URL
 }
 
+// Certificate returns the TLS certificate of the simulator.
+func (s Simulator) Certificate() *x509.Certificate {
+	return s.server.Certificate()
+}
+
 func (s Simulator) Run(commandStr string, buffers ...*gbytes.Buffer) error {
 	pwd, _ := s.server.URL.User.Password()
 	govcURL := fmt.Sprintf("https://%s:%s@%s", s.server.URL.User.Username(), pwd, s.server.URL.Host)